@@ -0,0 +1,843 @@
+// gRPC surface of the chess engine server for backend services and bots.
+// It mirrors the WebSocket protocol: requests map to the inbound events and
+// StreamEvents delivers the same outbound events, with the JSON payloads
+// documented in docs/asyncapi.json.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.5
+// 	protoc        v5.29.3
+// source: api/proto/eng/v1/eng.proto
+
+package engv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type TimeControl struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	WhiteTime      int64                  `protobuf:"varint,1,opt,name=white_time,json=whiteTime,proto3" json:"white_time,omitempty"` // Milliseconds
+	BlackTime      int64                  `protobuf:"varint,2,opt,name=black_time,json=blackTime,proto3" json:"black_time,omitempty"`
+	WhiteIncrement int64                  `protobuf:"varint,3,opt,name=white_increment,json=whiteIncrement,proto3" json:"white_increment,omitempty"`
+	BlackIncrement int64                  `protobuf:"varint,4,opt,name=black_increment,json=blackIncrement,proto3" json:"black_increment,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *TimeControl) Reset() {
+	*x = TimeControl{}
+	mi := &file_api_proto_eng_v1_eng_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TimeControl) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TimeControl) ProtoMessage() {}
+
+func (x *TimeControl) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_eng_v1_eng_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TimeControl.ProtoReflect.Descriptor instead.
+func (*TimeControl) Descriptor() ([]byte, []int) {
+	return file_api_proto_eng_v1_eng_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *TimeControl) GetWhiteTime() int64 {
+	if x != nil {
+		return x.WhiteTime
+	}
+	return 0
+}
+
+func (x *TimeControl) GetBlackTime() int64 {
+	if x != nil {
+		return x.BlackTime
+	}
+	return 0
+}
+
+func (x *TimeControl) GetWhiteIncrement() int64 {
+	if x != nil {
+		return x.WhiteIncrement
+	}
+	return 0
+}
+
+func (x *TimeControl) GetBlackIncrement() int64 {
+	if x != nil {
+		return x.BlackIncrement
+	}
+	return 0
+}
+
+type CreateGameRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TimeControl   *TimeControl           `protobuf:"bytes,1,opt,name=time_control,json=timeControl,proto3" json:"time_control,omitempty"`
+	Color         string                 `protobuf:"bytes,2,opt,name=color,proto3" json:"color,omitempty"`                             // Color of the human side, w or b
+	InitialFen    string                 `protobuf:"bytes,3,opt,name=initial_fen,json=initialFen,proto3" json:"initial_fen,omitempty"` // Start position, empty for the standard one
+	Variant       string                 `protobuf:"bytes,4,opt,name=variant,proto3" json:"variant,omitempty"`                         // standard, chess960, crazyhouse, kingofthehill or 3check
+	UserId        string                 `protobuf:"bytes,5,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`             // User playing the game, needed for rated games
+	Rated         bool                   `protobuf:"varint,6,opt,name=rated,proto3" json:"rated,omitempty"`
+	Analysis      bool                   `protobuf:"varint,7,opt,name=analysis,proto3" json:"analysis,omitempty"` // Analyze the game with the engine once it is over
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateGameRequest) Reset() {
+	*x = CreateGameRequest{}
+	mi := &file_api_proto_eng_v1_eng_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateGameRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateGameRequest) ProtoMessage() {}
+
+func (x *CreateGameRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_eng_v1_eng_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateGameRequest.ProtoReflect.Descriptor instead.
+func (*CreateGameRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_eng_v1_eng_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CreateGameRequest) GetTimeControl() *TimeControl {
+	if x != nil {
+		return x.TimeControl
+	}
+	return nil
+}
+
+func (x *CreateGameRequest) GetColor() string {
+	if x != nil {
+		return x.Color
+	}
+	return ""
+}
+
+func (x *CreateGameRequest) GetInitialFen() string {
+	if x != nil {
+		return x.InitialFen
+	}
+	return ""
+}
+
+func (x *CreateGameRequest) GetVariant() string {
+	if x != nil {
+		return x.Variant
+	}
+	return ""
+}
+
+func (x *CreateGameRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *CreateGameRequest) GetRated() bool {
+	if x != nil {
+		return x.Rated
+	}
+	return false
+}
+
+func (x *CreateGameRequest) GetAnalysis() bool {
+	if x != nil {
+		return x.Analysis
+	}
+	return false
+}
+
+type CreateGameResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GameId        string                 `protobuf:"bytes,1,opt,name=game_id,json=gameId,proto3" json:"game_id,omitempty"`
+	InitialFen    string                 `protobuf:"bytes,2,opt,name=initial_fen,json=initialFen,proto3" json:"initial_fen,omitempty"`
+	Variant       string                 `protobuf:"bytes,3,opt,name=variant,proto3" json:"variant,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateGameResponse) Reset() {
+	*x = CreateGameResponse{}
+	mi := &file_api_proto_eng_v1_eng_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateGameResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateGameResponse) ProtoMessage() {}
+
+func (x *CreateGameResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_eng_v1_eng_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateGameResponse.ProtoReflect.Descriptor instead.
+func (*CreateGameResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_eng_v1_eng_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CreateGameResponse) GetGameId() string {
+	if x != nil {
+		return x.GameId
+	}
+	return ""
+}
+
+func (x *CreateGameResponse) GetInitialFen() string {
+	if x != nil {
+		return x.InitialFen
+	}
+	return ""
+}
+
+func (x *CreateGameResponse) GetVariant() string {
+	if x != nil {
+		return x.Variant
+	}
+	return ""
+}
+
+type MakeMoveRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GameId        string                 `protobuf:"bytes,1,opt,name=game_id,json=gameId,proto3" json:"game_id,omitempty"`
+	Move          string                 `protobuf:"bytes,2,opt,name=move,proto3" json:"move,omitempty"` // UCI or SAN notation
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MakeMoveRequest) Reset() {
+	*x = MakeMoveRequest{}
+	mi := &file_api_proto_eng_v1_eng_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MakeMoveRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MakeMoveRequest) ProtoMessage() {}
+
+func (x *MakeMoveRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_eng_v1_eng_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MakeMoveRequest.ProtoReflect.Descriptor instead.
+func (*MakeMoveRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_eng_v1_eng_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *MakeMoveRequest) GetGameId() string {
+	if x != nil {
+		return x.GameId
+	}
+	return ""
+}
+
+func (x *MakeMoveRequest) GetMove() string {
+	if x != nil {
+		return x.Move
+	}
+	return ""
+}
+
+type MakeMoveResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Fen           string                 `protobuf:"bytes,1,opt,name=fen,proto3" json:"fen,omitempty"` // Position after the move
+	San           string                 `protobuf:"bytes,2,opt,name=san,proto3" json:"san,omitempty"`
+	Uci           string                 `protobuf:"bytes,3,opt,name=uci,proto3" json:"uci,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MakeMoveResponse) Reset() {
+	*x = MakeMoveResponse{}
+	mi := &file_api_proto_eng_v1_eng_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MakeMoveResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MakeMoveResponse) ProtoMessage() {}
+
+func (x *MakeMoveResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_eng_v1_eng_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MakeMoveResponse.ProtoReflect.Descriptor instead.
+func (*MakeMoveResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_eng_v1_eng_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *MakeMoveResponse) GetFen() string {
+	if x != nil {
+		return x.Fen
+	}
+	return ""
+}
+
+func (x *MakeMoveResponse) GetSan() string {
+	if x != nil {
+		return x.San
+	}
+	return ""
+}
+
+func (x *MakeMoveResponse) GetUci() string {
+	if x != nil {
+		return x.Uci
+	}
+	return ""
+}
+
+type StreamEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GameId        string                 `protobuf:"bytes,1,opt,name=game_id,json=gameId,proto3" json:"game_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamEventsRequest) Reset() {
+	*x = StreamEventsRequest{}
+	mi := &file_api_proto_eng_v1_eng_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamEventsRequest) ProtoMessage() {}
+
+func (x *StreamEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_eng_v1_eng_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamEventsRequest.ProtoReflect.Descriptor instead.
+func (*StreamEventsRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_eng_v1_eng_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *StreamEventsRequest) GetGameId() string {
+	if x != nil {
+		return x.GameId
+	}
+	return ""
+}
+
+// Event is an outbound message of the WebSocket protocol
+type Event struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Event         string                 `protobuf:"bytes,1,opt,name=event,proto3" json:"event,omitempty"`                                // Event name, e.g. CLOCK_UPDATE or GAME_OVER
+	PayloadJson   []byte                 `protobuf:"bytes,2,opt,name=payload_json,json=payloadJson,proto3" json:"payload_json,omitempty"` // Payload encoded as JSON
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_api_proto_eng_v1_eng_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_eng_v1_eng_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_api_proto_eng_v1_eng_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Event) GetEvent() string {
+	if x != nil {
+		return x.Event
+	}
+	return ""
+}
+
+func (x *Event) GetPayloadJson() []byte {
+	if x != nil {
+		return x.PayloadJson
+	}
+	return nil
+}
+
+type AnalyzeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Fen           string                 `protobuf:"bytes,1,opt,name=fen,proto3" json:"fen,omitempty"`
+	Multipv       int32                  `protobuf:"varint,2,opt,name=multipv,proto3" json:"multipv,omitempty"` // Candidate lines to report, 1 when omitted
+	Depth         int32                  `protobuf:"varint,3,opt,name=depth,proto3" json:"depth,omitempty"`     // Depth to stop at, 0 searches until the stream is cancelled
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AnalyzeRequest) Reset() {
+	*x = AnalyzeRequest{}
+	mi := &file_api_proto_eng_v1_eng_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AnalyzeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AnalyzeRequest) ProtoMessage() {}
+
+func (x *AnalyzeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_eng_v1_eng_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AnalyzeRequest.ProtoReflect.Descriptor instead.
+func (*AnalyzeRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_eng_v1_eng_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *AnalyzeRequest) GetFen() string {
+	if x != nil {
+		return x.Fen
+	}
+	return ""
+}
+
+func (x *AnalyzeRequest) GetMultipv() int32 {
+	if x != nil {
+		return x.Multipv
+	}
+	return 0
+}
+
+func (x *AnalyzeRequest) GetDepth() int32 {
+	if x != nil {
+		return x.Depth
+	}
+	return 0
+}
+
+type AnalysisLine struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Multipv       int32                  `protobuf:"varint,1,opt,name=multipv,proto3" json:"multipv,omitempty"` // Rank of the line, 1 for the best one
+	Move          string                 `protobuf:"bytes,2,opt,name=move,proto3" json:"move,omitempty"`        // First move of the line in UCI notation
+	Depth         int32                  `protobuf:"varint,3,opt,name=depth,proto3" json:"depth,omitempty"`
+	ScoreCp       int32                  `protobuf:"varint,4,opt,name=score_cp,json=scoreCp,proto3" json:"score_cp,omitempty"`
+	Mate          int32                  `protobuf:"varint,5,opt,name=mate,proto3" json:"mate,omitempty"` // Moves to mate, 0 when no mate was found
+	Pv            []string               `protobuf:"bytes,6,rep,name=pv,proto3" json:"pv,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AnalysisLine) Reset() {
+	*x = AnalysisLine{}
+	mi := &file_api_proto_eng_v1_eng_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AnalysisLine) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AnalysisLine) ProtoMessage() {}
+
+func (x *AnalysisLine) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_eng_v1_eng_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AnalysisLine.ProtoReflect.Descriptor instead.
+func (*AnalysisLine) Descriptor() ([]byte, []int) {
+	return file_api_proto_eng_v1_eng_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *AnalysisLine) GetMultipv() int32 {
+	if x != nil {
+		return x.Multipv
+	}
+	return 0
+}
+
+func (x *AnalysisLine) GetMove() string {
+	if x != nil {
+		return x.Move
+	}
+	return ""
+}
+
+func (x *AnalysisLine) GetDepth() int32 {
+	if x != nil {
+		return x.Depth
+	}
+	return 0
+}
+
+func (x *AnalysisLine) GetScoreCp() int32 {
+	if x != nil {
+		return x.ScoreCp
+	}
+	return 0
+}
+
+func (x *AnalysisLine) GetMate() int32 {
+	if x != nil {
+		return x.Mate
+	}
+	return 0
+}
+
+func (x *AnalysisLine) GetPv() []string {
+	if x != nil {
+		return x.Pv
+	}
+	return nil
+}
+
+type AnalysisUpdate struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AnalysisId    string                 `protobuf:"bytes,1,opt,name=analysis_id,json=analysisId,proto3" json:"analysis_id,omitempty"`
+	Fen           string                 `protobuf:"bytes,2,opt,name=fen,proto3" json:"fen,omitempty"`
+	Depth         int32                  `protobuf:"varint,3,opt,name=depth,proto3" json:"depth,omitempty"`
+	Lines         []*AnalysisLine        `protobuf:"bytes,4,rep,name=lines,proto3" json:"lines,omitempty"`  // Best line first
+	Final         bool                   `protobuf:"varint,5,opt,name=final,proto3" json:"final,omitempty"` // Set on the last update of a search limited by depth
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AnalysisUpdate) Reset() {
+	*x = AnalysisUpdate{}
+	mi := &file_api_proto_eng_v1_eng_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AnalysisUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AnalysisUpdate) ProtoMessage() {}
+
+func (x *AnalysisUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_eng_v1_eng_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AnalysisUpdate.ProtoReflect.Descriptor instead.
+func (*AnalysisUpdate) Descriptor() ([]byte, []int) {
+	return file_api_proto_eng_v1_eng_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *AnalysisUpdate) GetAnalysisId() string {
+	if x != nil {
+		return x.AnalysisId
+	}
+	return ""
+}
+
+func (x *AnalysisUpdate) GetFen() string {
+	if x != nil {
+		return x.Fen
+	}
+	return ""
+}
+
+func (x *AnalysisUpdate) GetDepth() int32 {
+	if x != nil {
+		return x.Depth
+	}
+	return 0
+}
+
+func (x *AnalysisUpdate) GetLines() []*AnalysisLine {
+	if x != nil {
+		return x.Lines
+	}
+	return nil
+}
+
+func (x *AnalysisUpdate) GetFinal() bool {
+	if x != nil {
+		return x.Final
+	}
+	return false
+}
+
+var File_api_proto_eng_v1_eng_proto protoreflect.FileDescriptor
+
+var file_api_proto_eng_v1_eng_proto_rawDesc = string([]byte{
+	0x0a, 0x1a, 0x61, 0x70, 0x69, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x65, 0x6e, 0x67, 0x2f,
+	0x76, 0x31, 0x2f, 0x65, 0x6e, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x06, 0x65, 0x6e,
+	0x67, 0x2e, 0x76, 0x31, 0x22, 0x9d, 0x01, 0x0a, 0x0b, 0x54, 0x69, 0x6d, 0x65, 0x43, 0x6f, 0x6e,
+	0x74, 0x72, 0x6f, 0x6c, 0x12, 0x1d, 0x0a, 0x0a, 0x77, 0x68, 0x69, 0x74, 0x65, 0x5f, 0x74, 0x69,
+	0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x77, 0x68, 0x69, 0x74, 0x65, 0x54,
+	0x69, 0x6d, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x62, 0x6c, 0x61, 0x63, 0x6b, 0x5f, 0x74, 0x69, 0x6d,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x62, 0x6c, 0x61, 0x63, 0x6b, 0x54, 0x69,
+	0x6d, 0x65, 0x12, 0x27, 0x0a, 0x0f, 0x77, 0x68, 0x69, 0x74, 0x65, 0x5f, 0x69, 0x6e, 0x63, 0x72,
+	0x65, 0x6d, 0x65, 0x6e, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0e, 0x77, 0x68, 0x69,
+	0x74, 0x65, 0x49, 0x6e, 0x63, 0x72, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x27, 0x0a, 0x0f, 0x62,
+	0x6c, 0x61, 0x63, 0x6b, 0x5f, 0x69, 0x6e, 0x63, 0x72, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x0e, 0x62, 0x6c, 0x61, 0x63, 0x6b, 0x49, 0x6e, 0x63, 0x72, 0x65,
+	0x6d, 0x65, 0x6e, 0x74, 0x22, 0xe7, 0x01, 0x0a, 0x11, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x47,
+	0x61, 0x6d, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x36, 0x0a, 0x0c, 0x74, 0x69,
+	0x6d, 0x65, 0x5f, 0x63, 0x6f, 0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x13, 0x2e, 0x65, 0x6e, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x43, 0x6f,
+	0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x52, 0x0b, 0x74, 0x69, 0x6d, 0x65, 0x43, 0x6f, 0x6e, 0x74, 0x72,
+	0x6f, 0x6c, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x6c, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x05, 0x63, 0x6f, 0x6c, 0x6f, 0x72, 0x12, 0x1f, 0x0a, 0x0b, 0x69, 0x6e, 0x69, 0x74,
+	0x69, 0x61, 0x6c, 0x5f, 0x66, 0x65, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x69,
+	0x6e, 0x69, 0x74, 0x69, 0x61, 0x6c, 0x46, 0x65, 0x6e, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x61, 0x72,
+	0x69, 0x61, 0x6e, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x76, 0x61, 0x72, 0x69,
+	0x61, 0x6e, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x75, 0x73, 0x65, 0x72, 0x49, 0x64, 0x12, 0x14, 0x0a, 0x05,
+	0x72, 0x61, 0x74, 0x65, 0x64, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x72, 0x61, 0x74,
+	0x65, 0x64, 0x12, 0x1a, 0x0a, 0x08, 0x61, 0x6e, 0x61, 0x6c, 0x79, 0x73, 0x69, 0x73, 0x18, 0x07,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x61, 0x6e, 0x61, 0x6c, 0x79, 0x73, 0x69, 0x73, 0x22, 0x68,
+	0x0a, 0x12, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x47, 0x61, 0x6d, 0x65, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x17, 0x0a, 0x07, 0x67, 0x61, 0x6d, 0x65, 0x5f, 0x69, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x67, 0x61, 0x6d, 0x65, 0x49, 0x64, 0x12, 0x1f, 0x0a,
+	0x0b, 0x69, 0x6e, 0x69, 0x74, 0x69, 0x61, 0x6c, 0x5f, 0x66, 0x65, 0x6e, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0a, 0x69, 0x6e, 0x69, 0x74, 0x69, 0x61, 0x6c, 0x46, 0x65, 0x6e, 0x12, 0x18,
+	0x0a, 0x07, 0x76, 0x61, 0x72, 0x69, 0x61, 0x6e, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x07, 0x76, 0x61, 0x72, 0x69, 0x61, 0x6e, 0x74, 0x22, 0x3e, 0x0a, 0x0f, 0x4d, 0x61, 0x6b, 0x65,
+	0x4d, 0x6f, 0x76, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x67,
+	0x61, 0x6d, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x67, 0x61,
+	0x6d, 0x65, 0x49, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x6d, 0x6f, 0x76, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x04, 0x6d, 0x6f, 0x76, 0x65, 0x22, 0x48, 0x0a, 0x10, 0x4d, 0x61, 0x6b, 0x65,
+	0x4d, 0x6f, 0x76, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x10, 0x0a, 0x03,
+	0x66, 0x65, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x66, 0x65, 0x6e, 0x12, 0x10,
+	0x0a, 0x03, 0x73, 0x61, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x73, 0x61, 0x6e,
+	0x12, 0x10, 0x0a, 0x03, 0x75, 0x63, 0x69, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75,
+	0x63, 0x69, 0x22, 0x2e, 0x0a, 0x13, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x45, 0x76, 0x65, 0x6e,
+	0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x67, 0x61, 0x6d,
+	0x65, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x67, 0x61, 0x6d, 0x65,
+	0x49, 0x64, 0x22, 0x40, 0x0a, 0x05, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x65,
+	0x76, 0x65, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x76, 0x65, 0x6e,
+	0x74, 0x12, 0x21, 0x0a, 0x0c, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x5f, 0x6a, 0x73, 0x6f,
+	0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0b, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64,
+	0x4a, 0x73, 0x6f, 0x6e, 0x22, 0x52, 0x0a, 0x0e, 0x41, 0x6e, 0x61, 0x6c, 0x79, 0x7a, 0x65, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x66, 0x65, 0x6e, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x03, 0x66, 0x65, 0x6e, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x75, 0x6c, 0x74,
+	0x69, 0x70, 0x76, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x6d, 0x75, 0x6c, 0x74, 0x69,
+	0x70, 0x76, 0x12, 0x14, 0x0a, 0x05, 0x64, 0x65, 0x70, 0x74, 0x68, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x05, 0x64, 0x65, 0x70, 0x74, 0x68, 0x22, 0x91, 0x01, 0x0a, 0x0c, 0x41, 0x6e, 0x61,
+	0x6c, 0x79, 0x73, 0x69, 0x73, 0x4c, 0x69, 0x6e, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x75, 0x6c,
+	0x74, 0x69, 0x70, 0x76, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x6d, 0x75, 0x6c, 0x74,
+	0x69, 0x70, 0x76, 0x12, 0x12, 0x0a, 0x04, 0x6d, 0x6f, 0x76, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x04, 0x6d, 0x6f, 0x76, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x64, 0x65, 0x70, 0x74, 0x68,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x64, 0x65, 0x70, 0x74, 0x68, 0x12, 0x19, 0x0a,
+	0x08, 0x73, 0x63, 0x6f, 0x72, 0x65, 0x5f, 0x63, 0x70, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x07, 0x73, 0x63, 0x6f, 0x72, 0x65, 0x43, 0x70, 0x12, 0x12, 0x0a, 0x04, 0x6d, 0x61, 0x74, 0x65,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x6d, 0x61, 0x74, 0x65, 0x12, 0x0e, 0x0a, 0x02,
+	0x70, 0x76, 0x18, 0x06, 0x20, 0x03, 0x28, 0x09, 0x52, 0x02, 0x70, 0x76, 0x22, 0x9b, 0x01, 0x0a,
+	0x0e, 0x41, 0x6e, 0x61, 0x6c, 0x79, 0x73, 0x69, 0x73, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x12,
+	0x1f, 0x0a, 0x0b, 0x61, 0x6e, 0x61, 0x6c, 0x79, 0x73, 0x69, 0x73, 0x5f, 0x69, 0x64, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x61, 0x6e, 0x61, 0x6c, 0x79, 0x73, 0x69, 0x73, 0x49, 0x64,
+	0x12, 0x10, 0x0a, 0x03, 0x66, 0x65, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x66,
+	0x65, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x64, 0x65, 0x70, 0x74, 0x68, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x05, 0x64, 0x65, 0x70, 0x74, 0x68, 0x12, 0x2a, 0x0a, 0x05, 0x6c, 0x69, 0x6e, 0x65,
+	0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x65, 0x6e, 0x67, 0x2e, 0x76, 0x31,
+	0x2e, 0x41, 0x6e, 0x61, 0x6c, 0x79, 0x73, 0x69, 0x73, 0x4c, 0x69, 0x6e, 0x65, 0x52, 0x05, 0x6c,
+	0x69, 0x6e, 0x65, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x66, 0x69, 0x6e, 0x61, 0x6c, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x05, 0x66, 0x69, 0x6e, 0x61, 0x6c, 0x32, 0x8e, 0x02, 0x0a, 0x0d, 0x45,
+	0x6e, 0x67, 0x69, 0x6e, 0x65, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x43, 0x0a, 0x0a,
+	0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x47, 0x61, 0x6d, 0x65, 0x12, 0x19, 0x2e, 0x65, 0x6e, 0x67,
+	0x2e, 0x76, 0x31, 0x2e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x47, 0x61, 0x6d, 0x65, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1a, 0x2e, 0x65, 0x6e, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x43,
+	0x72, 0x65, 0x61, 0x74, 0x65, 0x47, 0x61, 0x6d, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x3d, 0x0a, 0x08, 0x4d, 0x61, 0x6b, 0x65, 0x4d, 0x6f, 0x76, 0x65, 0x12, 0x17, 0x2e,
+	0x65, 0x6e, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x4d, 0x61, 0x6b, 0x65, 0x4d, 0x6f, 0x76, 0x65, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x18, 0x2e, 0x65, 0x6e, 0x67, 0x2e, 0x76, 0x31, 0x2e,
+	0x4d, 0x61, 0x6b, 0x65, 0x4d, 0x6f, 0x76, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x3c, 0x0a, 0x0c, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x73,
+	0x12, 0x1b, 0x2e, 0x65, 0x6e, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d,
+	0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0d, 0x2e,
+	0x65, 0x6e, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x30, 0x01, 0x12, 0x3b,
+	0x0a, 0x07, 0x41, 0x6e, 0x61, 0x6c, 0x79, 0x7a, 0x65, 0x12, 0x16, 0x2e, 0x65, 0x6e, 0x67, 0x2e,
+	0x76, 0x31, 0x2e, 0x41, 0x6e, 0x61, 0x6c, 0x79, 0x7a, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x16, 0x2e, 0x65, 0x6e, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x41, 0x6e, 0x61, 0x6c, 0x79,
+	0x73, 0x69, 0x73, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x30, 0x01, 0x42, 0x35, 0x5a, 0x33, 0x67,
+	0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x74, 0x65, 0x63, 0x75, 0x32, 0x33,
+	0x2f, 0x65, 0x6e, 0x67, 0x2d, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2f, 0x61, 0x70, 0x69, 0x2f,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x65, 0x6e, 0x67, 0x2f, 0x76, 0x31, 0x3b, 0x65, 0x6e, 0x67,
+	0x76, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+})
+
+var (
+	file_api_proto_eng_v1_eng_proto_rawDescOnce sync.Once
+	file_api_proto_eng_v1_eng_proto_rawDescData []byte
+)
+
+func file_api_proto_eng_v1_eng_proto_rawDescGZIP() []byte {
+	file_api_proto_eng_v1_eng_proto_rawDescOnce.Do(func() {
+		file_api_proto_eng_v1_eng_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_proto_eng_v1_eng_proto_rawDesc), len(file_api_proto_eng_v1_eng_proto_rawDesc)))
+	})
+	return file_api_proto_eng_v1_eng_proto_rawDescData
+}
+
+var file_api_proto_eng_v1_eng_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_api_proto_eng_v1_eng_proto_goTypes = []any{
+	(*TimeControl)(nil),         // 0: eng.v1.TimeControl
+	(*CreateGameRequest)(nil),   // 1: eng.v1.CreateGameRequest
+	(*CreateGameResponse)(nil),  // 2: eng.v1.CreateGameResponse
+	(*MakeMoveRequest)(nil),     // 3: eng.v1.MakeMoveRequest
+	(*MakeMoveResponse)(nil),    // 4: eng.v1.MakeMoveResponse
+	(*StreamEventsRequest)(nil), // 5: eng.v1.StreamEventsRequest
+	(*Event)(nil),               // 6: eng.v1.Event
+	(*AnalyzeRequest)(nil),      // 7: eng.v1.AnalyzeRequest
+	(*AnalysisLine)(nil),        // 8: eng.v1.AnalysisLine
+	(*AnalysisUpdate)(nil),      // 9: eng.v1.AnalysisUpdate
+}
+var file_api_proto_eng_v1_eng_proto_depIdxs = []int32{
+	0, // 0: eng.v1.CreateGameRequest.time_control:type_name -> eng.v1.TimeControl
+	8, // 1: eng.v1.AnalysisUpdate.lines:type_name -> eng.v1.AnalysisLine
+	1, // 2: eng.v1.EngineService.CreateGame:input_type -> eng.v1.CreateGameRequest
+	3, // 3: eng.v1.EngineService.MakeMove:input_type -> eng.v1.MakeMoveRequest
+	5, // 4: eng.v1.EngineService.StreamEvents:input_type -> eng.v1.StreamEventsRequest
+	7, // 5: eng.v1.EngineService.Analyze:input_type -> eng.v1.AnalyzeRequest
+	2, // 6: eng.v1.EngineService.CreateGame:output_type -> eng.v1.CreateGameResponse
+	4, // 7: eng.v1.EngineService.MakeMove:output_type -> eng.v1.MakeMoveResponse
+	6, // 8: eng.v1.EngineService.StreamEvents:output_type -> eng.v1.Event
+	9, // 9: eng.v1.EngineService.Analyze:output_type -> eng.v1.AnalysisUpdate
+	6, // [6:10] is the sub-list for method output_type
+	2, // [2:6] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_api_proto_eng_v1_eng_proto_init() }
+func file_api_proto_eng_v1_eng_proto_init() {
+	if File_api_proto_eng_v1_eng_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_proto_eng_v1_eng_proto_rawDesc), len(file_api_proto_eng_v1_eng_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   10,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_api_proto_eng_v1_eng_proto_goTypes,
+		DependencyIndexes: file_api_proto_eng_v1_eng_proto_depIdxs,
+		MessageInfos:      file_api_proto_eng_v1_eng_proto_msgTypes,
+	}.Build()
+	File_api_proto_eng_v1_eng_proto = out.File
+	file_api_proto_eng_v1_eng_proto_goTypes = nil
+	file_api_proto_eng_v1_eng_proto_depIdxs = nil
+}
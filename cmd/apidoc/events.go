@@ -0,0 +1,416 @@
+package main
+
+import (
+	"github.com/tecu23/eng-server/internal/apidoc"
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/server"
+)
+
+// clientEvents are the events handled by the hub
+var clientEvents = []apidoc.Event{
+	{
+		Name: "CREATE_SESSION",
+		Description: "Create a new game session. The engine plays the first move when the player takes " +
+			"the side not to move, and only ever moves for its own color",
+		Payload: messages.CreateSession{},
+	},
+	{
+		Name:        "CREATE_EXHIBITION",
+		Description: "Start a game between two pooled engines, played entirely by the server",
+		Payload:     messages.CreateExhibitionPayload{},
+	},
+	{
+		Name: "CREATE_SIMUL",
+		Description: "Play a simul of up to 20 boards against a single engine, which gives it with White unless " +
+			"the player asks for it. The boards queue for the engine, which searches one at a time for a " +
+			"slice of the clock of the board. Hints and takebacks are not available. Answered with SIMUL_CREATED",
+		Payload: messages.CreateSimulPayload{},
+	},
+	{
+		Name:        "SPECTATE",
+		Description: "Watch a game, the current state is sent back as GAME_STATE",
+		Payload:     messages.SpectatePayload{},
+	},
+	{
+		Name: "MAKE_MOVE",
+		Description: "Make a move in an active game, on your turn in a game against another player. With a " +
+			"move_id, a retry after a network hiccup is not played twice: if a move with the same ID was " +
+			"played, the state right after it is sent back as MOVE_PROCESSED. The last 16 move IDs of a game " +
+			"are remembered",
+		Payload: messages.MakeMovePayload{},
+	},
+	{
+		Name:        "RESIGN",
+		Description: "Resign an active game",
+		Payload:     messages.ResignPayload{},
+	},
+	{
+		Name:        "PREMOVE",
+		Description: "Queue a move to be played as soon as the engine or the opponent has answered, an empty move cancels it",
+		Payload:     messages.PremovePayload{},
+	},
+	{
+		Name:        "TAKEBACK_REQUEST",
+		Description: "Undo the last move, together with the engine reply if it was already played. Not allowed in rated games and games against another player",
+		Payload:     messages.TakebackRequestPayload{},
+	},
+	{
+		Name: "RESUME_GAME",
+		Description: "Take over a game restored after a server restart. The clock and the engine start again " +
+			"and the current state is sent back as GAME_STATE. Only the user who played the game may resume it, " +
+			"any other user gets a FORBIDDEN error; exhibitions have no player and anyone may resume them",
+		Payload: messages.ResumeGamePayload{},
+	},
+	{
+		Name: "CLAIM_GAME",
+		Description: "Move a game to this connection, e.g. to switch from a phone to a desktop mid-game. The " +
+			"connection must be authenticated with the same API key as the connection that played the game, " +
+			"which may have closed within the disconnect grace period. The old connection becomes a spectator " +
+			"and gets GAME_CLAIMED, or is closed with code 4001 when previous is close. The current state is " +
+			"sent back as GAME_STATE. In a game between players each player claims their own seat",
+		Payload: messages.ClaimGamePayload{},
+	},
+	{
+		Name: "CLAIM_VICTORY",
+		Description: "End a game between players whose opponent closed their connection and has not claimed " +
+			"the game back for DISCONNECT_GRACE_PERIOD. The game is won by abandonment, or drawn when the " +
+			"claiming player does not have the material to checkmate, and ends with GAME_OVER",
+		Payload: messages.ClaimVictoryPayload{},
+	},
+	{
+		Name:        "GET_GAME_STATE",
+		Description: "Query the full state of a game",
+		Payload:     messages.GetGameStatePayload{},
+	},
+	{
+		Name: "ADMIN_SUBSCRIBE",
+		Description: "Subscribe to the admin topic, which streams match progress, internal errors and the " +
+			"ADMIN_DASHBOARD. Requires the admin permission",
+	},
+	{
+		Name:        "START_MATCH",
+		Description: "Start a match between two configured engines. Requires the admin permission",
+		Payload:     messages.StartMatchPayload{},
+	},
+	{
+		Name: "RESYNC",
+		Description: "Replay the events of a followed game sent after last_seq, from the journal of the game. " +
+			"Needs EVENT_JOURNAL_DIR on the server and at most 128 missed events, beyond that the game is " +
+			"reloaded with GET_GAME_STATE. Answered with the missed events, then RESYNCED",
+		Payload: messages.ResyncPayload{},
+	},
+	{
+		Name: "SET_PREFERENCES",
+		Description: "Choose the updates sent to the connection. Muted classes map to CLOCK_UPDATE (clock), " +
+			"EVAL_UPDATE (eval), PRESENCE_UPDATE (presence), CHAT_MESSAGE (chat) and BOARD_ANNOTATION " +
+			"(annotations), the events that decide a game are always sent. With eval final only the evaluation " +
+			"each engine move was played on and the eval bar are sent. Replaces earlier preferences and is " +
+			"answered with PREFERENCES",
+		Payload: messages.SetPreferencesPayload{},
+	},
+	{
+		Name: "LOBBY_SUBSCRIBE",
+		Description: "Follow the tournaments of the lobby. The tournaments are sent back as LOBBY_STATE and " +
+			"every change as TOURNAMENT_UPDATE",
+	},
+	{
+		Name: "CREATE_TOURNAMENT",
+		Description: "Open an arena or Swiss tournament against the engine or between players. In an arena " +
+			"players start a new game as soon as theirs ends until the time is up, in a Swiss tournament every " +
+			"player plays one game per round. Against the engine each player starts at the difficulty of the " +
+			"tournament and is paired one level up after a win and one level down after a loss. Between players " +
+			"the players with the most points meet each other: a Swiss round avoids rematches and gives the " +
+			"odd player out a bye scored as a win, an arena pairs waiting players every second and avoids " +
+			"an immediate rematch when it can. The creator follows the lobby",
+		Payload: messages.CreateTournamentPayload{},
+	},
+	{
+		Name: "JOIN_TOURNAMENT",
+		Description: "Join an open or running tournament as the user of the API key, who follows the lobby. " +
+			"Games are created on this connection and announced with TOURNAMENT_PAIRING. Closing the " +
+			"connection withdraws the player, who may join again, from another connection too",
+		Payload: messages.JoinTournamentPayload{},
+	},
+	{
+		Name:        "LEAVE_TOURNAMENT",
+		Description: "Withdraw the user of this connection from a tournament, a game in progress is still scored",
+		Payload:     messages.TournamentRequestPayload{},
+	},
+	{
+		Name:        "START_TOURNAMENT",
+		Description: "Start the games of a tournament. Only its creator or an admin may start it",
+		Payload:     messages.TournamentRequestPayload{},
+	},
+	{
+		Name:        "REQUEST_HINT",
+		Description: "Ask for the engine's best move on your turn, answered with HINT. Each game has a limited hint budget, rated games and games against another player have none",
+		Payload:     messages.RequestHintPayload{},
+	},
+	{
+		Name: "ANNOTATE",
+		Description: "Draw arrows, highlight squares and comment on the board of a game, sent to the player and " +
+			"every spectator as BOARD_ANNOTATION. Requires the annotate permission of coach and admin keys",
+		Payload: messages.AnnotatePayload{},
+	},
+	{
+		Name: "CHAT_MESSAGE",
+		Description: "Send a chat message to a game. The players channel is reserved to the players and the " +
+			"spectators channel to the spectators, whose chat the players never see. Messages are at most 300 " +
+			"characters, a connection may send 5 in a row then one every 2 seconds, and the server may mask words " +
+			"listed in CHAT_BLOCKED_WORDS",
+		Payload: messages.ChatPayload{},
+	},
+	{
+		Name: "CREATE_SEEK",
+		Description: "Offer a game to the other players, answered with SEEK_CREATED. The seek stays open until " +
+			"a player accepts it, it is cancelled or the connection closes; a connection keeps at most 3 seeks " +
+			"open. Rated games need a user and count for the rating of both players",
+		Payload: messages.CreateSeekPayload{},
+	},
+	{
+		Name: "ACCEPT_SEEK",
+		Description: "Accept the seek of another player, whose rating range must include yours. The game is " +
+			"created at once and both players get SEEK_MATCHED, then its events like those of any other game",
+		Payload: messages.SeekRequestPayload{},
+	},
+	{
+		Name:        "CANCEL_SEEK",
+		Description: "Close a seek of this connection, answered with SEEKS_LIST",
+		Payload:     messages.SeekRequestPayload{},
+	},
+	{
+		Name:        "LIST_SEEKS",
+		Description: "Query the open seeks, answered with SEEKS_LIST",
+	},
+	{
+		Name:        "START_ANALYSIS",
+		Description: "Analyze a position on a pooled engine, answered with ANALYSIS_STARTED then streamed as ANALYSIS_UPDATE",
+		Payload:     messages.StartAnalysisPayload{},
+	},
+	{
+		Name:        "STOP_ANALYSIS",
+		Description: "Stop a live analysis and release its engine",
+		Payload:     messages.StopAnalysisPayload{},
+	},
+	{
+		Name: "LIST_GAMES",
+		Description: "Query the history of finished games, answered with GAMES_LIST. Leaving out user_id lists " +
+			"the games of every user and requires the list_games permission of service and admin keys",
+		Payload: messages.ListGamesPayload{},
+	},
+}
+
+// serverEvents are the events sent by the hub
+var serverEvents = []apidoc.Event{
+	{
+		Name:        "CONNECTED",
+		Description: "Connection successfully established",
+		Payload:     messages.ConnectedPayload{},
+	},
+	{
+		Name:        "GAME_CREATED",
+		Description: "Game session successfully created",
+		Payload:     messages.GameCreatedPayload{},
+	},
+	{
+		Name:        "MOVE_PROCESSED",
+		Description: "A move has been applied to the board",
+		Payload:     messages.GameStatePayload{},
+	},
+	{
+		Name:        "GAME_STATE",
+		Description: "Full state of a game, sent in reply to GET_GAME_STATE, SPECTATE, RESUME_GAME and CLAIM_GAME",
+		Payload:     messages.GameStatePayload{},
+	},
+	{
+		Name:        "ENGINE_MOVE",
+		Description: "Engine has made a move",
+		Payload:     messages.EngineMovePayload{},
+	},
+	{
+		Name: "EVAL_UPDATE",
+		Description: "Search update of an engine playing an exhibition game, or the eval bar evaluation " +
+			"after a move when the server enables it. The evaluation an engine move was played on is sent " +
+			"again once the move is made, marked final",
+		Payload: messages.EvalPayload{},
+	},
+	{
+		Name: "CLOCK_UPDATE",
+		Description: "Authoritative clock state, sent on every turn change and as a 5 second heartbeat while " +
+			"the clock runs, for clients to interpolate locally. CLOCK_UPDATE, EVAL_UPDATE and ANALYSIS_UPDATE " +
+			"are coalesced per connection, a client that falls behind only receives the latest update of each " +
+			"game or analysis and every connection is limited to about 20 messages per second",
+		Payload: messages.ClockUpdatePayload{},
+	},
+	{
+		Name:        "TIME_UP",
+		Description: "A player has run out of time",
+		Payload:     messages.TimeupPayload{},
+	},
+	{
+		Name: "ENGINE_CRASHED",
+		Description: "The engine process exited during its search. A replacement engine from the pool takes " +
+			"over the position and the game goes on; when none can be leased, or engines keep crashing on the " +
+			"same move, the game ends as lost for the engine with reason engine_failure",
+		Payload: messages.EngineCrashedPayload{},
+	},
+	{
+		Name:        "BOARD_ANNOTATION",
+		Description: "Arrows, highlights and a comment a coach drew on the board with ANNOTATE",
+		Payload:     messages.BoardAnnotationPayload{},
+	},
+	{
+		Name:        "CHAT_MESSAGE",
+		Description: "A chat message of a game, sent to the readers of its channel",
+		Payload:     messages.ChatMessagePayload{},
+	},
+	{
+		Name:        "PREMOVE_DISCARDED",
+		Description: "A queued premove was illegal after the engine's move",
+		Payload:     messages.PremoveDiscardedPayload{},
+	},
+	{
+		Name:        "TAKEBACK_APPLIED",
+		Description: "Moves have been taken back",
+		Payload:     messages.TakebackAppliedPayload{},
+	},
+	{
+		Name: "GAME_OVER",
+		Description: "The game has ended. When the server signs results, the certificate holds the result, the " +
+			"final position, a hash of the moves and the clocks, signed with the key of GET /results/key",
+		Payload: messages.GameOverPayload{},
+	},
+	{
+		Name: "GAME_ABANDONED",
+		Description: "The game was terminated after no move or command for SESSION_IDLE_TIMEOUT " +
+			"(30 minutes by default), sent to the player and spectators",
+		Payload: messages.GameAbandonedPayload{},
+	},
+	{
+		Name: "GAME_RESTORED",
+		Description: "A game in progress was restored after a server restart with SNAPSHOT_PATH set. It stays " +
+			"paused, with its clock stopped, until a player sends RESUME_GAME. Sent to admin subscribers and spectators",
+		Payload: messages.GameRestoredPayload{},
+	},
+	{
+		Name: "PRESENCE_UPDATE",
+		Description: "Whether the players of a game are connected and how many spectators and streams follow " +
+			"it, sent to everyone following the game whenever one of them changes, e.g. when a player " +
+			"disconnects, reconnects or a spectator joins",
+		Payload: messages.PresencePayload{},
+	},
+	{
+		Name: "GAME_CLAIMED",
+		Description: "Another connection of the same player claimed a game with CLAIM_GAME. The connection " +
+			"keeps receiving the events of the game as a spectator",
+		Payload: messages.GameClaimedPayload{},
+	},
+	{
+		Name:        "MATCH_PROGRESS",
+		Description: "Progress of a running match, sent to admin subscribers",
+		Payload:     messages.MatchProgressPayload{},
+	},
+	{
+		Name: "ADMIN_DASHBOARD",
+		Description: "Server-wide state sent to admin subscribers right after ADMIN_SUBSCRIBE and then every " +
+			"2 seconds. Coalesced per connection like CLOCK_UPDATE",
+		Payload: server.DashboardPayload{},
+	},
+	{
+		Name:        "RESYNCED",
+		Description: "Sent after the events replayed in answer to RESYNC",
+		Payload:     messages.ResyncedPayload{},
+	},
+	{
+		Name:        "PREFERENCES",
+		Description: "Updates the connection receives, sent in reply to SET_PREFERENCES",
+		Payload:     messages.PreferencesPayload{},
+	},
+	{
+		Name:        "LOBBY_STATE",
+		Description: "Tournaments of the lobby, sent in reply to LOBBY_SUBSCRIBE",
+		Payload:     messages.LobbyStatePayload{},
+	},
+	{
+		Name: "TOURNAMENT_UPDATE",
+		Description: "A tournament was created, a player joined or left, or a game started or ended. Sent to " +
+			"every connection following the lobby",
+		Payload: messages.TournamentPayload{},
+	},
+	{
+		Name: "TOURNAMENT_PAIRING",
+		Description: "A tournament game of the player was created, its events follow like those of a game " +
+			"created with CREATE_SESSION. Between players both players get it, with the color each plays",
+		Payload: messages.TournamentPairingPayload{},
+	},
+	{
+		Name: "SIMUL_CREATED",
+		Description: "The boards of a simul were created, the events of every board follow like those of a " +
+			"game created with CREATE_SESSION",
+		Payload: messages.SimulCreatedPayload{},
+	},
+	{
+		Name: "INTERNAL_ERROR",
+		Description: "The server recovered from a panic, sent to admin subscribers. A panic in a game session " +
+			"terminates that game; other components keep running",
+		Payload: messages.InternalErrorPayload{},
+	},
+	{
+		Name:        "HINT",
+		Description: "Move suggested in reply to REQUEST_HINT, only sent to the player",
+		Payload:     messages.HintPayload{},
+	},
+	{
+		Name:        "SEEK_CREATED",
+		Description: "A seek was opened, sent in reply to CREATE_SEEK",
+		Payload:     messages.SeekPayload{},
+	},
+	{
+		Name:        "SEEKS_LIST",
+		Description: "The open seeks, the oldest first. Sent in reply to LIST_SEEKS and CANCEL_SEEK",
+		Payload:     messages.SeeksListPayload{},
+	},
+	{
+		Name: "SEEK_MATCHED",
+		Description: "A seek was accepted, sent to both players with the color each plays. The events of the " +
+			"game follow like those of a game created with CREATE_SESSION",
+		Payload: messages.SeekMatchedPayload{},
+	},
+	{
+		Name:        "ANALYSIS_REPORT",
+		Description: "Engine review of a finished game that was created with analysis enabled",
+		Payload:     messages.AnalysisReportPayload{},
+	},
+	{
+		Name:        "ANALYSIS_STARTED",
+		Description: "A live analysis has started",
+		Payload:     messages.AnalysisStartedPayload{},
+	},
+	{
+		Name:        "ANALYSIS_UPDATE",
+		Description: "Top candidate lines of a live analysis, sent each time the engine completes an iteration",
+		Payload:     messages.AnalysisUpdatePayload{},
+	},
+	{
+		Name:        "GAMES_LIST",
+		Description: "A page of the game history, sent in reply to LIST_GAMES",
+		Payload:     messages.GamesListPayload{},
+	},
+	{
+		Name: "ERROR",
+		Description: "A message could not be handled. The code is stable for clients to branch on, " +
+			"event and request_id identify the failed message",
+		Payload: messages.ErrorPayload{},
+	},
+	{
+		Name: "REPLAY_STARTED",
+		Description: "First message of a /replay/{id} connection, followed by the journaled events of the game " +
+			"as they were sent to its clients, then REPLAY_FINISHED",
+		Payload: messages.ReplayStartedPayload{},
+	},
+	{
+		Name:        "REPLAY_FINISHED",
+		Description: "Every journaled event of the game was replayed, the server closes the connection",
+		Payload:     messages.ReplayFinishedPayload{},
+	},
+}
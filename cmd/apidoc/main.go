@@ -0,0 +1,173 @@
+// Command apidoc generates the AsyncAPI description of the websocket protocol
+// and the OpenAPI description of the REST endpoints, from the message types and
+// their doc comments. Run it through go generate ./docs
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+
+	"github.com/tecu23/eng-server/internal/apidoc"
+	"github.com/tecu23/eng-server/internal/color"
+	"github.com/tecu23/eng-server/internal/messages"
+)
+
+const module = "github.com/tecu23/eng-server"
+
+// documented are the packages declaring the types of the messages
+var documented = []string{
+	"internal/messages",
+	"pkg/engine",
+	"pkg/health",
+	"pkg/rating",
+	"pkg/server",
+	"pkg/tablebase",
+}
+
+var servers = []apidoc.Server{
+	{Name: "local", URL: "localhost:8080", Description: "Local development server"},
+	{Name: "production", URL: "chess-engine-server.example.com", Description: "Production server"},
+}
+
+const restDescription = `API documentation for the Chess Engine Server, which provides WebSocket-based
+communication for playing chess against UCI-compatible chess engines. The
+WebSocket events are described in the AsyncAPI document at /docs/asyncapi.json.
+
+Every route is rate limited per client IP and answers 429 Too Many Requests,
+with a Retry-After header and a JSON ErrorPayload with the RATE_LIMITED
+code, when the limit is exceeded. On a Unix socket (UNIX_SOCKET_PATH, or a
+Unix socket passed by systemd socket activation) requests carry no client
+IP, so the limit applies per user of the API key and the requests without a
+valid key share one limit. All routes except /health, /livez, /readyz,
+/version and /docs require the X-Api-Key header, or the session token sent
+with CONNECTED to a WebSocket connection as Authorization: Bearer <token>.
+The token stands for the key of the connection until the connection closes
+or SESSION_TOKEN_TTL (1h by default) has passed, so browsers need not embed
+the key in every fetch. Only connections opened with X-Api-Key get a token,
+a connection opened with a token gets no new one.
+
+Every key has a role whose permissions gate the routes and the WebSocket
+events, answered with 403 Forbidden or a FORBIDDEN error otherwise. Keys of
+API_KEYS are players (read, play, analyze), COACH_API_KEYS coaches (a player
+who may also annotate), SERVICE_API_KEYS services (read, analyze, list_games)
+and ADMIN_API_KEYS admins (every permission). When ADMIN_API_KEYS is not set
+no key is an admin and the admin routes are closed. OPEN_ADMIN=true makes
+every key an admin, for a development server only. A key listed as user:key
+authenticates that user, any other key a user of its own. Games, ratings and tournaments are those of the
+authenticated user, a user_id sent by the client must name it.
+
+Every HTTP response carries an X-Request-Id header with the correlation ID
+of the request, taken from the request header when the client sets one.`
+
+const wsDescription = `WebSocket protocol of the Chess Engine Server, opened at GET /ws with the
+X-Api-Key header.
+
+Clients must keep reading: messages that do not fit in the outbound buffer
+are dropped, and a connection whose buffer stays full for more than 5 seconds
+is closed. The server sends a ping frame every 5 seconds to measure the lag
+used for lag compensation, clients must answer with the standard pong. Games
+created over a connection are terminated when it closes, after
+DISCONNECT_GRACE_PERIOD when the server sets one. A game between players goes
+on while one of them is still connected, the absent player's clock keeps
+running and their opponent may claim the game with CLAIM_VICTORY once they
+have been away for DISCONNECT_GRACE_PERIOD.
+
+The wire encoding is negotiated through the Sec-WebSocket-Protocol header.
+eng.v1.msgpack sends every message as a MessagePack map in a binary frame,
+with the same fields as its JSON form. Clients may send binary MessagePack or
+text JSON frames. Without a subprotocol, or with eng.v1.json, all messages
+are JSON text frames. eng.v1.json.batch is JSON too, but when messages queue
+up for a slow client several of them share a text frame, one message per line,
+so clients split every frame on newlines.
+
+Every message is an envelope {"event", "payload", "request_id"}. The optional
+request_id is a correlation ID: the server assigns one when the client sends
+none, echoes it in the direct replies and ERROR messages to that message, and
+tags every log line it causes with it, down to the engine search. Events
+pushed to the game, like MOVE_PROCESSED, carry none.
+
+Events pushed to a game carry seq, numbering the events of the game from 1.
+CLOCK_UPDATE and EVAL_UPDATE, which may be coalesced, repeat the seq of the
+last event, so a gap in the numbers always means missed events and the
+CLOCK_UPDATE heartbeat tells an idle client the latest seq. After reconnecting
+and resuming or spectating the game, clients send RESYNC with the last seq
+they received to get the missed events again from the game journal, and drop
+the events they already have.
+
+The eng.v2 subprotocols, eng.v2.msgpack, eng.v2.json.batch and eng.v2.json,
+encode like their eng.v1 counterparts but wrap outbound messages in the
+versioned envelope {"v": 2, "event", "game_id", "seq", "ts", "request_id",
+"payload"}. game_id is set on every message about a game, even when the
+payload lacks it, and ts is the Unix time in milliseconds the message was
+sent. Every field is snake_case: the CLOCK_UPDATE payload becomes {game_id,
+white_time, black_time, active_color, running, server_time, lag_compensation}
+and the GAME_OVER payload spells game_id. The payloads documented here are the
+eng.v1 shapes, kept for clients that negotiate eng.v1 or no subprotocol.`
+
+func main() {
+	root := flag.String("root", ".", "Root directory of the module")
+	out := flag.String("out", "docs", "Directory the documents are written to")
+	flag.Parse()
+
+	docs, err := apidoc.LoadDocs(*root, module, documented...)
+	if err != nil {
+		log.Fatalf("Failed to parse the message types: %v", err)
+	}
+
+	// The color constants are untyped, so the parser cannot tie them to the type
+	docs.AddEnum(reflect.TypeOf(color.Color("")), color.White, color.Black)
+
+	// Documents are versioned with the protocol
+	version := strconv.Itoa(messages.ProtocolVersion)
+
+	asyncAPI := apidoc.AsyncAPI(apidoc.Info{
+		Title:       "Chess Engine Server WebSocket API",
+		Version:     version,
+		Description: wsDescription,
+		Servers:     servers,
+	}, "/ws", apidoc.NewSchemas(docs), messages.InboundMessage{}, messages.OutboundMessage{}, clientEvents, serverEvents)
+
+	restServers := make([]apidoc.Server, 0, len(servers))
+	for _, server := range servers {
+		scheme := "https://"
+		if server.Name == "local" {
+			scheme = "http://"
+		}
+		server.URL = scheme + server.URL
+		restServers = append(restServers, server)
+	}
+
+	openAPI := apidoc.OpenAPI(apidoc.Info{
+		Title:       "Chess Engine Server API",
+		Version:     version,
+		Description: restDescription,
+		Servers:     restServers,
+		Tags: []apidoc.Tag{
+			{Name: "connection", Description: "Connection management and monitoring"},
+			{Name: "game", Description: "Game session operations"},
+			{Name: "engine", Description: "Chess engine operations"},
+			{Name: "puzzle", Description: "Tactical puzzles found in analyzed games"},
+			{Name: "analysis", Description: "Background engine analysis of games"},
+		},
+	}, apidoc.NewSchemas(docs), routes)
+
+	write(filepath.Join(*out, "asyncapi.json"), asyncAPI)
+	write(filepath.Join(*out, "openapi.json"), openAPI)
+}
+
+// write writes a document as indented JSON
+func write(path string, doc apidoc.Schema) {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to encode %s: %v", path, err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		log.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
@@ -0,0 +1,626 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/tecu23/eng-server/internal/apidoc"
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/engine"
+	"github.com/tecu23/eng-server/pkg/health"
+	"github.com/tecu23/eng-server/pkg/server"
+)
+
+// Schemas of parameters shared by several routes
+var (
+	gameIDParam = apidoc.Param{
+		Name:        "id",
+		In:          "path",
+		Description: "ID of the game",
+		Schema:      apidoc.Schema{"type": "string", "format": "uuid"},
+	}
+	userIDParam = apidoc.Param{
+		Name:        "id",
+		In:          "path",
+		Description: "ID of the user",
+		Schema:      apidoc.Schema{"type": "string", "example": "user-42"},
+	}
+	profileNameParam = apidoc.Param{
+		Name:        "name",
+		In:          "path",
+		Description: "Name of the profile, lowercase letters, digits, - and _",
+		Schema:      apidoc.Schema{"type": "string", "example": "analysis-heavy"},
+	}
+	jobIDParam = apidoc.Param{
+		Name:        "id",
+		In:          "path",
+		Description: "ID of the analysis job",
+		Schema:      apidoc.Schema{"type": "string", "format": "uuid"},
+	}
+	batchIDParam = apidoc.Param{
+		Name:        "id",
+		In:          "path",
+		Description: "ID of the analysis batch",
+		Schema:      apidoc.Schema{"type": "string", "format": "uuid"},
+	}
+	dateTime = apidoc.Schema{"type": "string", "format": "date-time"}
+)
+
+// routes are the REST endpoints served by cmd/server
+var routes = []apidoc.Route{
+	{
+		Method:  http.MethodGet,
+		Path:    "/ws",
+		Summary: "WebSocket Connection Endpoint",
+		Description: "Establishes a WebSocket connection to the chess engine server. All subsequent " +
+			"communication occurs through this connection, its events are described in the AsyncAPI " +
+			"document served at /docs/asyncapi.json.",
+		Tag: "connection",
+		Params: []apidoc.Param{{
+			Name:        "Sec-WebSocket-Protocol",
+			In:          "header",
+			Description: "Requested wire encodings and envelopes, the server prefers eng.v2.msgpack",
+			Schema:      apidoc.Schema{"type": "string", "enum": server.Subprotocols},
+		}},
+		Responses: []apidoc.Response{
+			{Status: http.StatusSwitchingProtocols, Description: "WebSocket connection established"},
+			{Status: http.StatusBadRequest},
+			{Status: http.StatusInternalServerError},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/replay/{id}",
+		Summary: "Replay Game Events",
+		Description: "Replays the event journal of a game over a WebSocket, for frontend testing and bug " +
+			"reproduction against real game traces. With EVENT_JOURNAL_DIR set, every event sent to the clients " +
+			"of a game is journaled, hints included, and replayed here with the original gaps between events " +
+			"divided by speed, in the wire encoding the client negotiated. REPLAY_STARTED opens the replay, " +
+			"REPLAY_FINISHED closes it. A game still being played replays its events so far.",
+		Tag: "connection",
+		Params: []apidoc.Param{
+			gameIDParam,
+			{Name: "speed", In: "query", Description: "Playback speed, 1 by default, 0 sends every event at once", Schema: apidoc.Schema{"type": "number", "minimum": 0, "maximum": 1000, "example": 4}},
+			{
+				Name:        "Sec-WebSocket-Protocol",
+				In:          "header",
+				Description: "Requested wire encodings and envelopes, the server prefers eng.v2.msgpack",
+				Schema:      apidoc.Schema{"type": "string", "enum": server.Subprotocols},
+			},
+		},
+		Responses: []apidoc.Response{
+			{Status: http.StatusSwitchingProtocols, Description: "Replay started"},
+			{Status: http.StatusBadRequest, Description: "Invalid game ID or speed"},
+			{Status: http.StatusNotFound, Description: "No journal for this game, or the event journal is not enabled"},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/uci",
+		Summary: "Raw UCI Proxy",
+		Description: "Opens a WebSocket to an engine leased from the pool for as long as the connection lasts. " +
+			"Text frames carry UCI commands and engine output, one line per frame from the server, so GUIs " +
+			"like Cute Chess can use the server as a remote engine through a WebSocket bridge. uci, isready, " +
+			"setoption, ucinewgame, position, go, stop, ponderhit, debug and quit are accepted. Only Hash, Threads, " +
+			"MultiPV, UCI_Elo and UCI_LimitStrength may be set, the handshake lists no other option, and Hash " +
+			"and Threads are capped by UCI_PROXY_MAX_HASH and UCI_PROXY_MAX_THREADS. The proxy is disabled unless " +
+			"UCI_PROXY_MAX_SESSIONS is set, connections beyond it or finding no idle engine are closed with " +
+			"code 1013, and sessions end after UCI_PROXY_IDLE_TIMEOUT without a command or UCI_PROXY_MAX_DURATION.",
+		Tag: "engine",
+		Responses: []apidoc.Response{
+			{Status: http.StatusSwitchingProtocols, Description: "UCI session established"},
+			{Status: http.StatusBadRequest},
+			{Status: http.StatusNotFound, Description: "The UCI proxy is disabled"},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/health",
+		Summary: "Component Health",
+		Description: "Status of every server component with the build information and uptime, for monitoring. " +
+			"A component is degraded when it works close to a limit: no engine left in the pool or an event " +
+			"subscriber with its queue three quarters full. The server status is the worst component status.",
+		Tag:    "connection",
+		Public: true,
+		Responses: []apidoc.Response{
+			{Status: http.StatusOK, Description: "Every component is up or degraded", Body: health.Report{}},
+			{Status: http.StatusServiceUnavailable, Description: "A component is down", Body: health.Report{}},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/version",
+		Summary: "Server Version",
+		Description: "Build of the running server: the version, commit and build date set with ldflags at build " +
+			"time, falling back on the information embedded by the Go toolchain, and the protocol version. The " +
+			"same object is sent in the CONNECTED message and logged at startup.",
+		Tag:    "connection",
+		Public: true,
+		Responses: []apidoc.Response{
+			{Status: http.StatusOK, Description: "Build information", Body: messages.BuildInfo{}},
+		},
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        "/livez",
+		Summary:     "Liveness Probe",
+		Description: "Answers 200 as long as the process serves requests.",
+		Tag:         "connection",
+		Public:      true,
+		Responses: []apidoc.Response{
+			{Status: http.StatusOK, Description: "The server is alive", Body: messages.ProbeResponse{}},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/readyz",
+		Summary: "Readiness Probe",
+		Description: "Answers 200 once the server can take games: the engine pool is initialized, the repository " +
+			"is reachable and the hub is running. Answers 503 with the failing dependency in reason otherwise, " +
+			"including after shutdown has started.",
+		Tag:    "connection",
+		Public: true,
+		Responses: []apidoc.Response{
+			{Status: http.StatusOK, Description: "The server is ready", Body: messages.ProbeResponse{}},
+			{Status: http.StatusServiceUnavailable, Description: "A dependency is down", Body: messages.ProbeResponse{}},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/tablebase",
+		Summary: "Syzygy Tablebase Probe",
+		Description: "Probes the configured Syzygy tablebases for a position with at most 7 men. The same " +
+			"tablebases adjudicate engine vs engine games once they reach a covered endgame.",
+		Tag: "engine",
+		Params: []apidoc.Param{{
+			Name:        "fen",
+			In:          "query",
+			Description: "Position in FEN notation",
+			Required:    true,
+			Schema:      apidoc.Schema{"type": "string", "example": "8/8/8/8/8/4k3/4P3/4K3 w - - 0 1"},
+		}},
+		Responses: []apidoc.Response{
+			{Status: http.StatusOK, Description: "Tablebase values for the side to move", Body: messages.TablebaseProbeResponse{}},
+			{Status: http.StatusBadRequest, Description: "Invalid FEN"},
+			{Status: http.StatusNotFound, Description: "No tablebase is configured"},
+			{Status: http.StatusUnprocessableEntity, Description: "The position has more than 7 men"},
+		},
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        "/users/{id}/rating",
+		Summary:     "User Rating",
+		Description: "Returns the Glicko-2 rating of a user, updated after each rated game.",
+		Tag:         "game",
+		Params:      []apidoc.Param{userIDParam},
+		Responses: []apidoc.Response{
+			{Status: http.StatusOK, Description: "Current rating of the user", Body: messages.UserRatingResponse{}},
+			{Status: http.StatusNotFound, Description: "The user has not played a rated game"},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/users/{id}/games",
+		Summary: "User Game History",
+		Description: "Lists the finished games of a user, most recent first. Pages are fetched by passing the " +
+			"next_cursor of the previous page back as cursor.",
+		Tag: "game",
+		Params: []apidoc.Param{
+			userIDParam,
+			{Name: "result", In: "query", Description: "Result of the game for the user", Schema: apidoc.Schema{"type": "string", "enum": []string{"win", "loss", "draw"}}},
+			{Name: "color", In: "query", Description: "Color played by the user", Schema: apidoc.Schema{"type": "string", "enum": []string{"w", "b"}}},
+			{Name: "engine", In: "query", Description: "Name of the engine opponent", Schema: apidoc.Schema{"type": "string"}},
+			{Name: "since", In: "query", Description: "Only games ended at or after this time", Schema: dateTime},
+			{Name: "until", In: "query", Description: "Only games ended before this time", Schema: dateTime},
+			{Name: "time_control", In: "query", Description: "Initial time and increment in seconds", Schema: apidoc.Schema{"type": "string", "example": "300+2"}},
+			{Name: "cursor", In: "query", Description: "next_cursor of the previous page", Schema: apidoc.Schema{"type": "string"}},
+			{Name: "limit", In: "query", Description: "Games per page, at most 100", Schema: apidoc.Schema{"type": "integer", "example": 20}},
+		},
+		Responses: []apidoc.Response{
+			{Status: http.StatusOK, Description: "A page of the game history", Body: messages.GamesListPayload{}},
+			{Status: http.StatusBadRequest, Description: "Invalid filter or cursor"},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/explorer",
+		Summary: "Opening Explorer",
+		Description: "Statistics of a position over the finished standard games that started from the initial " +
+			"position: how many games reached it, how they ended and every move played from it, most played " +
+			"first. The position is the FEN, or the start position, followed by the moves. The first 40 plies of " +
+			"every game are indexed, transpositions share their statistics.",
+		Tag: "game",
+		Params: []apidoc.Param{
+			{Name: "fen", In: "query", Description: "Position to look up, the start position when omitted", Schema: apidoc.Schema{"type": "string"}},
+			{Name: "moves", In: "query", Description: "Comma separated moves in UCI notation played from the position", Schema: apidoc.Schema{"type": "string", "example": "e2e4,c7c5"}},
+		},
+		Responses: []apidoc.Response{
+			{Status: http.StatusOK, Description: "The position and the moves played from it", Body: messages.ExplorerPayload{}},
+			{Status: http.StatusBadRequest, Description: "Invalid position or move"},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/puzzles",
+		Summary: "Puzzles",
+		Description: "Lists puzzles, most recent first. Puzzles are found while analyzing finished games: the " +
+			"position after a blunder is a puzzle when the opponent has a single winning move, checked with a " +
+			"MultiPV search. The solution follows the engine line for as long as every move of the solver stays " +
+			"the only winning one. The rating is estimated from the length of the solution, whether the first " +
+			"move is quiet and how often the engine changed its mind before finding it.",
+		Tag: "puzzle",
+		Params: []apidoc.Param{
+			{Name: "min_rating", In: "query", Description: "Lowest estimated rating", Schema: apidoc.Schema{"type": "integer", "example": 1200}},
+			{Name: "max_rating", In: "query", Description: "Highest estimated rating", Schema: apidoc.Schema{"type": "integer", "example": 1800}},
+			{Name: "difficulty", In: "query", Description: "Difficulty of the puzzle", Schema: apidoc.Schema{"type": "string", "enum": []string{"easy", "medium", "hard"}}},
+			{Name: "mate", In: "query", Description: "Only puzzles whose solution forces mate", Schema: apidoc.Schema{"type": "boolean"}},
+			{Name: "limit", In: "query", Description: "Puzzles to return, at most 100", Schema: apidoc.Schema{"type": "integer", "example": 20}},
+		},
+		Responses: []apidoc.Response{
+			{Status: http.StatusOK, Description: "The matching puzzles", Body: messages.PuzzlesListPayload{}},
+			{Status: http.StatusBadRequest, Description: "Invalid filter"},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/puzzles/{id}",
+		Summary: "Puzzle",
+		Tag:     "puzzle",
+		Params: []apidoc.Param{{
+			Name:        "id",
+			In:          "path",
+			Description: "ID of the puzzle",
+			Schema:      apidoc.Schema{"type": "string", "format": "uuid"},
+		}},
+		Responses: []apidoc.Response{
+			{Status: http.StatusOK, Description: "The puzzle", Body: messages.Puzzle{}},
+			{Status: http.StatusNotFound, Description: "No puzzle with this ID"},
+		},
+	},
+	{
+		Method:  http.MethodPost,
+		Path:    "/games/{id}/analysis",
+		Summary: "Analyze Game",
+		Description: "Queues the engine analysis of a standard chess game. A game still being played is analyzed " +
+			"up to its current position ahead of every other job, a finished game after the live ones and " +
+			"before bulk imports. The report is published as ANALYSIS_READY once the job completed.",
+		Tag:    "analysis",
+		Params: []apidoc.Param{gameIDParam},
+		Responses: []apidoc.Response{
+			{Status: http.StatusAccepted, Description: "The queued job", Body: messages.AnalysisJob{}},
+			{Status: http.StatusNotFound, Description: "No game with this ID"},
+			{Status: http.StatusUnprocessableEntity, Description: "The game is not a standard chess game"},
+			{Status: http.StatusServiceUnavailable, Description: "Too many jobs are waiting"},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/analysis/jobs/{id}",
+		Summary: "Analysis Job",
+		Tag:     "analysis",
+		Params:  []apidoc.Param{jobIDParam},
+		Responses: []apidoc.Response{
+			{Status: http.StatusOK, Description: "The job and its place in the queue", Body: messages.AnalysisJob{}},
+			{Status: http.StatusNotFound, Description: "No job with this ID, finished jobs are forgotten after a while"},
+		},
+	},
+	{
+		Method:      http.MethodDelete,
+		Path:        "/analysis/jobs/{id}",
+		Summary:     "Cancel Analysis Job",
+		Description: "Drops a waiting job or stops a running one between two positions.",
+		Tag:         "analysis",
+		Params:      []apidoc.Param{jobIDParam},
+		Responses: []apidoc.Response{
+			{Status: http.StatusOK, Description: "The canceled job", Body: messages.AnalysisJob{}},
+			{Status: http.StatusNotFound, Description: "No job with this ID"},
+			{Status: http.StatusConflict, Description: "The job is already finished"},
+		},
+	},
+	{
+		Method:  http.MethodPost,
+		Path:    "/analysis/batch",
+		Summary: "Analyze PGN File",
+		Description: "Queues the analysis of every game of a PGN file, up to 200 games and 4 MiB, behind the games " +
+			"played on the server. The file is rejected as a whole when a game can not be read. Progress is " +
+			"followed with GET /analysis/batch/{id} and the annotated games downloaded once every job finished.",
+		Tag:      "analysis",
+		Body:     "",
+		BodyType: "application/x-chess-pgn",
+		Responses: []apidoc.Response{
+			{Status: http.StatusAccepted, Description: "The queued batch", Body: messages.AnalysisBatch{}},
+			{Status: http.StatusRequestEntityTooLarge, Description: "The file is larger than 4 MiB"},
+			{Status: http.StatusUnprocessableEntity, Description: "A game can not be read or is not standard chess"},
+			{Status: http.StatusServiceUnavailable, Description: "Too many jobs are waiting"},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/analysis/batch/{id}",
+		Summary: "Analysis Batch",
+		Tag:     "analysis",
+		Params:  []apidoc.Param{batchIDParam},
+		Responses: []apidoc.Response{
+			{Status: http.StatusOK, Description: "The progress of the batch and its jobs in the order of the file", Body: messages.AnalysisBatch{}},
+			{Status: http.StatusNotFound, Description: "No batch with this ID, batches are forgotten a day after they were queued"},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/analysis/batch/{id}/pgn",
+		Summary: "Annotated Batch PGN",
+		Description: "Exports the games of a finished batch with the evaluation of every move as a [%eval] comment, " +
+			"inaccuracies, mistakes and blunders marked ?!, ? and ?? with the move the engine preferred. Games " +
+			"whose analysis failed or was canceled are exported without annotations.",
+		Tag:    "analysis",
+		Params: []apidoc.Param{batchIDParam},
+		Responses: []apidoc.Response{
+			{Status: http.StatusOK, Description: "The annotated games", Body: "", ContentType: "application/x-chess-pgn"},
+			{Status: http.StatusNotFound, Description: "No batch with this ID"},
+			{Status: http.StatusConflict, Description: "Some games are still waiting or being analyzed"},
+		},
+	},
+	{
+		Method:      http.MethodDelete,
+		Path:        "/analysis/batch/{id}",
+		Summary:     "Cancel Analysis Batch",
+		Description: "Cancels the games of the batch not analyzed yet, the finished ones stay available for export.",
+		Tag:         "analysis",
+		Params:      []apidoc.Param{batchIDParam},
+		Responses: []apidoc.Response{
+			{Status: http.StatusOK, Description: "The canceled batch", Body: messages.AnalysisBatch{}},
+			{Status: http.StatusNotFound, Description: "No batch with this ID"},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/admin/analysis/jobs",
+		Summary: "Analysis Jobs",
+		Description: "Lists the analysis jobs, the waiting ones in the order they will run then the others from " +
+			"the newest. At most ANALYSIS_WORKERS jobs run at once, a quarter of the engine pool by default.",
+		Tag: "analysis",
+		Params: []apidoc.Param{
+			{Name: "status", In: "query", Description: "Only jobs with this status", Schema: apidoc.Schema{"type": "string", "enum": []string{"queued", "running", "completed", "failed", "canceled"}}},
+		},
+		Responses: []apidoc.Response{
+			{Status: http.StatusOK, Description: "The jobs", Body: messages.AnalysisJobsPayload{}},
+			{Status: http.StatusBadRequest, Description: "Invalid status"},
+			{Status: http.StatusForbidden, Description: "Not an admin key"},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/admin/fairplay",
+		Summary: "Fair Play Reports",
+		Description: "Lists the fair play reports for moderators. Every human player of a finished rated standard game " +
+			"is screened through the analysis queue: the player of a game against the engine, and both players " +
+			"of a game between players, each with a report of their own from a single analysis of the game. " +
+			"Reviews nobody asked a report for wait behind the requested reports. A report gives the share of the " +
+			"player's moves that were the engine choice, their average centipawn loss and the distribution of their " +
+			"move times, the first 16 plies left out. A game of at least 15 screened moves is flagged when at least " +
+			"90% of the moves matched the engine along with another sign: an average loss of 8 centipawns or less, " +
+			"or move times varying less than 35% around a mean of a second or more. A flag asks for a human look, " +
+			"it is no proof.",
+		Tag: "analysis",
+		Params: []apidoc.Param{
+			{Name: "flagged", In: "query", Description: "Only the reports of flagged games", Schema: apidoc.Schema{"type": "boolean"}},
+			{Name: "user_id", In: "query", Description: "Only the games of this user", Schema: apidoc.Schema{"type": "string"}},
+			{Name: "game_id", In: "query", Description: "Only the reports of this game", Schema: apidoc.Schema{"type": "string", "format": "uuid"}},
+			{Name: "limit", In: "query", Description: "Reports to return, at most 100", Schema: apidoc.Schema{"type": "integer", "example": 20}},
+		},
+		Responses: []apidoc.Response{
+			{Status: http.StatusOK, Description: "The reports, the most recent first", Body: messages.FairPlayReportsPayload{}},
+			{Status: http.StatusBadRequest, Description: "Invalid limit"},
+			{Status: http.StatusForbidden, Description: "Not an admin key"},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/engines",
+		Summary: "Pooled Engines",
+		Description: "Lists the engines of the pool with the name, author and options they reported in their UCI " +
+			"handshake, and whether each is idle or leased. Options set on an engine are checked against this list.",
+		Tag: "engine",
+		Responses: []apidoc.Response{
+			{Status: http.StatusOK, Description: "The pooled engines", Body: messages.EnginesResponse{}},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/engines/stats",
+		Summary: "Engine Leaderboard",
+		Description: "Ranks every engine configuration that finished a game by its score per game, a win counting 1 " +
+			"and a draw 0.5, then by games played. Each entry sums the results, the average depth and search time " +
+			"of the moves the engine searched, and how often it crashed or missed its deadline, so engine builds " +
+			"deployed over time can be compared. Engines are told apart by name and options, human players are not " +
+			"ranked. With ENGINE_STATS_PATH set, the totals are saved after every finished game and survive restarts.",
+		Tag: "engine",
+		Responses: []apidoc.Response{
+			{Status: http.StatusOK, Description: "The engine configurations, the best first", Body: messages.EngineLeaderboardPayload{}},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/admin/engines/stats",
+		Summary: "Engine Search Statistics",
+		Description: "Aggregates the search telemetry recorded for every engine move: depth reached, nodes, time " +
+			"used, eval and whether the best move changed in the last quarter of the search. Moves are grouped by " +
+			"engine name and the options set on the engine, so configurations can be compared over time by " +
+			"narrowing the time range. Book and fallback moves are not recorded.",
+		Tag: "engine",
+		Params: []apidoc.Param{
+			{Name: "engine", In: "query", Description: "Name of the engine", Schema: apidoc.Schema{"type": "string", "example": "Stockfish 17"}},
+			{Name: "since", In: "query", Description: "Only moves played at or after this time", Schema: dateTime},
+			{Name: "until", In: "query", Description: "Only moves played before this time", Schema: dateTime},
+		},
+		Responses: []apidoc.Response{
+			{Status: http.StatusOK, Description: "Statistics per engine configuration", Body: messages.EngineStatsPayload{}},
+			{Status: http.StatusBadRequest, Description: "Invalid time filter"},
+			{Status: http.StatusForbidden, Description: "Not an admin key"},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/admin/games/{id}/transcript",
+		Summary: "Engine Transcript",
+		Description: "Returns the UCI dialogue of the engines of a game: every command the server sent and every " +
+			"line the engines wrote, info lines included, to answer why an engine played a move or to reproduce a " +
+			"bug. The latest 20000 lines of a game are kept, transcripts of the last 200 finished games are kept " +
+			"in memory. Simul boards only record the shared engine while it searches for them.",
+		Tag: "engine",
+		Params: []apidoc.Param{
+			gameIDParam,
+			{Name: "format", In: "query", Description: "json, or text for one line per message with > marking commands sent", Schema: apidoc.Schema{"type": "string", "enum": []string{"json", "text"}}},
+		},
+		Responses: []apidoc.Response{
+			{Status: http.StatusOK, Description: "The transcript", Body: messages.EngineTranscript{}},
+			{Status: http.StatusBadRequest, Description: "Invalid game ID or format"},
+			{Status: http.StatusForbidden, Description: "Not an admin key"},
+			{Status: http.StatusNotFound, Description: "No running game or kept transcript with this ID"},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/admin/engines/allocation",
+		Summary: "Engine Resource Allocation",
+		Description: "Shows the Threads and Hash set on every pooled engine. They are computed when the pool " +
+			"starts so the pool at its maximum size does not oversubscribe the host: the logical CPUs are split " +
+			"between the engines, and half the memory, or the memory of the cgroup of the server when lower, " +
+			"goes to their hash tables, within ENGINE_CPU_QUOTA and ENGINE_MEMORY_LIMIT_MB. ENGINE_THREADS and " +
+			"ENGINE_HASH_MB override the computed values.",
+		Tag: "engine",
+		Responses: []apidoc.Response{
+			{Status: http.StatusOK, Description: "Current allocation", Body: engine.Allocation{}},
+			{Status: http.StatusForbidden, Description: "Not an admin key"},
+		},
+	},
+	{
+		Method:  http.MethodPut,
+		Path:    "/admin/engines/allocation",
+		Summary: "Override Engine Resource Allocation",
+		Description: "Overrides the computed Threads and Hash of the pooled engines, a zero field goes back to " +
+			"the computed value. Engines pick up the new values on their next lease, within the bounds they " +
+			"reported. Requires an admin key.",
+		Tag:  "engine",
+		Body: engine.AllocationOverride{},
+		Responses: []apidoc.Response{
+			{Status: http.StatusOK, Description: "New allocation", Body: engine.Allocation{}},
+			{Status: http.StatusBadRequest, Description: "Invalid override"},
+			{Status: http.StatusForbidden, Description: "Not an admin key"},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/engine-profiles",
+		Summary: "List Engine Profiles",
+		Description: "Lists the named engine option profiles that CREATE_SESSION and START_ANALYSIS accept as " +
+			"engine_profile, loaded from ENGINE_PROFILES_PATH at startup or defined by admins.",
+		Tag: "engine",
+		Responses: []apidoc.Response{
+			{Status: http.StatusOK, Description: "Profiles ordered by name", Body: messages.EngineProfilesResponse{}},
+		},
+	},
+	{
+		Method:  http.MethodPut,
+		Path:    "/admin/engine-profiles/{name}",
+		Summary: "Save Engine Profile",
+		Description: "Defines an engine option profile or replaces its options. The options are checked against " +
+			"the options the pooled engines reported. Requires an admin key.",
+		Tag:    "engine",
+		Params: []apidoc.Param{profileNameParam},
+		Body:   messages.EngineProfileRequest{},
+		Responses: []apidoc.Response{
+			{Status: http.StatusOK, Description: "Profile saved", Body: engine.Profile{}},
+			{Status: http.StatusBadRequest, Description: "Invalid name or options"},
+			{Status: http.StatusForbidden, Description: "Not an admin key"},
+		},
+	},
+	{
+		Method:      http.MethodDelete,
+		Path:        "/admin/engine-profiles/{name}",
+		Summary:     "Delete Engine Profile",
+		Description: "Removes an engine option profile, games already started with it keep their options. Requires an admin key.",
+		Tag:         "engine",
+		Params:      []apidoc.Param{profileNameParam},
+		Responses: []apidoc.Response{
+			{Status: http.StatusNoContent, Description: "Profile deleted"},
+			{Status: http.StatusForbidden, Description: "Not an admin key"},
+			{Status: http.StatusNotFound, Description: "Profile not found"},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/games/{id}/stream",
+		Summary: "Game Event Stream",
+		Description: "Server-Sent Events fallback for clients that cannot use WebSockets. Streams the same " +
+			"outbound events the WebSocket connections watching the game receive, starting with GAME_STATE. The " +
+			"event name is the message event and the data is its payload as JSON. A comment line is sent every " +
+			"15 seconds to keep the stream open. The stream ends with the session.",
+		Tag:    "game",
+		Params: []apidoc.Param{gameIDParam},
+		Responses: []apidoc.Response{
+			{Status: http.StatusOK, Description: "Event stream of the game", Body: "", ContentType: "text/event-stream"},
+			{Status: http.StatusNotFound, Description: "Game not found"},
+		},
+	},
+	{
+		Method:  http.MethodPost,
+		Path:    "/games/{id}/moves",
+		Summary: "Make Move",
+		Description: "Plays a move of the player, like MAKE_MOVE, and starts the engine reply. The resulting " +
+			"events are delivered on the event stream of the game. Only the key of the player may move.",
+		Tag:    "game",
+		Params: []apidoc.Param{gameIDParam},
+		Body:   messages.MoveRequest{},
+		Responses: []apidoc.Response{
+			{Status: http.StatusAccepted, Description: "Move played, the engine is thinking"},
+			{Status: http.StatusBadRequest, Description: "Invalid request body"},
+			{Status: http.StatusForbidden, Description: "The key is not the one of the player"},
+			{Status: http.StatusNotFound, Description: "Game not found"},
+			{Status: http.StatusConflict, Description: "The engine reply could not be started"},
+			{Status: http.StatusUnprocessableEntity, Description: "The move is not legal or not the player's turn"},
+		},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        "/games/{id}/resign",
+		Summary:     "Resign Game",
+		Description: "Resigns the game for the player, like RESIGN. Only the key of the player may resign.",
+		Tag:         "game",
+		Params:      []apidoc.Param{gameIDParam},
+		Responses: []apidoc.Response{
+			{Status: http.StatusNoContent, Description: "Game resigned"},
+			{Status: http.StatusForbidden, Description: "The key is not the one of the player"},
+			{Status: http.StatusNotFound, Description: "Game not found"},
+			{Status: http.StatusConflict, Description: "The game is already over"},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/results/key",
+		Summary: "Result Signing Key",
+		Description: "Returns the key GAME_OVER certificates are signed with. With RESULT_SIGNING_KEY_PATH set to a " +
+			"PEM Ed25519 private key, results are signed with it and the public key is returned so any system can " +
+			"verify them. With RESULT_HMAC_KEY set instead, results carry an HMAC-SHA256 the server alone can " +
+			"check, through POST /results/verify. A signature covers, one per line: the text eng-server result v1, " +
+			"then game_id, result, reason, final_fen, moves_hash, white_time, black_time, ended_at, algorithm and key_id.",
+		Tag: "game",
+		Responses: []apidoc.Response{
+			{Status: http.StatusOK, Description: "The signing key", Body: messages.ResultKeyResponse{}},
+			{Status: http.StatusNotFound, Description: "Results are not signed on this server"},
+		},
+	},
+	{
+		Method:  http.MethodPost,
+		Path:    "/results/verify",
+		Summary: "Verify Result",
+		Description: "Checks that the certificate of a GAME_OVER was signed with the server key and not altered " +
+			"since. Certificates signed with a previous key are not valid.",
+		Tag:  "game",
+		Body: messages.ResultCertificate{},
+		Responses: []apidoc.Response{
+			{Status: http.StatusOK, Description: "Whether the certificate is valid", Body: messages.ResultVerificationResponse{}},
+			{Status: http.StatusBadRequest, Description: "Invalid request body"},
+			{Status: http.StatusNotFound, Description: "Results are not signed on this server"},
+		},
+	},
+}
@@ -0,0 +1,144 @@
+// Command bench runs a suite of positions through an engine at a fixed depth
+// or move time and reports the nodes, speed and best moves. Reports are saved
+// as JSON, and a run compared with a baseline report shows how often the best
+// moves agree, so engine upgrades and option changes can be measured:
+//
+//	bench -engine ./stockfish-17 -depth 14 -out sf17.json
+//	bench -engine ./stockfish-18 -depth 14 -baseline sf17.json
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/pkg/bench"
+	"github.com/tecu23/eng-server/pkg/engine"
+)
+
+// defaultDepth is the search depth when neither -depth nor -movetime is set
+const defaultDepth = 12
+
+func main() {
+	enginePath := flag.String("engine", os.Getenv("ENGINE_PATH"), "path of the engine, defaults to ENGINE_PATH")
+	depth := flag.Int("depth", 0, "search depth of every position")
+	moveTime := flag.Int64("movetime", 0, "search time of every position in milliseconds")
+	suitePath := flag.String("suite", "", "file with one FEN per line, the built-in suite when empty")
+	out := flag.String("out", "", "where to save the report, bench-<time>.json when empty")
+	baselinePath := flag.String("baseline", "", "report of an earlier run to compare with")
+
+	options := make(map[string]string)
+	flag.Func("option", "engine option as name=value, may be repeated", func(v string) error {
+		name, value, ok := strings.Cut(v, "=")
+		if !ok || name == "" {
+			return errors.New("option must be name=value")
+		}
+		options[name] = value
+		return nil
+	})
+
+	flag.Parse()
+
+	if *enginePath == "" {
+		log.Fatal("no engine, set -engine or ENGINE_PATH")
+	}
+
+	limit := bench.Limit{Depth: *depth, MoveTime: *moveTime}
+	if limit.Depth == 0 && limit.MoveTime == 0 {
+		limit.Depth = defaultDepth
+	}
+
+	suite := bench.DefaultSuite
+	if *suitePath != "" {
+		var err error
+		if suite, err = bench.LoadSuite(*suitePath); err != nil {
+			log.Fatalf("could not load suite: %v", err)
+		}
+	}
+
+	var baseline *bench.Report
+	if *baselinePath != "" {
+		r, err := bench.Load(*baselinePath)
+		if err != nil {
+			log.Fatalf("could not load baseline: %v", err)
+		}
+		baseline = &r
+	}
+
+	eng, err := engine.NewUCIEngine(*enginePath, engine.Limits{}, zap.NewNop())
+	if err != nil {
+		log.Fatalf("could not start engine: %v", err)
+	}
+	defer eng.Close()
+
+	if err := eng.SetOptions(options); err != nil {
+		log.Fatalf("could not set options: %v", err)
+	}
+
+	report, err := bench.Run(context.Background(), eng, suite, limit)
+	if err != nil {
+		log.Fatalf("bench failed: %v", err)
+	}
+
+	printReport(report)
+
+	path := *out
+	if path == "" {
+		path = fmt.Sprintf("bench-%s.json", report.StartedAt.Format("20060102-150405"))
+	}
+	if err := report.Save(path); err != nil {
+		log.Fatalf("could not save report: %v", err)
+	}
+	fmt.Printf("\nReport saved to %s\n", path)
+
+	if baseline != nil {
+		printComparison(report.Compare(*baseline), *baseline)
+	}
+}
+
+// printReport writes the result of every position and the totals
+func printReport(r bench.Report) {
+	fmt.Printf("%s, %s\n\n", r.Engine, limitName(r.Limit))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "#\tbest\tdepth\tnodes\ttime ms\tnps\t")
+	for i, result := range r.Results {
+		fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%d\t%d\t\n", i+1, result.BestMove, result.Depth, result.Nodes, result.TimeMs, result.NPS)
+	}
+	fmt.Fprintf(w, "total\t\t\t%d\t%d\t%d\t\n", r.Nodes, r.TimeMs, r.NPS)
+	_ = w.Flush()
+}
+
+// printComparison writes how the run differs from the baseline
+func printComparison(c bench.Comparison, baseline bench.Report) {
+	fmt.Printf("\nCompared with %s, %s (%s)\n", baseline.Engine, limitName(baseline.Limit), baseline.StartedAt.Format(time.RFC3339))
+
+	if c.Positions == 0 {
+		fmt.Println("No position in common")
+		return
+	}
+
+	fmt.Printf("Best move agreement: %d/%d (%.0f%%)\n", c.Agreement, c.Positions, 100*float64(c.Agreement)/float64(c.Positions))
+	fmt.Printf("Nodes: %+.1f%%\n", 100*c.NodesDiff)
+	fmt.Printf("Speed: %+.1f%%\n", 100*c.NPSDiff)
+
+	for _, d := range c.Differing {
+		fmt.Printf("  %s  %s -> %s\n", d.FEN, d.Baseline, d.Current)
+	}
+}
+
+// limitName describes a search limit
+func limitName(l bench.Limit) string {
+	if l.Depth > 0 {
+		return fmt.Sprintf("depth %d", l.Depth)
+	}
+	return fmt.Sprintf("%d ms per position", l.MoveTime)
+}
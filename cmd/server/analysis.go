@@ -0,0 +1,156 @@
+// Package main is the entry point of the application
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/tecu23/eng-server/pkg/analysis"
+	"github.com/tecu23/eng-server/pkg/repository"
+)
+
+// maxBatchBytes limits the size of an uploaded PGN file
+const maxBatchBytes = 4 << 20
+
+// handleAnalyzeGame handles the POST /games/{id}/analysis endpoint
+func (app *application) handleAnalyzeGame(w http.ResponseWriter, r *http.Request) {
+	gameID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid game ID", http.StatusBadRequest)
+		return
+	}
+
+	job, err := app.Manager.AnalyzeGame(gameID)
+	switch {
+	case errors.Is(err, repository.ErrGameNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, analysis.ErrQueueFull):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(job)
+}
+
+// handleAnalysisJob handles the GET /analysis/jobs/{id} endpoint
+func (app *application) handleAnalysisJob(w http.ResponseWriter, r *http.Request) {
+	job, err := app.Manager.AnalysisJob(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(job)
+}
+
+// handleCancelAnalysisJob handles the DELETE /analysis/jobs/{id} endpoint
+func (app *application) handleCancelAnalysisJob(w http.ResponseWriter, r *http.Request) {
+	job, err := app.Manager.CancelAnalysisJob(r.PathValue("id"))
+	switch {
+	case errors.Is(err, analysis.ErrJobNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(job)
+}
+
+// handleAnalysisJobs handles the GET /admin/analysis/jobs endpoint
+func (app *application) handleAnalysisJobs(w http.ResponseWriter, r *http.Request) {
+	jobs, err := app.Manager.AnalysisJobs(r.URL.Query().Get("status"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(jobs)
+}
+
+// handleAnalyzeBatch handles the POST /analysis/batch endpoint
+func (app *application) handleAnalyzeBatch(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBatchBytes)
+
+	batch, err := app.Manager.AnalyzeBatch(r.Body)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		http.Error(w, "PGN file too large", http.StatusRequestEntityTooLarge)
+		return
+	case errors.Is(err, analysis.ErrQueueFull):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(batch)
+}
+
+// handleAnalysisBatch handles the GET /analysis/batch/{id} endpoint
+func (app *application) handleAnalysisBatch(w http.ResponseWriter, r *http.Request) {
+	batch, err := app.Manager.AnalysisBatch(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(batch)
+}
+
+// handleAnalysisBatchPGN handles the GET /analysis/batch/{id}/pgn endpoint
+func (app *application) handleAnalysisBatchPGN(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	pgn, err := app.Manager.AnalysisBatchPGN(id)
+	switch {
+	case errors.Is(err, analysis.ErrBatchNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, analysis.ErrBatchRunning):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-chess-pgn")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", id+".pgn"))
+	_, _ = w.Write([]byte(pgn))
+}
+
+// handleCancelAnalysisBatch handles the DELETE /analysis/batch/{id} endpoint
+func (app *application) handleCancelAnalysisBatch(w http.ResponseWriter, r *http.Request) {
+	batch, err := app.Manager.CancelAnalysisBatch(r.PathValue("id"))
+	switch {
+	case errors.Is(err, analysis.ErrBatchNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(batch)
+}
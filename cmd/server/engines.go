@@ -0,0 +1,102 @@
+// Package main is the entry point of the application
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/engine"
+)
+
+// handleEngines handles the GET /engines endpoint
+func (app *application) handleEngines(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(messages.EnginesResponse{Engines: app.Manager.Engines()})
+}
+
+// handleEngineStats handles the GET /admin/engines/stats endpoint
+func (app *application) handleEngineStats(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	stats, err := app.Manager.EngineStats(q.Get("engine"), q.Get("since"), q.Get("until"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(stats)
+}
+
+// handleEngineLeaderboard handles the GET /engines/stats endpoint
+func (app *application) handleEngineLeaderboard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(app.Manager.EngineLeaderboard())
+}
+
+// handleEngineTranscript handles the GET /admin/games/{id}/transcript endpoint
+func (app *application) handleEngineTranscript(w http.ResponseWriter, r *http.Request) {
+	gameID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid game ID", http.StatusBadRequest)
+		return
+	}
+
+	transcript, err := app.Manager.EngineTranscript(gameID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(transcript)
+	case "text":
+		// One line per command or output line, arrows pointing from the sender
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, l := range transcript.Lines {
+			arrow := "<"
+			if l.Direction == engine.TranscriptSent {
+				arrow = ">"
+			}
+			fmt.Fprintf(w, "%s %s %s %s\n", l.Time.Format(time.RFC3339Nano), l.EngineID, arrow, l.Line)
+		}
+	default:
+		http.Error(w, "format must be json or text", http.StatusBadRequest)
+	}
+}
+
+// handleEngineAllocation handles the GET /admin/engines/allocation endpoint
+func (app *application) handleEngineAllocation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(app.Manager.EngineAllocation())
+}
+
+// handlePutEngineAllocation handles the PUT /admin/engines/allocation endpoint
+func (app *application) handlePutEngineAllocation(w http.ResponseWriter, r *http.Request) {
+	var override engine.AllocationOverride
+	if err := json.NewDecoder(r.Body).Decode(&override); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	alloc, err := app.Manager.SetEngineAllocation(override)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	app.Logger.Info("Engine allocation overridden",
+		zap.Int("threads", alloc.Threads),
+		zap.Int64("hash_mb", alloc.HashMB))
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(alloc)
+}
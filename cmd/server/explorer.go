@@ -0,0 +1,27 @@
+// Package main is the entry point of the application
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// handleExplorer handles the GET /explorer endpoint
+func (app *application) handleExplorer(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	var moves []string
+	if v := q.Get("moves"); v != "" {
+		moves = strings.Split(v, ",")
+	}
+
+	position, err := app.Manager.Explorer(q.Get("fen"), moves)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(position)
+}
@@ -0,0 +1,33 @@
+// Package main is the entry point of the application
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/tecu23/eng-server/pkg/repository"
+)
+
+// handleFairPlayReports handles the GET /admin/fairplay endpoint
+func (app *application) handleFairPlayReports(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	limit := 0
+	if v := q.Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	filter := repository.FairPlayFilter{
+		UserID:  q.Get("user_id"),
+		GameID:  q.Get("game_id"),
+		Flagged: q.Get("flagged") == "true",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(app.Manager.FairPlayReports(filter, limit))
+}
@@ -0,0 +1,427 @@
+// Package main is the entry point of the application
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	engv1 "github.com/tecu23/eng-server/api/proto/eng/v1"
+	"github.com/tecu23/eng-server/internal/auth"
+	"github.com/tecu23/eng-server/internal/color"
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/engine"
+	"github.com/tecu23/eng-server/pkg/game"
+	"github.com/tecu23/eng-server/pkg/repository"
+	"github.com/tecu23/eng-server/pkg/server"
+)
+
+// grpcPermissions is the permission each gRPC method requires
+var grpcPermissions = map[string]auth.Permission{
+	engv1.EngineService_CreateGame_FullMethodName:   auth.PermPlay,
+	engv1.EngineService_MakeMove_FullMethodName:     auth.PermPlay,
+	engv1.EngineService_StreamEvents_FullMethodName: auth.PermRead,
+	engv1.EngineService_Analyze_FullMethodName:      auth.PermAnalyze,
+}
+
+// grpcKey is the context key of the API key a gRPC call authenticated with
+type grpcKey struct{}
+
+// grpcService implements the gRPC API for backend services and bots, which
+// mirrors the WebSocket protocol
+type grpcService struct {
+	engv1.UnimplementedEngineServiceServer
+
+	app *application
+
+	// Parent of the games created over gRPC, which outlive the call creating
+	// them. Cancelled when the server shuts down
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// grpcListenerFromEnv opens the listener of the gRPC API on GRPC_ADDR, nil
+// when it is not set and the API is disabled
+func grpcListenerFromEnv() (net.Listener, error) {
+	addr := os.Getenv("GRPC_ADDR")
+	if addr == "" {
+		return nil, nil
+	}
+	return net.Listen("tcp", addr)
+}
+
+// newGRPCServer creates the gRPC server, authenticating every call like the
+// REST routes. It terminates TLS with the certificate of the HTTP server
+func (app *application) newGRPCServer() (*grpc.Server, *grpcService, error) {
+	opts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(app.grpcAuthorizeUnary),
+		grpc.StreamInterceptor(app.grpcAuthorizeStream),
+	}
+
+	if app.TLS != nil {
+		config, err := app.TLS.grpcConfig()
+		if err != nil {
+			return nil, nil, err
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(config)))
+	}
+
+	svc := &grpcService{app: app}
+	svc.ctx, svc.cancel = context.WithCancel(context.Background())
+
+	srv := grpc.NewServer(opts...)
+	engv1.RegisterEngineServiceServer(srv, svc)
+
+	return srv, svc, nil
+}
+
+// grpcConfig returns the TLS configuration of the gRPC server, which needs
+// the certificate loaded as it does not go through ServeTLS
+func (t *tlsSettings) grpcConfig() (*tls.Config, error) {
+	config := t.config()
+	if t.autocert != nil {
+		return config, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(t.certFile, t.keyFile)
+	if err != nil {
+		return nil, err
+	}
+	config.Certificates = []tls.Certificate{cert}
+	config.NextProtos = []string{"h2"}
+	return config, nil
+}
+
+// grpcAPIKey returns the API key of a call, sent in the x-api-key metadata or
+// stood for by a session token in the authorization metadata
+func (app *application) grpcAPIKey(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if keys := md.Get("x-api-key"); len(keys) > 0 && keys[0] != "" {
+		return keys[0]
+	}
+
+	for _, value := range md.Get("authorization") {
+		if token, ok := strings.CutPrefix(value, "Bearer "); ok {
+			key, _ := app.Sessions.Key(token)
+			return key
+		}
+	}
+	return ""
+}
+
+// grpcAuthorize checks the key of a call grants the permission of its method,
+// returning the context carrying the key
+func (app *application) grpcAuthorize(ctx context.Context, method string) (context.Context, error) {
+	key := app.grpcAPIKey(ctx)
+	if !app.Auth.IsValidKey(key) {
+		app.Logger.Warn("gRPC authentication failed", zap.String("method", method))
+		return nil, status.Error(codes.Unauthenticated, "invalid API key")
+	}
+
+	perm, ok := grpcPermissions[method]
+	if !ok || !app.Auth.Can(key, perm) {
+		app.Logger.Warn("gRPC access denied", zap.String("method", method), zap.String("permission", string(perm)))
+		return nil, status.Errorf(codes.PermissionDenied, "%s permission required", perm)
+	}
+
+	return context.WithValue(ctx, grpcKey{}, key), nil
+}
+
+func (app *application) grpcAuthorizeUnary(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	ctx, err := app.grpcAuthorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (app *application) grpcAuthorizeStream(
+	srv any,
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	ctx, err := app.grpcAuthorize(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authorizedStream{ServerStream: ss, ctx: ctx})
+}
+
+// authorizedStream is a server stream whose context carries the API key
+type authorizedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authorizedStream) Context() context.Context {
+	return s.ctx
+}
+
+// callUser returns the user the key of a call authenticates
+func (svc *grpcService) callUser(ctx context.Context) string {
+	key, _ := ctx.Value(grpcKey{}).(string)
+	user, _ := svc.app.Auth.User(key)
+	return user
+}
+
+// grpcError converts an error of a game or the manager to a gRPC status
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, game.ErrIllegalMove):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, game.ErrNotYourTurn), errors.Is(err, game.ErrGameOver), errors.Is(err, game.ErrGameTerminated):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, engine.ErrNoEngineAvailable):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, repository.ErrGameNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	default:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+}
+
+// lookupGame resolves the game of a call
+func (svc *grpcService) lookupGame(gameID string) (*game.Game, error) {
+	id, err := uuid.Parse(gameID)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid game id")
+	}
+
+	session, ok := svc.app.Manager.GetSession(id)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "game %s not found", gameID)
+	}
+	return session, nil
+}
+
+// CreateGame starts a human vs engine game, like CREATE_SESSION. The game is
+// played by the user of the API key and ends once over or idle
+func (svc *grpcService) CreateGame(ctx context.Context, req *engv1.CreateGameRequest) (*engv1.CreateGameResponse, error) {
+	user := svc.callUser(ctx)
+	if req.UserId != "" && req.UserId != user {
+		return nil, status.Error(codes.PermissionDenied, "user_id is not the user of the API key")
+	}
+
+	clr := color.Color(req.Color)
+	switch clr {
+	case "":
+		clr = color.White
+	case color.White, color.Black:
+	default:
+		return nil, status.Error(codes.InvalidArgument, "color must be w or b")
+	}
+
+	variant, fen, err := server.StartPosition(messages.CreateSession{Variant: req.Variant, InitialFen: req.InitialFen})
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	tc := req.GetTimeControl()
+	session, err := svc.app.Manager.CreateSession(
+		svc.ctx,
+		tc.GetWhiteTime(),
+		tc.GetBlackTime(),
+		tc.GetWhiteIncrement(),
+		tc.GetBlackIncrement(),
+		clr,
+		fen,
+		variant,
+		nil,
+		game.TimeManagement{},
+		"",
+		game.Pacing{},
+		"",
+		user,
+		req.Rated,
+		req.Analysis,
+		0,
+		false,
+		uuid.New(), // No connection closes on the game, it ends once over or idle
+		svc.app.Publisher,
+	)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	if session.EngineOpens() {
+		if err := session.RequestEngineMove(""); err != nil {
+			return nil, grpcError(err)
+		}
+	}
+
+	state, err := session.State()
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return &engv1.CreateGameResponse{
+		GameId:     session.ID.String(),
+		InitialFen: state.BoardFEN,
+		Variant:    string(session.Variant),
+	}, nil
+}
+
+// MakeMove plays a move of the user's game, like MAKE_MOVE. The engine reply
+// is delivered on StreamEvents
+func (svc *grpcService) MakeMove(ctx context.Context, req *engv1.MakeMoveRequest) (*engv1.MakeMoveResponse, error) {
+	session, err := svc.lookupGame(req.GameId)
+	if err != nil {
+		return nil, err
+	}
+
+	clr, ok := session.ColorOfUser(svc.callUser(ctx))
+	if !ok {
+		return nil, status.Error(codes.PermissionDenied, "only the player can move")
+	}
+
+	outcome, err := session.ProcessMove(clr, req.Move, "", 0, "")
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	// A retried move was played and answered by the engine the first time,
+	// the opponent of a game between players answers themselves
+	if !outcome.Replayed && session.Mode != game.ModeHumanVsHuman {
+		if err := session.RequestEngineMove(""); err != nil {
+			return nil, grpcError(err)
+		}
+	}
+
+	return &engv1.MakeMoveResponse{
+		Fen: outcome.State.BoardFEN,
+		San: outcome.State.LastMove,
+		Uci: outcome.State.LastMoveUCI,
+	}, nil
+}
+
+// StreamEvents delivers the events of a game, starting with its state, until
+// the game is over or the client cancels the stream
+func (svc *grpcService) StreamEvents(req *engv1.StreamEventsRequest, stream engv1.EngineService_StreamEventsServer) error {
+	session, err := svc.lookupGame(req.GameId)
+	if err != nil {
+		return err
+	}
+
+	events := svc.app.Hub.Subscribe(session.ID.String())
+	defer svc.app.Hub.Unsubscribe(events)
+
+	state, err := session.State()
+	if err != nil {
+		return grpcError(err)
+	}
+
+	if err := sendEvent(stream, messages.OutboundMessage{Event: "GAME_STATE", Payload: state}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case msg := <-events.Events():
+			if err := sendEvent(stream, msg); err != nil {
+				return err
+			}
+			if msg.Event == "GAME_OVER" {
+				return nil
+			}
+
+		case <-session.Terminated():
+			return nil
+
+		case <-stream.Context().Done():
+			return nil
+
+		case <-svc.ctx.Done():
+			return nil
+		}
+	}
+}
+
+// sendEvent sends an outbound message of the WebSocket protocol on a stream
+func sendEvent(stream engv1.EngineService_StreamEventsServer, msg messages.OutboundMessage) error {
+	payload, err := json.Marshal(msg.Payload)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	return stream.Send(&engv1.Event{Event: msg.Event, PayloadJson: payload})
+}
+
+// Analyze runs a live analysis of a position, like START_ANALYSIS, until the
+// depth is reached or the client cancels the stream
+func (svc *grpcService) Analyze(req *engv1.AnalyzeRequest, stream engv1.EngineService_AnalyzeServer) error {
+	owner := uuid.New()
+
+	live, err := svc.app.Manager.StartAnalysis(req.Fen, int(req.Multipv), int(req.Depth), "", owner)
+	if err != nil {
+		return grpcError(err)
+	}
+	defer func() { _ = svc.app.Manager.StopAnalysis(live.ID, owner) }()
+
+	updates := svc.app.Hub.Subscribe(live.ID.String())
+	defer svc.app.Hub.Unsubscribe(updates)
+
+	for {
+		select {
+		case msg := <-updates.Events():
+			update, ok := msg.Payload.(messages.AnalysisUpdatePayload)
+			if !ok {
+				continue
+			}
+			if err := stream.Send(analysisUpdate(update)); err != nil {
+				return err
+			}
+			if update.Final {
+				return nil
+			}
+
+		case <-live.Done():
+			return nil
+
+		case <-stream.Context().Done():
+			return nil
+
+		case <-svc.ctx.Done():
+			return nil
+		}
+	}
+}
+
+// analysisUpdate converts an ANALYSIS_UPDATE payload to its gRPC message
+func analysisUpdate(payload messages.AnalysisUpdatePayload) *engv1.AnalysisUpdate {
+	update := &engv1.AnalysisUpdate{
+		AnalysisId: payload.AnalysisID,
+		Fen:        payload.FEN,
+		Depth:      int32(payload.Depth),
+		Final:      payload.Final,
+	}
+
+	for _, line := range payload.Lines {
+		update.Lines = append(update.Lines, &engv1.AnalysisLine{
+			Multipv: int32(line.MultiPV),
+			Move:    line.Move,
+			Depth:   int32(line.Depth),
+			ScoreCp: int32(line.ScoreCP),
+			Mate:    int32(line.Mate),
+			Pv:      line.PV,
+		})
+	}
+	return update
+}
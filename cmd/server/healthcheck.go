@@ -2,14 +2,138 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
-	"time"
+	"strings"
+
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/health"
 )
 
-// handleHealth handles the GET /health endpoint
+// backlogThreshold is the share of a subscriber queue that marks the
+// publisher degraded once filled
+const backlogThreshold = 0.75
+
+// registerHealthChecks registers the components reported by /health and /readyz
+func (app *application) registerHealthChecks() {
+	app.Health.Register("engine_pool", app.checkEnginePool)
+	app.Health.Register("repository", app.checkRepository)
+	app.Health.Register("hub", app.checkHub)
+	app.Health.Register("publisher", app.checkPublisher)
+	app.Health.Register("load", app.checkLoad)
+}
+
+// checkEnginePool is down until the pool is initialized and degraded while
+// every engine is busy and the pool cannot grow
+func (app *application) checkEnginePool() health.Component {
+	stats := app.Manager.EnginePoolStats()
+
+	switch {
+	case stats.Size == 0:
+		return health.Component{Status: health.StatusDown, Reason: "engine pool is not initialized", Details: stats}
+	case stats.Available == 0 && stats.Size >= stats.Max:
+		return health.Component{Status: health.StatusDegraded, Reason: "no engine available", Details: stats}
+	default:
+		return health.Component{Status: health.StatusUp, Details: stats}
+	}
+}
+
+// checkRepository is down while the repository is unreachable
+func (app *application) checkRepository() health.Component {
+	if err := app.Manager.PingRepository(); err != nil {
+		return health.Component{Status: health.StatusDown, Reason: "repository is unreachable: " + err.Error()}
+	}
+	return health.Component{Status: health.StatusUp}
+}
+
+// checkHub is down until the hub runs and once it is shut down
+func (app *application) checkHub() health.Component {
+	if !app.Hub.Running() {
+		return health.Component{Status: health.StatusDown, Reason: "hub is not running"}
+	}
+	return health.Component{Status: health.StatusUp, Details: app.Hub.DeliveryStats()}
+}
+
+// checkLoad is degraded while new games are refused for saturation
+func (app *application) checkLoad() health.Component {
+	stats := app.Hub.LoadStats()
+	if stats.Saturated {
+		return health.Component{Status: health.StatusDegraded, Reason: "server is saturated, new games are refused", Details: stats}
+	}
+	return health.Component{Status: health.StatusUp, Details: stats}
+}
+
+// checkPublisher is degraded while a subscriber falls behind on its events,
+// or once one was disconnected for falling behind
+func (app *application) checkPublisher() health.Component {
+	stats := app.Publisher.Stats()
+
+	var behind, disconnected []string
+	for _, sub := range stats {
+		switch {
+		case sub.Disconnected:
+			disconnected = append(disconnected, sub.Name)
+		case sub.Capacity > 0 && float64(sub.Queued) >= float64(sub.Capacity)*backlogThreshold:
+			behind = append(behind, sub.Name)
+		}
+	}
+
+	if len(disconnected) > 0 {
+		return health.Component{
+			Status:  health.StatusDegraded,
+			Reason:  fmt.Sprintf("%s disconnected for falling behind", strings.Join(disconnected, ", ")),
+			Details: stats,
+		}
+	}
+
+	if len(behind) > 0 {
+		return health.Component{
+			Status:  health.StatusDegraded,
+			Reason:  fmt.Sprintf("event backlog of %s", strings.Join(behind, ", ")),
+			Details: stats,
+		}
+	}
+	return health.Component{Status: health.StatusUp, Details: stats}
+}
+
+// handleHealth handles the GET /health endpoint, answering 503 when a
+// component is down
 func (app *application) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	report := app.Health.Report()
+
+	status := http.StatusOK
+	if report.Status == health.StatusDown {
+		status = http.StatusServiceUnavailable
+	}
+
+	writeJSON(w, status, report)
+}
+
+// handleLivez handles the GET /livez endpoint, answering as long as the
+// process serves requests
+func (app *application) handleLivez(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, messages.ProbeResponse{Status: "ok"})
+}
+
+// handleReadyz handles the GET /readyz endpoint, answering 503 with the reason
+// while a dependency needed to play games is down
+func (app *application) handleReadyz(w http.ResponseWriter, _ *http.Request) {
+	if down := app.Health.Report().Down(); len(down) > 0 {
+		writeJSON(w, http.StatusServiceUnavailable, messages.ProbeResponse{
+			Status: "unavailable",
+			Reason: strings.Join(down, "; "),
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, messages.ProbeResponse{Status: "ok"})
+}
+
+// writeJSON writes an uncached JSON body
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, `{"status":"ok","uptime":"%s"}`, time.Since(app.StartTime))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
 }
@@ -0,0 +1,44 @@
+// Package main is the entry point of the application
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/tecu23/eng-server/internal/messages"
+)
+
+// handleUserGames handles the GET /users/{id}/games endpoint
+func (app *application) handleUserGames(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	query := messages.ListGamesPayload{
+		UserID:      r.PathValue("id"),
+		Result:      q.Get("result"),
+		Color:       q.Get("color"),
+		Engine:      q.Get("engine"),
+		Since:       q.Get("since"),
+		Until:       q.Get("until"),
+		TimeControl: q.Get("time_control"),
+		Cursor:      q.Get("cursor"),
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		query.Limit = limit
+	}
+
+	games, err := app.Manager.ListGames(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(games)
+}
@@ -0,0 +1,105 @@
+// Package main is the entry point of the application
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+)
+
+// defaultUnixSocketMode lets the group of the server, e.g. a co-located
+// reverse proxy, connect to the socket
+const defaultUnixSocketMode = 0o660
+
+// listenFDsStart is the first file descriptor passed by systemd
+const listenFDsStart = 3
+
+// listenerFromEnv opens the listener of the server: the socket passed by
+// systemd socket activation unless SOCKET_ACTIVATION is false, then the Unix
+// socket at UNIX_SOCKET_PATH, then TCP on addr. Requests over a Unix socket
+// have no client IP, the rate limit applies per user of the API key instead
+func listenerFromEnv(addr string) (net.Listener, error) {
+	if os.Getenv("SOCKET_ACTIVATION") != "false" {
+		l, err := activatedListener()
+		if err != nil || l != nil {
+			return l, err
+		}
+	}
+
+	if path := os.Getenv("UNIX_SOCKET_PATH"); path != "" {
+		return unixListener(path)
+	}
+
+	return net.Listen("tcp", addr)
+}
+
+// activatedListener returns the first socket systemd passed to the process,
+// nil when the server was not socket activated. As sd_listen_fds does, the
+// variables are cleared so child processes do not take the socket too
+func activatedListener() (net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+
+	fds, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || fds < 1 {
+		return nil, nil
+	}
+
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	if fds > 1 {
+		return nil, fmt.Errorf("systemd passed %d sockets, the server listens on one", fds)
+	}
+
+	f := os.NewFile(uintptr(listenFDsStart), "LISTEN_FD_3")
+	defer f.Close()
+
+	l, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("systemd socket: %w", err)
+	}
+	return l, nil
+}
+
+// unixListener listens on a Unix socket with the mode of UNIX_SOCKET_MODE,
+// replacing the socket a previous run left behind. The socket is removed
+// when the listener closes
+func unixListener(path string) (net.Listener, error) {
+	mode := fs.FileMode(defaultUnixSocketMode)
+	if v := os.Getenv("UNIX_SOCKET_MODE"); v != "" {
+		m, err := strconv.ParseUint(v, 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid UNIX_SOCKET_MODE %q: %w", v, err)
+		}
+		mode = fs.FileMode(m)
+	}
+
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode().Type() != fs.ModeSocket {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(path, mode); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
@@ -2,9 +2,15 @@
 package main
 
 import (
+	"compress/flate"
+	"context"
 	"flag"
+	"fmt"
+	"net"
 	"net/http"
 	"os"
+	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,17 +20,31 @@ import (
 	"go.uber.org/zap/zapcore"
 
 	"github.com/tecu23/eng-server/internal/auth"
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/analysis"
+	"github.com/tecu23/eng-server/pkg/audit"
+	"github.com/tecu23/eng-server/pkg/book"
+	"github.com/tecu23/eng-server/pkg/certify"
 	"github.com/tecu23/eng-server/pkg/config"
 	"github.com/tecu23/eng-server/pkg/engine"
 	"github.com/tecu23/eng-server/pkg/events"
+	"github.com/tecu23/eng-server/pkg/game"
+	"github.com/tecu23/eng-server/pkg/health"
+	"github.com/tecu23/eng-server/pkg/journal"
 	"github.com/tecu23/eng-server/pkg/manager"
+	"github.com/tecu23/eng-server/pkg/match"
+	"github.com/tecu23/eng-server/pkg/rating"
 	"github.com/tecu23/eng-server/pkg/repository"
 	"github.com/tecu23/eng-server/pkg/server"
+	"github.com/tecu23/eng-server/pkg/tablebase"
+	"github.com/tecu23/eng-server/pkg/tournament"
+	"github.com/tecu23/eng-server/pkg/uciproxy"
 )
 
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
+	Subprotocols:    server.Subprotocols,
 
 	CheckOrigin: func(r *http.Request) bool {
 		path := os.Getenv("FRONTEND_PATH")
@@ -32,6 +52,62 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
+// defaultEngineRating is the rating of the engine when ENGINE_RATING is not set
+const defaultEngineRating = 2000
+
+// Move hint defaults used when HINT_DEPTH and HINT_BUDGET are not set
+const (
+	defaultHintDepth  = 8
+	defaultHintBudget = 3
+)
+
+// Audit log rotation defaults, overridden by AUDIT_LOG_MAX_BYTES and AUDIT_LOG_BACKUPS
+const (
+	defaultAuditMaxBytes = 10 << 20
+	defaultAuditBackups  = 5
+)
+
+// Engine pool defaults, overridden by ENGINE_POOL_MIN, ENGINE_POOL_MAX,
+// ENGINE_POOL_IDLE_TIMEOUT and ENGINE_POOL_MAX_WAIT
+const (
+	defaultPoolMin         = 5
+	defaultPoolMax         = 10
+	defaultPoolIdleTimeout = 5 * time.Minute
+	defaultPoolMaxWait     = 5 * time.Second
+)
+
+// UCI proxy defaults, overridden by UCI_PROXY_MAX_SESSIONS,
+// UCI_PROXY_IDLE_TIMEOUT, UCI_PROXY_MAX_DURATION, UCI_PROXY_MAX_HASH and
+// UCI_PROXY_MAX_THREADS. Sessions hold engines of the pool, so it is opt-in
+const (
+	defaultUCIProxySessions    = 0
+	defaultUCIProxyIdleTimeout = 5 * time.Minute
+	defaultUCIProxyMaxDuration = time.Hour
+	defaultUCIProxyMaxHash     = 256
+	defaultUCIProxyMaxThreads  = 1
+)
+
+// defaultAnalysisDepth is the post-game analysis depth when ANALYSIS_DEPTH is not set
+const defaultAnalysisDepth = 12
+
+// defaultAnalysisPoolShare is the part of the engine pool analysis jobs may use
+// at once when ANALYSIS_WORKERS is not set, games keep the rest
+const defaultAnalysisPoolShare = 4
+
+// Load shedding defaults, overridden by LOAD_SHED_MAX_INBOUND_QUEUE and
+// LOAD_SHED_RETRY_AFTER. A queue threshold of 0 only sheds while the engine
+// pool is exhausted
+const (
+	defaultLoadShedMaxInboundQueue = 256
+	defaultLoadShedRetryAfter      = 5 * time.Second
+)
+
+// defaultSessionTokenTTL is how long a session token lasts when SESSION_TOKEN_TTL is not set
+const defaultSessionTokenTTL = time.Hour
+
+// defaultSessionIdleTimeout is how long a game may stay idle when SESSION_IDLE_TIMEOUT is not set
+const defaultSessionIdleTimeout = 30 * time.Minute
+
 // App encapsulates global dependencies
 type application struct {
 	Auth      *auth.APIKeyAuth
@@ -39,7 +115,27 @@ type application struct {
 	Config    *config.Config
 	Publisher *events.Publisher
 	Hub       *server.Hub
+	Manager   *manager.Manager
 	Server    *http.Server
+	Tablebase *tablebase.Tablebase // nil when no tablebase is configured
+	Ratings   rating.Store
+
+	Compression server.Compression // permessage-deflate settings of new connections
+	Limiter     *rateLimiter       // nil when rate limiting is disabled
+	UCIProxy    *uciproxy.Proxy    // Raw UCI sessions on engines of the pool
+	Audit       *audit.Logger      // nil when no audit sink is configured
+
+	Snapshots repository.SnapshotStore // Keeps games in progress across restarts, nil disables it
+	Certifier *certify.Signer          // Signs the results of finished games, nil when results are not signed
+	Journal   *journal.Journal         // Events sent to each game, replayed by /replay, nil disables it
+	Health    *health.Checker          // Status of the components reported by /health and /readyz
+	Build     messages.BuildInfo       // Version of the server reported by /version and /health
+
+	Sessions *auth.SessionTokens // Tokens issued to connections for the REST routes
+	TLS      *tlsSettings        // Terminates TLS in the server, nil serves plain HTTP
+	Listener net.Listener        // TCP, Unix or systemd activated socket the server accepts on
+
+	GRPCListener net.Listener // Serves the gRPC API, nil disables it
 
 	StartTime time.Time
 }
@@ -58,27 +154,219 @@ func main() {
 	logger := initLogger(config.Debug)
 	defer logger.Sync()
 
+	build := buildInfo()
+	logger.Info("Starting eng-server",
+		zap.String("version", build.Version),
+		zap.String("commit", build.Commit),
+		zap.String("build_date", build.BuildDate),
+		zap.String("go_version", build.GoVersion),
+		zap.Int("protocol", build.Protocol),
+	)
+
 	err := godotenv.Load()
 	if err != nil {
 		logger.Fatal("loading env error", zap.Error(err))
 	}
 
 	// Initialize event publisher
-	publisher := events.NewPublisher()
+	publisher := events.NewPublisher(logger)
+
+	// Trace every event in debug mode
+	if config.Debug {
+		publisher.SubscribeAll(func(event events.Event) {
+			logger.Debug("Event published",
+				zap.String("type", string(event.Type)),
+				zap.String("game_id", event.GameID),
+			)
+		})
+	}
+
+	// Record the audit trail before anything publishes
+	auditLog, err := auditLoggerFromEnv(publisher, logger)
+	if err != nil {
+		logger.Fatal("audit log error", zap.Error(err))
+	}
+
+	// Games in progress are saved on shutdown and restored on startup when a snapshot file is set
+	var snapshots repository.SnapshotStore
+	if path := os.Getenv("SNAPSHOT_PATH"); path != "" {
+		snapshots = repository.NewFileSnapshotStore(path)
+	}
+
+	// The events of every game are journaled for replays when a directory is set
+	var eventJournal *journal.Journal
+	if dir := os.Getenv("EVENT_JOURNAL_DIR"); dir != "" {
+		eventJournal, err = journal.New(dir, logger)
+		if err != nil {
+			logger.Fatal("event journal error", zap.Error(err))
+		}
+	}
+
+	// Queued analysis jobs survive restarts when a job file is set
+	var analysisJobs analysis.JobStore
+	if path := os.Getenv("ANALYSIS_JOBS_PATH"); path != "" {
+		analysisJobs = repository.NewFileJobStore(path)
+	}
+
+	// Engine statistics survive restarts when a statistics file is set
+	var engineStats repository.EngineStatsStore
+	if path := os.Getenv("ENGINE_STATS_PATH"); path != "" {
+		engineStats = repository.NewFileEngineStatsStore(path)
+	}
 
 	// Initialize repository
 	repository := repository.NewInMemoryRepository(logger)
+	if engineStats != nil {
+		if err := repository.PersistEngineStats(engineStats); err != nil {
+			logger.Fatal("engine statistics error", zap.Error(err))
+		}
+	}
 
 	// Initlialize engine pool
-	enginePool := engine.NewEnginePool(os.Getenv("ENGINE_PATH"), 5, logger)
+	limits, err := engineLimitsFromEnv()
+	if err != nil {
+		logger.Fatal("engine limits error", zap.Error(err))
+	}
+
+	// Load the Syzygy tablebases when configured, engines probe them as well
+	engineOptions := make(map[string]string)
+
+	var tb *tablebase.Tablebase
+	if path := os.Getenv("SYZYGY_PATH"); path != "" {
+		tb, err = tablebase.Open(path, os.Getenv("SYZYGY_PROBE_CMD"))
+		if err != nil {
+			logger.Fatal("tablebase error", zap.Error(err))
+		}
+		engineOptions["SyzygyPath"] = path
+	}
+
+	scaling, err := poolScalingFromEnv()
+	if err != nil {
+		logger.Fatal("engine pool config error", zap.Error(err))
+	}
+
+	// An ENGINE_PATH starting with xboard: runs a CECP engine behind the UCI
+	// adapter, one starting with mock: runs the built-in mock engine
+	enginePool := engine.NewEnginePool(os.Getenv("ENGINE_PATH"), scaling, limits, engineOptions, logger)
+	override, err := allocationOverrideFromEnv()
+	if err != nil {
+		logger.Fatal("engine allocation error", zap.Error(err))
+	}
+	if _, err := enginePool.SetAllocationOverride(override); err != nil {
+		logger.Fatal("engine allocation error", zap.Error(err))
+	}
+
 	if err := enginePool.Initialize(); err != nil {
 		logger.Fatal("initialize engine error", zap.Error(err))
 	}
 
 	// Initialize game manager
-	gm := manager.NewManager(repository, enginePool, logger, publisher)
+	engineFallback := game.FallbackForfeit
+	if os.Getenv("ENGINE_TIMEOUT_FALLBACK") == string(game.FallbackRandomMove) {
+		engineFallback = game.FallbackRandomMove
+	}
+
+	// Load the opening book when one is configured
+	var openingBook *book.Book
+	if path := os.Getenv("OPENING_BOOK_PATH"); path != "" {
+		openingBook, err = book.Open(path)
+		if err != nil {
+			logger.Fatal("opening book error", zap.Error(err))
+		}
+	}
+
+	adjudication, err := adjudicationRulesFromEnv()
+	if err != nil {
+		logger.Fatal("adjudication rules error", zap.Error(err))
+	}
+
+	// Rated games are played against the engine as a player of fixed strength
+	engineRating := rating.Fixed(defaultEngineRating)
+	if v := os.Getenv("ENGINE_RATING"); v != "" {
+		r, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			logger.Fatal("invalid ENGINE_RATING", zap.Error(err))
+		}
+		engineRating = rating.Fixed(r)
+	}
+
+	// Analyze finished games in the background when players ask for it
+	analysisDepth := defaultAnalysisDepth
+	if depth, err := strconv.Atoi(os.Getenv("ANALYSIS_DEPTH")); err == nil && depth > 0 {
+		analysisDepth = depth
+	}
+	analyzer := analysis.NewAnalyzer(
+		enginePool,
+		analysisDepth,
+		analysisWorkersFromEnv(scaling),
+		repository,
+		analysisJobs,
+		publisher,
+		logger,
+	)
+	if err := analyzer.Restore(); err != nil {
+		logger.Fatal("analysis jobs error", zap.Error(err))
+	}
+
+	// The eval bar keeps an engine of the pool for itself, so it is opt-in
+	var evalBar *analysis.EvalBar
+	if depth, err := strconv.Atoi(os.Getenv("EVAL_BAR_DEPTH")); err == nil && depth > 0 {
+		evalBar = analysis.NewEvalBar(enginePool, depth, publisher, logger)
+	}
+
+	profiles, err := engineProfilesFromEnv(enginePool)
+	if err != nil {
+		logger.Fatal("engine profiles error", zap.Error(err))
+	}
+
+	// Results of finished games are signed when a signing key is set
+	certifier, err := resultSignerFromEnv()
+	if err != nil {
+		logger.Fatal("result signing key error", zap.Error(err))
+	}
+
+	gm := manager.NewManager(
+		repository,
+		enginePool,
+		engineFallback,
+		openingBook,
+		tb,
+		adjudication,
+		engineRating,
+		hintSettingsFromEnv(),
+		lagCompensationFromEnv(),
+		disconnectGraceFromEnv(logger),
+		analyzer,
+		evalBar,
+		profiles,
+		certifier,
+		logger,
+		publisher,
+	)
 
-	hub := server.NewHub(gm, publisher, logger)
+	// Initialize match runner
+	runner := match.NewRunner(
+		matchEnginesFromEnv(),
+		limits,
+		engineOptions,
+		tb,
+		adjudication,
+		repository,
+		publisher,
+		logger,
+	)
+
+	lobby := tournament.NewLobby(publisher, logger)
+
+	// HUB_SHARDS sets how many goroutines handle the messages of the games,
+	// hashed by game ID, one per CPU by default
+	hubShards := runtime.NumCPU()
+	if v, err := strconv.Atoi(os.Getenv("HUB_SHARDS")); err == nil && v > 0 {
+		hubShards = v
+	}
+
+	hub := server.NewHub(gm, runner, lobby, publisher, build, chatFilterFromEnv(), eventJournal, loadSheddingFromEnv(), hubShards, logger)
+	lobby.SetHost(hub)
 
 	var authKeys []string
 
@@ -91,16 +379,119 @@ func main() {
 		authKeys = keys
 	}
 
+	var adminKeys []string
+
+	if envAdminKeys := os.Getenv("ADMIN_API_KEYS"); envAdminKeys != "" {
+		keys := strings.Split(envAdminKeys, ",")
+		for i, key := range keys {
+			keys[i] = strings.TrimSpace(key)
+		}
+		adminKeys = keys
+	}
+
+	// Coaches may draw on the boards of the games they watch
+	var coachKeys []string
+
+	if envCoachKeys := os.Getenv("COACH_API_KEYS"); envCoachKeys != "" {
+		keys := strings.Split(envCoachKeys, ",")
+		for i, key := range keys {
+			keys[i] = strings.TrimSpace(key)
+		}
+		coachKeys = keys
+	}
+
+	// Services read results and game histories on behalf of other backends
+	var serviceKeys []string
+
+	if envServiceKeys := os.Getenv("SERVICE_API_KEYS"); envServiceKeys != "" {
+		keys := strings.Split(envServiceKeys, ",")
+		for i, key := range keys {
+			keys[i] = strings.TrimSpace(key)
+		}
+		serviceKeys = keys
+	}
+
+	compression := compressionFromEnv()
+	upgrader.EnableCompression = compression.Enabled
+
+	proxyLimits, err := uciProxyLimitsFromEnv()
+	if err != nil {
+		logger.Fatal("UCI proxy config error", zap.Error(err))
+	}
+
+	tlsConfig, err := tlsFromEnv()
+	if err != nil {
+		logger.Fatal("TLS config error", zap.Error(err))
+	}
+
+	listener, err := listenerFromEnv(":" + config.Port)
+	if err != nil {
+		logger.Fatal("listener error", zap.Error(err))
+	}
+
+	grpcListener, err := grpcListenerFromEnv()
+	if err != nil {
+		logger.Fatal("gRPC listener error", zap.Error(err))
+	}
+
+	keyAuth := auth.NewAPIKeyAuth(authKeys, adminKeys, coachKeys, serviceKeys)
+
+	// Admin routes stay closed without admin keys, unless explicitly opened
+	// for a development server
+	if open, _ := strconv.ParseBool(os.Getenv("OPEN_ADMIN")); open {
+		keyAuth.OpenAdmin()
+		logger.Warn("OPEN_ADMIN is set, every API key has the admin role; never use it in production")
+	} else if len(adminKeys) == 0 {
+		logger.Info("no ADMIN_API_KEYS configured, admin routes are closed")
+	}
+
 	app := &application{
-		Auth:      auth.NewAPIKeyAuth(authKeys),
-		Logger:    logger,
-		Config:    config,
-		Hub:       hub,
-		Publisher: publisher,
-		StartTime: time.Now(),
+		Auth:         keyAuth,
+		Sessions:     auth.NewSessionTokens(sessionTokenTTLFromEnv(logger)),
+		TLS:          tlsConfig,
+		Listener:     listener,
+		GRPCListener: grpcListener,
+		Logger:       logger,
+		Config:       config,
+		Hub:          hub,
+		Manager:      gm,
+		Publisher:    publisher,
+		Tablebase:    tb,
+		Ratings:      repository,
+		Compression:  compression,
+		Limiter:      rateLimiterFromEnv(),
+		UCIProxy:     uciproxy.New(enginePool, proxyLimits, logger),
+		Audit:        auditLog,
+		Snapshots:    snapshots,
+		Certifier:    certifier,
+		Journal:      eventJournal,
+		Build:        build,
+		StartTime:    time.Now(),
 	}
+	app.Health = health.NewChecker(app.StartTime, build)
+	app.registerHealthChecks()
 
 	go app.Hub.Run()
+	go analyzer.Run(context.Background())
+
+	// Restored games wait paused for their players to send RESUME_GAME
+	if app.Snapshots != nil {
+		if err := gm.RestoreSessions(context.Background(), app.Snapshots); err != nil {
+			logger.Error("Could not restore game sessions", zap.Error(err))
+		}
+	}
+
+	if idleTimeout := sessionIdleTimeoutFromEnv(logger); idleTimeout > 0 {
+		go gm.ReapIdle(context.Background(), idleTimeout)
+	}
+
+	if evalBar != nil {
+		go func() {
+			if err := evalBar.Run(context.Background()); err != nil {
+				logger.Error("eval bar stopped", zap.Error(err))
+			}
+		}()
+	}
 
 	err = app.serve()
 	if err != nil {
@@ -126,12 +517,478 @@ func initLogger(debug bool) *zap.Logger {
 	return logger
 }
 
+// engineLimitsFromEnv reads the engine sandbox configuration from the environment
+func engineLimitsFromEnv() (engine.Limits, error) {
+	limits := engine.Limits{
+		CgroupRoot: os.Getenv("ENGINE_CGROUP_ROOT"),
+	}
+
+	if v := os.Getenv("ENGINE_CPU_QUOTA"); v != "" {
+		quota, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return limits, fmt.Errorf("invalid ENGINE_CPU_QUOTA: %w", err)
+		}
+		limits.CPUQuota = quota
+	}
+
+	if v := os.Getenv("ENGINE_MEMORY_LIMIT_MB"); v != "" {
+		mb, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return limits, fmt.Errorf("invalid ENGINE_MEMORY_LIMIT_MB: %w", err)
+		}
+		limits.MemoryBytes = mb * 1024 * 1024
+	}
+
+	if v := os.Getenv("ENGINE_NICE"); v != "" {
+		nice, err := strconv.Atoi(v)
+		if err != nil {
+			return limits, fmt.Errorf("invalid ENGINE_NICE: %w", err)
+		}
+		limits.Niceness = nice
+	}
+
+	if v := os.Getenv("ENGINE_MOVE_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return limits, fmt.Errorf("invalid ENGINE_MOVE_TIMEOUT: %w", err)
+		}
+		limits.MoveTimeout = timeout
+	}
+
+	if v := os.Getenv("ENGINE_SECCOMP_WRAPPER"); v != "" {
+		limits.SeccompWrapper = strings.Fields(v)
+	}
+
+	// Engines run in containers of the image, ENGINE_PATH is then the command run in it
+	if image := os.Getenv("ENGINE_CONTAINER_IMAGE"); image != "" {
+		limits.Container = &engine.Container{
+			Runtime: os.Getenv("ENGINE_CONTAINER_RUNTIME"),
+			Image:   image,
+		}
+
+		if v := os.Getenv("ENGINE_CONTAINER_VOLUMES"); v != "" {
+			limits.Container.Volumes = strings.Split(v, ",")
+		}
+	}
+
+	return limits, nil
+}
+
+// poolScalingFromEnv reads the size bounds of the engine pool from the environment
+func poolScalingFromEnv() (engine.Scaling, error) {
+	scaling := engine.Scaling{
+		MinEngines:  defaultPoolMin,
+		MaxEngines:  defaultPoolMax,
+		IdleTimeout: defaultPoolIdleTimeout,
+		MaxWait:     defaultPoolMaxWait,
+	}
+
+	if v := os.Getenv("ENGINE_POOL_MIN"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return scaling, fmt.Errorf("invalid ENGINE_POOL_MIN: %q", v)
+		}
+		scaling.MinEngines = n
+		scaling.MaxEngines = max(scaling.MaxEngines, n)
+	}
+
+	if v := os.Getenv("ENGINE_POOL_MAX"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < scaling.MinEngines {
+			return scaling, fmt.Errorf("invalid ENGINE_POOL_MAX: %q, must be at least ENGINE_POOL_MIN", v)
+		}
+		scaling.MaxEngines = n
+	}
+
+	if v := os.Getenv("ENGINE_POOL_IDLE_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil || timeout < 0 {
+			return scaling, fmt.Errorf("invalid ENGINE_POOL_IDLE_TIMEOUT: %q", v)
+		}
+		scaling.IdleTimeout = timeout
+	}
+
+	if v := os.Getenv("ENGINE_POOL_MAX_WAIT"); v != "" {
+		wait, err := time.ParseDuration(v)
+		if err != nil || wait < 0 {
+			return scaling, fmt.Errorf("invalid ENGINE_POOL_MAX_WAIT: %q", v)
+		}
+		scaling.MaxWait = wait
+	}
+
+	return scaling, nil
+}
+
+// adjudicationRulesFromEnv reads the engine game adjudication rules from the environment
+func adjudicationRulesFromEnv() (game.AdjudicationRules, error) {
+	var rules game.AdjudicationRules
+
+	settings := []struct {
+		name  string
+		value *int
+	}{
+		{"ADJUDICATION_DRAW_MOVE_NUMBER", &rules.DrawMoveNumber},
+		{"ADJUDICATION_DRAW_MOVE_COUNT", &rules.DrawMoveCount},
+		{"ADJUDICATION_DRAW_SCORE", &rules.DrawScore},
+		{"ADJUDICATION_RESIGN_MOVE_COUNT", &rules.ResignMoveCount},
+		{"ADJUDICATION_RESIGN_SCORE", &rules.ResignScore},
+	}
+
+	for _, setting := range settings {
+		v := os.Getenv(setting.name)
+		if v == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return rules, fmt.Errorf("invalid %s: %w", setting.name, err)
+		}
+		*setting.value = n
+	}
+
+	return rules, nil
+}
+
+// hintSettingsFromEnv reads the move hint settings from the environment,
+// falling back to the defaults on missing or invalid values
+func hintSettingsFromEnv() game.HintSettings {
+	hints := game.HintSettings{
+		Depth:  defaultHintDepth,
+		Budget: defaultHintBudget,
+	}
+
+	if depth, err := strconv.Atoi(os.Getenv("HINT_DEPTH")); err == nil && depth > 0 {
+		hints.Depth = depth
+	}
+
+	if budget, err := strconv.Atoi(os.Getenv("HINT_BUDGET")); err == nil && budget >= 0 {
+		hints.Budget = budget
+	}
+
+	return hints
+}
+
+// analysisWorkersFromEnv reads how many games are analyzed at once from
+// ANALYSIS_WORKERS, at most the size of the engine pool and a quarter of it by default
+func analysisWorkersFromEnv(scaling engine.Scaling) int {
+	workers := max(scaling.MaxEngines/defaultAnalysisPoolShare, 1)
+	if v, err := strconv.Atoi(os.Getenv("ANALYSIS_WORKERS")); err == nil && v > 0 {
+		workers = v
+	}
+	return min(workers, scaling.MaxEngines)
+}
+
+// sessionIdleTimeoutFromEnv reads how long a game may go without a move or
+// command before it is reaped from SESSION_IDLE_TIMEOUT, 0 disables reaping
+func sessionIdleTimeoutFromEnv(logger *zap.Logger) time.Duration {
+	v := os.Getenv("SESSION_IDLE_TIMEOUT")
+	if v == "" {
+		return defaultSessionIdleTimeout
+	}
+
+	timeout, err := time.ParseDuration(v)
+	if err != nil || timeout < 0 {
+		logger.Warn("Invalid SESSION_IDLE_TIMEOUT, using the default", zap.String("value", v))
+		return defaultSessionIdleTimeout
+	}
+	return timeout
+}
+
+// sessionTokenTTLFromEnv reads how long the session tokens issued to
+// connections last from SESSION_TOKEN_TTL, they are revoked sooner when the
+// connection closes
+func sessionTokenTTLFromEnv(logger *zap.Logger) time.Duration {
+	v := os.Getenv("SESSION_TOKEN_TTL")
+	if v == "" {
+		return defaultSessionTokenTTL
+	}
+
+	ttl, err := time.ParseDuration(v)
+	if err != nil || ttl <= 0 {
+		logger.Warn("Invalid SESSION_TOKEN_TTL, using the default", zap.String("value", v))
+		return defaultSessionTokenTTL
+	}
+	return ttl
+}
+
+// disconnectGraceFromEnv reads how long the games of a closed connection are
+// kept from DISCONNECT_GRACE_PERIOD, they are terminated right away unless it is set
+func disconnectGraceFromEnv(logger *zap.Logger) time.Duration {
+	v := os.Getenv("DISCONNECT_GRACE_PERIOD")
+	if v == "" {
+		return 0
+	}
+
+	grace, err := time.ParseDuration(v)
+	if err != nil || grace < 0 {
+		logger.Warn("Invalid DISCONNECT_GRACE_PERIOD, terminating games on disconnect", zap.String("value", v))
+		return 0
+	}
+	return grace
+}
+
+// lagCompensationFromEnv reads the most network lag credited to a player per
+// move from LAG_COMPENSATION_MS, compensation is off unless it is set
+func lagCompensationFromEnv() time.Duration {
+	ms, err := strconv.Atoi(os.Getenv("LAG_COMPENSATION_MS"))
+	if err != nil || ms < 0 {
+		return 0
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// compressionFromEnv reads the websocket compression settings from the
+// environment. Compression is off unless WS_COMPRESSION is true
+func compressionFromEnv() server.Compression {
+	compression := server.DefaultCompression
+	compression.Enabled, _ = strconv.ParseBool(os.Getenv("WS_COMPRESSION"))
+
+	if threshold, err := strconv.Atoi(os.Getenv("WS_COMPRESSION_THRESHOLD")); err == nil && threshold >= 0 {
+		compression.Threshold = threshold
+	}
+
+	if level, err := strconv.Atoi(os.Getenv("WS_COMPRESSION_LEVEL")); err == nil && level >= flate.BestSpeed && level <= flate.BestCompression {
+		compression.Level = level
+	}
+
+	return compression
+}
+
+// rateLimiterFromEnv reads the per client request rate limit from the
+// environment, a RATE_LIMIT_RPS of 0 disables it
+func rateLimiterFromEnv() *rateLimiter {
+	rps, burst := float64(defaultRateLimitRPS), float64(defaultRateLimitBurst)
+
+	if v, err := strconv.ParseFloat(os.Getenv("RATE_LIMIT_RPS"), 64); err == nil && v >= 0 {
+		rps = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("RATE_LIMIT_BURST"), 64); err == nil && v >= 1 {
+		burst = v
+	}
+
+	if rps == 0 {
+		return nil
+	}
+	return newRateLimiter(rps, burst)
+}
+
+// allocationOverrideFromEnv reads the Threads and Hash in MB of every engine
+// from ENGINE_THREADS and ENGINE_HASH_MB, they are computed from the host
+// resources when unset
+func allocationOverrideFromEnv() (engine.AllocationOverride, error) {
+	var override engine.AllocationOverride
+
+	if v := os.Getenv("ENGINE_THREADS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return override, fmt.Errorf("invalid ENGINE_THREADS: %q", v)
+		}
+		override.Threads = n
+	}
+
+	if v := os.Getenv("ENGINE_HASH_MB"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			return override, fmt.Errorf("invalid ENGINE_HASH_MB: %q", v)
+		}
+		override.HashMB = n
+	}
+
+	return override, nil
+}
+
+// engineProfilesFromEnv loads the engine option profiles of the JSON file at
+// ENGINE_PROFILES_PATH, checked against the options of the pooled engines.
+// Without it games start with no profile until admins define some
+func engineProfilesFromEnv(pool *engine.Pool) (*engine.Profiles, error) {
+	path := os.Getenv("ENGINE_PROFILES_PATH")
+	if path == "" {
+		return engine.NewProfiles(), nil
+	}
+
+	profiles, err := engine.LoadProfiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, profile := range profiles.List() {
+		if err := pool.ValidateOptions(profile.Options); err != nil {
+			return nil, fmt.Errorf("profile %s: %w", profile.Name, err)
+		}
+	}
+	return profiles, nil
+}
+
+// uciProxyLimitsFromEnv reads the resource limits of the UCI proxy sessions
+func uciProxyLimitsFromEnv() (uciproxy.Limits, error) {
+	limits := uciproxy.Limits{
+		MaxSessions: defaultUCIProxySessions,
+		IdleTimeout: defaultUCIProxyIdleTimeout,
+		MaxDuration: defaultUCIProxyMaxDuration,
+		MaxOptions: map[string]int64{
+			"Hash":    defaultUCIProxyMaxHash,
+			"Threads": defaultUCIProxyMaxThreads,
+		},
+	}
+
+	if v := os.Getenv("UCI_PROXY_MAX_SESSIONS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return limits, fmt.Errorf("invalid UCI_PROXY_MAX_SESSIONS: %q", v)
+		}
+		limits.MaxSessions = n
+	}
+
+	if v := os.Getenv("UCI_PROXY_IDLE_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil || timeout < 0 {
+			return limits, fmt.Errorf("invalid UCI_PROXY_IDLE_TIMEOUT: %q", v)
+		}
+		limits.IdleTimeout = timeout
+	}
+
+	if v := os.Getenv("UCI_PROXY_MAX_DURATION"); v != "" {
+		duration, err := time.ParseDuration(v)
+		if err != nil || duration < 0 {
+			return limits, fmt.Errorf("invalid UCI_PROXY_MAX_DURATION: %q", v)
+		}
+		limits.MaxDuration = duration
+	}
+
+	for option, env := range map[string]string{"Hash": "UCI_PROXY_MAX_HASH", "Threads": "UCI_PROXY_MAX_THREADS"} {
+		if v := os.Getenv(env); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 1 {
+				return limits, fmt.Errorf("invalid %s: %q", env, v)
+			}
+			limits.MaxOptions[option] = n
+		}
+	}
+
+	return limits, nil
+}
+
+// chatFilterFromEnv builds the chat filter masking the comma-separated words
+// of CHAT_BLOCKED_WORDS, nil when it is unset
+func chatFilterFromEnv() server.ChatFilter {
+	words := os.Getenv("CHAT_BLOCKED_WORDS")
+	if words == "" {
+		return nil
+	}
+	return server.WordFilter(strings.Split(words, ","))
+}
+
+// loadSheddingFromEnv reads when new games are refused from the environment
+func loadSheddingFromEnv() server.LoadShedding {
+	shedding := server.LoadShedding{
+		MaxInboundQueue: defaultLoadShedMaxInboundQueue,
+		RetryAfter:      defaultLoadShedRetryAfter,
+	}
+
+	if v, err := strconv.Atoi(os.Getenv("LOAD_SHED_MAX_INBOUND_QUEUE")); err == nil && v >= 0 {
+		shedding.MaxInboundQueue = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("LOAD_SHED_RETRY_AFTER")); err == nil && v > 0 {
+		shedding.RetryAfter = v
+	}
+
+	return shedding
+}
+
+// resultSignerFromEnv creates the signer of game results with the Ed25519 key
+// file at RESULT_SIGNING_KEY_PATH, or keyed with the RESULT_HMAC_KEY secret,
+// and nil when neither is set
+func resultSignerFromEnv() (*certify.Signer, error) {
+	if path := os.Getenv("RESULT_SIGNING_KEY_PATH"); path != "" {
+		return certify.LoadEd25519Signer(path)
+	}
+
+	if secret := os.Getenv("RESULT_HMAC_KEY"); secret != "" {
+		return certify.NewHMACSigner([]byte(secret)), nil
+	}
+
+	return nil, nil
+}
+
+// auditLoggerFromEnv creates the audit logger writing to AUDIT_LOG_PATH, or
+// posting to AUDIT_LOG_URL, and nil when neither is set. AUDIT_REDACT lists
+// the fields whose values are left out and AUDIT_HMAC_KEY keys the hash chain
+func auditLoggerFromEnv(publisher *events.Publisher, logger *zap.Logger) (*audit.Logger, error) {
+	var sink audit.Sink
+
+	switch {
+	case os.Getenv("AUDIT_LOG_PATH") != "":
+		maxBytes := int64(defaultAuditMaxBytes)
+		if v, err := strconv.ParseInt(os.Getenv("AUDIT_LOG_MAX_BYTES"), 10, 64); err == nil && v > 0 {
+			maxBytes = v
+		}
+
+		backups := defaultAuditBackups
+		if v, err := strconv.Atoi(os.Getenv("AUDIT_LOG_BACKUPS")); err == nil && v >= 0 {
+			backups = v
+		}
+
+		fileSink, err := audit.NewFileSink(os.Getenv("AUDIT_LOG_PATH"), maxBytes, backups)
+		if err != nil {
+			return nil, err
+		}
+		sink = fileSink
+
+	case os.Getenv("AUDIT_LOG_URL") != "":
+		sink = audit.NewHTTPSink(os.Getenv("AUDIT_LOG_URL"))
+
+	default:
+		return nil, nil
+	}
+
+	var redact []string
+	if v := os.Getenv("AUDIT_REDACT"); v != "" {
+		redact = strings.Split(v, ",")
+	}
+
+	return audit.NewLogger(sink, []byte(os.Getenv("AUDIT_HMAC_KEY")), redact, publisher, logger)
+}
+
+// matchEnginesFromEnv reads the engines available for matches from a
+// comma-separated list of name=path pairs, xboard: paths are CECP engines
+func matchEnginesFromEnv() map[string]string {
+	engines := make(map[string]string)
+
+	for _, entry := range strings.Split(os.Getenv("MATCH_ENGINES"), ",") {
+		name, path, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || name == "" || path == "" {
+			continue
+		}
+		engines[name] = path
+	}
+
+	return engines
+}
+
 // Shutdown cleans up resources
 func (app *application) Shutdown() {
+	// Save the games in progress while their sessions still run
+	if app.Snapshots != nil {
+		if err := app.Manager.SnapshotSessions(app.Snapshots); err != nil {
+			app.Logger.Error("Could not snapshot game sessions", zap.Error(err))
+		}
+	}
+
 	// Shut down hub
 	if app.Hub != nil {
 		app.Hub.Shutdown()
 	}
 
+	if app.Journal != nil {
+		app.Journal.CloseAll()
+	}
+
+	if app.Audit != nil {
+		if err := app.Audit.Close(); err != nil {
+			app.Logger.Error("Could not close audit log", zap.Error(err))
+		}
+	}
+
 	app.Logger.Info("All components shut down successfully")
 }
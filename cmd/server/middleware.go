@@ -2,11 +2,48 @@
 package main
 
 import (
+	"bufio"
+	"fmt"
+	"net"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/google/uuid"
 	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/auth"
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/events"
 )
 
+// Rate limiter defaults, overridden by RATE_LIMIT_RPS and RATE_LIMIT_BURST
+const (
+	defaultRateLimitRPS   = 10
+	defaultRateLimitBurst = 20
+
+	limiterIdleTimeout = 3 * time.Minute // Clients idle this long are forgotten
+)
+
+// requestIDHeader carries the correlation ID of an HTTP request, echoed in the response
+const requestIDHeader = "X-Request-Id"
+
+// apiKey returns the API key of a request, sent in the X-Api-Key header or
+// stood for by the session token of a connection in the Authorization header
+func (app *application) apiKey(r *http.Request) string {
+	if key := r.Header.Get("X-Api-Key"); key != "" {
+		return key
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return ""
+	}
+	key, _ := app.Sessions.Key(token)
+	return key
+}
+
 func (app *application) authenticate(next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/health" {
@@ -14,9 +51,7 @@ func (app *application) authenticate(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		apiKey := r.Header.Get("X-Api-Key")
-
-		if app.Auth.IsValidKey(apiKey) {
+		if app.Auth.IsValidKey(app.apiKey(r)) {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -26,7 +61,217 @@ func (app *application) authenticate(next http.HandlerFunc) http.HandlerFunc {
 			zap.String("path", r.URL.Path),
 			zap.String("remote_addr", r.RemoteAddr),
 		)
+		app.Publisher.Publish(events.Event{
+			Type: events.EventAuthFailed,
+			Payload: map[string]string{
+				"path":        r.URL.Path,
+				"remote_addr": r.RemoteAddr,
+			},
+		})
 		w.Header().Set("WWW-Authenticate", "APIKey")
 		http.Error(w, "Unauthorized: invalid API key", http.StatusUnauthorized)
 	})
 }
+
+// authorize lets through the requests authenticated with a key whose role
+// grants the permission
+func (app *application) authorize(perm auth.Permission, next http.HandlerFunc) http.HandlerFunc {
+	return app.authenticate(func(w http.ResponseWriter, r *http.Request) {
+		if app.Auth.Can(app.apiKey(r), perm) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		app.Logger.Warn("Access denied",
+			zap.String("path", r.URL.Path),
+			zap.String("permission", string(perm)),
+			zap.String("remote_addr", r.RemoteAddr),
+		)
+		http.Error(w, fmt.Sprintf("Forbidden: %s permission required", perm), http.StatusForbidden)
+	})
+}
+
+// requireAdmin lets through the requests authenticated with an admin key
+func (app *application) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return app.authorize(auth.PermAdmin, next)
+}
+
+// recoverPanic turns a panic in a handler into a 500 response instead of
+// leaving the client with a dropped connection
+func (app *application) recoverPanic(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if err := recover(); err != nil {
+				w.Header().Set("Connection", "close")
+				app.Logger.Error("Handler panicked",
+					zap.String("path", r.URL.Path),
+					zap.String("panic", fmt.Sprint(err)),
+					zap.Stack("stack"),
+				)
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+			}
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// logRequest logs every request once it has been served
+func (app *application) logRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		// Correlation ID of the request, kept from the client when it sends one
+		id := r.Header.Get(requestIDHeader)
+		if id == "" {
+			id = uuid.NewString()
+			r.Header.Set(requestIDHeader, id)
+		}
+		w.Header().Set(requestIDHeader, id)
+
+		next.ServeHTTP(rec, r)
+
+		app.Logger.Info("Request served",
+			zap.String("method", r.Method),
+			zap.String("path", r.URL.Path),
+			zap.String("remote_addr", r.RemoteAddr),
+			zap.String("request_id", id),
+			zap.Int("status", rec.status),
+			zap.Duration("duration", time.Since(start)),
+		)
+	})
+}
+
+// rateLimit rejects clients sending requests faster than the limiter allows
+func (app *application) rateLimit(next http.Handler) http.Handler {
+	if app.Limiter == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !app.Limiter.allow(app.rateLimitClient(r)) {
+			app.Logger.Warn("Rate limit exceeded",
+				zap.String("path", r.URL.Path),
+				zap.String("remote_addr", r.RemoteAddr),
+			)
+			w.Header().Set("Retry-After", "1")
+			writeJSON(w, http.StatusTooManyRequests, messages.ErrorPayload{
+				Code:      messages.ErrorRateLimited,
+				Message:   "Too many requests",
+				RequestID: r.Header.Get(requestIDHeader),
+			})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitClient names the client a request is limited as: its IP over TCP.
+// Unix sockets carry no client address, every request would share a single
+// bucket, so the user of the API key stands for the client there, and the
+// requests without a valid key share one bucket
+func (app *application) rateLimitClient(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil && net.ParseIP(host) != nil {
+		return host
+	}
+
+	if user, ok := app.Auth.User(app.apiKey(r)); ok {
+		return "user:" + user
+	}
+	return "anonymous"
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the flusher of event streams
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// Hijack hands the connection over to the WebSocket upgrader
+func (rec *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rec.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+
+	rec.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+// rateLimiter is a token bucket per client IP
+type rateLimiter struct {
+	mu      sync.Mutex
+	clients map[string]*bucket
+	rps     float64
+	burst   float64
+
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rps, burst float64) *rateLimiter {
+	return &rateLimiter{
+		clients:   make(map[string]*bucket),
+		rps:       rps,
+		burst:     burst,
+		lastSweep: time.Now(),
+	}
+}
+
+// allow takes a token from the bucket of a client, reporting false when it is empty
+func (l *rateLimiter) allow(ip string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	b, ok := l.clients[ip]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.clients[ip] = b
+	}
+
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rps)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// sweep forgets the clients that have been idle for a while
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < limiterIdleTimeout {
+		return
+	}
+	l.lastSweep = now
+
+	for ip, b := range l.clients {
+		if now.Sub(b.last) > limiterIdleTimeout {
+			delete(l.clients, ip)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Package main is the entry point of the application
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/engine"
+)
+
+// handleEngineProfiles handles the GET /engine-profiles endpoint
+func (app *application) handleEngineProfiles(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(messages.EngineProfilesResponse{Profiles: app.Manager.EngineProfiles()})
+}
+
+// handlePutEngineProfile handles the PUT /admin/engine-profiles/{name} endpoint
+func (app *application) handlePutEngineProfile(w http.ResponseWriter, r *http.Request) {
+	var req messages.EngineProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	name := r.PathValue("name")
+	if err := app.Manager.SetEngineProfile(name, req.Options); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	app.Logger.Info("Engine profile saved", zap.String("profile", name), zap.Any("options", req.Options))
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(engine.Profile{Name: name, Options: req.Options})
+}
+
+// handleDeleteEngineProfile handles the DELETE /admin/engine-profiles/{name} endpoint
+func (app *application) handleDeleteEngineProfile(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	err := app.Manager.DeleteEngineProfile(name)
+	if errors.Is(err, engine.ErrProfileNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	app.Logger.Info("Engine profile deleted", zap.String("profile", name))
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,50 @@
+// Package main is the entry point of the application
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/tecu23/eng-server/pkg/repository"
+)
+
+// handlePuzzles handles the GET /puzzles endpoint
+func (app *application) handlePuzzles(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	limit := 0
+	if v := q.Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	puzzles, err := app.Manager.Puzzles(q.Get("min_rating"), q.Get("max_rating"), q.Get("difficulty"), q.Get("mate") == "true", limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(puzzles)
+}
+
+// handlePuzzle handles the GET /puzzles/{id} endpoint
+func (app *application) handlePuzzle(w http.ResponseWriter, r *http.Request) {
+	puzzle, err := app.Manager.Puzzle(r.PathValue("id"))
+	if errors.Is(err, repository.ErrPuzzleNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(puzzle)
+}
@@ -0,0 +1,35 @@
+// Package main is the entry point of the application
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/rating"
+)
+
+// handleUserRating handles the GET /users/{id}/rating endpoint
+func (app *application) handleUserRating(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+
+	userRating, err := app.Ratings.GetRating(userID)
+	if errors.Is(err, rating.ErrNotFound) {
+		http.Error(w, "User has no rating", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		app.Logger.Error("Rating lookup failed", zap.String("user_id", userID), zap.Error(err))
+		http.Error(w, "Rating lookup failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(messages.UserRatingResponse{
+		UserID: userID,
+		Rating: userRating,
+	})
+}
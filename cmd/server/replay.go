@@ -0,0 +1,66 @@
+// Package main is the entry point of the application
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/pkg/journal"
+	"github.com/tecu23/eng-server/pkg/server"
+)
+
+// maxReplaySpeed bounds the replay speed, 0 already sends the events at once
+const maxReplaySpeed = 1000
+
+// handleReplay handles the GET /replay/{id} endpoint, replaying the event
+// journal of a game over a WebSocket
+func (app *application) handleReplay(w http.ResponseWriter, r *http.Request) {
+	if app.Journal == nil {
+		http.Error(w, "Event journal is not enabled on this server", http.StatusNotFound)
+		return
+	}
+
+	gameID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid game ID", http.StatusBadRequest)
+		return
+	}
+
+	speed := 1.0
+	if v := r.URL.Query().Get("speed"); v != "" {
+		speed, err = strconv.ParseFloat(v, 64)
+		if err != nil || speed < 0 || speed > maxReplaySpeed {
+			http.Error(w, "speed must be a number between 0 and 1000", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Read before upgrading so a missing journal is a plain HTTP error
+	entries, err := app.Journal.Read(gameID.String())
+	switch {
+	case errors.Is(err, journal.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		app.Logger.Error("Could not read event journal", zap.String("game_id", gameID.String()), zap.Error(err))
+		http.Error(w, "Could not read event journal", http.StatusInternalServerError)
+		return
+	}
+
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		app.Logger.Error("Failed to upgrade to WebSocket", zap.Error(err))
+		return
+	}
+
+	app.Logger.Info("Replaying game events",
+		zap.String("game_id", gameID.String()),
+		zap.Int("events", len(entries)),
+		zap.Float64("speed", speed))
+
+	go server.Replay(ws, gameID.String(), entries, speed, app.Logger)
+}
@@ -0,0 +1,46 @@
+// Package main is the entry point of the application
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/tecu23/eng-server/internal/messages"
+)
+
+// handleResultKey handles the GET /results/key endpoint
+func (app *application) handleResultKey(w http.ResponseWriter, r *http.Request) {
+	if app.Certifier == nil {
+		http.Error(w, "Results are not signed on this server", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(messages.ResultKeyResponse{
+		Algorithm: app.Certifier.Algorithm(),
+		KeyID:     app.Certifier.KeyID(),
+		PublicKey: app.Certifier.PublicKey(),
+	})
+}
+
+// handleVerifyResult handles the POST /results/verify endpoint
+func (app *application) handleVerifyResult(w http.ResponseWriter, r *http.Request) {
+	if app.Certifier == nil {
+		http.Error(w, "Results are not signed on this server", http.StatusNotFound)
+		return
+	}
+
+	var cert messages.ResultCertificate
+	if err := json.NewDecoder(r.Body).Decode(&cert); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp := messages.ResultVerificationResponse{Valid: true}
+	if err := app.Certifier.Verify(cert); err != nil {
+		resp = messages.ResultVerificationResponse{Reason: err.Error()}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
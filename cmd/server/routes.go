@@ -3,19 +3,68 @@ package main
 
 import (
 	"net/http"
+
+	"github.com/tecu23/eng-server/docs"
+	"github.com/tecu23/eng-server/internal/auth"
 )
 
 func (app *application) routes() http.Handler {
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/health", app.handleHealth)
+	mux.HandleFunc("GET /livez", app.handleLivez)
+	mux.HandleFunc("GET /readyz", app.handleReadyz)
+	mux.HandleFunc("GET /version", app.handleVersion)
+
+	// Documentation page with the generated AsyncAPI and OpenAPI documents
+	mux.Handle("/docs/", http.StripPrefix("/docs/", http.FileServer(http.FS(docs.Files))))
+
+	mux.HandleFunc("/tablebase", app.authorize(auth.PermRead, app.handleTablebaseProbe))
+
+	mux.HandleFunc("GET /users/{id}/rating", app.authorize(auth.PermRead, app.handleUserRating))
+	mux.HandleFunc("GET /users/{id}/games", app.authorize(auth.PermRead, app.handleUserGames))
+
+	mux.HandleFunc("GET /explorer", app.authorize(auth.PermRead, app.handleExplorer))
+	mux.HandleFunc("GET /puzzles", app.authorize(auth.PermRead, app.handlePuzzles))
+	mux.HandleFunc("GET /puzzles/{id}", app.authorize(auth.PermRead, app.handlePuzzle))
+
+	mux.HandleFunc("POST /games/{id}/analysis", app.authorize(auth.PermAnalyze, app.handleAnalyzeGame))
+	mux.HandleFunc("GET /analysis/jobs/{id}", app.authorize(auth.PermAnalyze, app.handleAnalysisJob))
+	mux.HandleFunc("DELETE /analysis/jobs/{id}", app.authorize(auth.PermAnalyze, app.handleCancelAnalysisJob))
+	mux.HandleFunc("POST /analysis/batch", app.authorize(auth.PermAnalyze, app.handleAnalyzeBatch))
+	mux.HandleFunc("GET /analysis/batch/{id}", app.authorize(auth.PermAnalyze, app.handleAnalysisBatch))
+	mux.HandleFunc("GET /analysis/batch/{id}/pgn", app.authorize(auth.PermAnalyze, app.handleAnalysisBatchPGN))
+	mux.HandleFunc("DELETE /analysis/batch/{id}", app.authorize(auth.PermAnalyze, app.handleCancelAnalysisBatch))
+	mux.HandleFunc("GET /admin/analysis/jobs", app.requireAdmin(app.handleAnalysisJobs))
+	mux.HandleFunc("GET /admin/fairplay", app.requireAdmin(app.handleFairPlayReports))
+
+	mux.HandleFunc("GET /engines", app.authorize(auth.PermRead, app.handleEngines))
+	mux.HandleFunc("GET /engines/stats", app.authorize(auth.PermRead, app.handleEngineLeaderboard))
+	mux.HandleFunc("GET /admin/engines/stats", app.requireAdmin(app.handleEngineStats))
+	mux.HandleFunc("GET /admin/games/{id}/transcript", app.requireAdmin(app.handleEngineTranscript))
+	mux.HandleFunc("GET /admin/engines/allocation", app.requireAdmin(app.handleEngineAllocation))
+	mux.HandleFunc("PUT /admin/engines/allocation", app.requireAdmin(app.handlePutEngineAllocation))
+
+	mux.HandleFunc("GET /engine-profiles", app.authorize(auth.PermRead, app.handleEngineProfiles))
+	mux.HandleFunc("PUT /admin/engine-profiles/{name}", app.requireAdmin(app.handlePutEngineProfile))
+	mux.HandleFunc("DELETE /admin/engine-profiles/{name}", app.requireAdmin(app.handleDeleteEngineProfile))
+
+	// Fallback for clients that cannot use WebSockets
+	mux.HandleFunc("GET /games/{id}/stream", app.authorize(auth.PermRead, app.handleGameStream))
+	mux.HandleFunc("POST /games/{id}/moves", app.authorize(auth.PermPlay, app.handleGameMove))
+	mux.HandleFunc("POST /games/{id}/resign", app.authorize(auth.PermPlay, app.handleGameResign))
+
+	mux.HandleFunc("/ws", app.authenticate(app.handleWebSocket))
+	mux.HandleFunc("GET /replay/{id}", app.authorize(auth.PermRead, app.handleReplay))
 
-	// For serving all files in the docs directory
-	mux.Handle("/docs/", http.StripPrefix("/docs/", http.FileServer(http.Dir("./docs"))))
+	// Signed results of finished games, checked by the systems they are handed to
+	mux.HandleFunc("GET /results/key", app.authorize(auth.PermRead, app.handleResultKey))
+	mux.HandleFunc("POST /results/verify", app.authorize(auth.PermRead, app.handleVerifyResult))
 
-	mux.HandleFunc("/ws", app.authenticate(app.handleHealth))
+	// Raw UCI for desktop GUIs using the server as a remote engine
+	mux.HandleFunc("/uci", app.authorize(auth.PermAnalyze, app.handleUCIProxy))
 
 	app.Logger.Info("Routes configured successfully")
 
-	return mux
+	return app.recoverPanic(app.logRequest(app.rateLimit(mux)))
 }
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 )
 
 // Run starts the http server and handles graceful shutdown
@@ -22,6 +23,22 @@ func (app *application) serve() error {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	var challenges *http.Server
+	if app.TLS != nil {
+		app.Server.TLSConfig = app.TLS.config()
+		challenges = app.TLS.httpServer()
+	}
+
+	var grpcServer *grpc.Server
+	var grpcSvc *grpcService
+	if app.GRPCListener != nil {
+		var err error
+		grpcServer, grpcSvc, err = app.newGRPCServer()
+		if err != nil {
+			return err
+		}
+	}
+
 	shutdownError := make(chan error)
 
 	go func() {
@@ -36,6 +53,16 @@ func (app *application) serve() error {
 		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 		defer cancel()
 
+		if challenges != nil {
+			_ = challenges.Shutdown(ctx)
+		}
+
+		// Ends the event streams so the gRPC server stops gracefully
+		if grpcServer != nil {
+			grpcSvc.cancel()
+			grpcServer.GracefulStop()
+		}
+
 		err := app.Server.Shutdown(ctx)
 		if err != nil {
 			shutdownError <- err
@@ -48,9 +75,31 @@ func (app *application) serve() error {
 		shutdownError <- nil
 	}()
 
-	app.Logger.Info("Starting server", zap.String("address", app.Server.Addr))
+	if challenges != nil {
+		go func() {
+			app.Logger.Info("Starting ACME challenge server", zap.String("address", challenges.Addr))
+			if err := challenges.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				app.Logger.Error("ACME challenge server error", zap.Error(err))
+			}
+		}()
+	}
+
+	if grpcServer != nil {
+		go func() {
+			app.Logger.Info("Starting gRPC server", zap.String("address", app.GRPCListener.Addr().String()))
+			if err := grpcServer.Serve(app.GRPCListener); err != nil {
+				app.Logger.Error("gRPC server error", zap.Error(err))
+			}
+		}()
+	}
+
+	app.Logger.Info("Starting server",
+		zap.String("network", app.Listener.Addr().Network()),
+		zap.String("address", app.Listener.Addr().String()),
+		zap.Bool("tls", app.TLS != nil),
+	)
 
-	if err := app.Server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	if err := app.listen(); err != nil && err != http.ErrServerClosed {
 		app.Logger.Fatal("Server error", zap.Error(err))
 	}
 
@@ -62,3 +111,14 @@ func (app *application) serve() error {
 	app.Logger.Info("Server stopped gracefully")
 	return nil
 }
+
+// listen serves plain HTTP, or HTTPS with HTTP/2 when TLS is configured, on
+// the listener of the server
+func (app *application) listen() error {
+	if app.TLS == nil {
+		return app.Server.Serve(app.Listener)
+	}
+
+	// The certificate comes from the TLS configuration with Let's Encrypt
+	return app.Server.ServeTLS(app.Listener, app.TLS.certFile, app.TLS.keyFile)
+}
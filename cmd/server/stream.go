@@ -0,0 +1,187 @@
+// Package main is the entry point of the application
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/color"
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/game"
+	"github.com/tecu23/eng-server/pkg/server"
+)
+
+// streamHeartbeat keeps idle event streams open through proxies
+const streamHeartbeat = 15 * time.Second
+
+// lookupGame resolves the game of the request path, answering 404 when there is none
+func (app *application) lookupGame(w http.ResponseWriter, r *http.Request) (*game.Game, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid game id", http.StatusBadRequest)
+		return nil, false
+	}
+
+	session, ok := app.Manager.GetSession(id)
+	if !ok {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return nil, false
+	}
+
+	return session, true
+}
+
+// lookupPlayedGame resolves the game of the request path like lookupGame and
+// the color played by the key of the request, answering 403 unless the key
+// is the one of a player
+func (app *application) lookupPlayedGame(w http.ResponseWriter, r *http.Request, action string) (*game.Game, color.Color, bool) {
+	session, ok := app.lookupGame(w, r)
+	if !ok {
+		return nil, "", false
+	}
+
+	user, _ := app.Auth.User(app.apiKey(r))
+	clr, ok := session.ColorOfUser(user)
+	if !ok {
+		app.Logger.Warn("Request for the game of another player",
+			zap.String("request_id", r.Header.Get(requestIDHeader)),
+			zap.String("game_id", session.ID.String()),
+		)
+		http.Error(w, "Only the player can "+action, http.StatusForbidden)
+		return nil, "", false
+	}
+
+	return session, clr, true
+}
+
+// handleGameStream handles the GET /games/{id}/stream endpoint, sending the
+// events of a game as Server-Sent Events until the client goes away or the
+// session ends
+func (app *application) handleGameStream(w http.ResponseWriter, r *http.Request) {
+	session, ok := app.lookupGame(w, r)
+	if !ok {
+		return
+	}
+
+	// The stream outlives the server write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	stream := app.Hub.Subscribe(session.ID.String())
+	defer app.Hub.Unsubscribe(stream)
+
+	state, err := session.State()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusGone)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(msg messages.OutboundMessage) error {
+		data, err := server.EncodeEvent(msg)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	// Start with the current state so the client can render the board right away
+	if err := send(messages.OutboundMessage{Event: "GAME_STATE", Payload: state}); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case msg := <-stream.Events():
+			if err := send(msg); err != nil {
+				app.Logger.Info("Event stream closed", zap.String("game_id", stream.GameID), zap.Error(err))
+				return
+			}
+
+		case <-heartbeat.C:
+			if _, err := w.Write([]byte(": heartbeat\n\n")); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+
+		case <-session.Terminated():
+			return
+
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// handleGameMove handles the POST /games/{id}/moves endpoint. The engine
+// reply is delivered on the event stream of the game
+func (app *application) handleGameMove(w http.ResponseWriter, r *http.Request) {
+	session, clr, ok := app.lookupPlayedGame(w, r, "move")
+	if !ok {
+		return
+	}
+
+	var req messages.MoveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Move == "" {
+		http.Error(w, "Invalid move request", http.StatusBadRequest)
+		return
+	}
+
+	outcome, err := session.ProcessMove(clr, req.Move, req.MoveID, 0, r.Header.Get(requestIDHeader))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	// A retried move was played and answered by the engine the first time,
+	// the opponent of a game between players answers themselves
+	if outcome.Replayed || session.Mode == game.ModeHumanVsHuman {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	if err := session.RequestEngineMove(r.Header.Get(requestIDHeader)); err != nil {
+		app.Logger.Error("Could not request engine move",
+			zap.String("request_id", r.Header.Get(requestIDHeader)),
+			zap.Error(err),
+		)
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// handleGameResign handles the POST /games/{id}/resign endpoint
+func (app *application) handleGameResign(w http.ResponseWriter, r *http.Request) {
+	session, clr, ok := app.lookupPlayedGame(w, r, "resign")
+	if !ok {
+		return
+	}
+
+	if err := session.Resign(clr, r.Header.Get(requestIDHeader)); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,51 @@
+// Package main is the entry point of the application
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/corentings/chess/v2"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/tablebase"
+)
+
+// handleTablebaseProbe handles the GET /tablebase?fen=<fen> endpoint
+func (app *application) handleTablebaseProbe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if app.Tablebase == nil {
+		http.Error(w, "No tablebase is configured on this server", http.StatusNotFound)
+		return
+	}
+
+	fen := r.URL.Query().Get("fen")
+	if _, err := chess.FEN(fen); err != nil {
+		http.Error(w, "Invalid FEN: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := app.Tablebase.Probe(r.Context(), fen)
+	if errors.Is(err, tablebase.ErrTooManyPieces) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		app.Logger.Error("Tablebase probe failed", zap.String("fen", fen), zap.Error(err))
+		http.Error(w, "Tablebase probe failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(messages.TablebaseProbeResponse{
+		FEN: fen,
+		WDL: result.WDL,
+		DTZ: result.DTZ,
+	})
+}
@@ -0,0 +1,112 @@
+// Package main is the entry point of the application
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Defaults of the Let's Encrypt certificates, overridden by
+// TLS_AUTOCERT_CACHE_DIR and TLS_HTTP_ADDR
+const (
+	defaultAutocertCacheDir = "autocert"
+	defaultTLSHTTPAddr      = ":80"
+)
+
+// tlsSettings let the server terminate TLS itself, exposed without a reverse
+// proxy. HTTP/2 is negotiated over TLS for the REST and SSE routes, WebSocket
+// clients keep upgrading over HTTP/1.1
+type tlsSettings struct {
+	certFile string
+	keyFile  string
+
+	autocert *autocert.Manager // Certificates from Let's Encrypt, nil with certificate files
+	httpAddr string            // Answers the ACME HTTP-01 challenges and redirects to HTTPS, empty disables it
+}
+
+// tlsFromEnv reads the certificate from TLS_CERT_FILE and TLS_KEY_FILE, or
+// gets them from Let's Encrypt for the TLS_AUTOCERT_DOMAINS, cached in
+// TLS_AUTOCERT_CACHE_DIR. Nil serves plain HTTP
+func tlsFromEnv() (*tlsSettings, error) {
+	certFile, keyFile := os.Getenv("TLS_CERT_FILE"), os.Getenv("TLS_KEY_FILE")
+	domains := os.Getenv("TLS_AUTOCERT_DOMAINS")
+
+	switch {
+	case certFile != "" || keyFile != "":
+		if certFile == "" || keyFile == "" {
+			return nil, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+		}
+		if domains != "" {
+			return nil, errors.New("TLS_AUTOCERT_DOMAINS cannot be used with TLS_CERT_FILE")
+		}
+
+		// Loaded once here so a bad pair fails at startup
+		if _, err := tls.LoadX509KeyPair(certFile, keyFile); err != nil {
+			return nil, err
+		}
+		return &tlsSettings{certFile: certFile, keyFile: keyFile}, nil
+
+	case domains != "":
+		hosts := strings.Split(domains, ",")
+		for i, host := range hosts {
+			hosts[i] = strings.TrimSpace(host)
+		}
+
+		cacheDir := os.Getenv("TLS_AUTOCERT_CACHE_DIR")
+		if cacheDir == "" {
+			cacheDir = defaultAutocertCacheDir
+		}
+
+		httpAddr, ok := os.LookupEnv("TLS_HTTP_ADDR")
+		if !ok {
+			httpAddr = defaultTLSHTTPAddr
+		}
+
+		return &tlsSettings{
+			autocert: &autocert.Manager{
+				Prompt:     autocert.AcceptTOS,
+				HostPolicy: autocert.HostWhitelist(hosts...),
+				Cache:      autocert.DirCache(cacheDir),
+				Email:      os.Getenv("TLS_AUTOCERT_EMAIL"),
+			},
+			httpAddr: httpAddr,
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// config returns the TLS configuration of the server, answering the ACME
+// TLS-ALPN-01 challenges when certificates come from Let's Encrypt
+func (t *tlsSettings) config() *tls.Config {
+	if t.autocert != nil {
+		config := t.autocert.TLSConfig()
+		config.MinVersion = tls.VersionTLS12
+		return config
+	}
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+}
+
+// httpServer returns the plain HTTP server answering the ACME HTTP-01
+// challenges and redirecting everything else to HTTPS, nil when there is none
+func (t *tlsSettings) httpServer() *http.Server {
+	if t.autocert == nil || t.httpAddr == "" {
+		return nil
+	}
+
+	return &http.Server{
+		Addr:        t.httpAddr,
+		Handler:     t.autocert.HTTPHandler(nil),
+		IdleTimeout: 60 * time.Second,
+	}
+}
@@ -0,0 +1,25 @@
+// Package main is the entry point of the application
+package main
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// handleUCIProxy handles the /uci endpoint, a websocket carrying raw UCI
+// commands and engine output as text frames
+func (app *application) handleUCIProxy(w http.ResponseWriter, r *http.Request) {
+	if !app.UCIProxy.Enabled() {
+		http.Error(w, "UCI proxy is disabled", http.StatusNotFound)
+		return
+	}
+
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		app.Logger.Error("Failed to upgrade to WebSocket", zap.Error(err))
+		return
+	}
+
+	go app.UCIProxy.Serve(ws)
+}
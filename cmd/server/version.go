@@ -0,0 +1,40 @@
+// Package main is the entry point of the application
+package main
+
+import (
+	"net/http"
+
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/health"
+)
+
+// Build information set at build time, e.g.
+// -ldflags "-X main.version=1.2.0 -X main.commit=$(git rev-parse HEAD) -X main.buildDate=$(date -u +%FT%TZ)"
+var (
+	version   string
+	commit    string
+	buildDate string
+)
+
+// buildInfo returns the build of the server, falling back on what the Go
+// toolchain embedded for the values not set with ldflags
+func buildInfo() messages.BuildInfo {
+	build := health.ReadBuildInfo()
+
+	if version != "" {
+		build.Version = version
+	}
+	if commit != "" {
+		build.Commit = commit
+	}
+	if buildDate != "" {
+		build.BuildDate = buildDate
+	}
+
+	return build
+}
+
+// handleVersion handles the GET /version endpoint
+func (app *application) handleVersion(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, app.Build)
+}
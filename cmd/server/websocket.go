@@ -3,9 +3,11 @@ package main
 
 import (
 	"net/http"
+	"time"
 
 	"go.uber.org/zap"
 
+	"github.com/tecu23/eng-server/internal/messages"
 	"github.com/tecu23/eng-server/pkg/server"
 )
 
@@ -19,9 +21,35 @@ func (app *application) handleWebSocket(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Create and register connection
-	conn := server.NewConnection(ws, app.Hub, app.Publisher, app.Logger)
+	key := app.apiKey(r)
+	role, _ := app.Auth.Role(key)
+	user, _ := app.Auth.User(key)
+
+	// The REST routes accept a token for the key while the connection is open.
+	// Only a connection that presented the key itself gets one, a token must
+	// not mint fresh tokens that outlive it
+	var session *messages.SessionToken
+	var token string
+	if r.Header.Get("X-Api-Key") != "" {
+		var expires time.Time
+		token, expires, err = app.Sessions.Issue(key)
+		if err != nil {
+			app.Logger.Error("Failed to issue session token", zap.Error(err))
+		} else {
+			session = &messages.SessionToken{Token: token, ExpiresAt: expires}
+		}
+	}
+
+	conn := server.NewConnection(ws, app.Hub, app.Compression, role, key, user, session, app.Publisher, app.Logger)
 	app.Hub.Register(conn)
 
+	if session != nil {
+		go func() {
+			<-conn.Done()
+			app.Sessions.Revoke(token)
+		}()
+	}
+
 	app.Logger.Info("WebSocket connection established",
 		zap.String("remote_addr", r.RemoteAddr))
 
@@ -0,0 +1,105 @@
+// Command simulate plays many games at once against a running server over its
+// WebSocket API and reports how fast the engines answered. Started with the
+// built-in mock engine the server needs no engine binary, and a run with the
+// same seed plays the same games. All the connections come from one address,
+// so the rate limiter of the server is best disabled for the run:
+//
+//	ENGINE_PATH=mock:latency=50ms,jitter=20ms,seed=1 RATE_LIMIT_RPS=0 API_KEYS=key server
+//	simulate -url ws://localhost:8080/ws -key key -games 100 -moves 40
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/simulate"
+)
+
+func main() {
+	url := flag.String("url", "ws://localhost:8080/ws", "WebSocket endpoint of the server")
+	key := flag.String("key", os.Getenv("API_KEY"), "API key of the games, defaults to API_KEY")
+	games := flag.Int("games", 10, "games played at the same time")
+	moves := flag.Int("moves", 0, "moves of the player before resigning, 0 plays every game to its end")
+	think := flag.Duration("think", 0, "time the player takes before each move")
+	clock := flag.Duration("clock", 5*time.Minute, "clock of each side")
+	increment := flag.Duration("increment", 0, "increment of each side")
+	seed := flag.Int64("seed", 1, "seed of the player moves")
+	timeout := flag.Duration("timeout", 30*time.Minute, "bound of every game, 0 means no bound")
+	out := flag.String("out", "", "where to save the report as JSON, not saved when empty")
+	verbose := flag.Bool("v", false, "list every game")
+
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	report, err := simulate.Run(ctx, simulate.Config{
+		URL:     *url,
+		APIKey:  *key,
+		Games:   *games,
+		Moves:   *moves,
+		Think:   *think,
+		Seed:    *seed,
+		Timeout: *timeout,
+		TimeControl: messages.TimeControl{
+			WhiteTime:      clock.Milliseconds(),
+			BlackTime:      clock.Milliseconds(),
+			WhiteIncrement: increment.Milliseconds(),
+			BlackIncrement: increment.Milliseconds(),
+		},
+	})
+	if err != nil {
+		log.Fatalf("simulation failed: %v", err)
+	}
+
+	printReport(report, *verbose)
+
+	if *out != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatalf("could not encode report: %v", err)
+		}
+		if err := os.WriteFile(*out, data, 0o644); err != nil {
+			log.Fatalf("could not save report: %v", err)
+		}
+		fmt.Printf("\nReport saved to %s\n", *out)
+	}
+
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// printReport writes the totals, and the result of every game when verbose
+func printReport(r simulate.Report, verbose bool) {
+	if verbose {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "#\tgame\tresult\treason\tplies\ttime ms\terror")
+		for i, g := range r.Results {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%d\t%s\n", i+1, g.GameID, g.Result, g.Reason, g.Plies, g.DurationMs, g.Error)
+		}
+		_ = w.Flush()
+		fmt.Println()
+	}
+
+	fmt.Printf("Games: %d, finished %d, failed %d in %s\n", r.Games, r.Finished, r.Failed, time.Duration(r.DurationMs)*time.Millisecond)
+	fmt.Printf("Moves: %d (%.1f/s)\n", r.Moves, r.MovesPerS)
+	fmt.Printf("Engine replies: %d, p50 %dms, p95 %dms, p99 %dms, max %dms\n",
+		r.Latency.Replies, r.Latency.P50Ms, r.Latency.P95Ms, r.Latency.P99Ms, r.Latency.MaxMs)
+
+	if !verbose {
+		for i, g := range r.Results {
+			if g.Error != "" {
+				fmt.Printf("  game %d: %s\n", i+1, g.Error)
+			}
+		}
+	}
+}
@@ -0,0 +1,11 @@
+// Package docs embeds the API documentation served at /docs
+package docs
+
+//go:generate go run ../cmd/apidoc -root .. -out .
+
+import "embed"
+
+// Files are the documentation page and the generated AsyncAPI and OpenAPI documents
+//
+//go:embed index.html asyncapi.json openapi.json
+var Files embed.FS
@@ -0,0 +1,112 @@
+package apidoc
+
+import "reflect"
+
+// Info describes a generated document
+type Info struct {
+	Title       string
+	Version     string
+	Description string
+	Servers     []Server
+	Tags        []Tag // Groups of REST endpoints
+}
+
+// Server is a deployment of the server
+type Server struct {
+	Name        string // Key of the server in AsyncAPI documents
+	URL         string
+	Description string
+}
+
+// Tag groups REST endpoints
+type Tag struct {
+	Name        string
+	Description string
+}
+
+// Event is a websocket event and the type of its payload
+type Event struct {
+	Name        string
+	Description string
+	Payload     any // Zero value of the payload type, nil for events without payload
+}
+
+// AsyncAPI builds the AsyncAPI document of a websocket channel. The messages
+// are described as their envelope type, inbound for the events sent by the
+// clients and outbound for the events sent by the server
+func AsyncAPI(info Info, channel string, schemas *Schemas, inbound, outbound any, clientEvents, serverEvents []Event) Schema {
+	servers := Schema{}
+	for _, server := range info.Servers {
+		servers[server.Name] = Schema{
+			"url":         server.URL,
+			"protocol":    "ws",
+			"description": server.Description,
+		}
+	}
+
+	// An event sent both ways, e.g. a chat message, is keyed by its direction
+	names := map[string]int{}
+	for _, event := range append(append([]Event{}, clientEvents...), serverEvents...) {
+		names[event.Name]++
+	}
+
+	components := Schema{}
+	operation := func(summary, direction string, envelope any, events []Event) Schema {
+		refs := make([]Schema, 0, len(events))
+		for _, event := range events {
+			key := event.Name
+			if names[key] > 1 {
+				key += "_" + direction
+			}
+			components[key] = message(schemas, envelope, event)
+			refs = append(refs, Schema{"$ref": "#/components/messages/" + key})
+		}
+		return Schema{
+			"summary": summary,
+			"message": Schema{"oneOf": refs},
+		}
+	}
+
+	return Schema{
+		"asyncapi": "2.6.0",
+		"info": Schema{
+			"title":       info.Title,
+			"version":     info.Version,
+			"description": info.Description,
+		},
+		"servers":            servers,
+		"defaultContentType": "application/json",
+		"channels": Schema{
+			channel: Schema{
+				// Publish and subscribe are named from the client's point of view
+				"publish":   operation("Events sent by the client", "client", inbound, clientEvents),
+				"subscribe": operation("Events sent by the server", "server", outbound, serverEvents),
+			},
+		},
+		"components": Schema{
+			"messages": components,
+			"schemas":  schemas.Components(),
+		},
+	}
+}
+
+// message describes an event as its envelope with the event name and payload filled in
+func message(schemas *Schemas, envelope any, event Event) Schema {
+	payload := schemas.object(reflect.TypeOf(envelope))
+	properties := payload["properties"].(Schema)
+
+	properties["event"] = Schema{"type": "string", "enum": []string{event.Name}}
+	if event.Payload != nil {
+		properties["payload"] = schemas.Of(event.Payload)
+	} else {
+		delete(properties, "payload")
+	}
+	payload["required"] = []string{"event"}
+
+	return Schema{
+		"name":    event.Name,
+		"title":   event.Name,
+		"summary": event.Description,
+		"payload": payload,
+	}
+}
@@ -0,0 +1,138 @@
+// Package apidoc generates the AsyncAPI description of the websocket protocol
+// and the OpenAPI description of the REST endpoints from the Go message types
+package apidoc
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Docs holds what reflection cannot see of the message types: their doc
+// comments and the values of their string constants
+type Docs struct {
+	types  map[string]string   // Doc of a type, keyed by import path and name
+	fields map[string]string   // Doc of a struct field, keyed by type key and field name
+	enums  map[string][]string // Constant values of a string type, keyed by type key
+}
+
+// LoadDocs parses the sources of the given packages of a module, named by
+// their directory relative to the module root
+func LoadDocs(root, module string, packages ...string) (*Docs, error) {
+	docs := &Docs{
+		types:  make(map[string]string),
+		fields: make(map[string]string),
+		enums:  make(map[string][]string),
+	}
+
+	for _, dir := range packages {
+		fset := token.NewFileSet()
+		pkgs, err := parser.ParseDir(fset, filepath.Join(root, dir), func(info fs.FileInfo) bool {
+			return !strings.HasSuffix(info.Name(), "_test.go")
+		}, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+
+		path := module + "/" + filepath.ToSlash(dir)
+		for _, pkg := range pkgs {
+			for _, file := range pkg.Files {
+				docs.collect(path, file)
+			}
+		}
+	}
+
+	return docs, nil
+}
+
+// collect records the type, field and constant docs of a source file
+func (d *Docs) collect(path string, file *ast.File) {
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+
+		for _, spec := range gen.Specs {
+			switch spec := spec.(type) {
+			case *ast.TypeSpec:
+				key := path + "." + spec.Name.Name
+
+				doc := spec.Doc
+				if doc == nil && len(gen.Specs) == 1 {
+					doc = gen.Doc
+				}
+				if text := commentText(doc); text != "" {
+					d.types[key] = text
+				}
+
+				if st, ok := spec.Type.(*ast.StructType); ok {
+					d.collectFields(key, st)
+				}
+
+			case *ast.ValueSpec:
+				if gen.Tok != token.CONST {
+					continue
+				}
+				d.collectConstants(path, spec)
+			}
+		}
+	}
+}
+
+// collectFields records the docs of the fields of a struct, written above or
+// after the field
+func (d *Docs) collectFields(key string, st *ast.StructType) {
+	for _, field := range st.Fields.List {
+		text := commentText(field.Doc)
+		if text == "" {
+			text = commentText(field.Comment)
+		}
+		if text == "" {
+			continue
+		}
+
+		for _, name := range field.Names {
+			d.fields[key+"."+name.Name] = text
+		}
+	}
+}
+
+// collectConstants records the string constants declared with a named type
+func (d *Docs) collectConstants(path string, spec *ast.ValueSpec) {
+	ident, ok := spec.Type.(*ast.Ident)
+	if !ok {
+		return
+	}
+
+	for _, value := range spec.Values {
+		lit, ok := value.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			continue
+		}
+
+		if s, err := strconv.Unquote(lit.Value); err == nil {
+			key := path + "." + ident.Name
+			d.enums[key] = append(d.enums[key], s)
+		}
+	}
+}
+
+// AddEnum records the values of a string type whose constants are untyped
+func (d *Docs) AddEnum(t reflect.Type, values ...string) {
+	key := typeKey(t)
+	d.enums[key] = append(d.enums[key], values...)
+}
+
+// commentText flattens a comment into a single line
+func commentText(group *ast.CommentGroup) string {
+	if group == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(group.Text()), " ")
+}
@@ -0,0 +1,141 @@
+package apidoc
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Route is a REST endpoint
+type Route struct {
+	Method      string
+	Path        string // Path with {name} parameters, as given to http.ServeMux
+	Summary     string
+	Description string
+	Tag         string
+	Public      bool // Served without an API key
+	Params      []Param
+	Body        any    // Zero value of the request body, nil when it takes none
+	BodyType    string // Content type of the request body, application/json when empty
+	Responses   []Response
+}
+
+// Param is a path, query or header parameter of a route
+type Param struct {
+	Name        string
+	In          string // path, query or header
+	Description string
+	Required    bool
+	Schema      Schema
+}
+
+// Response is a possible answer of a route
+type Response struct {
+	Status      int
+	Description string
+	Body        any    // Zero value of the response body, nil when it has none
+	ContentType string // application/json when empty
+}
+
+// OpenAPI builds the OpenAPI document of REST routes
+func OpenAPI(info Info, schemas *Schemas, routes []Route) Schema {
+	servers := make([]Schema, 0, len(info.Servers))
+	for _, server := range info.Servers {
+		servers = append(servers, Schema{"url": server.URL, "description": server.Description})
+	}
+
+	tags := make([]Schema, 0, len(info.Tags))
+	for _, tag := range info.Tags {
+		tags = append(tags, Schema{"name": tag.Name, "description": tag.Description})
+	}
+
+	paths := Schema{}
+	for _, route := range routes {
+		item, ok := paths[route.Path].(Schema)
+		if !ok {
+			item = Schema{}
+			paths[route.Path] = item
+		}
+		item[strings.ToLower(route.Method)] = operation(schemas, route)
+	}
+
+	return Schema{
+		"openapi": "3.0.3",
+		"info": Schema{
+			"title":       info.Title,
+			"version":     info.Version,
+			"description": info.Description,
+		},
+		"servers": servers,
+		"tags":    tags,
+		"paths":   paths,
+		"components": Schema{
+			"schemas": schemas.Components(),
+			"securitySchemes": Schema{
+				"apiKey":       Schema{"type": "apiKey", "in": "header", "name": "X-Api-Key"},
+				"sessionToken": Schema{"type": "http", "scheme": "bearer", "description": "Token sent with CONNECTED"},
+			},
+		},
+	}
+}
+
+// operation describes a single route
+func operation(schemas *Schemas, route Route) Schema {
+	op := Schema{
+		"summary":     route.Summary,
+		"description": route.Description,
+		"tags":        []string{route.Tag},
+	}
+
+	if route.Public {
+		op["security"] = []Schema{}
+	} else {
+		op["security"] = []Schema{{"apiKey": []string{}}, {"sessionToken": []string{}}}
+	}
+
+	if len(route.Params) > 0 {
+		params := make([]Schema, 0, len(route.Params))
+		for _, param := range route.Params {
+			params = append(params, Schema{
+				"name":        param.Name,
+				"in":          param.In,
+				"description": param.Description,
+				"required":    param.Required || param.In == "path",
+				"schema":      param.Schema,
+			})
+		}
+		op["parameters"] = params
+	}
+
+	if route.Body != nil {
+		contentType := route.BodyType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		op["requestBody"] = Schema{
+			"required": true,
+			"content":  Schema{contentType: Schema{"schema": schemas.Of(route.Body)}},
+		}
+	}
+
+	responses := Schema{}
+	for _, response := range route.Responses {
+		description := response.Description
+		if description == "" {
+			description = http.StatusText(response.Status)
+		}
+
+		r := Schema{"description": description}
+		if response.Body != nil {
+			contentType := response.ContentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			r["content"] = Schema{contentType: Schema{"schema": schemas.Of(response.Body)}}
+		}
+		responses[strconv.Itoa(response.Status)] = r
+	}
+	op["responses"] = responses
+
+	return op
+}
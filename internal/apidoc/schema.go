@@ -0,0 +1,177 @@
+package apidoc
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON Schema object
+type Schema map[string]any
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	rawType  = reflect.TypeOf(json.RawMessage{})
+)
+
+// Schemas converts Go types into JSON Schemas, collecting the named structs
+// as components referenced from the other schemas
+type Schemas struct {
+	docs       *Docs
+	components map[string]Schema
+	types      map[reflect.Type]string // Component name of every collected struct
+}
+
+// NewSchemas returns an empty schema collection documented by docs
+func NewSchemas(docs *Docs) *Schemas {
+	return &Schemas{
+		docs:       docs,
+		components: make(map[string]Schema),
+		types:      make(map[reflect.Type]string),
+	}
+}
+
+// Of returns the schema of the type of v, a reference for named structs
+func (s *Schemas) Of(v any) Schema {
+	return s.schema(reflect.TypeOf(v))
+}
+
+// Components returns the schemas of the named structs met so far
+func (s *Schemas) Components() map[string]Schema {
+	return s.components
+}
+
+func (s *Schemas) schema(t reflect.Type) Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return Schema{"type": "string", "format": "date-time"}
+	case rawType:
+		return Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		return Schema{"$ref": "#/components/schemas/" + s.component(t)}
+
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return Schema{"type": "string", "format": "byte"}
+		}
+		return Schema{"type": "array", "items": s.schema(t.Elem())}
+
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": s.schema(t.Elem())}
+
+	case reflect.String:
+		schema := Schema{"type": "string"}
+		if values := s.docs.enums[typeKey(t)]; len(values) > 0 {
+			schema["enum"] = values
+		}
+		return schema
+
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+
+	case reflect.Int64, reflect.Uint64:
+		return Schema{"type": "integer", "format": "int64"}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return Schema{"type": "integer"}
+
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+
+	default:
+		// Interfaces hold any JSON value
+		return Schema{}
+	}
+}
+
+// component collects a named struct, returning its component name
+func (s *Schemas) component(t reflect.Type) string {
+	if name, ok := s.types[t]; ok {
+		return name
+	}
+
+	// Structs of different packages may share a name
+	name := t.Name()
+	if _, taken := s.components[name]; taken {
+		pkg := path.Base(t.PkgPath())
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+
+	// Registered before its fields, for recursive types
+	s.types[t] = name
+	s.components[name] = Schema{}
+
+	schema := s.object(t)
+	if doc := s.docs.types[typeKey(t)]; doc != "" {
+		schema["description"] = doc
+	}
+	s.components[name] = schema
+
+	return name
+}
+
+// object returns the schema of a struct with the fields encoding/json writes
+func (s *Schemas) object(t reflect.Type) Schema {
+	properties := Schema{}
+	s.fields(t, properties)
+	return Schema{"type": "object", "properties": properties}
+}
+
+// fields adds the JSON fields of a struct to properties, inlining embedded structs
+func (s *Schemas) fields(t reflect.Type, properties Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				s.fields(embedded, properties)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := s.schema(field.Type)
+		if doc := s.docs.fields[typeKey(t)+"."+field.Name]; doc != "" {
+			// Siblings of a reference are ignored, so the reference is wrapped
+			if _, ok := schema["$ref"]; ok {
+				schema = Schema{"allOf": []Schema{schema}}
+			}
+			schema["description"] = doc
+		}
+		properties[name] = schema
+	}
+}
+
+// typeKey identifies a named type in Docs
+func typeKey(t reflect.Type) string {
+	return t.PkgPath() + "." + t.Name()
+}
@@ -1,30 +1,82 @@
 package auth
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
 // APIKeyAuth provides a simple API key authentication
 type APIKeyAuth struct {
-	validKeys map[string]string
+	validKeys map[string]Role
+	users     map[string]string // User each key authenticates
+	open      bool              // Every key has the admin role, only ever set explicitly
 }
 
-// NewAPIKeyAuth creates a new API key authentication middleware
-func NewAPIKeyAuth(keys []string) *APIKeyAuth {
-	validKeys := make(map[string]string)
+// NewAPIKeyAuth creates a new API key authentication middleware. Plain keys
+// have the player role, coach, service and admin keys their own. A key
+// written user:key authenticates that user, any other key a user of its own.
+// Without admin keys no key has the admin role
+func NewAPIKeyAuth(keys, adminKeys, coachKeys, serviceKeys []string) *APIKeyAuth {
+	a := &APIKeyAuth{
+		validKeys: make(map[string]Role),
+		users:     make(map[string]string),
+	}
 	for _, key := range keys {
-		validKeys[key] = "valid"
+		a.add(key, RolePlayer)
+	}
+	for _, key := range coachKeys {
+		a.add(key, RoleCoach)
+	}
+	for _, key := range serviceKeys {
+		a.add(key, RoleService)
+	}
+	for _, key := range adminKeys {
+		a.add(key, RoleAdmin)
 	}
 
-	return &APIKeyAuth{
-		validKeys: validKeys,
+	return a
+}
+
+// OpenAdmin gives every key the admin role, for a development server set up
+// with a single key. Never use it on a server others can reach
+func (a *APIKeyAuth) OpenAdmin() {
+	a.open = true
+}
+
+// add registers a key, written user:key or key, with a role
+func (a *APIKeyAuth) add(entry string, role Role) {
+	user, key, bound := strings.Cut(entry, ":")
+	if !bound {
+		key = entry
+		user = keyUser(key)
 	}
+
+	a.validKeys[key] = role
+	a.users[key] = user
 }
 
-// AddKey adds a new valid API key
+// keyUser names the user of a key bound to no user, without revealing the key
+func keyUser(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "key-" + hex.EncodeToString(sum[:8])
+}
+
+// AddKey adds a new valid API key with the player role
 func (a *APIKeyAuth) AddKey(key string) {
-	a.validKeys[key] = "valid"
+	a.add(key, RolePlayer)
 }
 
 // RemoveKey removes a valid API key
 func (a *APIKeyAuth) RemoveKey(key string) {
 	delete(a.validKeys, key)
+	delete(a.users, key)
+}
+
+// User returns the user a key authenticates, false when the key is not valid
+func (a *APIKeyAuth) User(key string) (string, bool) {
+	user, valid := a.users[key]
+	return user, valid
 }
 
 // IsValidKey checks if a key is valid
@@ -32,3 +84,22 @@ func (a *APIKeyAuth) IsValidKey(key string) bool {
 	_, valid := a.validKeys[key]
 	return valid
 }
+
+// Role returns the role of a key, false when the key is not valid
+func (a *APIKeyAuth) Role(key string) (Role, bool) {
+	role, valid := a.validKeys[key]
+	if !valid {
+		return "", false
+	}
+
+	if a.open {
+		return RoleAdmin, true
+	}
+	return role, true
+}
+
+// Can checks if a key is valid and its role grants the permission
+func (a *APIKeyAuth) Can(key string, p Permission) bool {
+	role, valid := a.Role(key)
+	return valid && role.Can(p)
+}
@@ -0,0 +1,40 @@
+package auth
+
+// Role is the set of permissions attached to an API key
+type Role string
+
+const (
+	RolePlayer  Role = "player"  // Plays, watches and analyzes games
+	RoleCoach   Role = "coach"   // A player who may also annotate the games it watches
+	RoleService Role = "service" // A backend reading results and game histories, it plays no game
+	RoleAdmin   Role = "admin"   // Every permission
+)
+
+// Permission is an action restricted to some roles
+type Permission string
+
+const (
+	PermRead      Permission = "read"       // Ratings, engines, puzzles, replays and results
+	PermPlay      Permission = "play"       // Games, simuls, tournaments, hints and chat
+	PermAnalyze   Permission = "analyze"    // Engine analysis of positions and finished games
+	PermAnnotate  Permission = "annotate"   // Drawing on the boards of watched games
+	PermListGames Permission = "list_games" // Game history of every user at once
+	PermAdmin     Permission = "admin"      // Admin topic, matches and the admin routes
+)
+
+var rolePermissions = map[Role][]Permission{
+	RolePlayer:  {PermRead, PermPlay, PermAnalyze},
+	RoleCoach:   {PermRead, PermPlay, PermAnalyze, PermAnnotate},
+	RoleService: {PermRead, PermAnalyze, PermListGames},
+	RoleAdmin:   {PermRead, PermPlay, PermAnalyze, PermAnnotate, PermListGames, PermAdmin},
+}
+
+// Can reports whether the role grants the permission
+func (r Role) Can(p Permission) bool {
+	for _, granted := range rolePermissions[r] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
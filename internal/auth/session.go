@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"sync"
+	"time"
+)
+
+// SessionTokens are short lived tokens issued to WebSocket connections, which
+// browsers send to the REST routes instead of the API key they connected with
+type SessionTokens struct {
+	mu     sync.Mutex
+	ttl    time.Duration
+	tokens map[string]sessionToken
+}
+
+type sessionToken struct {
+	key     string
+	expires time.Time
+}
+
+// NewSessionTokens creates the token store, tokens expire ttl after they are issued
+func NewSessionTokens(ttl time.Duration) *SessionTokens {
+	return &SessionTokens{
+		ttl:    ttl,
+		tokens: make(map[string]sessionToken),
+	}
+}
+
+// Issue creates a token standing for the API key until it expires or is revoked
+func (s *SessionTokens) Issue(key string) (string, time.Time, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", time.Time{}, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	now := time.Now()
+	expires := now.Add(s.ttl)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Expired tokens are swept here, as each connection issues one
+	for t, st := range s.tokens {
+		if now.After(st.expires) {
+			delete(s.tokens, t)
+		}
+	}
+	s.tokens[token] = sessionToken{key: key, expires: expires}
+
+	return token, expires, nil
+}
+
+// Key returns the API key a token stands for, false once it expired or was revoked
+func (s *SessionTokens) Key(token string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.tokens[token]
+	if !ok || time.Now().After(st.expires) {
+		return "", false
+	}
+	return st.key, true
+}
+
+// Revoke invalidates a token before it expires
+func (s *SessionTokens) Revoke(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, token)
+}
@@ -0,0 +1,98 @@
+package messages
+
+import (
+	"reflect"
+	"time"
+)
+
+// EnvelopeVersion is the version of the envelope sent to the clients that
+// negotiate an eng.v2 subprotocol. Clients of the eng.v1 subprotocols, or of
+// none, keep receiving OutboundMessage
+const EnvelopeVersion = 2
+
+// Envelope wraps every outbound message of the eng.v2 subprotocols. The
+// envelope and its payloads name every field in snake_case, and every message
+// about a game carries its ID, whether or not the payload does
+type Envelope struct {
+	Version   int    `json:"v"` // EnvelopeVersion
+	Event     string `json:"event"`
+	GameID    string `json:"game_id,omitempty"` // Game the message is about, empty for messages about no game
+	Seq       int64  `json:"seq,omitempty"`     // Position of the event among the events of its game, as in OutboundMessage
+	Timestamp int64  `json:"ts"`                // Unix time in milliseconds the server sent the message
+	RequestID string `json:"request_id,omitempty"`
+	Payload   any    `json:"payload"`
+}
+
+// ClockUpdatePayloadV2 is ClockUpdatePayload in the naming of the envelope,
+// times are in milliseconds like everywhere else
+type ClockUpdatePayloadV2 struct {
+	GameID      string `json:"game_id"`
+	WhiteTime   int64  `json:"white_time"`
+	BlackTime   int64  `json:"black_time"`
+	ActiveColor string `json:"active_color"`
+	Running     bool   `json:"running"`
+	ServerTime  int64  `json:"server_time"`
+
+	LagCompensation int64 `json:"lag_compensation,omitempty"`
+}
+
+// GameOverPayloadV2 is GameOverPayload in the naming of the envelope
+type GameOverPayloadV2 struct {
+	GameID      string `json:"game_id"`
+	Reason      string `json:"reason"`
+	Result      string `json:"result"`
+	Description string `json:"description"`
+	PGN         string `json:"pgn"`
+
+	Rating         *RatingChange `json:"rating,omitempty"`
+	OpponentRating *RatingChange `json:"opponent_rating,omitempty"`
+
+	Certificate *ResultCertificate `json:"certificate,omitempty"`
+}
+
+// Envelope wraps the message in the envelope of the eng.v2 subprotocols,
+// renaming the payloads the first protocol spelled in camelCase
+func (m OutboundMessage) Envelope(sent time.Time) Envelope {
+	payload := m.Payload
+	switch p := payload.(type) {
+	case ClockUpdatePayload:
+		payload = ClockUpdatePayloadV2(p)
+	case GameOverPayload:
+		payload = GameOverPayloadV2(p)
+	}
+
+	gameID := m.GameID
+	if gameID == "" {
+		gameID = payloadGameID(m.Payload)
+	}
+
+	return Envelope{
+		Version:   EnvelopeVersion,
+		Event:     m.Event,
+		GameID:    gameID,
+		Seq:       m.Seq,
+		Timestamp: sent.UnixMilli(),
+		RequestID: m.RequestID,
+		Payload:   payload,
+	}
+}
+
+// payloadGameID returns the GameID field of a payload, empty when it has none
+func payloadGameID(payload any) string {
+	v := reflect.ValueOf(payload)
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+
+	f := v.FieldByName("GameID")
+	if !f.IsValid() || f.Kind() != reflect.String {
+		return ""
+	}
+	return f.String()
+}
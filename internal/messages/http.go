@@ -0,0 +1,60 @@
+package messages
+
+import (
+	"github.com/tecu23/eng-server/pkg/engine"
+	"github.com/tecu23/eng-server/pkg/rating"
+	"github.com/tecu23/eng-server/pkg/tablebase"
+)
+
+// ProbeResponse is the body of the liveness and readiness probes
+type ProbeResponse struct {
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"` // Dependency that is down, set when not ready
+}
+
+// TablebaseProbeResponse is the body returned by the GET /tablebase endpoint
+type TablebaseProbeResponse struct {
+	FEN string        `json:"fen"`
+	WDL tablebase.WDL `json:"wdl"` // Value for the side to move
+	DTZ int           `json:"dtz"` // Distance to zeroing the 50-move counter
+}
+
+// UserRatingResponse is the body returned by the GET /users/{id}/rating endpoint
+type UserRatingResponse struct {
+	UserID string `json:"user_id"`
+	rating.Rating
+}
+
+// EnginesResponse is the body returned by the GET /engines endpoint
+type EnginesResponse struct {
+	Engines []engine.Metadata `json:"engines"`
+}
+
+// EngineProfilesResponse is the body returned by the GET /engine-profiles endpoint
+type EngineProfilesResponse struct {
+	Profiles []engine.Profile `json:"profiles"`
+}
+
+// EngineProfileRequest is the body of the PUT /admin/engine-profiles/{name} endpoint
+type EngineProfileRequest struct {
+	Options map[string]string `json:"options"` // UCI option names and values, e.g. Hash=2048
+}
+
+// MoveRequest is the body of the POST /games/{id}/moves endpoint
+type MoveRequest struct {
+	Move   string `json:"move"`              // Move in UCI or SAN notation
+	MoveID string `json:"move_id,omitempty"` // Client generated ID, a retry with the ID of a played move is not played again
+}
+
+// ResultKeyResponse is the body returned by the GET /results/key endpoint
+type ResultKeyResponse struct {
+	Algorithm string `json:"algorithm"`            // hmac-sha256 or ed25519
+	KeyID     string `json:"key_id"`               // Key ID of the certificates signed with the key
+	PublicKey string `json:"public_key,omitempty"` // PEM public key, empty for an HMAC key which is not shared
+}
+
+// ResultVerificationResponse is the body returned by the POST /results/verify endpoint
+type ResultVerificationResponse struct {
+	Valid  bool   `json:"valid"`
+	Reason string `json:"reason,omitempty"` // Why the certificate is not valid
+}
@@ -5,24 +5,274 @@ import "encoding/json"
 // InboundMessage is the generic wrapper for messages coming from the client.
 // The "type" field tells us the action; "payload" is the data we parse further.
 type InboundMessage struct {
-	Event   string          `json:"event"`
-	Payload json.RawMessage `json:"payload"`
+	Event     string          `json:"event"`
+	Payload   json.RawMessage `json:"payload"`
+	RequestID string          `json:"request_id,omitempty"` // Correlation ID, assigned by the server when the client sends none
+}
+
+// TimeControl represents the clock settings requested for a game
+type TimeControl struct {
+	WhiteTime      int64 `json:"white_time"`
+	BlackTime      int64 `json:"black_time"`
+	WhiteIncrement int64 `json:"white_increment"`
+	BlackIncrement int64 `json:"black_increment"`
+}
+
+// OpeningBookOptions enables the server opening book for a game
+type OpeningBookOptions struct {
+	MaxPly    int    `json:"max_ply"`   // Book moves are only played within this many plies, 0 means no limit
+	Selection string `json:"selection"` // One of best, weighted or uniform
+}
+
+// TimeManagementOptions limits the searches of the engine, e.g. to fast fixed
+// searches for casual games
+type TimeManagementOptions struct {
+	Policy     string `json:"policy"`      // clock, movetime, depth or nodes
+	MoveTimeMs int64  `json:"movetime_ms"` // Search time per move of the movetime policy, at most a minute
+	Depth      int    `json:"depth"`       // Search depth of the depth policy, at most 60
+	Nodes      int64  `json:"nodes"`       // Searched nodes of the nodes policy
+}
+
+// PacingOptions delays the replies of the engine so they feel less robotic,
+// the delay grows with the complexity of the position
+type PacingOptions struct {
+	MinDelayMs int64 `json:"min_delay_ms"` // Shortest reply time, forced moves are still played at once
+	MaxDelayMs int64 `json:"max_delay_ms"` // Longest reply time, at most 10 seconds
 }
 
 // StartNewGamePayload represents the payload for creating a new game
 type CreateSession struct {
-	TimeControl struct {
-		WhiteTime      int64 `json:"white_time"`
-		BlackTime      int64 `json:"black_time"`
-		WhiteIncrement int64 `json:"white_increment"`
-		BlackIncrement int64 `json:"black_increment"`
-	} `json:"time_control"`
-	Color      string `json:"color"`
-	InitialFen string `json:"initial_fen"`
+	TimeControl TimeControl         `json:"time_control"`
+	Color       string              `json:"color"` // w or b, the color played by the user, White when empty
+	InitialFen  string              `json:"initial_fen"`
+	OpeningBook *OpeningBookOptions `json:"opening_book,omitempty"`
+
+	TimeManagement *TimeManagementOptions `json:"time_management,omitempty"` // Engine searches use the clock when omitted
+
+	// Strength of the engine: beginner, casual, intermediate, advanced, expert
+	// or master. Sets the engine Elo or skill level, caps its search time and
+	// picks the book selection when none is given. The easiest levels now and
+	// then play a slightly weaker candidate move. Full strength when empty
+	Difficulty string `json:"difficulty,omitempty"`
+
+	Pacing *PacingOptions `json:"pacing,omitempty"` // Engine replies as soon as it found its move when omitted, unless the difficulty paces it
+
+	Profile string `json:"engine_profile,omitempty"` // Named engine options set up by the operators, e.g. bullet
+
+	Variant          string `json:"variant"`                     // standard, chess960, crazyhouse, kingofthehill or 3check, empty means standard
+	Chess960Position *int   `json:"chess960_position,omitempty"` // Chess960 start position number, random when omitted
+
+	UserID string `json:"user_id,omitempty"` // User of the API key the connection authenticated with, which plays the game. Optional, rejected when it names another user
+	Rated  bool   `json:"rated"`             // Whether the game counts for the rating of the user
+
+	Analysis bool `json:"analysis"` // Analyze the game with the engine once it is over
+
+	// Seed of the random choices of the game: book moves, weaker candidates,
+	// reply delays and the Chess960 position. Random when 0. With
+	// deterministic the engines search on one thread and a fixed number of
+	// nodes per move unless a node or depth limit is set, so the same seed and
+	// moves of the player replay the game
+	Seed          int64 `json:"seed,omitempty"`
+	Deterministic bool  `json:"deterministic,omitempty"`
+}
+
+// CreateExhibitionPayload represents the payload for starting an engine vs engine game
+type CreateExhibitionPayload struct {
+	TimeControl TimeControl         `json:"time_control"`
+	InitialFen  string              `json:"initial_fen"`
+	OpeningBook *OpeningBookOptions `json:"opening_book,omitempty"`
+
+	Seed          int64 `json:"seed,omitempty"`          // Seed of the book choices, random when 0
+	Deterministic bool  `json:"deterministic,omitempty"` // Fixed nodes and one thread per engine, the same seed replays the game
+}
+
+// CreateSimulPayload starts a simul, a single engine plays every board against the player
+type CreateSimulPayload struct {
+	Boards      int         `json:"boards"` // At most 20
+	TimeControl TimeControl `json:"time_control"`
+	Color       string      `json:"color,omitempty"`      // w or b, the color the player takes on every board, Black when empty
+	Difficulty  string      `json:"difficulty,omitempty"` // Strength of the engine on every board
+	UserID      string      `json:"user_id,omitempty"`    // User of the API key of the connection, rejected when it names another user
+}
+
+// Arrow is an arrow drawn on the board between two squares
+type Arrow struct {
+	From  string `json:"from"`  // Square in algebraic notation, e.g. e2
+	To    string `json:"to"`    // Square in algebraic notation, e.g. e4
+	Color string `json:"color"` // green, red, yellow or blue, green when empty
+}
+
+// Highlight is a colored square of the board
+type Highlight struct {
+	Square string `json:"square"` // Square in algebraic notation, e.g. d5
+	Color  string `json:"color"`  // green, red, yellow or blue, green when empty
+}
+
+// AnnotatePayload represents the payload of a coach drawing on the board of a game
+type AnnotatePayload struct {
+	GameID     string      `json:"game_id"`
+	Arrows     []Arrow     `json:"arrows,omitempty"`     // At most 16
+	Highlights []Highlight `json:"highlights,omitempty"` // At most 64
+	Comment    string      `json:"comment,omitempty"`    // At most 500 characters
+	Clear      bool        `json:"clear"`                // Remove the earlier annotations before drawing these
+}
+
+// ChatPayload represents the payload of a chat message sent to a game
+type ChatPayload struct {
+	GameID  string `json:"game_id"`
+	Channel string `json:"channel"` // players or spectators
+	Text    string `json:"text"`    // At most 300 characters
+}
+
+// SetPreferencesPayload chooses the updates sent to a connection, replacing
+// the preferences it set before
+type SetPreferencesPayload struct {
+	Mute []string `json:"mute,omitempty"` // Event classes not sent: clock, eval, presence, chat or annotations
+	Eval string   `json:"eval,omitempty"` // all or final, final only sends the evaluations of finished searches. all when empty
+}
+
+// ResyncPayload asks for the events of a game missed after the last one seen
+type ResyncPayload struct {
+	GameID  string `json:"game_id"`
+	LastSeq int64  `json:"last_seq"` // Sequence number of the last event the client received, 0 for every event
+}
+
+// SpectatePayload represents the payload for watching a game
+type SpectatePayload struct {
+	GameID string `json:"game_id"`
 }
 
 // MakeMovePayload represents the payload for making a move during a game
 type MakeMovePayload struct {
 	GameID string `json:"game_id"`
 	Move   string `json:"move"`
+	MoveID string `json:"move_id,omitempty"` // Client generated ID, a retry with the ID of a played move is answered with MOVE_PROCESSED instead of playing it again
+}
+
+// PremovePayload represents a move queued while the engine is thinking,
+// an empty move cancels the queued premove
+type PremovePayload struct {
+	GameID string `json:"game_id"`
+	Move   string `json:"move"`
+}
+
+// GetGameStatePayload represents the payload for querying the state of a game
+type GetGameStatePayload struct {
+	GameID string `json:"game_id"`
+}
+
+// TakebackRequestPayload represents the payload for undoing the last move
+type TakebackRequestPayload struct {
+	GameID string `json:"game_id"`
+}
+
+// ResumeGamePayload represents the payload for taking over a game restored
+// after a server restart
+type ResumeGamePayload struct {
+	GameID string `json:"game_id"`
+}
+
+// ClaimGamePayload moves a game to the connection sending it, e.g. when the
+// player switches from their phone to their desktop mid-game
+type ClaimGamePayload struct {
+	GameID   string `json:"game_id"`
+	Previous string `json:"previous,omitempty"` // spectate, the default, keeps the old connection watching the game, close closes it
+}
+
+// ClaimVictoryPayload ends a game between players whose opponent left it
+// and stayed away for the disconnect grace period
+type ClaimVictoryPayload struct {
+	GameID string `json:"game_id"`
+}
+
+// CreateSeekPayload opens a seek, a game offered to the other players until
+// one of them accepts it
+type CreateSeekPayload struct {
+	TimeControl TimeControl `json:"time_control"`
+	Variant     string      `json:"variant"`              // standard, chess960, crazyhouse, kingofthehill or 3check, empty means standard
+	Color       string      `json:"color,omitempty"`      // w or b, the color played by the user, random when empty
+	MinRating   int         `json:"min_rating,omitempty"` // Lowest rating of an opponent, 0 for no bound
+	MaxRating   int         `json:"max_rating,omitempty"` // Highest rating of an opponent, 0 for no bound
+	Rated       bool        `json:"rated"`                // Whether the game counts for the rating of both players
+}
+
+// SeekRequestPayload refers to a seek, to accept or cancel it
+type SeekRequestPayload struct {
+	SeekID string `json:"seek_id"`
+}
+
+// CreateTournamentPayload opens a tournament against the engine or between players
+type CreateTournamentPayload struct {
+	Name        string      `json:"name"`
+	Format      string      `json:"format"`              // arena or swiss
+	Opponents   string      `json:"opponents,omitempty"` // engine, the default, or players
+	TimeControl TimeControl `json:"time_control"`
+	Difficulty  string      `json:"difficulty,omitempty"`  // Level every player starts at against the engine, intermediate when empty
+	Rounds      int         `json:"rounds,omitempty"`      // Rounds of a Swiss tournament, at most 15
+	DurationMs  int64       `json:"duration_ms,omitempty"` // Length of an arena, at most 3 hours
+}
+
+// JoinTournamentPayload adds the user of the connection to a tournament
+type JoinTournamentPayload struct {
+	TournamentID string `json:"tournament_id"`
+	UserID       string `json:"user_id,omitempty"` // Must be the user of the API key when set
+}
+
+// TournamentRequestPayload refers to a tournament, to start it or leave it
+type TournamentRequestPayload struct {
+	TournamentID string `json:"tournament_id"`
+	UserID       string `json:"user_id,omitempty"` // User leaving the tournament, must be the user of the API key when set
+}
+
+// StartMatchPayload represents the payload for starting an engine match
+type StartMatchPayload struct {
+	EngineA     string      `json:"engine_a"`
+	EngineB     string      `json:"engine_b"`
+	Games       int         `json:"games"`
+	TimeControl TimeControl `json:"time_control"`
+	Openings    []string    `json:"openings"` // Optional opening suite as FENs
+
+	Adjudication *AdjudicationOptions `json:"adjudication,omitempty"` // Overrides the server rules
+}
+
+// AdjudicationOptions configures when engine games are ended early
+type AdjudicationOptions struct {
+	DrawMoveNumber  int `json:"draw_move_number"`  // Draw adjudication starts at this full move number
+	DrawMoveCount   int `json:"draw_move_count"`   // Moves within the draw score, 0 disables draws
+	DrawScore       int `json:"draw_score"`        // Largest absolute score in centipawns considered drawn
+	ResignMoveCount int `json:"resign_move_count"` // Moves both engines agree on the resign score, 0 disables it
+	ResignScore     int `json:"resign_score"`      // Score in centipawns at which the losing side resigns
+}
+
+// RequestHintPayload asks for the best move in the current position
+type RequestHintPayload struct {
+	GameID string `json:"game_id"`
+	Depth  int    `json:"depth"` // Search depth, 0 or above the server limit uses the server depth
+}
+
+// StartAnalysisPayload starts a live analysis of a position
+type StartAnalysisPayload struct {
+	FEN     string `json:"fen"`
+	MultiPV int    `json:"multipv"` // Candidate lines to report, 1 when omitted
+	Depth   int    `json:"depth"`   // Depth to stop at, 0 searches until STOP_ANALYSIS
+
+	Profile string `json:"engine_profile,omitempty"` // Named engine options set up by the operators
+}
+
+// StopAnalysisPayload stops a live analysis
+type StopAnalysisPayload struct {
+	AnalysisID string `json:"analysis_id"`
+}
+
+// ListGamesPayload queries the game history, empty filters match every game
+type ListGamesPayload struct {
+	UserID      string `json:"user_id"`
+	Result      string `json:"result"`       // win, loss or draw for the user
+	Color       string `json:"color"`        // w or b, the color played by the user
+	Engine      string `json:"engine"`       // Name of an engine that played the game
+	Since       string `json:"since"`        // RFC 3339 time, games ended at or after it
+	Until       string `json:"until"`        // RFC 3339 time, games ended before it
+	TimeControl string `json:"time_control"` // Initial time and increment in seconds, e.g. 300+2
+	Cursor      string `json:"cursor"`       // next_cursor of the previous page
+	Limit       int    `json:"limit"`        // Games per page, at most 100
 }
@@ -1,22 +1,33 @@
 package messages
 
 import (
+	"time"
+
 	"github.com/tecu23/eng-server/internal/color"
 )
 
 // OutboundMessage is how we wrap responses before sending
 // them to the client
 type OutboundMessage struct {
-	Event   string      `json:"event"`
-	Payload interface{} `json:"payload"`
+	Event     string      `json:"event"`
+	Payload   interface{} `json:"payload"`
+	RequestID string      `json:"request_id,omitempty"` // Request this message answers, empty for pushed events
+	Seq       int64       `json:"seq,omitempty"`        // Position of the event among the events of its game, from 1. 0 for messages about no game
+	GameID    string      `json:"-"`                    // Game the message is about, sent in the envelope of the eng.v2 subprotocols
 }
 
-// ClockUpdatePayload contains information about the current state of the clock
+// ClockUpdatePayload is the authoritative state of the clock, sent when the
+// turn changes and as a periodic heartbeat. Clients run the active clock
+// locally from ServerTime until the next update
 type ClockUpdatePayload struct {
 	GameID      string `json:"gameId"`
 	WhiteTime   int64  `json:"whiteTimeMs"`
 	BlackTime   int64  `json:"blackTimeMs"`
 	ActiveColor string `json:"activeColor"`
+	Running     bool   `json:"running"`      // Whether the active clock is running down
+	ServerTime  int64  `json:"serverTimeMs"` // Unix time in milliseconds the times were measured at
+
+	LagCompensation int64 `json:"lagCompensationMs,omitempty"` // Lag credited to the player who moved last
 }
 
 // GameOverPayload contains the information about the state on an ended game
@@ -25,6 +36,332 @@ type GameOverPayload struct {
 	Reason      string `json:"reason"`
 	Result      string `json:"result"`
 	Description string `json:"description"`
+	PGN         string `json:"pgn"` // Full game record
+
+	Rating         *RatingChange `json:"rating,omitempty"`          // Set for rated games
+	OpponentRating *RatingChange `json:"opponent_rating,omitempty"` // Set for rated games between players, the change of the other player
+
+	Certificate *ResultCertificate `json:"certificate,omitempty"` // Set when the server signs results
+}
+
+// ResultCertificate is the result of a finished game signed by the server,
+// so systems the result is handed to can tell it was not forged by a client
+type ResultCertificate struct {
+	GameID    string `json:"game_id"`
+	Result    string `json:"result"`
+	Reason    string `json:"reason"`
+	FinalFEN  string `json:"final_fen"`
+	MovesHash string `json:"moves_hash"` // Hex SHA-256 of the moves in UCI notation, separated by spaces
+	WhiteTime int64  `json:"white_time"` // Milliseconds left on the clocks when the game ended
+	BlackTime int64  `json:"black_time"`
+	EndedAt   int64  `json:"ended_at"`  // Unix time in milliseconds
+	Algorithm string `json:"algorithm"` // hmac-sha256 or ed25519
+	KeyID     string `json:"key_id"`    // Identifies the signing key across rotations
+	Signature string `json:"signature"` // Base64 signature of the other fields
+}
+
+// HintPayload is the move suggested to the player, scores are from the player's point of view
+type HintPayload struct {
+	GameID    string `json:"game_id"`
+	Move      string `json:"move,omitempty"` // Suggested move in UCI notation
+	SAN       string `json:"san,omitempty"`
+	Depth     int    `json:"depth"`
+	ScoreCP   int    `json:"score_cp"`
+	Mate      int    `json:"mate"`       // Moves to mate, 0 when no mate was found
+	HintsLeft int    `json:"hints_left"` // Hints the player may still request in this game
+	Error     string `json:"error,omitempty"`
+}
+
+// AnalysisJob describes a game queued for analysis
+type AnalysisJob struct {
+	JobID         string    `json:"job_id"`
+	GameID        string    `json:"game_id"`
+	Priority      string    `json:"priority"`                 // live, report, review or import, from the most urgent
+	Status        string    `json:"status"`                   // queued, running, completed, failed or canceled
+	QueuePosition int       `json:"queue_position,omitempty"` // Place among the waiting jobs, 1 runs next
+	Moves         int       `json:"moves"`                    // Half-moves to analyze
+	Error         string    `json:"error,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	StartedAt     time.Time `json:"started_at"`
+	FinishedAt    time.Time `json:"finished_at"`
+}
+
+// AnalysisJobsPayload lists analysis jobs
+type AnalysisJobsPayload struct {
+	Jobs []AnalysisJob `json:"jobs"`
+}
+
+// AnalysisBatch is the progress of the games of an imported PGN file
+type AnalysisBatch struct {
+	BatchID   string        `json:"batch_id"`
+	Status    string        `json:"status"` // queued, running or completed once every game is done
+	Games     int           `json:"games"`
+	Completed int           `json:"completed"`
+	Failed    int           `json:"failed"`
+	Canceled  int           `json:"canceled"`
+	CreatedAt time.Time     `json:"created_at"`
+	Jobs      []AnalysisJob `json:"jobs"`
+}
+
+// AnalysisReportPayload is the engine review of a finished game
+type AnalysisReportPayload struct {
+	GameID string         `json:"game_id"`
+	Depth  int            `json:"depth"` // Search depth of every position
+	Moves  []MoveAnalysis `json:"moves"`
+	White  SideAnalysis   `json:"white"`
+	Black  SideAnalysis   `json:"black"`
+}
+
+// MoveAnalysis is the review of a single move
+type MoveAnalysis struct {
+	Ply            int         `json:"ply"`
+	Color          color.Color `json:"color"`
+	Move           string      `json:"move"` // Played move in UCI notation
+	SAN            string      `json:"san"`
+	Eval           int         `json:"eval"`                     // Centipawns after the move from White's point of view, capped at 1000
+	BestMove       string      `json:"best_move"`                // Engine choice in UCI notation
+	CPLoss         int         `json:"cp_loss"`                  // Centipawns lost compared to the engine choice
+	Classification string      `json:"classification,omitempty"` // inaccuracy, mistake or blunder
+}
+
+// SideAnalysis summarizes the play of one side
+type SideAnalysis struct {
+	Accuracy      float64 `json:"accuracy"` // 0 to 100
+	AverageCPLoss int     `json:"average_cp_loss"`
+	Inaccuracies  int     `json:"inaccuracies"`
+	Mistakes      int     `json:"mistakes"`
+	Blunders      int     `json:"blunders"`
+}
+
+// FairPlaySubject is the player of a finished human game screened for engine assistance
+type FairPlaySubject struct {
+	GameID    string      `json:"game_id"`
+	UserID    string      `json:"user_id,omitempty"`
+	Color     color.Color `json:"color"`
+	Rated     bool        `json:"rated"`
+	MoveTimes []int64     `json:"move_times"` // Milliseconds spent on each move of the game, by ply
+}
+
+// FairPlayReport compares the moves of a player with the engine choices and
+// describes how long the player took to move
+type FairPlayReport struct {
+	GameID        string      `json:"game_id"`
+	UserID        string      `json:"user_id,omitempty"`
+	Color         color.Color `json:"color"`
+	Rated         bool        `json:"rated"`
+	Moves         int         `json:"moves"`        // Moves of the player screened, the opening left out
+	EngineMatch   float64     `json:"engine_match"` // Percentage of the screened moves that were the engine choice
+	AverageCPLoss int         `json:"average_cp_loss"`
+	MoveTimes     MoveTimes   `json:"move_times"`
+	Flagged       bool        `json:"flagged"`
+	Reasons       []string    `json:"reasons,omitempty"` // Why the game was flagged: engine_match, low_cp_loss or uniform_move_times
+	AnalyzedAt    time.Time   `json:"analyzed_at"`
+}
+
+// MoveTimes is the distribution of the time a player spent on the screened moves
+type MoveTimes struct {
+	MeanMs    int64   `json:"mean_ms"`
+	StdDevMs  int64   `json:"std_dev_ms"`
+	MinMs     int64   `json:"min_ms"`
+	MaxMs     int64   `json:"max_ms"`
+	Variation float64 `json:"variation"` // Standard deviation over the mean, humans vary their pace far more than engines
+	Buckets   []int   `json:"buckets"`   // Moves made in under 1s, 1-3s, 3-10s, 10-30s and 30s or more
+}
+
+// FairPlayReportsPayload lists the fair play reports, the most recent first
+type FairPlayReportsPayload struct {
+	Reports []FairPlayReport `json:"reports"`
+}
+
+// Puzzle is a tactic found in the blunder of an analyzed game, the solver has
+// a single winning move at every step of the solution
+type Puzzle struct {
+	ID         string      `json:"id"`
+	GameID     string      `json:"game_id"`
+	Ply        int         `json:"ply"`        // Ply of the blunder in the game
+	FEN        string      `json:"fen"`        // Position after the blunder, the solver is to move
+	Color      color.Color `json:"color"`      // Side of the solver
+	Blunder    string      `json:"blunder"`    // Move that allowed the tactic, in UCI notation
+	Solution   []string    `json:"solution"`   // Moves of the solver and the replies in between, in UCI notation
+	Mate       bool        `json:"mate"`       // Whether the solution forces mate
+	Rating     int         `json:"rating"`     // Estimated rating of the puzzle
+	Difficulty string      `json:"difficulty"` // easy, medium or hard
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+// PuzzlesListPayload is a page of puzzles
+type PuzzlesListPayload struct {
+	Puzzles []Puzzle `json:"puzzles"`
+}
+
+// ExplorerStats counts the finished games that reached a position or played a move
+type ExplorerStats struct {
+	Games int `json:"games"`
+	White int `json:"white"` // Games won by White
+	Draws int `json:"draws"`
+	Black int `json:"black"` // Games won by Black
+}
+
+// ExplorerMove is a move played from a position of the opening explorer
+type ExplorerMove struct {
+	UCI string `json:"uci"`
+	SAN string `json:"san"`
+	ExplorerStats
+}
+
+// ExplorerPayload is a position of the opening explorer with the moves played from it
+type ExplorerPayload struct {
+	FEN string `json:"fen"`
+	ExplorerStats
+	Moves []ExplorerMove `json:"moves"` // Most played first
+}
+
+// AnalysisStartedPayload identifies a live analysis started by START_ANALYSIS
+type AnalysisStartedPayload struct {
+	AnalysisID string `json:"analysis_id"`
+	FEN        string `json:"fen"`
+	MultiPV    int    `json:"multipv"`
+	Shared     bool   `json:"shared"` // Joined the running search of the same position instead of starting one
+}
+
+// AnalysisUpdatePayload carries the best candidate lines of a live analysis
+type AnalysisUpdatePayload struct {
+	AnalysisID string         `json:"analysis_id"`
+	FEN        string         `json:"fen"`
+	Depth      int            `json:"depth"`
+	Lines      []AnalysisLine `json:"lines"` // Best line first
+	Final      bool           `json:"final"` // Set on the last update of a search limited by depth
+}
+
+// AnalysisLine is a candidate continuation, scores are from the point of view of the side to move
+type AnalysisLine struct {
+	MultiPV int      `json:"multipv"` // Rank of the line, 1 for the best one
+	Move    string   `json:"move"`    // First move of the line in UCI notation
+	Depth   int      `json:"depth"`
+	ScoreCP int      `json:"score_cp"`
+	Mate    int      `json:"mate"` // Moves to mate, 0 when no mate was found
+	PV      []string `json:"pv"`
+}
+
+// GameRecord summarizes a finished game in the game history
+type GameRecord struct {
+	GameID        string      `json:"game_id"`
+	Mode          string      `json:"mode"`
+	Variant       string      `json:"variant"`
+	UserID        string      `json:"user_id,omitempty"`
+	PlayerColor   color.Color `json:"player_color,omitempty"` // Color of the user, empty for engine only games
+	OpponentID    string      `json:"opponent_id,omitempty"`  // User playing the other color of a game between players
+	Engines       []string    `json:"engines"`                // Engines that played the game
+	TimeControl   string      `json:"time_control"`           // Initial time and increment in seconds, e.g. 300+2
+	Rated         bool        `json:"rated"`
+	Result        string      `json:"result"`
+	Reason        string      `json:"reason"`
+	StartedAt     time.Time   `json:"started_at"`
+	EndedAt       time.Time   `json:"ended_at"`
+	PGN           string      `json:"pgn"`
+	Seed          int64       `json:"seed"`                    // Replays the game with the same options
+	Deterministic bool        `json:"deterministic,omitempty"` // Whether the engines searched reproducibly
+
+	Analysis *AnalysisReportPayload `json:"analysis,omitempty"` // Set once a requested analysis completed
+}
+
+// EngineTranscript is the UCI dialogue of the engines of a game
+type EngineTranscript struct {
+	GameID  string           `json:"game_id"`
+	Dropped int              `json:"dropped"` // Oldest lines dropped to keep the transcript bounded
+	Lines   []TranscriptLine `json:"lines"`
+}
+
+// TranscriptLine is a command sent to an engine or a line it wrote
+type TranscriptLine struct {
+	Time      time.Time `json:"time"`
+	EngineID  string    `json:"engine_id"`
+	Direction string    `json:"direction"` // sent or received
+	Line      string    `json:"line"`
+}
+
+// MoveTelemetry describes the search behind a move played by an engine
+type MoveTelemetry struct {
+	GameID     string    `json:"game_id"`
+	Engine     string    `json:"engine"`           // Name the engine reported
+	Config     string    `json:"config,omitempty"` // Options set on the engine, e.g. Hash=64,Threads=2
+	Ply        int       `json:"ply"`              // Half-move the engine played
+	Move       string    `json:"move"`
+	Depth      int       `json:"depth"`
+	Nodes      int64     `json:"nodes"`
+	TimeMs     int64     `json:"time_ms"`        // Time from the search start to the best move
+	Eval       *int      `json:"eval,omitempty"` // Centipawns from the engine's point of view, nil when it reported no score
+	LateChange bool      `json:"late_change"`    // The best move changed in the last quarter of the search
+	Time       time.Time `json:"time"`
+}
+
+// EngineStats aggregates the move telemetry of an engine configuration
+type EngineStats struct {
+	Engine         string    `json:"engine"`
+	Config         string    `json:"config,omitempty"`
+	Moves          int       `json:"moves"`
+	AvgDepth       float64   `json:"avg_depth"`
+	AvgNodes       float64   `json:"avg_nodes"`
+	AvgTimeMs      float64   `json:"avg_time_ms"`
+	NodesPerSecond float64   `json:"nodes_per_second"` // Total nodes over total search time
+	AvgEval        float64   `json:"avg_eval"`         // Mean centipawn eval of the moves that reported one
+	LateChangeRate float64   `json:"late_change_rate"` // Share of moves whose best move changed late
+	FirstMove      time.Time `json:"first_move"`
+	LastMove       time.Time `json:"last_move"`
+}
+
+// EngineStatsPayload lists the aggregated telemetry of every engine configuration
+type EngineStatsPayload struct {
+	Engines []EngineStats `json:"engines"`
+}
+
+// EngineResult is the outcome of a finished game for one of its engines
+type EngineResult struct {
+	GameID   string      `json:"game_id"`
+	Engine   string      `json:"engine"`
+	Config   string      `json:"config,omitempty"`
+	Color    color.Color `json:"color"`
+	Score    float64     `json:"score"`    // 1 for a win, 0.5 for a draw, 0 for a loss
+	Crashes  int         `json:"crashes"`  // Crashes during its searches, whether or not the engine was replaced
+	Timeouts int         `json:"timeouts"` // Searches not answered before the deadline
+	Time     time.Time   `json:"time"`
+}
+
+// EngineStanding is the record of an engine configuration over every game it played
+type EngineStanding struct {
+	Engine    string    `json:"engine"`
+	Config    string    `json:"config,omitempty"`
+	Games     int       `json:"games"`
+	Wins      int       `json:"wins"`
+	Draws     int       `json:"draws"`
+	Losses    int       `json:"losses"`
+	Score     float64   `json:"score"` // Points per game, 1 for a win and 0.5 for a draw
+	Moves     int       `json:"moves"` // Moves the engine searched, book and fallback moves excluded
+	AvgDepth  float64   `json:"avg_depth"`
+	AvgTimeMs float64   `json:"avg_time_ms"`
+	Crashes   int       `json:"crashes"`
+	Timeouts  int       `json:"timeouts"`
+	FirstGame time.Time `json:"first_game"`
+	LastGame  time.Time `json:"last_game"`
+}
+
+// EngineLeaderboardPayload ranks the engine configurations by score
+type EngineLeaderboardPayload struct {
+	Engines []EngineStanding `json:"engines"`
+}
+
+// GamesListPayload is a page of the game history
+type GamesListPayload struct {
+	Games      []GameRecord `json:"games"`
+	NextCursor string       `json:"next_cursor,omitempty"` // Passed back to fetch the next page, empty on the last page
+}
+
+// RatingChange is the new rating of a user after a rated game
+type RatingChange struct {
+	UserID    string `json:"user_id"`
+	Rating    int    `json:"rating"`
+	Deviation int    `json:"deviation"`
+	Delta     int    `json:"delta"` // Rating points won or lost in the game
 }
 
 // Resignation payload
@@ -32,40 +369,351 @@ type ResignPayload struct {
 	GameID string `json:"gameId"`
 }
 
+// ReplayStartedPayload opens the replay of the event journal of a game
+type ReplayStartedPayload struct {
+	GameID     string  `json:"game_id"`
+	Events     int     `json:"events"`      // Recorded events that follow
+	DurationMs int64   `json:"duration_ms"` // Time between the first and last event as recorded
+	Speed      float64 `json:"speed"`       // Replay speed, 0 sends the events without waiting
+}
+
+// ResyncedPayload follows the events replayed in answer to RESYNC
+type ResyncedPayload struct {
+	GameID  string `json:"game_id"`
+	Events  int    `json:"events"`   // Events replayed before this message
+	LastSeq int64  `json:"last_seq"` // Sequence number of the last event of the game so far
+}
+
+// ReplayFinishedPayload closes the replay of a game, the connection is closed after it
+type ReplayFinishedPayload struct {
+	GameID string `json:"game_id"`
+	Events int    `json:"events"`
+}
+
 type ConnectedPayload struct {
-	ConnectionId string `json:"connection_id"`
+	ConnectionId string    `json:"connection_id"`
+	Server       BuildInfo `json:"server"` // Build of the server, to check client compatibility
+
+	Session *SessionToken `json:"session,omitempty"` // Token for the REST routes, valid while the connection is open. Only sent to connections opened with X-Api-Key
+}
+
+// SessionToken stands for the API key of a connection on the REST routes, sent
+// as Authorization: Bearer <token> so browsers need not embed the key
+type SessionToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ProtocolVersion is the version of the websocket and HTTP protocol, raised on
+// every change clients must adapt to
+const ProtocolVersion = 1
+
+// BuildInfo identifies the running server binary
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"build_date,omitempty"`
+	GoVersion string `json:"go_version"`
+	Protocol  int    `json:"protocol"` // ProtocolVersion of the server
 }
 
 // GameCreatedPayload represents the payload after a create game event
 type GameCreatedPayload struct {
-	GameID      string      `json:"game_id"`
-	InitialFEN  string      `json:"initial_fen"`
-	WhiteTime   int64       `json:"white_time"`
-	BlackTime   int64       `json:"black_time"`
-	CurrentTurn color.Color `json:"current_turn"`
+	GameID        string      `json:"game_id"`
+	InitialFEN    string      `json:"initial_fen"`
+	Variant       string      `json:"variant"`
+	WhiteTime     int64       `json:"white_time"`
+	BlackTime     int64       `json:"black_time"`
+	CurrentTurn   color.Color `json:"current_turn"`
+	Difficulty    string      `json:"difficulty,omitempty"`     // Difficulty the engine plays at, empty for its full strength
+	Profile       string      `json:"engine_profile,omitempty"` // Engine option profile of the game
+	Seed          int64       `json:"seed"`                     // Seed of the random choices, replays the game with deterministic set
+	Deterministic bool        `json:"deterministic,omitempty"`  // Whether the engines search reproducibly
+	Mode          string      `json:"mode,omitempty"`           // human_vs_human for games between players, empty for games against the engine
+	White         string      `json:"white,omitempty"`          // User playing White in a game between players
+	Black         string      `json:"black,omitempty"`          // User playing Black in a game between players
 }
 
 // GameStatePayload represents the payload returned after updating the game state
 type GameStatePayload struct {
 	GameID      string      `json:"game_id"`
 	BoardFEN    string      `json:"board_fen"`
+	Moves       []string    `json:"moves"`         // Move history in SAN
+	LastMove    string      `json:"last_move"`     // Last move in SAN, empty before the first move
+	LastMoveUCI string      `json:"last_move_uci"` // Last move in UCI notation
 	WhiteTime   int64       `json:"white_time"`
 	BlackTime   int64       `json:"black_time"`
 	CurrentTurn color.Color `json:"current_turn"`
+	Status      string      `json:"status"`
+	Result      string      `json:"result"`
 	IsCheckmate bool        `json:"is_checkmate"`
 	IsDraw      bool        `json:"is_draw"`
+
+	HalfmoveClock      int `json:"halfmove_clock"`                  // Plies since the last capture or pawn move, the 50-move rule applies from 100
+	FullmoveNumber     int `json:"fullmove_number"`                 // Number of the move being played, as in the FEN
+	RepetitionCount    int `json:"repetition_count"`                // Occurrences of the current position, a draw can be claimed from 3
+	MovesToTimeControl int `json:"moves_to_time_control,omitempty"` // Moves the side to move makes before the next time control, omitted without one
+}
+
+// TakebackAppliedPayload represents the game after moves have been taken back
+type TakebackAppliedPayload struct {
+	GameID      string      `json:"game_id"`
+	BoardFEN    string      `json:"board_fen"`
+	Moves       []string    `json:"moves"`        // Remaining move history in SAN
+	UndoneMoves int         `json:"undone_moves"` // Number of half-moves taken back
+	WhiteTime   int64       `json:"white_time"`
+	BlackTime   int64       `json:"black_time"`
+	CurrentTurn color.Color `json:"current_turn"`
+}
+
+// PremoveDiscardedPayload reports a premove that was illegal after the engine's move
+type PremoveDiscardedPayload struct {
+	GameID string `json:"game_id"`
+	Move   string `json:"move"`
+	Reason string `json:"reason"`
+}
+
+// BoardAnnotationPayload is a set of arrows, highlights and a comment a coach
+// drew on the board of a game
+type BoardAnnotationPayload struct {
+	GameID       string      `json:"game_id"`
+	ConnectionID string      `json:"connection_id"` // Connection of the coach
+	Ply          int         `json:"ply"`           // Moves played when the annotation was made
+	Arrows       []Arrow     `json:"arrows"`
+	Highlights   []Highlight `json:"highlights"`
+	Comment      string      `json:"comment,omitempty"`
+	Clear        bool        `json:"clear"` // Remove the earlier annotations before drawing these
+}
+
+// ChatMessagePayload is a chat message delivered to the readers of a channel
+type ChatMessagePayload struct {
+	GameID       string    `json:"game_id"`
+	Channel      string    `json:"channel"`       // players or spectators
+	ConnectionID string    `json:"connection_id"` // Connection of the sender
+	Text         string    `json:"text"`
+	SentAt       time.Time `json:"sent_at"`
+}
+
+// EngineCrashedPayload reports an engine process that exited during its search
+type EngineCrashedPayload struct {
+	GameID     string      `json:"game_id"`
+	Color      color.Color `json:"color"`      // Color the engine plays
+	Recovering bool        `json:"recovering"` // Whether a replacement engine takes over, the game ends otherwise
 }
 
 type ErrorPayload struct {
-	Message string `json:"message"`
+	Code      ErrorCode `json:"code"`
+	Message   string    `json:"message"`              // Human readable details, may change between versions
+	Event     string    `json:"event,omitempty"`      // Event of the inbound message that failed
+	RequestID string    `json:"request_id,omitempty"` // Correlation ID of the inbound message that failed
+
+	RetryAfterMs int64 `json:"retry_after_ms,omitempty"` // When to retry a request refused with SERVER_BUSY
+}
+
+// ErrorCode is a stable machine readable error, for clients to branch on
+type ErrorCode string
+
+// All the error codes sent in ERROR messages
+const (
+	ErrorInvalidPayload     ErrorCode = "INVALID_PAYLOAD"      // The payload does not decode or misses a field
+	ErrorUnknownEvent       ErrorCode = "UNKNOWN_EVENT"        // The server does not handle the event
+	ErrorGameNotFound       ErrorCode = "GAME_NOT_FOUND"       // No game or analysis with this ID
+	ErrorIllegalMove        ErrorCode = "ILLEGAL_MOVE"         // The move is not legal in the position
+	ErrorNotYourTurn        ErrorCode = "NOT_YOUR_TURN"        // The command is only allowed on the player's turn
+	ErrorGameOver           ErrorCode = "GAME_OVER"            // The game is finished or terminated
+	ErrorEngineUnavailable  ErrorCode = "ENGINE_UNAVAILABLE"   // Every engine of the pool is busy
+	ErrorForbidden          ErrorCode = "FORBIDDEN"            // The connection may not use this command or game
+	ErrorRateLimited        ErrorCode = "RATE_LIMITED"         // The client sends requests too fast
+	ErrorInvalidRequest     ErrorCode = "INVALID_REQUEST"      // The request is refused in the current state
+	ErrorTournamentNotFound ErrorCode = "TOURNAMENT_NOT_FOUND" // No tournament with this ID
+	ErrorSeekNotFound       ErrorCode = "SEEK_NOT_FOUND"       // No open seek with this ID, e.g. another player accepted it first
+	ErrorInternal           ErrorCode = "INTERNAL_ERROR"       // The server failed handling the request
+	ErrorServerBusy         ErrorCode = "SERVER_BUSY"          // The server is saturated and refuses new games, retry after retry_after_ms
+)
+
+// GameAbandonedPayload reports a game terminated for being idle too long
+type GameAbandonedPayload struct {
+	GameID      string `json:"game_id"`
+	IdleSeconds int64  `json:"idle_seconds"` // Time since the last move or command
+}
+
+// PresencePayload tells whether the player of a game is connected and how many
+// clients watch it, sent whenever either changes
+type PresencePayload struct {
+	GameID          string `json:"game_id"`
+	PlayerConnected bool   `json:"player_connected"` // False while the player is away, e.g. reconnecting
+	Spectators      int    `json:"spectators"`       // WebSocket connections watching the game
+	Streams         int    `json:"streams"`          // Server-Sent Events streams following the game
+
+	// Whether the player of the other color of a game between players is
+	// connected, omitted in games against an engine
+	OpponentConnected *bool `json:"opponent_connected,omitempty"`
+}
+
+// SeekPayload is an open seek of the seek pool
+type SeekPayload struct {
+	SeekID      string      `json:"seek_id"`
+	UserID      string      `json:"user_id"`
+	Rating      int         `json:"rating"` // Rating of the user when the seek was opened
+	TimeControl TimeControl `json:"time_control"`
+	Variant     string      `json:"variant"`
+	Color       color.Color `json:"color,omitempty"` // Color played by the user, random when empty
+	MinRating   int         `json:"min_rating,omitempty"`
+	MaxRating   int         `json:"max_rating,omitempty"`
+	Rated       bool        `json:"rated"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+// SeeksListPayload lists the open seeks, the oldest first
+type SeeksListPayload struct {
+	Seeks []SeekPayload `json:"seeks"`
+}
+
+// SeekMatchedPayload tells both players of an accepted seek about their game
+type SeekMatchedPayload struct {
+	SeekID   string      `json:"seek_id"`
+	GameID   string      `json:"game_id"`
+	Color    color.Color `json:"color"`    // Color played by the recipient
+	Opponent string      `json:"opponent"` // User playing the other color
+	Rated    bool        `json:"rated"`
+}
+
+// TournamentPayload is a tournament of the lobby and its standings
+type TournamentPayload struct {
+	TournamentID string               `json:"tournament_id"`
+	Name         string               `json:"name"`
+	Format       string               `json:"format"`    // arena or swiss
+	Opponents    string               `json:"opponents"` // engine or players
+	Status       string               `json:"status"`    // open, running or finished
+	TimeControl  TimeControl          `json:"time_control"`
+	Difficulty   string               `json:"difficulty,omitempty"` // Level every player starts at against the engine
+	Round        int                  `json:"round"`                // Current round of a Swiss tournament
+	Rounds       int                  `json:"rounds"`               // Rounds of a Swiss tournament
+	DurationMs   int64                `json:"duration_ms"`          // Length of an arena
+	StartedAt    time.Time            `json:"started_at"`
+	EndsAt       time.Time            `json:"ends_at"` // End of an arena, no game starts after it
+	FinishedAt   time.Time            `json:"finished_at"`
+	Standings    []TournamentStanding `json:"standings"` // Best player first
+}
+
+// TournamentStanding is the score of a player of a tournament
+type TournamentStanding struct {
+	Rank       int     `json:"rank"`
+	UserID     string  `json:"user_id"`
+	Points     float64 `json:"points"` // Swiss wins score 1 and draws ½, arena wins 2 and draws 1, doubled from the third win in a row
+	Games      int     `json:"games"`
+	Wins       int     `json:"wins"`
+	Draws      int     `json:"draws"`
+	Losses     int     `json:"losses"`
+	Byes       int     `json:"byes,omitempty"`       // Swiss rounds sat out by an odd player out of a tournament between players, scored as a win
+	Difficulty string  `json:"difficulty,omitempty"` // Level of the next game against the engine, one up after a win and one down after a loss
+	Playing    bool    `json:"playing"`
+	Withdrawn  bool    `json:"withdrawn"`
+}
+
+// PreferencesPayload describes the updates a connection receives
+type PreferencesPayload struct {
+	Mute []string `json:"mute"` // Event classes not sent, sorted
+	Eval string   `json:"eval"` // all or final
+}
+
+// LobbyStatePayload lists the tournaments of the lobby, the newest first
+type LobbyStatePayload struct {
+	Tournaments []TournamentPayload `json:"tournaments"`
+}
+
+// TournamentPairingPayload tells a player about their next tournament game,
+// its events follow like those of any game they created
+type TournamentPairingPayload struct {
+	TournamentID string      `json:"tournament_id"`
+	Round        int         `json:"round"` // Round of a Swiss tournament, the game number of the player in an arena
+	GameID       string      `json:"game_id"`
+	Color        color.Color `json:"color"`                // Color the player plays
+	Difficulty   string      `json:"difficulty,omitempty"` // Level of the engine, empty between players
+	Opponent     string      `json:"opponent,omitempty"`   // User playing the other color in a tournament between players
+}
+
+// SimulCreatedPayload lists the boards of a new simul, the events of every
+// board follow like those of any game the player created
+type SimulCreatedPayload struct {
+	SimulID string      `json:"simul_id"`
+	GameIDs []string    `json:"game_ids"`
+	Color   color.Color `json:"color"` // Color the player takes on every board
+}
+
+// GameClaimedPayload tells a connection that another connection of the same
+// player took over its game, it keeps watching the game as a spectator
+type GameClaimedPayload struct {
+	GameID       string `json:"game_id"`
+	ConnectionID string `json:"connection_id"` // Connection now playing the game
+}
+
+// GameRestoredPayload reports a game restored after a server restart, paused
+// until its player sends RESUME_GAME
+type GameRestoredPayload struct {
+	GameID      string      `json:"game_id"`
+	Mode        string      `json:"mode"`
+	Variant     string      `json:"variant"`
+	FEN         string      `json:"fen"`
+	WhiteTime   int64       `json:"white_time"`
+	BlackTime   int64       `json:"black_time"`
+	CurrentTurn color.Color `json:"current_turn"`
+}
+
+// InternalErrorPayload reports a panic the server recovered from to the admins
+type InternalErrorPayload struct {
+	Component string `json:"component"`         // Part of the server that failed, e.g. hub or game session
+	GameID    string `json:"game_id,omitempty"` // Game being handled, when known
+	Message   string `json:"message"`
 }
 
 type EngineMovePayload struct {
-	Move  string      `json:"move"`
+	Move  string      `json:"move"` // Move in UCI notation
+	SAN   string      `json:"san"`  // Move in SAN
 	Color color.Color `json:"color"`
 }
 
+// EvalPayload contains the latest search information of an engine
+type EvalPayload struct {
+	GameID  string      `json:"game_id"`
+	Color   color.Color `json:"color"`           // Color played by the engine reporting the evaluation, the side to move for the eval bar
+	Depth   int         `json:"depth"`           // Search depth in plies
+	ScoreCP int         `json:"score_cp"`        // Score in centipawns from the point of view of color
+	Mate    int         `json:"mate"`            // Moves to mate, 0 when no mate was found
+	PV      []string    `json:"pv"`              // Principal variation in UCI notation
+	Source  string      `json:"source"`          // engine for the search of a playing engine, eval_bar for the quick evaluation after a move
+	Ply     int         `json:"ply,omitempty"`   // Moves played in the evaluated position, set by the eval bar
+	Final   bool        `json:"final,omitempty"` // Evaluation of a finished search: the one an engine move was played on, or the eval bar's
+}
+
 // TimeupPayload contains information about which player ran out of time
 type TimeupPayload struct {
 	Color string `json:"color"` // The color of the player who ran out of time
 }
+
+// MatchGamePayload contains the outcome of a single game of a match
+type MatchGamePayload struct {
+	GameID  string `json:"game_id"`
+	White   string `json:"white"`
+	Black   string `json:"black"`
+	Opening string `json:"opening,omitempty"`
+	Result  string `json:"result"`
+}
+
+// MatchProgressPayload contains the aggregated results of an engine match
+type MatchProgressPayload struct {
+	MatchID     string             `json:"match_id"`
+	EngineA     string             `json:"engine_a"`
+	EngineB     string             `json:"engine_b"`
+	Status      string             `json:"status"`
+	TotalGames  int                `json:"total_games"`
+	PlayedGames int                `json:"played_games"`
+	Wins        int                `json:"wins"`       // Wins of engine A
+	Draws       int                `json:"draws"`      // Draws
+	Losses      int                `json:"losses"`     // Losses of engine A
+	Elo         float64            `json:"elo"`        // Elo difference of engine A over engine B
+	EloMargin   float64            `json:"elo_margin"` // 95% confidence margin of the Elo difference
+	Games       []MatchGamePayload `json:"games"`
+	StartedAt   time.Time          `json:"started_at"`
+	FinishedAt  time.Time          `json:"finished_at"`
+}
@@ -0,0 +1,573 @@
+// Package msgpack implements the subset of MessagePack the server speaks on
+// binary connections. Values are encoded following their json struct tags so
+// the message types serve both wire encodings unchanged
+package msgpack
+
+import (
+	"encoding"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrTruncated is returned when the data ends in the middle of a value
+var ErrTruncated = errors.New("msgpack: unexpected end of data")
+
+var (
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// Marshal returns the MessagePack encoding of v
+func Marshal(v interface{}) ([]byte, error) {
+	e := &encoder{buf: make([]byte, 0, 128)}
+	if err := e.encode(reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) encode(v reflect.Value) error {
+	if !v.IsValid() {
+		e.buf = append(e.buf, 0xc0)
+		return nil
+	}
+
+	// Types with their own JSON form keep it, e.g. time.Time and json.RawMessage
+	if v.Type().Implements(jsonMarshalerType) {
+		if v.Kind() == reflect.Pointer && v.IsNil() {
+			e.buf = append(e.buf, 0xc0)
+			return nil
+		}
+		return e.encodeJSON(v.Interface().(json.Marshaler))
+	}
+	if v.Type().Implements(textMarshalerType) {
+		if v.Kind() == reflect.Pointer && v.IsNil() {
+			e.buf = append(e.buf, 0xc0)
+			return nil
+		}
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+		e.encodeString(string(text))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			e.buf = append(e.buf, 0xc0)
+			return nil
+		}
+		return e.encode(v.Elem())
+
+	case reflect.Bool:
+		if v.Bool() {
+			e.buf = append(e.buf, 0xc3)
+		} else {
+			e.buf = append(e.buf, 0xc2)
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.encodeInt(v.Int())
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.encodeUint(v.Uint())
+
+	case reflect.Float32:
+		e.buf = append(e.buf, 0xca)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, math.Float32bits(float32(v.Float())))
+
+	case reflect.Float64:
+		e.buf = append(e.buf, 0xcb)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, math.Float64bits(v.Float()))
+
+	case reflect.String:
+		e.encodeString(v.String())
+
+	case reflect.Slice:
+		if v.IsNil() {
+			e.buf = append(e.buf, 0xc0)
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			e.encodeBytes(v.Bytes())
+			return nil
+		}
+		fallthrough
+
+	case reflect.Array:
+		e.encodeHeader(v.Len(), 0x90, 0xdc, 0xdd)
+		for i := 0; i < v.Len(); i++ {
+			if err := e.encode(v.Index(i)); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		if v.IsNil() {
+			e.buf = append(e.buf, 0xc0)
+			return nil
+		}
+		return e.encodeMap(v)
+
+	case reflect.Struct:
+		return e.encodeStruct(v)
+
+	default:
+		return fmt.Errorf("msgpack: unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+func (e *encoder) encodeInt(n int64) {
+	switch {
+	case n >= 0:
+		e.encodeUint(uint64(n))
+	case n >= -32:
+		e.buf = append(e.buf, byte(n))
+	case n >= math.MinInt8:
+		e.buf = append(e.buf, 0xd0, byte(n))
+	case n >= math.MinInt16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xd1), uint16(n))
+	case n >= math.MinInt32:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xd2), uint32(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint64(append(e.buf, 0xd3), uint64(n))
+	}
+}
+
+func (e *encoder) encodeUint(n uint64) {
+	switch {
+	case n <= 0x7f:
+		e.buf = append(e.buf, byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xcc, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xcd), uint16(n))
+	case n <= math.MaxUint32:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xce), uint32(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint64(append(e.buf, 0xcf), n)
+	}
+}
+
+func (e *encoder) encodeString(s string) {
+	switch n := len(s); {
+	case n < 32:
+		e.buf = append(e.buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xda), uint16(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xdb), uint32(n))
+	}
+	e.buf = append(e.buf, s...)
+}
+
+func (e *encoder) encodeBytes(b []byte) {
+	switch n := len(b); {
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xc5), uint16(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xc6), uint32(n))
+	}
+	e.buf = append(e.buf, b...)
+}
+
+// encodeHeader writes the length of an array or map in its shortest form
+func (e *encoder) encodeHeader(n int, fix, len16, len32 byte) {
+	switch {
+	case n < 16:
+		e.buf = append(e.buf, fix|byte(n))
+	case n <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, len16), uint16(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, len32), uint32(n))
+	}
+}
+
+// encodeMap writes the entries sorted by key, the same order encoding/json uses
+func (e *encoder) encodeMap(v reflect.Value) error {
+	keys := make([]string, 0, v.Len())
+	values := make(map[string]reflect.Value, v.Len())
+
+	iter := v.MapRange()
+	for iter.Next() {
+		key, err := mapKey(iter.Key())
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+		values[key] = iter.Value()
+	}
+	sort.Strings(keys)
+
+	e.encodeHeader(len(keys), 0x80, 0xde, 0xdf)
+	for _, key := range keys {
+		e.encodeString(key)
+		if err := e.encode(values[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mapKey converts a map key to a string the way encoding/json does
+func mapKey(k reflect.Value) (string, error) {
+	switch k.Kind() {
+	case reflect.String:
+		return k.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return fmt.Sprint(k.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return fmt.Sprint(k.Uint()), nil
+	default:
+		return "", fmt.Errorf("msgpack: unsupported map key type %s", k.Type())
+	}
+}
+
+func (e *encoder) encodeStruct(v reflect.Value) error {
+	fields := cachedFields(v.Type())
+
+	present := make([]reflect.Value, len(fields))
+	count := 0
+	for i, f := range fields {
+		fv, ok := fieldByIndex(v, f.index)
+		if !ok || (f.omitEmpty && isEmpty(fv)) {
+			continue
+		}
+		present[i] = fv
+		count++
+	}
+
+	e.encodeHeader(count, 0x80, 0xde, 0xdf)
+	for i, f := range fields {
+		if !present[i].IsValid() {
+			continue
+		}
+		e.encodeString(f.name)
+		if err := e.encode(present[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeJSON writes a value in the shape of its JSON encoding
+func (e *encoder) encodeJSON(m json.Marshaler) error {
+	data, err := m.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return err
+	}
+	return e.encode(reflect.ValueOf(generic))
+}
+
+// field is an encoded struct field
+type field struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+var fieldCache sync.Map // reflect.Type -> []field
+
+// cachedFields returns the encoded fields of a struct type, following the
+// json tags and flattening untagged embedded structs like encoding/json
+func cachedFields(t reflect.Type) []field {
+	if fields, ok := fieldCache.Load(t); ok {
+		return fields.([]field)
+	}
+
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			for _, inner := range cachedFields(ft) {
+				inner.index = append([]int{i}, inner.index...)
+				fields = append(fields, inner)
+			}
+			continue
+		}
+
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+
+		fields = append(fields, field{
+			name:      name,
+			index:     []int{i},
+			omitEmpty: strings.Contains(","+opts+",", ",omitempty,"),
+		})
+	}
+
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+// fieldByIndex follows embedded pointers, reporting false when one is nil
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// isEmpty reports whether omitempty drops a value
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	default:
+		return false
+	}
+}
+
+// Unmarshal decodes a MessagePack value into generic Go values: maps become
+// map[string]interface{}, arrays []interface{}, integers int64 or uint64 and
+// floats float64, the same shapes encoding/json produces
+func Unmarshal(data []byte) (interface{}, error) {
+	d := &decoder{data: data}
+
+	v, err := d.decode()
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, errors.New("msgpack: trailing data after value")
+	}
+	return v, nil
+}
+
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+// next returns the following n bytes
+func (d *decoder) next(n int) ([]byte, error) {
+	if n < 0 || len(d.data)-d.pos < n {
+		return nil, ErrTruncated
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// length reads a big endian length of the given size in bytes
+func (d *decoder) length(size int) (int, error) {
+	b, err := d.next(size)
+	if err != nil {
+		return 0, err
+	}
+
+	switch size {
+	case 1:
+		return int(b[0]), nil
+	case 2:
+		return int(binary.BigEndian.Uint16(b)), nil
+	default:
+		return int(binary.BigEndian.Uint32(b)), nil
+	}
+}
+
+func (d *decoder) decode() (interface{}, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+
+	switch c := b[0]; {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return d.decodeMap(int(c & 0x0f))
+	case c&0xf0 == 0x90:
+		return d.decodeArray(int(c & 0x0f))
+	case c&0xe0 == 0xa0:
+		return d.decodeString(int(c & 0x1f))
+	}
+
+	switch c := b[0]; c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.length(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		raw, err := d.next(n)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), raw...), nil
+
+	case 0xca:
+		raw, err := d.next(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(raw))), nil
+	case 0xcb:
+		raw, err := d.next(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(raw)), nil
+
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		raw, err := d.next(1 << (c - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		return readUint(raw), nil
+
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		raw, err := d.next(1 << (c - 0xd0))
+		if err != nil {
+			return nil, err
+		}
+		// Sign extend from the width of the value
+		shift := 64 - 8*len(raw)
+		return int64(readUint(raw)<<shift) >> shift, nil
+
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.length(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeString(n)
+
+	case 0xdc, 0xdd:
+		n, err := d.length(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(n)
+
+	case 0xde, 0xdf:
+		n, err := d.length(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(n)
+
+	default:
+		return nil, fmt.Errorf("msgpack: unsupported type byte 0x%02x", c)
+	}
+}
+
+func readUint(b []byte) uint64 {
+	var n uint64
+	for _, x := range b {
+		n = n<<8 | uint64(x)
+	}
+	return n
+}
+
+func (d *decoder) decodeString(n int) (interface{}, error) {
+	raw, err := d.next(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+func (d *decoder) decodeArray(n int) (interface{}, error) {
+	// Every element takes at least a byte, which bounds bogus lengths
+	if n > len(d.data)-d.pos {
+		return nil, ErrTruncated
+	}
+
+	arr := make([]interface{}, n)
+	for i := range arr {
+		v, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		arr[i] = v
+	}
+	return arr, nil
+}
+
+func (d *decoder) decodeMap(n int) (interface{}, error) {
+	if n > len(d.data)-d.pos {
+		return nil, ErrTruncated
+	}
+
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		k, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			key = fmt.Sprint(k)
+		}
+
+		v, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+	return m, nil
+}
@@ -0,0 +1,380 @@
+// Package analysis reviews finished games with an engine in the background
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/color"
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/engine"
+	"github.com/tecu23/eng-server/pkg/events"
+)
+
+// searchTimeout bounds the engine search of a single position
+const searchTimeout = 30 * time.Second
+
+// maxEval caps evaluations so a missed mate does not dwarf every other mistake
+const maxEval = 1000
+
+// Centipawn losses from which a move is classified
+const (
+	inaccuracyLoss = 50
+	mistakeLoss    = 100
+	blunderLoss    = 300
+)
+
+// Move classifications
+const (
+	Inaccuracy = "inaccuracy"
+	Mistake    = "mistake"
+	Blunder    = "blunder"
+)
+
+// ErrQueueFull is returned when too many games are waiting for analysis
+var ErrQueueFull = errors.New("analysis queue is full")
+
+// Store keeps the analysis reports with their games, the puzzles found in
+// them and the fair play reports of their players
+type Store interface {
+	SaveAnalysis(gameID string, report messages.AnalysisReportPayload) error
+	SavePuzzles(puzzles []messages.Puzzle) error
+	SaveFairPlay(report messages.FairPlayReport) error
+}
+
+// Analyzer runs the queued games through engines from the pool, the most
+// urgent first and at most a few at a time so games keep their engines
+type Analyzer struct {
+	pool     *engine.Pool
+	depth    int
+	workers  int
+	store    Store
+	jobStore JobStore // Keeps the jobs across restarts, nil keeps them in memory only
+
+	mu       sync.Mutex
+	jobs     map[string]*trackedJob
+	pending  jobHeap  // Jobs waiting for a worker
+	finished []string // IDs of the finished jobs still kept, the oldest first
+	seq      uint64
+	wake     chan struct{}
+	saveMu   sync.Mutex // Orders the writes to the job store
+
+	publisher *events.Publisher
+	logger    *zap.Logger
+}
+
+// NewAnalyzer creates an analyzer searching every position at the given
+// depth, with at most the given number of games analyzed at once
+func NewAnalyzer(
+	pool *engine.Pool,
+	depth int,
+	workers int,
+	store Store,
+	jobStore JobStore,
+	publisher *events.Publisher,
+	logger *zap.Logger,
+) *Analyzer {
+	workers = max(workers, 1)
+
+	return &Analyzer{
+		pool:      pool,
+		depth:     depth,
+		workers:   workers,
+		store:     store,
+		jobStore:  jobStore,
+		jobs:      make(map[string]*trackedJob),
+		wake:      make(chan struct{}, workers),
+		publisher: publisher,
+		logger:    logger,
+	}
+}
+
+// Queue adds the post-game report of a standard chess game to the analysis queue
+func (a *Analyzer) Queue(gameID, startFEN string, moves []string) error {
+	_, err := a.Submit(gameID, startFEN, moves, PriorityReport)
+	return err
+}
+
+// Run analyzes the queued games until the context is done
+func (a *Analyzer) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for range a.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+// work runs the waiting jobs one after the other until the context is done
+func (a *Analyzer) work(ctx context.Context) {
+	for {
+		j, jobCtx, ok := a.next(ctx)
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-a.wake:
+				continue
+			}
+		}
+
+		a.persist()
+		report, err := a.process(jobCtx, j.Job)
+		a.done(ctx, j, report, err)
+	}
+}
+
+// process analyzes a game, stores the report and publishes it
+func (a *Analyzer) process(ctx context.Context, j Job) (*messages.AnalysisReportPayload, error) {
+	logger := a.logger.With(zap.String("job_id", j.ID), zap.String("game_id", j.GameID))
+
+	eng, err := a.pool.GetEngine(ctx)
+	if err != nil {
+		logger.Error("no engine for game analysis", zap.Error(err))
+		return nil, err
+	}
+	defer a.pool.ReturnEngine(eng.ID.String())
+
+	report, err := a.analyze(ctx, eng, j)
+	if err != nil {
+		logger.Error("game analysis failed", zap.Error(err))
+		return nil, err
+	}
+
+	// Only the finished games of the server have a record to keep the report
+	// with, not a game still being played nor an imported one
+	if j.Priority == PriorityReport {
+		if err := a.store.SaveAnalysis(j.GameID, report); err != nil {
+			logger.Error("could not store game analysis", zap.Error(err))
+		}
+	}
+
+	// Puzzles are a by-product, the report is published whatever happens to them
+	puzzles, err := a.extractPuzzles(eng, j, report)
+	if err != nil {
+		logger.Warn("puzzle extraction failed", zap.Error(err))
+	}
+	if len(puzzles) > 0 {
+		if err := a.store.SavePuzzles(puzzles); err != nil {
+			logger.Error("could not store puzzles", zap.Error(err))
+		}
+	}
+
+	for _, subject := range j.FairPlay {
+		fp := fairPlayReport(subject, report)
+		if err := a.store.SaveFairPlay(fp); err != nil {
+			logger.Error("could not store fair play report", zap.Error(err))
+		}
+		if fp.Flagged {
+			logger.Warn("game flagged for fair play review", zap.String("user_id", fp.UserID), zap.Strings("reasons", fp.Reasons))
+		}
+	}
+
+	// Imported games are not followed by anyone, their batch keeps the
+	// report, and nobody asked for the report of a review
+	if j.BatchID == "" && j.Priority != PriorityReview {
+		a.publisher.Publish(events.Event{
+			Type:    events.EventAnalysisReady,
+			GameID:  j.GameID,
+			Payload: report,
+		})
+	}
+
+	logger.Info("game analysis completed", zap.Int("puzzles", len(puzzles)))
+	return &report, nil
+}
+
+// analyze evaluates every position of the game and classifies the moves
+func (a *Analyzer) analyze(ctx context.Context, eng *engine.UCIEngine, j Job) (messages.AnalysisReportPayload, error) {
+	report := messages.AnalysisReportPayload{
+		GameID: j.GameID,
+		Depth:  a.depth,
+		Moves:  make([]messages.MoveAnalysis, 0, len(j.Moves)),
+	}
+
+	fen, err := chess.FEN(j.StartFEN)
+	if err != nil {
+		return report, fmt.Errorf("invalid start position: %w", err)
+	}
+	pos := chess.NewGame(fen).Position()
+
+	before, best, err := a.evaluate(eng, pos)
+	if err != nil {
+		return report, err
+	}
+
+	var white, black sideTotals
+	for ply, uci := range j.Moves {
+		// A canceled job stops between two positions
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		move, err := chess.UCINotation{}.Decode(pos, uci)
+		if err != nil {
+			return report, fmt.Errorf("invalid move %s: %w", uci, err)
+		}
+
+		mover := pos.Turn()
+		san := chess.AlgebraicNotation{}.Encode(pos, move)
+		next := pos.Update(move)
+
+		after, nextBest, err := a.evaluate(eng, next)
+		if err != nil {
+			return report, err
+		}
+
+		// Losses are measured from the point of view of the side that moved
+		sign := 1
+		if mover == chess.Black {
+			sign = -1
+		}
+
+		loss := max(0, sign*(before-after))
+		if uci == best {
+			loss = 0
+		}
+
+		analysis := messages.MoveAnalysis{
+			Ply:            ply + 1,
+			Color:          color.Color(mover.String()),
+			Move:           uci,
+			SAN:            san,
+			Eval:           after,
+			BestMove:       best,
+			CPLoss:         loss,
+			Classification: classify(loss),
+		}
+		report.Moves = append(report.Moves, analysis)
+
+		totals := &white
+		if mover == chess.Black {
+			totals = &black
+		}
+		totals.add(analysis, moveAccuracy(sign*before, sign*after))
+
+		pos, before, best = next, after, nextBest
+	}
+
+	report.White = white.summary()
+	report.Black = black.summary()
+	return report, nil
+}
+
+// evaluate returns the capped evaluation of a position from White's point of
+// view and the best move of the engine, empty when the game is over
+func (a *Analyzer) evaluate(eng *engine.UCIEngine, pos *chess.Position) (int, string, error) {
+	switch pos.Status() {
+	case chess.Checkmate:
+		if pos.Turn() == chess.White {
+			return -maxEval, "", nil
+		}
+		return maxEval, "", nil
+	case chess.Stalemate:
+		return 0, "", nil
+	}
+
+	if err := eng.SendCommand(fmt.Sprintf("position fen %s", pos.String())); err != nil {
+		return 0, "", fmt.Errorf("engine command error: %w", err)
+	}
+	if err := eng.SendCommand(fmt.Sprintf("go depth %d", a.depth)); err != nil {
+		return 0, "", fmt.Errorf("engine command error: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), searchTimeout)
+	defer cancel()
+
+	best, err := eng.WaitBestMove(ctx)
+	if err != nil {
+		_ = eng.Stop()
+		return 0, "", fmt.Errorf("engine did not answer: %w", err)
+	}
+
+	info, ok := eng.LastInfo()
+	if !ok {
+		return 0, "", errors.New("engine reported no score")
+	}
+
+	eval := max(-maxEval, min(maxEval, info.Centipawns()))
+	if pos.Turn() == chess.Black {
+		eval = -eval
+	}
+
+	return eval, best, nil
+}
+
+// classify returns the classification of a move by its centipawn loss
+func classify(loss int) string {
+	switch {
+	case loss >= blunderLoss:
+		return Blunder
+	case loss >= mistakeLoss:
+		return Mistake
+	case loss >= inaccuracyLoss:
+		return Inaccuracy
+	default:
+		return ""
+	}
+}
+
+// winPercent converts an evaluation into the winning chances of the side it is for
+func winPercent(cp int) float64 {
+	return 50 + 50*(2/(1+math.Exp(-0.00368208*float64(cp)))-1)
+}
+
+// moveAccuracy scores a move from 0 to 100 by the winning chances it gave away,
+// evaluations are from the point of view of the side that moved
+func moveAccuracy(before, after int) float64 {
+	drop := winPercent(before) - winPercent(after)
+	accuracy := 103.1668*math.Exp(-0.04354*drop) - 3.1669
+	return math.Max(0, math.Min(100, accuracy))
+}
+
+// sideTotals accumulates the move analyses of one side
+type sideTotals struct {
+	moves        int
+	loss         int
+	accuracy     float64
+	inaccuracies int
+	mistakes     int
+	blunders     int
+}
+
+func (t *sideTotals) add(m messages.MoveAnalysis, accuracy float64) {
+	t.moves++
+	t.loss += m.CPLoss
+	t.accuracy += accuracy
+
+	switch m.Classification {
+	case Inaccuracy:
+		t.inaccuracies++
+	case Mistake:
+		t.mistakes++
+	case Blunder:
+		t.blunders++
+	}
+}
+
+func (t *sideTotals) summary() messages.SideAnalysis {
+	if t.moves == 0 {
+		return messages.SideAnalysis{}
+	}
+
+	return messages.SideAnalysis{
+		Accuracy:      math.Round(t.accuracy/float64(t.moves)*10) / 10,
+		AverageCPLoss: t.loss / t.moves,
+		Inaccuracies:  t.inaccuracies,
+		Mistakes:      t.mistakes,
+		Blunders:      t.blunders,
+	}
+}
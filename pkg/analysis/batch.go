@@ -0,0 +1,347 @@
+package analysis
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/messages"
+)
+
+const (
+	// MaxBatchGames is the number of games a batch may hold
+	MaxBatchGames = 200
+	// batchRetention is how long the games of a batch are kept after it was
+	// submitted, for its annotated PGN to be downloaded
+	batchRetention = 24 * time.Hour
+	// pgnLineLength is the length the movetext of exported games is wrapped at
+	pgnLineLength = 80
+)
+
+// Errors returned for batches
+var (
+	ErrBatchNotFound = errors.New("analysis batch not found")
+	ErrBatchRunning  = errors.New("analysis batch is still running")
+)
+
+// batchTags are the PGN tags of an imported game kept for its export
+var batchTags = []string{
+	"Event", "Site", "Date", "Round", "White", "Black", "Result",
+	"WhiteElo", "BlackElo", "ECO", "Opening", "TimeControl", "Termination",
+}
+
+// classificationNAGs are the move suffixes of the classified moves
+var classificationNAGs = map[string]string{
+	Inaccuracy: "?!",
+	Mistake:    "?",
+	Blunder:    "??",
+}
+
+// SubmitBatch queues every game of a PGN file for analysis behind the games
+// of the server. The whole file is rejected when a game can not be read
+func (a *Analyzer) SubmitBatch(r io.Reader) (messages.AnalysisBatch, error) {
+	batchID := uuid.New().String()
+	now := time.Now().UTC()
+
+	var jobs []Job
+	scanner := chess.NewScanner(r)
+	for scanner.HasNext() {
+		scanned, err := scanner.ScanGame()
+		if err != nil {
+			return messages.AnalysisBatch{}, fmt.Errorf("game %d: %w", len(jobs)+1, err)
+		}
+
+		if len(jobs) == MaxBatchGames {
+			return messages.AnalysisBatch{}, fmt.Errorf("a batch holds at most %d games", MaxBatchGames)
+		}
+
+		job, err := importGame(scanned.Raw)
+		if err != nil {
+			return messages.AnalysisBatch{}, fmt.Errorf("game %d: %w", len(jobs)+1, err)
+		}
+
+		job.ID = uuid.New().String()
+		job.GameID = fmt.Sprintf("%s:%d", batchID, len(jobs)+1)
+		job.Priority = PriorityImport
+		job.Status = JobQueued
+		job.CreatedAt = now
+		job.BatchID = batchID
+		job.BatchIndex = len(jobs)
+		jobs = append(jobs, job)
+	}
+
+	if len(jobs) == 0 {
+		return messages.AnalysisBatch{}, errors.New("no game found in the PGN")
+	}
+
+	if _, err := a.enqueue(jobs); err != nil {
+		return messages.AnalysisBatch{}, err
+	}
+
+	a.logger.Info("analysis batch queued", zap.String("batch_id", batchID), zap.Int("games", len(jobs)))
+
+	return a.Batch(batchID)
+}
+
+// importGame reads the start position, moves and tags of a single PGN game
+func importGame(pgn string) (Job, error) {
+	opt, err := chess.PGN(strings.NewReader(pgn))
+	if err != nil {
+		return Job{}, fmt.Errorf("invalid PGN: %w", err)
+	}
+	g := chess.NewGame(opt)
+
+	if variant := g.GetTagPair("Variant"); variant != "" && !strings.EqualFold(variant, "standard") {
+		return Job{}, fmt.Errorf("only standard chess games can be analyzed, not %s", variant)
+	}
+
+	positions := g.Positions()
+	moves := make([]string, 0, len(g.Moves()))
+	for i, m := range g.Moves() {
+		moves = append(moves, chess.UCINotation{}.Encode(positions[i], m))
+	}
+
+	tags := make(map[string]string)
+	for _, name := range batchTags {
+		if v := g.GetTagPair(name); v != "" {
+			tags[name] = v
+		}
+	}
+
+	return Job{StartFEN: positions[0].String(), Moves: moves, Tags: tags}, nil
+}
+
+// batchJobs returns the games of a batch in the order of the PGN, the caller holds mu
+func (a *Analyzer) batchJobs(id string) []*trackedJob {
+	var jobs []*trackedJob
+	for _, j := range a.jobs {
+		if j.BatchID == id {
+			jobs = append(jobs, j)
+		}
+	}
+
+	sort.Slice(jobs, func(i, k int) bool { return jobs[i].BatchIndex < jobs[k].BatchIndex })
+	return jobs
+}
+
+// Batch returns the progress of a batch
+func (a *Analyzer) Batch(id string) (messages.AnalysisBatch, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	jobs := a.batchJobs(id)
+	if len(jobs) == 0 {
+		return messages.AnalysisBatch{}, fmt.Errorf("%w: %s", ErrBatchNotFound, id)
+	}
+
+	batch := messages.AnalysisBatch{
+		BatchID:   id,
+		Status:    string(JobCompleted),
+		Games:     len(jobs),
+		CreatedAt: jobs[0].CreatedAt,
+		Jobs:      make([]messages.AnalysisJob, 0, len(jobs)),
+	}
+
+	for _, j := range jobs {
+		switch j.Status {
+		case JobCompleted:
+			batch.Completed++
+		case JobFailed:
+			batch.Failed++
+		case JobCanceled:
+			batch.Canceled++
+		case JobRunning:
+			batch.Status = string(JobRunning)
+		}
+		batch.Jobs = append(batch.Jobs, a.describe(j))
+	}
+
+	if batch.Status != string(JobRunning) && batch.Completed+batch.Failed+batch.Canceled < batch.Games {
+		batch.Status = string(JobQueued)
+	}
+	return batch, nil
+}
+
+// CancelBatch cancels the games of a batch that are not analyzed yet
+func (a *Analyzer) CancelBatch(id string) (messages.AnalysisBatch, error) {
+	a.mu.Lock()
+	jobs := a.batchJobs(id)
+	a.mu.Unlock()
+
+	if len(jobs) == 0 {
+		return messages.AnalysisBatch{}, fmt.Errorf("%w: %s", ErrBatchNotFound, id)
+	}
+
+	for _, j := range jobs {
+		if _, err := a.Cancel(j.ID); err != nil && !errors.Is(err, ErrJobFinished) {
+			return messages.AnalysisBatch{}, err
+		}
+	}
+
+	return a.Batch(id)
+}
+
+// BatchPGN exports the games of a finished batch annotated with the
+// evaluation of every move, the classification of the mistakes and the move
+// the engine preferred. Games whose analysis failed or was canceled are
+// exported as they were imported
+func (a *Analyzer) BatchPGN(id string) (string, error) {
+	a.mu.Lock()
+	jobs := a.batchJobs(id)
+	games := make([]Job, 0, len(jobs))
+	for _, j := range jobs {
+		if !j.Status.finished() {
+			a.mu.Unlock()
+			return "", ErrBatchRunning
+		}
+		games = append(games, j.Job)
+	}
+	a.mu.Unlock()
+
+	if len(games) == 0 {
+		return "", fmt.Errorf("%w: %s", ErrBatchNotFound, id)
+	}
+
+	var b strings.Builder
+	for _, j := range games {
+		if err := writeAnnotatedGame(&b, j, a.depth); err != nil {
+			return "", fmt.Errorf("game %d: %w", j.BatchIndex+1, err)
+		}
+	}
+	return b.String(), nil
+}
+
+// writeAnnotatedGame writes a game of a batch in PGN with its analysis
+func writeAnnotatedGame(b *strings.Builder, j Job, depth int) error {
+	fen, err := chess.FEN(j.StartFEN)
+	if err != nil {
+		return fmt.Errorf("invalid start position: %w", err)
+	}
+	pos := chess.NewGame(fen).Position()
+
+	result := j.Tags["Result"]
+	if result == "" {
+		result = "*"
+	}
+
+	for _, name := range batchTags {
+		v := j.Tags[name]
+		if name == "Result" {
+			v = result
+		}
+		if v != "" {
+			fmt.Fprintf(b, "[%s %q]\n", name, v)
+		}
+	}
+	if pos.String() != chess.StartingPosition().String() {
+		fmt.Fprintf(b, "[SetUp \"1\"]\n[FEN %q]\n", j.StartFEN)
+	}
+	if j.Report != nil {
+		fmt.Fprintf(b, "[Annotator \"eng-server depth %d\"]\n", depth)
+	}
+	b.WriteString("\n")
+
+	var tokens []string
+	if j.Report == nil && j.Error != "" {
+		tokens = append(tokens, fmt.Sprintf("{Analysis failed: %s}", j.Error))
+	}
+
+	moveNumber := fullMoveNumber(j.StartFEN)
+	for ply, uci := range j.Moves {
+		move, err := chess.UCINotation{}.Decode(pos, uci)
+		if err != nil {
+			return fmt.Errorf("invalid move %s: %w", uci, err)
+		}
+
+		san := chess.AlgebraicNotation{}.Encode(pos, move)
+		white := pos.Turn() == chess.White
+
+		var analysis *messages.MoveAnalysis
+		if j.Report != nil && ply < len(j.Report.Moves) {
+			analysis = &j.Report.Moves[ply]
+		}
+
+		// Black moves are numbered after a comment, and every analyzed move has one
+		switch {
+		case white:
+			tokens = append(tokens, fmt.Sprintf("%d.", moveNumber))
+		case ply == 0 || analysis != nil:
+			tokens = append(tokens, fmt.Sprintf("%d...", moveNumber))
+		}
+
+		if analysis != nil {
+			san += classificationNAGs[analysis.Classification]
+		}
+		tokens = append(tokens, san)
+
+		if analysis != nil {
+			tokens = append(tokens, moveComment(pos, *analysis))
+		}
+
+		pos = pos.Update(move)
+		if !white {
+			moveNumber++
+		}
+	}
+	tokens = append(tokens, result)
+
+	writeWrapped(b, tokens)
+	b.WriteString("\n")
+	return nil
+}
+
+// moveComment returns the comment of an analyzed move: its evaluation and,
+// for a mistake, the move the engine preferred
+func moveComment(pos *chess.Position, m messages.MoveAnalysis) string {
+	comment := fmt.Sprintf("[%%eval %.2f]", float64(m.Eval)/100)
+
+	if m.Classification != "" && m.BestMove != "" {
+		best := m.BestMove
+		if move, err := (chess.UCINotation{}).Decode(pos, m.BestMove); err == nil {
+			best = chess.AlgebraicNotation{}.Encode(pos, move)
+		}
+		comment += fmt.Sprintf(" %s%s, %s was best.", strings.ToUpper(m.Classification[:1]), m.Classification[1:], best)
+	}
+
+	return "{" + comment + "}"
+}
+
+// fullMoveNumber returns the move number of a FEN, 1 when it has none
+func fullMoveNumber(fen string) int {
+	fields := strings.Fields(fen)
+	if len(fields) < 6 {
+		return 1
+	}
+
+	var n int
+	if _, err := fmt.Sscanf(fields[5], "%d", &n); err != nil || n < 1 {
+		return 1
+	}
+	return n
+}
+
+// writeWrapped writes the movetext tokens on lines of at most pgnLineLength
+// characters, a comment longer than a line gets a line of its own
+func writeWrapped(b *strings.Builder, tokens []string) {
+	line := 0
+	for _, token := range tokens {
+		if line > 0 && line+1+len(token) > pgnLineLength {
+			b.WriteString("\n")
+			line = 0
+		}
+		if line > 0 {
+			b.WriteString(" ")
+			line++
+		}
+		b.WriteString(token)
+		line += len(token)
+	}
+	b.WriteString("\n")
+}
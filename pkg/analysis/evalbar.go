@@ -0,0 +1,158 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/color"
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/engine"
+	"github.com/tecu23/eng-server/pkg/events"
+)
+
+// EvalSourceBar marks the evaluations published by the eval bar
+const EvalSourceBar = "eval_bar"
+
+// evalPosition is the latest position of a game waiting for its evaluation
+type evalPosition struct {
+	fen string
+	ply int
+}
+
+// EvalBar evaluates the position of running games after every move on an
+// engine of its own, so the engines playing the games are not disturbed.
+// Only the latest position of each game is evaluated, older ones are dropped
+type EvalBar struct {
+	pool  *engine.Pool
+	depth int
+
+	mu      sync.Mutex
+	pending map[string]evalPosition // Latest unevaluated position by game ID
+	order   []string                // Game IDs in the order their positions arrived
+	wake    chan struct{}
+
+	publisher *events.Publisher
+	logger    *zap.Logger
+}
+
+// NewEvalBar creates an eval bar searching every position at the given depth
+func NewEvalBar(pool *engine.Pool, depth int, publisher *events.Publisher, logger *zap.Logger) *EvalBar {
+	return &EvalBar{
+		pool:      pool,
+		depth:     depth,
+		pending:   make(map[string]evalPosition),
+		wake:      make(chan struct{}, 1),
+		publisher: publisher,
+		logger:    logger,
+	}
+}
+
+// Evaluate queues the current position of a game, replacing the one still waiting
+func (b *EvalBar) Evaluate(gameID, fen string, ply int) {
+	b.mu.Lock()
+	if _, ok := b.pending[gameID]; !ok {
+		b.order = append(b.order, gameID)
+	}
+	b.pending[gameID] = evalPosition{fen: fen, ply: ply}
+	b.mu.Unlock()
+
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next pops the oldest waiting position
+func (b *EvalBar) next() (string, evalPosition, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.order) == 0 {
+		return "", evalPosition{}, false
+	}
+
+	gameID := b.order[0]
+	b.order = b.order[1:]
+
+	pos := b.pending[gameID]
+	delete(b.pending, gameID)
+	return gameID, pos, true
+}
+
+// Run takes an engine from the pool for the eval bar and evaluates the queued
+// positions until the context is done
+func (b *EvalBar) Run(ctx context.Context) error {
+	eng, err := b.pool.GetEngine(ctx)
+	if err != nil {
+		return fmt.Errorf("no engine for the eval bar: %w", err)
+	}
+	defer b.pool.ReturnEngine(eng.ID.String())
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-b.wake:
+		}
+
+		for {
+			gameID, pos, ok := b.next()
+			if !ok {
+				break
+			}
+			b.evaluate(ctx, eng, gameID, pos)
+		}
+	}
+}
+
+// evaluate searches a position and publishes its evaluation
+func (b *EvalBar) evaluate(ctx context.Context, eng *engine.UCIEngine, gameID string, pos evalPosition) {
+	if err := eng.SendCommand(fmt.Sprintf("position fen %s", pos.fen)); err != nil {
+		b.logger.Error("eval bar engine command error", zap.Error(err))
+		return
+	}
+	if err := eng.SendCommand(fmt.Sprintf("go depth %d", b.depth)); err != nil {
+		b.logger.Error("eval bar engine command error", zap.Error(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, searchTimeout)
+	defer cancel()
+
+	if _, err := eng.WaitBestMove(ctx); err != nil {
+		_ = eng.Stop()
+		b.logger.Warn("eval bar search did not finish", zap.String("game_id", gameID), zap.Error(err))
+		return
+	}
+
+	info, ok := eng.LastInfo()
+	if !ok {
+		return
+	}
+
+	// Scores are from the point of view of the side to move
+	turn := color.Color(color.White)
+	if fields := strings.Fields(pos.fen); len(fields) > 1 && fields[1] == color.Black {
+		turn = color.Black
+	}
+
+	b.publisher.Publish(events.Event{
+		Type:   events.EventEvalUpdated,
+		GameID: gameID,
+		Payload: messages.EvalPayload{
+			GameID:  gameID,
+			Color:   turn,
+			Depth:   info.Depth,
+			ScoreCP: info.ScoreCP,
+			Mate:    info.Mate,
+			PV:      info.PV,
+			Source:  EvalSourceBar,
+			Ply:     pos.ply,
+			Final:   true,
+		},
+	})
+}
@@ -0,0 +1,171 @@
+package analysis
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/messages"
+)
+
+// openingPlies are left out of the screening, openings are played from memory
+const openingPlies = 16
+
+// Thresholds from which a screened game is flagged. Few moves say little, so
+// games with fewer screened moves are never flagged
+const (
+	minScreenedMoves  = 15
+	flagEngineMatch   = 90.0 // Percentage of engine choices
+	flagCPLoss        = 8    // Average centipawn loss
+	flagMoveVariation = 0.35 // Move time standard deviation over the mean
+	flagMoveTimeFloor = 1000 // Mean move time in milliseconds below which uniform times are just premoves
+)
+
+// Reasons a game is flagged
+const (
+	ReasonEngineMatch      = "engine_match"
+	ReasonLowCPLoss        = "low_cp_loss"
+	ReasonUniformMoveTimes = "uniform_move_times"
+)
+
+// moveTimeBuckets are the upper bounds of the move time buckets in milliseconds
+var moveTimeBuckets = []int64{1000, 3000, 10000, 30000}
+
+// QueueReview screens the moves of the players of a finished human game for
+// engine assistance, along with the post-game report when it was requested.
+// The game is analyzed once whatever the number of players screened. Reviews
+// alone wait behind the reports players asked for
+func (a *Analyzer) QueueReview(subjects []messages.FairPlaySubject, startFEN string, moves []string, report bool) error {
+	if len(subjects) == 0 {
+		return nil
+	}
+
+	priority := PriorityReview
+	if report {
+		priority = PriorityReport
+	}
+
+	tracked, err := a.enqueue([]Job{{
+		ID:        uuid.New().String(),
+		GameID:    subjects[0].GameID,
+		Priority:  priority,
+		Status:    JobQueued,
+		StartFEN:  startFEN,
+		Moves:     moves,
+		CreatedAt: time.Now().UTC(),
+		FairPlay:  subjects,
+	}})
+	if err != nil {
+		return err
+	}
+
+	a.logger.Info("fair play review queued",
+		zap.String("job_id", tracked[0].JobID),
+		zap.String("game_id", subjects[0].GameID),
+		zap.Int("players", len(subjects)),
+		zap.String("priority", string(priority)))
+
+	return nil
+}
+
+// fairPlayReport screens the moves of the player in the analysis of their game
+func fairPlayReport(subject messages.FairPlaySubject, report messages.AnalysisReportPayload) messages.FairPlayReport {
+	fp := messages.FairPlayReport{
+		GameID:     subject.GameID,
+		UserID:     subject.UserID,
+		Color:      subject.Color,
+		Rated:      subject.Rated,
+		AnalyzedAt: time.Now().UTC(),
+	}
+
+	var matches, loss int
+	var times []int64
+	for i, m := range report.Moves {
+		if m.Color != subject.Color || m.Ply <= openingPlies {
+			continue
+		}
+
+		// The last move of a game has no engine choice to compare with
+		if m.BestMove == "" {
+			continue
+		}
+
+		fp.Moves++
+		loss += m.CPLoss
+		if m.Move == m.BestMove {
+			matches++
+		}
+		if i < len(subject.MoveTimes) {
+			times = append(times, subject.MoveTimes[i])
+		}
+	}
+
+	fp.MoveTimes = moveTimeDistribution(times)
+	if fp.Moves == 0 {
+		return fp
+	}
+
+	fp.EngineMatch = math.Round(float64(matches)*1000/float64(fp.Moves)) / 10
+	fp.AverageCPLoss = loss / fp.Moves
+
+	if fp.Moves < minScreenedMoves {
+		return fp
+	}
+
+	if fp.EngineMatch < flagEngineMatch {
+		return fp
+	}
+
+	// Strong play alone is no evidence, the engine match needs another sign
+	fp.Reasons = append(fp.Reasons, ReasonEngineMatch)
+	if fp.AverageCPLoss <= flagCPLoss {
+		fp.Reasons = append(fp.Reasons, ReasonLowCPLoss)
+	}
+	if len(times) >= minScreenedMoves && fp.MoveTimes.MeanMs >= flagMoveTimeFloor && fp.MoveTimes.Variation <= flagMoveVariation {
+		fp.Reasons = append(fp.Reasons, ReasonUniformMoveTimes)
+	}
+
+	fp.Flagged = len(fp.Reasons) > 1
+	return fp
+}
+
+// moveTimeDistribution summarizes the time spent on moves
+func moveTimeDistribution(times []int64) messages.MoveTimes {
+	dist := messages.MoveTimes{Buckets: make([]int, len(moveTimeBuckets)+1)}
+	if len(times) == 0 {
+		return dist
+	}
+
+	var sum int64
+	dist.MinMs = times[0]
+	for _, t := range times {
+		sum += t
+		dist.MinMs = min(dist.MinMs, t)
+		dist.MaxMs = max(dist.MaxMs, t)
+
+		bucket := len(moveTimeBuckets)
+		for b, bound := range moveTimeBuckets {
+			if t < bound {
+				bucket = b
+				break
+			}
+		}
+		dist.Buckets[bucket]++
+	}
+
+	mean := float64(sum) / float64(len(times))
+	var squares float64
+	for _, t := range times {
+		squares += (float64(t) - mean) * (float64(t) - mean)
+	}
+	stdDev := math.Sqrt(squares / float64(len(times)))
+
+	dist.MeanMs = int64(mean)
+	dist.StdDevMs = int64(stdDev)
+	if mean > 0 {
+		dist.Variation = math.Round(stdDev/mean*100) / 100
+	}
+	return dist
+}
@@ -0,0 +1,476 @@
+package analysis
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/messages"
+)
+
+// Priority orders the analysis jobs, a job only starts once no job of a
+// higher priority is waiting
+type Priority string
+
+// All the job priorities, from the most urgent
+const (
+	PriorityLive   Priority = "live"   // A game still being played, its player is waiting for the report
+	PriorityReport Priority = "report" // The post-game report of a finished game
+	PriorityReview Priority = "review" // The fair play review of a finished game nobody asked a report for
+	PriorityImport Priority = "import" // Games imported in bulk
+)
+
+// rank returns the position of the priority in the queue order, -1 when unknown
+func (p Priority) rank() int {
+	switch p {
+	case PriorityLive:
+		return 0
+	case PriorityReport:
+		return 1
+	case PriorityReview:
+		return 2
+	case PriorityImport:
+		return 3
+	default:
+		return -1
+	}
+}
+
+// JobStatus represents the lifecycle of an analysis job
+type JobStatus string
+
+// All the job statuses
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobCompleted JobStatus = "completed"
+	JobFailed    JobStatus = "failed"
+	JobCanceled  JobStatus = "canceled"
+)
+
+// finished reports whether the job will not run anymore
+func (s JobStatus) finished() bool {
+	return s == JobCompleted || s == JobFailed || s == JobCanceled
+}
+
+const (
+	// maxQueuedJobs is the number of jobs waiting for an engine the analyzer accepts
+	maxQueuedJobs = 1000
+	// maxFinishedJobs is the number of finished jobs kept for status queries,
+	// the oldest are dropped first
+	maxFinishedJobs = 500
+)
+
+// Errors returned by the job queue
+var (
+	ErrJobNotFound = errors.New("analysis job not found")
+	ErrJobFinished = errors.New("analysis job is already finished")
+)
+
+// Job is a game queued for analysis, kept until it is dropped from the history
+type Job struct {
+	ID         string    `json:"id"`
+	GameID     string    `json:"game_id"`
+	Priority   Priority  `json:"priority"`
+	Status     JobStatus `json:"status"`
+	StartFEN   string    `json:"start_fen"`
+	Moves      []string  `json:"moves"` // Moves in UCI notation
+	Error      string    `json:"error,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+
+	// Games of an imported PGN keep what is needed to export them annotated
+	BatchID    string                          `json:"batch_id,omitempty"`
+	BatchIndex int                             `json:"batch_index,omitempty"` // Position of the game in the PGN, from 0
+	Tags       map[string]string               `json:"tags,omitempty"`        // PGN tags of the game
+	Report     *messages.AnalysisReportPayload `json:"report,omitempty"`
+
+	FairPlay []messages.FairPlaySubject `json:"fair_play_subjects,omitempty"` // Players screened for engine assistance once analyzed
+}
+
+// JobStore keeps the analysis jobs across restarts
+type JobStore interface {
+	SaveJobs(jobs []Job) error
+	LoadJobs() ([]Job, error)
+}
+
+// trackedJob is a job with its place in the queue
+type trackedJob struct {
+	Job
+	seq    uint64             // Order of arrival, jobs of the same priority run first come first served
+	index  int                // Position in the pending heap, -1 when not waiting
+	cancel context.CancelFunc // Stops the analysis of a running job
+}
+
+// payload describes the job to clients
+func (j *trackedJob) payload() messages.AnalysisJob {
+	return messages.AnalysisJob{
+		JobID:      j.ID,
+		GameID:     j.GameID,
+		Priority:   string(j.Priority),
+		Status:     string(j.Status),
+		Moves:      len(j.Moves),
+		Error:      j.Error,
+		CreatedAt:  j.CreatedAt,
+		StartedAt:  j.StartedAt,
+		FinishedAt: j.FinishedAt,
+	}
+}
+
+// jobHeap holds the waiting jobs, the most urgent first
+type jobHeap []*trackedJob
+
+func (h jobHeap) Len() int { return len(h) }
+
+func (h jobHeap) Less(i, j int) bool {
+	if ri, rj := h[i].Priority.rank(), h[j].Priority.rank(); ri != rj {
+		return ri < rj
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h jobHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *jobHeap) Push(x any) {
+	j := x.(*trackedJob)
+	j.index = len(*h)
+	*h = append(*h, j)
+}
+
+func (h *jobHeap) Pop() any {
+	old := *h
+	j := old[len(old)-1]
+	old[len(old)-1] = nil
+	j.index = -1
+	*h = old[:len(old)-1]
+	return j
+}
+
+// Submit queues a standard chess game for analysis
+func (a *Analyzer) Submit(gameID, startFEN string, moves []string, priority Priority) (messages.AnalysisJob, error) {
+	if priority.rank() < 0 {
+		return messages.AnalysisJob{}, fmt.Errorf("priority must be %s, %s or %s", PriorityLive, PriorityReport, PriorityImport)
+	}
+
+	tracked, err := a.enqueue([]Job{{
+		ID:        uuid.New().String(),
+		GameID:    gameID,
+		Priority:  priority,
+		Status:    JobQueued,
+		StartFEN:  startFEN,
+		Moves:     moves,
+		CreatedAt: time.Now().UTC(),
+	}})
+	if err != nil {
+		return messages.AnalysisJob{}, err
+	}
+
+	a.logger.Info("analysis job queued",
+		zap.String("job_id", tracked[0].JobID),
+		zap.String("game_id", gameID),
+		zap.String("priority", string(priority)))
+
+	return tracked[0], nil
+}
+
+// enqueue queues every job or none of them when the queue has no room left
+func (a *Analyzer) enqueue(jobs []Job) ([]messages.AnalysisJob, error) {
+	a.mu.Lock()
+	if a.pending.Len()+len(jobs) > maxQueuedJobs {
+		a.mu.Unlock()
+		return nil, ErrQueueFull
+	}
+
+	payloads := make([]messages.AnalysisJob, 0, len(jobs))
+	for _, job := range jobs {
+		payloads = append(payloads, a.track(job).payload())
+	}
+	a.mu.Unlock()
+
+	a.persist()
+	for range jobs {
+		a.wakeWorker()
+	}
+	return payloads, nil
+}
+
+// SubmitPGN queues the game of a PGN for analysis
+func (a *Analyzer) SubmitPGN(gameID, pgn string, priority Priority) (messages.AnalysisJob, error) {
+	opt, err := chess.PGN(strings.NewReader(pgn))
+	if err != nil {
+		return messages.AnalysisJob{}, fmt.Errorf("invalid PGN: %w", err)
+	}
+	g := chess.NewGame(opt)
+
+	positions := g.Positions()
+	moves := make([]string, 0, len(g.Moves()))
+	for i, m := range g.Moves() {
+		moves = append(moves, chess.UCINotation{}.Encode(positions[i], m))
+	}
+
+	return a.Submit(gameID, positions[0].String(), moves, priority)
+}
+
+// track adds a job to the queue, or to the history once finished, the caller holds mu
+func (a *Analyzer) track(job Job) *trackedJob {
+	a.seq++
+	j := &trackedJob{Job: job, seq: a.seq, index: -1}
+	a.jobs[j.ID] = j
+
+	switch {
+	case job.Status == JobQueued:
+		heap.Push(&a.pending, j)
+	case job.BatchID == "":
+		a.finished = append(a.finished, j.ID)
+		a.trimFinished()
+	}
+	return j
+}
+
+// Job returns an analysis job
+func (a *Analyzer) Job(id string) (messages.AnalysisJob, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	j, ok := a.jobs[id]
+	if !ok {
+		return messages.AnalysisJob{}, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	return a.describe(j), nil
+}
+
+// Jobs returns the analysis jobs with the given status, every job when
+// empty, the waiting ones in the order they will run then the others from
+// the newest
+func (a *Analyzer) Jobs(status JobStatus) []messages.AnalysisJob {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	list := make([]*trackedJob, 0, len(a.jobs))
+	for _, j := range a.jobs {
+		if status == "" || j.Status == status {
+			list = append(list, j)
+		}
+	}
+
+	sort.Slice(list, func(i, k int) bool {
+		qi, qk := list[i].Status == JobQueued, list[k].Status == JobQueued
+		if qi != qk {
+			return qi
+		}
+		if qi {
+			return jobHeap(list).Less(i, k)
+		}
+		return list[i].seq > list[k].seq
+	})
+
+	jobs := make([]messages.AnalysisJob, 0, len(list))
+	for _, j := range list {
+		jobs = append(jobs, a.describe(j))
+	}
+	return jobs
+}
+
+// describe returns the payload of a job with its place in the queue, the caller holds mu
+func (a *Analyzer) describe(j *trackedJob) messages.AnalysisJob {
+	payload := j.payload()
+	if j.Status != JobQueued {
+		return payload
+	}
+
+	ahead := 0
+	for _, other := range a.pending {
+		if other != j && a.pending.Less(other.index, j.index) {
+			ahead++
+		}
+	}
+	payload.QueuePosition = ahead + 1
+	return payload
+}
+
+// Cancel drops a waiting job or stops a running one
+func (a *Analyzer) Cancel(id string) (messages.AnalysisJob, error) {
+	a.mu.Lock()
+
+	j, ok := a.jobs[id]
+	if !ok {
+		a.mu.Unlock()
+		return messages.AnalysisJob{}, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+
+	switch j.Status {
+	case JobQueued:
+		heap.Remove(&a.pending, j.index)
+		a.finish(j, JobCanceled, nil)
+	case JobRunning:
+		// The worker records the cancellation once the analysis stopped
+		j.cancel()
+	default:
+		a.mu.Unlock()
+		return messages.AnalysisJob{}, ErrJobFinished
+	}
+	payload := j.payload()
+	a.mu.Unlock()
+
+	a.logger.Info("analysis job canceled", zap.String("job_id", id))
+
+	a.persist()
+	return payload, nil
+}
+
+// next takes the most urgent waiting job and marks it running, false when none waits
+func (a *Analyzer) next(ctx context.Context) (*trackedJob, context.Context, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.pending.Len() == 0 {
+		return nil, nil, false
+	}
+
+	j := heap.Pop(&a.pending).(*trackedJob)
+	j.Status = JobRunning
+	j.StartedAt = time.Now().UTC()
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	j.cancel = cancel
+	return j, jobCtx, true
+}
+
+// done records the outcome of a job that ran. A job interrupted by the
+// shutdown of the analyzer waits again, to run after a restart
+func (a *Analyzer) done(ctx context.Context, j *trackedJob, report *messages.AnalysisReportPayload, err error) {
+	a.mu.Lock()
+	j.cancel()
+	j.cancel = nil
+
+	if j.BatchID != "" {
+		j.Report = report
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		j.Status = JobQueued
+		j.StartedAt = time.Time{}
+		heap.Push(&a.pending, j)
+	case errors.Is(err, context.Canceled):
+		a.finish(j, JobCanceled, nil)
+	case err != nil:
+		a.finish(j, JobFailed, err)
+	default:
+		a.finish(j, JobCompleted, nil)
+	}
+	a.mu.Unlock()
+
+	a.persist()
+}
+
+// finish moves a job to the history of finished jobs, the caller holds mu
+func (a *Analyzer) finish(j *trackedJob, status JobStatus, err error) {
+	j.Status = status
+	j.FinishedAt = time.Now().UTC()
+	if err != nil {
+		j.Error = err.Error()
+	}
+
+	// The games of a batch are kept together until the batch expires
+	if j.BatchID == "" {
+		a.finished = append(a.finished, j.ID)
+	}
+	a.trimFinished()
+}
+
+// trimFinished forgets the oldest finished jobs beyond the history size and
+// the games of expired batches, the caller holds mu
+func (a *Analyzer) trimFinished() {
+	for len(a.finished) > maxFinishedJobs {
+		delete(a.jobs, a.finished[0])
+		a.finished = a.finished[1:]
+	}
+
+	expired := time.Now().Add(-batchRetention)
+	for id, j := range a.jobs {
+		if j.BatchID != "" && j.Status.finished() && j.CreatedAt.Before(expired) {
+			delete(a.jobs, id)
+		}
+	}
+}
+
+// wakeWorker tells an idle worker a job is waiting
+func (a *Analyzer) wakeWorker() {
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Restore loads the jobs of the store, jobs that were waiting or running when
+// the server stopped are queued again
+func (a *Analyzer) Restore() error {
+	if a.jobStore == nil {
+		return nil
+	}
+
+	jobs, err := a.jobStore.LoadJobs()
+	if err != nil {
+		return err
+	}
+
+	// Jobs keep their order of arrival
+	sort.SliceStable(jobs, func(i, k int) bool { return jobs[i].CreatedAt.Before(jobs[k].CreatedAt) })
+
+	a.mu.Lock()
+	queued := 0
+	for _, job := range jobs {
+		if job.Priority.rank() < 0 {
+			continue
+		}
+		if !job.Status.finished() {
+			job.Status = JobQueued
+			job.StartedAt = time.Time{}
+			queued++
+		}
+		a.track(job)
+	}
+	a.mu.Unlock()
+
+	a.logger.Info("restored analysis jobs", zap.Int("jobs", len(jobs)), zap.Int("queued", queued))
+
+	for range queued {
+		a.wakeWorker()
+	}
+	return nil
+}
+
+// persist saves every job to the store, when one is configured
+func (a *Analyzer) persist() {
+	if a.jobStore == nil {
+		return
+	}
+
+	a.saveMu.Lock()
+	defer a.saveMu.Unlock()
+
+	a.mu.Lock()
+	jobs := make([]Job, 0, len(a.jobs))
+	for _, j := range a.jobs {
+		jobs = append(jobs, j.Job)
+	}
+	a.mu.Unlock()
+
+	if err := a.jobStore.SaveJobs(jobs); err != nil {
+		a.logger.Error("could not save analysis jobs", zap.Error(err))
+	}
+}
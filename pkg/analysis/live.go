@@ -0,0 +1,44 @@
+package analysis
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// MaxMultiPV is the largest number of candidate lines a live analysis reports
+const MaxMultiPV = 5
+
+// Live is an interactive analysis of a position, streaming the best candidate
+// lines as the search deepens. Analyses of the same position with the same
+// settings share a single engine search
+type Live struct {
+	ID           uuid.UUID
+	ConnectionID uuid.UUID
+	FEN          string
+	MultiPV      int
+	Shared       bool // Whether the analysis joined the search of an earlier one
+
+	search *positionSearch
+
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// Stop unsubscribes the analysis from its search, the engine goes back to the
+// pool once no analysis follows the search anymore
+func (l *Live) Stop() {
+	l.search.unsubscribe(l)
+}
+
+// close marks the analysis stopped
+func (l *Live) close() {
+	l.stopOnce.Do(func() {
+		close(l.done)
+	})
+}
+
+// Done is closed once the analysis stopped
+func (l *Live) Done() <-chan struct{} {
+	return l.done
+}
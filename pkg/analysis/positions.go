@@ -0,0 +1,296 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/engine"
+	"github.com/tecu23/eng-server/pkg/events"
+)
+
+// Positions is the registry of the running live analysis searches. A position
+// requested again while searched, however it was reached, subscribes to the
+// running search instead of taking another engine
+type Positions struct {
+	pool *engine.Pool
+
+	mu       sync.Mutex
+	searches map[string]*positionSearch // Running searches by search key
+
+	publisher *events.Publisher
+	logger    *zap.Logger
+}
+
+// NewPositions creates an empty registry searching on engines of the pool
+func NewPositions(pool *engine.Pool, publisher *events.Publisher, logger *zap.Logger) *Positions {
+	return &Positions{
+		pool:      pool,
+		searches:  make(map[string]*positionSearch),
+		publisher: publisher,
+		logger:    logger,
+	}
+}
+
+// positionSearch is an engine search followed by one or more live analyses
+type positionSearch struct {
+	key       string
+	multiPV   int
+	positions *Positions
+	engine    *engine.UCIEngine
+	lines     []engine.Info // Latest update of each candidate line, indexed by MultiPV - 1
+
+	// Guarded by the mutex of the registry
+	subscribers []*Live
+	stopped     bool
+
+	stop chan struct{}
+}
+
+// searchKey identifies the searches that give the same lines: the position
+// without its move counters, so transpositions share a search, and the
+// settings of the search
+func searchKey(fen string, multiPV, depth int, options map[string]string) string {
+	fields := strings.Fields(fen)
+	if len(fields) > 4 {
+		fields = fields[:4]
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s|%d|%d", strings.Join(fields, " "), multiPV, depth)
+	for _, name := range slices.Sorted(maps.Keys(options)) {
+		fmt.Fprintf(&b, "|%s=%s", name, options[name])
+	}
+	return b.String()
+}
+
+// Start subscribes a live analysis to the search of the position, until the
+// given depth or until stopped when depth is 0. The search starts on an engine
+// from the pool unless the same one is already running
+func (p *Positions) Start(
+	fen string,
+	multiPV, depth int,
+	options map[string]string,
+	connectionID uuid.UUID,
+) (*Live, error) {
+	if multiPV < 1 || multiPV > MaxMultiPV {
+		return nil, fmt.Errorf("multipv must be between 1 and %d", MaxMultiPV)
+	}
+
+	live := &Live{
+		ID:           uuid.New(),
+		ConnectionID: connectionID,
+		FEN:          fen,
+		MultiPV:      multiPV,
+		done:         make(chan struct{}),
+	}
+	key := searchKey(fen, multiPV, depth, options)
+
+	p.mu.Lock()
+	if search, ok := p.searches[key]; ok {
+		live.search = search
+		live.Shared = true
+		search.subscribers = append(search.subscribers, live)
+		lines := search.snapshot(live)
+		p.mu.Unlock()
+
+		p.logger.Info("live analysis joined a running search",
+			zap.String("analysis_id", live.ID.String()),
+			zap.String("fen", fen))
+
+		// The current lines straight away rather than at the next iteration
+		if len(lines.Lines) > 0 {
+			p.publish(live, lines)
+		}
+		return live, nil
+	}
+	p.mu.Unlock()
+
+	search, err := p.startSearch(key, fen, multiPV, depth, options)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	// Another analysis of the position may have started meanwhile, both keep
+	// their search and later ones join the registered one
+	if _, ok := p.searches[key]; !ok {
+		p.searches[key] = search
+	}
+	live.search = search
+	search.subscribers = append(search.subscribers, live)
+	p.mu.Unlock()
+
+	go search.stream()
+
+	return live, nil
+}
+
+// startSearch takes an engine from the pool and starts searching the position
+func (p *Positions) startSearch(key, fen string, multiPV, depth int, options map[string]string) (*positionSearch, error) {
+	eng, err := p.pool.GetEngine(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	// Restored by the pool once the engine is returned, the profile does not
+	// get to change the number of lines
+	if err := eng.SetOptions(options); err != nil {
+		p.pool.ReturnEngine(eng.ID.String())
+		return nil, err
+	}
+	if err := eng.SetOption("MultiPV", strconv.Itoa(multiPV)); err != nil {
+		p.pool.ReturnEngine(eng.ID.String())
+		return nil, fmt.Errorf("engine command error: %w", err)
+	}
+
+	command := "go infinite"
+	if depth > 0 {
+		command = fmt.Sprintf("go depth %d", depth)
+	}
+
+	for _, cmd := range []string{
+		fmt.Sprintf("position fen %s", fen),
+		command,
+	} {
+		if err := eng.SendCommand(cmd); err != nil {
+			p.pool.ReturnEngine(eng.ID.String())
+			return nil, fmt.Errorf("engine command error: %w", err)
+		}
+	}
+
+	return &positionSearch{
+		key:       key,
+		multiPV:   multiPV,
+		positions: p,
+		engine:    eng,
+		lines:     make([]engine.Info, multiPV),
+		stop:      make(chan struct{}),
+	}, nil
+}
+
+// stream publishes the candidate lines to every subscriber whenever the engine
+// completed a set of them, until the search ends or nobody follows it anymore
+func (s *positionSearch) stream() {
+	for {
+		select {
+		case <-s.stop:
+			return
+		case info := <-s.engine.InfoChan:
+			if info.MultiPV < 1 || info.MultiPV > s.multiPV {
+				continue
+			}
+
+			s.positions.mu.Lock()
+			s.lines[info.MultiPV-1] = info
+			s.positions.mu.Unlock()
+
+			// Engines report the lines of an iteration in order, the last one completes it
+			if info.MultiPV == s.multiPV {
+				s.broadcast(false)
+			}
+		case <-s.engine.BestMoveChan:
+			// The search reached its depth
+			s.broadcast(true)
+			s.finish()
+			return
+		}
+	}
+}
+
+// broadcast sends the current candidate lines to every subscriber
+func (s *positionSearch) broadcast(final bool) {
+	s.positions.mu.Lock()
+	updates := make([]messages.AnalysisUpdatePayload, len(s.subscribers))
+	subscribers := slices.Clone(s.subscribers)
+	for i, live := range subscribers {
+		updates[i] = s.snapshot(live)
+		updates[i].Final = final
+	}
+	s.positions.mu.Unlock()
+
+	for i, live := range subscribers {
+		s.positions.publish(live, updates[i])
+	}
+}
+
+// snapshot returns the current lines as an update of the given analysis, the
+// caller holds the mutex of the registry
+func (s *positionSearch) snapshot(live *Live) messages.AnalysisUpdatePayload {
+	payload := messages.AnalysisUpdatePayload{
+		AnalysisID: live.ID.String(),
+		FEN:        live.FEN,
+		Lines:      make([]messages.AnalysisLine, 0, len(s.lines)),
+	}
+
+	for _, info := range s.lines {
+		if len(info.PV) == 0 {
+			continue
+		}
+
+		payload.Depth = max(payload.Depth, info.Depth)
+		payload.Lines = append(payload.Lines, messages.AnalysisLine{
+			MultiPV: info.MultiPV,
+			Move:    info.PV[0],
+			Depth:   info.Depth,
+			ScoreCP: info.ScoreCP,
+			Mate:    info.Mate,
+			PV:      info.PV,
+		})
+	}
+
+	return payload
+}
+
+// publish sends an update to the connection of an analysis
+func (p *Positions) publish(live *Live, payload messages.AnalysisUpdatePayload) {
+	p.publisher.Publish(events.Event{
+		Type:    events.EventAnalysisUpdated,
+		GameID:  live.ID.String(),
+		Payload: payload,
+	})
+}
+
+// unsubscribe stops an analysis, the search ends with its last subscriber
+func (s *positionSearch) unsubscribe(live *Live) {
+	s.positions.mu.Lock()
+	s.subscribers = slices.DeleteFunc(s.subscribers, func(l *Live) bool { return l == live })
+	last := len(s.subscribers) == 0
+	s.positions.mu.Unlock()
+
+	live.close()
+	if last {
+		s.finish()
+	}
+}
+
+// finish ends the search, stops every analysis still following it and gives
+// the engine back to the pool, which stops the search and restores its options
+func (s *positionSearch) finish() {
+	s.positions.mu.Lock()
+	if s.stopped {
+		s.positions.mu.Unlock()
+		return
+	}
+	s.stopped = true
+	if s.positions.searches[s.key] == s {
+		delete(s.positions.searches, s.key)
+	}
+	subscribers := s.subscribers
+	s.subscribers = nil
+	s.positions.mu.Unlock()
+
+	close(s.stop)
+	for _, live := range subscribers {
+		live.close()
+	}
+	s.positions.pool.ReturnEngine(s.engine.ID.String())
+}
@@ -0,0 +1,225 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"github.com/google/uuid"
+
+	"github.com/tecu23/eng-server/internal/color"
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/engine"
+)
+
+// Puzzle verification thresholds in centipawns, from the point of view of the solver
+const (
+	puzzleWinning    = 200 // Evaluation every move of the solution must keep
+	puzzleMaxSecond  = 50  // Best evaluation the second best move may reach, so the solution is unique
+	maxSolutionMoves = 3   // Moves of the solver in the longest solution
+)
+
+// Puzzle difficulties by estimated rating
+const (
+	PuzzleEasy   = "easy"
+	PuzzleMedium = "medium"
+	PuzzleHard   = "hard"
+)
+
+// errNoMultiPV is returned when the engine can not list a second best move,
+// so the unique solution of a puzzle can not be verified
+var errNoMultiPV = errors.New("engine does not support MultiPV")
+
+// extractPuzzles turns the blunders of an analyzed game into puzzles: the
+// position after the blunder is a puzzle when the opponent has a single
+// winning move, verified with a second search listing the two best moves
+func (a *Analyzer) extractPuzzles(eng *engine.UCIEngine, j Job, report messages.AnalysisReportPayload) ([]messages.Puzzle, error) {
+	var blunders []int
+	for i, m := range report.Moves {
+		if m.Classification == Blunder {
+			blunders = append(blunders, i)
+		}
+	}
+	if len(blunders) == 0 {
+		return nil, nil
+	}
+
+	if _, ok := eng.ReportedOption("MultiPV"); !ok {
+		return nil, errNoMultiPV
+	}
+	if err := eng.SetOption("MultiPV", "2"); err != nil {
+		return nil, err
+	}
+
+	fen, err := chess.FEN(j.StartFEN)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start position: %w", err)
+	}
+	positions := []*chess.Position{chess.NewGame(fen).Position()}
+	for _, uci := range j.Moves {
+		pos := positions[len(positions)-1]
+		move, err := chess.UCINotation{}.Decode(pos, uci)
+		if err != nil {
+			return nil, fmt.Errorf("invalid move %s: %w", uci, err)
+		}
+		positions = append(positions, pos.Update(move))
+	}
+
+	var puzzles []messages.Puzzle
+	for _, i := range blunders {
+		blunder := report.Moves[i]
+
+		puzzle, ok, err := a.solve(eng, positions[i+1])
+		if err != nil {
+			return puzzles, err
+		}
+		if !ok {
+			continue
+		}
+
+		puzzle.ID = uuid.New().String()
+		puzzle.GameID = j.GameID
+		puzzle.Ply = blunder.Ply
+		puzzle.Blunder = blunder.Move
+		puzzles = append(puzzles, puzzle)
+	}
+
+	return puzzles, nil
+}
+
+// solve follows the engine line from a position while every move of the
+// solver is the only winning one, it returns false when not even the first
+// move qualifies
+func (a *Analyzer) solve(eng *engine.UCIEngine, start *chess.Position) (messages.Puzzle, bool, error) {
+	puzzle := messages.Puzzle{
+		FEN:       start.String(),
+		Color:     color.Color(start.Turn().String()),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	var firstChanges int
+	pos := start
+
+	for solverMoves := 0; solverMoves < maxSolutionMoves; solverMoves++ {
+		lines, stats, err := a.candidates(eng, pos)
+		if err != nil {
+			return puzzle, false, err
+		}
+
+		// The first move is a puzzle only when there is a choice to make
+		if len(lines) == 0 || (solverMoves == 0 && len(lines) < 2) {
+			break
+		}
+
+		best := lines[0]
+		if !winning(best) || (len(lines) > 1 && !unique(lines[1])) || len(best.PV) == 0 {
+			break
+		}
+
+		if solverMoves == 0 {
+			firstChanges = stats.BestMoveChanges
+		}
+
+		next, err := play(pos, best.PV[0])
+		if err != nil {
+			return puzzle, false, err
+		}
+		puzzle.Solution = append(puzzle.Solution, best.PV[0])
+		puzzle.Mate = best.Mate > 0
+		pos = next
+
+		// The opponent replies with the engine line
+		if pos.Status() != chess.NoMethod || len(best.PV) < 2 {
+			break
+		}
+		if next, err = play(pos, best.PV[1]); err != nil {
+			return puzzle, false, err
+		}
+		if next.Status() != chess.NoMethod {
+			break
+		}
+		puzzle.Solution = append(puzzle.Solution, best.PV[1])
+		pos = next
+	}
+
+	// A solution ends with a move of the solver
+	if len(puzzle.Solution)%2 == 0 && len(puzzle.Solution) > 0 {
+		puzzle.Solution = puzzle.Solution[:len(puzzle.Solution)-1]
+	}
+	if len(puzzle.Solution) == 0 {
+		return puzzle, false, nil
+	}
+
+	puzzle.Rating = puzzleRating(start, puzzle.Solution, firstChanges)
+	puzzle.Difficulty = puzzleDifficulty(puzzle.Rating)
+	return puzzle, true, nil
+}
+
+// candidates searches a position and returns its two best lines
+func (a *Analyzer) candidates(eng *engine.UCIEngine, pos *chess.Position) ([]engine.Info, engine.SearchStats, error) {
+	if err := eng.SendCommand(fmt.Sprintf("position fen %s", pos.String())); err != nil {
+		return nil, engine.SearchStats{}, fmt.Errorf("engine command error: %w", err)
+	}
+	if err := eng.SendCommand(fmt.Sprintf("go depth %d", a.depth)); err != nil {
+		return nil, engine.SearchStats{}, fmt.Errorf("engine command error: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), searchTimeout)
+	defer cancel()
+
+	if _, err := eng.WaitBestMove(ctx); err != nil {
+		_ = eng.Stop()
+		return nil, engine.SearchStats{}, fmt.Errorf("engine did not answer: %w", err)
+	}
+
+	return eng.SearchLines(), eng.LastSearch(), nil
+}
+
+// winning reports whether a line wins for the side to move
+func winning(line engine.Info) bool {
+	return line.Mate > 0 || (line.Mate == 0 && line.ScoreCP >= puzzleWinning)
+}
+
+// unique reports whether the second best line falls far enough behind for
+// the best move to be the only solution
+func unique(second engine.Info) bool {
+	return second.Mate < 0 || (second.Mate == 0 && second.ScoreCP <= puzzleMaxSecond)
+}
+
+// play applies a move in UCI notation to a position
+func play(pos *chess.Position, uci string) (*chess.Position, error) {
+	move, err := chess.UCINotation{}.Decode(pos, uci)
+	if err != nil {
+		return nil, fmt.Errorf("invalid engine move %s: %w", uci, err)
+	}
+	return pos.Update(move), nil
+}
+
+// puzzleRating estimates the rating of a puzzle: longer solutions, a quiet
+// first move and a move the engine only settled on late are harder to find
+func puzzleRating(pos *chess.Position, solution []string, bestMoveChanges int) int {
+	rating := 1000 + 250*(len(solution)/2)
+
+	if move, err := (chess.UCINotation{}).Decode(pos, solution[0]); err == nil {
+		if !move.HasTag(chess.Capture) && !move.HasTag(chess.Check) {
+			rating += 300
+		}
+	}
+
+	rating += 100 * min(bestMoveChanges, 3)
+	return rating
+}
+
+// puzzleDifficulty names the difficulty of a puzzle rating
+func puzzleDifficulty(rating int) string {
+	switch {
+	case rating < 1200:
+		return PuzzleEasy
+	case rating < 1600:
+		return PuzzleMedium
+	default:
+		return PuzzleHard
+	}
+}
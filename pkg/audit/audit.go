@@ -0,0 +1,256 @@
+// Package audit writes a tamper-evident trail of security relevant events
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/pkg/events"
+)
+
+// queueSize is how many events wait for the sink. A trail falling further
+// behind is disconnected rather than holding back publishers, so it never
+// has gaps, it stops and the publisher health check reports it
+const queueSize = 1024
+
+// redacted replaces the value of redacted fields
+const redacted = "[REDACTED]"
+
+// AuditedEvents are the events written to the audit trail
+var AuditedEvents = []events.EventType{
+	events.EventConnectionOpened,
+	events.EventConnectionClosed,
+	events.EventAuthFailed,
+	events.EventAdminAction,
+	events.EventGameCreated,
+	events.EventGameOver,
+	events.EventGameTerminated,
+	events.EventInternalError,
+}
+
+// ErrTampered is returned by Verify when the trail has been altered
+var ErrTampered = errors.New("audit trail has been tampered with")
+
+// Record is a line of the audit trail. Hash covers every other field and the
+// hash of the previous record, so removing or editing a record breaks the chain
+type Record struct {
+	Seq    uint64                 `json:"seq"`
+	Time   time.Time              `json:"time"`
+	Type   events.EventType       `json:"type"`
+	GameID string                 `json:"game_id,omitempty"`
+	Data   map[string]interface{} `json:"data,omitempty"`
+	Prev   string                 `json:"prev"`
+	Hash   string                 `json:"hash"`
+}
+
+// Logger appends the audited events to a sink
+type Logger struct {
+	sink   Sink
+	key    []byte          // HMAC key of the chain, plain SHA-256 when empty
+	redact map[string]bool // Data fields whose values are replaced
+
+	mu   sync.Mutex
+	seq  uint64
+	prev string
+
+	subscriber *events.Subscriber
+	logger     *zap.Logger
+}
+
+// NewLogger creates an audit logger continuing the chain of the sink, if it
+// already holds records, and subscribes it to the audited events
+func NewLogger(
+	sink Sink,
+	key []byte,
+	redact []string,
+	publisher *events.Publisher,
+	logger *zap.Logger,
+) (*Logger, error) {
+	l := &Logger{
+		sink:   sink,
+		key:    key,
+		redact: make(map[string]bool),
+		logger: logger,
+	}
+
+	for _, field := range redact {
+		if field = strings.TrimSpace(field); field != "" {
+			l.redact[field] = true
+		}
+	}
+
+	last, err := sink.Last()
+	if err != nil {
+		return nil, fmt.Errorf("could not resume audit trail: %w", err)
+	}
+	if last != nil {
+		l.seq = last.Seq
+		l.prev = last.Hash
+	}
+
+	l.subscriber = publisher.NewSubscriber("audit", events.SubscriberOptions{
+		QueueSize: queueSize,
+		Overflow:  events.OverflowDisconnect,
+	})
+	for _, eventType := range AuditedEvents {
+		l.subscriber.Subscribe(eventType, l.record)
+	}
+
+	return l, nil
+}
+
+// record appends an event to the trail
+func (l *Logger) record(event events.Event) {
+	data, err := l.data(event.Payload)
+	if err != nil {
+		l.logger.Error("Could not encode audit record", zap.String("type", string(event.Type)), zap.Error(err))
+		data = map[string]interface{}{"error": err.Error()}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rec := Record{
+		Seq:    l.seq + 1,
+		Time:   time.Now().UTC(),
+		Type:   event.Type,
+		GameID: event.GameID,
+		Data:   data,
+		Prev:   l.prev,
+	}
+	rec.Hash = hashRecord(rec, l.key)
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		l.logger.Error("Could not encode audit record", zap.Error(err))
+		return
+	}
+
+	if err := l.sink.Write(line); err != nil {
+		l.logger.Error("Could not write audit record", zap.Uint64("seq", rec.Seq), zap.Error(err))
+		return
+	}
+
+	l.seq = rec.Seq
+	l.prev = rec.Hash
+}
+
+// data converts an event payload to the generic form of its JSON encoding
+// and redacts the configured fields at any depth
+func (l *Logger) data(payload interface{}) (map[string]interface{}, error) {
+	if payload == nil {
+		return nil, nil
+	}
+
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// Numbers keep their exact text, so the record hashes the same once read back
+	var generic interface{}
+	if err := decode(raw, &generic); err != nil {
+		return nil, err
+	}
+
+	data, ok := l.redactValue(generic).(map[string]interface{})
+	if !ok {
+		data = map[string]interface{}{"value": generic}
+	}
+	return data, nil
+}
+
+func (l *Logger) redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, inner := range v {
+			if l.redact[k] {
+				v[k] = redacted
+				continue
+			}
+			v[k] = l.redactValue(inner)
+		}
+		return v
+	case []interface{}:
+		for i, inner := range v {
+			v[i] = l.redactValue(inner)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// Close stops recording and closes the sink
+func (l *Logger) Close() error {
+	l.subscriber.Close()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.sink.Close()
+}
+
+// hashRecord returns the chain hash of a record, computed over its JSON
+// encoding with an empty hash
+func hashRecord(rec Record, key []byte) string {
+	rec.Hash = ""
+	raw, _ := json.Marshal(rec)
+
+	if len(key) > 0 {
+		mac := hmac.New(sha256.New, key)
+		mac.Write(raw)
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify checks the chain of an audit trail, returning the number of records
+// read and ErrTampered at the first record that does not follow its predecessor.
+// A trail split across rotated files is verified by reading them oldest first
+func Verify(r io.Reader, key []byte) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var prev *Record
+	count := 0
+	for scanner.Scan() {
+		var rec Record
+		if err := decode(scanner.Bytes(), &rec); err != nil {
+			return count, fmt.Errorf("record %d: %w", count+1, err)
+		}
+
+		if rec.Hash != hashRecord(rec, key) {
+			return count, fmt.Errorf("record %d: %w", rec.Seq, ErrTampered)
+		}
+		if prev != nil && (rec.Prev != prev.Hash || rec.Seq != prev.Seq+1) {
+			return count, fmt.Errorf("record %d: %w", rec.Seq, ErrTampered)
+		}
+
+		prev = &rec
+		count++
+	}
+
+	return count, scanner.Err()
+}
+
+// decode unmarshals JSON keeping numbers as json.Number
+func decode(raw []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
@@ -0,0 +1,177 @@
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Sink stores the audit records
+type Sink interface {
+	// Write appends an encoded record
+	Write(line []byte) error
+	// Last returns the last stored record, nil when there is none
+	Last() (*Record, error)
+	Close() error
+}
+
+// FileSink appends the records to a file as JSON lines, rotating it once it
+// grows past a size and keeping a number of rotated files as path.1, path.2...
+type FileSink struct {
+	path     string
+	maxBytes int64
+	backups  int
+
+	file *os.File
+	size int64
+}
+
+// NewFileSink opens the audit file for appending, creating it when needed
+func NewFileSink(path string, maxBytes int64, backups int) (*FileSink, error) {
+	s := &FileSink{
+		path:     path,
+		maxBytes: maxBytes,
+		backups:  backups,
+	}
+
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("could not open audit log: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("could not open audit log: %w", err)
+	}
+
+	s.file = file
+	s.size = info.Size()
+	return nil
+}
+
+func (s *FileSink) Write(line []byte) error {
+	if s.maxBytes > 0 && s.size > 0 && s.size+int64(len(line))+1 > s.maxBytes {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(append(line, '\n'))
+	s.size += int64(n)
+	if err != nil {
+		return err
+	}
+
+	// Records must survive a crash right after the event
+	return s.file.Sync()
+}
+
+// rotate shifts the rotated files by one, dropping the oldest, and starts a new file
+func (s *FileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+
+	if s.backups <= 0 {
+		if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return s.open()
+	}
+
+	for i := s.backups - 1; i >= 1; i-- {
+		err := os.Rename(s.rotated(i), s.rotated(i+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.Rename(s.path, s.rotated(1)); err != nil {
+		return err
+	}
+
+	return s.open()
+}
+
+func (s *FileSink) rotated(i int) string {
+	return fmt.Sprintf("%s.%d", s.path, i)
+}
+
+// Last reads the last record of the current file, or of the most recent
+// rotated file when the current one is still empty
+func (s *FileSink) Last() (*Record, error) {
+	for _, path := range []string{s.path, s.rotated(1)} {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		data = bytes.TrimRight(data, "\n")
+		if len(data) == 0 {
+			continue
+		}
+
+		line := data[bytes.LastIndexByte(data, '\n')+1:]
+
+		var rec Record
+		if err := decode(line, &rec); err != nil {
+			return nil, fmt.Errorf("%s: last record is corrupt: %w", path, err)
+		}
+		return &rec, nil
+	}
+
+	return nil, nil
+}
+
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// HTTPSink posts every record as JSON to an external collector
+type HTTPSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPSink creates a sink posting to the given URL
+func NewHTTPSink(url string) *HTTPSink {
+	return &HTTPSink{
+		url:    url,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (s *HTTPSink) Write(line []byte) error {
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(line))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("audit collector answered %s", resp.Status)
+	}
+	return nil
+}
+
+// Last returns nil, the collector keeps the chain and a restart starts a new one
+func (s *HTTPSink) Last() (*Record, error) {
+	return nil, nil
+}
+
+func (s *HTTPSink) Close() error {
+	return nil
+}
@@ -0,0 +1,281 @@
+// Package bench runs a suite of positions through an engine at a fixed search
+// limit and compares the results between runs, so engine upgrades and option
+// changes can be measured
+package bench
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/corentings/chess/v2"
+
+	"github.com/tecu23/eng-server/pkg/engine"
+)
+
+// DefaultSuite is a mix of opening, middlegame and endgame positions, most of
+// them from the Stockfish bench
+var DefaultSuite = []string{
+	"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 10",
+	"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 11",
+	"4rrk1/pp1n3p/3q2pQ/2p1pb2/2PP4/2P3N1/P2B2PP/4RRK1 b - - 7 19",
+	"rq3rk1/ppp2ppp/1bnpb3/3N2B1/3NP3/7P/PPPQ1PP1/2KR3R w - - 7 14",
+	"r1bq1r1k/1pp1n1pp/1p1p4/4p2Q/4Pp2/1BNP4/PPP2PPP/3R1RK1 w - - 2 14",
+	"r3r1k1/2p2ppp/p1p1bn2/8/1q2P3/2NPQN2/PPP3PP/R4RK1 b - - 2 15",
+	"r1bbk1nr/pp3p1p/2n5/1N4p1/2Np1B2/8/PPP2PPP/2KR1B1R w kq - 0 13",
+	"r1bq1rk1/ppp1nppp/4n3/3p3Q/3P4/1BP1B3/PP1N2PP/R4RK1 w - - 1 16",
+	"4r1k1/r1q2ppp/ppp2n2/4P3/5Rb1/1N1BQ3/PPP3PP/R5K1 w - - 1 17",
+	"2rqkb1r/ppp2p2/2npb1p1/1N1Nn2p/2P1PP2/8/PP2B1PP/R1BQK2R b KQ - 0 11",
+	"8/8/8/8/5kp1/P7/8/1K1N4 w - - 0 1",
+	"8/3k4/8/8/8/4B3/4KB2/2B5 w - - 0 1",
+}
+
+// searchTimeout bounds a single search beyond its own limit, so a hung
+// engine does not stall the run
+const searchTimeout = 5 * time.Minute
+
+// Limit is the fixed search limit every position is searched with
+type Limit struct {
+	Depth    int   `json:"depth,omitempty"`
+	MoveTime int64 `json:"movetime_ms,omitempty"`
+}
+
+// goCommand returns the go command of the limit
+func (l Limit) goCommand() (string, error) {
+	switch {
+	case l.Depth > 0 && l.MoveTime > 0:
+		return "", errors.New("set either a depth or a move time")
+	case l.Depth > 0:
+		return fmt.Sprintf("go depth %d", l.Depth), nil
+	case l.MoveTime > 0:
+		return fmt.Sprintf("go movetime %d", l.MoveTime), nil
+	default:
+		return "", errors.New("a depth or a move time is required")
+	}
+}
+
+// Result is the search of a single position
+type Result struct {
+	FEN      string `json:"fen"`
+	BestMove string `json:"best_move"`
+	Depth    int    `json:"depth"`
+	Nodes    int64  `json:"nodes"`
+	TimeMs   int64  `json:"time_ms"`
+	NPS      int64  `json:"nps"`
+	ScoreCP  int    `json:"score_cp"`
+	Mate     int    `json:"mate,omitempty"`
+}
+
+// Report is a complete run of a suite
+type Report struct {
+	Engine    string            `json:"engine"`
+	Author    string            `json:"author,omitempty"`
+	Options   map[string]string `json:"options,omitempty"` // Options set on the engine for the run
+	Limit     Limit             `json:"limit"`
+	StartedAt time.Time         `json:"started_at"`
+	Nodes     int64             `json:"nodes"`   // Nodes of every search together
+	TimeMs    int64             `json:"time_ms"` // Search time of every search together
+	NPS       int64             `json:"nps"`     // Nodes per second over the whole run
+	Results   []Result          `json:"results"`
+}
+
+// Run searches every position of the suite with the engine, which must be
+// leased to the caller
+func Run(ctx context.Context, eng *engine.UCIEngine, suite []string, limit Limit) (Report, error) {
+	goCommand, err := limit.goCommand()
+	if err != nil {
+		return Report{}, err
+	}
+
+	meta := eng.Metadata()
+	report := Report{
+		Engine:    meta.Name,
+		Author:    meta.Author,
+		Options:   eng.Options(),
+		Limit:     limit,
+		StartedAt: time.Now().UTC(),
+	}
+
+	for _, fen := range suite {
+		result, err := search(ctx, eng, fen, goCommand)
+		if err != nil {
+			return report, fmt.Errorf("position %s: %w", fen, err)
+		}
+
+		report.Results = append(report.Results, result)
+		report.Nodes += result.Nodes
+		report.TimeMs += result.TimeMs
+	}
+
+	report.NPS = nps(report.Nodes, report.TimeMs)
+	return report, nil
+}
+
+// search runs a single search from a clean engine state
+func search(ctx context.Context, eng *engine.UCIEngine, fen, goCommand string) (Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, searchTimeout)
+	defer cancel()
+
+	// Every search starts with an empty hash, as in a new game
+	if err := eng.NewGame(ctx, eng.Options()); err != nil {
+		return Result{}, err
+	}
+
+	if err := eng.SendCommand("position fen " + fen); err != nil {
+		return Result{}, err
+	}
+	if err := eng.SendCommand(goCommand); err != nil {
+		return Result{}, err
+	}
+
+	bestMove, err := eng.WaitBestMove(ctx)
+	if err != nil {
+		_ = eng.Stop()
+		return Result{}, err
+	}
+
+	stats := eng.LastSearch()
+	result := Result{
+		FEN:      fen,
+		BestMove: bestMove,
+		Depth:    stats.Depth,
+		Nodes:    stats.Nodes,
+		TimeMs:   stats.Elapsed.Milliseconds(),
+	}
+	result.NPS = nps(result.Nodes, result.TimeMs)
+
+	if info, ok := eng.LastInfo(); ok {
+		result.ScoreCP = info.ScoreCP
+		result.Mate = info.Mate
+	}
+
+	return result, nil
+}
+
+// nps returns the nodes per second of a search
+func nps(nodes, ms int64) int64 {
+	if ms <= 0 {
+		return 0
+	}
+	return nodes * 1000 / ms
+}
+
+// LoadSuite reads a suite file with one FEN per line, blank lines and lines
+// starting with # are skipped
+func LoadSuite(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var suite []string
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		fen := strings.TrimSpace(scanner.Text())
+		if fen == "" || strings.HasPrefix(fen, "#") {
+			continue
+		}
+
+		if _, err := chess.FEN(fen); err != nil {
+			return nil, fmt.Errorf("line %d: invalid FEN: %w", line, err)
+		}
+		suite = append(suite, fen)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(suite) == 0 {
+		return nil, errors.New("the suite has no positions")
+	}
+	return suite, nil
+}
+
+// Save writes the report as JSON
+func (r Report) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Load reads a report written by Save
+func Load(path string) (Report, error) {
+	var r Report
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return r, fmt.Errorf("invalid report %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// Comparison measures a run against a baseline run of the same suite
+type Comparison struct {
+	Positions int     // Positions searched in both runs
+	Agreement int     // Positions where both runs found the same best move
+	NodesDiff float64 // Relative change of the total nodes, 0.1 is 10% more
+	NPSDiff   float64 // Relative change of the nodes per second
+	Differing []Diff  // Positions where the best moves differ
+}
+
+// Diff is a position where the runs found different best moves
+type Diff struct {
+	FEN      string
+	Baseline string
+	Current  string
+}
+
+// Compare matches the positions of the report with those of the baseline
+func (r Report) Compare(baseline Report) Comparison {
+	base := make(map[string]Result, len(baseline.Results))
+	for _, result := range baseline.Results {
+		base[result.FEN] = result
+	}
+
+	var c Comparison
+	var nodes, baseNodes, ms, baseMs int64
+
+	for _, result := range r.Results {
+		baseResult, ok := base[result.FEN]
+		if !ok {
+			continue
+		}
+
+		c.Positions++
+		if result.BestMove == baseResult.BestMove {
+			c.Agreement++
+		} else {
+			c.Differing = append(c.Differing, Diff{FEN: result.FEN, Baseline: baseResult.BestMove, Current: result.BestMove})
+		}
+
+		nodes += result.Nodes
+		ms += result.TimeMs
+		baseNodes += baseResult.Nodes
+		baseMs += baseResult.TimeMs
+	}
+
+	c.NodesDiff = relative(nodes, baseNodes)
+	c.NPSDiff = relative(nps(nodes, ms), nps(baseNodes, baseMs))
+	return c
+}
+
+// relative returns the relative change from the baseline value
+func relative(value, baseline int64) float64 {
+	if baseline == 0 {
+		return 0
+	}
+	return float64(value-baseline) / float64(baseline)
+}
@@ -0,0 +1,118 @@
+// Package book provides Polyglot opening book lookups
+package book
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+
+	"github.com/corentings/chess/v2"
+)
+
+// Selection defines how a move is picked among the book moves of a position
+type Selection string
+
+// All the supported selection strategies
+const (
+	SelectBest     Selection = "best"     // Always play the move with the highest weight
+	SelectWeighted Selection = "weighted" // Pick randomly, proportionally to the move weights
+	SelectUniform  Selection = "uniform"  // Pick randomly among all book moves
+)
+
+// Options configures how a game uses the book
+type Options struct {
+	MaxPly    int       // Book moves are only played within this many plies, 0 means no limit
+	Selection Selection // How moves are picked among the candidates, weighted when empty
+}
+
+// Rand picks among the book moves for the random selections, so a seeded game
+// replays its book moves
+type Rand interface {
+	Intn(n int) int
+}
+
+// Book is a Polyglot (.bin) opening book
+type Book struct {
+	book *chess.PolyglotBook
+}
+
+// Open loads a Polyglot book from disk
+func Open(path string) (*Book, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening book: %w", err)
+	}
+	defer f.Close()
+
+	b, err := chess.LoadFromReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("error reading book: %w", err)
+	}
+
+	return &Book{book: b}, nil
+}
+
+// Move returns a book move in UCI notation for the position, or false when
+// the position is out of book or beyond the configured depth. Random
+// selections draw from rng, or from math/rand when it is nil
+func (b *Book) Move(fen string, ply int, opts Options, rng Rand) (string, bool) {
+	if opts.MaxPly > 0 && ply >= opts.MaxPly {
+		return "", false
+	}
+
+	hash, err := chess.NewZobristHasher().HashPosition(fen)
+	if err != nil {
+		return "", false
+	}
+
+	entries := b.book.FindMoves(chess.ZobristHashToUint64(hash))
+	if len(entries) == 0 {
+		return "", false
+	}
+
+	if rng == nil {
+		rng = globalRand{}
+	}
+
+	entry := pick(entries, opts.Selection, rng)
+	move := chess.DecodeMove(entry.Move).ToMove()
+
+	return chess.UCINotation{}.Encode(nil, &move), true
+}
+
+// pick selects one entry according to the selection strategy, entries are
+// sorted by descending weight
+func pick(entries []chess.PolyglotEntry, selection Selection, rng Rand) chess.PolyglotEntry {
+	switch selection {
+	case SelectUniform:
+		return entries[rng.Intn(len(entries))]
+
+	case SelectWeighted, "":
+		total := 0
+		for _, e := range entries {
+			total += int(e.Weight)
+		}
+		if total == 0 {
+			return entries[rng.Intn(len(entries))]
+		}
+
+		r := rng.Intn(total)
+		for _, e := range entries {
+			r -= int(e.Weight)
+			if r < 0 {
+				return e
+			}
+		}
+		return entries[0]
+
+	default:
+		return entries[0]
+	}
+}
+
+// globalRand draws from the shared source of math/rand
+type globalRand struct{}
+
+func (globalRand) Intn(n int) int {
+	return rand.Intn(n)
+}
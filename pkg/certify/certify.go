@@ -0,0 +1,180 @@
+// Package certify signs the results of finished games and verifies the signatures
+package certify
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/tecu23/eng-server/internal/messages"
+)
+
+// Signature algorithms of the certificates
+const (
+	AlgorithmHMAC    = "hmac-sha256"
+	AlgorithmEd25519 = "ed25519"
+)
+
+// signedVersion prefixes the signed text, so the fields may change without
+// an old signature verifying a new layout
+const signedVersion = "eng-server result v1"
+
+// ErrInvalidSignature is returned for a certificate the key did not sign
+var ErrInvalidSignature = errors.New("invalid result signature")
+
+// Signer signs results with a server key, either an HMAC secret only the
+// server knows or an Ed25519 key whose public half anyone may verify with
+type Signer struct {
+	algorithm string
+	keyID     string
+	secret    []byte             // HMAC secret
+	private   ed25519.PrivateKey // Ed25519 key
+}
+
+// NewHMACSigner creates a signer keyed with an HMAC secret
+func NewHMACSigner(secret []byte) *Signer {
+	sum := sha256.Sum256(secret)
+	return &Signer{
+		algorithm: AlgorithmHMAC,
+		keyID:     hex.EncodeToString(sum[:4]),
+		secret:    secret,
+	}
+}
+
+// NewEd25519Signer creates a signer with an Ed25519 private key
+func NewEd25519Signer(key ed25519.PrivateKey) *Signer {
+	pub := key.Public().(ed25519.PublicKey)
+	sum := sha256.Sum256(pub)
+	return &Signer{
+		algorithm: AlgorithmEd25519,
+		keyID:     hex.EncodeToString(sum[:4]),
+		private:   key,
+	}
+}
+
+// LoadEd25519Signer creates a signer with the Ed25519 private key of a PEM
+// PKCS #8 file, as written by openssl genpkey -algorithm ed25519
+func LoadEd25519Signer(path string) (*Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block in %s", path)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key in %s: %w", path, err)
+	}
+
+	private, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s does not hold an Ed25519 key", path)
+	}
+
+	return NewEd25519Signer(private), nil
+}
+
+// Algorithm returns the algorithm of the signatures
+func (s *Signer) Algorithm() string {
+	return s.algorithm
+}
+
+// KeyID returns the ID of the signing key
+func (s *Signer) KeyID() string {
+	return s.keyID
+}
+
+// PublicKey returns the PEM encoded public key results are verified with,
+// empty for an HMAC signer whose secret is not shared
+func (s *Signer) PublicKey() string {
+	if s.private == nil {
+		return ""
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(s.private.Public())
+	if err != nil {
+		return ""
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+// Sign fills in the algorithm, key ID and signature of a certificate
+func (s *Signer) Sign(cert *messages.ResultCertificate) {
+	cert.Algorithm = s.algorithm
+	cert.KeyID = s.keyID
+
+	text := []byte(SignedText(*cert))
+	if s.private != nil {
+		cert.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(s.private, text))
+		return
+	}
+
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write(text)
+	cert.Signature = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks that the certificate was signed with the key of the signer
+// and not altered since
+func (s *Signer) Verify(cert messages.ResultCertificate) error {
+	if cert.Algorithm != s.algorithm || cert.KeyID != s.keyID {
+		return fmt.Errorf("%w: signed with another key", ErrInvalidSignature)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(cert.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	text := []byte(SignedText(cert))
+	if s.private != nil {
+		if !ed25519.Verify(s.private.Public().(ed25519.PublicKey), text, sig) {
+			return ErrInvalidSignature
+		}
+		return nil
+	}
+
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write(text)
+	if !hmac.Equal(mac.Sum(nil), sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// SignedText returns the text a certificate signature covers: the version
+// line, then every field but the signature, one per line in declaration order
+func SignedText(cert messages.ResultCertificate) string {
+	return strings.Join([]string{
+		signedVersion,
+		cert.GameID,
+		cert.Result,
+		cert.Reason,
+		cert.FinalFEN,
+		cert.MovesHash,
+		strconv.FormatInt(cert.WhiteTime, 10),
+		strconv.FormatInt(cert.BlackTime, 10),
+		strconv.FormatInt(cert.EndedAt, 10),
+		cert.Algorithm,
+		cert.KeyID,
+	}, "\n")
+}
+
+// MovesHash returns the hex SHA-256 of moves in UCI notation, as certified
+func MovesHash(moves []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(moves, " ")))
+	return hex.EncodeToString(sum[:])
+}
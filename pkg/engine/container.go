@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"context"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// DefaultContainerRuntime is the runtime used when a container has none configured
+const DefaultContainerRuntime = "docker"
+
+// removeTimeout bounds the wait for the runtime to remove a killed engine container
+const removeTimeout = 10 * time.Second
+
+// Container runs the engine in a Docker or Podman container instead of as a
+// local process. The container has no network, no capabilities and a read-only
+// root filesystem, the CPU and memory limits are applied by the runtime
+type Container struct {
+	Runtime string   // docker, podman or a compatible CLI, DefaultContainerRuntime when empty
+	Image   string   // Image holding the engine
+	Volumes []string // Bind mounts in the runtime's host:container[:options] form, e.g. tablebases
+}
+
+// runtime returns the container CLI to use
+func (c *Container) runtime() string {
+	if c.Runtime == "" {
+		return DefaultContainerRuntime
+	}
+	return c.Runtime
+}
+
+// command builds the run invocation of an engine container. The engine path
+// is the command run in the image, the image entrypoint is used when it is empty
+func (c *Container) command(name, enginePath string, l Limits) *exec.Cmd {
+	args := []string{
+		"run", "--rm", "-i",
+		"--name", name,
+		"--network", "none",
+		"--read-only",
+		"--cap-drop", "ALL",
+		"--security-opt", "no-new-privileges",
+	}
+
+	if l.CPUQuota > 0 {
+		args = append(args, "--cpus", strconv.FormatFloat(l.CPUQuota, 'f', -1, 64))
+	}
+
+	// The same value for memory and swap leaves the engine no swap
+	if l.MemoryBytes > 0 {
+		memory := strconv.FormatInt(l.MemoryBytes, 10)
+		args = append(args, "--memory", memory, "--memory-swap", memory)
+	}
+
+	for _, volume := range c.Volumes {
+		args = append(args, "--volume", volume)
+	}
+
+	args = append(args, c.Image)
+	if enginePath != "" {
+		args = append(args, enginePath)
+	}
+
+	return exec.Command(c.runtime(), args...)
+}
+
+// remove force-removes an engine container, killing the runtime client alone
+// leaves the container running
+func (c *Container) remove(name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), removeTimeout)
+	defer cancel()
+
+	return exec.CommandContext(ctx, c.runtime(), "rm", "-f", name).Run()
+}
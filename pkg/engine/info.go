@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Info is a search update reported by the engine through an "info" line
+type Info struct {
+	Depth   int      // Search depth in plies
+	MultiPV int      // Index of the principal variation, 1 for the best line
+	ScoreCP int      // Score in centipawns from the engine's point of view
+	Mate    int      // Moves to mate, 0 when no mate was found, negative when being mated
+	Nodes   int64    // Nodes searched so far
+	PV      []string // Principal variation in UCI notation
+}
+
+// parseInfo parses a UCI "info" line, it returns false for lines without a score
+func parseInfo(line string) (Info, bool) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || fields[0] != "info" {
+		return Info{}, false
+	}
+
+	info := Info{MultiPV: 1}
+	hasScore := false
+
+	for i := 1; i < len(fields); i++ {
+		switch fields[i] {
+		case "depth":
+			if i+1 < len(fields) {
+				info.Depth, _ = strconv.Atoi(fields[i+1])
+				i++
+			}
+		case "multipv":
+			if i+1 < len(fields) {
+				info.MultiPV, _ = strconv.Atoi(fields[i+1])
+				i++
+			}
+		case "nodes":
+			if i+1 < len(fields) {
+				info.Nodes, _ = strconv.ParseInt(fields[i+1], 10, 64)
+				i++
+			}
+		case "score":
+			if i+2 < len(fields) {
+				value, err := strconv.Atoi(fields[i+2])
+				if err == nil {
+					hasScore = true
+					if fields[i+1] == "mate" {
+						info.Mate = value
+					} else {
+						info.ScoreCP = value
+					}
+				}
+				i += 2
+			}
+		case "pv":
+			// The principal variation always closes the line
+			info.PV = append([]string{}, fields[i+1:]...)
+			i = len(fields)
+		}
+	}
+
+	return info, hasScore
+}
+
+// MateScore is the centipawn value used for a forced mate
+const MateScore = 100000
+
+// Centipawns returns the score in centipawns from the engine's point of view,
+// mates are mapped beyond any material score
+func (i Info) Centipawns() int {
+	switch {
+	case i.Mate > 0:
+		return MateScore - i.Mate
+	case i.Mate < 0:
+		return -MateScore - i.Mate
+	default:
+		return i.ScoreCP
+	}
+}
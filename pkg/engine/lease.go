@@ -0,0 +1,75 @@
+package engine
+
+import "errors"
+
+// ErrNotLeased is returned when a search is sent to an engine that is back in the pool
+var ErrNotLeased = errors.New("engine is not leased")
+
+// State is the ownership state of a pooled engine
+type State int
+
+// All the engine states, a pooled engine goes Idle, Leased, Searching and
+// Leased again for every search, then Resetting and Idle once it is returned.
+// Engines started outside the pool stay leased to their creator
+const (
+	StateIdle      State = iota // In the pool, waiting for a lease
+	StateLeased                 // Owned by a single game or analysis, not searching
+	StateSearching              // Owned and searching
+	StateResetting              // Returned, being cleaned up before it is idle again
+)
+
+func (s State) String() string {
+	switch s {
+	case StateIdle:
+		return "idle"
+	case StateLeased:
+		return "leased"
+	case StateSearching:
+		return "searching"
+	case StateResetting:
+		return "resetting"
+	default:
+		return "unknown"
+	}
+}
+
+// State returns the ownership state of the engine
+func (e *UCIEngine) State() State {
+	e.infoMu.Lock()
+	defer e.infoMu.Unlock()
+
+	return e.state
+}
+
+// acquire leases an idle engine, it fails when the engine is already owned
+func (e *UCIEngine) acquire() bool {
+	e.infoMu.Lock()
+	defer e.infoMu.Unlock()
+
+	if e.state != StateIdle {
+		return false
+	}
+	e.state = StateLeased
+	return true
+}
+
+// release ends the lease of the engine, it fails when the engine was not
+// leased so an engine returned twice only goes back to the pool once
+func (e *UCIEngine) release() bool {
+	e.infoMu.Lock()
+	defer e.infoMu.Unlock()
+
+	if e.state != StateLeased && e.state != StateSearching {
+		return false
+	}
+	e.state = StateResetting
+	return true
+}
+
+// idle marks a returned engine as ready for its next lease
+func (e *UCIEngine) idle() {
+	e.infoMu.Lock()
+	defer e.infoMu.Unlock()
+
+	e.state = StateIdle
+}
@@ -0,0 +1,410 @@
+package engine
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/corentings/chess/v2"
+)
+
+// MockPrefix selects the built-in mock engine, which runs inside the server
+// process so no engine binary is needed. Settings follow the prefix as comma
+// separated name=value pairs, e.g. mock:latency=50ms,jitter=20ms,seed=7
+const MockPrefix = "mock:"
+
+// Defaults of the mock engine settings
+const (
+	defaultMockLatency = 100 * time.Millisecond
+	defaultMockDepth   = 10
+	maxMockDepth       = 64
+)
+
+// errMockKilled is the exit error of a killed mock engine
+var errMockKilled = errors.New("mock engine killed")
+
+// MockScript tells the mock engine how to answer searches. The moves and
+// scores only depend on the seed and the searched position, so a run of the
+// same games gives the same answers whichever engine of the pool plays them
+type MockScript struct {
+	Latency time.Duration // Time a search takes, capped by its movetime or a share of the clock
+	Jitter  time.Duration // Upper bound of the time added to the latency, drawn from the position
+	Seed    int64         // Picks the moves and scores the script does not give
+	Depth   int           // Deepest info line reported by a search
+	Moves   []string      // Moves in UCI notation played at each ply from the start, when legal
+}
+
+// ParseMockScript reads the settings of a mock engine path without its prefix:
+// latency, jitter, seed, depth and moves, the moves separated by spaces
+func ParseMockScript(spec string) (MockScript, error) {
+	script := MockScript{Latency: defaultMockLatency, Depth: defaultMockDepth}
+
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return MockScript{}, fmt.Errorf("mock engine setting %q must be name=value", pair)
+		}
+
+		var err error
+		switch name {
+		case "latency":
+			script.Latency, err = time.ParseDuration(value)
+		case "jitter":
+			script.Jitter, err = time.ParseDuration(value)
+		case "seed":
+			script.Seed, err = strconv.ParseInt(value, 10, 64)
+		case "depth":
+			script.Depth, err = strconv.Atoi(value)
+			if err == nil && (script.Depth < 1 || script.Depth > maxMockDepth) {
+				err = fmt.Errorf("must be between 1 and %d", maxMockDepth)
+			}
+		case "moves":
+			script.Moves = strings.Fields(value)
+		default:
+			return MockScript{}, fmt.Errorf("unknown mock engine setting %s", name)
+		}
+		if err != nil {
+			return MockScript{}, fmt.Errorf("invalid mock engine %s: %w", name, err)
+		}
+	}
+
+	if script.Latency < 0 || script.Jitter < 0 {
+		return MockScript{}, errors.New("mock engine latency and jitter must not be negative")
+	}
+
+	return script, nil
+}
+
+// mockOptions are the options the mock engine reports, the ones the server sets
+var mockOptions = []string{
+	"option name Hash type spin default 16 min 1 max 33554432",
+	"option name Threads type spin default 1 min 1 max 1024",
+	"option name MultiPV type spin default 1 min 1 max 500",
+	"option name Skill Level type spin default 20 min 0 max 20",
+	"option name UCI_LimitStrength type check default false",
+	"option name UCI_Elo type spin default 1320 min 1320 max 3190",
+	"option name SyzygyPath type string default <empty>",
+	"option name Ponder type check default false",
+}
+
+// mockEngine is a UCI engine answering from its script, it reads the commands
+// and writes its output through pipes in place of an engine process
+type mockEngine struct {
+	script MockScript
+
+	in  *io.PipeReader // Commands written by the server
+	out *io.PipeWriter // Output read by the server
+
+	// Only the goroutine running the engine touches these
+	pos     *chess.Position
+	ply     int // Moves played from the start position, to follow the scripted moves
+	multiPV int
+	search  *mockSearch // Latest search, nil before the first one
+
+	done chan struct{} // Closed once the engine stopped
+	err  error         // Why the engine stopped, set before done is closed
+}
+
+// mockSearch is a search running on its own goroutine until it answers
+type mockSearch struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+// startMock starts a mock engine and returns the pipes the server writes its
+// commands to and reads its output from
+func startMock(spec string) (*mockEngine, io.WriteCloser, io.ReadCloser, error) {
+	script, err := ParseMockScript(spec)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	m := &mockEngine{
+		script:  script,
+		in:      inR,
+		out:     outW,
+		pos:     chess.StartingPosition(),
+		multiPV: 1,
+		done:    make(chan struct{}),
+	}
+	go m.run()
+
+	return m, inW, outR, nil
+}
+
+// wait blocks until the engine stopped, like waiting for a process
+func (m *mockEngine) wait() error {
+	<-m.done
+	return m.err
+}
+
+// kill stops the engine at once, its pending output is dropped
+func (m *mockEngine) kill() error {
+	m.in.CloseWithError(errMockKilled)
+	m.out.CloseWithError(errMockKilled)
+	return nil
+}
+
+// run processes the commands until quit or until the engine is killed
+func (m *mockEngine) run() {
+	defer close(m.done)
+
+	scanner := bufio.NewScanner(m.in)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		if fields[0] == "quit" {
+			// Closed first so a search answering nobody does not block
+			m.out.Close()
+			m.stopSearch()
+			return
+		}
+		m.command(fields)
+	}
+
+	m.err = scanner.Err()
+	if m.err == nil {
+		m.err = io.ErrUnexpectedEOF
+	}
+	m.out.CloseWithError(m.err)
+	m.stopSearch()
+}
+
+func (m *mockEngine) command(fields []string) {
+	switch fields[0] {
+	case "uci":
+		m.write("id name eng-server mock")
+		m.write("id author eng-server")
+		for _, opt := range mockOptions {
+			m.write(opt)
+		}
+		m.write("uciok")
+
+	case "isready":
+		m.write("readyok")
+
+	case "ucinewgame":
+		m.stopSearch()
+		m.pos, m.ply = chess.StartingPosition(), 0
+
+	case "setoption":
+		line := strings.Join(fields, " ")
+		if rest, ok := strings.CutPrefix(line, "setoption name MultiPV value "); ok {
+			if n, err := strconv.Atoi(rest); err == nil && n > 0 {
+				m.multiPV = n
+			}
+		}
+
+	case "position":
+		m.position(fields[1:])
+
+	case "go":
+		m.stopSearch()
+		m.startSearch(fields[1:])
+
+	case "stop":
+		m.stopSearch()
+	}
+}
+
+// position sets up the position of the next search
+func (m *mockEngine) position(args []string) {
+	pos := chess.StartingPosition()
+	ply := 0
+
+	i := 0
+	if len(args) > 0 && args[0] == "fen" {
+		end := len(args)
+		if j := slices.Index(args, "moves"); j >= 0 {
+			end = j
+		}
+
+		opt, err := chess.FEN(strings.Join(args[1:end], " "))
+		if err != nil {
+			m.write("info string invalid position: " + err.Error())
+			return
+		}
+		pos = chess.NewGame(opt).Position()
+		ply = -1 // Scripted moves are played from the start position only
+		i = end
+	} else if len(args) > 0 && args[0] == "startpos" {
+		i = 1
+	}
+
+	if i < len(args) && args[i] == "moves" {
+		for _, uci := range args[i+1:] {
+			move, err := (chess.UCINotation{}).Decode(pos, uci)
+			if err != nil {
+				m.write("info string invalid move " + uci)
+				return
+			}
+			pos = pos.Update(move)
+			if ply >= 0 {
+				ply++
+			}
+		}
+	}
+
+	m.pos, m.ply = pos, ply
+}
+
+// startSearch answers the go command on its own goroutine, so stop and
+// isready are processed while the engine thinks
+func (m *mockEngine) startSearch(args []string) {
+	limits := make(map[string]int64)
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "infinite", "ponder":
+			limits[args[i]] = 1
+		case "depth", "movetime", "wtime", "btime", "winc", "binc", "movestogo", "nodes":
+			if i+1 < len(args) {
+				if n, err := strconv.ParseInt(args[i+1], 10, 64); err == nil {
+					limits[args[i]] = n
+				}
+				i++
+			}
+		}
+	}
+
+	h := m.hash()
+
+	think := m.script.Latency
+	if m.script.Jitter > 0 {
+		think += time.Duration(h % uint64(m.script.Jitter+1))
+	}
+	own := "wtime"
+	if m.pos.Turn() == chess.Black {
+		own = "btime"
+	}
+	switch {
+	case limits["movetime"] > 0:
+		think = min(think, time.Duration(limits["movetime"])*time.Millisecond)
+	case limits[own] > 0:
+		// A share of the clock, so the mock never loses on time
+		think = min(think, time.Duration(limits[own])*time.Millisecond/20)
+	}
+
+	depth := m.script.Depth
+	if d := limits["depth"]; d > 0 {
+		depth = min(int(d), maxMockDepth)
+	}
+
+	s := &mockSearch{stop: make(chan struct{}), done: make(chan struct{})}
+	m.search = s
+
+	go m.think(s, m.candidates(h), int(h%41)-20, depth, think, limits["infinite"] == 1 || limits["ponder"] == 1)
+}
+
+// stopSearch ends the latest search and waits for its best move
+func (m *mockEngine) stopSearch() {
+	s := m.search
+	if s == nil {
+		return
+	}
+
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+	<-s.done
+}
+
+// think reports the search one depth at a time over the thinking time, then
+// answers with the first candidate. Infinite searches wait to be stopped
+func (m *mockEngine) think(s *mockSearch, moves []string, score, depth int, think time.Duration, infinite bool) {
+	defer close(s.done)
+
+	if len(moves) == 0 {
+		m.write("info depth 0 score cp 0")
+		if infinite {
+			<-s.stop
+		}
+		m.write("bestmove (none)")
+		return
+	}
+
+	started := time.Now()
+	step := think / time.Duration(depth)
+	timer := time.NewTimer(step)
+	defer timer.Stop()
+
+	stopped := false
+	for d := 1; d <= depth && !stopped; d++ {
+		select {
+		case <-timer.C:
+			timer.Reset(step)
+		case <-s.stop:
+			stopped = true
+			continue
+		}
+
+		elapsed := max(time.Since(started).Milliseconds(), 1)
+		nodes := int64(d) * 1000
+		for i, move := range moves[:min(m.multiPV, len(moves))] {
+			m.write(fmt.Sprintf("info depth %d seldepth %d multipv %d score cp %d nodes %d nps %d time %d pv %s",
+				d, d, i+1, score-10*i, nodes, nodes*1000/elapsed, elapsed, move))
+		}
+	}
+
+	if infinite && !stopped {
+		<-s.stop
+	}
+	m.write("bestmove " + moves[0])
+}
+
+// candidates orders the legal moves of the position: the scripted move first
+// when there is one, the others rotated by the hash of the position
+func (m *mockEngine) candidates(h uint64) []string {
+	valid := m.pos.ValidMoves()
+	moves := make([]string, 0, len(valid))
+	for _, move := range valid {
+		moves = append(moves, (chess.UCINotation{}).Encode(m.pos, &move))
+	}
+	if len(moves) == 0 {
+		return nil
+	}
+
+	slices.Sort(moves)
+	r := int(h % uint64(len(moves)))
+	moves = append(moves[r:], moves[:r]...)
+
+	if m.ply >= 0 && m.ply < len(m.script.Moves) {
+		if i := slices.Index(moves, m.script.Moves[m.ply]); i > 0 {
+			scripted := moves[i]
+			moves = append(moves[:i], moves[i+1:]...)
+			moves = append([]string{scripted}, moves...)
+		}
+	}
+
+	return moves
+}
+
+// hash mixes the seed with the searched position
+func (m *mockEngine) hash() uint64 {
+	f := fnv.New64a()
+	fmt.Fprintf(f, "%d %s", m.script.Seed, m.pos.String())
+	return f.Sum64()
+}
+
+// write sends a line to the server, lines of a killed engine are dropped
+func (m *mockEngine) write(line string) {
+	_, _ = io.WriteString(m.out, line+"\n")
+}
@@ -0,0 +1,150 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Option types defined by the UCI protocol
+const (
+	OptionCheck  = "check"
+	OptionSpin   = "spin"
+	OptionCombo  = "combo"
+	OptionButton = "button"
+	OptionString = "string"
+)
+
+// Option is an option the engine reported in its UCI handshake
+type Option struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`              // check, spin, combo, button or string
+	Default string   `json:"default,omitempty"` // Value the engine starts with, buttons have none
+	Min     *int64   `json:"min,omitempty"`     // Lowest value of a spin option
+	Max     *int64   `json:"max,omitempty"`     // Highest value of a spin option
+	Vars    []string `json:"vars,omitempty"`    // Allowed values of a combo option
+}
+
+// parseOption parses an option line of the UCI handshake, e.g.
+// option name Hash type spin default 16 min 1 max 33554432
+func parseOption(line string) (Option, bool) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || fields[0] != "option" {
+		return Option{}, false
+	}
+
+	// Names and values may contain spaces, they run until the next keyword
+	var opt Option
+	var key string
+	var value []string
+
+	flush := func() {
+		v := strings.Join(value, " ")
+		if v == "<empty>" {
+			v = ""
+		}
+
+		switch key {
+		case "name":
+			opt.Name = v
+		case "type":
+			opt.Type = v
+		case "default":
+			opt.Default = v
+		case "min":
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+				opt.Min = &n
+			}
+		case "max":
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+				opt.Max = &n
+			}
+		case "var":
+			opt.Vars = append(opt.Vars, v)
+		}
+		value = value[:0]
+	}
+
+	for _, field := range fields[1:] {
+		switch field {
+		case "name", "type", "default", "min", "max", "var":
+			// The name comes first, keywords only start once it is known
+			if key == "name" && len(value) == 0 {
+				value = append(value, field)
+				continue
+			}
+			flush()
+			key = field
+		default:
+			value = append(value, field)
+		}
+	}
+	flush()
+
+	if opt.Name == "" || opt.Type == "" {
+		return Option{}, false
+	}
+	return opt, true
+}
+
+// String formats the option as the engine reported it in its UCI handshake
+func (o Option) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "option name %s type %s", o.Name, o.Type)
+
+	if o.Type != OptionButton {
+		def := o.Default
+		if def == "" && o.Type == OptionString {
+			def = "<empty>"
+		}
+		fmt.Fprintf(&b, " default %s", def)
+	}
+	if o.Min != nil {
+		fmt.Fprintf(&b, " min %d", *o.Min)
+	}
+	if o.Max != nil {
+		fmt.Fprintf(&b, " max %d", *o.Max)
+	}
+	for _, v := range o.Vars {
+		fmt.Fprintf(&b, " var %s", v)
+	}
+
+	return b.String()
+}
+
+// validate checks a value against the type and bounds of the option
+func (o Option) validate(value string) error {
+	switch o.Type {
+	case OptionCheck:
+		if value != "true" && value != "false" {
+			return fmt.Errorf("option %s must be true or false", o.Name)
+		}
+
+	case OptionSpin:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("option %s must be an integer", o.Name)
+		}
+		if (o.Min != nil && n < *o.Min) || (o.Max != nil && n > *o.Max) {
+			return fmt.Errorf("option %s must be between %d and %d", o.Name, derefOr(o.Min, n), derefOr(o.Max, n))
+		}
+
+	case OptionCombo:
+		for _, v := range o.Vars {
+			if strings.EqualFold(v, value) {
+				return nil
+			}
+		}
+		return fmt.Errorf("option %s must be one of %s", o.Name, strings.Join(o.Vars, ", "))
+	}
+
+	return nil
+}
+
+// derefOr returns the value of a bound, or the fallback when it is not set
+func derefOr(bound *int64, fallback int64) int64 {
+	if bound == nil {
+		return fallback
+	}
+	return *bound
+}
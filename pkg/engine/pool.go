@@ -1,30 +1,87 @@
 package engine
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 
 	"go.uber.org/zap"
 )
 
+// readyTimeout bounds the wait for an engine to answer isready, when it is
+// started, leased or returned
+const readyTimeout = 5 * time.Second
+
+// ErrNoEngineAvailable is returned when every engine stays busy for the whole wait
+var ErrNoEngineAvailable = errors.New("no engines available in the pool")
+
+// Scaling bounds the size of the pool, which starts engines on demand and
+// stops them again once they idle
+type Scaling struct {
+	MinEngines  int           // Engines started up front and always kept
+	MaxEngines  int           // Most engines running at once
+	IdleTimeout time.Duration // Idle time after which an engine above the minimum is stopped, 0 keeps every engine
+	MaxWait     time.Duration // Longest wait for an engine when the caller's context has no deadline
+}
+
 // Pool manages multiple chess engines
 type Pool struct {
 	engines    map[string]*UCIEngine
-	available  chan string // IDs of available engines
-	maxEngines int         // Maximum number of engine to create
-	enginePath string      // Path to the engine executable
+	available  chan string          // IDs of available engines
+	idleSince  map[string]time.Time // When each available engine was returned
+	scaling    Scaling              // Size bounds of the pool
+	starting   int                  // Engines being started on demand, counted against the maximum
+	enginePath string               // Path to the engine executable
+	limits     Limits               // Resource limits applied to every engine
+	options    map[string]string    // UCI options set on every engine
+	closed     bool                 // Set on shutdown, engines recycled after it are not returned
+	done       chan struct{}        // Closed on shutdown to stop the idle reaper
+	metrics    poolMetrics
 	mu         sync.RWMutex
-	logger     *zap.Logger
+
+	allocMu    sync.RWMutex
+	allocation Allocation // Threads and Hash of every engine, from the host resources or overridden
+
+	logger *zap.Logger
+}
+
+// poolMetrics counts the leases of the pool since startup
+type poolMetrics struct {
+	waiting    int
+	leases     uint64
+	timeouts   uint64
+	scaleUps   uint64
+	scaleDowns uint64
+	waitTime   time.Duration
 }
 
 // NewEnginePool creates a new engine pool
-func NewEnginePool(enginePath string, maxEngines int, logger *zap.Logger) *Pool {
+func NewEnginePool(
+	enginePath string,
+	scaling Scaling,
+	limits Limits,
+	options map[string]string,
+	logger *zap.Logger,
+) *Pool {
+	scaling.MinEngines = max(scaling.MinEngines, 1)
+	scaling.MaxEngines = max(scaling.MaxEngines, scaling.MinEngines)
+
+	cores, memoryMB := hostResources()
+
 	return &Pool{
 		engines:    make(map[string]*UCIEngine),
-		available:  make(chan string, maxEngines),
-		maxEngines: maxEngines,
+		available:  make(chan string, scaling.MaxEngines),
+		idleSince:  make(map[string]time.Time),
+		scaling:    scaling,
 		enginePath: enginePath,
+		limits:     limits,
+		options:    options,
+		done:       make(chan struct{}),
+		allocation: computeAllocation(cores, memoryMB, scaling.MaxEngines, limits),
 		logger:     logger,
 	}
 }
@@ -34,38 +91,278 @@ func (p *Pool) Initialize() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	for i := 0; i < p.maxEngines; i++ {
-		engine, err := NewUCIEngine(p.enginePath, p.logger)
+	for i := 0; i < p.scaling.MinEngines; i++ {
+		engine, err := p.start()
 		if err != nil {
 			return err
 		}
 
 		p.engines[engine.ID.String()] = engine
+		p.idleSince[engine.ID.String()] = time.Now()
 		p.available <- engine.ID.String()
 	}
 
-	p.logger.Info("Engine pool initialized", zap.Int("count", len(p.engines)))
+	if p.scaling.IdleTimeout > 0 && p.scaling.MaxEngines > p.scaling.MinEngines {
+		go p.reap()
+	}
+
+	alloc := p.Allocation()
+	p.logger.Info("Engine pool initialized",
+		zap.Int("count", len(p.engines)),
+		zap.Int("max", p.scaling.MaxEngines),
+		zap.Int("threads", alloc.Threads),
+		zap.Int64("hash_mb", alloc.HashMB))
 	return nil
 }
 
-// GetEngine retrieves an available engine from the pool with timeout
-func (p *Pool) GetEngine() (*UCIEngine, error) {
-	// Try to get an available engine with a timeout
-	select {
-	case engineID := <-p.available:
-		p.mu.RLock()
-		engine, exists := p.engines[engineID]
-		p.mu.RUnlock()
+// Allocation returns the share of the host resources given to every engine
+func (p *Pool) Allocation() Allocation {
+	p.allocMu.RLock()
+	defer p.allocMu.RUnlock()
+
+	return p.allocation
+}
+
+// SetAllocationOverride replaces the computed Threads and Hash of the
+// engines, zero fields go back to the computed values. Engines pick up the
+// new values on their next lease
+func (p *Pool) SetAllocationOverride(o AllocationOverride) (Allocation, error) {
+	if err := o.validate(); err != nil {
+		return Allocation{}, err
+	}
+
+	p.allocMu.Lock()
+	defer p.allocMu.Unlock()
+
+	p.allocation = p.allocation.with(o)
+	return p.allocation, nil
+}
+
+// engineOptions returns the options set on an engine for every game: the
+// pool options and the allocated Threads and Hash, within the bounds the
+// engine reported. Options the pool sets explicitly win over the allocation
+func (p *Pool) engineOptions(engine *UCIEngine) map[string]string {
+	options := make(map[string]string, len(p.options)+2)
+	for name, value := range p.options {
+		options[name] = value
+	}
 
-		if !exists {
-			return nil, errors.New("invalid engine ID from pool")
+	alloc := p.Allocation()
+	for name, value := range map[string]int64{"Threads": int64(alloc.Threads), "Hash": alloc.HashMB} {
+		if _, set := options[name]; set || value <= 0 {
+			continue
 		}
+		if opt, ok := engine.option(name); ok && opt.Type == OptionSpin {
+			options[name] = strconv.FormatInt(clampSpin(opt, value), 10)
+		}
+	}
+	return options
+}
+
+// start starts an engine with the pool options and waits until it is ready
+func (p *Pool) start() (*UCIEngine, error) {
+	engine, err := NewUCIEngine(p.enginePath, p.limits, p.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := engine.SetOptions(p.engineOptions(engine)); err != nil {
+		engine.Close()
+		return nil, err
+	}
+
+	// The engine answers isready once done with the uci handshake
+	ctx, cancel := context.WithTimeout(context.Background(), readyTimeout)
+	defer cancel()
+
+	if err := engine.IsReady(ctx); err != nil {
+		engine.Close()
+		return nil, err
+	}
+	engine.idle()
+
+	return engine, nil
+}
+
+// GetEngine retrieves an engine set up for a new game. It takes an idle
+// engine, starts a new one while the pool is below its maximum, or else waits
+// for an engine to be returned until the context is done. A context without
+// deadline waits at most the MaxWait of the pool
+func (p *Pool) GetEngine(ctx context.Context) (*UCIEngine, error) {
+	if _, ok := ctx.Deadline(); !ok && p.scaling.MaxWait > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.scaling.MaxWait)
+		defer cancel()
+	}
+
+	started := time.Now()
+	engine, err := p.acquire(ctx)
+
+	p.mu.Lock()
+	if err != nil {
+		p.metrics.timeouts++
+	} else {
+		p.metrics.leases++
+		p.metrics.waitTime += time.Since(started)
+	}
+	p.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
 
-		p.logger.Debug("Engine retrieved from pool", zap.String("engine_id", engineID))
+	p.logger.Debug("Engine retrieved from pool", zap.String("engine_id", engine.ID.String()))
+	return engine, nil
+}
+
+// acquire takes an idle engine, grows the pool or queues for a returned engine
+func (p *Pool) acquire(ctx context.Context) (*UCIEngine, error) {
+	select {
+	case engineID, ok := <-p.available:
+		return p.lease(engineID, ok)
+	default:
+	}
+
+	if engine, ok := p.grow(); ok {
 		return engine, nil
+	}
+
+	p.mu.Lock()
+	p.metrics.waiting++
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		p.metrics.waiting--
+		p.mu.Unlock()
+	}()
+
+	select {
+	case engineID, ok := <-p.available:
+		return p.lease(engineID, ok)
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w: %w", ErrNoEngineAvailable, ctx.Err())
+	}
+}
+
+// lease sets up an idle engine for a new game, replacing it when it does not answer
+func (p *Pool) lease(engineID string, ok bool) (*UCIEngine, error) {
+	if !ok {
+		return nil, errors.New("engine pool is shut down")
+	}
+
+	p.mu.Lock()
+	engine, exists := p.engines[engineID]
+	delete(p.idleSince, engineID)
+	p.mu.Unlock()
+
+	if !exists {
+		return nil, errors.New("invalid engine ID from pool")
+	}
+	if !engine.acquire() {
+		return nil, fmt.Errorf("engine %s from the pool is %s", engineID, engine.State())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), readyTimeout)
+	defer cancel()
 
-	case <-time.After(5 * time.Second):
-		return nil, errors.New("no engines available in the pool")
+	// Nothing of the previous game, hash included, leaks into the next one
+	if err := engine.NewGame(ctx, p.engineOptions(engine)); err != nil {
+		p.logger.Error("Engine failed to set up a new game", zap.String("engine_id", engineID), zap.Error(err))
+
+		if engine, err = p.replace(engine); err != nil {
+			return nil, err
+		}
+		engine.acquire()
+	}
+
+	return engine, nil
+}
+
+// grow starts an extra engine for a caller when the pool is below its maximum
+func (p *Pool) grow() (*UCIEngine, bool) {
+	p.mu.Lock()
+	if p.closed || len(p.engines)+p.starting >= p.scaling.MaxEngines {
+		p.mu.Unlock()
+		return nil, false
+	}
+	p.starting++
+	p.mu.Unlock()
+
+	engine, err := p.start()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.starting--
+	if err != nil {
+		p.logger.Error("Could not start an extra engine", zap.Error(err))
+		return nil, false
+	}
+	if p.closed {
+		go engine.Close()
+		return nil, false
+	}
+
+	engine.acquire()
+	p.engines[engine.ID.String()] = engine
+	p.metrics.scaleUps++
+
+	p.logger.Info("Engine pool grew",
+		zap.String("engine_id", engine.ID.String()),
+		zap.Int("size", len(p.engines)))
+	return engine, true
+}
+
+// reap stops the engines idle for longer than the idle timeout, down to the
+// minimum size, until the pool is shut down
+func (p *Pool) reap() {
+	ticker := time.NewTicker(max(p.scaling.IdleTimeout/2, time.Second))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.shrink()
+		}
+	}
+}
+
+// shrink stops the available engines idle for longer than the idle timeout
+func (p *Pool) shrink() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return
+	}
+
+	// Engines are only returned under the lock, so every ID taken here can be put back
+	for n := len(p.available); n > 0; n-- {
+		var engineID string
+		select {
+		case engineID = <-p.available:
+		default:
+			return
+		}
+
+		if len(p.engines) <= p.scaling.MinEngines || time.Since(p.idleSince[engineID]) < p.scaling.IdleTimeout {
+			p.available <- engineID
+			continue
+		}
+
+		engine := p.engines[engineID]
+		delete(p.engines, engineID)
+		delete(p.idleSince, engineID)
+		p.metrics.scaleDowns++
+
+		go engine.Close()
+
+		p.logger.Info("Idle engine stopped",
+			zap.String("engine_id", engineID),
+			zap.Int("size", len(p.engines)))
 	}
 }
 
@@ -82,22 +379,89 @@ func (p *Pool) GetEngineByID(engineID string) (*UCIEngine, error) {
 	return engine, nil
 }
 
-// ReturnEngine returns an engine to the pool
+// ReturnEngine returns an engine to the pool and ends the lease of its
+// caller. The engine is cleaned up in the background and only available again
+// once it is idle, returning it twice has no effect
 func (p *Pool) ReturnEngine(engineID string) {
 	p.mu.RLock()
-	_, exists := p.engines[engineID]
+	engine, exists := p.engines[engineID]
 	p.mu.RUnlock()
 
-	if exists {
-		// Non-blocking send to available channel
-		select {
-		case p.available <- engineID:
-			p.logger.Debug("Engine returned to pool", zap.String("engine_id", engineID))
-		default:
-			p.logger.Warn("Failed to return engine to pool, channel full",
-				zap.String("engine_id", engineID))
+	if !exists {
+		return
+	}
+
+	if !engine.release() {
+		p.logger.Warn("Engine returned without a lease", zap.String("engine_id", engineID))
+		return
+	}
+
+	go p.recycle(engine)
+}
+
+// recycle stops whatever the engine was doing and makes it available again,
+// replacing it when it does not answer
+func (p *Pool) recycle(engine *UCIEngine) {
+	ctx, cancel := context.WithTimeout(context.Background(), readyTimeout)
+	defer cancel()
+
+	if err := engine.Reset(ctx); err != nil {
+		p.logger.Error("Engine failed to reset", zap.String("engine_id", engine.ID.String()), zap.Error(err))
+
+		var replaceErr error
+		if engine, replaceErr = p.replace(engine); replaceErr != nil {
+			return
 		}
 	}
+	engine.idle()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return
+	}
+
+	// Non-blocking send to available channel
+	engineID := engine.ID.String()
+	select {
+	case p.available <- engineID:
+		p.idleSince[engineID] = time.Now()
+		p.logger.Debug("Engine returned to pool", zap.String("engine_id", engineID))
+	default:
+		p.logger.Warn("Failed to return engine to pool, channel full",
+			zap.String("engine_id", engineID))
+	}
+}
+
+// replace closes an engine that stopped answering and starts a new one in its place
+func (p *Pool) replace(old *UCIEngine) (*UCIEngine, error) {
+	p.mu.Lock()
+	delete(p.engines, old.ID.String())
+	p.mu.Unlock()
+
+	// A hung engine may take a while to quit, the pool does not wait for it
+	go old.Close()
+
+	engine, err := p.start()
+	if err != nil {
+		p.logger.Error("Could not start a replacement engine", zap.Error(err))
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		go engine.Close()
+		return nil, errors.New("engine pool is shut down")
+	}
+	p.engines[engine.ID.String()] = engine
+
+	p.logger.Info("Engine replaced",
+		zap.String("old_engine_id", old.ID.String()),
+		zap.String("engine_id", engine.ID.String()))
+	return engine, nil
 }
 
 // Shutdown closes all engines in the pool
@@ -105,6 +469,10 @@ func (p *Pool) Shutdown() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	if p.closed {
+		return
+	}
+
 	for id, engine := range p.engines {
 		if err := engine.Close(); err != nil {
 			p.logger.Error("Error closing engine",
@@ -113,6 +481,8 @@ func (p *Pool) Shutdown() {
 		}
 	}
 
+	p.closed = true
+	close(p.done)
 	close(p.available)
 	p.engines = make(map[string]*UCIEngine)
 
@@ -137,3 +507,81 @@ func (p *Pool) ConfigureEngine(engineID string, options map[string]string) error
 
 	return nil
 }
+
+// ValidateOptions checks options against the options the engines of the pool
+// reported, so a bad value is refused before an engine is leased for it
+func (p *Pool) ValidateOptions(options map[string]string) error {
+	p.mu.RLock()
+	var sample *UCIEngine
+	for _, engine := range p.engines {
+		sample = engine
+		break
+	}
+	p.mu.RUnlock()
+
+	if sample == nil {
+		return errors.New("no engine to validate the options against")
+	}
+
+	for name, value := range options {
+		opt, ok := sample.option(name)
+		if !ok {
+			return fmt.Errorf("engine does not support option %s", name)
+		}
+		if err := opt.validate(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Engines describes every engine of the pool, ordered by ID
+func (p *Pool) Engines() []Metadata {
+	p.mu.RLock()
+	engines := make([]Metadata, 0, len(p.engines))
+	for _, engine := range p.engines {
+		engines = append(engines, engine.Metadata())
+	}
+	p.mu.RUnlock()
+
+	sort.Slice(engines, func(i, j int) bool { return engines[i].ID < engines[j].ID })
+	return engines
+}
+
+// PoolStats describes the occupancy of the pool
+type PoolStats struct {
+	Size       int     `json:"size"`        // Engines started
+	Available  int     `json:"available"`   // Engines waiting for a game
+	InUse      int     `json:"in_use"`      // Engines playing or analyzing
+	Min        int     `json:"min"`         // Engines always kept
+	Max        int     `json:"max"`         // Most engines the pool grows to
+	Waiting    int     `json:"waiting"`     // Callers queued for an engine
+	Leases     uint64  `json:"leases"`      // Engines handed out since startup
+	Timeouts   uint64  `json:"timeouts"`    // Callers that gave up waiting for an engine
+	ScaleUps   uint64  `json:"scale_ups"`   // Engines started on demand
+	ScaleDowns uint64  `json:"scale_downs"` // Idle engines stopped
+	AvgWaitMs  float64 `json:"avg_wait_ms"` // Mean time a lease took, setup included
+}
+
+// Stats returns the current occupancy of the pool
+func (p *Pool) Stats() PoolStats {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	stats := PoolStats{
+		Size:       len(p.engines),
+		Available:  len(p.available),
+		Min:        p.scaling.MinEngines,
+		Max:        p.scaling.MaxEngines,
+		Waiting:    p.metrics.waiting,
+		Leases:     p.metrics.leases,
+		Timeouts:   p.metrics.timeouts,
+		ScaleUps:   p.metrics.scaleUps,
+		ScaleDowns: p.metrics.scaleDowns,
+	}
+	stats.InUse = stats.Size - stats.Available
+	if p.metrics.leases > 0 {
+		stats.AvgWaitMs = float64(p.metrics.waitTime.Milliseconds()) / float64(p.metrics.leases)
+	}
+	return stats
+}
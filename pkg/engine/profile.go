@@ -0,0 +1,125 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// ErrProfileNotFound is returned for a profile name nobody defined
+var ErrProfileNotFound = errors.New("engine profile not found")
+
+// profileName matches the names profiles may have, e.g. analysis-heavy
+var profileName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Profile is a named set of engine options, e.g. bullet with Hash=64
+type Profile struct {
+	Name    string            `json:"name"`
+	Options map[string]string `json:"options"`
+}
+
+// Profiles holds the option profiles operators defined, referenced by name
+// instead of repeating raw options in every request
+type Profiles struct {
+	mu       sync.RWMutex
+	profiles map[string]map[string]string
+}
+
+// NewProfiles creates an empty set of profiles
+func NewProfiles() *Profiles {
+	return &Profiles{profiles: make(map[string]map[string]string)}
+}
+
+// LoadProfiles reads profiles from a JSON file mapping profile names to their
+// options, e.g. {"bullet": {"Hash": "64"}}
+func LoadProfiles(path string) (*Profiles, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading engine profiles: %w", err)
+	}
+
+	var defs map[string]map[string]string
+	if err := json.Unmarshal(data, &defs); err != nil {
+		return nil, fmt.Errorf("invalid engine profiles: %w", err)
+	}
+
+	p := NewProfiles()
+	for name, options := range defs {
+		if err := p.Set(name, options); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Get returns a copy of the options of a profile
+func (p *Profiles) Get(name string) (map[string]string, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	options, ok := p.profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+	}
+
+	copied := make(map[string]string, len(options))
+	for k, v := range options {
+		copied[k] = v
+	}
+	return copied, nil
+}
+
+// Set defines a profile or replaces its options
+func (p *Profiles) Set(name string, options map[string]string) error {
+	if !profileName.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q, use lowercase letters, digits, - and _", name)
+	}
+	if len(options) == 0 {
+		return fmt.Errorf("profile %s has no options", name)
+	}
+
+	copied := make(map[string]string, len(options))
+	for k, v := range options {
+		copied[k] = v
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.profiles[name] = copied
+	return nil
+}
+
+// Delete removes a profile
+func (p *Profiles) Delete(name string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.profiles[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+	}
+	delete(p.profiles, name)
+	return nil
+}
+
+// List returns every profile, ordered by name
+func (p *Profiles) List() []Profile {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	profiles := make([]Profile, 0, len(p.profiles))
+	for name, options := range p.profiles {
+		copied := make(map[string]string, len(options))
+		for k, v := range options {
+			copied[k] = v
+		}
+		profiles = append(profiles, Profile{Name: name, Options: copied})
+	}
+
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles
+}
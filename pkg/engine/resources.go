@@ -0,0 +1,158 @@
+package engine
+
+import (
+	"bufio"
+	"errors"
+	"math"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const (
+	// hashMemoryShare is the share of the host memory the hash tables of
+	// the pool may take together, the rest is left to the server and the OS
+	hashMemoryShare = 0.5
+	// hashLimitShare is the share of the memory limit of an engine its hash
+	// table may take, the engine needs some memory besides it
+	hashLimitShare = 0.75
+)
+
+// Allocation is the share of the host resources given to every engine of
+// the pool, so the pool at its maximum size does not oversubscribe the host
+type Allocation struct {
+	Cores    int   `json:"cores"`     // Logical CPUs of the host
+	MemoryMB int64 `json:"memory_mb"` // Memory of the host, or of its cgroup when lower, 0 when unknown
+	Engines  int   `json:"engines"`   // Most engines the pool runs at once
+
+	AutoThreads int   `json:"auto_threads"` // Threads computed from the host resources
+	AutoHashMB  int64 `json:"auto_hash_mb"` // Hash in MB computed from the host resources, 0 when the memory is unknown
+
+	Override AllocationOverride `json:"override"` // Values set by the operators instead of the computed ones
+
+	Threads int   `json:"threads"` // Threads option set on every engine
+	HashMB  int64 `json:"hash_mb"` // Hash option set on every engine, 0 leaves the engine default
+}
+
+// AllocationOverride replaces the computed allocation, zero fields keep the
+// computed values
+type AllocationOverride struct {
+	Threads int   `json:"threads"`
+	HashMB  int64 `json:"hash_mb"`
+}
+
+// validate checks the values of an override
+func (o AllocationOverride) validate() error {
+	if o.Threads < 0 || o.HashMB < 0 {
+		return errors.New("threads and hash_mb must not be negative")
+	}
+	return nil
+}
+
+// computeAllocation splits the host resources between the engines of the
+// pool, within the CPU quota and memory limit of every engine
+func computeAllocation(cores int, memoryMB int64, engines int, limits Limits) Allocation {
+	engines = max(engines, 1)
+
+	a := Allocation{Cores: cores, MemoryMB: memoryMB, Engines: engines}
+
+	a.AutoThreads = max(cores/engines, 1)
+	if limits.CPUQuota > 0 {
+		a.AutoThreads = min(a.AutoThreads, max(int(math.Ceil(limits.CPUQuota)), 1))
+	}
+
+	if memoryMB > 0 {
+		hash := int64(float64(memoryMB) * hashMemoryShare / float64(engines))
+		if limits.MemoryBytes > 0 {
+			hash = min(hash, int64(float64(limits.MemoryBytes>>20)*hashLimitShare))
+		}
+		a.AutoHashMB = floorPowerOfTwo(max(hash, 1))
+	}
+
+	return a.with(AllocationOverride{})
+}
+
+// with applies an override to the computed allocation
+func (a Allocation) with(o AllocationOverride) Allocation {
+	a.Override = o
+
+	a.Threads = a.AutoThreads
+	if o.Threads > 0 {
+		a.Threads = o.Threads
+	}
+
+	a.HashMB = a.AutoHashMB
+	if o.HashMB > 0 {
+		a.HashMB = o.HashMB
+	}
+	return a
+}
+
+// floorPowerOfTwo rounds down to a power of two, the hash sizes engines
+// use best
+func floorPowerOfTwo(n int64) int64 {
+	p := int64(1)
+	for p*2 <= n {
+		p *= 2
+	}
+	return p
+}
+
+// hostResources returns the logical CPUs and the memory in MB available to
+// the server, the memory is 0 when it can not be read
+func hostResources() (int, int64) {
+	memoryMB := memInfoTotal()
+	if limit := cgroupMemoryLimit(); limit > 0 && (memoryMB == 0 || limit < memoryMB) {
+		memoryMB = limit
+	}
+	return runtime.NumCPU(), memoryMB
+}
+
+// memInfoTotal reads the total memory of a Linux host in MB
+func memInfoTotal() int64 {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kb, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return 0
+			}
+			return kb >> 10
+		}
+	}
+	return 0
+}
+
+// cgroupMemoryLimit reads the memory limit of the cgroup v2 the server runs
+// in, in MB, 0 when there is none
+func cgroupMemoryLimit() int64 {
+	data, err := os.ReadFile("/sys/fs/cgroup/memory.max")
+	if err != nil {
+		return 0
+	}
+
+	bytes, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0 // max means unlimited
+	}
+	return bytes >> 20
+}
+
+// clampSpin bounds a value to the range of a spin option
+func clampSpin(opt Option, v int64) int64 {
+	if opt.Min != nil {
+		v = max(v, *opt.Min)
+	}
+	if opt.Max != nil {
+		v = min(v, *opt.Max)
+	}
+	return v
+}
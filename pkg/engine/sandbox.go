@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"os/exec"
+	"time"
+)
+
+// Limits defines the resource constraints applied to every engine process
+type Limits struct {
+	CPUQuota    float64       // Maximum number of CPU cores the engine may use, 0 disables the limit
+	MemoryBytes int64         // Maximum memory the engine may allocate in bytes, 0 disables the limit
+	Niceness    int           // Scheduling priority of the engine process (-20 to 19)
+	CgroupRoot  string        // Parent cgroup under which a cgroup per engine is created
+	MoveTimeout time.Duration // Wall clock a single search may take before the engine is killed, 0 disables it
+
+	// SeccompWrapper is an optional command (e.g. a minijail or bwrap invocation)
+	// the engine is executed through to apply a seccomp filter
+	SeccompWrapper []string
+
+	// Container runs the engine in a container instead, the runtime applies
+	// the CPU and memory limits and the niceness and seccomp wrapper are unused
+	Container *Container
+}
+
+// DefaultCgroupRoot is the cgroup used as parent for engine cgroups when none is configured
+const DefaultCgroupRoot = "/sys/fs/cgroup/eng-server"
+
+// hasCgroupLimits reports whether any limit requires a cgroup
+func (l Limits) hasCgroupLimits() bool {
+	return l.CPUQuota > 0 || l.MemoryBytes > 0
+}
+
+// command builds the exec.Cmd used to start the engine, wrapping it in
+// the seccomp launcher when one is configured
+func (l Limits) command(name, enginePath string) *exec.Cmd {
+	if l.Container != nil {
+		return l.Container.command(name, enginePath, l)
+	}
+
+	if len(l.SeccompWrapper) == 0 {
+		return exec.Command(enginePath)
+	}
+
+	args := append(append([]string{}, l.SeccompWrapper[1:]...), enginePath)
+	return exec.Command(l.SeccompWrapper[0], args...)
+}
+
+// confine sets up the sandbox of an engine before it starts, so the process
+// runs confined from its first instruction. Containers are confined by their
+// runtime
+func (l Limits) confine(name string, cmd *exec.Cmd) (*sandbox, error) {
+	l.prepareCommand(cmd)
+
+	if l.Container != nil {
+		return &sandbox{}, nil
+	}
+	return l.prepare(name, cmd)
+}
+
+// settle finishes confining a started engine, applying what only applies to
+// a running process
+func (l Limits) settle(sb *sandbox, pid int) error {
+	sb.started()
+
+	if l.Container != nil {
+		return nil
+	}
+	return l.renice(pid)
+}
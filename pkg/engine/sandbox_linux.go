@@ -0,0 +1,142 @@
+//go:build linux
+
+package engine
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// cpuPeriod is the cgroup v2 scheduling period in microseconds
+const cpuPeriod = 100000
+
+// sandbox holds the OS resources created to confine a single engine process
+type sandbox struct {
+	cgroupPath string
+	cgroupDir  *os.File // Cgroup the process is started in, closed once it started
+}
+
+// prepareCommand sets the process attributes required before the engine starts
+func (l Limits) prepareCommand(cmd *exec.Cmd) {
+	// Run the engine in its own process group so a kill reaches any children
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// prepare creates the cgroup of an engine and has the process start in it,
+// so neither the engine nor its children ever run outside the limits
+func (l Limits) prepare(name string, cmd *exec.Cmd) (*sandbox, error) {
+	sb := &sandbox{}
+
+	if !l.hasCgroupLimits() {
+		return sb, nil
+	}
+
+	root := l.CgroupRoot
+	if root == "" {
+		root = DefaultCgroupRoot
+	}
+
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating cgroup root: %w", err)
+	}
+
+	// Delegate the cpu and memory controllers to the engine cgroups
+	if err := writeCgroupFile(root, "cgroup.subtree_control", "+cpu +memory"); err != nil {
+		return nil, err
+	}
+
+	sb.cgroupPath = filepath.Join(root, name)
+	if err := os.Mkdir(sb.cgroupPath, 0o755); err != nil && !os.IsExist(err) {
+		return nil, fmt.Errorf("error creating engine cgroup: %w", err)
+	}
+
+	if l.CPUQuota > 0 {
+		quota := int64(l.CPUQuota * cpuPeriod)
+		if err := writeCgroupFile(sb.cgroupPath, "cpu.max", fmt.Sprintf("%d %d", quota, cpuPeriod)); err != nil {
+			sb.release()
+			return nil, err
+		}
+	}
+
+	if l.MemoryBytes > 0 {
+		limit := strconv.FormatInt(l.MemoryBytes, 10)
+		if err := writeCgroupFile(sb.cgroupPath, "memory.max", limit); err != nil {
+			sb.release()
+			return nil, err
+		}
+		// Never let the engine escape the memory limit through swap
+		_ = writeCgroupFile(sb.cgroupPath, "memory.swap.max", "0")
+	}
+
+	dir, err := os.Open(sb.cgroupPath)
+	if err != nil {
+		sb.release()
+		return nil, fmt.Errorf("error opening engine cgroup: %w", err)
+	}
+	sb.cgroupDir = dir
+
+	// clone3 places the child in the cgroup before it executes anything
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(dir.Fd())
+
+	return sb, nil
+}
+
+// renice sets the scheduling priority of a started engine process
+func (l Limits) renice(pid int) error {
+	if l.Niceness == 0 {
+		return nil
+	}
+
+	if err := syscall.Setpriority(syscall.PRIO_PROCESS, pid, l.Niceness); err != nil {
+		return fmt.Errorf("error setting engine niceness: %w", err)
+	}
+	return nil
+}
+
+// started drops the handle on the cgroup once the process runs in it
+func (sb *sandbox) started() {
+	if sb.cgroupDir != nil {
+		_ = sb.cgroupDir.Close()
+		sb.cgroupDir = nil
+	}
+}
+
+// kill terminates the engine process group
+func (sb *sandbox) kill(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+
+	if err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL); err != nil {
+		return cmd.Process.Kill()
+	}
+
+	return nil
+}
+
+// release removes the engine cgroup, it must be called after the process exited
+func (sb *sandbox) release() {
+	if sb == nil {
+		return
+	}
+	sb.started()
+
+	if sb.cgroupPath == "" {
+		return
+	}
+
+	_ = os.Remove(sb.cgroupPath)
+	sb.cgroupPath = ""
+}
+
+func writeCgroupFile(dir, file, value string) error {
+	if err := os.WriteFile(filepath.Join(dir, file), []byte(value), 0o644); err != nil {
+		return fmt.Errorf("error writing %s: %w", file, err)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+//go:build !linux
+
+package engine
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// sandbox is a no-op on platforms without cgroup support
+type sandbox struct{}
+
+// prepareCommand is a no-op on platforms without process groups
+func (l Limits) prepareCommand(_ *exec.Cmd) {}
+
+// prepare only accepts limits that do not need cgroups or setpriority
+func (l Limits) prepare(_ string, _ *exec.Cmd) (*sandbox, error) {
+	if l.hasCgroupLimits() || l.Niceness != 0 {
+		return nil, errors.New("engine resource limits are only supported on linux")
+	}
+
+	return &sandbox{}, nil
+}
+
+// renice is a no-op, prepare refused any niceness
+func (l Limits) renice(_ int) error {
+	return nil
+}
+
+// started is a no-op on platforms without cgroup support
+func (sb *sandbox) started() {}
+
+// kill terminates the engine process
+func (sb *sandbox) kill(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+
+	return cmd.Process.Kill()
+}
+
+// release is a no-op on platforms without cgroup support
+func (sb *sandbox) release() {}
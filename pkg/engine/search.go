@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// lateChangeFraction is the share of the search time after which a change of
+// the best move counts as late
+const lateChangeFraction = 0.75
+
+// SearchStats describes how the engine reached its last best move
+type SearchStats struct {
+	Depth           int           // Deepest depth reported for the best line
+	Nodes           int64         // Nodes searched
+	Elapsed         time.Duration // Time from the go command to the best move
+	BestMoveChanges int           // Times the first move of the best line changed
+	LastChange      time.Duration // When the best line last changed, from the start of the search
+	Completed       bool          // The engine answered with a best move
+}
+
+// LateChange reports whether the best move changed in the last part of the search
+func (s SearchStats) LateChange() bool {
+	return s.BestMoveChanges > 0 && s.LastChange >= time.Duration(float64(s.Elapsed)*lateChangeFraction)
+}
+
+// searchTracker follows the best line of the current search, guarded by infoMu
+type searchTracker struct {
+	started  time.Time
+	bestMove string
+	stats    SearchStats
+	lines    map[int]Info // Latest update of every line of a MultiPV search
+}
+
+// reset starts tracking a new search
+func (t *searchTracker) reset() {
+	*t = searchTracker{started: time.Now()}
+}
+
+// update records a search update of the best line
+func (t *searchTracker) update(info Info) {
+	if info.Depth > t.stats.Depth {
+		t.stats.Depth = info.Depth
+	}
+	if info.Nodes > t.stats.Nodes {
+		t.stats.Nodes = info.Nodes
+	}
+
+	if len(info.PV) == 0 || info.PV[0] == t.bestMove {
+		return
+	}
+
+	if t.bestMove != "" {
+		t.stats.BestMoveChanges++
+		t.stats.LastChange = time.Since(t.started)
+	}
+	t.bestMove = info.PV[0]
+}
+
+// updateLine records the latest update of a line of the search
+func (t *searchTracker) updateLine(info Info) {
+	if t.lines == nil {
+		t.lines = make(map[int]Info)
+	}
+	t.lines[info.MultiPV] = info
+}
+
+// finish records the end of the search
+func (t *searchTracker) finish() {
+	if t.started.IsZero() || t.stats.Completed {
+		return
+	}
+	t.stats.Elapsed = time.Since(t.started)
+	t.stats.Completed = true
+}
+
+// Trace tags the engine logs with the client request behind the searches that
+// follow, empty for searches nobody requested
+func (e *UCIEngine) Trace(requestID string) {
+	e.infoMu.Lock()
+	defer e.infoMu.Unlock()
+
+	e.trace = requestID
+}
+
+// traceField returns the request ID of the current search as a log field
+func (e *UCIEngine) traceField() zap.Field {
+	e.infoMu.Lock()
+	defer e.infoMu.Unlock()
+
+	if e.trace == "" {
+		return zap.Skip()
+	}
+	return zap.String("request_id", e.trace)
+}
+
+// LastSearch returns the statistics of the latest search
+func (e *UCIEngine) LastSearch() SearchStats {
+	e.infoMu.Lock()
+	defer e.infoMu.Unlock()
+
+	return e.search.stats
+}
+
+// SearchLines returns the latest update of every line of the latest search,
+// best line first. A search with MultiPV set reports one line per candidate move
+func (e *UCIEngine) SearchLines() []Info {
+	e.infoMu.Lock()
+	defer e.infoMu.Unlock()
+
+	lines := make([]Info, 0, len(e.search.lines))
+	for _, info := range e.search.lines {
+		lines = append(lines, info)
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i].MultiPV < lines[j].MultiPV })
+	return lines
+}
+
+// Config returns the options set on the engine, sorted by name, e.g.
+// Hash=64,Threads=2, empty when it runs with its defaults
+func (e *UCIEngine) Config() string {
+	e.infoMu.Lock()
+	defer e.infoMu.Unlock()
+
+	options := make([]string, 0, len(e.options))
+	for name, value := range e.options {
+		options = append(options, name+"="+value)
+	}
+	sort.Strings(options)
+	return strings.Join(options, ",")
+}
+
+// Options returns a copy of the options set on the engine
+func (e *UCIEngine) Options() map[string]string {
+	e.infoMu.Lock()
+	defer e.infoMu.Unlock()
+
+	options := make(map[string]string, len(e.options))
+	for name, value := range e.options {
+		options[name] = value
+	}
+	return options
+}
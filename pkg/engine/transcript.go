@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Directions of the lines of a transcript
+const (
+	TranscriptSent     = "sent"     // Command written to the engine
+	TranscriptReceived = "received" // Line the engine wrote
+)
+
+// TranscriptLine is a line of the UCI dialogue with an engine
+type TranscriptLine struct {
+	Time      time.Time
+	EngineID  uuid.UUID
+	Direction string
+	Line      string
+}
+
+// Transcript records the UCI dialogue of the engines attached to it, keeping
+// the latest lines once full. Engines speaking another protocol are recorded
+// in UCI, as the server sees them
+type Transcript struct {
+	mu      sync.Mutex
+	lines   []TranscriptLine
+	start   int // Index of the oldest line once the transcript wrapped
+	limit   int
+	dropped int // Lines dropped to stay within the limit
+}
+
+// NewTranscript creates a transcript keeping at most limit lines
+func NewTranscript(limit int) *Transcript {
+	return &Transcript{limit: max(limit, 1)}
+}
+
+// add records a line, dropping the oldest one when full
+func (t *Transcript) add(engineID uuid.UUID, direction, line string) {
+	entry := TranscriptLine{Time: time.Now(), EngineID: engineID, Direction: direction, Line: line}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.lines) < t.limit {
+		t.lines = append(t.lines, entry)
+		return
+	}
+
+	t.lines[t.start] = entry
+	t.start = (t.start + 1) % t.limit
+	t.dropped++
+}
+
+// Lines returns the recorded lines from the oldest and how many older ones were dropped
+func (t *Transcript) Lines() ([]TranscriptLine, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lines := make([]TranscriptLine, 0, len(t.lines))
+	lines = append(lines, t.lines[t.start:]...)
+	lines = append(lines, t.lines[:t.start]...)
+	return lines, t.dropped
+}
+
+// Record attaches a transcript to the engine, every command and output line
+// is added to it until another one is attached. A nil transcript stops recording
+func (e *UCIEngine) Record(t *Transcript) {
+	e.infoMu.Lock()
+	defer e.infoMu.Unlock()
+
+	e.transcript = t
+}
+
+// record adds a line to the attached transcript, if any
+func (e *UCIEngine) record(direction, line string) {
+	e.infoMu.Lock()
+	t := e.transcript
+	e.infoMu.Unlock()
+
+	if t != nil {
+		t.add(e.ID, direction, line)
+	}
+}
@@ -2,72 +2,252 @@ package engine
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os/exec"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
+// ErrEngineCrashed is returned when the engine process exited while it was in use
+var ErrEngineCrashed = errors.New("engine process exited")
+
+// handshakeTimeout bounds the wait for uciok when an engine starts
+const handshakeTimeout = 5 * time.Second
+
 // UCIEngine represents a UCI-compatible chess engine
 type UCIEngine struct {
 	ID uuid.UUID
 
-	cmd *exec.Cmd
+	cmd     *exec.Cmd
+	sandbox *sandbox
+	limits  Limits
+	mock    *mockEngine // Built-in engine running in place of the process, nil for real engines
 
 	stdinPipe  io.WriteCloser
 	stdoutPipe io.ReadCloser
 	reader     *bufio.Reader
 
-	mutex        sync.Mutex
+	protocol protocol // Translates the UCI commands for engines speaking another protocol, nil for UCI engines
+
+	stdin        chan writeRequest // Commands for the goroutine owning stdin
 	quitChan     chan struct{}
-	BestMoveChan chan string
+	BestMoveChan chan string   // Best move of the latest search only
+	InfoChan     chan Info     // Search updates, dropped when nobody is listening
+	readyChan    chan struct{} // Signals the readyok answering isready
+	handshake    chan struct{} // Closed once the engine sent uciok
+	uciokOnce    sync.Once     // Closes handshake on the first uciok, engines may repeat it
+	exited       chan struct{} // Closed once the engine process exited
+	exitErr      error         // Exit status of the process, set before exited is closed
+	closing      atomic.Bool   // Set when the engine is asked to quit, so its exit is no crash
+	closeOnce    sync.Once     // Closes quitChan and releases the sandbox once, by Close or abort
+
+	infoMu   sync.Mutex
+	lastInfo *Info             // Latest principal variation update of the current search
+	name     string            // Name reported by the engine in its UCI handshake
+	author   string            // Author reported by the engine in its UCI handshake
+	state    State             // Ownership of the engine
+	searches uint64            // Searches started with go
+	answered uint64            // Searches answered with bestmove, in order
+	search   searchTracker     // Progress of the current search
+	options  map[string]string // Options set on the engine
+	reported []Option          // Options the engine reported in its UCI handshake
+	trace    string            // Request ID of the client request behind the current search
+	tap      func(line string) // Receives every line of the engine output, nil when nobody listens
+
+	transcript *Transcript // Records the dialogue for the game leasing the engine, nil when not recorded
+
+	watchdogMu sync.Mutex
+	watchdog   *time.Timer // Kills the engine when a search exceeds the move timeout
 
 	logger *zap.Logger
 }
 
-// NewUCIEngine starts the engine process and returns a UCIEngine instance.
-func NewUCIEngine(enginePath string, logger *zap.Logger) (*UCIEngine, error) {
-	cmd := exec.Command(enginePath)
+// NewUCIEngine starts the engine process confined by the given limits and returns a UCIEngine instance.
+// The engine is leased to its caller, the pool makes the engines it starts idle
+func NewUCIEngine(enginePath string, limits Limits, logger *zap.Logger) (*UCIEngine, error) {
+	id := uuid.New()
 
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return nil, fmt.Errorf("StdoutPipe error: %w", err)
-	}
+	name := "engine-" + id.String()
+	proto, enginePath := splitProtocol(enginePath)
 
-	stdin, err := cmd.StdinPipe()
-	if err != nil {
-		return nil, fmt.Errorf("StdinPipe error: %w", err)
-	}
-
-	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("error starting engine: %w", err)
+	var (
+		cmd    *exec.Cmd
+		sb     *sandbox
+		mock   *mockEngine
+		stdin  io.WriteCloser
+		stdout io.ReadCloser
+		err    error
+	)
+	if spec, ok := strings.CutPrefix(enginePath, MockPrefix); ok {
+		if proto != nil {
+			return nil, errors.New("the mock engine speaks UCI only")
+		}
+		// The mock runs in the server, the limits confine processes only
+		if mock, stdin, stdout, err = startMock(spec); err != nil {
+			return nil, fmt.Errorf("error starting mock engine: %w", err)
+		}
+		sb = &sandbox{}
+	} else if cmd, sb, stdin, stdout, err = startProcess(name, enginePath, limits); err != nil {
+		return nil, err
 	}
 
 	e := &UCIEngine{
+		ID:           id,
 		cmd:          cmd,
+		sandbox:      sb,
+		limits:       limits,
+		mock:         mock,
 		stdinPipe:    stdin,
 		stdoutPipe:   stdout,
 		reader:       bufio.NewReader(stdout),
+		protocol:     proto,
+		stdin:        make(chan writeRequest),
 		quitChan:     make(chan struct{}),
 		BestMoveChan: make(chan string, 1),
+		InfoChan:     make(chan Info, 16),
+		readyChan:    make(chan struct{}, 1),
+		state:        StateLeased,
+		handshake:    make(chan struct{}),
+		exited:       make(chan struct{}),
 		logger:       logger,
 	}
 
+	// Only these goroutines touch the pipes of the engine
+	go e.writeLoop()
+	go e.readLoop()
+	go e.monitor()
+
 	// Initialize UCI mode
 	if err := e.writeCommand("uci"); err != nil {
+		e.abort()
 		return nil, fmt.Errorf("error sending uci cmd: %w", err)
 	}
 
-	// Some engines print info on startup; you might need to read until you see "uciok"
-	go e.readLoop()
+	// The engine identifies itself and lists its options before uciok
+	select {
+	case <-e.handshake:
+	case <-e.exited:
+		e.abort()
+		return nil, fmt.Errorf("engine exited during the UCI handshake: %v", e.exitErr)
+	case <-time.After(handshakeTimeout):
+		e.abort()
+		return nil, fmt.Errorf("engine did not answer uciok within %s", handshakeTimeout)
+	}
 
 	return e, nil
 }
 
+// startProcess starts the engine process confined by the limits. A failed
+// start leaves no pipe open and no process behind
+func startProcess(name, enginePath string, limits Limits) (*exec.Cmd, *sandbox, io.WriteCloser, io.ReadCloser, error) {
+	cmd := limits.command(name, enginePath)
+
+	var sb *sandbox
+	var pipes []io.Closer
+	fail := func(err error) (*exec.Cmd, *sandbox, io.WriteCloser, io.ReadCloser, error) {
+		for _, p := range pipes {
+			_ = p.Close()
+		}
+		if cmd.Process != nil {
+			_ = sb.kill(cmd)
+			_ = cmd.Wait()
+		}
+		sb.release()
+		return nil, nil, nil, nil, err
+	}
+
+	// The sandbox comes first, the child ends of the pipes are only closed
+	// by the command once it started or was waited for
+	sb, err := limits.confine(name, cmd)
+	if err != nil {
+		return fail(fmt.Errorf("error sandboxing engine: %w", err))
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fail(fmt.Errorf("StdoutPipe error: %w", err))
+	}
+	pipes = append(pipes, stdout)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fail(fmt.Errorf("StdinPipe error: %w", err))
+	}
+	pipes = append(pipes, stdin)
+
+	if err := cmd.Start(); err != nil {
+		return fail(fmt.Errorf("error starting engine: %w", err))
+	}
+
+	if err := limits.settle(sb, cmd.Process.Pid); err != nil {
+		return fail(fmt.Errorf("error sandboxing engine: %w", err))
+	}
+
+	return cmd, sb, stdin, stdout, nil
+}
+
+// abort kills an engine that failed to start
+func (e *UCIEngine) abort() {
+	e.closeOnce.Do(func() {
+		e.closing.Store(true)
+		close(e.quitChan)
+		_ = e.kill()
+		<-e.exited
+		e.sandbox.release()
+	})
+}
+
+// kill terminates the engine process, and its container when it runs in one
+func (e *UCIEngine) kill() error {
+	if e.mock != nil {
+		return e.mock.kill()
+	}
+	if c := e.limits.Container; c != nil {
+		if err := c.remove("engine-" + e.ID.String()); err != nil {
+			e.logger.Error("Error removing engine container", zap.String("engine_id", e.ID.String()), zap.Error(err))
+		}
+	}
+	return e.sandbox.kill(e.cmd)
+}
+
+// monitor waits for the engine process to exit, however it ends
+func (e *UCIEngine) monitor() {
+	if e.mock != nil {
+		e.exitErr = e.mock.wait()
+	} else {
+		e.exitErr = e.cmd.Wait()
+	}
+	close(e.exited)
+
+	if e.closing.Load() {
+		return
+	}
+
+	e.disarmWatchdog()
+	e.logger.Error("Engine process exited unexpectedly",
+		zap.String("engine_id", e.ID.String()),
+		zap.Error(e.exitErr),
+		e.traceField())
+}
+
+// Crashed reports whether the engine process exited without being closed
+func (e *UCIEngine) Crashed() bool {
+	select {
+	case <-e.exited:
+		return !e.closing.Load()
+	default:
+		return false
+	}
+}
+
 func (e *UCIEngine) readLoop() {
 	for {
 		select {
@@ -77,59 +257,504 @@ func (e *UCIEngine) readLoop() {
 			line, err := e.reader.ReadString('\n')
 			if err != nil {
 				if err == io.EOF {
-					e.logger.Error("Engine closed stdout")
+					e.logger.Error("Engine closed stdout", e.traceField())
 				} else {
-					e.logger.Error("Error reading engine output ", zap.Error(err))
+					e.logger.Error("Error reading engine output ", zap.Error(err), e.traceField())
 				}
 				return
 			}
 			line = strings.TrimSpace(line)
-			// Check if the engine sent a best move.
-			if strings.HasPrefix(line, "bestmove") {
-				fields := strings.Fields(line)
-				if len(fields) >= 2 {
-					bestMove := fields[1]
-					// Send bestMove into the channel without blocking.
-					select {
-					case e.BestMoveChan <- bestMove:
-					default:
-					}
+
+			if e.protocol == nil {
+				e.handleLine(line)
+				continue
+			}
+
+			lines, replies := e.protocol.output(line)
+			for _, reply := range replies {
+				if err := e.write(reply); err != nil {
+					e.logger.Error("Error answering engine", zap.Error(err), e.traceField())
 				}
 			}
+			for _, l := range lines {
+				e.handleLine(l)
+			}
+		}
+	}
+}
+
+// handleLine processes a line of UCI output
+func (e *UCIEngine) handleLine(line string) {
+	e.infoMu.Lock()
+	tap := e.tap
+	e.infoMu.Unlock()
+	if tap != nil {
+		tap(line)
+	}
+	e.record(TranscriptReceived, line)
+
+	if name, ok := strings.CutPrefix(line, "id name "); ok {
+		e.infoMu.Lock()
+		e.name = name
+		e.infoMu.Unlock()
+		return
+	}
+
+	if author, ok := strings.CutPrefix(line, "id author "); ok {
+		e.infoMu.Lock()
+		e.author = author
+		e.infoMu.Unlock()
+		return
+	}
+
+	if line == "uciok" {
+		e.uciokOnce.Do(func() {
+			close(e.handshake)
+		})
+		return
+	}
+
+	if line == "readyok" {
+		select {
+		case e.readyChan <- struct{}{}:
+		default:
+		}
+		return
+	}
+
+	if opt, ok := parseOption(line); ok {
+		e.infoMu.Lock()
+		e.reported = append(e.reported, opt)
+		e.infoMu.Unlock()
+		return
+	}
+
+	if info, ok := parseInfo(line); ok {
+		e.infoMu.Lock()
+		if info.MultiPV == 1 {
+			e.lastInfo = &info
+			e.search.update(info)
+		}
+		e.search.updateLine(info)
+		e.infoMu.Unlock()
+
+		select {
+		case e.InfoChan <- info:
+		default:
+		}
+		return
+	}
 
+	// Check if the engine sent a best move.
+	if strings.HasPrefix(line, "bestmove") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 {
+			e.disarmWatchdog()
+			e.answerSearch(fields[1])
 		}
 	}
 }
 
-func (e *UCIEngine) writeCommand(cmd string) error {
-	e.mutex.Lock()
-	defer e.mutex.Unlock()
+// answerSearch matches a best move with the oldest search not answered yet.
+// Engines answer every go with exactly one bestmove, so only the answer to
+// the latest search is delivered and the ones of abandoned searches are dropped
+func (e *UCIEngine) answerSearch(bestMove string) {
+	e.infoMu.Lock()
+	defer e.infoMu.Unlock()
+
+	if e.answered == e.searches {
+		e.logger.Debug("Engine sent a best move without a search", zap.String("engine_id", e.ID.String()))
+		return
+	}
+	e.answered++
+
+	if e.answered != e.searches {
+		e.logger.Debug("Dropped the best move of an abandoned search", zap.String("engine_id", e.ID.String()))
+		return
+	}
+
+	e.search.finish()
+	if e.state == StateSearching {
+		e.state = StateLeased
+	}
 
-	_, err := io.WriteString(e.stdinPipe, cmd+"\n")
-	return err
+	// Send bestMove into the channel without blocking.
+	select {
+	case e.BestMoveChan <- bestMove:
+	default:
+	}
 }
 
-// Close exists the engine
-func (e *UCIEngine) Close() error {
-	close(e.quitChan)
-	_ = e.writeCommand("quit")
-	if err := e.cmd.Wait(); err != nil {
-		return err
+// writeRequest is a command for the goroutine owning stdin
+type writeRequest struct {
+	line string
+	done chan error
+}
+
+// writeLoop writes the commands to the engine one at a time until it is closed
+func (e *UCIEngine) writeLoop() {
+	for {
+		select {
+		case <-e.quitChan:
+			return
+		case req := <-e.stdin:
+			_, err := io.WriteString(e.stdinPipe, req.line+"\n")
+			req.done <- err
+		}
+	}
+}
+
+// writeCommand sends a UCI command, translated for engines speaking another protocol
+func (e *UCIEngine) writeCommand(cmd string) error {
+	e.record(TranscriptSent, cmd)
+
+	if e.protocol == nil {
+		return e.write(cmd)
+	}
+
+	lines, replies := e.protocol.command(cmd)
+	for _, line := range lines {
+		if err := e.write(line); err != nil {
+			return err
+		}
+	}
+	for _, reply := range replies {
+		e.handleLine(reply)
 	}
 	return nil
 }
 
-// SendCommand writes the command to the engine or returns an error
+// write sends a line to the engine process
+func (e *UCIEngine) write(line string) error {
+	req := writeRequest{line: line, done: make(chan error, 1)}
+
+	select {
+	case e.stdin <- req:
+		return <-req.done
+	case <-e.quitChan:
+		return errors.New("engine is closed")
+	case <-e.exited:
+		return ErrEngineCrashed
+	}
+}
+
+// Close exits the engine. It is safe to call more than once, e.g. by a game
+// and by the pool shutting down, every call waits for the process to exit
+func (e *UCIEngine) Close() error {
+	e.closeOnce.Do(func() {
+		e.closing.Store(true)
+		e.disarmWatchdog()
+		_ = e.writeCommand("quit")
+		close(e.quitChan)
+		defer e.sandbox.release()
+
+		<-e.exited
+	})
+
+	<-e.exited
+	return e.exitErr
+}
+
+// SendCommand writes the command to the engine or returns an error. Only the
+// owner of a lease may send commands, a go command starts a new search
 func (e *UCIEngine) SendCommand(cmd string) error {
+	search := strings.HasPrefix(cmd, "go")
+
+	e.infoMu.Lock()
+	if e.state != StateLeased && e.state != StateSearching {
+		e.infoMu.Unlock()
+		return ErrNotLeased
+	}
+	if search {
+		e.drainBestMove()
+		e.lastInfo = nil
+		e.search.reset()
+		e.searches++
+		e.state = StateSearching
+	}
+	e.infoMu.Unlock()
+
 	err := e.writeCommand(cmd)
 	if err != nil {
 		return err
 	}
 
+	if search {
+		e.armWatchdog()
+	}
+
 	return nil
 }
 
-// SetOption updates the engine configuration
+// Stop asks the engine to end the current search and report its best move
+func (e *UCIEngine) Stop() error {
+	return e.writeCommand("stop")
+}
+
+// WaitBestMove waits for the engine's best move until the context is done or
+// the engine process exits
+func (e *UCIEngine) WaitBestMove(ctx context.Context) (string, error) {
+	select {
+	case bestMove := <-e.BestMoveChan:
+		return bestMove, nil
+	case <-e.exited:
+		return "", ErrEngineCrashed
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// LastInfo returns the latest search update of the best line, or false when
+// the engine has not reported a score since the search started
+func (e *UCIEngine) LastInfo() (Info, bool) {
+	e.infoMu.Lock()
+	defer e.infoMu.Unlock()
+
+	if e.lastInfo == nil {
+		return Info{}, false
+	}
+	return *e.lastInfo, true
+}
+
+// Name returns the name the engine reported, empty until the engine answered the uci command
+func (e *UCIEngine) Name() string {
+	e.infoMu.Lock()
+	defer e.infoMu.Unlock()
+
+	return e.name
+}
+
+// Tap hands every line the engine writes to fn, from the goroutine reading the
+// engine output, so fn must not block. A nil fn removes the tap
+func (e *UCIEngine) Tap(fn func(line string)) {
+	e.infoMu.Lock()
+	defer e.infoMu.Unlock()
+
+	e.tap = fn
+}
+
+// drainBestMove discards a best move left over from an abandoned search, the
+// caller holds infoMu so no best move is delivered meanwhile
+func (e *UCIEngine) drainBestMove() {
+	select {
+	case <-e.BestMoveChan:
+	default:
+	}
+}
+
+// armWatchdog starts the per-move wall clock for a search
+func (e *UCIEngine) armWatchdog() {
+	if e.limits.MoveTimeout <= 0 {
+		return
+	}
+
+	e.watchdogMu.Lock()
+	defer e.watchdogMu.Unlock()
+
+	if e.watchdog != nil {
+		e.watchdog.Stop()
+	}
+
+	e.watchdog = time.AfterFunc(e.limits.MoveTimeout, func() {
+		e.logger.Error("Engine exceeded move timeout, killing process",
+			zap.String("engine_id", e.ID.String()),
+			zap.Duration("timeout", e.limits.MoveTimeout),
+			e.traceField())
+
+		if err := e.kill(); err != nil {
+			e.logger.Error("Error killing engine", zap.Error(err))
+		}
+	})
+}
+
+// disarmWatchdog stops the per-move wall clock once the engine answered
+func (e *UCIEngine) disarmWatchdog() {
+	e.watchdogMu.Lock()
+	defer e.watchdogMu.Unlock()
+
+	if e.watchdog != nil {
+		e.watchdog.Stop()
+		e.watchdog = nil
+	}
+}
+
+// SetOption updates the engine configuration, the option must be one the
+// engine reported and the value must fit its type and bounds
 func (e *UCIEngine) SetOption(name, value string) error {
+	opt, ok := e.option(name)
+	if !ok {
+		return fmt.Errorf("engine does not support option %s", name)
+	}
+	if err := opt.validate(value); err != nil {
+		return err
+	}
+
+	cmd := fmt.Sprintf("setoption name %s value %s", opt.Name, value)
+	if opt.Type == OptionButton {
+		cmd = "setoption name " + opt.Name
+	}
+
+	if err := e.writeCommand(cmd); err != nil {
+		return err
+	}
+
+	e.infoMu.Lock()
+	if e.options == nil {
+		e.options = make(map[string]string)
+	}
+	e.options[name] = value
+	e.infoMu.Unlock()
+
 	return nil
 }
+
+// SetOptions applies every option of the map to the engine
+func (e *UCIEngine) SetOptions(options map[string]string) error {
+	for name, value := range options {
+		if err := e.SetOption(name, value); err != nil {
+			return fmt.Errorf("error setting option %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// IsReady waits for the engine to answer isready, once it processed every
+// command sent before
+func (e *UCIEngine) IsReady(ctx context.Context) error {
+	select {
+	case <-e.readyChan:
+	default:
+	}
+
+	if err := e.writeCommand("isready"); err != nil {
+		return err
+	}
+
+	select {
+	case <-e.readyChan:
+		return nil
+	case <-e.exited:
+		return ErrEngineCrashed
+	case <-ctx.Done():
+		return fmt.Errorf("engine did not answer isready: %w", ctx.Err())
+	}
+}
+
+// NewGame prepares the engine for a new game: it restores the given options,
+// clears the engine state with ucinewgame and waits for the engine to be ready
+func (e *UCIEngine) NewGame(ctx context.Context, options map[string]string) error {
+	if err := e.RestoreOptions(options); err != nil {
+		return err
+	}
+
+	if err := e.writeCommand("ucinewgame"); err != nil {
+		return err
+	}
+
+	return e.IsReady(ctx)
+}
+
+// Reset ends whatever the engine was doing for its last user: it stops the
+// search, waits for the engine to be ready and drops the search output left
+func (e *UCIEngine) Reset(ctx context.Context) error {
+	if err := e.Stop(); err != nil {
+		return err
+	}
+
+	// The best move of a stopped search comes before readyok
+	if err := e.IsReady(ctx); err != nil {
+		return err
+	}
+	e.disarmWatchdog()
+
+	e.infoMu.Lock()
+	e.drainBestMove()
+	e.infoMu.Unlock()
+
+	for drained := false; !drained; {
+		select {
+		case <-e.InfoChan:
+		default:
+			drained = true
+		}
+	}
+
+	e.infoMu.Lock()
+	e.lastInfo = nil
+	e.trace = ""
+	e.tap = nil
+	e.infoMu.Unlock()
+
+	return nil
+}
+
+// RestoreOptions sets the given options, and the other options set on the
+// engine back to the defaults it reported
+func (e *UCIEngine) RestoreOptions(options map[string]string) error {
+	e.infoMu.Lock()
+	changed := make(map[string]string)
+	for name, value := range e.options {
+		if _, keep := options[name]; keep {
+			continue
+		}
+		if opt, ok := e.lookupOption(name); ok && opt.Type != OptionButton && opt.Default != value {
+			changed[name] = opt.Default
+		}
+	}
+	for name, value := range options {
+		if current, ok := e.options[name]; !ok || current != value {
+			changed[name] = value
+		}
+	}
+	e.infoMu.Unlock()
+
+	return e.SetOptions(changed)
+}
+
+// option returns the reported option with the given name, UCI option names
+// are case insensitive
+func (e *UCIEngine) option(name string) (Option, bool) {
+	e.infoMu.Lock()
+	defer e.infoMu.Unlock()
+
+	return e.lookupOption(name)
+}
+
+// ReportedOption returns the option with the given name as the engine
+// reported it in its handshake
+func (e *UCIEngine) ReportedOption(name string) (Option, bool) {
+	return e.option(name)
+}
+
+// lookupOption finds a reported option, the caller holds infoMu
+func (e *UCIEngine) lookupOption(name string) (Option, bool) {
+	for _, opt := range e.reported {
+		if strings.EqualFold(opt.Name, name) {
+			return opt, true
+		}
+	}
+	return Option{}, false
+}
+
+// Metadata describes an engine as it identified itself in its UCI handshake
+type Metadata struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Author  string   `json:"author"`
+	State   string   `json:"state"`   // idle, leased, searching or resetting
+	Options []Option `json:"options"` // Options in the order the engine reported them
+}
+
+// Metadata returns the identity and options the engine reported
+func (e *UCIEngine) Metadata() Metadata {
+	e.infoMu.Lock()
+	defer e.infoMu.Unlock()
+
+	return Metadata{
+		ID:      e.ID.String(),
+		Name:    e.name,
+		Author:  e.author,
+		State:   e.state.String(),
+		Options: append([]Option(nil), e.reported...),
+	}
+}
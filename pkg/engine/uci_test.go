@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// scriptEngine writes a shell script engine answering every uci with uciok
+// and every isready with readyok
+func scriptEngine(t *testing.T) string {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("script engines need a POSIX shell")
+	}
+
+	path := filepath.Join(t.TempDir(), "engine.sh")
+	script := `#!/bin/sh
+while read -r line; do
+	case "$line" in
+	uci) echo "id name Script"; echo "uciok" ;;
+	isready) echo "readyok" ;;
+	quit) exit 0 ;;
+	esac
+done
+`
+	require.NoError(t, os.WriteFile(path, []byte(script), 0o755))
+	return path
+}
+
+func TestUCIEngineRepeatedUciok(t *testing.T) {
+	e, err := NewUCIEngine(scriptEngine(t), Limits{}, zap.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = e.Close() })
+
+	// A second handshake must not close the handshake channel again
+	require.NoError(t, e.SendCommand("uci"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, e.IsReady(ctx))
+	require.Equal(t, "Script", e.Name())
+}
+
+func TestUCIEngineConcurrentClose(t *testing.T) {
+	e, err := NewUCIEngine(scriptEngine(t), Limits{}, zap.NewNop())
+	require.NoError(t, err)
+
+	// A game and the pool shutting down may close the same engine at once
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = e.Close()
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		require.Equal(t, errs[0], err)
+	}
+	require.Error(t, e.IsReady(context.Background()))
+}
+
+func TestPoolShutdownRacesReturn(t *testing.T) {
+	pool := NewEnginePool(MockPrefix, Scaling{MaxEngines: 4}, Limits{}, nil, zap.NewNop())
+	require.NoError(t, pool.Initialize())
+
+	engines := make([]*UCIEngine, 4)
+	for i := range engines {
+		var err error
+		engines[i], err = pool.GetEngine(context.Background())
+		require.NoError(t, err)
+	}
+
+	var wg sync.WaitGroup
+	for _, e := range engines {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			pool.ReturnEngine(e.ID.String())
+		}()
+		go func() {
+			defer wg.Done()
+			_ = e.Close()
+		}()
+		go func() {
+			defer wg.Done()
+			pool.Shutdown()
+		}()
+	}
+	wg.Wait()
+
+	require.Zero(t, pool.Stats().Size)
+}
+
+func TestFailedSandboxLeaksNoDescriptors(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("open descriptors are counted through /proc")
+	}
+	enginePath := scriptEngine(t)
+
+	// A cgroup root below a regular file can never be created
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, nil, 0o644))
+	limits := Limits{CgroupRoot: filepath.Join(file, "cgroup"), CPUQuota: 1}
+
+	openFDs := func() int {
+		entries, err := os.ReadDir("/proc/self/fd")
+		require.NoError(t, err)
+		return len(entries)
+	}
+
+	before := openFDs()
+	for range 10 {
+		_, _, _, _, err := startProcess("engine", enginePath, limits)
+		require.Error(t, err)
+	}
+	require.Equal(t, before, openFDs())
+}
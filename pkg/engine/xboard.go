@@ -0,0 +1,477 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/corentings/chess/v2"
+)
+
+// XBoardPrefix marks the path of an engine speaking the CECP/XBoard protocol,
+// e.g. xboard:/usr/games/crafty. Such engines must support protover 2
+const XBoardPrefix = "xboard:"
+
+// xboardMateScore is the score from which CECP engines report mates, 100000 + N
+// meaning mate in N moves
+const xboardMateScore = 100000
+
+// protocol translates between the UCI commands UCIEngine sends and an engine
+// process speaking another protocol
+type protocol interface {
+	// command translates a UCI command into lines for the engine, and UCI
+	// output answered on behalf of the engine
+	command(cmd string) (lines, replies []string)
+	// output translates a line of the engine into UCI output, and lines the
+	// engine expects in answer
+	output(line string) (lines, replies []string)
+}
+
+// splitProtocol returns the protocol an engine path asks for, nil for UCI,
+// and the path of the engine binary
+func splitProtocol(enginePath string) (protocol, string) {
+	if path, ok := strings.CutPrefix(enginePath, XBoardPrefix); ok {
+		return newXBoard(), path
+	}
+	return nil, enginePath
+}
+
+// xboard adapts an engine speaking CECP protocol version 2. Positions are set
+// up from scratch with setboard and the moves played in force mode, searches
+// map to go, or to analyze mode for infinite ones, and the thinking output of
+// the engine is turned into info lines with its moves in UCI notation
+type xboard struct {
+	mu sync.Mutex
+
+	features map[string]string // Features the engine announced
+	options  []Option          // Options the engine announced with feature option
+	ready    bool              // Set once the engine sent feature done=1
+
+	start   *chess.Position // Position of the last position command
+	current *chess.Position // Position after the moves of the last position command
+
+	searching bool     // A go command waits for the move of the engine
+	analyzing bool     // The search runs in analyze mode until stopped
+	pv        []string // Latest principal variation in UCI notation
+	pings     int
+}
+
+func newXBoard() *xboard {
+	start := chess.StartingPosition()
+	return &xboard{features: make(map[string]string), start: start, current: start}
+}
+
+func (x *xboard) command(cmd string) ([]string, []string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	fields := strings.Fields(cmd)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	switch fields[0] {
+	case "uci":
+		return []string{"xboard", "protover 2"}, nil
+
+	case "isready":
+		if x.features["ping"] != "1" {
+			return nil, []string{"readyok"}
+		}
+		x.pings++
+		return []string{fmt.Sprintf("ping %d", x.pings)}, nil
+
+	case "ucinewgame":
+		return []string{"new", "force"}, nil
+
+	case "position":
+		return x.position(fields[1:])
+
+	case "go":
+		return x.search(fields[1:]), nil
+
+	case "stop":
+		switch {
+		case x.analyzing:
+			x.analyzing = false
+			x.searching = false
+			return []string{"exit"}, []string{x.bestMove()}
+		case x.searching:
+			return []string{"?"}, nil
+		}
+		return nil, nil
+
+	case "setoption":
+		return x.setOption(cmd), nil
+
+	case "quit":
+		return []string{"quit"}, nil
+	}
+
+	// ponderhit and debug have no counterpart, pondering is off
+	return nil, nil
+}
+
+// position sets up the board from scratch, moves are played in force mode
+func (x *xboard) position(args []string) ([]string, []string) {
+	lines := []string{"new", "force"}
+
+	pos := chess.StartingPosition()
+	i := 0
+	if len(args) > 0 && args[0] == "fen" {
+		end := len(args)
+		for j, arg := range args {
+			if arg == "moves" {
+				end = j
+				break
+			}
+		}
+
+		fen := strings.Join(args[1:end], " ")
+		opt, err := chess.FEN(fen)
+		if err != nil {
+			return nil, []string{"info string invalid position: " + err.Error()}
+		}
+		pos = chess.NewGame(opt).Position()
+		lines = append(lines, "setboard "+fen)
+		i = end
+	} else if len(args) > 0 && args[0] == "startpos" {
+		i = 1
+	}
+
+	x.start = pos
+	if i < len(args) && args[i] == "moves" {
+		for _, uci := range args[i+1:] {
+			move, err := (chess.UCINotation{}).Decode(pos, uci)
+			if err != nil {
+				return nil, []string{"info string invalid move " + uci}
+			}
+			pos = pos.Update(move)
+
+			if x.features["usermove"] == "1" {
+				uci = "usermove " + uci
+			}
+			lines = append(lines, uci)
+		}
+	}
+	x.current = pos
+
+	return lines, nil
+}
+
+// search maps the limits of a go command to the CECP time controls. The
+// engine plays the side to move, as CECP engines think for the side to move
+// when they leave force mode
+func (x *xboard) search(args []string) []string {
+	x.pv = nil
+	x.searching = true
+
+	limits := make(map[string]int64)
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "infinite", "ponder":
+			limits[args[i]] = 1
+		case "depth", "movetime", "wtime", "btime", "winc", "binc", "movestogo":
+			if i+1 < len(args) {
+				if n, err := strconv.ParseInt(args[i+1], 10, 64); err == nil {
+					limits[args[i]] = n
+				}
+				i++
+			}
+		}
+	}
+
+	var lines []string
+	if depth, ok := limits["depth"]; ok {
+		lines = append(lines, fmt.Sprintf("sd %d", depth))
+	}
+
+	own, opp, inc := "wtime", "btime", "winc"
+	if x.current.Turn() == chess.Black {
+		own, opp, inc = "btime", "wtime", "binc"
+	}
+
+	switch {
+	case limits["movetime"] > 0:
+		lines = append(lines, fmt.Sprintf("st %d", max((limits["movetime"]+999)/1000, 1)))
+
+	case limits[own] > 0:
+		// The base time only matters to engines that ignore the time command
+		base := max(limits[own]/60000, 1)
+		lines = append(lines,
+			fmt.Sprintf("level %d %d %d", limits["movestogo"], base, limits[inc]/1000),
+			fmt.Sprintf("time %d", limits[own]/10),
+			fmt.Sprintf("otim %d", limits[opp]/10),
+		)
+
+	case limits["depth"] == 0 && x.features["analyze"] != "0":
+		// Searches without limits run until stopped
+		x.analyzing = true
+		return append(lines, "post", "analyze")
+	}
+
+	return append(lines, "post", "go")
+}
+
+// setOption maps a UCI option to the CECP command setting it
+func (x *xboard) setOption(cmd string) []string {
+	rest, ok := strings.CutPrefix(cmd, "setoption name ")
+	if !ok {
+		return nil
+	}
+	name, value, hasValue := strings.Cut(rest, " value ")
+	name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+
+	switch name {
+	case "Hash":
+		return []string{"memory " + value}
+	case "Threads":
+		return []string{"cores " + value}
+	}
+
+	if !hasValue {
+		return []string{"option " + name}
+	}
+	return []string{fmt.Sprintf("option %s=%s", name, value)}
+}
+
+func (x *xboard) output(line string) ([]string, []string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	switch {
+	case fields[0] == "feature":
+		return x.feature(line)
+
+	case fields[0] == "pong":
+		return []string{"readyok"}, nil
+
+	case fields[0] == "move" && len(fields) > 1:
+		if !x.searching {
+			return nil, nil
+		}
+		x.searching = false
+		move, ok := x.uciMove(x.current, fields[1])
+		if !ok {
+			return []string{"info string invalid engine move " + fields[1], "bestmove (none)"}, nil
+		}
+		return []string{"bestmove " + move}, nil
+
+	case fields[0] == "resign" || fields[0] == "1-0" || fields[0] == "0-1" || fields[0] == "1/2-1/2":
+		if !x.searching || x.analyzing {
+			return nil, nil
+		}
+		x.searching = false
+		return []string{"bestmove (none)"}, nil
+
+	case strings.HasPrefix(fields[0], "Error") || strings.HasPrefix(fields[0], "Illegal"):
+		return []string{"info string " + line}, nil
+	}
+
+	if info, ok := x.thinking(fields); ok {
+		return []string{info}, nil
+	}
+	return nil, nil
+}
+
+// feature records the features of the handshake and accepts them, the UCI
+// handshake is answered once the engine announced all of them
+func (x *xboard) feature(line string) ([]string, []string) {
+	var replies []string
+	done := false
+
+	for _, f := range splitFeatures(strings.TrimPrefix(line, "feature")) {
+		name, value, _ := strings.Cut(f, "=")
+		value = strings.Trim(value, `"`)
+
+		switch name {
+		case "option":
+			if opt, ok := parseXBoardOption(value); ok {
+				x.options = append(x.options, opt)
+			}
+		case "done":
+			done = value == "1"
+		default:
+			x.features[name] = value
+		}
+		replies = append(replies, "accepted "+name)
+	}
+
+	if !done || x.ready {
+		return nil, replies
+	}
+	x.ready = true
+
+	lines := []string{"id name " + x.features["myname"]}
+	if x.features["memory"] == "1" {
+		lines = append(lines, "option name Hash type spin default 16 min 1 max 33554432")
+	}
+	if x.features["smp"] == "1" {
+		lines = append(lines, "option name Threads type spin default 1 min 1 max 1024")
+	}
+	for _, opt := range x.options {
+		lines = append(lines, opt.String())
+	}
+	lines = append(lines, "uciok")
+
+	// Thinking output is needed for the scores, pondering is left to the server
+	replies = append(replies, "post", "easy", "new", "force")
+	return lines, replies
+}
+
+// splitFeatures splits the arguments of a feature command, values may be
+// quoted and contain spaces
+func splitFeatures(s string) []string {
+	var features []string
+	var b strings.Builder
+	quoted := false
+
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			b.WriteRune(r)
+		case r == ' ' && !quoted:
+			if b.Len() > 0 {
+				features = append(features, b.String())
+				b.Reset()
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() > 0 {
+		features = append(features, b.String())
+	}
+	return features
+}
+
+// parseXBoardOption parses the value of a feature option, e.g.
+// Hash Size -spin 64 1 1024 or Style -combo Solid /// *Normal /// Risky
+func parseXBoardOption(value string) (Option, bool) {
+	i := strings.Index(value, " -")
+	if i < 0 {
+		return Option{}, false
+	}
+	opt := Option{Name: strings.TrimSpace(value[:i])}
+
+	kind, args, _ := strings.Cut(value[i+2:], " ")
+	args = strings.TrimSpace(args)
+
+	switch kind {
+	case "check":
+		opt.Type = OptionCheck
+		opt.Default = "false"
+		if args == "1" {
+			opt.Default = "true"
+		}
+
+	case "spin", "slider":
+		parts := strings.Fields(args)
+		if len(parts) != 3 {
+			return Option{}, false
+		}
+		lo, err1 := strconv.ParseInt(parts[1], 10, 64)
+		hi, err2 := strconv.ParseInt(parts[2], 10, 64)
+		if err1 != nil || err2 != nil {
+			return Option{}, false
+		}
+		opt.Type, opt.Default, opt.Min, opt.Max = OptionSpin, parts[0], &lo, &hi
+
+	case "combo":
+		opt.Type = OptionCombo
+		for _, v := range strings.Split(args, "///") {
+			v = strings.TrimSpace(v)
+			if def, ok := strings.CutPrefix(v, "*"); ok {
+				v = def
+				opt.Default = def
+			}
+			opt.Vars = append(opt.Vars, v)
+		}
+
+	case "button", "save", "reset":
+		opt.Type = OptionButton
+
+	case "string", "file", "path":
+		opt.Type, opt.Default = OptionString, args
+
+	default:
+		return Option{}, false
+	}
+
+	return opt, true
+}
+
+// thinking converts a line of thinking output, ply score time nodes pv with
+// the time in centiseconds, into an info line
+func (x *xboard) thinking(fields []string) (string, bool) {
+	if len(fields) < 5 {
+		return "", false
+	}
+
+	var n [4]int64
+	for i := range n {
+		v, err := strconv.ParseInt(strings.TrimRight(fields[i], ".&"), 10, 64)
+		if err != nil {
+			return "", false
+		}
+		n[i] = v
+	}
+	depth, score, centis, nodes := n[0], n[1], n[2], n[3]
+
+	// The PV starts from the searched position, engines may write it in SAN
+	var pv []string
+	pos := x.current
+	for _, m := range fields[4:] {
+		move, ok := x.uciMove(pos, m)
+		if !ok {
+			break
+		}
+		decoded, _ := (chess.UCINotation{}).Decode(pos, move)
+		pos = pos.Update(decoded)
+		pv = append(pv, move)
+	}
+	if len(pv) == 0 {
+		return "", false
+	}
+	x.pv = pv
+
+	scoreField := fmt.Sprintf("cp %d", score)
+	switch {
+	case score >= xboardMateScore:
+		scoreField = fmt.Sprintf("mate %d", score-xboardMateScore)
+	case score <= -xboardMateScore:
+		scoreField = fmt.Sprintf("mate %d", score+xboardMateScore)
+	}
+
+	return fmt.Sprintf("info depth %d score %s time %d nodes %d pv %s",
+		depth, scoreField, centis*10, nodes, strings.Join(pv, " ")), true
+}
+
+// uciMove converts a move in coordinate notation or SAN to a legal move in
+// UCI notation
+func (x *xboard) uciMove(pos *chess.Position, move string) (string, bool) {
+	move = strings.TrimSuffix(strings.TrimSuffix(move, "+"), "#")
+
+	for _, m := range pos.ValidMoves() {
+		uci := (chess.UCINotation{}).Encode(pos, &m)
+		if uci == move || strings.TrimRight((chess.AlgebraicNotation{}).Encode(pos, &m), "+#") == move {
+			return uci, true
+		}
+	}
+	return "", false
+}
+
+// bestMove answers a stopped analysis with the first move of its principal variation
+func (x *xboard) bestMove() string {
+	if len(x.pv) == 0 {
+		return "bestmove (none)"
+	}
+	return "bestmove " + x.pv[0]
+}
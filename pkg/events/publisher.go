@@ -1,6 +1,13 @@
 package events
 
-import "sync"
+import (
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/messages"
+)
 
 // EventType represents the type of event
 type EventType string
@@ -10,10 +17,29 @@ const (
 	EventGameCreated      EventType = "GAME_CREATED"
 	EventMoveProcessed    EventType = "MOVE_PROCESSED"
 	EventEngineMoved      EventType = "ENGINE_MOVED"
+	EventEngineCrashed    EventType = "ENGINE_CRASHED"
+	EventEvalUpdated      EventType = "EVAL_UPDATED"
 	EventClockUpdated     EventType = "CLOCK_UPDATED"
+	EventTakebackApplied  EventType = "TAKEBACK_APPLIED"
+	EventPremoveDiscarded EventType = "PREMOVE_DISCARDED"
+	EventHintReady        EventType = "HINT_READY"
+	EventBoardAnnotation  EventType = "BOARD_ANNOTATION"
+	EventChatMessage      EventType = "CHAT_MESSAGE"
+	EventPresenceUpdated  EventType = "PRESENCE_UPDATED"
 	EventTimeUp           EventType = "TIME_UP"
+	EventGameOver         EventType = "GAME_OVER"
+	EventAnalysisReady    EventType = "ANALYSIS_READY"
+	EventAnalysisUpdated  EventType = "ANALYSIS_UPDATED"
 	EventGameTerminated   EventType = "GAME_TERMINATED"
+	EventGameAbandoned    EventType = "GAME_ABANDONED"
+	EventGameRestored     EventType = "GAME_RESTORED"
+	EventConnectionOpened EventType = "CONNECTION_OPENED"
 	EventConnectionClosed EventType = "CONNECTION_CLOSED"
+	EventAuthFailed       EventType = "AUTH_FAILED"
+	EventAdminAction      EventType = "ADMIN_ACTION"
+	EventMatchProgress    EventType = "MATCH_PROGRESS"
+	EventTournamentUpdate EventType = "TOURNAMENT_UPDATED"
+	EventInternalError    EventType = "INTERNAL_ERROR"
 )
 
 // Event represents an event in the system
@@ -26,62 +52,170 @@ type Event struct {
 // Handler is a function that processes events
 type Handler func(event Event)
 
-// Publisher is the central event publisher
+// Transient reports whether an event is a high frequency update that a
+// later one of the same type supersedes, so it may be dropped under load
+func (t EventType) Transient() bool {
+	switch t {
+	case EventClockUpdated, EventEvalUpdated, EventAnalysisUpdated:
+		return true
+	default:
+		return false
+	}
+}
+
+// Publisher is the central event publisher. Every subscriber has its own
+// bounded queue and receives its events one at a time, in publication order
 type Publisher struct {
-	mu          sync.RWMutex
-	subscribers map[EventType][]Handler
+	mu           sync.RWMutex
+	subscribers  []*Subscriber
+	disconnected []*Subscriber // Closed by OverflowDisconnect, still reported by Stats
+
+	logger *zap.Logger
 }
 
 // NewPublisher creates a new event publisher
-func NewPublisher() *Publisher {
+func NewPublisher(logger *zap.Logger) *Publisher {
 	return &Publisher{
-		subscribers: make(map[EventType][]Handler),
+		logger: logger,
 	}
 }
 
-// Subscribe registers a handler for a specific event type
-func (p *Publisher) Subscribe(eventType EventType, handler Handler) {
+// NewSubscriber creates a subscriber whose handlers share one queue, so
+// events of different types reach it in the order they were published
+func (p *Publisher) NewSubscriber(name string, opts SubscriberOptions) *Subscriber {
+	return p.start(newSubscriber(p, name, opts))
+}
+
+// start registers a subscriber and starts delivering its events
+func (p *Publisher) start(sub *Subscriber) *Subscriber {
+	p.mu.Lock()
+	p.subscribers = append(p.subscribers, sub)
+	p.mu.Unlock()
+
+	go sub.run()
+	return sub
+}
+
+// remove forgets a closed subscriber
+func (p *Publisher) remove(sub *Subscriber) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	p.subscribers[eventType] = append(p.subscribers[eventType], handler)
+	for i, s := range p.subscribers {
+		if s == sub {
+			p.subscribers = append(p.subscribers[:i:i], p.subscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+// ownedSubscriber creates a subscriber closed along with its only subscription
+func (p *Publisher) ownedSubscriber(name string) *Subscriber {
+	sub := newSubscriber(p, name, SubscriberOptions{})
+	sub.owned = true
+	return p.start(sub)
+}
+
+// Subscribe registers a handler for a specific event type on a subscriber of its own
+func (p *Publisher) Subscribe(eventType EventType, handler Handler) *Subscription {
+	return p.ownedSubscriber(string(eventType)).Subscribe(eventType, handler)
+}
+
+// SubscribeAll registers a handler for all event types
+func (p *Publisher) SubscribeAll(handler Handler) *Subscription {
+	subscription, _ := p.SubscribePattern("*", handler)
+	return subscription
+}
+
+// SubscribePattern registers a handler for every event type matching a
+// pattern, e.g. GAME_*, on a subscriber of its own
+func (p *Publisher) SubscribePattern(pattern string, handler Handler) (*Subscription, error) {
+	sub := p.ownedSubscriber(pattern)
+
+	subscription, err := sub.SubscribePattern(pattern, handler)
+	if err != nil {
+		sub.Close()
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// SubscribeGame registers a handler for every event of a game. The
+// subscription is cancelled once the game has been terminated
+func (p *Publisher) SubscribeGame(gameID string, handler Handler) *Subscription {
+	subscription := &Subscription{eventType: "*", pattern: true, gameID: gameID}
+	subscription.handler = func(event Event) {
+		handler(event)
+		if event.Type == EventGameTerminated {
+			subscription.Cancel()
+		}
+	}
+
+	return p.ownedSubscriber("game " + gameID).add(subscription)
 }
 
-// Publish broadcasts an event to all subsribers
+// Publish queues an event for every subscriber interested in it
 func (p *Publisher) Publish(event Event) {
 	p.mu.RLock()
-	handlers := p.subscribers[event.Type]
+	subscribers := p.subscribers
 	p.mu.RUnlock()
 
-	// Call all handlers
-	for _, handler := range handlers {
-		go handler(event) // Run handlers concurrently
+	for _, sub := range subscribers {
+		if sub.wants(event) {
+			sub.enqueue(event)
+		}
 	}
 }
 
-// SubscribeAll registers a handler for all event types
-func (p *Publisher) SubscribeAll(handler Handler) {
+// retire keeps reporting a subscriber disconnected for falling behind
+func (p *Publisher) retire(sub *Subscriber) {
 	p.mu.Lock()
-	defer p.mu.Unlock()
-
-	// Special event type for "all events"
-	p.subscribers["*"] = append(p.subscribers["*"], handler)
+	p.disconnected = append(p.disconnected, sub)
+	p.mu.Unlock()
 }
 
-// Publish broadcasts an event to all subscribers including "all events" handlers
-func (p *Publisher) publish(event Event) {
+// Stats returns the delivery counters of every subscriber, including the
+// ones disconnected for falling behind
+func (p *Publisher) Stats() []SubscriberStats {
 	p.mu.RLock()
-	handlers := p.subscribers[event.Type]
-	allHandlers := p.subscribers["*"]
-	p.mu.RUnlock()
+	defer p.mu.RUnlock()
 
-	// Call specific event handlers
-	for _, handler := range handlers {
-		go handler(event)
+	stats := make([]SubscriberStats, 0, len(p.subscribers)+len(p.disconnected))
+	for _, sub := range p.subscribers {
+		stats = append(stats, sub.stats())
+	}
+	for _, sub := range p.disconnected {
+		stats = append(stats, sub.stats())
+	}
+	return stats
+}
+
+// ReportPanic logs a recovered panic with its stack and publishes it as an
+// internal error. Goroutines call it from their deferred recover
+func (p *Publisher) ReportPanic(component, gameID string, r interface{}) {
+	p.logger.Error("Recovered from panic",
+		zap.String("component", component),
+		zap.String("game_id", gameID),
+		zap.String("panic", fmt.Sprint(r)),
+		zap.Stack("stack"),
+	)
+
+	// A panic while handling an internal error must not report itself forever
+	if component == "event handler "+string(EventInternalError) {
+		return
 	}
 
-	// Call "all events" handlers
-	for _, handler := range allHandlers {
-		go handler(event)
+	event := Event{
+		Type:   EventInternalError,
+		GameID: gameID,
+		Payload: messages.InternalErrorPayload{
+			Component: component,
+			GameID:    gameID,
+			Message:   fmt.Sprint(r),
+		},
 	}
+
+	// Published from its own goroutine, a handler reporting a panic must not
+	// wait for room in its own queue
+	go p.Publish(event)
 }
@@ -0,0 +1,343 @@
+package events
+
+import (
+	"fmt"
+	"path"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// DefaultQueueSize is the queue size of subscribers that do not set one
+const DefaultQueueSize = 256
+
+// OverflowPolicy decides what happens to an event published while the queue
+// of a subscriber is full
+type OverflowPolicy int
+
+// The overflow policies
+const (
+	// OverflowDropStale drops the oldest queued transient update. When only
+	// events that must be delivered are queued, a transient event is dropped
+	// on arrival and any other one drops the oldest queued event
+	OverflowDropStale OverflowPolicy = iota
+	// OverflowDisconnect closes the subscriber, for consumers that must see
+	// every event or none rather than hold back the publisher
+	OverflowDisconnect
+	// OverflowDropOldest drops the oldest queued event, whatever its type
+	OverflowDropOldest
+)
+
+// SubscriberOptions configure the queue of a subscriber
+type SubscriberOptions struct {
+	QueueSize int // DefaultQueueSize when 0
+	Overflow  OverflowPolicy
+}
+
+// SubscriberStats are the delivery counters of a subscriber
+type SubscriberStats struct {
+	Name      string `json:"name"`
+	Queued    int    `json:"queued"`
+	Capacity  int    `json:"capacity"` // Size of the queue
+	Delivered int64  `json:"delivered"`
+	Dropped   int64  `json:"dropped"`
+
+	Disconnected bool `json:"disconnected,omitempty"` // Closed by OverflowDisconnect
+}
+
+// Subscriber receives the events of the types it subscribed to, one at a
+// time, from a bounded queue
+type Subscriber struct {
+	name      string
+	publisher *Publisher
+	size      int
+	overflow  OverflowPolicy
+
+	mu       sync.Mutex
+	cond     *sync.Cond // Signals queue changes to the consumer
+	queue    []Event
+	handlers map[EventType][]*Subscription // Subscriptions to a single event type
+	patterns []*Subscription               // Subscriptions to the event types matching a pattern
+	nextID   uint64
+	closed   bool
+	owned    bool // Created for a single subscription, closed when it is cancelled
+
+	delivered    atomic.Int64
+	dropped      atomic.Int64
+	disconnected atomic.Bool
+}
+
+func newSubscriber(p *Publisher, name string, opts SubscriberOptions) *Subscriber {
+	size := opts.QueueSize
+	if size <= 0 {
+		size = DefaultQueueSize
+	}
+
+	sub := &Subscriber{
+		name:      name,
+		publisher: p,
+		size:      size,
+		overflow:  opts.Overflow,
+		queue:     make([]Event, 0, size),
+		handlers:  make(map[EventType][]*Subscription),
+	}
+	sub.cond = sync.NewCond(&sub.mu)
+	return sub
+}
+
+// Subscription is a handler registered on a subscriber, until it is cancelled
+type Subscription struct {
+	sub       *Subscriber
+	id        uint64
+	eventType EventType // Event type, or pattern like GAME_* when pattern is set
+	pattern   bool
+	gameID    string // Only events of this game are handled when set
+	handler   Handler
+}
+
+// Subscribe registers a handler for a specific event type
+func (s *Subscriber) Subscribe(eventType EventType, handler Handler) *Subscription {
+	return s.add(&Subscription{eventType: eventType, handler: handler})
+}
+
+// SubscribeGame registers a handler for the events of a specific type and game
+func (s *Subscriber) SubscribeGame(gameID string, eventType EventType, handler Handler) *Subscription {
+	return s.add(&Subscription{eventType: eventType, gameID: gameID, handler: handler})
+}
+
+// SubscribePattern registers a handler for every event type matching a
+// pattern in the syntax of path.Match, e.g. GAME_* or * for all events
+func (s *Subscriber) SubscribePattern(pattern string, handler Handler) (*Subscription, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid event pattern %q: %w", pattern, err)
+	}
+
+	return s.add(&Subscription{eventType: EventType(pattern), pattern: true, handler: handler}), nil
+}
+
+func (s *Subscriber) add(subscription *Subscription) *Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	subscription.sub = s
+	subscription.id = s.nextID
+
+	if subscription.pattern {
+		s.patterns = append(s.patterns, subscription)
+	} else {
+		s.handlers[subscription.eventType] = append(s.handlers[subscription.eventType], subscription)
+	}
+	return subscription
+}
+
+// Cancel stops delivering events to the handler. Events already queued for it
+// may still be handled. Safe to call more than once and from the handler itself
+func (sub *Subscription) Cancel() {
+	s := sub.sub
+
+	s.mu.Lock()
+	if sub.pattern {
+		s.patterns = without(s.patterns, sub)
+	} else if handlers := without(s.handlers[sub.eventType], sub); len(handlers) > 0 {
+		s.handlers[sub.eventType] = handlers
+	} else {
+		delete(s.handlers, sub.eventType)
+	}
+
+	done := s.owned && len(s.handlers) == 0 && len(s.patterns) == 0 && !s.closed
+	s.mu.Unlock()
+
+	if done {
+		s.Close()
+	}
+}
+
+// without returns the subscriptions except the given one
+func without(subs []*Subscription, sub *Subscription) []*Subscription {
+	for i, s := range subs {
+		if s.id == sub.id {
+			return append(subs[:i:i], subs[i+1:]...)
+		}
+	}
+	return subs
+}
+
+// matches reports whether the subscription handles an event
+func (sub *Subscription) matches(event Event) bool {
+	if sub.gameID != "" && sub.gameID != event.GameID {
+		return false
+	}
+
+	if sub.pattern {
+		ok, _ := path.Match(string(sub.eventType), string(event.Type))
+		return ok
+	}
+	return sub.eventType == event.Type
+}
+
+// Close stops the subscriber and removes it from the publisher. Queued events are discarded
+func (s *Subscriber) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	s.queue = nil
+	s.cond.Broadcast()
+	s.mu.Unlock()
+
+	s.publisher.remove(s)
+}
+
+// handlersFor returns the handlers of an event, the ones subscribed to its
+// type first, then the pattern ones, each in subscription order
+func (s *Subscriber) handlersFor(event Event) []Handler {
+	var handlers []Handler
+	for _, subs := range [][]*Subscription{s.handlers[event.Type], s.patterns} {
+		for _, sub := range subs {
+			if sub.matches(event) {
+				handlers = append(handlers, sub.handler)
+			}
+		}
+	}
+	return handlers
+}
+
+// wants reports whether the subscriber has handlers for an event
+func (s *Subscriber) wants(event Event) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return !s.closed && len(s.handlersFor(event)) > 0
+}
+
+// enqueue adds an event to the queue, applying the overflow policy when it
+// is full. It never waits for the subscriber, a slow one loses events or is
+// disconnected instead of holding back the publisher
+func (s *Subscriber) enqueue(event Event) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+
+	if len(s.queue) >= s.size {
+		if s.overflow == OverflowDisconnect {
+			s.mu.Unlock()
+			s.disconnect(event)
+			return
+		}
+
+		if !s.makeRoom(event) {
+			s.mu.Unlock()
+			return
+		}
+	}
+
+	s.queue = append(s.queue, event)
+	s.cond.Broadcast()
+	s.mu.Unlock()
+}
+
+// makeRoom drops a queued event as the overflow policy allows, reporting
+// false when the incoming event is dropped instead
+func (s *Subscriber) makeRoom(event Event) bool {
+	if s.overflow == OverflowDropStale {
+		for i, queued := range s.queue {
+			if queued.Type.Transient() {
+				s.drop(i)
+				return true
+			}
+		}
+
+		if event.Type.Transient() {
+			s.dropped.Add(1)
+			return false
+		}
+
+		s.publisher.logger.Warn("Subscriber queue full of events that must be delivered, dropping the oldest",
+			zap.String("subscriber", s.name),
+			zap.String("type", string(s.queue[0].Type)),
+			zap.String("game_id", s.queue[0].GameID),
+		)
+	}
+
+	s.drop(0)
+	return true
+}
+
+// disconnect closes a subscriber whose queue overflowed
+func (s *Subscriber) disconnect(event Event) {
+	s.publisher.logger.Error("Subscriber fell behind, disconnecting it",
+		zap.String("subscriber", s.name),
+		zap.String("type", string(event.Type)),
+		zap.String("game_id", event.GameID),
+	)
+
+	s.dropped.Add(1)
+	s.disconnected.Store(true)
+	s.Close()
+	s.publisher.retire(s)
+}
+
+// drop removes the queued event at index i
+func (s *Subscriber) drop(i int) {
+	s.queue = append(s.queue[:i], s.queue[i+1:]...)
+	s.dropped.Add(1)
+}
+
+// run delivers the queued events one at a time
+func (s *Subscriber) run() {
+	for {
+		s.mu.Lock()
+		for len(s.queue) == 0 && !s.closed {
+			s.cond.Wait()
+		}
+
+		if s.closed {
+			s.mu.Unlock()
+			return
+		}
+
+		event := s.queue[0]
+		s.queue = append(s.queue[:0], s.queue[1:]...)
+		handlers := s.handlersFor(event)
+		s.cond.Broadcast()
+		s.mu.Unlock()
+
+		for _, handler := range handlers {
+			s.deliver(handler, event)
+		}
+		s.delivered.Add(1)
+	}
+}
+
+// deliver runs a handler, keeping a panicking handler from taking the server down
+func (s *Subscriber) deliver(handler Handler, event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.publisher.ReportPanic("event handler "+string(event.Type), event.GameID, r)
+		}
+	}()
+
+	handler(event)
+}
+
+func (s *Subscriber) stats() SubscriberStats {
+	s.mu.Lock()
+	queued := len(s.queue)
+	s.mu.Unlock()
+
+	return SubscriberStats{
+		Name:      s.name,
+		Queued:    queued,
+		Capacity:  s.size,
+		Delivered: s.delivered.Load(),
+		Dropped:   s.dropped.Load(),
+
+		Disconnected: s.disconnected.Load(),
+	}
+}
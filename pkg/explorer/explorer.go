@@ -0,0 +1,163 @@
+// Package explorer aggregates the opening moves of finished games into a tree
+// of positions, with how often each move was played and how the games ended
+package explorer
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/corentings/chess/v2"
+
+	"github.com/tecu23/eng-server/internal/messages"
+)
+
+// MaxPly is the depth of the tree, moves past it are not aggregated
+const MaxPly = 40
+
+// ErrNotIndexed is returned for games that are not added to the tree
+var ErrNotIndexed = errors.New("game is not indexed")
+
+// stats counts the results of the games that went through a move or position
+type stats struct {
+	games, white, draws, black int
+}
+
+// add counts a game result
+func (s *stats) add(result string) {
+	s.games++
+	switch result {
+	case "1-0":
+		s.white++
+	case "0-1":
+		s.black++
+	default:
+		s.draws++
+	}
+}
+
+// move is a move played from a position
+type move struct {
+	san string
+	stats
+}
+
+// node is a position of the tree
+type node struct {
+	stats
+	moves map[string]*move // Moves played from the position by UCI notation
+}
+
+// Tree is an opening tree, positions are keyed by their placement, side to
+// move and castling rights so transpositions share their statistics
+type Tree struct {
+	mu    sync.RWMutex
+	nodes map[string]*node
+}
+
+// New creates an empty tree
+func New() *Tree {
+	return &Tree{nodes: make(map[string]*node)}
+}
+
+// key identifies a position in the tree
+func key(pos *chess.Position) string {
+	fields := strings.Fields(pos.String())
+	if len(fields) < 3 {
+		return pos.String()
+	}
+	return strings.Join(fields[:3], " ")
+}
+
+// AddPGN adds the opening of a finished game. Games started from another
+// position than the standard one and unfinished games are not indexed
+func (t *Tree) AddPGN(pgn string) error {
+	opt, err := chess.PGN(strings.NewReader(pgn))
+	if err != nil {
+		return fmt.Errorf("invalid PGN: %w", err)
+	}
+	g := chess.NewGame(opt)
+
+	if g.GetTagPair("FEN") != "" {
+		return ErrNotIndexed
+	}
+
+	result := g.GetTagPair("Result")
+	switch result {
+	case "1-0", "0-1", "1/2-1/2":
+	default:
+		return ErrNotIndexed
+	}
+
+	positions := g.Positions()
+	moves := g.Moves()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for ply := 0; ply <= len(moves) && ply <= MaxPly; ply++ {
+		k := key(positions[ply])
+		n, ok := t.nodes[k]
+		if !ok {
+			n = &node{moves: make(map[string]*move)}
+			t.nodes[k] = n
+		}
+		n.add(result)
+
+		if ply == len(moves) || ply == MaxPly {
+			break
+		}
+
+		uci := chess.UCINotation{}.Encode(positions[ply], moves[ply])
+		m, ok := n.moves[uci]
+		if !ok {
+			m = &move{san: chess.AlgebraicNotation{}.Encode(positions[ply], moves[ply])}
+			n.moves[uci] = m
+		}
+		m.add(result)
+	}
+
+	return nil
+}
+
+// Lookup returns the statistics of a position and of the moves played from
+// it, most played first. A position no game reached has no moves
+func (t *Tree) Lookup(pos *chess.Position) messages.ExplorerPayload {
+	payload := messages.ExplorerPayload{
+		FEN:   pos.String(),
+		Moves: []messages.ExplorerMove{},
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	n, ok := t.nodes[key(pos)]
+	if !ok {
+		return payload
+	}
+
+	payload.ExplorerStats = n.payload()
+	for uci, m := range n.moves {
+		payload.Moves = append(payload.Moves, messages.ExplorerMove{
+			UCI:           uci,
+			SAN:           m.san,
+			ExplorerStats: m.payload(),
+		})
+	}
+
+	sort.Slice(payload.Moves, func(i, j int) bool {
+		if payload.Moves[i].Games != payload.Moves[j].Games {
+			return payload.Moves[i].Games > payload.Moves[j].Games
+		}
+		return payload.Moves[i].UCI < payload.Moves[j].UCI
+	})
+
+	return payload
+}
+
+// payload converts the counts of a move or position
+func (s stats) payload() messages.ExplorerStats {
+	return messages.ExplorerStats{Games: s.games, White: s.white, Draws: s.draws, Black: s.black}
+}
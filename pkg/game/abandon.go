@@ -0,0 +1,58 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/tecu23/eng-server/internal/color"
+)
+
+// errNoOpponent is returned for victory claims in games without a player to abandon them
+var errNoOpponent = errors.New("only games between players can be claimed")
+
+// claimVictoryCommand ends a game between players whose opponent left
+type claimVictoryCommand struct {
+	request
+	color color.Color // Side of the player who stayed
+	reply chan error
+}
+
+// ClaimVictory ends a game between players in favour of the given color,
+// whose opponent left the game. Whether the opponent has been away long
+// enough is up to the caller, the claim is drawn when the color cannot checkmate
+func (s *Game) ClaimVictory(clr color.Color, requestID string) error {
+	reply := make(chan error, 1)
+	if err := s.send(claimVictoryCommand{request: request{requestID}, color: clr, reply: reply}); err != nil {
+		return err
+	}
+
+	return s.await(reply)
+}
+
+// claimVictory ends the game by abandonment of the opponent of the given color
+func (s *Game) claimVictory(clr color.Color) error {
+	if s.Status() == StatusCompleted {
+		return ErrGameOver
+	}
+
+	if s.Mode != ModeHumanVsHuman {
+		return errNoOpponent
+	}
+
+	if !s.canCheckmate(clr) {
+		s.complete(
+			"abandonment_vs_insufficient_material",
+			"1/2-1/2",
+			fmt.Sprintf("%s left the game, but %s cannot checkmate", colorName(clr.Opp()), colorName(clr)),
+		)
+		return nil
+	}
+
+	result := "1-0"
+	if clr == color.Black {
+		result = "0-1"
+	}
+
+	s.complete("abandonment", result, fmt.Sprintf("%s left the game", colorName(clr.Opp())))
+	return nil
+}
@@ -0,0 +1,135 @@
+package game
+
+import (
+	"fmt"
+
+	"github.com/corentings/chess/v2"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/color"
+	"github.com/tecu23/eng-server/pkg/engine"
+	"github.com/tecu23/eng-server/pkg/tablebase"
+)
+
+// AdjudicationRules ends engine games early based on the engines' evaluations
+type AdjudicationRules struct {
+	DrawMoveNumber int // Draw adjudication starts at this full move number
+	DrawMoveCount  int // Moves both engines must stay within the draw score, 0 disables draws
+	DrawScore      int // Largest absolute score in centipawns considered drawn
+
+	ResignMoveCount int // Moves both engines must agree on the resign score, 0 disables resignation
+	ResignScore     int // Score in centipawns at which the losing side resigns
+}
+
+// adjudicateEval updates the adjudication streaks with the score the engine of
+// the given color reported for its move and ends the game once a rule is met
+func (s *Game) adjudicateEval(turn chess.Color, info engine.Info) {
+	if s.Status() == StatusCompleted {
+		return
+	}
+
+	rules := s.adjudication
+
+	// Scores are compared from White's point of view
+	score := info.Centipawns()
+	if turn == chess.Black {
+		score = -score
+	}
+
+	if rules.DrawMoveCount > 0 {
+		if s.moveNumber() >= rules.DrawMoveNumber && abs(score) <= rules.DrawScore {
+			s.drawStreak++
+		} else {
+			s.drawStreak = 0
+		}
+
+		if s.drawStreak >= 2*rules.DrawMoveCount {
+			s.log().Info("game adjudicated as a draw", zap.Int("score", score))
+			s.complete("adjudication", "1/2-1/2", "Draw by adjudication")
+			return
+		}
+	}
+
+	if rules.ResignMoveCount > 0 {
+		switch {
+		case score >= rules.ResignScore:
+			s.resignStreak = max(s.resignStreak, 0) + 1
+		case score <= -rules.ResignScore:
+			s.resignStreak = min(s.resignStreak, 0) - 1
+		default:
+			s.resignStreak = 0
+		}
+
+		if abs(s.resignStreak) >= 2*rules.ResignMoveCount {
+			winner := color.Color(color.White)
+			result := "1-0"
+			if s.resignStreak < 0 {
+				winner = color.Black
+				result = "0-1"
+			}
+
+			s.log().Info("game adjudicated as a win", zap.String("winner", string(winner)), zap.Int("score", score))
+			s.complete("adjudication", result, fmt.Sprintf("%s wins by adjudication", colorName(winner)))
+		}
+	}
+}
+
+// moveNumber returns the full move number of the current position
+func (s *Game) moveNumber() int {
+	return len(s.history)/2 + 1
+}
+
+// abs returns the absolute value of n
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// probeTablebase looks up the searched position in the tablebase, it returns
+// false when the position is not covered or the probe failed
+func (s *Game) probeTablebase(req searchRequest) (tablebase.Result, bool) {
+	if req.tablebase == nil || tablebase.CountPieces(req.fen) > tablebase.MaxPieces {
+		return tablebase.Result{}, false
+	}
+
+	result, err := req.tablebase.Probe(s.ctx, req.fen)
+	if err != nil {
+		req.logger.Warn("tablebase probe failed", zap.String("fen", req.fen), zap.Error(err))
+		return tablebase.Result{}, false
+	}
+
+	return result, true
+}
+
+// adjudicateTablebase ends the game with the tablebase verdict for the side to move
+func (s *Game) adjudicateTablebase(turn chess.Color, tb tablebase.Result) {
+	if s.Status() == StatusCompleted {
+		return
+	}
+
+	clr := color.Color(turn.String())
+
+	s.log().Info("game adjudicated by tablebase",
+		zap.String("color", string(clr)),
+		zap.String("wdl", string(tb.WDL)),
+		zap.Int("dtz", tb.DTZ))
+
+	if !tb.Decisive() {
+		s.complete("tablebase", "1/2-1/2", "Tablebase draw")
+		return
+	}
+
+	winner := clr
+	if tb.WDL == tablebase.WDLLoss {
+		winner = clr.Opp()
+	}
+
+	result := "1-0"
+	if winner == color.Black {
+		result = "0-1"
+	}
+
+	s.complete("tablebase", result, fmt.Sprintf("%s wins by tablebase", colorName(winner)))
+}
@@ -0,0 +1,35 @@
+package game
+
+import (
+	"time"
+
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/certify"
+)
+
+// certify signs the result of the finished game, nil when results are not signed
+func (s *Game) certify(reason, result string) *messages.ResultCertificate {
+	if s.certifier == nil {
+		return nil
+	}
+
+	moves := make([]string, 0, len(s.history))
+	for _, m := range s.history {
+		moves = append(moves, m.UCI)
+	}
+
+	times := s.Clock.GetRemainingTime()
+	cert := &messages.ResultCertificate{
+		GameID:    s.ID.String(),
+		Result:    result,
+		Reason:    reason,
+		FinalFEN:  s.fen(),
+		MovesHash: certify.MovesHash(moves),
+		WhiteTime: times.White,
+		BlackTime: times.Black,
+		EndedAt:   time.Now().UnixMilli(),
+	}
+	s.certifier.Sign(cert)
+
+	return cert
+}
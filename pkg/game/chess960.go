@@ -0,0 +1,515 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/corentings/chess/v2"
+)
+
+// noFile marks a castling right that has been lost
+const noFile chess.File = -1
+
+// Castling sides, indexing chess960.rooks
+const (
+	kingSide  = 0
+	queenSide = 1
+)
+
+// knightPlacements lists where the two knights go among the five files left
+// once the bishops and the queen are placed, indexed by the Scharnagl number
+var knightPlacements = [10][2]int{
+	{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2},
+	{1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4},
+}
+
+// Chess960StartPosition returns the FEN of the Chess960 starting position
+// with the given Scharnagl number, 518 being the standard position
+func Chess960StartPosition(n int) (string, error) {
+	if n < 0 || n >= 960 {
+		return "", fmt.Errorf("chess960 position must be between 0 and 959, got %d", n)
+	}
+
+	var rank [8]byte
+
+	rank[2*(n%4)+1] = 'b'
+	n /= 4
+	rank[2*(n%4)] = 'b'
+	n /= 4
+
+	// place puts a piece on the i-th empty file
+	place := func(i int, piece byte) {
+		for f := range rank {
+			if rank[f] != 0 {
+				continue
+			}
+			if i == 0 {
+				rank[f] = piece
+				return
+			}
+			i--
+		}
+	}
+
+	place(n%6, 'q')
+	n /= 6
+
+	knights := knightPlacements[n]
+	place(knights[1], 'n')
+	place(knights[0], 'n')
+
+	// The remaining files hold rook, king and rook in that order
+	place(0, 'r')
+	place(0, 'k')
+	place(0, 'r')
+
+	black := string(rank[:])
+	white := strings.ToUpper(black)
+
+	return fmt.Sprintf("%s/pppppppp/8/8/8/8/PPPPPPPP/%s w KQkq - 0 1", black, white), nil
+}
+
+// castleMove is a Chess960 castling move
+type castleMove struct {
+	side     int
+	kingFrom chess.Square
+	kingTo   chess.Square
+	rookFrom chess.Square
+	rookTo   chess.Square
+}
+
+// uci encodes the move as king takes own rook, as expected by engines in Chess960 mode
+func (m castleMove) uci() string {
+	return m.kingFrom.String() + m.rookFrom.String()
+}
+
+// san returns the castling notation without check markers
+func (m castleMove) san() string {
+	if m.side == queenSide {
+		return "O-O-O"
+	}
+	return "O-O"
+}
+
+// chess960 tracks the castling rights the chess library cannot represent for
+// Fischer Random games, where kings and rooks start on arbitrary files. The
+// library itself always sees positions without castling rights
+type chess960 struct {
+	rooks [2][2]chess.File // Castling rook files by color and side, noFile once the right is lost
+}
+
+// newChess960 reads the castling rights of a Chess960 FEN, written either in
+// Shredder-FEN (HAha) or X-FEN (KQkq), and returns the FEN the library can load
+func newChess960(fen string) (*chess960, string, error) {
+	fields := strings.Fields(fen)
+	if len(fields) < 4 {
+		return nil, "", errors.New("invalid start position: incomplete FEN")
+	}
+
+	board, err := boardFromFEN(fields[0])
+	if err != nil {
+		return nil, "", err
+	}
+
+	c := &chess960{rooks: [2][2]chess.File{{noFile, noFile}, {noFile, noFile}}}
+
+	if fields[2] != "-" {
+		for _, r := range fields[2] {
+			clr := chess.White
+			if r >= 'a' && r <= 'z' {
+				clr = chess.Black
+			}
+
+			king, ok := kingSquare(board, clr)
+			if !ok || king.Rank() != backRank(clr) {
+				return nil, "", errors.New("invalid start position: castling king is not on its back rank")
+			}
+
+			var file chess.File
+			switch upper := r &^ 0x20; {
+			case upper == 'K':
+				file = outermostRook(board, clr, king, 1)
+			case upper == 'Q':
+				file = outermostRook(board, clr, king, -1)
+			case upper >= 'A' && upper <= 'H':
+				file = chess.File(upper - 'A')
+			default:
+				return nil, "", fmt.Errorf("invalid start position: bad castling field %q", fields[2])
+			}
+
+			if file == noFile || board[chess.NewSquare(file, backRank(clr))] != chess.NewPiece(chess.Rook, clr) {
+				return nil, "", fmt.Errorf("invalid start position: no rook to castle with for %q", r)
+			}
+
+			side := kingSide
+			if file < king.File() {
+				side = queenSide
+			}
+			c.rooks[colorIndex(clr)][side] = file
+		}
+	}
+
+	fields[2] = "-"
+	return c, strings.Join(fields, " "), nil
+}
+
+// castlingField returns the castling rights in Shredder-FEN
+func (c *chess960) castlingField() string {
+	var sb strings.Builder
+	for _, clr := range []chess.Color{chess.White, chess.Black} {
+		for _, side := range []int{kingSide, queenSide} {
+			file := c.rooks[colorIndex(clr)][side]
+			if file == noFile {
+				continue
+			}
+
+			letter := file.String()
+			if clr == chess.White {
+				letter = strings.ToUpper(letter)
+			}
+			sb.WriteString(letter)
+		}
+	}
+
+	if sb.Len() == 0 {
+		return "-"
+	}
+	return sb.String()
+}
+
+// fen returns the FEN of the position with the castling rights in Shredder-FEN
+func (c *chess960) fen(state *chess.Game) string {
+	fields := strings.Fields(state.FEN())
+	if len(fields) < 3 {
+		return state.FEN()
+	}
+
+	fields[2] = c.castlingField()
+	return strings.Join(fields, " ")
+}
+
+// legalMoves lists the legal moves including castling, written as king takes rook
+func (c *chess960) legalMoves(state *chess.Game) []string {
+	moves := standardRules{}.legalMoves(state)
+	for _, m := range c.castleMoves(state.Position()) {
+		moves = append(moves, m.uci())
+	}
+	return moves
+}
+
+func (c *chess960) outcome(state *chess.Game) (variantOutcome, bool) {
+	return standardRules{}.outcome(state)
+}
+
+func (c *chess960) clone() variantRules {
+	clone := *c
+	return &clone
+}
+
+// castleMoves returns the legal castling moves of the side to move
+func (c *chess960) castleMoves(pos *chess.Position) []castleMove {
+	turn := pos.Turn()
+	board := pos.Board().SquareMap()
+
+	king, ok := kingSquare(board, turn)
+	if !ok {
+		return nil
+	}
+
+	rank := backRank(turn)
+	var moves []castleMove
+
+	for _, side := range []int{kingSide, queenSide} {
+		file := c.rooks[colorIndex(turn)][side]
+		if file == noFile {
+			continue
+		}
+
+		m := castleMove{
+			side:     side,
+			kingFrom: king,
+			rookFrom: chess.NewSquare(file, rank),
+			kingTo:   chess.NewSquare(chess.FileG, rank),
+			rookTo:   chess.NewSquare(chess.FileF, rank),
+		}
+		if side == queenSide {
+			m.kingTo = chess.NewSquare(chess.FileC, rank)
+			m.rookTo = chess.NewSquare(chess.FileD, rank)
+		}
+
+		if c.canCastle(board, turn, m) {
+			moves = append(moves, m)
+		}
+	}
+
+	return moves
+}
+
+// canCastle checks that every square the king and rook cross is empty and
+// that the king is never in check on its way
+func (c *chess960) canCastle(board map[chess.Square]chess.Piece, clr chess.Color, m castleMove) bool {
+	// The castling pieces are lifted, so a slider behind them is seen as well
+	lifted := make(map[chess.Square]chess.Piece, len(board))
+	for sq, p := range board {
+		if sq != m.kingFrom && sq != m.rookFrom {
+			lifted[sq] = p
+		}
+	}
+
+	for _, sq := range squaresBetween(m.rookFrom, m.rookTo) {
+		if lifted[sq] != chess.NoPiece {
+			return false
+		}
+	}
+
+	for _, sq := range squaresBetween(m.kingFrom, m.kingTo) {
+		if lifted[sq] != chess.NoPiece {
+			return false
+		}
+		if isAttacked(lifted, sq, clr.Other()) {
+			return false
+		}
+	}
+
+	// The king may not castle out of check either
+	return !isAttacked(board, m.kingFrom, clr.Other())
+}
+
+// parse decodes a move, castling being written as O-O, O-O-O or king takes own rook
+func (c *chess960) parse(state *chess.Game, notation string) (playedMove, error) {
+	castle := strings.TrimRight(strings.ReplaceAll(strings.TrimSpace(notation), "0", "O"), "+#")
+
+	for _, m := range c.castleMoves(state.Position()) {
+		if castle == m.san() || castle == m.uci() {
+			return c.encode(state, m)
+		}
+	}
+
+	return parseMove(state.Position(), notation)
+}
+
+// push plays a move on the state and returns the state to continue from.
+// Castling replaces the state, since the library cannot play it
+func (c *chess960) push(state *chess.Game, m playedMove) (*chess.Game, error) {
+	if m.castle == nil {
+		c.update(state.Position(), m.UCI)
+		if err := state.PushMove(m.SAN, nil); err != nil {
+			return nil, err
+		}
+		return state, nil
+	}
+
+	pos := state.Position()
+	board := pos.Board().SquareMap()
+
+	king := board[m.castle.kingFrom]
+	rook := board[m.castle.rookFrom]
+	delete(board, m.castle.kingFrom)
+	delete(board, m.castle.rookFrom)
+	board[m.castle.kingTo] = king
+	board[m.castle.rookTo] = rook
+
+	turn := pos.Turn()
+	c.rooks[colorIndex(turn)] = [2]chess.File{noFile, noFile}
+
+	fields := strings.Fields(state.FEN())
+	fields[0] = chess.NewBoard(board).String()
+	fields[1] = turn.Other().String()
+	fields[2] = "-"
+	fields[3] = "-"
+	fields[4] = fmt.Sprint(pos.HalfMoveClock() + 1)
+	if turn == chess.Black {
+		var fullMove int
+		fmt.Sscan(fields[5], &fullMove)
+		fields[5] = fmt.Sprint(fullMove + 1)
+	}
+
+	fen, err := chess.FEN(strings.Join(fields, " "))
+	if err != nil {
+		return nil, err
+	}
+
+	return chess.NewGame(fen), nil
+}
+
+// encode returns the notations of a castling move, adding the check marker
+func (c *chess960) encode(state *chess.Game, m castleMove) (playedMove, error) {
+	played := playedMove{SAN: m.san(), UCI: m.uci(), castle: &m}
+
+	// Play the move on a copy to find out whether it gives check
+	probe := *c
+	next, err := probe.push(state, played)
+	if err != nil {
+		return playedMove{}, err
+	}
+
+	pos := next.Position()
+	board := pos.Board().SquareMap()
+	if king, ok := kingSquare(board, pos.Turn()); ok && isAttacked(board, king, pos.Turn().Other()) {
+		if next.Method() == chess.Checkmate {
+			played.SAN += "#"
+		} else {
+			played.SAN += "+"
+		}
+	}
+
+	return played, nil
+}
+
+// update drops the castling rights affected by a regular move
+func (c *chess960) update(pos *chess.Position, uci string) {
+	if len(uci) < 4 {
+		return
+	}
+
+	from := parseSquare(uci[0:2])
+	to := parseSquare(uci[2:4])
+	board := pos.Board().SquareMap()
+
+	if p := board[from]; p.Type() == chess.King {
+		c.rooks[colorIndex(p.Color())] = [2]chess.File{noFile, noFile}
+	}
+
+	// A rook leaving or captured on its starting square loses its castling right
+	for _, clr := range []chess.Color{chess.White, chess.Black} {
+		for side, file := range c.rooks[colorIndex(clr)] {
+			if file == noFile {
+				continue
+			}
+			home := chess.NewSquare(file, backRank(clr))
+			if home == from || home == to {
+				c.rooks[colorIndex(clr)][side] = noFile
+			}
+		}
+	}
+}
+
+// boardFromFEN decodes the piece placement field of a FEN
+func boardFromFEN(placement string) (map[chess.Square]chess.Piece, error) {
+	fen, err := chess.FEN(placement + " w - - 0 1")
+	if err != nil {
+		return nil, fmt.Errorf("invalid start position: %w", err)
+	}
+	return chess.NewGame(fen).Position().Board().SquareMap(), nil
+}
+
+// outermostRook finds the rook furthest from the king in the given direction
+func outermostRook(board map[chess.Square]chess.Piece, clr chess.Color, king chess.Square, dir int) chess.File {
+	rook := chess.NewPiece(chess.Rook, clr)
+	found := noFile
+	for f := int(king.File()) + dir; f >= 0 && f < 8; f += dir {
+		if board[chess.NewSquare(chess.File(f), king.Rank())] == rook {
+			found = chess.File(f)
+		}
+	}
+	return found
+}
+
+// kingSquare returns the square of the king of the given color
+func kingSquare(board map[chess.Square]chess.Piece, clr chess.Color) (chess.Square, bool) {
+	king := chess.NewPiece(chess.King, clr)
+	for sq, p := range board {
+		if p == king {
+			return sq, true
+		}
+	}
+	return 0, false
+}
+
+// squaresBetween returns the squares from one square to another on the same
+// rank, both included
+func squaresBetween(from, to chess.Square) []chess.Square {
+	step := 1
+	if to < from {
+		step = -1
+	}
+
+	var squares []chess.Square
+	for sq := int(from); ; sq += step {
+		squares = append(squares, chess.Square(sq))
+		if sq == int(to) {
+			return squares
+		}
+	}
+}
+
+// isAttacked reports whether a square is attacked by a piece of the given color
+func isAttacked(board map[chess.Square]chess.Piece, sq chess.Square, by chess.Color) bool {
+	file, rank := int(sq.File()), int(sq.Rank())
+
+	at := func(f, r int) chess.Piece {
+		if f < 0 || f > 7 || r < 0 || r > 7 {
+			return chess.NoPiece
+		}
+		return board[chess.NewSquare(chess.File(f), chess.Rank(r))]
+	}
+
+	is := func(p chess.Piece, types ...chess.PieceType) bool {
+		if p == chess.NoPiece || p.Color() != by {
+			return false
+		}
+		for _, t := range types {
+			if p.Type() == t {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Pawns attack towards the opponent
+	pawnRank := rank - 1
+	if by == chess.Black {
+		pawnRank = rank + 1
+	}
+	if is(at(file-1, pawnRank), chess.Pawn) || is(at(file+1, pawnRank), chess.Pawn) {
+		return true
+	}
+
+	for _, d := range [][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}} {
+		if is(at(file+d[0], rank+d[1]), chess.Knight) {
+			return true
+		}
+	}
+
+	for _, d := range [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}, {1, 1}, {1, -1}, {-1, 1}, {-1, -1}} {
+		slider := chess.Rook
+		if d[0] != 0 && d[1] != 0 {
+			slider = chess.Bishop
+		}
+
+		for f, r, dist := file+d[0], rank+d[1], 1; f >= 0 && f < 8 && r >= 0 && r < 8; f, r, dist = f+d[0], r+d[1], dist+1 {
+			p := at(f, r)
+			if p == chess.NoPiece {
+				continue
+			}
+			if is(p, slider, chess.Queen) || (dist == 1 && is(p, chess.King)) {
+				return true
+			}
+			break
+		}
+	}
+
+	return false
+}
+
+// parseSquare decodes a square written as file and rank, e.g. e4
+func parseSquare(s string) chess.Square {
+	return chess.NewSquare(chess.File(s[0]-'a'), chess.Rank(s[1]-'1'))
+}
+
+// backRank returns the rank the pieces of a color start on
+func backRank(clr chess.Color) chess.Rank {
+	if clr == chess.Black {
+		return chess.Rank8
+	}
+	return chess.Rank1
+}
+
+// colorIndex maps a color to an index of the castling rights
+func colorIndex(clr chess.Color) int {
+	if clr == chess.Black {
+		return 1
+	}
+	return 0
+}
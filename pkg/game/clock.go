@@ -5,6 +5,7 @@ package game
 //		the time increments by a specified amount
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
@@ -22,6 +23,12 @@ type TimeControl struct {
 	MovesPerControl int          // For classical time controls (e.g., 40 moves in 2 hours)
 }
 
+// String returns the time control as the initial time and increment of White
+// in seconds, e.g. 300+2
+func (tc TimeControl) String() string {
+	return fmt.Sprintf("%d+%d", tc.WhiteTime/1000, tc.WhiteIncrement/1000)
+}
+
 // TimingMethod defines the different ways to time a chess game
 type TimingMethod int
 
@@ -49,6 +56,10 @@ type Clock struct {
 
 	startTime time.Time
 	isRunning bool
+	stopTicks context.CancelFunc // Ends the heartbeat of the current run
+	flagTimer *time.Timer        // Fires when the active player runs out of time
+
+	compensation time.Duration // Lag credited to the last move
 
 	// delay fields for the DelayTiming method
 	delayStartTime time.Time
@@ -61,11 +72,18 @@ type Clock struct {
 	tickChan   chan ClockTick
 }
 
-// ClockTick defines a single clock tick
+// ClockSyncInterval is how often a running clock sends a heartbeat. Between
+// heartbeats clients run the clock locally from the last authoritative state
+const ClockSyncInterval = 5 * time.Second
+
+// ClockTick defines the authoritative state of the clock at a point in time
 type ClockTick struct {
-	White       int64
-	Black       int64
-	ActiveColor color.Color
+	White        int64
+	Black        int64
+	ActiveColor  color.Color
+	Running      bool          // Whether the active player's time is running down
+	At           time.Time     // When the remaining times were measured
+	Compensation time.Duration // Lag credited to the player who moved last
 }
 
 // NewClock creates a new chess clock with the given time controls
@@ -83,8 +101,9 @@ func NewClock(tc TimeControl) *Clock {
 	}
 }
 
-// Start starts the clock for the current player
-func (c *Clock) Start() {
+// Start starts the clock for the current player. The heartbeat stops with
+// the clock or once the context is cancelled
+func (c *Clock) Start(ctx context.Context) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
@@ -95,10 +114,14 @@ func (c *Clock) Start() {
 	c.startTime = time.Now()
 	c.isRunning = true
 
-	go c.tickRoutine()
+	ctx, c.stopTicks = context.WithCancel(ctx)
+	go c.tickRoutine(ctx)
+
+	c.armFlag()
 }
 
-// Stop stops the clock
+// Stop stops the clock and ends its heartbeat goroutine, it is safe to call
+// more than once
 func (c *Clock) Stop() {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
@@ -108,10 +131,88 @@ func (c *Clock) Stop() {
 	}
 
 	c.updateTime()
+	c.halt()
+}
+
+// halt stops the clock and its heartbeat, the caller holds the mutex
+func (c *Clock) halt() {
 	c.isRunning = false
+	if c.stopTicks != nil {
+		c.stopTicks()
+		c.stopTicks = nil
+	}
+	if c.flagTimer != nil {
+		c.flagTimer.Stop()
+		c.flagTimer = nil
+	}
 }
 
-// Switch switches the active player and handles time increments
+// armFlag schedules the check of the active player's time, so a player who
+// stops moving still loses on time. The caller holds the mutex
+func (c *Clock) armFlag() {
+	if c.flagTimer != nil {
+		c.flagTimer.Stop()
+		c.flagTimer = nil
+	}
+	if !c.isRunning {
+		return
+	}
+
+	c.flagTimer = time.AfterFunc(max(c.left(), 0), c.checkFlag)
+}
+
+// checkFlag signals time up once the active player's time has run out
+func (c *Clock) checkFlag() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.isRunning {
+		return
+	}
+
+	// Lag credited since the timer was armed gave the player more time
+	if c.left() > 0 {
+		c.armFlag()
+		return
+	}
+
+	c.updateTime()
+}
+
+// left returns the time the active player has left, negative once they
+// flagged. The caller holds the mutex
+func (c *Clock) left() time.Duration {
+	remaining := c.whiteTimeMs
+	if c.activeColor == color.Black {
+		remaining = c.blackTimeMs
+	}
+
+	left := time.Duration(remaining) * time.Millisecond
+	if c.isRunning {
+		left -= time.Since(c.startTime)
+	}
+	return left
+}
+
+// Flagged reports whether the active player ran out of time, in which case
+// the clock stops and signals time up. A move is only completed, and its
+// increment granted, when it is made before the flag falls
+func (c *Clock) Flagged() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.left() > 0 {
+		return false
+	}
+
+	if c.isRunning {
+		c.updateTime()
+	}
+	return true
+}
+
+// Switch ends the move of the active player, crediting their increment, and
+// starts the time of the opponent. A player who flagged gets no increment
 func (c *Clock) Switch() {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
@@ -120,11 +221,11 @@ func (c *Clock) Switch() {
 		c.updateTime()
 	}
 
-	if c.timingMethod == IncrementTiming {
+	if c.timingMethod == IncrementTiming && c.left() > 0 {
 		if c.activeColor == color.White {
 			c.whiteTimeMs += c.whiteIncrement
 		} else {
-			c.blackIncrement += c.blackIncrement
+			c.blackTimeMs += c.blackIncrement
 		}
 	}
 
@@ -137,6 +238,40 @@ func (c *Clock) Switch() {
 	if c.isRunning {
 		c.startTime = time.Now()
 	}
+	c.armFlag()
+}
+
+// Compensate credits network lag to the active player before their move
+// switches the clock, never more than the time the move took. It returns the
+// applied credit
+func (c *Clock) Compensate(lag time.Duration) time.Duration {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.compensation = 0
+	if !c.isRunning || lag <= 0 {
+		return 0
+	}
+
+	c.compensation = min(lag, time.Since(c.startTime))
+	c.startTime = c.startTime.Add(c.compensation)
+	c.armFlag()
+	return c.compensation
+}
+
+// Restore resets both clocks and the active color, used to undo moves
+func (c *Clock) Restore(whiteTimeMs, blackTimeMs int64, activeColor color.Color) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.whiteTimeMs = whiteTimeMs
+	c.blackTimeMs = blackTimeMs
+	c.activeColor = activeColor
+
+	if c.isRunning {
+		c.startTime = time.Now()
+	}
+	c.armFlag()
 }
 
 // updateTime updates the time based on elapsed time
@@ -163,7 +298,7 @@ func (c *Clock) updateTime() {
 			c.blackTimeMs = 0
 		}
 
-		c.isRunning = false
+		c.halt()
 	}
 }
 
@@ -218,24 +353,57 @@ func (c *Clock) GetTickChannel() <-chan ClockTick {
 	return c.tickChan
 }
 
-// TickRoutine sends periodic updates of the clock state
-func (c *Clock) tickRoutine() {
-	ticker := time.NewTicker(100 * time.Millisecond)
+// Sync returns the current authoritative state of the clock
+func (c *Clock) Sync() ClockTick {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.sync()
+}
+
+// sync measures the clock, the caller holds the mutex
+func (c *Clock) sync() ClockTick {
+	now := time.Now()
+	tick := ClockTick{
+		White:        c.whiteTimeMs,
+		Black:        c.blackTimeMs,
+		ActiveColor:  c.activeColor,
+		Running:      c.isRunning,
+		At:           now,
+		Compensation: c.compensation,
+	}
+
+	if c.isRunning {
+		elapsed := now.Sub(c.startTime).Milliseconds()
+		if c.activeColor == color.White {
+			tick.White = max(tick.White-elapsed, 0)
+		} else {
+			tick.Black = max(tick.Black-elapsed, 0)
+		}
+	}
+
+	return tick
+}
+
+// TickRoutine sends a heartbeat of the clock state until the context is cancelled
+func (c *Clock) tickRoutine(ctx context.Context) {
+	ticker := time.NewTicker(ClockSyncInterval)
 	defer ticker.Stop()
 
-	for range ticker.C {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
 		c.mutex.RLock()
 		if !c.isRunning {
 			c.mutex.RUnlock()
 			return
 		}
 
-		times := c.GetRemainingTime()
-		tick := ClockTick{
-			White:       times.White,
-			Black:       times.Black,
-			ActiveColor: c.activeColor,
-		}
+		tick := c.sync()
 		c.mutex.RUnlock()
 
 		// Send tick update
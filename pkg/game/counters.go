@@ -0,0 +1,57 @@
+package game
+
+import (
+	"strconv"
+	"strings"
+)
+
+// moveCounters returns the halfmove clock and the fullmove number of the
+// position, read from its FEN which the variants keep up to date as well
+func (s *Game) moveCounters() (halfmove, fullmove int) {
+	fields := strings.Fields(s.fen())
+	if len(fields) < 6 {
+		return 0, 1
+	}
+
+	halfmove, _ = strconv.Atoi(fields[4])
+	fullmove, _ = strconv.Atoi(fields[5])
+	return halfmove, fullmove
+}
+
+// repetitions counts the occurrences of the current position in the game, the
+// current one included. Positions repeat when the pieces, the side to move,
+// the castling rights and the en passant square are the same
+func (s *Game) repetitions() int {
+	current := positionKey(s.state.Position().String())
+
+	count := 0
+	for _, pos := range s.state.Positions() {
+		if positionKey(pos.String()) == current {
+			count++
+		}
+	}
+	return max(count, 1)
+}
+
+// positionKey strips the move counters from a FEN
+func positionKey(fen string) string {
+	fields := strings.Fields(fen)
+	if len(fields) > 4 {
+		fields = fields[:4]
+	}
+	return strings.Join(fields, " ")
+}
+
+// movesToControl returns the moves the side to move makes before the next
+// time control, 0 when the time control has none
+func (s *Game) movesToControl() int {
+	perControl := s.timeControl.MovesPerControl
+	if perControl <= 0 {
+		return 0
+	}
+
+	// After an odd number of plies the side to move is the one that did not
+	// start, so either way it made half of them
+	made := len(s.history) / 2
+	return perControl - made%perControl
+}
@@ -0,0 +1,143 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/corentings/chess/v2"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/color"
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/engine"
+	"github.com/tecu23/eng-server/pkg/events"
+)
+
+// maxEngineRestarts is how many engines may crash in a row on the same move
+// before the game is given up
+const maxEngineRestarts = 2
+
+// engineReplacedCommand carries the engine leased to replace a crashed one
+type engineReplacedCommand struct {
+	request
+	id     int // Search interrupted by the crash
+	turn   chess.Color
+	engine *engine.UCIEngine
+	err    error
+}
+
+// recoverEngine leases a replacement for an engine that crashed during its
+// search, the search starts again on the new engine once it is installed
+func (s *Game) recoverEngine(result engineResultCommand) {
+	crashed := s.engineFor(result.turn)
+	clr := color.Color(result.turn.String())
+
+	// The engine of a simul is shared, so a board cannot swap it for its own
+	s.engineRestarts++
+	s.failuresOf(clr).crashes++
+	recovering := s.enginePool != nil && s.simul == nil && s.engineRestarts <= maxEngineRestarts
+
+	s.log().Error("engine crashed during its search",
+		zap.String("engine_id", crashed.ID.String()),
+		zap.String("color", string(clr)),
+		zap.Int("restarts", s.engineRestarts),
+		zap.Bool("recovering", recovering))
+
+	s.Publisher.Publish(events.Event{
+		Type:   events.EventEngineCrashed,
+		GameID: s.ID.String(),
+		Payload: messages.EngineCrashedPayload{
+			GameID:     s.ID.String(),
+			Color:      clr,
+			Recovering: recovering,
+		},
+	})
+
+	if !recovering {
+		s.searching = false
+		s.engineFailed(clr)
+		return
+	}
+
+	// The search stays in flight until the replacement is installed
+	go func() {
+		cmd := engineReplacedCommand{request: result.request, id: result.id, turn: result.turn}
+
+		cmd.engine, cmd.err = s.enginePool.GetEngine(s.ctx)
+		if cmd.err == nil {
+			// Options of the game, e.g. of its variant, were set on the crashed engine
+			cmd.err = cmd.engine.SetOptions(crashed.Options())
+		}
+
+		if err := s.send(cmd); err != nil && cmd.engine != nil {
+			s.enginePool.ReturnEngine(cmd.engine.ID.String())
+		}
+	}()
+}
+
+// installEngine swaps the crashed engine for its replacement and searches
+// the current position again
+func (s *Game) installEngine(c engineReplacedCommand) {
+	clr := color.Color(c.turn.String())
+
+	if c.err != nil {
+		s.log().Error("could not replace crashed engine", zap.Error(c.err))
+		if c.engine != nil {
+			s.enginePool.ReturnEngine(c.engine.ID.String())
+		}
+
+		if c.id == s.searchID {
+			s.searching = false
+			s.engineFailed(clr)
+		}
+		return
+	}
+
+	// The pool notices the crash when the engine is returned and starts a new one
+	s.engineMu.Lock()
+	crashed := s.engineFor(c.turn)
+	if s.Mode == ModeExhibition && c.turn == chess.Black {
+		s.OpponentEngine = c.engine
+	} else {
+		s.Engine = c.engine
+	}
+	s.engineMu.Unlock()
+
+	crashed.Record(nil)
+	s.recordEngine(c.engine)
+	s.enginePool.ReturnEngine(crashed.ID.String())
+
+	if s.Mode == ModeExhibition {
+		go s.forwardInfo(c.engine, clr)
+	}
+
+	s.log().Info("replaced crashed engine",
+		zap.String("old_engine_id", crashed.ID.String()),
+		zap.String("engine_id", c.engine.ID.String()))
+
+	// The search was abandoned meanwhile, e.g. by a takeback
+	if c.id != s.searchID {
+		return
+	}
+
+	s.searching = false
+	if err := s.startSearch(); err != nil {
+		s.log().Error("could not search again after engine crash", zap.Error(err))
+	}
+}
+
+// engineFailed ends the game as lost for the color whose engine crashed and
+// could not be replaced
+func (s *Game) engineFailed(clr color.Color) {
+	if s.Status() == StatusCompleted {
+		return
+	}
+
+	result := "1-0"
+	if clr == color.White {
+		result = "0-1"
+	}
+
+	s.complete("engine_failure", result,
+		fmt.Sprintf("The %s engine crashed and could not be replaced", strings.ToLower(colorName(clr))))
+}
@@ -0,0 +1,318 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/corentings/chess/v2"
+)
+
+// dropPattern matches a Crazyhouse drop, e.g. N@f3, in both SAN and UCI
+var dropPattern = regexp.MustCompile(`^([PNBRQ])@([a-h][1-8])[+#]?$`)
+
+// pocketPieces are the pieces that can be held in hand, in FEN order
+var pocketPieces = []chess.PieceType{chess.Queen, chess.Rook, chess.Bishop, chess.Knight, chess.Pawn}
+
+// dropMove puts a piece from the pocket on the board
+type dropMove struct {
+	piece chess.PieceType
+	to    chess.Square
+}
+
+// notation returns the drop in the notation shared by SAN and UCI
+func (m dropMove) notation() string {
+	return strings.ToUpper(m.piece.String()) + "@" + m.to.String()
+}
+
+// crazyhouse lets captured pieces be dropped back on the board by the capturing side
+type crazyhouse struct {
+	pockets  [2][7]int // Pieces in hand by color, indexed by piece type
+	promoted [64]bool  // Squares holding promoted pieces, which return to the pocket as pawns
+}
+
+// newCrazyhouse reads the pockets, written as [Qn] after the placement, and the
+// promoted piece markers (~) of a FEN and returns the FEN the library can load
+func newCrazyhouse(fen string) (*crazyhouse, string, error) {
+	c := &crazyhouse{}
+
+	if fen == "" || fen == "startpos" {
+		return c, fen, nil
+	}
+
+	fields := strings.Fields(fen)
+	placement := fields[0]
+
+	if open := strings.IndexByte(placement, '['); open >= 0 {
+		if !strings.HasSuffix(placement, "]") {
+			return nil, "", errors.New("invalid start position: unterminated pocket")
+		}
+
+		for _, r := range placement[open+1 : len(placement)-1] {
+			clr := chess.White
+			if r >= 'a' && r <= 'z' {
+				clr = chess.Black
+			}
+
+			piece := chess.PieceTypeFromString(strings.ToLower(string(r)))
+			if piece == chess.NoPieceType || piece == chess.King {
+				return nil, "", fmt.Errorf("invalid start position: bad pocket piece %q", r)
+			}
+			c.pockets[colorIndex(clr)][piece]++
+		}
+		placement = placement[:open]
+	}
+
+	// Promoted pieces are marked with a ~ right after them
+	sq := 0
+	for rank, row := range strings.Split(placement, "/") {
+		sq = (7 - rank) * 8
+		for _, r := range row {
+			switch {
+			case r == '~':
+				if sq > 0 {
+					c.promoted[sq-1] = true
+				}
+			case r >= '1' && r <= '8':
+				sq += int(r - '0')
+			default:
+				sq++
+			}
+		}
+	}
+
+	fields[0] = strings.ReplaceAll(placement, "~", "")
+	return c, strings.Join(fields, " "), nil
+}
+
+func (c *crazyhouse) parse(state *chess.Game, notation string) (playedMove, error) {
+	notation = strings.TrimSpace(notation)
+
+	match := dropPattern.FindStringSubmatch(notation)
+	if match == nil {
+		return parseMove(state.Position(), notation)
+	}
+
+	m := dropMove{
+		piece: chess.PieceTypeFromString(strings.ToLower(match[1])),
+		to:    parseSquare(match[2]),
+	}
+
+	if !c.canDrop(state.Position(), m) {
+		return playedMove{}, fmt.Errorf("move %s is not legal", notation)
+	}
+
+	played := playedMove{SAN: m.notation(), UCI: m.notation(), drop: &m}
+
+	// Play the drop on a copy to find out whether it gives check
+	probe := *c
+	next, err := probe.push(state, played)
+	if err != nil {
+		return playedMove{}, err
+	}
+
+	if out, over := probe.outcome(next); over && out.reason == chess.Checkmate.String() {
+		played.SAN += "#"
+	} else if inCheck(next.Position()) {
+		played.SAN += "+"
+	}
+
+	return played, nil
+}
+
+// canDrop checks that the piece is in hand, the square is empty, pawns stay
+// off the back ranks and the drop does not leave the own king in check
+func (c *crazyhouse) canDrop(pos *chess.Position, m dropMove) bool {
+	turn := pos.Turn()
+	if c.pockets[colorIndex(turn)][m.piece] == 0 {
+		return false
+	}
+
+	board := pos.Board().SquareMap()
+	if board[m.to] != chess.NoPiece {
+		return false
+	}
+
+	if m.piece == chess.Pawn && (m.to.Rank() == chess.Rank1 || m.to.Rank() == chess.Rank8) {
+		return false
+	}
+
+	board[m.to] = chess.NewPiece(m.piece, turn)
+	king, ok := kingSquare(board, turn)
+	return ok && !isAttacked(board, king, turn.Other())
+}
+
+// drops lists the legal drops of the side to move
+func (c *crazyhouse) drops(pos *chess.Position) []dropMove {
+	var drops []dropMove
+	for _, piece := range pocketPieces {
+		if c.pockets[colorIndex(pos.Turn())][piece] == 0 {
+			continue
+		}
+
+		for sq := chess.A1; sq <= chess.H8; sq++ {
+			if m := (dropMove{piece: piece, to: sq}); c.canDrop(pos, m) {
+				drops = append(drops, m)
+			}
+		}
+	}
+	return drops
+}
+
+func (c *crazyhouse) push(state *chess.Game, m playedMove) (*chess.Game, error) {
+	pos := state.Position()
+	turn := pos.Turn()
+
+	if m.drop == nil {
+		c.capture(pos, m.UCI)
+		if err := state.PushMove(m.SAN, nil); err != nil {
+			return nil, err
+		}
+		return state, nil
+	}
+
+	c.pockets[colorIndex(turn)][m.drop.piece]--
+
+	board := pos.Board().SquareMap()
+	board[m.drop.to] = chess.NewPiece(m.drop.piece, turn)
+
+	fields := strings.Fields(state.FEN())
+	fields[0] = chess.NewBoard(board).String()
+	fields[1] = turn.Other().String()
+	fields[3] = "-"
+	fields[4] = fmt.Sprint(pos.HalfMoveClock() + 1)
+	if turn == chess.Black {
+		var fullMove int
+		fmt.Sscan(fields[5], &fullMove)
+		fields[5] = fmt.Sprint(fullMove + 1)
+	}
+
+	fen, err := chess.FEN(strings.Join(fields, " "))
+	if err != nil {
+		return nil, err
+	}
+
+	return chess.NewGame(fen), nil
+}
+
+// capture moves a piece taken by a regular move into the pocket of the
+// capturing side and keeps track of promoted pieces
+func (c *crazyhouse) capture(pos *chess.Position, uci string) {
+	if len(uci) < 4 {
+		return
+	}
+
+	from := parseSquare(uci[0:2])
+	to := parseSquare(uci[2:4])
+	board := pos.Board().SquareMap()
+	mover := board[from]
+
+	captured := board[to].Type()
+	if c.promoted[to] {
+		captured = chess.Pawn
+	}
+	if mover.Type() == chess.Pawn && to == pos.EnPassantSquare() {
+		captured = chess.Pawn
+	}
+
+	if captured != chess.NoPieceType {
+		c.pockets[colorIndex(mover.Color())][captured]++
+	}
+
+	c.promoted[to] = c.promoted[from] || len(uci) == 5
+	c.promoted[from] = false
+}
+
+// fen returns the FEN with the pockets and the promoted piece markers
+func (c *crazyhouse) fen(state *chess.Game) string {
+	fields := strings.Fields(state.FEN())
+	board := state.Position().Board().SquareMap()
+
+	var sb strings.Builder
+	for rank := 7; rank >= 0; rank-- {
+		empty := 0
+		for file := 0; file < 8; file++ {
+			sq := chess.NewSquare(chess.File(file), chess.Rank(rank))
+			p := board[sq]
+			if p == chess.NoPiece {
+				empty++
+				continue
+			}
+
+			if empty > 0 {
+				fmt.Fprint(&sb, empty)
+				empty = 0
+			}
+
+			letter := p.Type().String()
+			if p.Color() == chess.White {
+				letter = strings.ToUpper(letter)
+			}
+			sb.WriteString(letter)
+
+			if c.promoted[sq] {
+				sb.WriteString("~")
+			}
+		}
+
+		if empty > 0 {
+			fmt.Fprint(&sb, empty)
+		}
+		if rank > 0 {
+			sb.WriteString("/")
+		}
+	}
+
+	sb.WriteString("[")
+	for _, clr := range []chess.Color{chess.White, chess.Black} {
+		for _, piece := range pocketPieces {
+			letter := piece.String()
+			if clr == chess.White {
+				letter = strings.ToUpper(letter)
+			}
+			sb.WriteString(strings.Repeat(letter, c.pockets[colorIndex(clr)][piece]))
+		}
+	}
+	sb.WriteString("]")
+
+	fields[0] = sb.String()
+	return strings.Join(fields, " ")
+}
+
+func (c *crazyhouse) legalMoves(state *chess.Game) []string {
+	moves := standardRules{}.legalMoves(state)
+	for _, m := range c.drops(state.Position()) {
+		moves = append(moves, m.notation())
+	}
+	return moves
+}
+
+// outcome follows the library, except that a mate or stalemate a drop can
+// escape does not end the game and pieces in hand are sufficient material
+func (c *crazyhouse) outcome(state *chess.Game) (variantOutcome, bool) {
+	switch state.Method() {
+	case chess.Checkmate, chess.Stalemate:
+		if len(c.drops(state.Position())) > 0 {
+			return variantOutcome{}, false
+		}
+	case chess.InsufficientMaterial:
+		if c.pockets != [2][7]int{} {
+			return variantOutcome{}, false
+		}
+	}
+
+	return standardRules{}.outcome(state)
+}
+
+func (c *crazyhouse) clone() variantRules {
+	clone := *c
+	return &clone
+}
+
+// inCheck reports whether the side to move is in check
+func inCheck(pos *chess.Position) bool {
+	board := pos.Board().SquareMap()
+	king, ok := kingSquare(board, pos.Turn())
+	return ok && isAttacked(board, king, pos.Turn().Other())
+}
@@ -0,0 +1,69 @@
+package game
+
+import (
+	"math/rand"
+	"sync"
+
+	"github.com/tecu23/eng-server/pkg/engine"
+)
+
+// deterministicNodes is the search of the deterministic games that set no
+// node or depth limit themselves
+const deterministicNodes = 1_000_000
+
+// gameRand is the source of the random choices of a game: book moves, weaker
+// candidates, fallback moves and pacing. Seeded, a replay makes the same
+// choices. Searches and the session loop both draw from it
+type gameRand struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+func newGameRand(seed int64) *gameRand {
+	return &gameRand{rng: rand.New(rand.NewSource(seed))}
+}
+
+func (r *gameRand) Intn(n int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.rng.Intn(n)
+}
+
+func (r *gameRand) Float64() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.rng.Float64()
+}
+
+// newSeed draws the seed of a game created without one, never 0
+func newSeed() int64 {
+	return rand.Int63n(1<<62) + 1
+}
+
+// pinDeterminism makes the engine searches of a game reproducible: one search
+// thread on the engines that have the option, and a fixed number of nodes per
+// move instead of time limits, which depend on the load of the machine. Fixed
+// depth searches are kept. Engines that randomize their own play, e.g. at a
+// reduced skill level, stay random
+func pinDeterminism(params *CreateGameParams, engines ...*engine.UCIEngine) error {
+	for _, eng := range engines {
+		if eng == nil {
+			continue
+		}
+		if _, ok := eng.ReportedOption("Threads"); !ok {
+			continue
+		}
+		if err := eng.SetOption("Threads", "1"); err != nil {
+			return err
+		}
+	}
+
+	switch params.TimeManagement.Policy {
+	case TimePolicyNodes, TimePolicyDepth:
+	default:
+		params.TimeManagement = TimeManagement{Policy: TimePolicyNodes, Nodes: deterministicNodes}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/tecu23/eng-server/pkg/book"
+	"github.com/tecu23/eng-server/pkg/engine"
+)
+
+// Difficulty is a named strength of the engine opponent, so clients can pick
+// a level without knowing the UCI options of the engine
+type Difficulty string
+
+// All the difficulty levels, from the weakest to the full strength engine
+const (
+	DifficultyBeginner     Difficulty = "beginner"
+	DifficultyCasual       Difficulty = "casual"
+	DifficultyIntermediate Difficulty = "intermediate"
+	DifficultyAdvanced     Difficulty = "advanced"
+	DifficultyExpert       Difficulty = "expert"
+	DifficultyMaster       Difficulty = "master"
+)
+
+// errNoStrengthOptions is returned for a limited difficulty on an engine
+// that can not play weaker
+var errNoStrengthOptions = errors.New("engine supports neither UCI_Elo nor Skill Level")
+
+// difficultyLevel is what a difficulty sets up for the engine
+type difficultyLevel struct {
+	Elo           int              // UCI_Elo played with UCI_LimitStrength, 0 plays at full strength
+	SkillLevel    int              // Skill Level of engines without UCI_Elo, from 0 to 20
+	MoveTime      time.Duration    // Longest search per move, 0 leaves the search limits alone
+	BookSelection book.Selection   // How book moves are picked, weaker levels vary their openings more
+	RandomMoves   randomMovePolicy // Weaker candidate moves played now and then
+	Pacing        Pacing           // Reply delay of the casual levels
+}
+
+// difficultyLevels maps every difficulty to its settings
+var difficultyLevels = map[Difficulty]difficultyLevel{
+	DifficultyBeginner: {
+		Elo: 800, SkillLevel: 0, MoveTime: 100 * time.Millisecond, BookSelection: book.SelectUniform,
+		RandomMoves: randomMovePolicy{Chance: 0.3, Lines: 4, MaxLoss: 300},
+		Pacing:      Pacing{MinDelay: time.Second, MaxDelay: 4 * time.Second},
+	},
+	DifficultyCasual: {
+		Elo: 1200, SkillLevel: 3, MoveTime: 250 * time.Millisecond, BookSelection: book.SelectUniform,
+		RandomMoves: randomMovePolicy{Chance: 0.2, Lines: 3, MaxLoss: 150},
+		Pacing:      Pacing{MinDelay: 500 * time.Millisecond, MaxDelay: 3 * time.Second},
+	},
+	DifficultyIntermediate: {
+		Elo: 1600, SkillLevel: 8, MoveTime: 500 * time.Millisecond, BookSelection: book.SelectWeighted,
+		RandomMoves: randomMovePolicy{Chance: 0.1, Lines: 3, MaxLoss: 80},
+		Pacing:      Pacing{MinDelay: 300 * time.Millisecond, MaxDelay: 2 * time.Second},
+	},
+	DifficultyAdvanced: {Elo: 2000, SkillLevel: 12, MoveTime: time.Second, BookSelection: book.SelectWeighted},
+	DifficultyExpert:   {Elo: 2400, SkillLevel: 16, MoveTime: 2 * time.Second, BookSelection: book.SelectBest},
+	DifficultyMaster:   {SkillLevel: 20, BookSelection: book.SelectBest},
+}
+
+// level returns the settings of the difficulty
+func (d Difficulty) level() (difficultyLevel, error) {
+	level, ok := difficultyLevels[d]
+	if !ok {
+		return difficultyLevel{}, errors.New("difficulty must be beginner, casual, intermediate, advanced, expert or master")
+	}
+	return level, nil
+}
+
+// randomMoves returns the random move policy of the difficulty, none for an
+// unknown or empty difficulty
+func (d Difficulty) randomMoves() randomMovePolicy {
+	return difficultyLevels[d].RandomMoves
+}
+
+// engineOptions returns the options that limit the strength of the engine,
+// UCI_Elo when the engine has it and Skill Level otherwise. Values are clamped
+// to the bounds the engine reported
+func (l difficultyLevel) engineOptions(eng *engine.UCIEngine) (map[string]string, error) {
+	if l.Elo == 0 {
+		// Full strength, undo a limit the pool may have configured
+		if _, ok := eng.ReportedOption("UCI_LimitStrength"); ok {
+			return map[string]string{"UCI_LimitStrength": "false"}, nil
+		}
+		if opt, ok := eng.ReportedOption("Skill Level"); ok {
+			return map[string]string{"Skill Level": clampOption(opt, l.SkillLevel)}, nil
+		}
+		return nil, nil
+	}
+
+	if _, ok := eng.ReportedOption("UCI_LimitStrength"); ok {
+		if opt, ok := eng.ReportedOption("UCI_Elo"); ok {
+			return map[string]string{
+				"UCI_LimitStrength": "true",
+				"UCI_Elo":           clampOption(opt, l.Elo),
+			}, nil
+		}
+	}
+
+	if opt, ok := eng.ReportedOption("Skill Level"); ok {
+		return map[string]string{"Skill Level": clampOption(opt, l.SkillLevel)}, nil
+	}
+
+	return nil, errNoStrengthOptions
+}
+
+// limitTime caps the search time of the engine, a clock managed engine
+// searches for the level's move time instead
+func (l difficultyLevel) limitTime(tm TimeManagement) TimeManagement {
+	if l.MoveTime == 0 {
+		return tm
+	}
+
+	switch tm.Policy {
+	case "", TimePolicyClock:
+		return TimeManagement{Policy: TimePolicyMoveTime, MoveTime: l.MoveTime}
+	case TimePolicyMoveTime:
+		tm.MoveTime = min(tm.MoveTime, l.MoveTime)
+	}
+	return tm
+}
+
+// applyDifficulty sets up the engine, search limits, book selection and
+// pacing of a new game for its difficulty, a book selection or pacing asked
+// by the client is kept
+func applyDifficulty(params *CreateGameParams, eng *engine.UCIEngine) error {
+	if params.Difficulty == "" {
+		return nil
+	}
+
+	level, err := params.Difficulty.level()
+	if err != nil {
+		return err
+	}
+
+	options, err := level.engineOptions(eng)
+	if err != nil {
+		return fmt.Errorf("difficulty %s: %w", params.Difficulty, err)
+	}
+
+	// Weaker candidates come from a MultiPV search, engines without MultiPV
+	// only ever play their best move
+	if level.RandomMoves.Lines > 1 {
+		if opt, ok := eng.ReportedOption("MultiPV"); ok {
+			if options == nil {
+				options = make(map[string]string)
+			}
+			options["MultiPV"] = clampOption(opt, level.RandomMoves.Lines)
+		}
+	}
+	if err := eng.SetOptions(options); err != nil {
+		return err
+	}
+
+	params.TimeManagement = level.limitTime(params.TimeManagement)
+
+	if params.BookOptions.Selection == "" {
+		params.BookOptions.Selection = level.BookSelection
+	}
+
+	if !params.Pacing.enabled() {
+		params.Pacing = level.Pacing
+	}
+
+	return nil
+}
+
+// clampOption fits a spin value within the bounds of the option
+func clampOption(opt engine.Option, value int) string {
+	v := int64(value)
+	if opt.Min != nil {
+		v = max(v, *opt.Min)
+	}
+	if opt.Max != nil {
+		v = min(v, *opt.Max)
+	}
+	return strconv.FormatInt(v, 10)
+}
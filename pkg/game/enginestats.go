@@ -0,0 +1,87 @@
+package game
+
+import (
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/color"
+	"github.com/tecu23/eng-server/internal/messages"
+)
+
+// engineFailures counts how the engine of a color failed during a game
+type engineFailures struct {
+	crashes  int
+	timeouts int
+}
+
+// failuresOf returns the failure counts of the engine of a color
+func (s *Game) failuresOf(clr color.Color) *engineFailures {
+	f, ok := s.failures[clr]
+	if !ok {
+		f = &engineFailures{}
+		s.failures[clr] = f
+	}
+	return f
+}
+
+// recordEngineResults stores the outcome of the finished game for each of its engines
+func (s *Game) recordEngineResults(result string) {
+	if s.telemetry == nil {
+		return
+	}
+
+	for _, turn := range []chess.Color{chess.White, chess.Black} {
+		clr := color.Color(turn.String())
+		if s.Mode == ModeHumanVsEngine && clr == s.PlayerColor {
+			continue
+		}
+
+		eng := s.engineFor(turn)
+		if eng == nil {
+			continue
+		}
+
+		score, ok := scoreOf(result, clr)
+		if !ok {
+			continue
+		}
+
+		failures := s.failuresOf(clr)
+		err := s.telemetry.SaveEngineResult(messages.EngineResult{
+			GameID:   s.ID.String(),
+			Engine:   engineName(eng),
+			Config:   eng.Config(),
+			Color:    clr,
+			Score:    score,
+			Crashes:  failures.crashes,
+			Timeouts: failures.timeouts,
+			Time:     time.Now(),
+		})
+		if err != nil {
+			s.log().Error("could not save engine result", zap.Error(err))
+		}
+	}
+}
+
+// scoreOf returns the points a color scored in a game result, false for an
+// unfinished game
+func scoreOf(result string, clr color.Color) (float64, bool) {
+	switch result {
+	case "1/2-1/2":
+		return 0.5, true
+	case "1-0":
+		if clr == color.White {
+			return 1, true
+		}
+		return 0, true
+	case "0-1":
+		if clr == color.Black {
+			return 1, true
+		}
+		return 0, true
+	default:
+		return 0, false
+	}
+}
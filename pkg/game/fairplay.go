@@ -0,0 +1,66 @@
+package game
+
+import (
+	"strings"
+
+	"github.com/tecu23/eng-server/internal/color"
+	"github.com/tecu23/eng-server/internal/messages"
+)
+
+// FairPlayQueue screens the moves of the players of a finished human game for
+// engine assistance, along with the post-game report when it was requested
+type FairPlayQueue interface {
+	QueueReview(subjects []messages.FairPlaySubject, startFEN string, moves []string, report bool) error
+}
+
+// reviewed reports whether the finished game is screened for fair play. Only
+// rated games count for anything a player could cheat for, and only games a
+// human played
+func (s *Game) reviewed() bool {
+	return s.fairPlay != nil && s.rated && s.Mode != ModeExhibition
+}
+
+// fairPlaySubjects describes the players of the finished game for its review,
+// both colors of a game between players
+func (s *Game) fairPlaySubjects() []messages.FairPlaySubject {
+	colors := []color.Color{s.PlayerColor}
+	if s.Mode == ModeHumanVsHuman {
+		colors = append(colors, s.PlayerColor.Opp())
+	}
+
+	times := s.moveTimes()
+	subjects := make([]messages.FairPlaySubject, 0, len(colors))
+	for _, clr := range colors {
+		subjects = append(subjects, messages.FairPlaySubject{
+			GameID:    s.ID.String(),
+			UserID:    s.UserOf(clr),
+			Color:     clr,
+			Rated:     s.rated,
+			MoveTimes: times,
+		})
+	}
+	return subjects
+}
+
+// moveTimes returns the milliseconds spent on every move of the game, by ply.
+// The clock of a side only runs on its turn, so the time a move took is what
+// its clock lost since the opponent's move before it, increments included
+func (s *Game) moveTimes() []int64 {
+	// Clocks of White and Black before the move, starting from the initial times
+	last := [2]int64{s.timeControl.WhiteTime, s.timeControl.BlackTime}
+
+	mover := 0
+	if fields := strings.Fields(s.startFEN); len(fields) > 1 && fields[1] == "b" {
+		mover = 1
+	}
+
+	times := make([]int64, len(s.history))
+	for i, m := range s.history {
+		before := [2]int64{m.WhiteTimeBefore, m.BlackTimeBefore}
+		times[i] = max(0, last[mover]-before[mover])
+
+		last = before
+		mover = 1 - mover
+	}
+	return times
+}
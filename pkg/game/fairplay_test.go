@@ -0,0 +1,65 @@
+package game
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/color"
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/events"
+)
+
+// reviewQueue records the players queued for review
+type reviewQueue chan []messages.FairPlaySubject
+
+func (q reviewQueue) QueueReview(subjects []messages.FairPlaySubject, _ string, _ []string, _ bool) error {
+	q <- subjects
+	return nil
+}
+
+func TestRatedPlayerGameScreensBothPlayers(t *testing.T) {
+	logger := zap.NewNop()
+	queue := make(reviewQueue, 1)
+
+	session, err := CreateGame(context.Background(), CreateGameParams{
+		GameID:         uuid.New(),
+		TimeControl:    TimeControl{WhiteTime: 60000, BlackTime: 60000},
+		PlayerColor:    color.Black,
+		Mode:           ModeHumanVsHuman,
+		Opponent:       uuid.New(),
+		OpponentUserID: "alice",
+		UserID:         "bob",
+		Rated:          true,
+		FairPlay:       queue,
+	}, uuid.New(), nil, events.NewPublisher(logger), logger)
+	require.NoError(t, err)
+	session.Start()
+	t.Cleanup(session.Close)
+
+	_, err = session.ProcessMove(color.White, "e2e4", "", 0, "")
+	require.NoError(t, err)
+	_, err = session.ProcessMove(color.Black, "e7e5", "", 0, "")
+	require.NoError(t, err)
+	require.NoError(t, session.Resign(color.White, ""))
+
+	select {
+	case subjects := <-queue:
+		require.Len(t, subjects, 2)
+		assert.Equal(t, "bob", subjects[0].UserID)
+		assert.Equal(t, color.Color(color.Black), subjects[0].Color)
+		assert.Equal(t, "alice", subjects[1].UserID)
+		assert.Equal(t, color.Color(color.White), subjects[1].Color)
+		for _, s := range subjects {
+			assert.Equal(t, session.ID.String(), s.GameID)
+			assert.Len(t, s.MoveTimes, 2)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the game was not queued for review")
+	}
+}
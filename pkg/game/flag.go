@@ -0,0 +1,47 @@
+package game
+
+import (
+	"github.com/corentings/chess/v2"
+
+	"github.com/tecu23/eng-server/internal/color"
+)
+
+// canCheckmate reports whether a side has the material to ever checkmate,
+// which decides a game lost on time: a player who runs out of time against a
+// side that cannot mate draws instead of losing. As on the common servers a
+// lone king cannot mate, nor can a single knight or bishop unless the other
+// side has pieces of its own that could block its king in. Only standard and
+// Chess960 games are won by checkmate alone, in the other variants pieces in
+// hand, the hill or checks let any side win
+func (s *Game) canCheckmate(clr color.Color) bool {
+	if s.Variant != VariantStandard && s.Variant != VariantChess960 {
+		return true
+	}
+
+	var minors, majors, blockers int
+	for _, piece := range s.state.Position().Board().SquareMap() {
+		if piece.Type() == chess.King {
+			continue
+		}
+		if color.Color(piece.Color().String()) != clr {
+			blockers++
+			continue
+		}
+
+		switch piece.Type() {
+		case chess.Knight, chess.Bishop:
+			minors++
+		default:
+			majors++ // Pawns count, they can promote
+		}
+	}
+
+	switch {
+	case majors > 0 || minors > 1:
+		return true
+	case minors == 1:
+		return blockers > 0
+	default:
+		return false
+	}
+}
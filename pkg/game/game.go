@@ -1,8 +1,13 @@
 package game
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/corentings/chess/v2"
 	"github.com/google/uuid"
@@ -10,43 +15,209 @@ import (
 
 	"github.com/tecu23/eng-server/internal/color"
 	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/book"
+	"github.com/tecu23/eng-server/pkg/certify"
 	"github.com/tecu23/eng-server/pkg/engine"
 	"github.com/tecu23/eng-server/pkg/events"
+	"github.com/tecu23/eng-server/pkg/rating"
+	"github.com/tecu23/eng-server/pkg/tablebase"
 )
 
 type CreateGameParams struct {
-	GameID       uuid.UUID
-	StartPostion string
-	TimeControl  TimeControl
+	GameID         uuid.UUID
+	StartPostion   string
+	TimeControl    TimeControl
+	TimeManagement TimeManagement // Search limits of the engine, the zero value lets it manage its clock
+	Difficulty     Difficulty     // Strength of the engine, empty leaves the engine options alone
+	Pacing         Pacing         // Artificial delay of the engine replies, the difficulty's when zero
+	PlayerColor    color.Color
+	Variant        Variant // Rules of the game, empty means standard chess
+	EngineFallback EngineFallback
+	Mode           GameMode
+	OpponentEngine *engine.UCIEngine // Engine playing Black in an exhibition game
+	Opponent       uuid.UUID         // Connection playing the other color of a game between players
+	OpponentUserID string            // User playing the other color of a game between players
+	Book           *book.Book        // Opening book played for the engine, nil disables it
+	BookOptions    book.Options
+	Tablebase      *tablebase.Tablebase // Adjudicates exhibition endgames, nil disables it
+	Adjudication   AdjudicationRules    // Eval based adjudication of exhibition games
+	UserID         string               // User playing the game, empty for anonymous players
+	Rated          bool                 // Whether the game counts for the rating of the user
+	Ratings        rating.Store         // Where user ratings are kept, nil disables rated games
+	EngineRating   rating.Rating        // Rating the engine opponent is played as
+	Archive        Archive              // Keeps the record of the game once finished, nil disables it
+	Hints          HintSettings         // Move hints available to the player
+	Analysis       AnalysisQueue        // Analyzes the game once finished, nil when not requested
+	EvalBar        Evaluator            // Evaluates the position after every move, nil disables it
+	Telemetry      Telemetry            // Keeps the search statistics of engine moves, nil disables it
+	Transcripts    TranscriptStore      // Keeps the engine transcript once finished, nil disables it
+	Simul          *Simul               // Simul the game is a board of, its engine is the simul's
+	Seed           int64                // Seeds the random choices of the game, 0 draws a seed
+	Deterministic  bool                 // Pins the engines to one thread and fixed nodes so the seed replays the game
+
+	LagCompensation time.Duration // Most network lag credited to the player per move, 0 disables it
+	EnginePool      EnginePool    // Takes the engines back once the session ends, nil closes them instead
+	Statuses        StatusStore   // Where status transitions are recorded, nil disables it
+
+	Certifier *certify.Signer // Signs the results of finished games, nil leaves them unsigned
+	FairPlay  FairPlayQueue   // Screens the players of finished rated games, nil disables it
 }
 
+// StatusStore records the status transitions of games
+type StatusStore interface {
+	UpdateStatus(id uuid.UUID, status GameStatus) error
+}
+
+// EnginePool takes back the engines of a terminated session and leases a
+// replacement for an engine that crashed
+type EnginePool interface {
+	GetEngine(ctx context.Context) (*engine.UCIEngine, error)
+	ReturnEngine(engineID string)
+}
+
+// GameMode defines who plays the game
+type GameMode string
+
+// All the supported game modes
+const (
+	ModeHumanVsEngine GameMode = "human_vs_engine"
+	ModeExhibition    GameMode = "engine_vs_engine"
+	ModeHumanVsHuman  GameMode = "human_vs_human"
+)
+
+// EngineFallback defines what happens when the engine does not answer before its deadline
+type EngineFallback string
+
+// All the supported engine fallbacks
+const (
+	FallbackForfeit    EngineFallback = "forfeit" // The engine loses on time
+	FallbackRandomMove EngineFallback = "random"  // A random legal move is played for the engine
+)
+
+// stopGracePeriod is how long the engine may take to answer a stop command
+const stopGracePeriod = 500 * time.Millisecond
+
+// commandQueueSize is the number of commands a session buffers
+const commandQueueSize = 16
+
+// ErrGameTerminated is returned when a command is sent to a terminated game
+var ErrGameTerminated = errors.New("game has been terminated")
+
+// Errors returned for player commands the game refuses
+var (
+	ErrGameOver    = errors.New("game is already over")
+	ErrIllegalMove = errors.New("illegal move")
+	ErrNotYourTurn = errors.New("not your turn")
+)
+
+// errExhibition is returned for player commands sent to an exhibition game
+var errExhibition = errors.New("exhibition games are played by engines only")
+
 type GameStatus string
 
 const (
 	StatusActive    GameStatus = "active"
 	StatusPending   GameStatus = "pending"
 	StatusCompleted GameStatus = "completed"
+	StatusPaused    GameStatus = "paused" // Restored after a restart, waiting for its player to resume it
 )
 
+// Game is a single game session against an engine or another player. All
+// game state is owned by the session loop and must only be changed through commands
 type Game struct {
-	ID     uuid.UUID
-	Engine *engine.UCIEngine
-
-	ConnectionID uuid.UUID
-
-	Clock  *Clock
-	Game   *chess.Game
-	Status GameStatus
-
-	done chan bool
-
-	mu sync.Mutex
+	ID      uuid.UUID
+	Mode    GameMode
+	Variant Variant
+	Engine  *engine.UCIEngine // The engine opponent, or the engine playing White in an exhibition, nil between players
+
+	// OpponentEngine plays Black in an exhibition game
+	OpponentEngine *engine.UCIEngine
+
+	PlayerColor color.Color // The color played by the connection
+
+	Clock *Clock
+
+	state     *chess.Game   // Owned by the session loop
+	startFEN  string        // Position the game started from
+	rules     variantRules  // Rules of the variant, owned by the session loop
+	history   []playedMove  // Moves played so far, owned by the session loop
+	result    string        // Final result once the game is over, owned by the session loop
+	status    atomic.Value  // GameStatus, readable from any goroutine
+	owner     atomic.Value  // uuid.UUID of the connection playing the game, readable from any goroutine
+	opponent  atomic.Value  // uuid.UUID of the connection playing the other color of a game between players, readable from any goroutine
+	searching bool          // Whether an engine search is in flight, owned by the session loop
+	searchID  int           // Identifies the latest search so stale results are dropped, owned by the session loop
+	premove   string        // Move queued by the player while the engine thinks, owned by the session loop
+	trace     string        // Request ID of the command being handled, owned by the session loop
+	commands  chan command  // Commands consumed by the session loop
+	finished  chan struct{} // Closed when the game is over
+	terminate sync.Once
+	engineMu  sync.Mutex // Guards the engines swapped after a crash against shutdown
+
+	ctx    context.Context    // Cancelled when the session terminates, ending its goroutines and engine searches
+	cancel context.CancelFunc // Cancels ctx
+
+	lastActivity atomic.Int64 // Unix nanoseconds of the last command of a player or engine
+
+	moveIDs     map[string]messages.GameStatePayload // States reached by the moves sent with a client move ID, owned by the session loop
+	moveIDOrder []string                             // Remembered move IDs, the oldest first
+
+	failures map[color.Color]*engineFailures // Failures of the engine of each color, owned by the session loop
+
+	startRules variantRules // Variant state of the starting position
+
+	engineFallback EngineFallback
+	book           *book.Book
+	bookOptions    book.Options
+	tablebase      *tablebase.Tablebase
+	adjudication   AdjudicationRules
+	engineRestarts int // Consecutive engine crashes without a move, owned by the session loop
+	drawStreak     int // Consecutive plies within the draw score, owned by the session loop
+	resignStreak   int // Consecutive plies beyond the resign score, positive for White, owned by the session loop
+
+	userID         string
+	opponentUserID string // User playing the other color of a game between players
+	rated          bool
+	ratings        rating.Store
+	engineRating   rating.Rating
+
+	timeControl    TimeControl
+	timeManagement TimeManagement
+	difficulty     Difficulty
+	randomMoves    randomMovePolicy // Weaker candidate moves the engine plays now and then
+	pacing         Pacing
+	timeManager    timeManager // Builds the go command of engine searches
+	createdAt      time.Time
+	records        Archive
+	analysis       AnalysisQueue
+	evalBar        Evaluator
+	telemetry      Telemetry
+	transcript     *engine.Transcript // UCI dialogue of the engines of the game
+	transcripts    TranscriptStore
+	simul          *Simul // Shares its engine with the other boards, nil outside a simul
+	seed           int64
+	deterministic  bool
+	rng            *gameRand // Random choices of the game, drawn from seed
+
+	lagCompensation time.Duration
+	enginePool      EnginePool
+	statuses        StatusStore
+
+	hints     HintSettings
+	hintsLeft int  // Hints the player may still request, owned by the session loop
+	hinting   bool // Whether a hint search is in flight, owned by the session loop
+
+	certifier *certify.Signer
+	fairPlay  FairPlayQueue
 
 	Publisher *events.Publisher
 	Logger    *zap.Logger
 }
 
+// CreateGame creates a game session. The session terminates when the context
+// is cancelled
 func CreateGame(
+	ctx context.Context,
 	params CreateGameParams,
 	connectionId uuid.UUID,
 	eng *engine.UCIEngine,
@@ -55,159 +226,359 @@ func CreateGame(
 ) (*Game, error) {
 	clock := NewClock(params.TimeControl)
 
+	if err := params.Pacing.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := applyDifficulty(&params, eng); err != nil {
+		return nil, err
+	}
+
+	if params.Deterministic {
+		if err := pinDeterminism(&params, eng, params.OpponentEngine); err != nil {
+			return nil, err
+		}
+	}
+	if params.Seed == 0 {
+		params.Seed = newSeed()
+	}
+
+	timeManager, err := newTimeManager(params.TimeManagement, params.TimeControl)
+	if err != nil {
+		return nil, err
+	}
+
 	var internalGame *chess.Game
 
-	if params.StartPostion == "" || params.StartPostion == "startpos" {
+	variant := params.Variant
+	if variant == "" {
+		variant = VariantStandard
+	}
+
+	startPosition := params.StartPostion
+	if variant == VariantChess960 && (startPosition == "" || startPosition == "startpos") {
+		return nil, errors.New("chess960 games need a start position")
+	}
+
+	rules, startPosition, err := newRules(variant, startPosition)
+	if err != nil {
+		return nil, err
+	}
+
+	if startPosition == "" || startPosition == "startpos" {
 		internalGame = chess.NewGame()
 	} else {
-		internalGame = chess.NewGame()
+		fen, err := chess.FEN(startPosition)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start position: %w", err)
+		}
+		internalGame = chess.NewGame(fen)
 	}
 
-	session := &Game{
-		ID: params.GameID,
+	for _, eng := range []*engine.UCIEngine{eng, params.OpponentEngine} {
+		if eng == nil {
+			continue
+		}
+		if err := enableVariant(eng, variant); err != nil {
+			return nil, fmt.Errorf("error enabling %s on engine: %w", variant, err)
+		}
+	}
 
-		ConnectionID: connectionId,
+	mode := params.Mode
+	if mode == "" {
+		mode = ModeHumanVsEngine
+	}
 
-		Engine: eng,
+	ctx, cancel := context.WithCancel(ctx)
 
-		Game:   internalGame,
-		Clock:  clock,
-		Status: StatusPending,
+	session := &Game{
+		ID:   params.GameID,
+		Mode: mode,
+
+		PlayerColor: params.PlayerColor,
+
+		Engine:         eng,
+		OpponentEngine: params.OpponentEngine,
+
+		Variant: variant,
+
+		Clock:    clock,
+		state:    internalGame,
+		startFEN: internalGame.FEN(),
+		rules:    rules,
+
+		commands: make(chan command, commandQueueSize),
+		ctx:      ctx,
+		cancel:   cancel,
+		finished: make(chan struct{}),
+		moveIDs:  make(map[string]messages.GameStatePayload),
+		failures: make(map[color.Color]*engineFailures),
+
+		engineFallback: params.EngineFallback,
+		book:           params.Book,
+		bookOptions:    params.BookOptions,
+		tablebase:      params.Tablebase,
+		adjudication:   params.Adjudication,
+
+		userID:         params.UserID,
+		opponentUserID: params.OpponentUserID,
+		rated:          params.Rated && params.UserID != "" && (mode != ModeHumanVsHuman || params.OpponentUserID != ""),
+		ratings:        params.Ratings,
+		engineRating:   params.EngineRating,
+
+		timeControl:    params.TimeControl,
+		timeManagement: params.TimeManagement,
+		difficulty:     params.Difficulty,
+		randomMoves:    params.Difficulty.randomMoves(),
+		pacing:         params.Pacing,
+		timeManager:    timeManager,
+		createdAt:      time.Now(),
+		records:        params.Archive,
+		analysis:       params.Analysis,
+		evalBar:        params.EvalBar,
+		telemetry:      params.Telemetry,
+		transcript:     engine.NewTranscript(transcriptLines),
+		transcripts:    params.Transcripts,
+		simul:          params.Simul,
+		seed:           params.Seed,
+		deterministic:  params.Deterministic,
+		rng:            newGameRand(params.Seed),
+
+		lagCompensation: params.LagCompensation,
+		enginePool:      params.EnginePool,
+		statuses:        params.Statuses,
+
+		hints:     params.Hints,
+		hintsLeft: params.Hints.Budget,
+
+		certifier: params.Certifier,
+		fairPlay:  params.FairPlay,
 
-		done:      make(chan bool),
 		Logger:    logger,
 		Publisher: publisher,
 	}
+	session.status.Store(StatusPending)
+	session.owner.Store(connectionId)
+	session.opponent.Store(params.Opponent)
+	session.touch()
+	session.startRules = rules.clone()
+
+	// Simul boards record the shared engine only while it searches for them
+	if session.simul == nil {
+		session.recordEngine(session.Engine)
+		session.recordEngine(session.OpponentEngine)
+	}
 
 	return session, nil
 }
 
-func (s *Game) ProcessMove(move string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// setStatus moves the game to a new status and records the transition
+func (s *Game) setStatus(status GameStatus) {
+	s.status.Store(status)
 
-	// Record the move.
-	s.Clock.Switch()
-	s.Game.PushMove(move, nil)
+	if s.statuses == nil {
+		return
+	}
+	if err := s.statuses.UpdateStatus(s.ID, status); err != nil {
+		s.Logger.Error("could not record game status", zap.String("status", string(status)), zap.Error(err))
+	}
+}
 
-	s.Logger.Info(
-		"processed move",
-		zap.String("move", move),
-		zap.String("new_turn", string(s.Game.Position().Turn())),
-	)
+// EngineOpens reports whether the engine plays the first move of a game
+// against a player, when the player chose the side not to move
+func (s *Game) EngineOpens() bool {
+	if s.Mode != ModeHumanVsEngine {
+		return false
+	}
 
-	// Publish move processed event
-	s.Publisher.Publish(events.Event{
-		Type:   events.EventMoveProcessed,
-		GameID: s.ID.String(),
-		Payload: messages.GameStatePayload{
-			GameID:    s.ID.String(),
-			WhiteTime: s.Clock.GetRemainingTime().White,
-			BlackTime: s.Clock.GetRemainingTime().Black,
-		},
-	})
+	fields := strings.Fields(s.startFEN)
+	return len(fields) > 1 && color.Color(fields[1]) != s.PlayerColor
+}
 
-	return nil
+// playerToMove reports whether the player is on turn, owned by the session loop
+func (s *Game) playerToMove() bool {
+	return color.Color(s.state.Position().Turn().String()) == s.PlayerColor
 }
 
-func (s *Game) ProcessEngineMove() {
-	s.mu.Lock()
-	wTime, bTime, mvs, fen, turn := s.Clock.GetRemainingTime().White, s.Clock.GetRemainingTime().Black, s.Game.Moves(), s.Game.FEN(), s.Game.Position().
-		Turn()
-	s.mu.Unlock()
+// Owner returns the ID of the connection playing the game, uuid.Nil for a
+// restored game nobody resumed yet
+func (s *Game) Owner() uuid.UUID {
+	return s.owner.Load().(uuid.UUID)
+}
 
-	command := fmt.Sprintf("position fen %s", fen)
-	if err := s.Engine.SendCommand(command); err != nil {
-		// Handle error
-		s.Logger.Error("engine command error", zap.Error(err))
+// Transfer hands the seat of a color to another connection of its player
+func (s *Game) Transfer(clr color.Color, connectionID uuid.UUID) {
+	if s.Mode == ModeHumanVsHuman && clr != s.PlayerColor {
+		s.opponent.Store(connectionID)
 		return
 	}
+	s.owner.Store(connectionID)
+}
+
+// UserID returns the user playing the game, empty for anonymous games
+func (s *Game) UserID() string {
+	return s.userID
+}
 
-	movestogo := len(mvs) / 2
+// Status returns the current status of the game
+func (s *Game) Status() GameStatus {
+	return s.status.Load().(GameStatus)
+}
 
-	command = fmt.Sprintf(
-		"go wtime %d btime %d movestogo %d",
-		wTime,
-		bTime,
-		40-movestogo,
-	)
-	if err := s.Engine.SendCommand(command); err != nil {
-		// Handle error
-		s.Logger.Error("engine command error", zap.Error(err))
+// Seed returns the seed of the random choices of the game, with the same
+// seed a deterministic game replays move for move
+func (s *Game) Seed() int64 {
+	return s.seed
+}
 
-		return
+// Deterministic reports whether the engines of the game are pinned to
+// reproducible searches
+func (s *Game) Deterministic() bool {
+	return s.deterministic
+}
+
+// Start starts the clock and the session loop. Exhibition games start
+// playing right away
+func (s *Game) Start() {
+	s.setStatus(StatusActive)
+	s.Clock.Start(s.ctx)
+	s.publishClock()
+
+	go s.run()
+
+	if s.Mode == ModeExhibition {
+		go s.forwardInfo(s.Engine, color.White)
+		go s.forwardInfo(s.OpponentEngine, color.Black)
+
+		if err := s.RequestEngineMove(""); err != nil {
+			s.Logger.Error("could not start exhibition game", zap.Error(err))
+		}
 	}
+}
 
-	// Wait for the best move from the engine.
-	bestMove := <-s.Engine.BestMoveChan
+// ProcessMove applies a move of the player of the given color and waits for
+// the result. The lag measured on the player's connection is credited to
+// their clock, up to the lag compensation of the game. A move sent again with the move ID of a
+// played move is not applied twice, its first outcome is returned marked as
+// replayed. An empty move ID disables the check. The request ID tags the logs
+// of the move
+func (s *Game) ProcessMove(clr color.Color, move, moveID string, lag time.Duration, requestID string) (MoveOutcome, error) {
+	reply := make(chan moveReply, 1)
+	if err := s.send(moveCommand{request: request{requestID}, color: clr, move: move, moveID: moveID, lag: lag, reply: reply}); err != nil {
+		return MoveOutcome{}, err
+	}
 
-	// Process the move as if the engine made it.
-	if err := s.ProcessMove(bestMove); err != nil {
-		s.Logger.Error("failed to process engine move", zap.Error(err))
-		return
+	select {
+	case r := <-reply:
+		return r.outcome, r.err
+	case <-s.ctx.Done():
+		return MoveOutcome{}, ErrGameTerminated
 	}
+}
 
-	// Publish engine moved event
-	s.Publisher.Publish(events.Event{
-		Type:   events.EventEngineMoved,
-		GameID: s.ID.String(),
-		Payload: messages.EngineMovePayload{
-			Move:  bestMove,
-			Color: color.Color(turn),
-		},
-	})
+// RequestEngineMove starts an engine search without waiting for its result.
+// The request ID tags the logs of the search, down to the engine
+func (s *Game) RequestEngineMove(requestID string) error {
+	reply := make(chan error, 1)
+	if err := s.send(engineRequestCommand{request: request{requestID}, reply: reply}); err != nil {
+		return err
+	}
 
-	s.Logger.Info("engine move processed", zap.String("move", bestMove))
-}
-
-func (s *Game) StartClockUpdates() {
-	go func() {
-		tickChan := s.Clock.GetTickChannel()
-		for {
-			select {
-			case <-s.done:
-				return
-			case tick := <-tickChan:
-				// Publish clock update event
-				s.Publisher.Publish(events.Event{
-					Type:   events.EventClockUpdated,
-					GameID: s.ID.String(),
-					Payload: messages.ClockUpdatePayload{
-						WhiteTime:   tick.White,
-						BlackTime:   tick.Black,
-						ActiveColor: string(tick.ActiveColor),
-					},
-				})
-			}
-		}
-	}()
-}
-
-func (s *Game) StartTimeoutMonitor() {
-	go func() {
-		timeupChan := s.Clock.GetTimeupChannel()
-		for {
-			select {
-			case <-s.done:
-				return
-			case color := <-timeupChan:
-				// Publish time up event
-				s.Publisher.Publish(events.Event{
-					Type:   events.EventTimeUp,
-					GameID: s.ID.String(),
-					Payload: messages.TimeupPayload{
-						Color: string(color),
-					},
-				})
-				s.Logger.Info("player time expired", zap.String("color", string(color)))
-			}
-		}
-	}()
+	return s.await(reply)
+}
+
+// State returns a snapshot of the board, move history, clocks and result
+func (s *Game) State() (messages.GameStatePayload, error) {
+	reply := make(chan messages.GameStatePayload, 1)
+	if err := s.send(stateCommand{reply: reply}); err != nil {
+		return messages.GameStatePayload{}, err
+	}
+
+	select {
+	case state := <-reply:
+		return state, nil
+	case <-s.ctx.Done():
+		return messages.GameStatePayload{}, ErrGameTerminated
+	}
+}
+
+// Premove queues a move of the player of the given color, played as soon as
+// the opponent has moved. An empty move cancels the queued premove
+func (s *Game) Premove(clr color.Color, move string, requestID string) error {
+	reply := make(chan error, 1)
+	if err := s.send(premoveCommand{request: request{requestID}, color: clr, move: move, reply: reply}); err != nil {
+		return err
+	}
+
+	return s.await(reply)
+}
+
+// Takeback undoes the last move of the player, or the engine reply as well
+// when the engine already answered
+func (s *Game) Takeback(requestID string) error {
+	reply := make(chan error, 1)
+	if err := s.send(takebackCommand{request: request{requestID}, reply: reply}); err != nil {
+		return err
+	}
+
+	return s.await(reply)
+}
+
+// RequestHint starts a hint search for the player, the hint is published once ready
+func (s *Game) RequestHint(depth int, requestID string) error {
+	reply := make(chan error, 1)
+	if err := s.send(hintCommand{request: request{requestID}, depth: depth, reply: reply}); err != nil {
+		return err
+	}
+
+	return s.await(reply)
+}
+
+// Resign ends the game with the given color resigning
+func (s *Game) Resign(clr color.Color, requestID string) error {
+	reply := make(chan error, 1)
+	if err := s.send(resignCommand{request: request{requestID}, color: clr, reply: reply}); err != nil {
+		return err
+	}
+
+	return s.await(reply)
+}
+
+// Finished returns a channel closed once the game is over
+func (s *Game) Finished() <-chan struct{} {
+	return s.finished
 }
 
-func (s *Game) Terminate() {
-	close(s.done)
-	s.Engine.Close()
+// Terminated returns a channel closed once the session has been terminated
+func (s *Game) Terminated() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// Close is the single teardown of the session: it cancels the session
+// context, which ends the session loop, its searches and the clock heartbeat,
+// and hands the engines back. It is safe to call more than once and from any
+// goroutine, the calls after the first return once teardown is done
+func (s *Game) Close() {
+	s.terminate.Do(s.shutdown)
+}
+
+// shutdown releases the session resources
+func (s *Game) shutdown() {
+	s.cancel()
+	s.Clock.Stop()
+
+	s.engineMu.Lock()
+	if s.simul != nil {
+		s.simul.leave()
+	} else if s.Engine != nil {
+		s.releaseEngine(s.Engine)
+	}
+	if s.OpponentEngine != nil {
+		s.releaseEngine(s.OpponentEngine)
+	}
+	s.engineMu.Unlock()
+
+	s.saveTranscript()
 
 	// Publish game terminated event
 	s.Publisher.Publish(events.Event{
@@ -218,3 +589,46 @@ func (s *Game) Terminate() {
 		},
 	})
 }
+
+// releaseEngine hands an engine back to the pool, which stops its search
+// before another game gets it, or closes it when the session owns it
+func (s *Game) releaseEngine(eng *engine.UCIEngine) {
+	eng.Record(nil)
+
+	if s.enginePool == nil {
+		eng.Close()
+		return
+	}
+
+	s.enginePool.ReturnEngine(eng.ID.String())
+}
+
+// touch records activity on the game
+func (s *Game) touch() {
+	s.lastActivity.Store(time.Now().UnixNano())
+}
+
+// LastActivity returns when a player or engine last acted on the game
+func (s *Game) LastActivity() time.Time {
+	return time.Unix(0, s.lastActivity.Load())
+}
+
+// send queues a command for the session loop
+func (s *Game) send(cmd command) error {
+	select {
+	case s.commands <- cmd:
+		return nil
+	case <-s.ctx.Done():
+		return ErrGameTerminated
+	}
+}
+
+// await waits for the session loop to answer a command
+func (s *Game) await(reply <-chan error) error {
+	select {
+	case err := <-reply:
+		return err
+	case <-s.ctx.Done():
+		return ErrGameTerminated
+	}
+}
@@ -0,0 +1,165 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/engine"
+	"github.com/tecu23/eng-server/pkg/events"
+)
+
+// hintTimeout bounds a hint search, which should only take a moment at hint depths
+const hintTimeout = 10 * time.Second
+
+// HintSettings configures the move hints of human games
+type HintSettings struct {
+	Depth  int // Search depth of a hint, clients may ask for a shallower one
+	Budget int // Hints a player may request per game, 0 disables hints
+}
+
+// hintCommand asks for the best move of the player in the current position
+type hintCommand struct {
+	request
+	depth int
+	reply chan error
+}
+
+// hintResultCommand carries the outcome of a hint search
+type hintResultCommand struct {
+	request
+	move  string
+	depth int
+	eval  *engine.Info
+	err   error
+}
+
+// requestHint starts a shallow engine search for the player to move
+func (s *Game) requestHint(depth int) error {
+	if s.Status() == StatusCompleted {
+		return ErrGameOver
+	}
+
+	if s.Mode == ModeExhibition {
+		return errExhibition
+	}
+
+	if s.Mode == ModeHumanVsHuman {
+		return errPlayerHint
+	}
+
+	if s.simul != nil {
+		return errSimul
+	}
+
+	if s.rated {
+		return errRatedHint
+	}
+
+	if s.searching || s.hinting {
+		return errors.New("engine is busy, try again once it answered")
+	}
+
+	if !s.playerToMove() {
+		return fmt.Errorf("%w, hints are only available on your turn", ErrNotYourTurn)
+	}
+
+	if s.hintsLeft <= 0 {
+		return errors.New("no hints left in this game")
+	}
+
+	if depth <= 0 || depth > s.hints.Depth {
+		depth = s.hints.Depth
+	}
+
+	s.hintsLeft--
+	s.hinting = true
+	go s.hint(s.fen(), depth, s.trace)
+
+	return nil
+}
+
+// hint runs the hint search on the given position and reports back to the session loop
+func (s *Game) hint(fen string, depth int, requestID string) {
+	result := hintResultCommand{request: request{requestID}, depth: depth}
+
+	defer func() {
+		if r := recover(); r != nil {
+			s.Publisher.ReportPanic("hint search", s.ID.String(), r)
+			result.err = fmt.Errorf("hint search failed: %v", r)
+			_ = s.send(result)
+		}
+	}()
+
+	s.Engine.Trace(requestID)
+	result.move, result.err = s.searchHint(fen, depth)
+
+	if info, ok := s.Engine.LastInfo(); ok && result.err == nil {
+		result.eval = &info
+	}
+
+	_ = s.send(result)
+}
+
+// searchHint asks the engine for its best move at a fixed depth
+func (s *Game) searchHint(fen string, depth int) (string, error) {
+	if err := s.Engine.SendCommand(fmt.Sprintf("position fen %s", fen)); err != nil {
+		return "", fmt.Errorf("engine command error: %w", err)
+	}
+
+	if err := s.Engine.SendCommand(fmt.Sprintf("go depth %d", depth)); err != nil {
+		return "", fmt.Errorf("engine command error: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, hintTimeout)
+	defer cancel()
+
+	return s.awaitEngineMove(ctx, s.Engine)
+}
+
+// finishHint publishes the result of a hint search to the player
+func (s *Game) finishHint(result hintResultCommand) {
+	s.hinting = false
+
+	if s.Status() == StatusCompleted {
+		return
+	}
+
+	payload := messages.HintPayload{
+		GameID: s.ID.String(),
+		Depth:  result.depth,
+	}
+
+	var played playedMove
+	err := result.err
+	if err == nil {
+		played, err = s.parseMove(result.move)
+	}
+
+	if err != nil {
+		s.log().Error("hint search failed", zap.Error(err))
+
+		// Failed hints do not count against the budget
+		s.hintsLeft++
+		payload.Error = "could not compute a hint"
+	} else {
+		payload.Move = played.UCI
+		payload.SAN = played.SAN
+		if result.eval != nil {
+			payload.ScoreCP = result.eval.ScoreCP
+			payload.Mate = result.eval.Mate
+		}
+	}
+
+	payload.HintsLeft = s.hintsLeft
+
+	s.Publisher.Publish(events.Event{
+		Type:    events.EventHintReady,
+		GameID:  s.ID.String(),
+		Payload: payload,
+	})
+}
@@ -0,0 +1,48 @@
+package game
+
+import (
+	"github.com/tecu23/eng-server/internal/messages"
+)
+
+// maxMoveIDs is how many client move IDs a game remembers, retries follow the
+// move they repeat within seconds
+const maxMoveIDs = 16
+
+// MoveOutcome is the result of a player move
+type MoveOutcome struct {
+	State    messages.GameStatePayload // State right after the move
+	Replayed bool                      // The move ID was already played, the move was not applied again
+}
+
+// moveReply answers a moveCommand
+type moveReply struct {
+	outcome MoveOutcome
+	err     error
+}
+
+// playedMoveID returns the outcome of a move already played under a client
+// move ID
+func (s *Game) playedMoveID(moveID string) (MoveOutcome, bool) {
+	if moveID == "" {
+		return MoveOutcome{}, false
+	}
+
+	state, ok := s.moveIDs[moveID]
+	return MoveOutcome{State: state, Replayed: true}, ok
+}
+
+// rememberMoveID records the state a move sent with a client move ID led to,
+// forgetting the oldest ID beyond maxMoveIDs. Moves that were refused are not
+// remembered, retrying them cannot play them twice
+func (s *Game) rememberMoveID(moveID string, state messages.GameStatePayload) {
+	if moveID == "" {
+		return
+	}
+
+	if len(s.moveIDOrder) == maxMoveIDs {
+		delete(s.moveIDs, s.moveIDOrder[0])
+		s.moveIDOrder = s.moveIDOrder[1:]
+	}
+	s.moveIDs[moveID] = state
+	s.moveIDOrder = append(s.moveIDOrder, moveID)
+}
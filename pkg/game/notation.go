@@ -0,0 +1,76 @@
+package game
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/corentings/chess/v2"
+)
+
+// uciPattern matches moves in long algebraic (UCI) notation, e.g. e2e4 or e7e8q
+var uciPattern = regexp.MustCompile(`^[a-h][1-8][a-h][1-8][qrbn]?$`)
+
+// playedMove is a move of the game in both supported notations
+type playedMove struct {
+	SAN string
+	UCI string
+
+	// Clock snapshot taken right before the move was played
+	WhiteTimeBefore int64
+	BlackTimeBefore int64
+
+	castle *castleMove // Set for Chess960 castling, which the library cannot play
+	drop   *dropMove   // Set for Crazyhouse drops, which the library cannot play
+}
+
+// parseMove decodes a move in either UCI (e2e4) or SAN (Nf3, O-O, exd8=Q)
+// notation, detecting the format automatically
+func parseMove(pos *chess.Position, notation string) (playedMove, error) {
+	notation = strings.TrimSpace(notation)
+
+	if uciPattern.MatchString(notation) {
+		for _, m := range pos.ValidMoves() {
+			if (chess.UCINotation{}).Encode(pos, &m) == notation {
+				return encodeMove(pos, &m), nil
+			}
+		}
+		return playedMove{}, fmt.Errorf("move %s is not legal", notation)
+	}
+
+	m, err := chess.AlgebraicNotation{}.Decode(pos, notation)
+	if err != nil {
+		return playedMove{}, fmt.Errorf("move %s is not legal", notation)
+	}
+
+	return encodeMove(pos, m), nil
+}
+
+// parseMove decodes a move legal under the variant for the current position
+func (s *Game) parseMove(notation string) (playedMove, error) {
+	return s.rules.parse(s.state, notation)
+}
+
+// pushMove plays a parsed move on the game state
+func (s *Game) pushMove(m playedMove) error {
+	state, err := s.rules.push(s.state, m)
+	if err != nil {
+		return err
+	}
+
+	s.state = state
+	return nil
+}
+
+// fen returns the FEN of the current position, including the variant fields
+func (s *Game) fen() string {
+	return s.rules.fen(s.state)
+}
+
+// encodeMove returns both notations of a move played from the given position
+func encodeMove(pos *chess.Position, m *chess.Move) playedMove {
+	return playedMove{
+		SAN: chess.AlgebraicNotation{}.Encode(pos, m),
+		UCI: chess.UCINotation{}.Encode(pos, m),
+	}
+}
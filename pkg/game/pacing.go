@@ -0,0 +1,76 @@
+package game
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// maxPacingDelay bounds the artificial delay of an engine reply
+	maxPacingDelay = 10 * time.Second
+	// pacingClockShare keeps the engine from spending more than this fraction
+	// of its remaining clock on a paced reply
+	pacingClockShare = 20
+	// complexMoveCount is the number of legal moves from which a position
+	// counts as fully complex
+	complexMoveCount = 40
+)
+
+// Pacing delays the replies of the engine so they do not arrive the instant
+// its search ends. The delay grows with the complexity of the position, the
+// search time already counts towards it and the engine clock keeps running
+type Pacing struct {
+	MinDelay time.Duration // Shortest reply time of a position with a choice of moves
+	MaxDelay time.Duration // Longest reply time, of the most complex positions
+}
+
+// enabled reports whether replies are paced
+func (p Pacing) enabled() bool {
+	return p.MaxDelay > 0
+}
+
+// validate checks the delays of the pacing
+func (p Pacing) validate() error {
+	if p.MinDelay < 0 || p.MaxDelay < p.MinDelay || p.MaxDelay > maxPacingDelay {
+		return fmt.Errorf("pacing delays must satisfy 0 <= min <= max <= %s", maxPacingDelay)
+	}
+	return nil
+}
+
+// delay returns how much longer a reply waits once the engine found its move.
+// A forced move is played at once, otherwise the reply time is scaled by the
+// number of legal moves and by how often the engine changed its mind, with
+// some jitter. The wait never takes more than a small share of the clock left
+func (p Pacing) delay(rng *gameRand, legalMoves int, searched time.Duration, bestMoveChanges int, remaining time.Duration) time.Duration {
+	if !p.enabled() || legalMoves <= 1 {
+		return 0
+	}
+
+	complexity := float64(min(legalMoves, complexMoveCount)) / complexMoveCount
+	complexity = min(complexity+0.25*float64(bestMoveChanges), 1)
+
+	jitter := 0.75 + 0.5*rng.Float64()
+	target := p.MinDelay + time.Duration(float64(p.MaxDelay-p.MinDelay)*complexity*jitter)
+	target = min(target, p.MaxDelay)
+
+	wait := min(target-searched, remaining/pacingClockShare)
+	return max(wait, 0)
+}
+
+// pace waits before an engine reply is played, it returns false when the
+// session terminated in the meantime
+func (s *Game) pace(delay time.Duration) bool {
+	if delay <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-s.ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,98 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/corentings/chess/v2"
+
+	"github.com/tecu23/eng-server/internal/color"
+)
+
+// pgnVariants are the names of the variants in the PGN Variant tag
+var pgnVariants = map[Variant]string{
+	VariantChess960:      "Chess960",
+	VariantCrazyhouse:    "Crazyhouse",
+	VariantKingOfTheHill: "King of the Hill",
+	VariantThreeCheck:    "Three-check",
+}
+
+// playerName returns the name of a player in the PGN tags, their user when known
+func playerName(userID string) string {
+	if userID == "" {
+		return "Player"
+	}
+	return userID
+}
+
+// pgn exports the game in PGN. Games that did not start from the standard
+// position carry it in the FEN tag, with the variant fields of the position
+func (s *Game) pgn() string {
+	white, black := "Engine", "Engine"
+	switch s.Mode {
+	case ModeHumanVsEngine:
+		if s.PlayerColor == color.White {
+			white = "Player"
+		} else {
+			black = "Player"
+		}
+	case ModeHumanVsHuman:
+		white, black = playerName(s.UserOf(color.White)), playerName(s.UserOf(color.Black))
+	}
+
+	result := s.result
+	if result == "" {
+		result = "*"
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[Event \"eng-server game\"]\n")
+	fmt.Fprintf(&sb, "[Site \"eng-server\"]\n")
+	fmt.Fprintf(&sb, "[White \"%s\"]\n", white)
+	fmt.Fprintf(&sb, "[Black \"%s\"]\n", black)
+	fmt.Fprintf(&sb, "[Result \"%s\"]\n", result)
+
+	startFEN := s.startFEN
+	if fen, err := chess.FEN(s.startFEN); err == nil {
+		startFEN = s.startRules.fen(chess.NewGame(fen))
+	}
+
+	if name, ok := pgnVariants[s.Variant]; ok {
+		fmt.Fprintf(&sb, "[Variant \"%s\"]\n", name)
+	}
+
+	if s.Variant == VariantChess960 || s.startFEN != chess.StartingPosition().String() {
+		fmt.Fprintf(&sb, "[SetUp \"1\"]\n")
+		fmt.Fprintf(&sb, "[FEN \"%s\"]\n", startFEN)
+	}
+	sb.WriteString("\n")
+
+	// Move numbers continue from the starting position
+	fields := strings.Fields(startFEN)
+	moveNumber := 1
+	blackToMove := false
+	if len(fields) >= 6 {
+		fmt.Sscan(fields[5], &moveNumber)
+		blackToMove = fields[1] == "b"
+	}
+
+	for i, m := range s.history {
+		switch {
+		case i == 0 && blackToMove:
+			fmt.Fprintf(&sb, "%d... ", moveNumber)
+		case !blackToMove:
+			fmt.Fprintf(&sb, "%d. ", moveNumber)
+		}
+
+		sb.WriteString(m.SAN)
+		sb.WriteString(" ")
+
+		if blackToMove {
+			moveNumber++
+		}
+		blackToMove = !blackToMove
+	}
+
+	sb.WriteString(result)
+	return sb.String()
+}
@@ -0,0 +1,76 @@
+package game
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+
+	"github.com/tecu23/eng-server/internal/color"
+)
+
+// Assistance refused in games between players, there is no engine to search
+// for the player and undoing a move would need the consent of the opponent
+var (
+	errPlayerHint     = errors.New("hints are not available against another player")
+	errPlayerTakeback = errors.New("takebacks are not available against another player")
+)
+
+// toMove reports whether the given color is on turn, owned by the session loop
+func (s *Game) toMove(clr color.Color) bool {
+	return color.Color(s.state.Position().Turn().String()) == clr
+}
+
+// Opponent returns the ID of the connection playing the other color of a
+// game between players, uuid.Nil for games against an engine
+func (s *Game) Opponent() uuid.UUID {
+	return s.opponent.Load().(uuid.UUID)
+}
+
+// OpponentUserID returns the user playing the other color of a game between
+// players, empty for games against an engine and anonymous opponents
+func (s *Game) OpponentUserID() string {
+	return s.opponentUserID
+}
+
+// ColorOf returns the color played over a connection, false when the
+// connection plays no color of the game
+func (s *Game) ColorOf(connectionID uuid.UUID) (color.Color, bool) {
+	switch {
+	case connectionID == uuid.Nil:
+		return "", false
+	case connectionID == s.Owner():
+		return s.PlayerColor, true
+	case s.Mode == ModeHumanVsHuman && connectionID == s.Opponent():
+		return s.PlayerColor.Opp(), true
+	default:
+		return "", false
+	}
+}
+
+// UserOf returns the user playing a color of the game, empty for the engine
+// and anonymous players
+func (s *Game) UserOf(clr color.Color) string {
+	switch {
+	case clr == s.PlayerColor:
+		return s.userID
+	case s.Mode == ModeHumanVsHuman:
+		return s.opponentUserID
+	default:
+		return ""
+	}
+}
+
+// ColorOfUser returns the color a user plays, false when the user plays no
+// color of the game. Anonymous players are never matched
+func (s *Game) ColorOfUser(userID string) (color.Color, bool) {
+	switch {
+	case userID == "":
+		return "", false
+	case userID == s.userID:
+		return s.PlayerColor, true
+	case s.Mode == ModeHumanVsHuman && userID == s.opponentUserID:
+		return s.PlayerColor.Opp(), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,87 @@
+package game
+
+import (
+	"errors"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/color"
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/events"
+)
+
+// premoveCommand queues a move while the opponent is thinking
+type premoveCommand struct {
+	request
+	color color.Color // Side the premove is played for
+	move  string
+	reply chan error
+}
+
+// queuePremove stores a move to be played once the opponent moved,
+// replacing any previously queued premove
+func (s *Game) queuePremove(clr color.Color, move string) error {
+	if s.Status() == StatusCompleted {
+		return ErrGameOver
+	}
+
+	if s.Mode == ModeExhibition {
+		return errExhibition
+	}
+
+	move = strings.TrimSpace(move)
+	if move == "" {
+		s.premove = ""
+		return nil
+	}
+
+	// The engine thinks while its search runs, a player while it is their turn
+	waiting := s.searching
+	if s.Mode == ModeHumanVsHuman {
+		waiting = !s.toMove(clr)
+	}
+	if !waiting {
+		return errors.New("it is your turn, send the move instead")
+	}
+
+	s.premove = move
+	s.log().Debug("premove queued", zap.String("move", move))
+
+	return nil
+}
+
+// playPremove applies the queued premove right after the opponent moved,
+// discarding it if it is not legal in the new position
+func (s *Game) playPremove() {
+	if s.premove == "" || s.Status() == StatusCompleted {
+		s.premove = ""
+		return
+	}
+
+	move := s.premove
+	s.premove = ""
+
+	if _, err := s.applyMove(move, 0); err != nil {
+		s.log().Info("premove discarded", zap.String("move", move), zap.Error(err))
+
+		s.Publisher.Publish(events.Event{
+			Type:   events.EventPremoveDiscarded,
+			GameID: s.ID.String(),
+			Payload: messages.PremoveDiscardedPayload{
+				GameID: s.ID.String(),
+				Move:   move,
+				Reason: err.Error(),
+			},
+		})
+		return
+	}
+
+	if s.Status() == StatusCompleted || s.Mode == ModeHumanVsHuman {
+		return
+	}
+
+	if err := s.startSearch(); err != nil {
+		s.log().Error("could not start engine search after premove", zap.Error(err))
+	}
+}
@@ -0,0 +1,62 @@
+package game
+
+import (
+	"github.com/tecu23/eng-server/pkg/engine"
+)
+
+// randomMovePolicy makes a weak engine feel more human: now and then it plays
+// one of the other candidate lines of a MultiPV search instead of the best move
+type randomMovePolicy struct {
+	Chance  float64 // Share of the moves where a weaker candidate may be played, 0 disables the policy
+	Lines   int     // Candidate lines searched with MultiPV, the best one included
+	MaxLoss int     // Largest loss in centipawns compared to the best line a candidate may have
+}
+
+// pick returns a weaker candidate to play instead of the best move, the
+// smaller its loss the likelier a candidate is picked. Mates are never thrown
+// away or walked into, and neither are lines more than MaxLoss worse
+func (p randomMovePolicy) pick(rng *gameRand, bestMove string, lines []engine.Info) (string, bool) {
+	if p.Chance <= 0 || len(lines) < 2 || rng.Float64() >= p.Chance {
+		return "", false
+	}
+
+	best := lines[0]
+	if best.MultiPV != 1 || best.Mate != 0 {
+		return "", false
+	}
+
+	type candidate struct {
+		move   string
+		weight int
+	}
+
+	var candidates []candidate
+	total := 0
+	for _, line := range lines[1:] {
+		if line.Mate != 0 || len(line.PV) == 0 || line.PV[0] == bestMove {
+			continue
+		}
+
+		loss := best.ScoreCP - line.ScoreCP
+		if loss > p.MaxLoss {
+			continue
+		}
+
+		weight := p.MaxLoss - max(loss, 0) + 1
+		candidates = append(candidates, candidate{move: line.PV[0], weight: weight})
+		total += weight
+	}
+
+	if len(candidates) == 0 {
+		return "", false
+	}
+
+	r := rng.Intn(total)
+	for _, c := range candidates {
+		r -= c.weight
+		if r < 0 {
+			return c.move, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,80 @@
+package game
+
+import (
+	"errors"
+	"math"
+
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/color"
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/rating"
+)
+
+// Assistance refused in rated games, which must be the player's own play
+var (
+	errRatedTakeback = errors.New("takebacks are not allowed in rated games")
+	errRatedHint     = errors.New("hints are not available in rated games")
+)
+
+// updateRating rates the players of a finished rated game, against the
+// engine or each other, and returns the change of the player and of the
+// opponent between players, nil for unrated games
+func (s *Game) updateRating(result string) (*messages.RatingChange, *messages.RatingChange) {
+	if !s.rated || s.ratings == nil {
+		return nil, nil
+	}
+
+	switch s.Mode {
+	case ModeHumanVsEngine:
+		return s.rate(s.userID, s.PlayerColor, s.engineRating, result), nil
+	case ModeHumanVsHuman:
+		// Both players are rated against the rating the other had before the game
+		player, opponent := s.currentRating(s.userID), s.currentRating(s.opponentUserID)
+		return s.rate(s.userID, s.PlayerColor, opponent, result),
+			s.rate(s.opponentUserID, s.PlayerColor.Opp(), player, result)
+	default:
+		return nil, nil
+	}
+}
+
+// currentRating returns the rating of a user, the default one for new users
+func (s *Game) currentRating(userID string) rating.Rating {
+	current, err := s.ratings.GetRating(userID)
+	if err != nil {
+		if !errors.Is(err, rating.ErrNotFound) {
+			s.log().Error("could not read player rating", zap.String("user_id", userID), zap.Error(err))
+		}
+		return rating.Default()
+	}
+	return current
+}
+
+// rate updates the rating of the user playing a color with the result of
+// the game against an opponent of the given rating
+func (s *Game) rate(userID string, clr color.Color, opponent rating.Rating, result string) *messages.RatingChange {
+	score := 0.5
+	switch {
+	case result == "1-0" && clr == color.White, result == "0-1" && clr == color.Black:
+		score = 1
+	case result == "1-0" || result == "0-1":
+		score = 0
+	}
+
+	var before rating.Rating
+	after, err := s.ratings.UpdateRating(userID, func(current rating.Rating) rating.Rating {
+		before = current
+		return current.Update(rating.Result{Opponent: opponent, Score: score})
+	})
+	if err != nil {
+		s.log().Error("could not update player rating", zap.String("user_id", userID), zap.Error(err))
+		return nil
+	}
+
+	return &messages.RatingChange{
+		UserID:    userID,
+		Rating:    int(math.Round(after.Rating)),
+		Deviation: int(math.Round(after.Deviation)),
+		Delta:     int(math.Round(after.Rating - before.Rating)),
+	}
+}
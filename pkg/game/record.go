@@ -0,0 +1,139 @@
+package game
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/engine"
+)
+
+// Archive keeps the records of finished games
+type Archive interface {
+	SaveRecord(record messages.GameRecord) error
+}
+
+// AnalysisQueue accepts finished standard chess games for post-game analysis
+type AnalysisQueue interface {
+	Queue(gameID, startFEN string, moves []string) error
+}
+
+// Evaluator evaluates positions of running games for the eval bar
+type Evaluator interface {
+	Evaluate(gameID, fen string, ply int)
+}
+
+// Telemetry keeps the search statistics of the moves engines played and the
+// outcome of the games they finished
+type Telemetry interface {
+	SaveTelemetry(telemetry messages.MoveTelemetry) error
+	SaveEngineResult(result messages.EngineResult) error
+}
+
+// queueAnalysis hands the finished game to the analysis queue, when the
+// player asked for it or the game is screened for fair play
+func (s *Game) queueAnalysis() {
+	if s.analysis == nil && !s.reviewed() {
+		return
+	}
+
+	if s.Variant != VariantStandard {
+		s.log().Info("skipping analysis of variant game", zap.String("variant", string(s.Variant)))
+		return
+	}
+
+	moves := make([]string, 0, len(s.history))
+	for _, m := range s.history {
+		moves = append(moves, m.UCI)
+	}
+
+	if s.reviewed() {
+		if err := s.fairPlay.QueueReview(s.fairPlaySubjects(), s.startFEN, moves, s.analysis != nil); err != nil {
+			s.log().Error("could not queue fair play review", zap.Error(err))
+		}
+		return
+	}
+
+	if err := s.analysis.Queue(s.ID.String(), s.startFEN, moves); err != nil {
+		s.log().Error("could not queue game analysis", zap.Error(err))
+	}
+}
+
+// archive stores the record of the finished game, when an archive is configured
+func (s *Game) archive(reason, result string) {
+	if s.records == nil {
+		return
+	}
+
+	record := messages.GameRecord{
+		GameID:        s.ID.String(),
+		Mode:          string(s.Mode),
+		Variant:       string(s.Variant),
+		UserID:        s.userID,
+		TimeControl:   s.timeControl.String(),
+		Rated:         s.rated,
+		Result:        result,
+		Reason:        reason,
+		StartedAt:     s.createdAt,
+		EndedAt:       time.Now(),
+		PGN:           s.pgn(),
+		Seed:          s.seed,
+		Deterministic: s.deterministic,
+	}
+
+	switch s.Mode {
+	case ModeHumanVsEngine:
+		record.PlayerColor = s.PlayerColor
+	case ModeHumanVsHuman:
+		record.PlayerColor = s.PlayerColor
+		record.OpponentID = s.opponentUserID
+	}
+
+	for _, eng := range []*engine.UCIEngine{s.Engine, s.OpponentEngine} {
+		if eng != nil {
+			record.Engines = append(record.Engines, engineName(eng))
+		}
+	}
+
+	if err := s.records.SaveRecord(record); err != nil {
+		s.log().Error("could not archive game", zap.Error(err))
+	}
+}
+
+// engineName returns the name an engine reported, or a generic one
+func engineName(eng *engine.UCIEngine) string {
+	if name := eng.Name(); name != "" {
+		return name
+	}
+	return "engine"
+}
+
+// recordTelemetry stores how the engine searched the move it just played
+func (s *Game) recordTelemetry(result engineResultCommand, played playedMove) {
+	if s.telemetry == nil || result.stats == nil {
+		return
+	}
+
+	telemetry := messages.MoveTelemetry{
+		GameID:     s.ID.String(),
+		Engine:     engineName(result.engine),
+		Config:     result.engine.Config(),
+		Ply:        len(s.history),
+		Move:       played.UCI,
+		Depth:      result.stats.Depth,
+		Nodes:      result.stats.Nodes,
+		TimeMs:     result.stats.Elapsed.Milliseconds(),
+		LateChange: result.stats.LateChange(),
+		Time:       time.Now(),
+	}
+
+	if result.eval != nil {
+		eval := result.eval.Centipawns()
+		telemetry.Eval = &eval
+	}
+
+	if err := s.telemetry.SaveTelemetry(telemetry); err != nil {
+		s.log().Error("could not save move telemetry", zap.Error(err))
+	}
+}
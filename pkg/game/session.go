@@ -0,0 +1,822 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/color"
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/engine"
+	"github.com/tecu23/eng-server/pkg/events"
+	"github.com/tecu23/eng-server/pkg/tablebase"
+)
+
+// command is a message consumed by the session loop
+type command interface{}
+
+// moveCommand applies a player move
+type moveCommand struct {
+	request
+	color  color.Color // Side the move is played for
+	move   string
+	moveID string        // Client ID of the move, empty when the client sent none
+	lag    time.Duration // Network lag measured on the player's connection
+	reply  chan moveReply
+}
+
+// resignCommand ends the game by resignation
+type resignCommand struct {
+	request
+	color color.Color
+	reply chan error
+}
+
+// stateCommand queries a snapshot of the game state
+type stateCommand struct {
+	reply chan messages.GameStatePayload
+}
+
+// engineRequestCommand starts an engine search for the side to move
+type engineRequestCommand struct {
+	request
+	reply chan error
+}
+
+// engineResultCommand carries the outcome of an engine search
+type engineResultCommand struct {
+	request // Request that started the search
+	id      int
+	move    string
+	turn    chess.Color
+	forfeit bool
+	crashed bool // The engine process exited during the search
+	late    bool // The engine did not answer before its deadline, a fallback applied
+	err     error
+
+	adjudication *tablebase.Result // Tablebase verdict for the side to move, ends the game instead of a move
+	eval         *engine.Info      // Last search update of the engine, nil when it reported none
+
+	engine *engine.UCIEngine   // Engine that searched the move, nil for book moves
+	stats  *engine.SearchStats // How the engine reached its move, nil when it did not answer
+}
+
+// tickCommand carries a periodic clock update
+type tickCommand struct {
+	tick ClockTick
+}
+
+// timeUpCommand signals that a player ran out of time
+type timeUpCommand struct {
+	color color.Color
+}
+
+// searchRequest is the snapshot of the game an engine search works on
+type searchRequest struct {
+	id          int
+	requestID   string      // Request that started the search, empty for internal searches
+	logger      *zap.Logger // Session logger tagged with the request
+	engine      *engine.UCIEngine
+	fen         string
+	whiteTime   int64
+	blackTime   int64
+	movesPlayed int
+	turn        chess.Color
+	legalMoves  []string
+	goCommand   string // Starts the search within the limits of the time management
+	fallback    EngineFallback
+	tablebase   *tablebase.Tablebase // Probed before searching, nil disables adjudication
+	randomMoves randomMovePolicy     // Weaker candidates played instead of the best move now and then
+	pacing      Pacing               // Delays the reply once the engine found its move
+	simul       *Simul               // Schedules the search on the engine shared by a simul, nil outside one
+}
+
+// run is the session loop, the only goroutine allowed to touch game state
+func (s *Game) run() {
+	// The state may be inconsistent after a panic, so the session ends and
+	// waiting callers get ErrGameTerminated instead of hanging
+	defer func() {
+		if r := recover(); r != nil {
+			s.Publisher.ReportPanic("game session", s.ID.String(), r)
+			s.Close()
+		}
+	}()
+
+	tickChan := s.Clock.GetTickChannel()
+	timeupChan := s.Clock.GetTimeupChannel()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			// Cancelled from outside, e.g. by the server shutting down
+			s.Close()
+			return
+		case cmd := <-s.commands:
+			s.handle(cmd)
+		case tick := <-tickChan:
+			s.handle(tickCommand{tick: tick})
+		case clr := <-timeupChan:
+			s.handle(timeUpCommand{color: clr})
+		}
+	}
+}
+
+// handle dispatches a command to its handler
+func (s *Game) handle(cmd command) {
+	// Clock ticks and state queries, e.g. from spectators, keep no game alive
+	switch cmd.(type) {
+	case tickCommand, stateCommand:
+	default:
+		s.touch()
+	}
+
+	if c, ok := cmd.(traced); ok {
+		s.trace = c.requestID()
+		defer func() { s.trace = "" }()
+	}
+
+	switch c := cmd.(type) {
+	case moveCommand:
+		outcome, err := s.playerMove(c.color, c.move, c.moveID, c.lag)
+		c.reply <- moveReply{outcome: outcome, err: err}
+	case premoveCommand:
+		c.reply <- s.queuePremove(c.color, c.move)
+	case resignCommand:
+		c.reply <- s.resign(c.color)
+	case claimVictoryCommand:
+		c.reply <- s.claimVictory(c.color)
+	case takebackCommand:
+		c.reply <- s.takeback()
+	case stateCommand:
+		c.reply <- s.snapshot()
+	case checkpointCommand:
+		c.reply <- s.checkpoint()
+	case resumeCommand:
+		c.reply <- s.resume(c.owner)
+	case engineRequestCommand:
+		c.reply <- s.startSearch()
+	case engineResultCommand:
+		s.finishSearch(c)
+	case engineReplacedCommand:
+		s.installEngine(c)
+	case hintCommand:
+		c.reply <- s.requestHint(c.depth)
+	case hintResultCommand:
+		s.finishHint(c)
+	case tickCommand:
+		// Heartbeats buffered before the clock stopped are stale once the
+		// game is over or paused
+		if s.Status() == StatusActive {
+			s.publishTick(c.tick)
+		}
+	case timeUpCommand:
+		s.timeUp(c.color)
+	default:
+		s.log().Error("unknown session command", zap.String("type", fmt.Sprintf("%T", cmd)))
+	}
+}
+
+// playerMove applies a move sent by the player of a color on their turn,
+// unless its move ID was already played
+func (s *Game) playerMove(clr color.Color, move, moveID string, lag time.Duration) (MoveOutcome, error) {
+	if s.Mode == ModeExhibition {
+		return MoveOutcome{}, errExhibition
+	}
+
+	// Checked first, the retry of a move usually arrives on the engine's turn
+	if outcome, ok := s.playedMoveID(moveID); ok {
+		s.log().Info("ignoring replayed move", zap.String("move_id", moveID))
+		return outcome, nil
+	}
+
+	if s.searching {
+		return MoveOutcome{}, fmt.Errorf("%w, the engine is thinking, send a premove instead", ErrNotYourTurn)
+	}
+
+	if s.hinting {
+		return MoveOutcome{}, errors.New("hint search in progress")
+	}
+
+	if !s.toMove(clr) {
+		return MoveOutcome{}, ErrNotYourTurn
+	}
+
+	if _, err := s.applyMove(move, min(lag, s.lagCompensation)); err != nil {
+		return MoveOutcome{}, err
+	}
+
+	outcome := MoveOutcome{State: s.snapshot()}
+	s.rememberMoveID(moveID, outcome.State)
+
+	// Between players the opponent may have queued their reply already
+	if s.Mode == ModeHumanVsHuman {
+		s.playPremove()
+	}
+	return outcome, nil
+}
+
+// applyMove validates and records a move in UCI or SAN notation, then switches
+// the clock after crediting the lag allowance of the move
+func (s *Game) applyMove(move string, lagCredit time.Duration) (playedMove, error) {
+	if s.Status() == StatusCompleted {
+		return playedMove{}, ErrGameOver
+	}
+	if s.Status() == StatusPaused {
+		return playedMove{}, errPaused
+	}
+
+	played, err := s.parseMove(move)
+	if err != nil {
+		return playedMove{}, fmt.Errorf("%w %s: %w", ErrIllegalMove, move, err)
+	}
+
+	times := s.Clock.GetRemainingTime()
+	played.WhiteTimeBefore = times.White
+	played.BlackTimeBefore = times.Black
+
+	// A move made after the flag fell does not count, the game is lost on time
+	s.Clock.Compensate(lagCredit)
+	if s.Clock.Flagged() {
+		mover := color.Color(s.state.Position().Turn().String())
+		s.timeUp(mover)
+		return playedMove{}, fmt.Errorf("%w, %s ran out of time before moving", ErrGameOver, colorName(mover))
+	}
+
+	if err := s.pushMove(played); err != nil {
+		return playedMove{}, fmt.Errorf("%w %s: %w", ErrIllegalMove, move, err)
+	}
+	s.Clock.Switch()
+	s.publishClock()
+
+	s.history = append(s.history, played)
+
+	s.log().Info(
+		"processed move",
+		zap.String("move", played.UCI),
+		zap.String("san", played.SAN),
+		zap.String("new_turn", s.state.Position().Turn().String()),
+	)
+
+	// Publish move processed event
+	s.Publisher.Publish(events.Event{
+		Type:    events.EventMoveProcessed,
+		GameID:  s.ID.String(),
+		Payload: s.snapshot(),
+	})
+
+	if out, over := s.rules.outcome(s.state); over {
+		s.complete(out.reason, out.result, out.description)
+	} else if s.evalBar != nil && s.Variant == VariantStandard {
+		s.evalBar.Evaluate(s.ID.String(), s.fen(), len(s.history))
+	}
+
+	return played, nil
+}
+
+// snapshot builds the full state of the game
+func (s *Game) snapshot() messages.GameStatePayload {
+	times := s.Clock.GetRemainingTime()
+
+	moves := make([]string, 0, len(s.history))
+	for _, m := range s.history {
+		moves = append(moves, m.SAN)
+	}
+
+	var lastMove playedMove
+	if len(s.history) > 0 {
+		lastMove = s.history[len(s.history)-1]
+	}
+
+	result := s.result
+	if result == "" {
+		result = "*"
+	}
+
+	halfmove, fullmove := s.moveCounters()
+
+	return messages.GameStatePayload{
+		GameID:      s.ID.String(),
+		BoardFEN:    s.fen(),
+		Moves:       moves,
+		LastMove:    lastMove.SAN,
+		LastMoveUCI: lastMove.UCI,
+		WhiteTime:   times.White,
+		BlackTime:   times.Black,
+		CurrentTurn: color.Color(s.state.Position().Turn().String()),
+		Status:      string(s.Status()),
+		Result:      result,
+		IsCheckmate: s.result != "" && s.state.Method() == chess.Checkmate,
+		IsDraw:      s.result == "1/2-1/2",
+
+		HalfmoveClock:      halfmove,
+		FullmoveNumber:     fullmove,
+		RepetitionCount:    s.repetitions(),
+		MovesToTimeControl: s.movesToControl(),
+	}
+}
+
+// resign ends the game in favour of the opponent of the given color
+func (s *Game) resign(clr color.Color) error {
+	if s.Status() == StatusCompleted {
+		return ErrGameOver
+	}
+
+	if s.Mode == ModeExhibition {
+		return errExhibition
+	}
+
+	result := "1-0"
+	if clr == color.White {
+		result = "0-1"
+	}
+
+	s.complete("resignation", result, fmt.Sprintf("%s resigned", colorName(clr)))
+	return nil
+}
+
+// timeUp ends the game with the given color losing on time
+func (s *Game) timeUp(clr color.Color) {
+	// The clock reports an expired time when it is stopped, also by the move
+	// or resignation that just ended the game
+	if s.Status() != StatusActive {
+		s.log().Debug("ignoring time up of an inactive game", zap.String("color", string(clr)), zap.String("status", string(s.Status())))
+		return
+	}
+
+	s.Publisher.Publish(events.Event{
+		Type:   events.EventTimeUp,
+		GameID: s.ID.String(),
+		Payload: messages.TimeupPayload{
+			Color: string(clr),
+		},
+	})
+
+	s.log().Info("player time expired", zap.String("color", string(clr)))
+
+	if !s.canCheckmate(clr.Opp()) {
+		s.complete(
+			"timeout_vs_insufficient_material",
+			"1/2-1/2",
+			fmt.Sprintf("%s ran out of time, but %s cannot checkmate", colorName(clr), colorName(clr.Opp())),
+		)
+		return
+	}
+
+	result := "1-0"
+	if clr == color.White {
+		result = "0-1"
+	}
+
+	s.complete("timeout", result, fmt.Sprintf("%s ran out of time", colorName(clr)))
+}
+
+// complete marks the game as finished and publishes the result
+func (s *Game) complete(reason, result, description string) {
+	// finished is closed once, a second outcome would also be archived twice
+	if s.Status() == StatusCompleted {
+		return
+	}
+
+	s.result = result
+	s.setStatus(StatusCompleted)
+	s.Clock.Stop()
+	s.publishClock()
+	close(s.finished)
+
+	s.archive(reason, result)
+	s.recordEngineResults(result)
+	s.queueAnalysis()
+
+	playerRating, opponentRating := s.updateRating(result)
+
+	s.Publisher.Publish(events.Event{
+		Type:   events.EventGameOver,
+		GameID: s.ID.String(),
+		Payload: messages.GameOverPayload{
+			GameID:         s.ID.String(),
+			Reason:         reason,
+			Result:         result,
+			Description:    description,
+			PGN:            s.pgn(),
+			Rating:         playerRating,
+			OpponentRating: opponentRating,
+			Certificate:    s.certify(reason, result),
+		},
+	})
+
+	s.log().Info("game over", zap.String("reason", reason), zap.String("result", result))
+}
+
+// publishClock sends the authoritative clock state after the turn changed
+func (s *Game) publishClock() {
+	s.publishTick(s.Clock.Sync())
+}
+
+// publishTick forwards a clock tick to subscribers
+func (s *Game) publishTick(tick ClockTick) {
+	s.Publisher.Publish(events.Event{
+		Type:   events.EventClockUpdated,
+		GameID: s.ID.String(),
+		Payload: messages.ClockUpdatePayload{
+			GameID:      s.ID.String(),
+			WhiteTime:   tick.White,
+			BlackTime:   tick.Black,
+			ActiveColor: string(tick.ActiveColor),
+			Running:     tick.Running,
+			ServerTime:  tick.At.UnixMilli(),
+
+			LagCompensation: tick.Compensation.Milliseconds(),
+		},
+	})
+}
+
+// startSearch snapshots the game and hands it to an engine search goroutine
+func (s *Game) startSearch() error {
+	if s.Status() == StatusCompleted {
+		return ErrGameOver
+	}
+
+	if s.Status() == StatusPaused {
+		return errPaused
+	}
+
+	if s.searching {
+		return errors.New("engine is already thinking")
+	}
+
+	if s.hinting {
+		return errors.New("hint search in progress")
+	}
+
+	if s.Mode == ModeHumanVsHuman {
+		return errors.New("games between players have no engine")
+	}
+
+	// The engine only plays its own color against a player
+	if s.Mode == ModeHumanVsEngine && s.playerToMove() {
+		return errors.New("the engine is not to move")
+	}
+
+	pos := s.state.Position()
+
+	times := s.Clock.GetRemainingTime()
+
+	// Play straight from the opening book while in book
+	if move, ok := s.bookMove(); ok {
+		s.searchID++
+		s.searching = true
+		result := engineResultCommand{request: request{s.trace}, id: s.searchID, move: move, turn: pos.Turn()}
+
+		remaining := times.White
+		if pos.Turn() == chess.Black {
+			remaining = times.Black
+		}
+
+		// A paced book move is played like a search result once the delay is over
+		if delay := s.pacing.delay(s.rng, len(s.rules.legalMoves(s.state)), 0, 0, time.Duration(remaining)*time.Millisecond); delay > 0 {
+			go func() {
+				if s.pace(delay) {
+					_ = s.send(result)
+				}
+			}()
+			return nil
+		}
+
+		s.finishSearch(result)
+		return nil
+	}
+
+	legalMoves := s.rules.legalMoves(s.state)
+
+	s.searchID++
+
+	req := searchRequest{
+		id:          s.searchID,
+		requestID:   s.trace,
+		logger:      s.log(),
+		engine:      s.engineFor(pos.Turn()),
+		fen:         s.fen(),
+		whiteTime:   times.White,
+		blackTime:   times.Black,
+		movesPlayed: len(s.history),
+		turn:        pos.Turn(),
+		legalMoves:  legalMoves,
+		fallback:    s.engineFallback,
+		randomMoves: s.randomMoves,
+		pacing:      s.pacing,
+		simul:       s.simul,
+	}
+	req.goCommand = s.timeManager.goCommand(req)
+
+	if s.Mode == ModeExhibition {
+		req.tablebase = s.tablebase
+	}
+
+	s.searching = true
+	go s.search(req)
+
+	return nil
+}
+
+// finishSearch applies the result of an engine search
+func (s *Game) finishSearch(result engineResultCommand) {
+	if result.id != s.searchID {
+		s.log().Debug("dropping result of an abandoned search", zap.String("move", result.move))
+		return
+	}
+
+	if result.crashed {
+		s.recoverEngine(result)
+		return
+	}
+
+	s.searching = false
+
+	if result.late {
+		s.failuresOf(color.Color(result.turn.String())).timeouts++
+	}
+
+	if result.err != nil {
+		s.log().Error("engine search failed", zap.Error(result.err))
+		return
+	}
+
+	if result.adjudication != nil {
+		s.adjudicateTablebase(result.turn, *result.adjudication)
+		return
+	}
+
+	if result.forfeit {
+		s.log().Info("engine forfeited on time", zap.String("color", result.turn.String()))
+		s.timeUp(color.Color(result.turn.String()))
+		return
+	}
+
+	// Process the move as if the engine made it.
+	played, err := s.applyMove(result.move, 0)
+	if err != nil {
+		s.log().Error("failed to process engine move", zap.Error(err))
+		return
+	}
+
+	s.engineRestarts = 0
+	s.recordTelemetry(result, played)
+
+	// Publish engine moved event
+	s.Publisher.Publish(events.Event{
+		Type:   events.EventEngineMoved,
+		GameID: s.ID.String(),
+		Payload: messages.EngineMovePayload{
+			Move:  played.UCI,
+			SAN:   played.SAN,
+			Color: color.Color(result.turn.String()),
+		},
+	})
+
+	s.log().Info("engine move processed", zap.String("move", result.move))
+
+	if s.Mode == ModeExhibition {
+		if result.eval != nil {
+			s.publishEval(color.Color(result.turn.String()), *result.eval, true)
+			s.adjudicateEval(result.turn, *result.eval)
+		}
+
+		if s.Status() != StatusCompleted {
+			if err := s.startSearch(); err != nil {
+				s.log().Error("could not continue exhibition game", zap.Error(err))
+			}
+		}
+		return
+	}
+
+	s.playPremove()
+}
+
+// bookMove returns a legal book move for the current position, if any
+func (s *Game) bookMove() (string, bool) {
+	// Polyglot books only cover standard chess
+	if s.book == nil || s.Variant != VariantStandard {
+		return "", false
+	}
+
+	move, ok := s.book.Move(s.state.FEN(), len(s.history), s.bookOptions, s.rng)
+	if !ok {
+		return "", false
+	}
+
+	if _, err := parseMove(s.state.Position(), move); err != nil {
+		s.log().Warn("ignoring illegal book move", zap.String("move", move), zap.Error(err))
+		return "", false
+	}
+
+	s.log().Debug("playing book move", zap.String("move", move))
+	return move, true
+}
+
+// engineFor returns the engine playing the given color
+func (s *Game) engineFor(turn chess.Color) *engine.UCIEngine {
+	if s.Mode == ModeExhibition && turn == chess.Black {
+		return s.OpponentEngine
+	}
+	return s.Engine
+}
+
+// forwardInfo publishes the engine's search updates until the session terminates
+func (s *Game) forwardInfo(eng *engine.UCIEngine, clr color.Color) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.Publisher.ReportPanic("engine info", s.ID.String(), r)
+		}
+	}()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case info := <-eng.InfoChan:
+			// The other candidates of a MultiPV search are not the engine's evaluation
+			if info.MultiPV != 1 {
+				continue
+			}
+
+			s.publishEval(clr, info, false)
+		}
+	}
+}
+
+// publishEval publishes an evaluation of a playing engine, final for the one
+// its move was played on
+func (s *Game) publishEval(clr color.Color, info engine.Info, final bool) {
+	s.Publisher.Publish(events.Event{
+		Type:   events.EventEvalUpdated,
+		GameID: s.ID.String(),
+		Payload: messages.EvalPayload{
+			GameID:  s.ID.String(),
+			Color:   clr,
+			Depth:   info.Depth,
+			ScoreCP: info.ScoreCP,
+			Mate:    info.Mate,
+			PV:      info.PV,
+			Source:  "engine",
+			Final:   final,
+		},
+	})
+}
+
+// search runs the engine on the given snapshot and reports back to the session loop
+func (s *Game) search(req searchRequest) {
+	result := engineResultCommand{request: request{req.requestID}, id: req.id, turn: req.turn}
+
+	// Report the failure so the session does not wait for the move forever
+	defer func() {
+		if r := recover(); r != nil {
+			s.Publisher.ReportPanic("engine search", s.ID.String(), r)
+			result.err = fmt.Errorf("engine search failed: %v", r)
+			_ = s.send(result)
+		}
+	}()
+
+	if tb, ok := s.probeTablebase(req); ok {
+		result.adjudication = &tb
+		_ = s.send(result)
+		return
+	}
+
+	// A simul board waits for the shared engine, which it holds until the
+	// outcome of its search is read
+	var turn *simulTurn
+	if req.simul != nil {
+		if turn = s.awaitSimul(&req); turn == nil {
+			return
+		}
+		defer turn.release(true)
+	}
+
+	result.move, result.late, result.err = s.searchMove(req)
+	result.forfeit = result.late && result.err == nil && result.move == ""
+	result.crashed = result.err != nil && (errors.Is(result.err, engine.ErrEngineCrashed) || req.engine.Crashed())
+	if info, ok := req.engine.LastInfo(); ok && result.err == nil && !result.forfeit {
+		result.eval = &info
+	}
+
+	stats := req.engine.LastSearch()
+	lines := req.engine.SearchLines()
+	if turn != nil {
+		turn.release(!stats.Completed)
+	}
+
+	// A fallback move was not searched by the engine, so it has no telemetry
+	if stats.Completed && result.err == nil && !result.forfeit {
+		result.engine = req.engine
+		result.stats = &stats
+
+		if move, ok := req.randomMoves.pick(s.rng, result.move, lines); ok {
+			req.logger.Debug("playing a weaker candidate move", zap.String("best_move", result.move), zap.String("move", move))
+			result.move = move
+		}
+
+		engineTime := req.whiteTime
+		if req.turn == chess.Black {
+			engineTime = req.blackTime
+		}
+		remaining := time.Duration(engineTime)*time.Millisecond - stats.Elapsed
+
+		if !s.pace(req.pacing.delay(s.rng, len(req.legalMoves), stats.Elapsed, stats.BestMoveChanges, remaining)) {
+			return
+		}
+	}
+
+	// The session may have terminated while the engine was thinking
+	_ = s.send(result)
+}
+
+// searchMove asks the engine for a move, applying the fallback when it does
+// not answer in time. It reports whether the engine was late, the move is
+// empty when the engine forfeits
+func (s *Game) searchMove(req searchRequest) (string, bool, error) {
+	req.engine.Trace(req.requestID)
+
+	command := fmt.Sprintf("position fen %s", req.fen)
+	if err := req.engine.SendCommand(command); err != nil {
+		return "", false, fmt.Errorf("engine command error: %w", err)
+	}
+
+	if err := req.engine.SendCommand(req.goCommand); err != nil {
+		return "", false, fmt.Errorf("engine command error: %w", err)
+	}
+
+	engineTime := req.whiteTime
+	if req.turn == chess.Black {
+		engineTime = req.blackTime
+	}
+
+	// Wait for the best move from the engine, at most until its clock runs out.
+	ctx, cancel := context.WithTimeout(s.ctx, time.Duration(engineTime)*time.Millisecond)
+	defer cancel()
+
+	bestMove, err := s.awaitEngineMove(ctx, req.engine)
+	if err == nil {
+		return bestMove, false, nil
+	}
+
+	// A crashed engine is replaced rather than forfeiting
+	if errors.Is(err, engine.ErrEngineCrashed) {
+		return "", false, err
+	}
+
+	req.logger.Warn("engine did not answer before its deadline", zap.Error(err))
+
+	switch req.fallback {
+	case FallbackRandomMove:
+		if len(req.legalMoves) == 0 {
+			return "", true, errors.New("no legal moves available")
+		}
+
+		move := req.legalMoves[s.rng.Intn(len(req.legalMoves))]
+		req.logger.Info("playing random move for engine", zap.String("move", move))
+		return move, true, nil
+
+	default:
+		return "", true, nil
+	}
+}
+
+// awaitEngineMove waits for the engine's best move, sending stop once the
+// context expires and giving the engine a short grace period to answer it.
+// The context derives from the session, so waiting ends with the session,
+// which then hands the engine back itself
+func (s *Game) awaitEngineMove(ctx context.Context, eng *engine.UCIEngine) (string, error) {
+	bestMove, err := eng.WaitBestMove(ctx)
+	if err == nil {
+		return bestMove, nil
+	}
+
+	if s.ctx.Err() != nil {
+		return "", ErrGameTerminated
+	}
+
+	if errors.Is(err, engine.ErrEngineCrashed) {
+		return "", err
+	}
+
+	if err := eng.Stop(); err != nil {
+		return "", fmt.Errorf("error sending stop: %w", err)
+	}
+
+	graceCtx, cancel := context.WithTimeout(s.ctx, stopGracePeriod)
+	defer cancel()
+
+	return eng.WaitBestMove(graceCtx)
+}
+
+// colorName returns the human readable name of a color
+func colorName(clr color.Color) string {
+	if clr == color.White {
+		return "White"
+	}
+	return "Black"
+}
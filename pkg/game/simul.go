@@ -0,0 +1,207 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/pkg/engine"
+)
+
+// Simul limits
+const (
+	MaxSimulBoards = 20
+	// simulSlice is the longest search a board gets before the engine moves on
+	// to the next board
+	simulSlice = time.Second
+	// simulMinSlice keeps a search meaningful on a board short of time
+	simulMinSlice = 20 * time.Millisecond
+	// simulMovesToGo is how many moves the remaining time of a board is split into
+	simulMovesToGo = 30
+)
+
+// errSimul is returned for player commands that need the engine to themselves
+var errSimul = errors.New("not available in a simul, the engine is shared by every board")
+
+// Simul is a simultaneous exhibition, a single leased engine plays every board.
+// Boards queue for the engine and search one at a time, in the order they
+// asked, for a slice of their own clock
+type Simul struct {
+	ID     uuid.UUID
+	Engine *engine.UCIEngine
+
+	mu     sync.Mutex
+	busy   bool            // Whether a board holds the engine
+	queue  []chan struct{} // Boards waiting for the engine, the first one is next
+	boards int             // Boards still being played
+	closed bool            // Whether the engine went back to the pool
+
+	pool   EnginePool // Takes the engine back after the last board, nil closes it instead
+	logger *zap.Logger
+}
+
+// NewSimul creates a simul of the given number of boards played by the engine
+func NewSimul(boards int, eng *engine.UCIEngine, pool EnginePool, logger *zap.Logger) (*Simul, error) {
+	if boards < 1 || boards > MaxSimulBoards {
+		return nil, fmt.Errorf("a simul has between 1 and %d boards", MaxSimulBoards)
+	}
+
+	id := uuid.New()
+	return &Simul{
+		ID:     id,
+		Engine: eng,
+		boards: boards,
+		pool:   pool,
+		logger: logger.With(zap.String("simul_id", id.String())),
+	}, nil
+}
+
+// acquire waits until the engine is free for the board, false when the game
+// ended meanwhile
+func (sm *Simul) acquire(ctx context.Context) bool {
+	sm.mu.Lock()
+	if !sm.busy && len(sm.queue) == 0 {
+		sm.busy = true
+		sm.mu.Unlock()
+		return true
+	}
+
+	turn := make(chan struct{})
+	sm.queue = append(sm.queue, turn)
+	sm.mu.Unlock()
+
+	select {
+	case <-turn:
+		return true
+	case <-ctx.Done():
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	for i, waiting := range sm.queue {
+		if waiting == turn {
+			sm.queue = append(sm.queue[:i], sm.queue[i+1:]...)
+			return false
+		}
+	}
+
+	// The engine was handed over while the game ended, pass it on
+	sm.handOver()
+	return false
+}
+
+// release hands the engine to the next board. A board that stopped waiting
+// for its move leaves the engine searching, so the engine is reset first: the
+// search is stopped and its best move, which comes before readyok, is read
+// and dropped before the next board may search
+func (sm *Simul) release(searching bool) {
+	if searching {
+		ctx, cancel := context.WithTimeout(context.Background(), stopGracePeriod)
+		if err := sm.Engine.Reset(ctx); err != nil {
+			sm.logger.Warn("could not reset engine after abandoned simul search", zap.Error(err))
+		}
+		cancel()
+	}
+	sm.Engine.Record(nil)
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.handOver()
+}
+
+// handOver wakes the next waiting board or frees the engine, the caller holds mu
+func (sm *Simul) handOver() {
+	if len(sm.queue) == 0 {
+		sm.busy = false
+		return
+	}
+
+	next := sm.queue[0]
+	sm.queue = sm.queue[1:]
+	close(next)
+}
+
+// slice returns the search time of a board with the given time left, an equal
+// share of its clock but never longer than the slice of the simul
+func (sm *Simul) slice(remaining time.Duration) time.Duration {
+	return max(min(remaining/simulMovesToGo, simulSlice), simulMinSlice)
+}
+
+// leave is called once a board ended, the engine goes back to the pool after
+// the last one
+func (sm *Simul) leave() {
+	sm.mu.Lock()
+	sm.boards--
+	last := sm.boards <= 0 && !sm.closed
+	if last {
+		sm.closed = true
+	}
+	sm.mu.Unlock()
+
+	if !last {
+		return
+	}
+
+	sm.logger.Info("simul finished, returning its engine")
+
+	if sm.pool == nil {
+		sm.Engine.Close()
+		return
+	}
+	sm.pool.ReturnEngine(sm.Engine.ID.String())
+}
+
+// simulTurn is the hold of a board on the engine of its simul
+type simulTurn struct {
+	simul    *Simul
+	released bool
+}
+
+// release hands the engine on, only the first call of a turn does
+func (t *simulTurn) release(searching bool) {
+	if t.released {
+		return
+	}
+	t.released = true
+	t.simul.release(searching)
+}
+
+// awaitSimul waits for the engine of the simul and fits the search into the
+// time the board has left, nil when the game ended while waiting. The clock of
+// the board keeps running while it waits, like that of a simul giver walking
+// between the boards
+func (s *Game) awaitSimul(req *searchRequest) *simulTurn {
+	waitStart := time.Now()
+	if !req.simul.acquire(s.ctx) {
+		return nil
+	}
+
+	s.recordEngine(req.simul.Engine)
+
+	waited := time.Since(waitStart).Milliseconds()
+	remaining := req.whiteTime
+	if req.turn == chess.Black {
+		req.blackTime -= waited
+		remaining = req.blackTime
+	} else {
+		req.whiteTime -= waited
+		remaining = req.whiteTime
+	}
+
+	slice := req.simul.slice(time.Duration(remaining) * time.Millisecond)
+	req.goCommand = fmt.Sprintf("go movetime %d", slice.Milliseconds())
+
+	req.logger.Debug("simul board got the engine",
+		zap.Int64("waited_ms", waited),
+		zap.Duration("slice", slice))
+
+	return &simulTurn{simul: req.simul}
+}
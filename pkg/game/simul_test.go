@@ -0,0 +1,40 @@
+package game
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/pkg/engine"
+)
+
+func TestSimulReleaseDropsAbandonedBestMove(t *testing.T) {
+	logger := zap.NewNop()
+	pool := engine.NewEnginePool(engine.MockPrefix+"latency=1ms", engine.Scaling{MaxEngines: 1}, engine.Limits{}, nil, logger)
+	require.NoError(t, pool.Initialize())
+	t.Cleanup(pool.Shutdown)
+
+	ctx := context.Background()
+	eng, err := pool.GetEngine(ctx)
+	require.NoError(t, err)
+
+	sm, err := NewSimul(2, eng, pool, logger)
+	require.NoError(t, err)
+
+	// A board gives up on its search, e.g. because its game ended
+	require.True(t, sm.acquire(ctx))
+	require.NoError(t, eng.SendCommand("position startpos"))
+	require.NoError(t, eng.SendCommand("go infinite"))
+	sm.release(true)
+
+	// The next board must not receive the best move of the abandoned search
+	require.True(t, sm.acquire(ctx))
+	waitCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	_, err = eng.WaitBestMove(waitCtx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
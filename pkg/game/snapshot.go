@@ -0,0 +1,250 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/color"
+	"github.com/tecu23/eng-server/pkg/book"
+	"github.com/tecu23/eng-server/pkg/engine"
+	"github.com/tecu23/eng-server/pkg/events"
+)
+
+// Snapshot is everything needed to restore a game in progress after a restart
+type Snapshot struct {
+	GameID         uuid.UUID      `json:"game_id"`
+	Mode           GameMode       `json:"mode"`
+	Variant        Variant        `json:"variant"`
+	StartFEN       string         `json:"start_fen"`
+	FEN            string         `json:"fen"` // Position reached, checked after replaying the moves
+	Moves          []SnapshotMove `json:"moves"`
+	TimeControl    TimeControl    `json:"time_control"`
+	TimeManagement TimeManagement `json:"time_management"`
+	Difficulty     Difficulty     `json:"difficulty,omitempty"` // Already applied to the time management and engine options
+	Pacing         Pacing         `json:"pacing"`
+	WhiteTime      int64          `json:"white_time"` // Remaining times in milliseconds
+	BlackTime      int64          `json:"black_time"`
+	PlayerColor    color.Color    `json:"player_color,omitempty"`
+	UserID         string         `json:"user_id,omitempty"`
+	Rated          bool           `json:"rated"`
+	HintsLeft      int            `json:"hints_left"`
+	Book           *book.Options  `json:"book,omitempty"` // Opening book options, nil when the game plays without a book
+	Analysis       bool           `json:"analysis"`       // Whether the game is analyzed once over
+	CreatedAt      time.Time      `json:"created_at"`
+	Seed           int64          `json:"seed"`
+	Deterministic  bool           `json:"deterministic,omitempty"`
+
+	EngineOptions         map[string]string `json:"engine_options,omitempty"`
+	OpponentEngineOptions map[string]string `json:"opponent_engine_options,omitempty"` // Black engine of an exhibition game
+}
+
+// SnapshotMove is a played move with the clocks before it, kept for takebacks
+type SnapshotMove struct {
+	UCI             string `json:"uci"`
+	WhiteTimeBefore int64  `json:"white_time_before"`
+	BlackTimeBefore int64  `json:"black_time_before"`
+}
+
+var (
+	// errPaused is returned for moves in a restored game nobody resumed yet
+	errPaused = errors.New("game is paused")
+	// errNotPaused is returned when resuming a game that is not waiting for its player
+	errNotPaused = errors.New("game is not paused")
+)
+
+// checkpointCommand takes a snapshot of the game
+type checkpointCommand struct {
+	reply chan Snapshot
+}
+
+// resumeCommand restarts a restored game for a new connection
+type resumeCommand struct {
+	owner uuid.UUID
+	reply chan error
+}
+
+// Checkpoint returns a snapshot of the game to restore it later
+func (s *Game) Checkpoint() (Snapshot, error) {
+	reply := make(chan Snapshot, 1)
+	if err := s.send(checkpointCommand{reply: reply}); err != nil {
+		return Snapshot{}, err
+	}
+
+	select {
+	case snap := <-reply:
+		return snap, nil
+	case <-s.ctx.Done():
+		return Snapshot{}, ErrGameTerminated
+	}
+}
+
+// checkpoint builds the snapshot of the game
+func (s *Game) checkpoint() Snapshot {
+	clock := s.Clock.Sync()
+
+	snap := Snapshot{
+		GameID:         s.ID,
+		Mode:           s.Mode,
+		Variant:        s.Variant,
+		StartFEN:       s.startFEN,
+		FEN:            s.fen(),
+		Moves:          make([]SnapshotMove, 0, len(s.history)),
+		TimeControl:    s.timeControl,
+		TimeManagement: s.timeManagement,
+		Difficulty:     s.difficulty,
+		Pacing:         s.pacing,
+		WhiteTime:      clock.White,
+		BlackTime:      clock.Black,
+		PlayerColor:    s.PlayerColor,
+		UserID:         s.userID,
+		Rated:          s.rated,
+		HintsLeft:      s.hintsLeft,
+		Analysis:       s.analysis != nil,
+		CreatedAt:      s.createdAt,
+		Seed:           s.seed,
+		Deterministic:  s.deterministic,
+	}
+
+	if s.Engine != nil {
+		snap.EngineOptions = s.Engine.Options()
+	}
+
+	if s.book != nil {
+		opts := s.bookOptions
+		snap.Book = &opts
+	}
+
+	if s.OpponentEngine != nil {
+		snap.OpponentEngineOptions = s.OpponentEngine.Options()
+	}
+
+	for _, m := range s.history {
+		snap.Moves = append(snap.Moves, SnapshotMove{
+			UCI:             m.UCI,
+			WhiteTimeBefore: m.WhiteTimeBefore,
+			BlackTimeBefore: m.BlackTimeBefore,
+		})
+	}
+
+	return snap
+}
+
+// RestoreGame recreates a game from its snapshot. The caller provides the
+// hooks and engines in params as for a new game. The game keeps its ID, has
+// no connection until a player resumes it and starts with StartPaused. The
+// game terminates when the context is cancelled
+func RestoreGame(
+	ctx context.Context,
+	params CreateGameParams,
+	snap Snapshot,
+	eng *engine.UCIEngine,
+	publisher *events.Publisher,
+	logger *zap.Logger,
+) (*Game, error) {
+	params.GameID = snap.GameID
+	params.StartPostion = snap.StartFEN
+	params.TimeControl = snap.TimeControl
+	params.TimeManagement = snap.TimeManagement
+	params.Pacing = snap.Pacing
+	params.Variant = snap.Variant
+	params.Mode = snap.Mode
+	params.PlayerColor = snap.PlayerColor
+	params.UserID = snap.UserID
+	params.Rated = snap.Rated
+	params.Seed = snap.Seed
+	params.Deterministic = snap.Deterministic
+
+	if err := eng.SetOptions(snap.EngineOptions); err != nil {
+		return nil, err
+	}
+	if params.OpponentEngine != nil {
+		if err := params.OpponentEngine.SetOptions(snap.OpponentEngineOptions); err != nil {
+			return nil, err
+		}
+	}
+
+	session, err := CreateGame(ctx, params, uuid.Nil, eng, publisher, logger)
+	if err != nil {
+		return nil, err
+	}
+	session.difficulty = snap.Difficulty
+	session.randomMoves = snap.Difficulty.randomMoves()
+
+	for _, m := range snap.Moves {
+		played, err := session.parseMove(m.UCI)
+		if err != nil {
+			session.cancel()
+			return nil, fmt.Errorf("could not replay move %s: %w", m.UCI, err)
+		}
+		if err := session.pushMove(played); err != nil {
+			session.cancel()
+			return nil, fmt.Errorf("could not replay move %s: %w", m.UCI, err)
+		}
+
+		played.WhiteTimeBefore = m.WhiteTimeBefore
+		played.BlackTimeBefore = m.BlackTimeBefore
+		session.history = append(session.history, played)
+	}
+
+	if fen := session.fen(); fen != snap.FEN {
+		session.cancel()
+		return nil, fmt.Errorf("replayed position %s does not match the snapshot %s", fen, snap.FEN)
+	}
+
+	session.Clock.Restore(
+		snap.WhiteTime,
+		snap.BlackTime,
+		color.Color(session.state.Position().Turn().String()),
+	)
+	session.hintsLeft = snap.HintsLeft
+	session.createdAt = snap.CreatedAt
+
+	return session, nil
+}
+
+// StartPaused starts the session loop of a restored game, leaving the clock
+// stopped until a player resumes it
+func (s *Game) StartPaused() {
+	s.setStatus(StatusPaused)
+	s.publishClock()
+
+	go s.run()
+}
+
+// Resume restarts a paused game, played from now on over the given connection
+func (s *Game) Resume(connectionID uuid.UUID) error {
+	reply := make(chan error, 1)
+	if err := s.send(resumeCommand{owner: connectionID, reply: reply}); err != nil {
+		return err
+	}
+
+	return s.await(reply)
+}
+
+// resume restarts the clock and the engine of a paused game
+func (s *Game) resume(owner uuid.UUID) error {
+	if s.Status() != StatusPaused {
+		return errNotPaused
+	}
+
+	s.owner.Store(owner)
+	s.setStatus(StatusActive)
+	s.Clock.Start(s.ctx)
+	s.publishClock()
+
+	if s.Mode == ModeExhibition {
+		go s.forwardInfo(s.Engine, color.White)
+		go s.forwardInfo(s.OpponentEngine, color.Black)
+		return s.startSearch()
+	}
+
+	if !s.playerToMove() {
+		return s.startSearch()
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/corentings/chess/v2"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/color"
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/events"
+)
+
+// takebackCommand undoes the last move of the player
+type takebackCommand struct {
+	request
+	reply chan error
+}
+
+// takeback undoes moves until it is the player's turn again. While the engine
+// is thinking only the player's move is undone, otherwise the engine reply is
+// undone as well
+func (s *Game) takeback() error {
+	if s.Status() == StatusCompleted {
+		return ErrGameOver
+	}
+
+	if s.Mode == ModeExhibition {
+		return errExhibition
+	}
+
+	if s.Mode == ModeHumanVsHuman {
+		return errPlayerTakeback
+	}
+
+	if s.simul != nil {
+		return errSimul
+	}
+
+	if s.rated {
+		return errRatedTakeback
+	}
+
+	if s.hinting {
+		return errors.New("hint search in progress")
+	}
+
+	undo := 2
+	if s.searching {
+		undo = 1
+	}
+
+	if len(s.history) < undo {
+		return errors.New("no moves to take back")
+	}
+
+	// Abandon the running search, its result will be dropped
+	if s.searching {
+		s.searchID++
+		s.searching = false
+		if err := s.Engine.Stop(); err != nil {
+			s.log().Error("error stopping engine search", zap.Error(err))
+		}
+	}
+
+	kept := s.history[:len(s.history)-undo]
+	restored := s.history[len(s.history)-undo]
+
+	state, rules, err := s.replay(kept)
+	if err != nil {
+		return fmt.Errorf("could not take back move: %w", err)
+	}
+
+	s.state = state
+	s.rules = rules
+	s.history = kept
+	s.premove = ""
+	s.Clock.Restore(
+		restored.WhiteTimeBefore,
+		restored.BlackTimeBefore,
+		color.Color(state.Position().Turn().String()),
+	)
+	s.publishClock()
+
+	// Resync the engine with the restored position
+	if err := s.Engine.SendCommand(fmt.Sprintf("position fen %s", s.fen())); err != nil {
+		s.log().Error("engine command error", zap.Error(err))
+	}
+
+	moves := make([]string, 0, len(kept))
+	for _, m := range kept {
+		moves = append(moves, m.SAN)
+	}
+
+	s.Publisher.Publish(events.Event{
+		Type:   events.EventTakebackApplied,
+		GameID: s.ID.String(),
+		Payload: messages.TakebackAppliedPayload{
+			GameID:      s.ID.String(),
+			BoardFEN:    s.fen(),
+			Moves:       moves,
+			UndoneMoves: undo,
+			WhiteTime:   restored.WhiteTimeBefore,
+			BlackTime:   restored.BlackTimeBefore,
+			CurrentTurn: color.Color(state.Position().Turn().String()),
+		},
+	})
+
+	s.log().Info("takeback applied", zap.Int("undone_moves", undo))
+	return nil
+}
+
+// replay rebuilds the game and its variant state from the starting position
+// with the given moves
+func (s *Game) replay(moves []playedMove) (*chess.Game, variantRules, error) {
+	fen, err := chess.FEN(s.startFEN)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	state := chess.NewGame(fen)
+	rules := s.startRules.clone()
+	for _, m := range moves {
+		if state, err = rules.push(state, m); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return state, rules, nil
+}
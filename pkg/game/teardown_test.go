@@ -0,0 +1,122 @@
+package game
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/color"
+	"github.com/tecu23/eng-server/pkg/engine"
+	"github.com/tecu23/eng-server/pkg/events"
+)
+
+// startEngineGame starts a game against an engine leased from the pool
+func startEngineGame(t *testing.T, pool *engine.Pool, publisher *events.Publisher) *Game {
+	t.Helper()
+
+	eng, err := pool.GetEngine(context.Background())
+	require.NoError(t, err)
+
+	session, err := CreateGame(context.Background(), CreateGameParams{
+		GameID:      uuid.New(),
+		TimeControl: TimeControl{WhiteTime: 60000, BlackTime: 60000},
+		PlayerColor: color.White,
+		Mode:        ModeHumanVsEngine,
+		EnginePool:  pool,
+	}, uuid.New(), eng, publisher, zap.NewNop())
+	require.NoError(t, err)
+
+	session.Start()
+	return session
+}
+
+func TestCloseIsIdempotentUnderConcurrency(t *testing.T) {
+	logger := zap.NewNop()
+	pool := engine.NewEnginePool(engine.MockPrefix+"latency=1ms", engine.Scaling{MaxEngines: 1}, engine.Limits{}, nil, logger)
+	require.NoError(t, pool.Initialize())
+	t.Cleanup(pool.Shutdown)
+
+	publisher := events.NewPublisher(logger)
+	var terminated atomic.Int32
+	publisher.NewSubscriber("test", events.SubscriberOptions{}).Subscribe(events.EventGameTerminated, func(events.Event) {
+		terminated.Add(1)
+	})
+
+	session := startEngineGame(t, pool, publisher)
+
+	// Every teardown path races the player and the engine of the game
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			session.Close()
+		}()
+		go func() {
+			defer wg.Done()
+			_, _ = session.ProcessMove(color.White, "e2e4", "", 0, "")
+		}()
+		go func() {
+			defer wg.Done()
+			_ = session.RequestEngineMove("")
+		}()
+	}
+	wg.Wait()
+
+	select {
+	case <-session.Terminated():
+	default:
+		t.Fatal("the session is not terminated")
+	}
+
+	// The engine goes back to the pool once, for the next game
+	assert.Eventually(t, func() bool { return pool.Stats().Available == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool { return terminated.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.EqualValues(t, 1, terminated.Load())
+
+	next := startEngineGame(t, pool, publisher)
+	next.Close()
+}
+
+func TestClockStopEndsHeartbeat(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	clock := NewClock(TimeControl{WhiteTime: 60000, BlackTime: 60000, TimingMethod: IncrementTiming})
+	clock.Start(context.Background())
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			clock.Switch()
+		}()
+		go func() {
+			defer wg.Done()
+			clock.Sync()
+			clock.GetRemainingTime()
+		}()
+		go func() {
+			defer wg.Done()
+			clock.Stop()
+		}()
+	}
+	wg.Wait()
+	clock.Stop()
+
+	// The heartbeat goroutine of the clock is gone. Polled by hand, Eventually
+	// runs its condition on goroutines of its own
+	for deadline := time.Now().Add(5 * time.Second); runtime.NumGoroutine() > before && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+}
@@ -0,0 +1,110 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TimePolicy selects how the engine limits its searches
+type TimePolicy string
+
+// All the supported time policies
+const (
+	TimePolicyClock    TimePolicy = "clock"    // The engine budgets its own clock, the default
+	TimePolicyMoveTime TimePolicy = "movetime" // Fixed search time per move
+	TimePolicyDepth    TimePolicy = "depth"    // Fixed search depth per move
+	TimePolicyNodes    TimePolicy = "nodes"    // Fixed number of searched nodes per move
+)
+
+// Limits of the fixed time policies, so a game cannot hold an engine for long
+const (
+	maxMoveTime = time.Minute
+	maxDepth    = 60
+	maxNodes    = 1_000_000_000
+)
+
+// minMovesToGo keeps the clock policy from spending most of the clock on a
+// single move once a game runs past the moves of its time control
+const minMovesToGo = 10
+
+// TimeManagement configures the search limits of the engine of a game, the
+// zero value lets the engine manage its clock
+type TimeManagement struct {
+	Policy   TimePolicy
+	MoveTime time.Duration // Search time per move of the movetime policy
+	Depth    int           // Search depth of the depth policy
+	Nodes    int64         // Searched nodes of the nodes policy
+}
+
+// timeManager builds the go command that starts an engine search
+type timeManager interface {
+	goCommand(req searchRequest) string
+}
+
+// newTimeManager validates the time management of a game
+func newTimeManager(tm TimeManagement, tc TimeControl) (timeManager, error) {
+	switch tm.Policy {
+	case "", TimePolicyClock:
+		return clockManager{
+			movesPerControl: tc.MovesPerControl,
+			whiteIncrement:  tc.WhiteIncrement,
+			blackIncrement:  tc.BlackIncrement,
+		}, nil
+
+	case TimePolicyMoveTime:
+		if tm.MoveTime <= 0 || tm.MoveTime > maxMoveTime {
+			return nil, fmt.Errorf("move time must be between 1ms and %s", maxMoveTime)
+		}
+		return fixedManager(fmt.Sprintf("go movetime %d", tm.MoveTime.Milliseconds())), nil
+
+	case TimePolicyDepth:
+		if tm.Depth <= 0 || tm.Depth > maxDepth {
+			return nil, fmt.Errorf("depth must be between 1 and %d", maxDepth)
+		}
+		return fixedManager(fmt.Sprintf("go depth %d", tm.Depth)), nil
+
+	case TimePolicyNodes:
+		if tm.Nodes <= 0 || tm.Nodes > maxNodes {
+			return nil, fmt.Errorf("nodes must be between 1 and %d", maxNodes)
+		}
+		return fixedManager(fmt.Sprintf("go nodes %d", tm.Nodes)), nil
+
+	default:
+		return nil, errors.New("time policy must be clock, movetime, depth or nodes")
+	}
+}
+
+// clockManager hands the remaining clocks to the engine, spread over the
+// moves left in the time control
+type clockManager struct {
+	movesPerControl int
+	whiteIncrement  int64
+	blackIncrement  int64
+}
+
+func (m clockManager) goCommand(req searchRequest) string {
+	movesPerControl := m.movesPerControl
+	if movesPerControl <= 0 {
+		movesPerControl = 40
+	}
+
+	// Full moves played so far
+	played := req.movesPlayed / 2
+
+	return fmt.Sprintf(
+		"go wtime %d btime %d winc %d binc %d movestogo %d",
+		req.whiteTime,
+		req.blackTime,
+		m.whiteIncrement,
+		m.blackIncrement,
+		max(movesPerControl-played, minMovesToGo),
+	)
+}
+
+// fixedManager sends the same go command for every move
+type fixedManager string
+
+func (m fixedManager) goCommand(searchRequest) string {
+	return string(m)
+}
@@ -0,0 +1,27 @@
+package game
+
+import "go.uber.org/zap"
+
+// request tags a command with the ID of the client request that sent it
+type request struct {
+	id string
+}
+
+// requestID returns the ID of the client request, empty for internal commands
+func (r request) requestID() string {
+	return r.id
+}
+
+// traced is a command sent on behalf of a client request
+type traced interface {
+	requestID() string
+}
+
+// log returns the session logger, tagged with the request of the command
+// being handled. Only the session loop may call it
+func (s *Game) log() *zap.Logger {
+	if s.trace == "" {
+		return s.Logger
+	}
+	return s.Logger.With(zap.String("request_id", s.trace))
+}
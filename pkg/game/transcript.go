@@ -0,0 +1,55 @@
+package game
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/engine"
+)
+
+// transcriptLines bounds the UCI dialogue kept per game, the latest lines are kept
+const transcriptLines = 20000
+
+// TranscriptStore keeps the engine transcripts of finished games
+type TranscriptStore interface {
+	SaveTranscript(transcript messages.EngineTranscript) error
+}
+
+// recordEngine attaches the transcript of the game to an engine it plays with
+func (s *Game) recordEngine(eng *engine.UCIEngine) {
+	if eng != nil {
+		eng.Record(s.transcript)
+	}
+}
+
+// Transcript returns the UCI dialogue of the engines of the game so far
+func (s *Game) Transcript() messages.EngineTranscript {
+	lines, dropped := s.transcript.Lines()
+
+	transcript := messages.EngineTranscript{
+		GameID:  s.ID.String(),
+		Dropped: dropped,
+		Lines:   make([]messages.TranscriptLine, 0, len(lines)),
+	}
+	for _, l := range lines {
+		transcript.Lines = append(transcript.Lines, messages.TranscriptLine{
+			Time:      l.Time,
+			EngineID:  l.EngineID.String(),
+			Direction: l.Direction,
+			Line:      l.Line,
+		})
+	}
+
+	return transcript
+}
+
+// saveTranscript keeps the transcript of the finished game, when a store is configured
+func (s *Game) saveTranscript() {
+	if s.transcripts == nil {
+		return
+	}
+
+	if err := s.transcripts.SaveTranscript(s.Transcript()); err != nil {
+		s.log().Error("could not save engine transcript", zap.Error(err))
+	}
+}
@@ -0,0 +1,241 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/corentings/chess/v2"
+
+	"github.com/tecu23/eng-server/internal/color"
+	"github.com/tecu23/eng-server/pkg/engine"
+)
+
+// Variant defines the rules a game is played with
+type Variant string
+
+// All the supported variants
+const (
+	VariantStandard      Variant = "standard"
+	VariantChess960      Variant = "chess960"
+	VariantCrazyhouse    Variant = "crazyhouse"
+	VariantKingOfTheHill Variant = "kingofthehill"
+	VariantThreeCheck    Variant = "3check"
+)
+
+// variantOutcome describes how the rules of a variant ended a game
+type variantOutcome struct {
+	reason      string
+	result      string
+	description string
+}
+
+// variantRules plugs the rules of a variant into move validation and game end
+// detection. The chess library only knows standard chess, so variants keep
+// the extra state they need and adjust the library positions around it
+type variantRules interface {
+	// parse decodes a move that is legal under the variant
+	parse(state *chess.Game, notation string) (playedMove, error)
+	// push plays a parsed move and returns the state to continue from
+	push(state *chess.Game, m playedMove) (*chess.Game, error)
+	// fen returns the FEN of the position, including the variant fields engines expect
+	fen(state *chess.Game) string
+	// legalMoves lists the legal moves of the position in UCI notation
+	legalMoves(state *chess.Game) []string
+	// outcome reports whether the position ends the game
+	outcome(state *chess.Game) (variantOutcome, bool)
+	// clone copies the variant state, used to replay a game from its start
+	clone() variantRules
+}
+
+// newRules returns the rules of a variant and the FEN the library can load
+// for the given start position
+func newRules(variant Variant, fen string) (variantRules, string, error) {
+	switch variant {
+	case VariantStandard:
+		return standardRules{}, fen, nil
+	case VariantChess960:
+		return newChess960(fen)
+	case VariantCrazyhouse:
+		return newCrazyhouse(fen)
+	case VariantKingOfTheHill:
+		return kingOfTheHill{}, fen, nil
+	case VariantThreeCheck:
+		return newThreeCheck(fen)
+	default:
+		return nil, "", fmt.Errorf("unsupported variant %q", variant)
+	}
+}
+
+// uciVariant returns the UCI_Variant value engines use for a variant, empty
+// for variants played through other options
+func (v Variant) uciVariant() string {
+	switch v {
+	case VariantCrazyhouse, VariantKingOfTheHill, VariantThreeCheck:
+		return string(v)
+	default:
+		return ""
+	}
+}
+
+// standardRules are the rules of standard chess, fully handled by the library
+type standardRules struct{}
+
+func (standardRules) parse(state *chess.Game, notation string) (playedMove, error) {
+	return parseMove(state.Position(), notation)
+}
+
+func (standardRules) push(state *chess.Game, m playedMove) (*chess.Game, error) {
+	if err := state.PushMove(m.SAN, nil); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (standardRules) fen(state *chess.Game) string {
+	return state.FEN()
+}
+
+func (standardRules) legalMoves(state *chess.Game) []string {
+	pos := state.Position()
+
+	var moves []string
+	for _, m := range pos.ValidMoves() {
+		moves = append(moves, chess.UCINotation{}.Encode(pos, &m))
+	}
+	return moves
+}
+
+func (standardRules) outcome(state *chess.Game) (variantOutcome, bool) {
+	if state.Outcome() == chess.NoOutcome {
+		return variantOutcome{}, false
+	}
+
+	method := state.Method()
+	return variantOutcome{
+		reason:      method.String(),
+		result:      state.Outcome().String(),
+		description: fmt.Sprintf("Game ended by %s", method),
+	}, true
+}
+
+func (r standardRules) clone() variantRules {
+	return r
+}
+
+// centerSquares are the squares a king must reach to win King of the Hill
+var centerSquares = map[chess.Square]bool{
+	chess.D4: true, chess.E4: true, chess.D5: true, chess.E5: true,
+}
+
+// kingOfTheHill wins the game for the first king reaching the center
+type kingOfTheHill struct {
+	standardRules
+}
+
+func (kingOfTheHill) outcome(state *chess.Game) (variantOutcome, bool) {
+	pos := state.Position()
+	mover := pos.Turn().Other()
+
+	if king, ok := kingSquare(pos.Board().SquareMap(), mover); ok && centerSquares[king] {
+		return winOutcome("king_of_the_hill", mover, "reached the center"), true
+	}
+
+	return standardRules{}.outcome(state)
+}
+
+func (r kingOfTheHill) clone() variantRules {
+	return r
+}
+
+// threeCheck wins the game for the first side giving three checks
+type threeCheck struct {
+	standardRules
+	checks [2]int // Checks given by each color
+}
+
+// newThreeCheck reads the remaining checks of a FEN written as 3+3 before the
+// halfmove clock, when present, and returns the FEN the library can load
+func newThreeCheck(fen string) (*threeCheck, string, error) {
+	r := &threeCheck{}
+
+	fields := strings.Fields(fen)
+	if len(fields) == 7 {
+		var white, black int
+		if _, err := fmt.Sscanf(fields[4], "%d+%d", &white, &black); err != nil {
+			return nil, "", fmt.Errorf("invalid start position: bad check counter %q", fields[4])
+		}
+		r.checks = [2]int{3 - white, 3 - black}
+		fields = append(fields[:4], fields[5:]...)
+		fen = strings.Join(fields, " ")
+	}
+
+	return r, fen, nil
+}
+
+func (r *threeCheck) push(state *chess.Game, m playedMove) (*chess.Game, error) {
+	mover := state.Position().Turn()
+
+	state, err := r.standardRules.push(state, m)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasSuffix(m.SAN, "+") || strings.HasSuffix(m.SAN, "#") {
+		r.checks[colorIndex(mover)]++
+	}
+	return state, nil
+}
+
+// fen adds the remaining checks of each side to the FEN
+func (r *threeCheck) fen(state *chess.Game) string {
+	fields := strings.Fields(state.FEN())
+	if len(fields) != 6 {
+		return state.FEN()
+	}
+
+	remaining := fmt.Sprintf("%d+%d", max(0, 3-r.checks[0]), max(0, 3-r.checks[1]))
+	fields = append(fields[:4], append([]string{remaining}, fields[4:]...)...)
+	return strings.Join(fields, " ")
+}
+
+func (r *threeCheck) outcome(state *chess.Game) (variantOutcome, bool) {
+	for _, clr := range []chess.Color{chess.White, chess.Black} {
+		if r.checks[colorIndex(clr)] >= 3 {
+			return winOutcome("three_check", clr, "gave three checks"), true
+		}
+	}
+
+	return r.standardRules.outcome(state)
+}
+
+func (r *threeCheck) clone() variantRules {
+	c := *r
+	return &c
+}
+
+// winOutcome builds the outcome of a game won by a variant rule
+func winOutcome(reason string, winner chess.Color, what string) variantOutcome {
+	result := "1-0"
+	if winner == chess.Black {
+		result = "0-1"
+	}
+
+	return variantOutcome{
+		reason:      reason,
+		result:      result,
+		description: fmt.Sprintf("%s %s", colorName(color.Color(winner.String())), what),
+	}
+}
+
+// enableVariant switches an engine to the rules of a variant
+func enableVariant(eng *engine.UCIEngine, variant Variant) error {
+	if variant == VariantChess960 {
+		return eng.SetOption("UCI_Chess960", "true")
+	}
+
+	if name := variant.uciVariant(); name != "" {
+		return eng.SetOption("UCI_Variant", name)
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+// Package health reports the status of the server components for probes and monitoring
+package health
+
+import (
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tecu23/eng-server/internal/messages"
+)
+
+// Status is the health of a component or of the whole server
+type Status string
+
+// All the possible statuses, from best to worst
+const (
+	StatusUp       Status = "up"
+	StatusDegraded Status = "degraded" // Working, but close to a limit
+	StatusDown     Status = "down"
+)
+
+// worse reports whether the status is worse than the other
+func (s Status) worse(other Status) bool {
+	rank := map[Status]int{StatusUp: 0, StatusDegraded: 1, StatusDown: 2}
+	return rank[s] > rank[other]
+}
+
+// Component is the health of a single component
+type Component struct {
+	Status  Status      `json:"status"`
+	Reason  string      `json:"reason,omitempty"`  // Why the component is not up
+	Details interface{} `json:"details,omitempty"` // Component specific counters
+}
+
+// Check measures the health of a component
+type Check func() Component
+
+// Report is the health of the server and all its components
+type Report struct {
+	Status        Status               `json:"status"`
+	Uptime        string               `json:"uptime"`
+	UptimeSeconds int64                `json:"uptime_seconds"`
+	Build         messages.BuildInfo   `json:"build"`
+	Components    map[string]Component `json:"components"`
+}
+
+// Checker runs the checks of the registered components
+type Checker struct {
+	started time.Time
+	build   messages.BuildInfo
+
+	mu     sync.RWMutex
+	checks map[string]Check
+}
+
+// NewChecker creates a checker for a server started at the given time
+func NewChecker(started time.Time, build messages.BuildInfo) *Checker {
+	return &Checker{
+		started: started,
+		build:   build,
+		checks:  make(map[string]Check),
+	}
+}
+
+// Register adds a component to the report, replacing any check of the same name
+func (c *Checker) Register(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checks[name] = check
+}
+
+// Report runs every check. The server status is the worst component status
+func (c *Checker) Report() Report {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	uptime := time.Since(c.started)
+	report := Report{
+		Status:        StatusUp,
+		Uptime:        uptime.Truncate(time.Second).String(),
+		UptimeSeconds: int64(uptime.Seconds()),
+		Build:         c.build,
+		Components:    make(map[string]Component, len(c.checks)),
+	}
+
+	for name, check := range c.checks {
+		component := check()
+		report.Components[name] = component
+
+		if component.Status.worse(report.Status) {
+			report.Status = component.Status
+		}
+	}
+
+	return report
+}
+
+// Down returns the components that are down, sorted by name, with their reason
+func (r Report) Down() []string {
+	var down []string
+	for name, component := range r.Components {
+		if component.Status == StatusDown {
+			down = append(down, name+": "+component.Reason)
+		}
+	}
+	sort.Strings(down)
+	return down
+}
+
+// ReadBuildInfo returns the build information embedded by the Go toolchain
+func ReadBuildInfo() messages.BuildInfo {
+	build := messages.BuildInfo{Version: "(devel)", Protocol: messages.ProtocolVersion}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return build
+	}
+
+	build.GoVersion = info.GoVersion
+	if info.Main.Version != "" {
+		build.Version = info.Main.Version
+	}
+
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			build.Commit = setting.Value
+		case "vcs.time":
+			build.BuildDate = setting.Value
+		}
+	}
+
+	return build
+}
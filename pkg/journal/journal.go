@@ -0,0 +1,191 @@
+// Package journal records the events sent to the clients of every game, so
+// real game traces can be replayed to frontends
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// maxEntryBytes bounds a line of a journal, PGNs and analysis reports included
+const maxEntryBytes = 4 << 20
+
+// ErrNotFound is returned when a game has no journal
+var ErrNotFound = errors.New("no event journal for this game")
+
+// Entry is an event sent to the clients of a game
+type Entry struct {
+	Seq     int64           `json:"seq,omitempty"` // Sequence number the event was sent with, 0 in older journals
+	Time    time.Time       `json:"time"`
+	Event   string          `json:"event"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// Journal appends the events of the games to a file per game, as JSON lines
+// named after the game ID in its directory
+type Journal struct {
+	dir string
+
+	mu    sync.Mutex
+	files map[string]*os.File // Journals of the games being played
+
+	logger *zap.Logger
+}
+
+// New creates a journal writing to the directory, which is created when needed
+func New(dir string, logger *zap.Logger) (*Journal, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("could not create event journal directory: %w", err)
+	}
+
+	return &Journal{
+		dir:    dir,
+		files:  make(map[string]*os.File),
+		logger: logger,
+	}, nil
+}
+
+// path returns the file of the journal of a game, game IDs are UUIDs so they
+// never reach out of the directory
+func (j *Journal) path(gameID string) (string, error) {
+	id, err := uuid.Parse(gameID)
+	if err != nil {
+		return "", fmt.Errorf("invalid game ID: %w", err)
+	}
+	return filepath.Join(j.dir, id.String()+".jsonl"), nil
+}
+
+// Open starts recording the events of a game, appending to its journal when
+// the game is restored
+func (j *Journal) Open(gameID string) {
+	path, err := j.path(gameID)
+	if err != nil {
+		j.logger.Error("could not open event journal", zap.String("game_id", gameID), zap.Error(err))
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, ok := j.files[gameID]; ok {
+		return
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		j.logger.Error("could not open event journal", zap.String("game_id", gameID), zap.Error(err))
+		return
+	}
+	j.files[gameID] = file
+}
+
+// Append records an event of a game with its sequence number, events of games
+// without an open journal are not recorded
+func (j *Journal) Append(gameID string, seq int64, event string, payload any) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	file, ok := j.files[gameID]
+	if !ok {
+		return
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		j.logger.Error("could not encode journal event", zap.String("event", event), zap.Error(err))
+		return
+	}
+
+	line, err := json.Marshal(Entry{Seq: seq, Time: time.Now().UTC(), Event: event, Payload: data})
+	if err != nil {
+		j.logger.Error("could not encode journal event", zap.String("event", event), zap.Error(err))
+		return
+	}
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		j.logger.Error("could not write event journal", zap.String("game_id", gameID), zap.Error(err))
+	}
+}
+
+// Close stops recording the events of a game
+func (j *Journal) Close(gameID string) {
+	j.mu.Lock()
+	file, ok := j.files[gameID]
+	delete(j.files, gameID)
+	j.mu.Unlock()
+
+	if !ok {
+		return
+	}
+	if err := file.Close(); err != nil {
+		j.logger.Error("could not close event journal", zap.String("game_id", gameID), zap.Error(err))
+	}
+}
+
+// CloseAll stops recording every game, on shutdown
+func (j *Journal) CloseAll() {
+	j.mu.Lock()
+	ids := make([]string, 0, len(j.files))
+	for id := range j.files {
+		ids = append(ids, id)
+	}
+	j.mu.Unlock()
+
+	for _, id := range ids {
+		j.Close(id)
+	}
+}
+
+// Read returns the events recorded for a game in the order they were sent,
+// the journal of a game still being played holds its events so far
+func (j *Journal) Read(gameID string) ([]Entry, error) {
+	path, err := j.path(gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, gameID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEntryBytes)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("corrupt journal line %d: %w", len(entries)+1, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+// LastSeq returns the sequence number of the last event recorded for a game,
+// 0 when it has none
+func (j *Journal) LastSeq(gameID string) (int64, error) {
+	entries, err := j.Read(gameID)
+	if err != nil {
+		return 0, err
+	}
+	if len(entries) == 0 {
+		return 0, nil
+	}
+	return entries[len(entries)-1].Seq, nil
+}
@@ -0,0 +1,79 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/color"
+	"github.com/tecu23/eng-server/pkg/game"
+	"github.com/tecu23/eng-server/pkg/repository"
+)
+
+// Errors returned for victory claims made before the opponent abandoned the game
+var (
+	ErrOpponentConnected = errors.New("the opponent is connected")
+	ErrClaimTooEarly     = errors.New("the opponent may still come back")
+)
+
+// markAbsent records that the player of a game between players lost their
+// connection. It reports whether the opponent is still connected, the game
+// then goes on for them to wait or claim the victory
+func (m *Manager) markAbsent(session *game.Game, connectionID uuid.UUID) bool {
+	if session.Mode != game.ModeHumanVsHuman {
+		return false
+	}
+
+	clr, ok := session.ColorOf(connectionID)
+	if !ok {
+		return false
+	}
+
+	m.connMu.Lock()
+	defer m.connMu.Unlock()
+
+	absent, ok := m.absentSince[session.ID]
+	if !ok {
+		absent = make(map[color.Color]time.Time)
+		m.absentSince[session.ID] = absent
+	}
+	absent[clr] = time.Now()
+
+	_, gone := absent[clr.Opp()]
+	return !gone
+}
+
+// ClaimVictory ends a game between players in favour of the player of a
+// color, once their opponent has been disconnected for the disconnect grace
+// period. The game is drawn when the player cannot checkmate
+func (m *Manager) ClaimVictory(gameID uuid.UUID, clr color.Color, requestID string) error {
+	session, ok := m.GetSession(gameID)
+	if !ok {
+		return fmt.Errorf("%w: %s", repository.ErrGameNotFound, gameID)
+	}
+
+	m.connMu.Lock()
+	since, absent := m.absentSince[gameID][clr.Opp()]
+	m.connMu.Unlock()
+
+	if !absent {
+		return ErrOpponentConnected
+	}
+	if left := m.disconnectGrace - time.Since(since); left > 0 {
+		return fmt.Errorf("%w, the victory can be claimed in %s", ErrClaimTooEarly, left.Round(time.Second))
+	}
+
+	if err := session.ClaimVictory(clr, requestID); err != nil {
+		return err
+	}
+
+	m.logger.Info("victory claimed",
+		zap.String("session_id", gameID.String()),
+		zap.String("color", string(clr)),
+		zap.Duration("absent", time.Since(since)),
+	)
+	return nil
+}
@@ -0,0 +1,109 @@
+package manager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tecu23/eng-server/internal/color"
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/game"
+)
+
+// createPlayerGame creates a game between players from a position, the
+// owner plays White
+func createPlayerGame(t *testing.T, m *Manager, fen string, white, black uuid.UUID) *game.Game {
+	t.Helper()
+
+	session, err := m.CreatePlayerGame(
+		context.Background(),
+		messages.TimeControl{WhiteTime: 60000, BlackTime: 60000},
+		fen,
+		game.VariantStandard,
+		Player{ConnectionID: white, UserID: "alice"},
+		color.White,
+		Player{ConnectionID: black, UserID: "bob"},
+		false,
+	)
+	require.NoError(t, err)
+	return session
+}
+
+// result returns the result of a game, empty while it is played
+func result(t *testing.T, session *game.Game) string {
+	t.Helper()
+
+	state, err := session.State()
+	require.NoError(t, err)
+	return state.Result
+}
+
+func TestClaimVictoryAfterGracePeriod(t *testing.T) {
+	const grace = 50 * time.Millisecond
+	m := newTestManager(t, 1, grace)
+
+	white, black := uuid.New(), uuid.New()
+	session := createPlayerGame(t, m, "", white, black)
+
+	// Nobody left the game yet
+	assert.ErrorIs(t, m.ClaimVictory(session.ID, color.White, ""), ErrOpponentConnected)
+
+	// The game goes on for White while Black is away
+	m.terminateSessionsByConnectionID(black.String())
+	_, ok := m.GetSession(session.ID)
+	require.True(t, ok)
+
+	assert.ErrorIs(t, m.ClaimVictory(session.ID, color.Black, ""), ErrOpponentConnected)
+	assert.ErrorIs(t, m.ClaimVictory(session.ID, color.White, ""), ErrClaimTooEarly)
+
+	time.Sleep(grace)
+	require.NoError(t, m.ClaimVictory(session.ID, color.White, ""))
+	assert.Equal(t, "1-0", result(t, session))
+}
+
+func TestClaimVictoryWithoutMatingMaterialIsDrawn(t *testing.T) {
+	m := newTestManager(t, 1, 0)
+
+	white, black := uuid.New(), uuid.New()
+	session := createPlayerGame(t, m, "4k2r/8/8/8/8/8/8/4K3 w - - 0 1", white, black)
+
+	m.terminateSessionsByConnectionID(black.String())
+	require.NoError(t, m.ClaimVictory(session.ID, color.White, ""))
+	assert.Equal(t, "1/2-1/2", result(t, session))
+}
+
+func TestReclaimedSeatCannotBeClaimed(t *testing.T) {
+	m := newTestManager(t, 1, 0)
+
+	white, black := uuid.New(), uuid.New()
+	session := createPlayerGame(t, m, "", white, black)
+
+	m.terminateSessionsByConnectionID(black.String())
+
+	back := uuid.New()
+	_, err := m.TransferSession(session.ID, color.Black, back)
+	require.NoError(t, err)
+	assert.Equal(t, back, session.Opponent())
+
+	assert.ErrorIs(t, m.ClaimVictory(session.ID, color.White, ""), ErrOpponentConnected)
+}
+
+func TestGameBetweenPlayersEndsWhenBothLeave(t *testing.T) {
+	m := newTestManager(t, 1, 0)
+
+	white, black := uuid.New(), uuid.New()
+	session := createPlayerGame(t, m, "", white, black)
+
+	m.terminateSessionsByConnectionID(black.String())
+	m.terminateSessionsByConnectionID(white.String())
+
+	_, ok := m.GetSession(session.ID)
+	assert.False(t, ok)
+
+	conns, _ := indexed(m)
+	assert.Zero(t, conns)
+}
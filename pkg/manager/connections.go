@@ -0,0 +1,182 @@
+package manager
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/color"
+	"github.com/tecu23/eng-server/pkg/game"
+	"github.com/tecu23/eng-server/pkg/repository"
+)
+
+// indexSession records a game as played over a connection
+func (m *Manager) indexSession(connectionID, gameID uuid.UUID) {
+	m.connMu.Lock()
+	defer m.connMu.Unlock()
+
+	m.indexSessionLocked(connectionID, gameID)
+}
+
+func (m *Manager) indexSessionLocked(connectionID, gameID uuid.UUID) {
+	games, ok := m.connSessions[connectionID]
+	if !ok {
+		games = make(map[uuid.UUID]bool)
+		m.connSessions[connectionID] = games
+	}
+	games[gameID] = true
+}
+
+// unindexSession forgets a removed game, along with its pending termination.
+// The connection is read under the lock, so a concurrent transfer is either
+// undone here or sees the game removed
+func (m *Manager) unindexSession(session *game.Game) {
+	m.connMu.Lock()
+	defer m.connMu.Unlock()
+
+	m.unindexSessionLocked(session.Owner(), session.ID)
+	if opponent := session.Opponent(); opponent != uuid.Nil {
+		m.unindexSessionLocked(opponent, session.ID)
+	}
+	delete(m.absentSince, session.ID)
+}
+
+func (m *Manager) unindexSessionLocked(connectionID, gameID uuid.UUID) {
+	if games, ok := m.connSessions[connectionID]; ok {
+		delete(games, gameID)
+		if len(games) == 0 {
+			delete(m.connSessions, connectionID)
+		}
+	}
+
+	if timer, ok := m.graceTimers[gameID]; ok {
+		timer.Stop()
+		delete(m.graceTimers, gameID)
+	}
+}
+
+// SessionsForConnection returns the IDs of the games played over a connection
+func (m *Manager) SessionsForConnection(connectionID uuid.UUID) []uuid.UUID {
+	m.connMu.Lock()
+	defer m.connMu.Unlock()
+
+	ids := make([]uuid.UUID, 0, len(m.connSessions[connectionID]))
+	for id := range m.connSessions[connectionID] {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// TransferSession moves the seat of a color to another connection of its
+// player. A game whose connection closed is kept alive, its pending
+// termination is cancelled
+func (m *Manager) TransferSession(gameID uuid.UUID, clr color.Color, connectionID uuid.UUID) (*game.Game, error) {
+	session, ok := m.GetSession(gameID)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", repository.ErrGameNotFound, gameID)
+	}
+
+	if session.Status() == game.StatusCompleted {
+		return nil, game.ErrGameOver
+	}
+
+	m.connMu.Lock()
+	// Removed meanwhile, the game must not be indexed again
+	if _, ok := m.GetSession(gameID); !ok {
+		m.connMu.Unlock()
+		return nil, fmt.Errorf("%w: %s", repository.ErrGameNotFound, gameID)
+	}
+
+	previous := session.Owner()
+	if session.Mode == game.ModeHumanVsHuman && clr != session.PlayerColor {
+		previous = session.Opponent()
+	}
+	m.unindexSessionLocked(previous, gameID)
+	session.Transfer(clr, connectionID)
+	m.indexSessionLocked(connectionID, gameID)
+	delete(m.absentSince[gameID], clr)
+	m.connMu.Unlock()
+
+	m.logger.Info("transferred game session",
+		zap.String("session_id", gameID.String()),
+		zap.String("from_connection_id", previous.String()),
+		zap.String("to_connection_id", connectionID.String()),
+	)
+	return session, nil
+}
+
+// terminateSessionsByConnectionID terminates the games of a closed
+// connection, once the disconnect grace period has passed when one is set
+func (m *Manager) terminateSessionsByConnectionID(connectionID string) {
+	connID, err := uuid.Parse(connectionID)
+	if err != nil {
+		m.logger.Error("Invalid connection ID in connection closed event", zap.Error(err))
+		return
+	}
+
+	ids := m.SessionsForConnection(connID)
+	if len(ids) == 0 {
+		return
+	}
+
+	m.logger.Info("Terminating sessions for connection",
+		zap.String("connection_id", connectionID),
+		zap.Int("sessions", len(ids)),
+		zap.Duration("grace", m.disconnectGrace),
+	)
+
+	for _, id := range ids {
+		// A game between players goes on while the opponent is still there
+		if session, ok := m.GetSession(id); ok && m.markAbsent(session, connID) {
+			continue
+		}
+
+		if m.disconnectGrace <= 0 {
+			m.RemoveSession(id)
+			continue
+		}
+
+		m.connMu.Lock()
+		if _, pending := m.graceTimers[id]; !pending {
+			gameID := id
+			m.graceTimers[id] = time.AfterFunc(m.disconnectGrace, func() {
+				m.expireGrace(gameID)
+			})
+		}
+		m.connMu.Unlock()
+	}
+}
+
+// expireGrace terminates a game whose player did not come back in time
+func (m *Manager) expireGrace(gameID uuid.UUID) {
+	m.connMu.Lock()
+	_, pending := m.graceTimers[gameID]
+	delete(m.graceTimers, gameID)
+	m.connMu.Unlock()
+
+	// Cancelled while the timer fired
+	if !pending {
+		return
+	}
+
+	m.logger.Info("Disconnect grace period expired", zap.String("session_id", gameID.String()))
+	m.RemoveSession(gameID)
+}
+
+// CancelTermination keeps a game whose connection closed alive, for a player
+// resuming it within the grace period. It reports whether a termination was pending
+func (m *Manager) CancelTermination(gameID uuid.UUID) bool {
+	m.connMu.Lock()
+	defer m.connMu.Unlock()
+
+	timer, ok := m.graceTimers[gameID]
+	if !ok {
+		return false
+	}
+
+	timer.Stop()
+	delete(m.graceTimers, gameID)
+	return true
+}
@@ -0,0 +1,221 @@
+package manager
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/color"
+	"github.com/tecu23/eng-server/pkg/engine"
+	"github.com/tecu23/eng-server/pkg/events"
+	"github.com/tecu23/eng-server/pkg/game"
+	"github.com/tecu23/eng-server/pkg/rating"
+	"github.com/tecu23/eng-server/pkg/repository"
+)
+
+// newTestManager creates a manager whose games are played by mock engines
+func newTestManager(t *testing.T, engines int, grace time.Duration) *Manager {
+	t.Helper()
+
+	logger := zap.NewNop()
+	pool := engine.NewEnginePool(engine.MockPrefix+"latency=1ms", engine.Scaling{MaxEngines: engines}, engine.Limits{}, nil, logger)
+	require.NoError(t, pool.Initialize())
+	t.Cleanup(pool.Shutdown)
+
+	return NewManager(
+		repository.NewInMemoryRepository(logger),
+		pool,
+		"",
+		nil,
+		nil,
+		game.AdjudicationRules{},
+		rating.Rating{},
+		game.HintSettings{},
+		0,
+		grace,
+		nil,
+		nil,
+		engine.NewProfiles(),
+		nil,
+		logger,
+		events.NewPublisher(logger),
+	)
+}
+
+// createTestSession creates a game played over a connection
+func createTestSession(t *testing.T, m *Manager, connectionID uuid.UUID) *game.Game {
+	t.Helper()
+
+	session, err := m.CreateSession(
+		context.Background(),
+		60000, 60000, 0, 0,
+		color.White,
+		"",
+		game.VariantStandard,
+		nil,
+		game.TimeManagement{},
+		"",
+		game.Pacing{},
+		"",
+		"",
+		false,
+		false,
+		0,
+		false,
+		connectionID,
+		m.publisher,
+	)
+	require.NoError(t, err)
+	return session
+}
+
+// indexed returns how many connections and pending terminations the manager tracks
+func indexed(m *Manager) (int, int) {
+	m.connMu.Lock()
+	defer m.connMu.Unlock()
+
+	return len(m.connSessions), len(m.graceTimers)
+}
+
+func TestSessionIndexConcurrentAddRemove(t *testing.T) {
+	const connections, perConnection = 4, 3
+	m := newTestManager(t, connections*perConnection, 0)
+
+	connIDs := make([]uuid.UUID, connections)
+	for i := range connIDs {
+		connIDs[i] = uuid.New()
+	}
+
+	var mu sync.Mutex
+	var sessions []*game.Game
+
+	var wg sync.WaitGroup
+	for _, connID := range connIDs {
+		for range perConnection {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				session := createTestSession(t, m, connID)
+				mu.Lock()
+				sessions = append(sessions, session)
+				mu.Unlock()
+
+				m.SessionsForConnection(connID)
+			}()
+		}
+	}
+	wg.Wait()
+
+	for _, connID := range connIDs {
+		assert.Len(t, m.SessionsForConnection(connID), perConnection)
+	}
+
+	// Every game is removed twice at once, like a termination racing the
+	// GAME_TERMINATED event, while the index is read
+	for _, session := range sessions {
+		for range 2 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				m.RemoveSession(session.ID)
+			}()
+		}
+	}
+	for _, connID := range connIDs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.SessionsForConnection(connID)
+		}()
+	}
+	wg.Wait()
+
+	for _, connID := range connIDs {
+		assert.Empty(t, m.SessionsForConnection(connID))
+	}
+
+	conns, timers := indexed(m)
+	assert.Zero(t, conns)
+	assert.Zero(t, timers)
+}
+
+func TestSessionIndexConcurrentTransferRemove(t *testing.T) {
+	const games = 64
+	m := newTestManager(t, games, 0)
+
+	from, to := uuid.New(), uuid.New()
+
+	sessions := make([]*game.Game, games)
+	for i := range sessions {
+		sessions[i] = createTestSession(t, m, from)
+	}
+
+	var wg sync.WaitGroup
+	for _, session := range sessions {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, _ = m.TransferSession(session.ID, color.White, to)
+		}()
+		go func() {
+			defer wg.Done()
+			m.RemoveSession(session.ID)
+		}()
+	}
+	wg.Wait()
+
+	// A game transferred while it was removed must not stay indexed
+	assert.Empty(t, m.SessionsForConnection(from))
+	assert.Empty(t, m.SessionsForConnection(to))
+
+	conns, _ := indexed(m)
+	assert.Zero(t, conns)
+}
+
+func TestReapConcurrentWithDisconnect(t *testing.T) {
+	const games = 6
+	m := newTestManager(t, games, time.Millisecond)
+
+	connID := uuid.New()
+	for range games {
+		createTestSession(t, m, connID)
+	}
+
+	// The connection closes while the reaper terminates its idle games and
+	// its player comes back for them
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		m.terminateSessionsByConnectionID(connID.String())
+	}()
+	go func() {
+		defer wg.Done()
+		m.reapIdle(0)
+	}()
+	go func() {
+		defer wg.Done()
+		for _, id := range m.SessionsForConnection(connID) {
+			m.CancelTermination(id)
+		}
+	}()
+	wg.Wait()
+
+	// Grace timers that were still pending remove nothing twice
+	time.Sleep(10 * time.Millisecond)
+
+	assert.Empty(t, m.ActiveSessions())
+	assert.Empty(t, m.SessionsForConnection(connID))
+
+	require.Eventually(t, func() bool {
+		conns, timers := indexed(m)
+		return conns == 0 && timers == 0
+	}, time.Second, 5*time.Millisecond)
+}
@@ -0,0 +1,31 @@
+package manager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailedExhibitionReturnsEngines(t *testing.T) {
+	m := newTestManager(t, 2, 0)
+
+	// Each attempt leases both engines of the pool, a leak would block the next one
+	for range 3 {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		_, err := m.CreateExhibition(ctx, 60000, 60000, 0, 0, "not a fen", nil, 0, false, uuid.New())
+		cancel()
+		require.Error(t, err)
+		require.NotErrorIs(t, err, context.DeadlineExceeded)
+	}
+
+	assert.Equal(t, 2, m.EnginePoolStats().Size)
+
+	// Returned engines are reset before they are available again
+	assert.Eventually(t, func() bool {
+		return m.EnginePoolStats().Available == 2
+	}, time.Second, 5*time.Millisecond)
+}
@@ -0,0 +1,88 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/analysis"
+	"github.com/tecu23/eng-server/pkg/game"
+)
+
+// errVariantAnalysis is returned when analyzing a game of another variant than standard chess
+var errVariantAnalysis = errors.New("only standard chess games can be analyzed")
+
+// AnalyzeGame queues the analysis of a game. A game still being played is
+// analyzed up to its current position ahead of the reports of finished games
+func (m *Manager) AnalyzeGame(gameID uuid.UUID) (messages.AnalysisJob, error) {
+	if session, ok := m.GetSession(gameID); ok && session.Status() != game.StatusCompleted {
+		if session.Variant != game.VariantStandard {
+			return messages.AnalysisJob{}, errVariantAnalysis
+		}
+
+		snap, err := session.Checkpoint()
+		if err != nil {
+			return messages.AnalysisJob{}, err
+		}
+
+		moves := make([]string, 0, len(snap.Moves))
+		for _, mv := range snap.Moves {
+			moves = append(moves, mv.UCI)
+		}
+		return m.analyzer.Submit(gameID.String(), snap.StartFEN, moves, analysis.PriorityLive)
+	}
+
+	record, err := m.repository.GetRecord(gameID.String())
+	if err != nil {
+		return messages.AnalysisJob{}, err
+	}
+	if record.Variant != string(game.VariantStandard) {
+		return messages.AnalysisJob{}, errVariantAnalysis
+	}
+
+	return m.analyzer.SubmitPGN(record.GameID, record.PGN, analysis.PriorityReport)
+}
+
+// AnalysisJob returns an analysis job
+func (m *Manager) AnalysisJob(id string) (messages.AnalysisJob, error) {
+	return m.analyzer.Job(id)
+}
+
+// AnalysisJobs lists the analysis jobs with the given status, every job when empty
+func (m *Manager) AnalysisJobs(status string) (messages.AnalysisJobsPayload, error) {
+	switch s := analysis.JobStatus(status); s {
+	case "", analysis.JobQueued, analysis.JobRunning, analysis.JobCompleted, analysis.JobFailed, analysis.JobCanceled:
+		return messages.AnalysisJobsPayload{Jobs: m.analyzer.Jobs(s)}, nil
+	default:
+		return messages.AnalysisJobsPayload{}, fmt.Errorf("status must be %s, %s, %s, %s or %s",
+			analysis.JobQueued, analysis.JobRunning, analysis.JobCompleted, analysis.JobFailed, analysis.JobCanceled)
+	}
+}
+
+// CancelAnalysisJob drops a waiting analysis job or stops a running one
+func (m *Manager) CancelAnalysisJob(id string) (messages.AnalysisJob, error) {
+	return m.analyzer.Cancel(id)
+}
+
+// AnalyzeBatch queues every game of a PGN file for analysis
+func (m *Manager) AnalyzeBatch(r io.Reader) (messages.AnalysisBatch, error) {
+	return m.analyzer.SubmitBatch(r)
+}
+
+// AnalysisBatch returns the progress of a batch
+func (m *Manager) AnalysisBatch(id string) (messages.AnalysisBatch, error) {
+	return m.analyzer.Batch(id)
+}
+
+// AnalysisBatchPGN returns the annotated PGN of a finished batch
+func (m *Manager) AnalysisBatchPGN(id string) (string, error) {
+	return m.analyzer.BatchPGN(id)
+}
+
+// CancelAnalysisBatch cancels the games of a batch not analyzed yet
+func (m *Manager) CancelAnalysisBatch(id string) (messages.AnalysisBatch, error) {
+	return m.analyzer.CancelBatch(id)
+}
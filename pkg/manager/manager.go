@@ -1,37 +1,103 @@
 package manager
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/corentings/chess/v2"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 
 	"github.com/tecu23/eng-server/internal/color"
 	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/analysis"
+	"github.com/tecu23/eng-server/pkg/book"
+	"github.com/tecu23/eng-server/pkg/certify"
 	"github.com/tecu23/eng-server/pkg/engine"
 	"github.com/tecu23/eng-server/pkg/events"
 	"github.com/tecu23/eng-server/pkg/game"
+	"github.com/tecu23/eng-server/pkg/rating"
 	"github.com/tecu23/eng-server/pkg/repository"
+	"github.com/tecu23/eng-server/pkg/tablebase"
 )
 
 type Manager struct {
-	repository *repository.InMemoryGameRepository
+	repository repository.Repository
 	enginePool *engine.Pool
 
+	engineFallback  game.EngineFallback    // What to do when an engine does not answer in time
+	openingBook     *book.Book             // Opening book available to games, nil when not configured
+	tablebase       *tablebase.Tablebase   // Adjudicates exhibition endgames, nil when not configured
+	adjudication    game.AdjudicationRules // Eval based adjudication of exhibition games
+	engineRating    rating.Rating          // Rating the engine is played as in rated games
+	hints           game.HintSettings      // Move hints available in human games
+	lagAllowance    time.Duration          // Most network lag credited to a player per move
+	disconnectGrace time.Duration          // How long the games of a closed connection are kept, and an absent opponent waited for
+	analyzer        *analysis.Analyzer     // Post-game analysis of human games
+	evalBar         *analysis.EvalBar      // Evaluates positions after every move, nil when disabled
+	profiles        *engine.Profiles       // Named engine options games and analyses may ask for
+	certifier       *certify.Signer        // Signs the results of finished games, nil when disabled
+
+	mu        sync.Mutex
+	analyses  map[uuid.UUID]*analysis.Live // Running live analyses
+	seeks     map[uuid.UUID]Seek           // Open seeks of the seek pool
+	positions *analysis.Positions          // Searches shared by the live analyses of a position
+
+	connMu       sync.Mutex
+	connSessions map[uuid.UUID]map[uuid.UUID]bool        // Connection IDs to the IDs of their games
+	graceTimers  map[uuid.UUID]*time.Timer               // Pending terminations of games whose connection closed
+	absentSince  map[uuid.UUID]map[color.Color]time.Time // When the players of games between players lost their connection
+
 	publisher *events.Publisher
 	logger    *zap.Logger
 }
 
 // NewManager creates a new manager with in-memory storage
 func NewManager(
-	repo *repository.InMemoryGameRepository,
+	repo repository.Repository,
 	engPool *engine.Pool,
+	engineFallback game.EngineFallback,
+	openingBook *book.Book,
+	tb *tablebase.Tablebase,
+	adjudication game.AdjudicationRules,
+	engineRating rating.Rating,
+	hints game.HintSettings,
+	lagAllowance time.Duration,
+	disconnectGrace time.Duration,
+	analyzer *analysis.Analyzer,
+	evalBar *analysis.EvalBar,
+	profiles *engine.Profiles,
+	certifier *certify.Signer,
 	logger *zap.Logger,
 	publisher *events.Publisher,
 ) *Manager {
 	manager := &Manager{
-		repository: repo,
-		enginePool: engPool,
-		logger:     logger,
-		publisher:  publisher,
+		repository:      repo,
+		enginePool:      engPool,
+		engineFallback:  engineFallback,
+		openingBook:     openingBook,
+		tablebase:       tb,
+		adjudication:    adjudication,
+		engineRating:    engineRating,
+		hints:           hints,
+		lagAllowance:    lagAllowance,
+		disconnectGrace: disconnectGrace,
+		analyzer:        analyzer,
+		evalBar:         evalBar,
+		profiles:        profiles,
+		certifier:       certifier,
+		analyses:        make(map[uuid.UUID]*analysis.Live),
+		seeks:           make(map[uuid.UUID]Seek),
+		positions:       analysis.NewPositions(engPool, publisher, logger),
+		connSessions:    make(map[uuid.UUID]map[uuid.UUID]bool),
+		graceTimers:     make(map[uuid.UUID]*time.Timer),
+		absentSince:     make(map[uuid.UUID]map[color.Color]time.Time),
+		logger:          logger,
+		publisher:       publisher,
 	}
 
 	// Set up event handlers
@@ -42,8 +108,10 @@ func NewManager(
 
 // setupEventHandlers sets up event handlers for the game manager
 func (m *Manager) setupEventHandlers() {
+	sub := m.publisher.NewSubscriber("manager", events.SubscriberOptions{})
+
 	// Handle connection closed events
-	m.publisher.Subscribe(events.EventConnectionClosed, func(event events.Event) {
+	sub.Subscribe(events.EventConnectionClosed, func(event events.Event) {
 		payload, ok := event.Payload.(map[string]string)
 		if !ok {
 			m.logger.Error("Invalid connection closed payload type")
@@ -54,10 +122,12 @@ func (m *Manager) setupEventHandlers() {
 
 		// Find all game sessions associated with this connection and terminate them
 		m.terminateSessionsByConnectionID(connectionID)
+		m.stopAnalysesByConnectionID(connectionID)
+		m.cancelSeeksByConnectionID(connectionID)
 	})
 
 	// Handle game terminated events
-	m.publisher.Subscribe(events.EventGameTerminated, func(event events.Event) {
+	sub.Subscribe(events.EventGameTerminated, func(event events.Event) {
 		// Remove the session from the manager
 		if event.GameID != "" {
 			gameID, err := uuid.Parse(event.GameID)
@@ -70,43 +140,174 @@ func (m *Manager) setupEventHandlers() {
 	})
 }
 
-// terminateSessionsByConnectionID finds and terminates all game sessions for a connection
-func (m *Manager) terminateSessionsByConnectionID(connectionID string) {
-	m.logger.Info("Terminating sessions for connection", zap.String("connection_id", connectionID))
+// CreateSession creates a new game session with the given parameters and registers it.
+// The session terminates when the context is cancelled
+func (m *Manager) CreateSession(
+	ctx context.Context,
+	whiteTime, blackTime, whiteIncrement, blackIncremenent int64,
+	turn color.Color,
+	fen string,
+	variant game.Variant,
+	bookOpts *book.Options,
+	timeManagement game.TimeManagement,
+	difficulty game.Difficulty,
+	pacing game.Pacing,
+	profile string,
+	userID string,
+	rated bool,
+	analyze bool,
+	seed int64,
+	deterministic bool,
+	connectionId uuid.UUID,
+	publisher *events.Publisher,
+) (*game.Game, error) {
+	sessionID := uuid.New()
+
+	if rated && userID == "" {
+		return nil, errors.New("rated games need a user")
+	}
 
-	activeGames, err := m.repository.ListActiveGames()
+	gameBook, err := m.bookFor(bookOpts)
 	if err != nil {
-		m.logger.Error(
-			"Could not terminate sessions for connection",
-			zap.String("connection_id", connectionID),
-			zap.Error(err),
-		)
+		return nil, err
 	}
 
-	for _, g := range activeGames {
-		if g.ConnectionID.String() == connectionID {
-			gameID := g.ID
-			go func() {
-				g.Terminate()
-				m.RemoveSession(gameID)
-			}()
-		}
+	options, err := m.profileOptions(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	eng, err := m.enginePool.GetEngine(ctx)
+	if err != nil {
+		m.logger.Error("failed to initialize engine", zap.Error(err))
+		return nil, err
+	}
+
+	// Restored by the pool once the engine is returned
+	if err := eng.SetOptions(options); err != nil {
+		m.enginePool.ReturnEngine(eng.ID.String())
+		return nil, err
+	}
+
+	tc := game.TimeControl{
+		WhiteTime:       whiteTime,
+		WhiteIncrement:  whiteIncrement,
+		BlackTime:       blackTime,
+		BlackIncrement:  blackIncremenent,
+		MovesPerControl: 40,
+		TimingMethod:    game.IncrementTiming,
+	}
+
+	params := game.CreateGameParams{
+		GameID:         sessionID,
+		StartPostion:   fen,
+		TimeControl:    tc,
+		TimeManagement: timeManagement,
+		Difficulty:     difficulty,
+		Pacing:         pacing,
+		PlayerColor:    turn,
+		Variant:        variant,
+		EngineFallback: m.engineFallback,
+		UserID:         userID,
+		Rated:          rated,
+		Ratings:        m.repository,
+		EngineRating:   m.engineRating,
+		Archive:        m.repository,
+		Telemetry:      m.repository,
+		Transcripts:    m.repository,
+		Hints:          m.hints,
+		Seed:           seed,
+		Deterministic:  deterministic,
+
+		LagCompensation: m.lagAllowance,
+		EnginePool:      m.enginePool,
+		Statuses:        m.repository,
+		Certifier:       m.certifier,
+	}
+
+	if analyze && m.analyzer != nil {
+		params.Analysis = m.analyzer
+	}
+	if m.analyzer != nil {
+		params.FairPlay = m.analyzer
+	}
+
+	if m.evalBar != nil {
+		params.EvalBar = m.evalBar
+	}
+
+	if gameBook != nil {
+		params.Book = gameBook
+		params.BookOptions = *bookOpts
+	}
+
+	session, err := game.CreateGame(ctx, params, connectionId, eng, publisher, m.logger)
+	if err != nil {
+		m.enginePool.ReturnEngine(eng.ID.String())
+		return nil, err
 	}
+
+	if err := m.repository.SaveGame(session); err != nil {
+		return nil, err
+	}
+
+	m.indexSession(connectionId, sessionID)
+
+	m.logger.Info("created new game session", zap.String("session_id", sessionID.String()))
+
+	// Publish game created event before the clock starts, so it is the first
+	// event of the game
+	publisher.Publish(events.Event{
+		Type:   events.EventGameCreated,
+		GameID: sessionID.String(),
+		Payload: messages.GameCreatedPayload{
+			GameID:        sessionID.String(),
+			InitialFEN:    fen,
+			Variant:       string(session.Variant),
+			WhiteTime:     whiteTime,
+			BlackTime:     blackTime,
+			CurrentTurn:   turn,
+			Difficulty:    string(difficulty),
+			Profile:       profile,
+			Seed:          session.Seed(),
+			Deterministic: session.Deterministic(),
+		},
+	})
+
+	// Start the clock and the session loop
+	session.Start()
+
+	return session, nil
 }
 
-// CreateSession creates a new game session with the given parameters and registers it.
-func (m *Manager) CreateSession(
-	whiteTime, blackTime, whiteIncrement, blackIncremenent int64,
-	turn color.Color,
+// CreateExhibition creates an engine vs engine game played entirely by two pooled engines.
+// The game terminates when the context is cancelled
+func (m *Manager) CreateExhibition(
+	ctx context.Context,
+	whiteTime, blackTime, whiteIncrement, blackIncrement int64,
 	fen string,
+	bookOpts *book.Options,
+	seed int64,
+	deterministic bool,
 	connectionId uuid.UUID,
-	publisher *events.Publisher,
 ) (*game.Game, error) {
 	sessionID := uuid.New()
 
-	eng, err := m.enginePool.GetEngine()
+	gameBook, err := m.bookFor(bookOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	white, err := m.enginePool.GetEngine(ctx)
+	if err != nil {
+		m.logger.Error("failed to initialize engine", zap.Error(err))
+		return nil, err
+	}
+
+	black, err := m.enginePool.GetEngine(ctx)
 	if err != nil {
 		m.logger.Error("failed to initialize engine", zap.Error(err))
+		m.enginePool.ReturnEngine(white.ID.String())
 		return nil, err
 	}
 
@@ -114,46 +315,420 @@ func (m *Manager) CreateSession(
 		WhiteTime:       whiteTime,
 		WhiteIncrement:  whiteIncrement,
 		BlackTime:       blackTime,
-		BlackIncrement:  blackIncremenent,
+		BlackIncrement:  blackIncrement,
 		MovesPerControl: 40,
 		TimingMethod:    game.IncrementTiming,
 	}
 
+	params := game.CreateGameParams{
+		GameID:         sessionID,
+		StartPostion:   fen,
+		TimeControl:    tc,
+		EngineFallback: m.engineFallback,
+		Mode:           game.ModeExhibition,
+		OpponentEngine: black,
+		Tablebase:      m.tablebase,
+		Adjudication:   m.adjudication,
+		Archive:        m.repository,
+		Telemetry:      m.repository,
+		Transcripts:    m.repository,
+		EnginePool:     m.enginePool,
+		Statuses:       m.repository,
+		Certifier:      m.certifier,
+		Seed:           seed,
+		Deterministic:  deterministic,
+	}
+
+	if m.evalBar != nil {
+		params.EvalBar = m.evalBar
+	}
+
+	if gameBook != nil {
+		params.Book = gameBook
+		params.BookOptions = *bookOpts
+	}
+
+	session, err := game.CreateGame(ctx, params, connectionId, white, m.publisher, m.logger)
+	if err != nil {
+		m.enginePool.ReturnEngine(white.ID.String())
+		m.enginePool.ReturnEngine(black.ID.String())
+		return nil, err
+	}
+
+	if err := m.repository.SaveGame(session); err != nil {
+		return nil, err
+	}
+
+	m.indexSession(connectionId, sessionID)
+
+	m.logger.Info("created new exhibition game", zap.String("session_id", sessionID.String()))
+
+	// Publish game created event before the engines start playing
+	m.publisher.Publish(events.Event{
+		Type:   events.EventGameCreated,
+		GameID: sessionID.String(),
+		Payload: messages.GameCreatedPayload{
+			GameID:        sessionID.String(),
+			InitialFEN:    fen,
+			Variant:       string(session.Variant),
+			WhiteTime:     whiteTime,
+			BlackTime:     blackTime,
+			CurrentTurn:   color.White,
+			Seed:          session.Seed(),
+			Deterministic: session.Deterministic(),
+		},
+	})
+
+	session.Start()
+
+	return session, nil
+}
+
+// Player is a player of a game between players
+type Player struct {
+	ConnectionID uuid.UUID
+	UserID       string
+}
+
+// CreatePlayerGame creates a game between two players, without an engine.
+// The owner plays the given color and the opponent the other one. The game
+// terminates when the context is cancelled
+func (m *Manager) CreatePlayerGame(
+	ctx context.Context,
+	tc messages.TimeControl,
+	fen string,
+	variant game.Variant,
+	owner Player,
+	clr color.Color,
+	opponent Player,
+	rated bool,
+) (*game.Game, error) {
+	sessionID := uuid.New()
+
+	if rated && (owner.UserID == "" || opponent.UserID == "") {
+		return nil, errors.New("rated games need a user for both players")
+	}
+
 	params := game.CreateGameParams{
 		GameID:       sessionID,
 		StartPostion: fen,
-		TimeControl:  tc,
+		TimeControl: game.TimeControl{
+			WhiteTime:       tc.WhiteTime,
+			WhiteIncrement:  tc.WhiteIncrement,
+			BlackTime:       tc.BlackTime,
+			BlackIncrement:  tc.BlackIncrement,
+			MovesPerControl: 40,
+			TimingMethod:    game.IncrementTiming,
+		},
+		PlayerColor:    clr,
+		Variant:        variant,
+		Mode:           game.ModeHumanVsHuman,
+		Opponent:       opponent.ConnectionID,
+		OpponentUserID: opponent.UserID,
+		UserID:         owner.UserID,
+		Rated:          rated,
+		Ratings:        m.repository,
+		Archive:        m.repository,
+		Statuses:       m.repository,
+		Certifier:      m.certifier,
+
+		LagCompensation: m.lagAllowance,
+	}
+
+	if m.analyzer != nil {
+		params.FairPlay = m.analyzer
+	}
+	if m.evalBar != nil {
+		params.EvalBar = m.evalBar
 	}
 
-	session, err := game.CreateGame(params, connectionId, eng, publisher, m.logger)
+	session, err := game.CreateGame(ctx, params, owner.ConnectionID, nil, m.publisher, m.logger)
+	if err != nil {
+		return nil, err
+	}
 
 	if err := m.repository.SaveGame(session); err != nil {
 		return nil, err
 	}
 
-	m.logger.Info("created new game session", zap.String("session_id", sessionID.String()))
+	m.indexSession(owner.ConnectionID, sessionID)
+	m.indexSession(opponent.ConnectionID, sessionID)
 
-	// Start sending periodic clock updates
-	go session.Clock.Start()
-	go session.StartClockUpdates()
-	go session.StartTimeoutMonitor()
+	m.logger.Info("created new game between players", zap.String("session_id", sessionID.String()))
 
-	// Publish game created event
-	publisher.Publish(events.Event{
+	white, black := owner.UserID, opponent.UserID
+	if clr == color.Black {
+		white, black = black, white
+	}
+
+	turn := color.Color(color.White)
+	if fields := strings.Fields(fen); len(fields) > 1 {
+		turn = color.Color(fields[1])
+	}
+
+	m.publisher.Publish(events.Event{
 		Type:   events.EventGameCreated,
 		GameID: sessionID.String(),
 		Payload: messages.GameCreatedPayload{
 			GameID:      sessionID.String(),
 			InitialFEN:  fen,
-			WhiteTime:   whiteTime,
-			BlackTime:   blackTime,
+			Variant:     string(session.Variant),
+			WhiteTime:   tc.WhiteTime,
+			BlackTime:   tc.BlackTime,
 			CurrentTurn: turn,
+			Seed:        session.Seed(),
+			Mode:        string(game.ModeHumanVsHuman),
+			White:       white,
+			Black:       black,
 		},
 	})
 
+	session.Start()
+
 	return session, nil
 }
 
+// bookFor returns the opening book to use for a game requesting the given options
+func (m *Manager) bookFor(opts *book.Options) (*book.Book, error) {
+	if opts == nil {
+		return nil, nil
+	}
+
+	if m.openingBook == nil {
+		return nil, errors.New("no opening book is configured on this server")
+	}
+
+	return m.openingBook, nil
+}
+
+// StartAnalysis starts a live analysis of a position streaming its best candidate lines
+func (m *Manager) StartAnalysis(fen string, multiPV, depth int, profile string, connectionID uuid.UUID) (*analysis.Live, error) {
+	if fen == "" {
+		fen = chess.StartingPosition().String()
+	}
+	if _, err := chess.FEN(fen); err != nil {
+		return nil, fmt.Errorf("invalid position: %w", err)
+	}
+
+	if multiPV == 0 {
+		multiPV = 1
+	}
+
+	options, err := m.profileOptions(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	live, err := m.positions.Start(fen, multiPV, depth, options, connectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.analyses[live.ID] = live
+	m.mu.Unlock()
+
+	// Forget the analysis once it stopped, by itself or on request
+	go func() {
+		<-live.Done()
+
+		m.mu.Lock()
+		delete(m.analyses, live.ID)
+		m.mu.Unlock()
+	}()
+
+	m.logger.Info("started live analysis", zap.String("analysis_id", live.ID.String()), zap.Bool("shared", live.Shared))
+	return live, nil
+}
+
+// StopAnalysis stops a live analysis started by the given connection
+func (m *Manager) StopAnalysis(id, connectionID uuid.UUID) error {
+	m.mu.Lock()
+	live, ok := m.analyses[id]
+	m.mu.Unlock()
+
+	if !ok || live.ConnectionID != connectionID {
+		return fmt.Errorf("%w: analysis %s", repository.ErrGameNotFound, id)
+	}
+
+	live.Stop()
+	return nil
+}
+
+// stopAnalysesByConnectionID stops the live analyses of a closed connection
+func (m *Manager) stopAnalysesByConnectionID(connectionID string) {
+	m.mu.Lock()
+	var stale []*analysis.Live
+	for _, live := range m.analyses {
+		if live.ConnectionID.String() == connectionID {
+			stale = append(stale, live)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, live := range stale {
+		go live.Stop()
+	}
+}
+
+// ListGames returns a page of the finished games matching the query
+func (m *Manager) ListGames(query messages.ListGamesPayload) (messages.GamesListPayload, error) {
+	filter, err := repository.NewGameFilter(query)
+	if err != nil {
+		return messages.GamesListPayload{}, err
+	}
+
+	games, next, err := m.repository.ListGames(filter, query.Cursor, query.Limit)
+	if err != nil {
+		return messages.GamesListPayload{}, err
+	}
+
+	return messages.GamesListPayload{Games: games, NextCursor: next}, nil
+}
+
+// EngineTranscript returns the UCI dialogue of the engines of a running or finished game
+func (m *Manager) EngineTranscript(gameID uuid.UUID) (messages.EngineTranscript, error) {
+	if session, ok := m.GetSession(gameID); ok {
+		return session.Transcript(), nil
+	}
+
+	return m.repository.GetTranscript(gameID.String())
+}
+
+// EngineStats aggregates the move telemetry of every engine configuration
+// matching the engine name and time range, empty values match everything
+func (m *Manager) EngineStats(engineName, since, until string) (messages.EngineStatsPayload, error) {
+	filter, err := repository.NewTelemetryFilter(engineName, since, until)
+	if err != nil {
+		return messages.EngineStatsPayload{}, err
+	}
+
+	return messages.EngineStatsPayload{Engines: m.repository.EngineStats(filter)}, nil
+}
+
+// FairPlayReports returns the fair play reports matching the filter, most recent first
+func (m *Manager) FairPlayReports(filter repository.FairPlayFilter, limit int) messages.FairPlayReportsPayload {
+	return messages.FairPlayReportsPayload{Reports: m.repository.ListFairPlay(filter, limit)}
+}
+
+// EngineLeaderboard ranks the engine configurations by their results over every game
+func (m *Manager) EngineLeaderboard() messages.EngineLeaderboardPayload {
+	return messages.EngineLeaderboardPayload{Engines: m.repository.EngineLeaderboard()}
+}
+
+// Explorer returns the opening explorer statistics of the position reached by
+// playing the moves, in UCI notation, from the FEN. The standard start
+// position is used when the FEN is empty
+func (m *Manager) Explorer(fen string, moves []string) (messages.ExplorerPayload, error) {
+	if fen == "" {
+		fen = chess.StartingPosition().String()
+	}
+
+	opt, err := chess.FEN(fen)
+	if err != nil {
+		return messages.ExplorerPayload{}, fmt.Errorf("invalid position: %w", err)
+	}
+	pos := chess.NewGame(opt).Position()
+
+	for _, uci := range moves {
+		next, ok := playLegal(pos, uci)
+		if !ok {
+			return messages.ExplorerPayload{}, fmt.Errorf("move %s is not legal", uci)
+		}
+		pos = next
+	}
+
+	return m.repository.Explorer(pos), nil
+}
+
+// playLegal plays a move in UCI notation when it is legal in the position
+func playLegal(pos *chess.Position, uci string) (*chess.Position, bool) {
+	for _, m := range pos.ValidMoves() {
+		if (chess.UCINotation{}).Encode(pos, &m) == uci {
+			return pos.Update(&m), true
+		}
+	}
+	return nil, false
+}
+
+// Puzzles returns the most recent puzzles matching the rating range,
+// difficulty and mate filter, empty values match everything
+func (m *Manager) Puzzles(minRating, maxRating, difficulty string, mate bool, limit int) (messages.PuzzlesListPayload, error) {
+	filter, err := repository.NewPuzzleFilter(minRating, maxRating, difficulty, mate)
+	if err != nil {
+		return messages.PuzzlesListPayload{}, err
+	}
+
+	return messages.PuzzlesListPayload{Puzzles: m.repository.ListPuzzles(filter, limit)}, nil
+}
+
+// Puzzle returns a puzzle by its ID
+func (m *Manager) Puzzle(id string) (messages.Puzzle, error) {
+	return m.repository.GetPuzzle(id)
+}
+
+// ActiveSessions returns the sessions in progress
+func (m *Manager) ActiveSessions() []*game.Game {
+	sessions, err := m.repository.ListActiveGames()
+	if err != nil {
+		m.logger.Error("Could not list active sessions", zap.Error(err))
+	}
+	return sessions
+}
+
+// Engines returns the metadata of the pooled engines
+func (m *Manager) Engines() []engine.Metadata {
+	return m.enginePool.Engines()
+}
+
+// EngineAllocation returns the Threads and Hash given to every pooled engine
+func (m *Manager) EngineAllocation() engine.Allocation {
+	return m.enginePool.Allocation()
+}
+
+// SetEngineAllocation overrides the Threads and Hash of the pooled engines
+// computed from the host resources
+func (m *Manager) SetEngineAllocation(override engine.AllocationOverride) (engine.Allocation, error) {
+	return m.enginePool.SetAllocationOverride(override)
+}
+
+// EngineProfiles returns the engine option profiles
+func (m *Manager) EngineProfiles() []engine.Profile {
+	return m.profiles.List()
+}
+
+// SetEngineProfile defines or replaces an engine option profile, its options
+// must be ones the pooled engines support
+func (m *Manager) SetEngineProfile(name string, options map[string]string) error {
+	if err := m.enginePool.ValidateOptions(options); err != nil {
+		return err
+	}
+	return m.profiles.Set(name, options)
+}
+
+// DeleteEngineProfile removes an engine option profile
+func (m *Manager) DeleteEngineProfile(name string) error {
+	return m.profiles.Delete(name)
+}
+
+// profileOptions returns the options of a profile, none for an empty name
+func (m *Manager) profileOptions(name string) (map[string]string, error) {
+	if name == "" {
+		return nil, nil
+	}
+	return m.profiles.Get(name)
+}
+
+// EnginePoolStats returns the occupancy of the engine pool
+func (m *Manager) EnginePoolStats() engine.PoolStats {
+	return m.enginePool.Stats()
+}
+
+// PingRepository reports whether the repository is reachable
+func (m *Manager) PingRepository() error {
+	return m.repository.Ping()
+}
+
 // GetSession returns a session by ID
 func (m *Manager) GetSession(id uuid.UUID) (*game.Game, bool) {
 	session, err := m.repository.GetGame(id)
@@ -167,11 +742,18 @@ func (m *Manager) GetSession(id uuid.UUID) (*game.Game, bool) {
 func (m *Manager) RemoveSession(id uuid.UUID) {
 	session, err := m.repository.GetGame(id)
 	if err != nil {
-		m.logger.Error("could not remove game session", zap.Error(err))
+		// Terminating a session removes it a second time once GAME_TERMINATED is handled
+		m.logger.Debug("game session already removed", zap.String("session_id", id.String()))
 		return
 	}
 
-	session.Terminate()
+	session.Close()
+
+	if err := m.repository.DeleteGame(id); err != nil {
+		m.logger.Debug("game session already removed", zap.String("session_id", id.String()))
+		return
+	}
+	m.unindexSession(session)
 
 	m.logger.Info("removed game session", zap.String("session_id", id.String()))
 }
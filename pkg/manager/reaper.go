@@ -0,0 +1,63 @@
+package manager
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/events"
+)
+
+// maxReapInterval bounds how long an idle game outlives its timeout
+const maxReapInterval = time.Minute
+
+// ReapIdle terminates the games nobody acted on for longer than the timeout,
+// until the context is done. Their engines go back to the pool
+func (m *Manager) ReapIdle(ctx context.Context, timeout time.Duration) {
+	ticker := time.NewTicker(min(timeout/2, maxReapInterval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.reapIdle(timeout)
+		}
+	}
+}
+
+// reapIdle terminates the games idle for longer than the timeout
+func (m *Manager) reapIdle(timeout time.Duration) {
+	sessions, err := m.repository.ListSessions()
+	if err != nil {
+		m.logger.Error("Could not list sessions to reap", zap.Error(err))
+		return
+	}
+
+	for _, session := range sessions {
+		idle := time.Since(session.LastActivity())
+		if idle < timeout {
+			continue
+		}
+
+		m.logger.Info("Reaping idle game session",
+			zap.String("session_id", session.ID.String()),
+			zap.Duration("idle", idle),
+		)
+
+		// Published before terminating, so clients learn why the game ended
+		m.publisher.Publish(events.Event{
+			Type:   events.EventGameAbandoned,
+			GameID: session.ID.String(),
+			Payload: messages.GameAbandonedPayload{
+				GameID:      session.ID.String(),
+				IdleSeconds: int64(idle.Seconds()),
+			},
+		})
+
+		m.RemoveSession(session.ID)
+	}
+}
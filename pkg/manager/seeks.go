@@ -0,0 +1,212 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/color"
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/game"
+	"github.com/tecu23/eng-server/pkg/rating"
+)
+
+// maxSeeksPerConnection bounds the seeks a connection keeps open
+const maxSeeksPerConnection = 3
+
+// Errors returned for seeks the pool refuses
+var (
+	ErrSeekNotFound = errors.New("seek not found")
+	ErrOwnSeek      = errors.New("cannot accept your own seek")
+	ErrSeekRating   = errors.New("your rating is outside the rating range of the seek")
+)
+
+// Seek is a game offered by a player to the other players, until one of them
+// accepts it
+type Seek struct {
+	ID           uuid.UUID
+	ConnectionID uuid.UUID
+	UserID       string
+	Rating       int // Rating of the user when the seek was opened
+	TimeControl  messages.TimeControl
+	Variant      game.Variant
+	FEN          string      // Start position of the game, a Chess960 position is drawn when the seek is opened
+	Color        color.Color // Color played by the user, random when empty
+	MinRating    int         // Lowest rating of an opponent, 0 for no bound
+	MaxRating    int         // Highest rating of an opponent, 0 for no bound
+	Rated        bool
+	CreatedAt    time.Time
+}
+
+// accepts reports whether a player of the given rating may accept the seek
+func (s Seek) accepts(r int) bool {
+	return (s.MinRating == 0 || r >= s.MinRating) && (s.MaxRating == 0 || r <= s.MaxRating)
+}
+
+// Payload returns the seek as sent to the clients
+func (s Seek) Payload() messages.SeekPayload {
+	return messages.SeekPayload{
+		SeekID:      s.ID.String(),
+		UserID:      s.UserID,
+		Rating:      s.Rating,
+		TimeControl: s.TimeControl,
+		Variant:     string(s.Variant),
+		Color:       s.Color,
+		MinRating:   s.MinRating,
+		MaxRating:   s.MaxRating,
+		Rated:       s.Rated,
+		CreatedAt:   s.CreatedAt,
+	}
+}
+
+// userRating returns the rating of a user rounded for the seek pool, the
+// default rating for new users
+func (m *Manager) userRating(userID string) int {
+	current, err := m.repository.GetRating(userID)
+	if err != nil {
+		if !errors.Is(err, rating.ErrNotFound) {
+			m.logger.Error("could not read user rating", zap.String("user_id", userID), zap.Error(err))
+		}
+		current = rating.Default()
+	}
+	return int(math.Round(current.Rating))
+}
+
+// CreateSeek opens a seek in the pool, tagged with the current rating of its user
+func (m *Manager) CreateSeek(seek Seek) (Seek, error) {
+	if seek.TimeControl.WhiteTime <= 0 || seek.TimeControl.BlackTime <= 0 {
+		return Seek{}, errors.New("seeks need a time control")
+	}
+	if seek.MinRating < 0 || seek.MaxRating < 0 || (seek.MaxRating > 0 && seek.MinRating > seek.MaxRating) {
+		return Seek{}, errors.New("invalid rating range")
+	}
+	if seek.Rated && seek.UserID == "" {
+		return Seek{}, errors.New("rated games need a user")
+	}
+
+	seek.ID = uuid.New()
+	seek.Rating = m.userRating(seek.UserID)
+	seek.CreatedAt = time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	open := 0
+	for _, s := range m.seeks {
+		if s.ConnectionID == seek.ConnectionID {
+			open++
+		}
+	}
+	if open >= maxSeeksPerConnection {
+		return Seek{}, fmt.Errorf("at most %d seeks may be open at once", maxSeeksPerConnection)
+	}
+
+	m.seeks[seek.ID] = seek
+
+	m.logger.Info("seek created", zap.String("seek_id", seek.ID.String()), zap.String("user_id", seek.UserID))
+	return seek, nil
+}
+
+// Seeks returns the open seeks, the oldest first
+func (m *Manager) Seeks() []Seek {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	seeks := make([]Seek, 0, len(m.seeks))
+	for _, s := range m.seeks {
+		seeks = append(seeks, s)
+	}
+	sort.Slice(seeks, func(i, j int) bool { return seeks[i].CreatedAt.Before(seeks[j].CreatedAt) })
+	return seeks
+}
+
+// CancelSeek closes a seek of a connection
+func (m *Manager) CancelSeek(id, connectionID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	seek, ok := m.seeks[id]
+	if !ok || seek.ConnectionID != connectionID {
+		return fmt.Errorf("%w: %s", ErrSeekNotFound, id)
+	}
+
+	delete(m.seeks, id)
+	return nil
+}
+
+// AcceptSeek closes a seek and creates its game, the user of the seek plays
+// its color against the accepting player. The game terminates when the
+// context is cancelled
+func (m *Manager) AcceptSeek(ctx context.Context, id uuid.UUID, accepter Player) (*game.Game, Seek, error) {
+	r := m.userRating(accepter.UserID)
+
+	m.mu.Lock()
+	seek, ok := m.seeks[id]
+	switch {
+	case !ok:
+		m.mu.Unlock()
+		return nil, Seek{}, fmt.Errorf("%w: %s", ErrSeekNotFound, id)
+	case seek.ConnectionID == accepter.ConnectionID || (seek.UserID != "" && seek.UserID == accepter.UserID):
+		m.mu.Unlock()
+		return nil, Seek{}, ErrOwnSeek
+	case !seek.accepts(r):
+		m.mu.Unlock()
+		return nil, Seek{}, ErrSeekRating
+	}
+	// Taken out of the pool first, so two players cannot accept it at once
+	delete(m.seeks, id)
+	m.mu.Unlock()
+
+	clr := seek.Color
+	if clr == "" {
+		clr = color.White
+		if rand.Intn(2) == 1 {
+			clr = color.Black
+		}
+	}
+
+	session, err := m.CreatePlayerGame(
+		ctx,
+		seek.TimeControl,
+		seek.FEN,
+		seek.Variant,
+		Player{ConnectionID: seek.ConnectionID, UserID: seek.UserID},
+		clr,
+		accepter,
+		seek.Rated,
+	)
+	if err != nil {
+		return nil, Seek{}, err
+	}
+
+	m.logger.Info("seek accepted",
+		zap.String("seek_id", id.String()),
+		zap.String("session_id", session.ID.String()),
+		zap.String("user_id", accepter.UserID),
+	)
+	return session, seek, nil
+}
+
+// cancelSeeksByConnectionID closes the seeks of a closed connection
+func (m *Manager) cancelSeeksByConnectionID(connectionID string) {
+	connID, err := uuid.Parse(connectionID)
+	if err != nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, seek := range m.seeks {
+		if seek.ConnectionID == connID {
+			delete(m.seeks, id)
+		}
+	}
+}
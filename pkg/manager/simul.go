@@ -0,0 +1,106 @@
+package manager
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/color"
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/events"
+	"github.com/tecu23/eng-server/pkg/game"
+)
+
+// CreateSimul creates the boards of a simul, played by the player of the
+// connection against a single pooled engine that moves on every board in turn.
+// The boards terminate when the context is cancelled
+func (m *Manager) CreateSimul(
+	ctx context.Context,
+	boards int,
+	whiteTime, blackTime, whiteIncrement, blackIncrement int64,
+	playerColor color.Color,
+	difficulty game.Difficulty,
+	userID string,
+	connectionId uuid.UUID,
+) (*game.Simul, []*game.Game, error) {
+	eng, err := m.enginePool.GetEngine(ctx)
+	if err != nil {
+		m.logger.Error("failed to initialize engine", zap.Error(err))
+		return nil, nil, err
+	}
+
+	simul, err := game.NewSimul(boards, eng, m.enginePool, m.logger)
+	if err != nil {
+		m.enginePool.ReturnEngine(eng.ID.String())
+		return nil, nil, err
+	}
+
+	tc := game.TimeControl{
+		WhiteTime:       whiteTime,
+		WhiteIncrement:  whiteIncrement,
+		BlackTime:       blackTime,
+		BlackIncrement:  blackIncrement,
+		MovesPerControl: 40,
+		TimingMethod:    game.IncrementTiming,
+	}
+
+	// Every board is set up before any starts, so a failure leaves nothing running
+	sessions := make([]*game.Game, 0, boards)
+	for range boards {
+		params := game.CreateGameParams{
+			GameID:         uuid.New(),
+			TimeControl:    tc,
+			Difficulty:     difficulty,
+			PlayerColor:    playerColor,
+			EngineFallback: m.engineFallback,
+			UserID:         userID,
+			Archive:        m.repository,
+			Telemetry:      m.repository,
+			Transcripts:    m.repository,
+			Simul:          simul,
+
+			LagCompensation: m.lagAllowance,
+			EnginePool:      m.enginePool,
+			Statuses:        m.repository,
+			Certifier:       m.certifier,
+		}
+
+		session, err := game.CreateGame(ctx, params, connectionId, eng, m.publisher, m.logger)
+		if err != nil {
+			m.enginePool.ReturnEngine(eng.ID.String())
+			return nil, nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	for _, session := range sessions {
+		if err := m.repository.SaveGame(session); err != nil {
+			m.logger.Error("could not save simul board", zap.String("session_id", session.ID.String()), zap.Error(err))
+		}
+
+		m.indexSession(connectionId, session.ID)
+
+		// Published before the clock starts, so GAME_CREATED is the first event of the board
+		m.publisher.Publish(events.Event{
+			Type:   events.EventGameCreated,
+			GameID: session.ID.String(),
+			Payload: messages.GameCreatedPayload{
+				GameID:      session.ID.String(),
+				Variant:     string(session.Variant),
+				WhiteTime:   whiteTime,
+				BlackTime:   blackTime,
+				CurrentTurn: playerColor,
+				Difficulty:  string(difficulty),
+			},
+		})
+
+		session.Start()
+	}
+
+	m.logger.Info("created new simul",
+		zap.String("simul_id", simul.ID.String()),
+		zap.Int("boards", boards))
+
+	return simul, sessions, nil
+}
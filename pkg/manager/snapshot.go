@@ -0,0 +1,201 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/events"
+	"github.com/tecu23/eng-server/pkg/game"
+	"github.com/tecu23/eng-server/pkg/repository"
+)
+
+// SnapshotSessions saves the games in progress to the store, to restore them
+// after a restart. Games that fail to checkpoint are logged and skipped
+func (m *Manager) SnapshotSessions(store repository.SnapshotStore) error {
+	sessions, err := m.repository.ListSessions()
+	if err != nil {
+		return err
+	}
+
+	snaps := make([]game.Snapshot, 0, len(sessions))
+	for _, session := range sessions {
+		if status := session.Status(); status != game.StatusActive && status != game.StatusPaused {
+			continue
+		}
+
+		// Both players would have to come back for a restored game between players
+		if session.Mode == game.ModeHumanVsHuman {
+			continue
+		}
+
+		snap, err := session.Checkpoint()
+		if err != nil {
+			m.logger.Error("Could not snapshot game session",
+				zap.String("session_id", session.ID.String()),
+				zap.Error(err),
+			)
+			continue
+		}
+		snaps = append(snaps, snap)
+	}
+
+	if err := store.SaveSnapshots(snaps); err != nil {
+		return err
+	}
+
+	m.logger.Info("Saved game snapshots", zap.Int("games", len(snaps)))
+	return nil
+}
+
+// RestoreSessions recreates the games saved by SnapshotSessions in a paused
+// state, publishing GAME_RESTORED for each. The store is cleared afterwards
+// so the same games are never restored twice. The games terminate when the
+// context is cancelled
+func (m *Manager) RestoreSessions(ctx context.Context, store repository.SnapshotStore) error {
+	snaps, err := store.LoadSnapshots()
+	if err != nil {
+		return err
+	}
+
+	restored := 0
+	for _, snap := range snaps {
+		session, err := m.restoreSession(ctx, snap)
+		if err != nil {
+			m.logger.Error("Could not restore game session",
+				zap.String("session_id", snap.GameID.String()),
+				zap.Error(err),
+			)
+			continue
+		}
+		restored++
+
+		turn := session.Clock.Sync().ActiveColor
+		m.publisher.Publish(events.Event{
+			Type:   events.EventGameRestored,
+			GameID: session.ID.String(),
+			Payload: messages.GameRestoredPayload{
+				GameID:      session.ID.String(),
+				Mode:        string(session.Mode),
+				Variant:     string(session.Variant),
+				FEN:         snap.FEN,
+				WhiteTime:   snap.WhiteTime,
+				BlackTime:   snap.BlackTime,
+				CurrentTurn: turn,
+			},
+		})
+	}
+
+	if len(snaps) > 0 {
+		m.logger.Info("Restored game sessions",
+			zap.Int("games", restored),
+			zap.Int("failed", len(snaps)-restored),
+		)
+	}
+
+	return store.ClearSnapshots()
+}
+
+// restoreSession recreates a single game from its snapshot with fresh engines
+func (m *Manager) restoreSession(ctx context.Context, snap game.Snapshot) (*game.Game, error) {
+	eng, err := m.enginePool.GetEngine(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	params := game.CreateGameParams{
+		EngineFallback: m.engineFallback,
+		Archive:        m.repository,
+		Telemetry:      m.repository,
+		Transcripts:    m.repository,
+		EnginePool:     m.enginePool,
+		Statuses:       m.repository,
+		Certifier:      m.certifier,
+	}
+
+	if snap.Mode == game.ModeExhibition {
+		black, err := m.enginePool.GetEngine(ctx)
+		if err != nil {
+			m.enginePool.ReturnEngine(eng.ID.String())
+			return nil, err
+		}
+
+		params.OpponentEngine = black
+		params.Tablebase = m.tablebase
+		params.Adjudication = m.adjudication
+	} else {
+		params.Ratings = m.repository
+		params.EngineRating = m.engineRating
+		params.Hints = m.hints
+		params.LagCompensation = m.lagAllowance
+
+		if snap.Analysis && m.analyzer != nil {
+			params.Analysis = m.analyzer
+		}
+		if m.analyzer != nil {
+			params.FairPlay = m.analyzer
+		}
+	}
+
+	if m.evalBar != nil {
+		params.EvalBar = m.evalBar
+	}
+
+	release := func() {
+		m.enginePool.ReturnEngine(eng.ID.String())
+		if params.OpponentEngine != nil {
+			m.enginePool.ReturnEngine(params.OpponentEngine.ID.String())
+		}
+	}
+
+	if snap.Book != nil {
+		gameBook, err := m.bookFor(snap.Book)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		if gameBook != nil {
+			params.Book = gameBook
+			params.BookOptions = *snap.Book
+		}
+	}
+
+	session, err := game.RestoreGame(ctx, params, snap, eng, m.publisher, m.logger)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	if err := m.repository.SaveGame(session); err != nil {
+		release()
+		return nil, err
+	}
+
+	session.StartPaused()
+
+	m.logger.Info("restored game session", zap.String("session_id", session.ID.String()))
+	return session, nil
+}
+
+// ResumeSession restarts a restored game for the connection taking it over
+func (m *Manager) ResumeSession(gameID, connectionID uuid.UUID) (*game.Game, error) {
+	session, ok := m.GetSession(gameID)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", repository.ErrGameNotFound, gameID)
+	}
+
+	if err := session.Resume(connectionID); err != nil {
+		return nil, err
+	}
+
+	m.indexSession(connectionID, gameID)
+
+	m.logger.Info("resumed game session",
+		zap.String("session_id", gameID.String()),
+		zap.String("connection_id", connectionID.String()),
+	)
+	return session, nil
+}
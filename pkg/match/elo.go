@@ -0,0 +1,49 @@
+package match
+
+import "math"
+
+// EloEstimate is the Elo difference implied by a match score with its 95% confidence margin
+type EloEstimate struct {
+	Difference float64 // Elo difference of engine A over engine B
+	Margin     float64 // Half width of the 95% confidence interval
+}
+
+// maxEloDifference bounds the estimate when one side scored every point
+const maxEloDifference = 1200
+
+// EstimateElo computes the Elo difference and its error bar from a W/D/L score
+func EstimateElo(wins, draws, losses int) EloEstimate {
+	games := float64(wins + draws + losses)
+	if games == 0 {
+		return EloEstimate{}
+	}
+
+	score := (float64(wins) + float64(draws)/2) / games
+
+	// Standard deviation of the per-game score
+	variance := (float64(wins)*math.Pow(1-score, 2) +
+		float64(draws)*math.Pow(0.5-score, 2) +
+		float64(losses)*math.Pow(score, 2)) / games
+	stdErr := math.Sqrt(variance / games)
+
+	lower := eloFromScore(score - 1.96*stdErr)
+	upper := eloFromScore(score + 1.96*stdErr)
+
+	return EloEstimate{
+		Difference: eloFromScore(score),
+		Margin:     (upper - lower) / 2,
+	}
+}
+
+// eloFromScore converts an expected score into an Elo difference
+func eloFromScore(score float64) float64 {
+	if score <= 0 {
+		return -maxEloDifference
+	}
+	if score >= 1 {
+		return maxEloDifference
+	}
+
+	elo := -400 * math.Log10(1/score-1)
+	return math.Max(-maxEloDifference, math.Min(maxEloDifference, elo))
+}
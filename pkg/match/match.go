@@ -0,0 +1,138 @@
+// Package match runs multi-game matches between two configured engines
+package match
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/game"
+)
+
+// Status represents the lifecycle of a match
+type Status string
+
+// All the possible match statuses
+const (
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	StatusAborted   Status = "aborted"
+)
+
+// Config describes a match between two engines
+type Config struct {
+	EngineA     string           // Name of the first configured engine
+	EngineB     string           // Name of the second configured engine
+	Games       int              // Number of games to play
+	TimeControl game.TimeControl // Time control used for every game
+	Openings    []string         // Optional opening suite as FENs, each opening is played with both colors
+
+	// Adjudication overrides the server adjudication rules, nil uses the defaults
+	Adjudication *game.AdjudicationRules
+}
+
+// GameRecord is the outcome of a single game of the match
+type GameRecord struct {
+	GameID  string
+	White   string
+	Black   string
+	Opening string
+	Result  string
+}
+
+// Match is a running or finished match
+type Match struct {
+	ID     uuid.UUID
+	Config Config
+
+	mu         sync.RWMutex
+	status     Status
+	wins       int
+	draws      int
+	losses     int
+	games      []GameRecord
+	startedAt  time.Time
+	finishedAt time.Time
+}
+
+// newMatch creates a running match for the given configuration
+func newMatch(cfg Config) *Match {
+	return &Match{
+		ID:        uuid.New(),
+		Config:    cfg,
+		status:    StatusRunning,
+		startedAt: time.Now(),
+	}
+}
+
+// record adds the outcome of a game, scored from engine A's point of view
+func (m *Match) record(rec GameRecord) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.games = append(m.games, rec)
+
+	switch rec.Result {
+	case "1/2-1/2":
+		m.draws++
+	case "1-0":
+		if rec.White == m.Config.EngineA {
+			m.wins++
+		} else {
+			m.losses++
+		}
+	case "0-1":
+		if rec.Black == m.Config.EngineA {
+			m.wins++
+		} else {
+			m.losses++
+		}
+	}
+}
+
+// finish marks the match with its final status
+func (m *Match) finish(status Status) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.status = status
+	m.finishedAt = time.Now()
+}
+
+// Summary returns a snapshot of the match and its Elo estimate
+func (m *Match) Summary() messages.MatchProgressPayload {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	games := make([]messages.MatchGamePayload, 0, len(m.games))
+	for _, g := range m.games {
+		games = append(games, messages.MatchGamePayload{
+			GameID:  g.GameID,
+			White:   g.White,
+			Black:   g.Black,
+			Opening: g.Opening,
+			Result:  g.Result,
+		})
+	}
+
+	elo := EstimateElo(m.wins, m.draws, m.losses)
+
+	return messages.MatchProgressPayload{
+		MatchID:     m.ID.String(),
+		EngineA:     m.Config.EngineA,
+		EngineB:     m.Config.EngineB,
+		Status:      string(m.status),
+		TotalGames:  m.Config.Games,
+		PlayedGames: len(m.games),
+		Wins:        m.wins,
+		Draws:       m.draws,
+		Losses:      m.losses,
+		Elo:         elo.Difference,
+		EloMargin:   elo.Margin,
+		Games:       games,
+		StartedAt:   m.startedAt,
+		FinishedAt:  m.finishedAt,
+	}
+}
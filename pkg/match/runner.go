@@ -0,0 +1,245 @@
+package match
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/pkg/engine"
+	"github.com/tecu23/eng-server/pkg/events"
+	"github.com/tecu23/eng-server/pkg/game"
+	"github.com/tecu23/eng-server/pkg/tablebase"
+)
+
+// Store persists matches and the games played in them
+type Store interface {
+	SaveGame(game *game.Game) error
+	SaveMatch(match *Match) error
+}
+
+// gameGracePeriod is added to the total clock time before a game is considered stuck
+const gameGracePeriod = time.Minute
+
+// Runner schedules matches between configured engines
+type Runner struct {
+	engines map[string]string // Maps engine names to their executable path
+	limits  engine.Limits
+	options map[string]string // UCI options set on every engine
+
+	tablebase    *tablebase.Tablebase   // Adjudicates endgames, nil when not configured
+	adjudication game.AdjudicationRules // Default adjudication for matches that do not set their own
+
+	mu      sync.Mutex
+	running map[uuid.UUID]*Match
+
+	store     Store
+	publisher *events.Publisher
+	logger    *zap.Logger
+}
+
+// NewRunner creates a new match runner for the given engines
+func NewRunner(
+	engines map[string]string,
+	limits engine.Limits,
+	options map[string]string,
+	tb *tablebase.Tablebase,
+	adjudication game.AdjudicationRules,
+	store Store,
+	publisher *events.Publisher,
+	logger *zap.Logger,
+) *Runner {
+	return &Runner{
+		engines:      engines,
+		limits:       limits,
+		options:      options,
+		tablebase:    tb,
+		adjudication: adjudication,
+		running:      make(map[uuid.UUID]*Match),
+		store:        store,
+		publisher:    publisher,
+		logger:       logger,
+	}
+}
+
+// Start validates the configuration and plays the match in the background
+func (r *Runner) Start(cfg Config) (*Match, error) {
+	if _, ok := r.engines[cfg.EngineA]; !ok {
+		return nil, fmt.Errorf("unknown engine %q", cfg.EngineA)
+	}
+	if _, ok := r.engines[cfg.EngineB]; !ok {
+		return nil, fmt.Errorf("unknown engine %q", cfg.EngineB)
+	}
+	if cfg.Games <= 0 {
+		return nil, errors.New("a match needs at least one game")
+	}
+	if cfg.TimeControl.WhiteTime <= 0 || cfg.TimeControl.BlackTime <= 0 {
+		return nil, errors.New("a match needs a positive time control")
+	}
+
+	if cfg.Adjudication == nil {
+		cfg.Adjudication = &r.adjudication
+	}
+
+	m := newMatch(cfg)
+	if err := r.store.SaveMatch(m); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.running[m.ID] = m
+	r.mu.Unlock()
+
+	r.logger.Info("match started",
+		zap.String("match_id", m.ID.String()),
+		zap.String("engine_a", cfg.EngineA),
+		zap.String("engine_b", cfg.EngineB),
+		zap.Int("games", cfg.Games))
+
+	go r.run(m)
+
+	return m, nil
+}
+
+// run plays every game of the match, alternating colors
+func (r *Runner) run(m *Match) {
+	defer func() {
+		r.mu.Lock()
+		delete(r.running, m.ID)
+		r.mu.Unlock()
+	}()
+
+	r.publishProgress(m)
+
+	for i := 0; i < m.Config.Games; i++ {
+		rec, err := r.playGame(m, i)
+		if err != nil {
+			r.logger.Error("match aborted", zap.String("match_id", m.ID.String()), zap.Error(err))
+			m.finish(StatusAborted)
+			r.persist(m)
+			return
+		}
+
+		m.record(rec)
+		r.persist(m)
+	}
+
+	m.finish(StatusCompleted)
+	r.persist(m)
+
+	summary := m.Summary()
+	r.logger.Info("match completed",
+		zap.String("match_id", summary.MatchID),
+		zap.Int("wins", summary.Wins),
+		zap.Int("draws", summary.Draws),
+		zap.Int("losses", summary.Losses),
+		zap.Float64("elo", summary.Elo),
+		zap.Float64("elo_margin", summary.EloMargin))
+}
+
+// playGame plays the game with the given index and returns its outcome
+func (r *Runner) playGame(m *Match, index int) (GameRecord, error) {
+	white, black := m.Config.EngineA, m.Config.EngineB
+	if index%2 == 1 {
+		white, black = black, white
+	}
+
+	var opening string
+	if len(m.Config.Openings) > 0 {
+		// Each opening is played twice so both engines get each side
+		opening = m.Config.Openings[(index/2)%len(m.Config.Openings)]
+	}
+
+	whiteEngine, err := engine.NewUCIEngine(r.engines[white], r.limits, r.logger)
+	if err != nil {
+		return GameRecord{}, fmt.Errorf("could not start %s: %w", white, err)
+	}
+
+	blackEngine, err := engine.NewUCIEngine(r.engines[black], r.limits, r.logger)
+	if err != nil {
+		whiteEngine.Close()
+		return GameRecord{}, fmt.Errorf("could not start %s: %w", black, err)
+	}
+
+	for _, eng := range []*engine.UCIEngine{whiteEngine, blackEngine} {
+		if err := eng.SetOptions(r.options); err != nil {
+			whiteEngine.Close()
+			blackEngine.Close()
+			return GameRecord{}, err
+		}
+	}
+
+	params := game.CreateGameParams{
+		GameID:         uuid.New(),
+		StartPostion:   opening,
+		TimeControl:    m.Config.TimeControl,
+		EngineFallback: game.FallbackForfeit,
+		Mode:           game.ModeExhibition,
+		OpponentEngine: blackEngine,
+		Tablebase:      r.tablebase,
+		Adjudication:   *m.Config.Adjudication,
+	}
+
+	session, err := game.CreateGame(context.Background(), params, uuid.Nil, whiteEngine, r.publisher, r.logger)
+	if err != nil {
+		whiteEngine.Close()
+		blackEngine.Close()
+		return GameRecord{}, err
+	}
+	defer session.Close()
+
+	if err := r.store.SaveGame(session); err != nil {
+		return GameRecord{}, err
+	}
+
+	rec := GameRecord{
+		GameID:  session.ID.String(),
+		White:   white,
+		Black:   black,
+		Opening: opening,
+		Result:  "*",
+	}
+
+	session.Start()
+
+	tc := m.Config.TimeControl
+	timeout := time.Duration(tc.WhiteTime+tc.BlackTime)*time.Millisecond + gameGracePeriod
+
+	select {
+	case <-session.Finished():
+		state, err := session.State()
+		if err != nil {
+			return GameRecord{}, err
+		}
+		rec.Result = state.Result
+
+	case <-session.Terminated():
+		return GameRecord{}, errors.New("game was terminated before it finished")
+
+	case <-time.After(timeout):
+		r.logger.Warn("match game did not finish in time", zap.String("game_id", rec.GameID))
+	}
+
+	return rec, nil
+}
+
+// persist saves the match and publishes its progress
+func (r *Runner) persist(m *Match) {
+	if err := r.store.SaveMatch(m); err != nil {
+		r.logger.Error("could not save match", zap.String("match_id", m.ID.String()), zap.Error(err))
+	}
+
+	r.publishProgress(m)
+}
+
+// publishProgress notifies subscribers about the state of the match
+func (r *Runner) publishProgress(m *Match) {
+	r.publisher.Publish(events.Event{
+		Type:    events.EventMatchProgress,
+		Payload: m.Summary(),
+	})
+}
@@ -0,0 +1,143 @@
+// Package rating tracks player ratings with the Glicko-2 system
+package rating
+
+import (
+	"errors"
+	"math"
+)
+
+// Glicko-2 system constants
+const (
+	DefaultRating     = 1500.0
+	DefaultDeviation  = 350.0
+	DefaultVolatility = 0.06
+
+	// tau constrains the change in volatility over time
+	tau = 0.5
+	// glickoScale converts between the Glicko and the Glicko-2 scales
+	glickoScale = 173.7178
+	// convergence is the tolerance of the volatility iteration
+	convergence = 0.000001
+)
+
+// ErrNotFound is returned when a user has no rating yet
+var ErrNotFound = errors.New("rating not found")
+
+// Rating is the Glicko-2 rating of a player
+type Rating struct {
+	Rating     float64 `json:"rating"`
+	Deviation  float64 `json:"deviation"`
+	Volatility float64 `json:"volatility"`
+	Games      int     `json:"games"` // Rated games played
+}
+
+// Default returns the rating of a player without rated games
+func Default() Rating {
+	return Rating{
+		Rating:     DefaultRating,
+		Deviation:  DefaultDeviation,
+		Volatility: DefaultVolatility,
+	}
+}
+
+// Fixed returns the rating of a player whose strength is known, like an engine
+// at a given level, with a small deviation so it barely moves
+func Fixed(rating float64) Rating {
+	return Rating{
+		Rating:     rating,
+		Deviation:  50,
+		Volatility: DefaultVolatility,
+	}
+}
+
+// Result is one rated game from the point of view of the rated player
+type Result struct {
+	Opponent Rating
+	Score    float64 // 1 for a win, 0.5 for a draw and 0 for a loss
+}
+
+// Store persists the ratings of users
+type Store interface {
+	// GetRating returns the rating of a user, ErrNotFound when there is none
+	GetRating(userID string) (Rating, error)
+	// UpdateRating applies update to the rating of a user, starting from
+	// Default for new users, and returns the stored rating
+	UpdateRating(userID string, update func(Rating) Rating) (Rating, error)
+}
+
+// Update computes the rating after a rating period with the given games
+func (r Rating) Update(results ...Result) Rating {
+	mu := (r.Rating - DefaultRating) / glickoScale
+	phi := r.Deviation / glickoScale
+
+	// A period without games only increases the deviation
+	if len(results) == 0 {
+		r.Deviation = math.Min(DefaultDeviation, math.Sqrt(phi*phi+r.Volatility*r.Volatility)*glickoScale)
+		return r
+	}
+
+	var invV, sum float64
+	for _, result := range results {
+		muJ := (result.Opponent.Rating - DefaultRating) / glickoScale
+		phiJ := result.Opponent.Deviation / glickoScale
+
+		g := 1 / math.Sqrt(1+3*phiJ*phiJ/(math.Pi*math.Pi))
+		e := 1 / (1 + math.Exp(-g*(mu-muJ)))
+
+		invV += g * g * e * (1 - e)
+		sum += g * (result.Score - e)
+	}
+
+	v := 1 / invV
+	delta := v * sum
+
+	sigma := newVolatility(phi, v, delta, r.Volatility)
+
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	newPhi := 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	newMu := mu + newPhi*newPhi*sum
+
+	return Rating{
+		Rating:     newMu*glickoScale + DefaultRating,
+		Deviation:  math.Min(DefaultDeviation, newPhi*glickoScale),
+		Volatility: sigma,
+		Games:      r.Games + len(results),
+	}
+}
+
+// newVolatility finds the new volatility with the Illinois algorithm
+func newVolatility(phi, v, delta, sigma float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-d)/(2*d*d) - (x-a)/(tau*tau)
+	}
+
+	lower := a
+	var upper float64
+	if delta*delta > phi*phi+v {
+		upper = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*tau) < 0 {
+			k++
+		}
+		upper = a - k*tau
+	}
+
+	fLower, fUpper := f(lower), f(upper)
+	for math.Abs(upper-lower) > convergence {
+		c := lower + (lower-upper)*fLower/(fUpper-fLower)
+		fC := f(c)
+
+		if fC*fUpper <= 0 {
+			lower, fLower = upper, fUpper
+		} else {
+			fLower /= 2
+		}
+		upper, fUpper = c, fC
+	}
+
+	return math.Exp(lower / 2)
+}
@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/tecu23/eng-server/internal/messages"
+)
+
+// EngineTotals sums what an engine configuration did over every game it played
+type EngineTotals struct {
+	Engine    string    `json:"engine"`
+	Config    string    `json:"config,omitempty"`
+	Games     int       `json:"games"`
+	Wins      int       `json:"wins"`
+	Draws     int       `json:"draws"`
+	Losses    int       `json:"losses"`
+	Moves     int       `json:"moves"`
+	Depth     int64     `json:"depth"`   // Depth of every move, summed
+	TimeMs    int64     `json:"time_ms"` // Search time of every move, summed
+	Crashes   int       `json:"crashes"`
+	Timeouts  int       `json:"timeouts"`
+	FirstGame time.Time `json:"first_game"`
+	LastGame  time.Time `json:"last_game"`
+}
+
+// engineKey identifies an engine configuration
+type engineKey struct{ engine, config string }
+
+// standing returns the leaderboard entry of the totals
+func (t EngineTotals) standing() messages.EngineStanding {
+	s := messages.EngineStanding{
+		Engine:    t.Engine,
+		Config:    t.Config,
+		Games:     t.Games,
+		Wins:      t.Wins,
+		Draws:     t.Draws,
+		Losses:    t.Losses,
+		Moves:     t.Moves,
+		Crashes:   t.Crashes,
+		Timeouts:  t.Timeouts,
+		FirstGame: t.FirstGame,
+		LastGame:  t.LastGame,
+	}
+	if t.Games > 0 {
+		s.Score = (float64(t.Wins) + float64(t.Draws)/2) / float64(t.Games)
+	}
+	if t.Moves > 0 {
+		s.AvgDepth = float64(t.Depth) / float64(t.Moves)
+		s.AvgTimeMs = float64(t.TimeMs) / float64(t.Moves)
+	}
+	return s
+}
+
+// EngineStatsStore keeps the engine totals across restarts
+type EngineStatsStore interface {
+	SaveEngineTotals(totals []EngineTotals) error
+	LoadEngineTotals() ([]EngineTotals, error)
+}
+
+// FileEngineStatsStore keeps the engine totals in a JSON file
+type FileEngineStatsStore struct {
+	path string
+}
+
+var _ EngineStatsStore = (*FileEngineStatsStore)(nil)
+
+// NewFileEngineStatsStore creates an engine statistics store writing to the given file
+func NewFileEngineStatsStore(path string) *FileEngineStatsStore {
+	return &FileEngineStatsStore{path: path}
+}
+
+// SaveEngineTotals replaces the stored totals
+func (s *FileEngineStatsStore) SaveEngineTotals(totals []EngineTotals) error {
+	data, err := json.Marshal(totals)
+	if err != nil {
+		return err
+	}
+
+	return writeAtomic(s.path, data)
+}
+
+// LoadEngineTotals returns the stored totals, none when the file does not exist
+func (s *FileEngineStatsStore) LoadEngineTotals() ([]EngineTotals, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var totals []EngineTotals
+	if err := json.Unmarshal(data, &totals); err != nil {
+		return nil, fmt.Errorf("invalid engine statistics file %s: %w", s.path, err)
+	}
+
+	return totals, nil
+}
+
+// PersistEngineStats loads the engine totals kept by the store and saves them
+// back after every finished game. Moves of games still in progress are saved
+// with the next finished game
+func (r *InMemoryGameRepository) PersistEngineStats(store EngineStatsStore) error {
+	totals, err := store.LoadEngineTotals()
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, t := range totals {
+		r.engineTotals[engineKey{t.Engine, t.Config}] = &t
+	}
+	r.engineStore = store
+
+	return nil
+}
+
+// totalsOf returns the totals of an engine configuration, the caller holds the write lock
+func (r *InMemoryGameRepository) totalsOf(engine, config string) *EngineTotals {
+	k := engineKey{engine, config}
+	t, ok := r.engineTotals[k]
+	if !ok {
+		t = &EngineTotals{Engine: engine, Config: config}
+		r.engineTotals[k] = t
+	}
+	return t
+}
+
+// SaveEngineResult adds the outcome of a finished game to the totals of its engine
+func (r *InMemoryGameRepository) SaveEngineResult(result messages.EngineResult) error {
+	r.mu.Lock()
+	t := r.totalsOf(result.Engine, result.Config)
+
+	t.Games++
+	switch result.Score {
+	case 1:
+		t.Wins++
+	case 0:
+		t.Losses++
+	default:
+		t.Draws++
+	}
+	t.Crashes += result.Crashes
+	t.Timeouts += result.Timeouts
+	if t.FirstGame.IsZero() {
+		t.FirstGame = result.Time
+	}
+	t.LastGame = result.Time
+
+	store := r.engineStore
+	r.mu.Unlock()
+
+	if store == nil {
+		return nil
+	}
+
+	// Saves are serialized so an older copy of the totals never replaces a newer one
+	r.engineStoreMu.Lock()
+	defer r.engineStoreMu.Unlock()
+
+	r.mu.RLock()
+	totals := make([]EngineTotals, 0, len(r.engineTotals))
+	for _, t := range r.engineTotals {
+		totals = append(totals, *t)
+	}
+	r.mu.RUnlock()
+
+	return store.SaveEngineTotals(totals)
+}
+
+// EngineLeaderboard ranks the engine configurations that finished a game by
+// score, then by games played, then by name
+func (r *InMemoryGameRepository) EngineLeaderboard() []messages.EngineStanding {
+	r.mu.RLock()
+	standings := make([]messages.EngineStanding, 0, len(r.engineTotals))
+	for _, t := range r.engineTotals {
+		if t.Games > 0 {
+			standings = append(standings, t.standing())
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Games != b.Games {
+			return a.Games > b.Games
+		}
+		if a.Engine != b.Engine {
+			return a.Engine < b.Engine
+		}
+		return a.Config < b.Config
+	})
+
+	return standings
+}
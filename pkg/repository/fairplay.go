@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"github.com/tecu23/eng-server/internal/messages"
+)
+
+// FairPlayFilter selects fair play reports, zero fields match every report
+type FairPlayFilter struct {
+	UserID  string
+	GameID  string
+	Flagged bool // Only the reports of flagged games
+}
+
+// matches reports whether a report passes the filter
+func (f FairPlayFilter) matches(r messages.FairPlayReport) bool {
+	if f.UserID != "" && r.UserID != f.UserID {
+		return false
+	}
+
+	if f.GameID != "" && r.GameID != f.GameID {
+		return false
+	}
+
+	return !f.Flagged || r.Flagged
+}
+
+// SaveFairPlay stores the fair play report of the player of a game
+func (r *InMemoryGameRepository) SaveFairPlay(report messages.FairPlayReport) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.fairPlay = append(r.fairPlay, report)
+	return nil
+}
+
+// ListFairPlay returns the fair play reports matching the filter, most recent first
+func (r *InMemoryGameRepository) ListFairPlay(filter FairPlayFilter, limit int) []messages.FairPlayReport {
+	if limit <= 0 || limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	reports := make([]messages.FairPlayReport, 0, min(limit, len(r.fairPlay)))
+	for i := len(r.fairPlay) - 1; i >= 0 && len(reports) < limit; i-- {
+		if filter.matches(r.fairPlay[i]) {
+			reports = append(reports, r.fairPlay[i])
+		}
+	}
+	return reports
+}
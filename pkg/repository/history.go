@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/color"
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/explorer"
+	"github.com/tecu23/eng-server/pkg/game"
+)
+
+// Results a game history can be filtered on, from the point of view of the user
+const (
+	ResultWin  = "win"
+	ResultLoss = "loss"
+	ResultDraw = "draw"
+)
+
+// MaxPageSize bounds the number of games returned in a single page
+const MaxPageSize = 100
+
+// ErrInvalidCursor is returned for a pagination cursor the repository did not issue
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// GameFilter selects games from the history, zero fields match every game
+type GameFilter struct {
+	UserID      string
+	Result      string      // win, loss or draw for the user
+	Color       color.Color // Color played by the user
+	Engine      string      // Name of an engine that played the game
+	Since       time.Time   // Games ended at or after this time
+	Until       time.Time   // Games ended before this time
+	TimeControl string      // e.g. 300+2
+}
+
+// matches reports whether a record passes the filter
+func (f GameFilter) matches(record messages.GameRecord) bool {
+	if f.UserID != "" && record.UserID != f.UserID && record.OpponentID != f.UserID {
+		return false
+	}
+
+	clr := f.userColor(record)
+	if f.Color != "" && clr != f.Color {
+		return false
+	}
+
+	if f.Result != "" && userResult(record.Result, clr) != f.Result {
+		return false
+	}
+
+	if f.Engine != "" {
+		found := false
+		for _, name := range record.Engines {
+			if strings.EqualFold(name, f.Engine) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if !f.Since.IsZero() && record.EndedAt.Before(f.Since) {
+		return false
+	}
+
+	if !f.Until.IsZero() && !record.EndedAt.Before(f.Until) {
+		return false
+	}
+
+	return f.TimeControl == "" || record.TimeControl == f.TimeControl
+}
+
+// userColor returns the color the user of the filter played in a game, the
+// color of the player who created it when the filter has no user
+func (f GameFilter) userColor(record messages.GameRecord) color.Color {
+	if f.UserID != "" && record.UserID != f.UserID && record.OpponentID == f.UserID {
+		return record.PlayerColor.Opp()
+	}
+	return record.PlayerColor
+}
+
+// userResult returns the result of a game for the user who played a color
+func userResult(result string, clr color.Color) string {
+	switch {
+	case result == "1/2-1/2":
+		return ResultDraw
+	case result == "1-0" && clr == color.White,
+		result == "0-1" && clr == color.Black:
+		return ResultWin
+	case result == "1-0" || result == "0-1":
+		return ResultLoss
+	default:
+		return ""
+	}
+}
+
+// SaveRecord adds a finished game to the history
+func (r *InMemoryGameRepository) SaveRecord(record messages.GameRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.records = append(r.records, record)
+
+	if record.Variant == string(game.VariantStandard) {
+		if err := r.explorer.AddPGN(record.PGN); err != nil && !errors.Is(err, explorer.ErrNotIndexed) {
+			r.logger.Warn("could not add game to the opening explorer", zap.String("game_id", record.GameID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// GetRecord returns the record of a finished game
+func (r *InMemoryGameRepository) GetRecord(gameID string) (messages.GameRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for i := len(r.records) - 1; i >= 0; i-- {
+		if r.records[i].GameID == gameID {
+			return r.records[i], nil
+		}
+	}
+
+	return messages.GameRecord{}, fmt.Errorf("%w: %s", ErrGameNotFound, gameID)
+}
+
+// Explorer returns the opening explorer statistics of a position
+func (r *InMemoryGameRepository) Explorer(pos *chess.Position) messages.ExplorerPayload {
+	return r.explorer.Lookup(pos)
+}
+
+// SaveAnalysis stores the analysis report of a finished game with its record
+func (r *InMemoryGameRepository) SaveAnalysis(gameID string, report messages.AnalysisReportPayload) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := len(r.records) - 1; i >= 0; i-- {
+		if r.records[i].GameID == gameID {
+			r.records[i].Analysis = &report
+			return nil
+		}
+	}
+
+	return errors.New("game record not found")
+}
+
+// ListGames returns a page of the games matching the filter, most recent
+// first, and the cursor of the next page, empty on the last page
+func (r *InMemoryGameRepository) ListGames(
+	filter GameFilter,
+	cursor string,
+	limit int,
+) ([]messages.GameRecord, string, error) {
+	if limit <= 0 || limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	// Records are appended as games end, so the cursor is the position of the
+	// last returned record counted from the oldest one
+	start := -1
+	if cursor != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			return nil, "", ErrInvalidCursor
+		}
+		if _, err := fmt.Sscanf(string(raw), "%d", &start); err != nil || start < 0 {
+			return nil, "", ErrInvalidCursor
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if start < 0 || start > len(r.records) {
+		start = len(r.records)
+	}
+
+	games := make([]messages.GameRecord, 0, limit)
+	for i := start - 1; i >= 0; i-- {
+		if !filter.matches(r.records[i]) {
+			continue
+		}
+
+		if len(games) == limit {
+			next := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprint(i + 1)))
+			return games, next, nil
+		}
+		games = append(games, r.records[i])
+	}
+
+	return games, "", nil
+}
+
+// NewGameFilter validates a game history query and builds its filter
+func NewGameFilter(query messages.ListGamesPayload) (GameFilter, error) {
+	filter := GameFilter{
+		UserID:      query.UserID,
+		Engine:      query.Engine,
+		TimeControl: query.TimeControl,
+	}
+
+	switch query.Result {
+	case "", ResultWin, ResultLoss, ResultDraw:
+		filter.Result = query.Result
+	default:
+		return filter, fmt.Errorf("invalid result filter %q", query.Result)
+	}
+
+	switch color.Color(query.Color) {
+	case "", color.White, color.Black:
+		filter.Color = color.Color(query.Color)
+	default:
+		return filter, fmt.Errorf("invalid color filter %q", query.Color)
+	}
+
+	var err error
+	if query.Since != "" {
+		if filter.Since, err = time.Parse(time.RFC3339, query.Since); err != nil {
+			return filter, fmt.Errorf("invalid since filter: %w", err)
+		}
+	}
+	if query.Until != "" {
+		if filter.Until, err = time.Parse(time.RFC3339, query.Until); err != nil {
+			return filter, fmt.Errorf("invalid until filter: %w", err)
+		}
+	}
+
+	return filter, nil
+}
@@ -7,21 +7,50 @@ import (
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/explorer"
 	"github.com/tecu23/eng-server/pkg/game"
+	"github.com/tecu23/eng-server/pkg/match"
+	"github.com/tecu23/eng-server/pkg/rating"
 )
 
 // InMemoryGameRepository in an in-memory implementation of GameRepository
 type InMemoryGameRepository struct {
-	games  map[uuid.UUID]*game.Game
-	mu     sync.RWMutex
-	logger *zap.Logger
+	games    map[uuid.UUID]*game.Game
+	statuses map[uuid.UUID]game.GameStatus // Status of each game, kept up to date by the sessions
+	matches  map[uuid.UUID]*match.Match
+	ratings  map[string]rating.Rating
+	records  []messages.GameRecord // Finished games in the order they ended
+	puzzles  []messages.Puzzle     // Puzzles in the order they were found
+	explorer *explorer.Tree        // Opening tree of the finished standard games
+
+	transcripts     map[string]messages.EngineTranscript // Engine transcripts of finished games by game ID
+	transcriptOrder []string                             // Game IDs of the transcripts, the oldest first
+
+	engineTotals  map[engineKey]*EngineTotals // Totals of every engine configuration since the first game
+	engineStore   EngineStatsStore            // Keeps the totals across restarts, nil keeps them in memory only
+	engineStoreMu sync.Mutex                  // Serializes the saves of the totals
+
+	fairPlay []messages.FairPlayReport // Fair play reports in the order the games were screened
+
+	telemetry []messages.MoveTelemetry // Engine moves in the order they were played
+	mu        sync.RWMutex
+	logger    *zap.Logger
 }
 
 // NewInMemoryRepository creates a new in-memory repository
 func NewInMemoryRepository(logger *zap.Logger) *InMemoryGameRepository {
 	return &InMemoryGameRepository{
-		games:  make(map[uuid.UUID]*game.Game),
-		logger: logger,
+		games:    make(map[uuid.UUID]*game.Game),
+		statuses: make(map[uuid.UUID]game.GameStatus),
+		matches:  make(map[uuid.UUID]*match.Match),
+		ratings:  make(map[string]rating.Rating),
+		explorer: explorer.New(),
+		logger:   logger,
+
+		transcripts: make(map[string]messages.EngineTranscript),
+
+		engineTotals: make(map[engineKey]*EngineTotals),
 	}
 }
 
@@ -31,6 +60,20 @@ func (r *InMemoryGameRepository) SaveGame(game *game.Game) error {
 	defer r.mu.Unlock()
 
 	r.games[game.ID] = game
+	r.statuses[game.ID] = game.Status()
+	return nil
+}
+
+// UpdateStatus records a status transition of a game
+func (r *InMemoryGameRepository) UpdateStatus(id uuid.UUID, status game.GameStatus) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.games[id]; !ok {
+		return ErrGameNotFound
+	}
+
+	r.statuses[id] = status
 	return nil
 }
 
@@ -41,23 +84,121 @@ func (r *InMemoryGameRepository) GetGame(id uuid.UUID) (*game.Game, error) {
 
 	game, ok := r.games[id]
 	if !ok {
-		return nil, errors.New("game not found")
+		return nil, ErrGameNotFound
 	}
 
 	return game, nil
 }
 
-// ListActiveGames returns all active games
-func (r *InMemoryGameRepository) ListActiveGames() ([]*game.Game, error) {
+// ListSessions returns every game held by the repository, whatever its status
+func (r *InMemoryGameRepository) ListSessions() ([]*game.Game, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sessions := make([]*game.Game, 0, len(r.games))
+	for _, g := range r.games {
+		sessions = append(sessions, g)
+	}
+
+	return sessions, nil
+}
+
+// DeleteGame removes a game from the repository
+func (r *InMemoryGameRepository) DeleteGame(id uuid.UUID) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	if _, ok := r.games[id]; !ok {
+		return ErrGameNotFound
+	}
+
+	delete(r.games, id)
+	delete(r.statuses, id)
+	return nil
+}
+
+// ListActiveGames returns all active games
+func (r *InMemoryGameRepository) ListActiveGames() ([]*game.Game, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	var activeGames []*game.Game
-	for _, g := range r.games {
-		if g.Status == game.StatusActive {
+	for id, g := range r.games {
+		if r.statuses[id] == game.StatusActive {
 			activeGames = append(activeGames, g)
 		}
 	}
 
 	return activeGames, nil
 }
+
+// Ping always succeeds, the games are held in memory
+func (r *InMemoryGameRepository) Ping() error {
+	return nil
+}
+
+// SaveMatch saves a match to the repository
+func (r *InMemoryGameRepository) SaveMatch(m *match.Match) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.matches[m.ID] = m
+	return nil
+}
+
+// GetMatch retrieves a match by ID
+func (r *InMemoryGameRepository) GetMatch(id uuid.UUID) (*match.Match, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	m, ok := r.matches[id]
+	if !ok {
+		return nil, errors.New("match not found")
+	}
+
+	return m, nil
+}
+
+// ListMatches returns all matches
+func (r *InMemoryGameRepository) ListMatches() ([]*match.Match, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matches := make([]*match.Match, 0, len(r.matches))
+	for _, m := range r.matches {
+		matches = append(matches, m)
+	}
+
+	return matches, nil
+}
+
+// GetRating retrieves the rating of a user
+func (r *InMemoryGameRepository) GetRating(userID string) (rating.Rating, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	userRating, ok := r.ratings[userID]
+	if !ok {
+		return rating.Rating{}, rating.ErrNotFound
+	}
+
+	return userRating, nil
+}
+
+// UpdateRating atomically updates the rating of a user, new users start from the default rating
+func (r *InMemoryGameRepository) UpdateRating(
+	userID string,
+	update func(rating.Rating) rating.Rating,
+) (rating.Rating, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	userRating, ok := r.ratings[userID]
+	if !ok {
+		userRating = rating.Default()
+	}
+
+	userRating = update(userRating)
+	r.ratings[userID] = userRating
+	return userRating, nil
+}
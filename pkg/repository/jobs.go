@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/tecu23/eng-server/pkg/analysis"
+)
+
+// FileJobStore keeps the analysis jobs in a JSON file
+type FileJobStore struct {
+	path string
+}
+
+var _ analysis.JobStore = (*FileJobStore)(nil)
+
+// NewFileJobStore creates a job store writing to the given file
+func NewFileJobStore(path string) *FileJobStore {
+	return &FileJobStore{path: path}
+}
+
+// SaveJobs replaces the stored jobs
+func (s *FileJobStore) SaveJobs(jobs []analysis.Job) error {
+	data, err := json.Marshal(jobs)
+	if err != nil {
+		return err
+	}
+
+	return writeAtomic(s.path, data)
+}
+
+// LoadJobs returns the stored jobs, none when the file does not exist
+func (s *FileJobStore) LoadJobs() ([]analysis.Job, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []analysis.Job
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("invalid analysis job file %s: %w", s.path, err)
+	}
+
+	return jobs, nil
+}
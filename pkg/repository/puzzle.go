@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/tecu23/eng-server/internal/messages"
+)
+
+// ErrPuzzleNotFound is returned for a puzzle the repository does not hold
+var ErrPuzzleNotFound = errors.New("puzzle not found")
+
+// PuzzleFilter selects puzzles, zero fields match every puzzle
+type PuzzleFilter struct {
+	MinRating  int
+	MaxRating  int
+	Difficulty string // easy, medium or hard
+	Mate       bool   // Only puzzles whose solution forces mate
+}
+
+// matches reports whether a puzzle passes the filter
+func (f PuzzleFilter) matches(p messages.Puzzle) bool {
+	if f.MinRating > 0 && p.Rating < f.MinRating {
+		return false
+	}
+
+	if f.MaxRating > 0 && p.Rating > f.MaxRating {
+		return false
+	}
+
+	if f.Mate && !p.Mate {
+		return false
+	}
+
+	return f.Difficulty == "" || p.Difficulty == f.Difficulty
+}
+
+// NewPuzzleFilter validates a puzzle query and builds its filter
+func NewPuzzleFilter(minRating, maxRating, difficulty string, mate bool) (PuzzleFilter, error) {
+	filter := PuzzleFilter{Difficulty: difficulty, Mate: mate}
+
+	var err error
+	if minRating != "" {
+		if filter.MinRating, err = strconv.Atoi(minRating); err != nil {
+			return filter, fmt.Errorf("invalid min_rating: %w", err)
+		}
+	}
+	if maxRating != "" {
+		if filter.MaxRating, err = strconv.Atoi(maxRating); err != nil {
+			return filter, fmt.Errorf("invalid max_rating: %w", err)
+		}
+	}
+
+	switch difficulty {
+	case "", "easy", "medium", "hard":
+	default:
+		return filter, errors.New("difficulty must be easy, medium or hard")
+	}
+
+	return filter, nil
+}
+
+// SavePuzzles stores the puzzles found in a game
+func (r *InMemoryGameRepository) SavePuzzles(puzzles []messages.Puzzle) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.puzzles = append(r.puzzles, puzzles...)
+	return nil
+}
+
+// ListPuzzles returns the puzzles matching the filter, most recent first
+func (r *InMemoryGameRepository) ListPuzzles(filter PuzzleFilter, limit int) []messages.Puzzle {
+	if limit <= 0 || limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	puzzles := make([]messages.Puzzle, 0, min(limit, len(r.puzzles)))
+	for i := len(r.puzzles) - 1; i >= 0 && len(puzzles) < limit; i-- {
+		if filter.matches(r.puzzles[i]) {
+			puzzles = append(puzzles, r.puzzles[i])
+		}
+	}
+	return puzzles
+}
+
+// GetPuzzle returns a puzzle by its ID
+func (r *InMemoryGameRepository) GetPuzzle(id string) (messages.Puzzle, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, p := range r.puzzles {
+		if p.ID == id {
+			return p, nil
+		}
+	}
+	return messages.Puzzle{}, ErrPuzzleNotFound
+}
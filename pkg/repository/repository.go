@@ -0,0 +1,50 @@
+// Package repository stores games, matches, ratings and the game history
+package repository
+
+import (
+	"errors"
+
+	"github.com/corentings/chess/v2"
+	"github.com/google/uuid"
+
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/game"
+	"github.com/tecu23/eng-server/pkg/rating"
+)
+
+// ErrGameNotFound is returned for a game the repository does not hold
+var ErrGameNotFound = errors.New("game not found")
+
+// GameRepository stores the game sessions and tracks their status
+type GameRepository interface {
+	SaveGame(g *game.Game) error
+	GetGame(id uuid.UUID) (*game.Game, error)
+	ListActiveGames() ([]*game.Game, error)
+	ListSessions() ([]*game.Game, error) // Every game, whatever its status
+	DeleteGame(id uuid.UUID) error
+	UpdateStatus(id uuid.UUID, status game.GameStatus) error
+}
+
+// Repository is everything the game manager persists
+type Repository interface {
+	GameRepository
+	game.Archive
+	game.Telemetry
+	game.TranscriptStore
+	rating.Store
+
+	ListGames(filter GameFilter, cursor string, limit int) ([]messages.GameRecord, string, error)
+	GetRecord(gameID string) (messages.GameRecord, error)
+	GetTranscript(gameID string) (messages.EngineTranscript, error)
+	EngineStats(filter TelemetryFilter) []messages.EngineStats
+	EngineLeaderboard() []messages.EngineStanding
+	SavePuzzles(puzzles []messages.Puzzle) error
+	ListPuzzles(filter PuzzleFilter, limit int) []messages.Puzzle
+	GetPuzzle(id string) (messages.Puzzle, error)
+	ListFairPlay(filter FairPlayFilter, limit int) []messages.FairPlayReport
+	Explorer(pos *chess.Position) messages.ExplorerPayload
+
+	Ping() error // Reports whether the storage behind the repository is reachable
+}
+
+var _ Repository = (*InMemoryGameRepository)(nil)
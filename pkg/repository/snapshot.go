@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/tecu23/eng-server/pkg/game"
+)
+
+// SnapshotStore keeps the snapshots of the games in progress across restarts
+type SnapshotStore interface {
+	SaveSnapshots(snaps []game.Snapshot) error
+	LoadSnapshots() ([]game.Snapshot, error)
+	ClearSnapshots() error
+}
+
+// FileSnapshotStore keeps the snapshots in a JSON file
+type FileSnapshotStore struct {
+	path string
+}
+
+var _ SnapshotStore = (*FileSnapshotStore)(nil)
+
+// NewFileSnapshotStore creates a snapshot store writing to the given file
+func NewFileSnapshotStore(path string) *FileSnapshotStore {
+	return &FileSnapshotStore{path: path}
+}
+
+// SaveSnapshots replaces the stored snapshots
+func (s *FileSnapshotStore) SaveSnapshots(snaps []game.Snapshot) error {
+	data, err := json.Marshal(snaps)
+	if err != nil {
+		return err
+	}
+
+	return writeAtomic(s.path, data)
+}
+
+// writeAtomic replaces a file. The data is written next to its destination
+// and renamed so a crash never leaves a partial file
+func writeAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadSnapshots returns the stored snapshots, none when the file does not exist
+func (s *FileSnapshotStore) LoadSnapshots() ([]game.Snapshot, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var snaps []game.Snapshot
+	if err := json.Unmarshal(data, &snaps); err != nil {
+		return nil, fmt.Errorf("invalid snapshot file %s: %w", s.path, err)
+	}
+
+	return snaps, nil
+}
+
+// ClearSnapshots removes the stored snapshots once they have been restored
+func (s *FileSnapshotStore) ClearSnapshots() error {
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tecu23/eng-server/internal/messages"
+)
+
+// TelemetryFilter selects the engine moves aggregated into statistics, zero fields match every move
+type TelemetryFilter struct {
+	Engine string    // Name of the engine
+	Since  time.Time // Moves played at or after this time
+	Until  time.Time // Moves played before this time
+}
+
+// matches reports whether a move passes the filter
+func (f TelemetryFilter) matches(t messages.MoveTelemetry) bool {
+	if f.Engine != "" && !strings.EqualFold(t.Engine, f.Engine) {
+		return false
+	}
+
+	if !f.Since.IsZero() && t.Time.Before(f.Since) {
+		return false
+	}
+
+	return f.Until.IsZero() || t.Time.Before(f.Until)
+}
+
+// SaveTelemetry stores the search statistics of an engine move
+func (r *InMemoryGameRepository) SaveTelemetry(telemetry messages.MoveTelemetry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.telemetry = append(r.telemetry, telemetry)
+
+	t := r.totalsOf(telemetry.Engine, telemetry.Config)
+	t.Moves++
+	t.Depth += int64(telemetry.Depth)
+	t.TimeMs += telemetry.TimeMs
+	return nil
+}
+
+// EngineStats aggregates the moves matching the filter per engine and
+// configuration, sorted by engine name then configuration
+func (r *InMemoryGameRepository) EngineStats(filter TelemetryFilter) []messages.EngineStats {
+	type key struct{ engine, config string }
+
+	type totals struct {
+		moves, evals, late int
+		depth, evalSum     int64
+		nodes, timeMs      int64
+		first, last        time.Time
+	}
+
+	r.mu.RLock()
+	groups := make(map[key]*totals)
+	for _, t := range r.telemetry {
+		if !filter.matches(t) {
+			continue
+		}
+
+		k := key{t.Engine, t.Config}
+		g, ok := groups[k]
+		if !ok {
+			g = &totals{first: t.Time}
+			groups[k] = g
+		}
+
+		g.moves++
+		g.depth += int64(t.Depth)
+		g.nodes += t.Nodes
+		g.timeMs += t.TimeMs
+		if t.Eval != nil {
+			g.evals++
+			g.evalSum += int64(*t.Eval)
+		}
+		if t.LateChange {
+			g.late++
+		}
+		g.last = t.Time
+	}
+	r.mu.RUnlock()
+
+	stats := make([]messages.EngineStats, 0, len(groups))
+	for k, g := range groups {
+		s := messages.EngineStats{
+			Engine:         k.engine,
+			Config:         k.config,
+			Moves:          g.moves,
+			AvgDepth:       float64(g.depth) / float64(g.moves),
+			AvgNodes:       float64(g.nodes) / float64(g.moves),
+			AvgTimeMs:      float64(g.timeMs) / float64(g.moves),
+			LateChangeRate: float64(g.late) / float64(g.moves),
+			FirstMove:      g.first,
+			LastMove:       g.last,
+		}
+		if g.timeMs > 0 {
+			s.NodesPerSecond = float64(g.nodes) * 1000 / float64(g.timeMs)
+		}
+		if g.evals > 0 {
+			s.AvgEval = float64(g.evalSum) / float64(g.evals)
+		}
+		stats = append(stats, s)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Engine != stats[j].Engine {
+			return stats[i].Engine < stats[j].Engine
+		}
+		return stats[i].Config < stats[j].Config
+	})
+
+	return stats
+}
+
+// NewTelemetryFilter validates an engine statistics query and builds its filter
+func NewTelemetryFilter(engine, since, until string) (TelemetryFilter, error) {
+	filter := TelemetryFilter{Engine: engine}
+
+	var err error
+	if since != "" {
+		if filter.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			return filter, fmt.Errorf("invalid since filter: %w", err)
+		}
+	}
+	if until != "" {
+		if filter.Until, err = time.Parse(time.RFC3339, until); err != nil {
+			return filter, fmt.Errorf("invalid until filter: %w", err)
+		}
+	}
+
+	return filter, nil
+}
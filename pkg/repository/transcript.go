@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/tecu23/eng-server/internal/messages"
+)
+
+// maxTranscripts bounds the engine transcripts of finished games kept in memory
+const maxTranscripts = 200
+
+// SaveTranscript stores the engine transcript of a finished game, forgetting
+// the oldest one once the limit is reached
+func (r *InMemoryGameRepository) SaveTranscript(transcript messages.EngineTranscript) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.transcripts[transcript.GameID]; !ok {
+		r.transcriptOrder = append(r.transcriptOrder, transcript.GameID)
+	}
+	r.transcripts[transcript.GameID] = transcript
+
+	for len(r.transcriptOrder) > maxTranscripts {
+		delete(r.transcripts, r.transcriptOrder[0])
+		r.transcriptOrder = r.transcriptOrder[1:]
+	}
+	return nil
+}
+
+// GetTranscript returns the engine transcript of a finished game
+func (r *InMemoryGameRepository) GetTranscript(gameID string) (messages.EngineTranscript, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	transcript, ok := r.transcripts[gameID]
+	if !ok {
+		return messages.EngineTranscript{}, fmt.Errorf("%w: %s", ErrGameNotFound, gameID)
+	}
+	return transcript, nil
+}
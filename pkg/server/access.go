@@ -0,0 +1,63 @@
+package server
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/auth"
+	"github.com/tecu23/eng-server/internal/messages"
+)
+
+// eventPermissions is the permission each client event requires, events not
+// listed only concern the connection itself
+var eventPermissions = map[string]auth.Permission{
+	"CREATE_SESSION":    auth.PermPlay,
+	"CREATE_EXHIBITION": auth.PermPlay,
+	"CREATE_SIMUL":      auth.PermPlay,
+	"SPECTATE":          auth.PermRead,
+	"ADMIN_SUBSCRIBE":   auth.PermAdmin,
+	"START_MATCH":       auth.PermAdmin,
+	"RESYNC":            auth.PermRead,
+	"LOBBY_SUBSCRIBE":   auth.PermRead,
+	"CREATE_TOURNAMENT": auth.PermPlay,
+	"JOIN_TOURNAMENT":   auth.PermPlay,
+	"LEAVE_TOURNAMENT":  auth.PermPlay,
+	"START_TOURNAMENT":  auth.PermPlay,
+	"CREATE_SEEK":       auth.PermPlay,
+	"ACCEPT_SEEK":       auth.PermPlay,
+	"CANCEL_SEEK":       auth.PermPlay,
+	"LIST_SEEKS":        auth.PermRead,
+	"MAKE_MOVE":         auth.PermPlay,
+	"RESIGN":            auth.PermPlay,
+	"PREMOVE":           auth.PermPlay,
+	"TAKEBACK_REQUEST":  auth.PermPlay,
+	"RESUME_GAME":       auth.PermPlay,
+	"CLAIM_GAME":        auth.PermPlay,
+	"CLAIM_VICTORY":     auth.PermPlay,
+	"REQUEST_HINT":      auth.PermPlay,
+	"ANNOTATE":          auth.PermAnnotate,
+	"CHAT_MESSAGE":      auth.PermPlay,
+	"GET_GAME_STATE":    auth.PermRead,
+	"START_ANALYSIS":    auth.PermAnalyze,
+	"STOP_ANALYSIS":     auth.PermAnalyze,
+	"LIST_GAMES":        auth.PermRead,
+}
+
+// can reports whether the role of the connection grants the permission
+func (c *Connection) can(p auth.Permission) bool {
+	return c.role.Can(p)
+}
+
+// permitted checks the permission the event of a message requires, replying
+// FORBIDDEN when the connection lacks it
+func (h *Hub) permitted(msg InboundHubMessage) bool {
+	perm, ok := eventPermissions[msg.Message.Event]
+	if !ok || msg.Conn.can(perm) {
+		return true
+	}
+
+	h.requestLogger(msg).Warn("Message denied", zap.String("permission", string(perm)))
+	h.replyError(msg, messages.ErrorForbidden, fmt.Sprintf("%s permission required", perm))
+	return false
+}
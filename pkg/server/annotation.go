@@ -0,0 +1,74 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/tecu23/eng-server/internal/messages"
+)
+
+// Bounds of a single annotation
+const (
+	maxArrows        = 16
+	maxHighlights    = 64
+	maxCommentLength = 500
+)
+
+// annotationColors are the colors arrows and highlights may be drawn in
+var annotationColors = map[string]bool{"green": true, "red": true, "yellow": true, "blue": true}
+
+// squarePattern matches a square in algebraic notation
+var squarePattern = regexp.MustCompile(`^[a-h][1-8]$`)
+
+// validateAnnotation checks an annotation and fills in the default colors
+func validateAnnotation(payload *messages.AnnotatePayload) error {
+	if len(payload.Arrows) > maxArrows {
+		return fmt.Errorf("at most %d arrows per annotation", maxArrows)
+	}
+	if len(payload.Highlights) > maxHighlights {
+		return fmt.Errorf("at most %d highlights per annotation", maxHighlights)
+	}
+	if utf8.RuneCountInString(payload.Comment) > maxCommentLength {
+		return fmt.Errorf("comments are at most %d characters", maxCommentLength)
+	}
+
+	for i := range payload.Arrows {
+		arrow := &payload.Arrows[i]
+		if !squarePattern.MatchString(arrow.From) || !squarePattern.MatchString(arrow.To) || arrow.From == arrow.To {
+			return fmt.Errorf("invalid arrow %s-%s", arrow.From, arrow.To)
+		}
+		if err := annotationColor(&arrow.Color); err != nil {
+			return err
+		}
+	}
+
+	for i := range payload.Highlights {
+		highlight := &payload.Highlights[i]
+		if !squarePattern.MatchString(highlight.Square) {
+			return fmt.Errorf("invalid square %s", highlight.Square)
+		}
+		if err := annotationColor(&highlight.Color); err != nil {
+			return err
+		}
+	}
+
+	if len(payload.Arrows) == 0 && len(payload.Highlights) == 0 && payload.Comment == "" && !payload.Clear {
+		return errors.New("the annotation is empty")
+	}
+
+	return nil
+}
+
+// annotationColor checks a color, green when empty
+func annotationColor(color *string) error {
+	if *color == "" {
+		*color = "green"
+		return nil
+	}
+	if !annotationColors[*color] {
+		return fmt.Errorf("color must be green, red, yellow or blue, not %s", *color)
+	}
+	return nil
+}
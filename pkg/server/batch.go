@@ -0,0 +1,70 @@
+package server
+
+// Limits of a frame joining several messages
+const (
+	maxBatchMessages = 64       // Messages joined in one frame
+	maxBatchBytes    = 32 << 10 // Size after which no more messages are joined
+)
+
+// batcher joins the messages of a connection that negotiated
+// eng.v1.json.batch, one JSON message per line, so a backlog is written in a
+// single frame instead of a frame and a syscall each. It is only used by the
+// write pump, which writes every frame before joining the next ones
+type batcher struct {
+	buf  []byte // Reused between batches
+	ends []int  // Offset in buf where each joined frame ends
+}
+
+// drainBacklog joins the messages waiting in the send buffer to the one just
+// received, written as a single frame
+func (c *Connection) drainBacklog(first []byte) []byte {
+	if len(c.send) == 0 {
+		return first
+	}
+
+	b := c.batch
+	b.buf = append(b.buf[:0], first...)
+	for n := 1; n < maxBatchMessages && len(b.buf) < maxBatchBytes; n++ {
+		select {
+		case message := <-c.send:
+			c.outbox.spend()
+			b.buf = append(b.buf, '\n')
+			b.buf = append(b.buf, message...)
+		default:
+			return b.buf
+		}
+	}
+	return b.buf
+}
+
+// join packs messages into as few frames as the limits allow
+func (b *batcher) join(messages [][]byte) [][]byte {
+	if len(messages) < 2 {
+		return messages
+	}
+
+	b.buf = b.buf[:0]
+	b.ends = b.ends[:0]
+
+	start, n := 0, 0
+	for _, message := range messages {
+		if n == maxBatchMessages || len(b.buf)-start >= maxBatchBytes {
+			b.ends = append(b.ends, len(b.buf))
+			start, n = len(b.buf), 0
+		}
+		if n > 0 {
+			b.buf = append(b.buf, '\n')
+		}
+		b.buf = append(b.buf, message...)
+		n++
+	}
+	b.ends = append(b.ends, len(b.buf))
+
+	frames := messages[:0]
+	start = 0
+	for _, end := range b.ends {
+		frames = append(frames, b.buf[start:end])
+		start = end
+	}
+	return frames
+}
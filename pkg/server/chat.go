@@ -0,0 +1,112 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tecu23/eng-server/internal/messages"
+)
+
+// Chat channels of a game
+const (
+	ChatPlayers    = "players"    // Read and written by the players of the game
+	ChatSpectators = "spectators" // Read and written by the spectators, hidden from the players
+)
+
+// Chat limits of a connection
+const (
+	maxChatLength  = 300             // Longest chat message in characters
+	chatBurst      = 5               // Messages a connection may send in a row
+	chatRefillTime = 2 * time.Second // Time for a connection to earn another message
+)
+
+// ChatFilter checks a chat message before it is delivered. It returns the text
+// to deliver, e.g. with words masked, or an error to refuse the message
+type ChatFilter func(gameID, text string) (string, error)
+
+// WordFilter is a chat filter masking the given words, matched as whole words
+// regardless of case
+func WordFilter(words []string) ChatFilter {
+	quoted := make([]string, 0, len(words))
+	for _, w := range words {
+		if w = strings.TrimSpace(w); w != "" {
+			quoted = append(quoted, regexp.QuoteMeta(w))
+		}
+	}
+	if len(quoted) == 0 {
+		return nil
+	}
+
+	pattern := regexp.MustCompile(`(?i)\b(` + strings.Join(quoted, "|") + `)\b`)
+	return func(_, text string) (string, error) {
+		return pattern.ReplaceAllStringFunc(text, func(word string) string {
+			return strings.Repeat("*", utf8.RuneCountInString(word))
+		}), nil
+	}
+}
+
+// chatLimiter is a token bucket limiting the chat messages of a connection,
+// shared by the shards handling the games the connection chats in
+type chatLimiter struct {
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// allow takes a token, it returns false when the connection sends too fast
+func (l *chatLimiter) allow(now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.last.IsZero() {
+		l.tokens = chatBurst
+	} else {
+		l.tokens = min(l.tokens+now.Sub(l.last).Seconds()/chatRefillTime.Seconds(), chatBurst)
+	}
+	l.last = now
+
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// validateChat checks a chat message and trims its text
+func validateChat(payload *messages.ChatPayload) error {
+	switch payload.Channel {
+	case ChatPlayers, ChatSpectators:
+	default:
+		return fmt.Errorf("channel must be %s or %s", ChatPlayers, ChatSpectators)
+	}
+
+	payload.Text = strings.TrimSpace(payload.Text)
+	if payload.Text == "" {
+		return errors.New("the message is empty")
+	}
+	if utf8.RuneCountInString(payload.Text) > maxChatLength {
+		return fmt.Errorf("messages are at most %d characters", maxChatLength)
+	}
+	return nil
+}
+
+// chatMember reports whether a connection may use a chat channel of a game
+func (h *Hub) chatMember(conn *Connection, gameID, channel string) bool {
+	if channel == ChatPlayers {
+		return h.playing(conn, gameID)
+	}
+	return h.spectating(conn, gameID)
+}
+
+// chatRecipients returns the connections reading a chat channel of a game
+func (h *Hub) chatRecipients(gameID, channel string) []*Connection {
+	if channel == ChatPlayers {
+		return h.playersOfGame(gameID)
+	}
+	return h.spectatorsForGame(gameID)
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+// What happens to the connection a game is claimed from
+const (
+	ClaimSpectate = "spectate" // The old connection keeps watching the game
+	ClaimClose    = "close"    // The old connection is closed
+)
+
+// closeClaimed is the websocket close code of a connection whose game was
+// claimed by another connection of the same player
+const closeClaimed = 4001
+
+// validateClaim checks what happens to the old connection of a claimed game
+func validateClaim(previous string) (string, error) {
+	switch previous {
+	case "", ClaimSpectate:
+		return ClaimSpectate, nil
+	case ClaimClose:
+		return ClaimClose, nil
+	default:
+		return "", fmt.Errorf("previous must be %s or %s", ClaimSpectate, ClaimClose)
+	}
+}
+
+// mayClaim reports whether a connection authenticated as a player of a game,
+// whose own connection may have closed since, and whether it claims the seat
+// of the opponent of a game between players. When both players use the same
+// key, the seat left by a closed connection is claimed
+func (h *Hub) mayClaim(conn *Connection, gameID string) (opponent bool, ok bool) {
+	s := h.shard(gameID)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if conn.key == "" {
+		return false, false
+	}
+
+	owner := s.ownerKeys[gameID] == conn.key
+	other := s.opponentKeys[gameID] == conn.key
+	switch {
+	case owner && other:
+		return s.owners[gameID] != nil && s.opponents[gameID] == nil, true
+	case owner, other:
+		return other, true
+	default:
+		return false, false
+	}
+}
+
+// transferGame makes a connection play a seat of a game instead of its
+// previous connection, nil when it closed, which is returned
+func (h *Hub) transferGame(conn *Connection, gameID string, opponent bool) *Connection {
+	s := h.shard(gameID)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seats := s.owners
+	if opponent {
+		seats = s.opponents
+	}
+
+	previous := seats[gameID]
+	if previous != nil {
+		games := h.connGames[previous]
+		for i, id := range games {
+			if id == gameID {
+				games = append(games[:i], games[i+1:]...)
+				break
+			}
+		}
+		if len(games) == 0 {
+			delete(h.connGames, previous)
+		} else {
+			h.connGames[previous] = games
+		}
+	}
+
+	seats[gameID] = conn
+	h.connGames[conn] = append(h.connGames[conn], gameID)
+
+	// The new owner no longer watches the game as a spectator
+	delete(s.spectators[gameID], conn)
+
+	h.logger.Info("Game claimed by another connection",
+		zap.String("connection_id", conn.ID.String()),
+		zap.String("game_id", gameID))
+
+	return previous
+}
+
+// forgetGame drops what the hub keeps about a game once it is terminated
+func (h *Hub) forgetGame(gameID string) {
+	s := h.shard(gameID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.ownerKeys, gameID)
+	delete(s.opponentKeys, gameID)
+	delete(s.seqs, gameID)
+}
+
+// closeClaimedConnection closes a connection whose game another connection
+// of the same player claimed, the close frame tells the client why
+func closeClaimedConnection(conn *Connection, gameID string) {
+	conn.writeMu.Lock()
+	_ = conn.ws.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(closeClaimed, "game "+gameID+" claimed by another connection"),
+		time.Now().Add(time.Second))
+	conn.writeMu.Unlock()
+
+	conn.Close()
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tecu23/eng-server/internal/messages"
+)
+
+func TestClaimedGameOnlyPlayableByClaimingConnection(t *testing.T) {
+	th := newTestHub(t)
+
+	old := th.dial(t, "k1", "alice")
+	gameID := old.createGame()
+
+	claimer := th.dial(t, "k1", "alice")
+	claimer.send("CLAIM_GAME", messages.ClaimGamePayload{GameID: gameID})
+	claimer.await("GAME_STATE", nil)
+	old.await("GAME_CLAIMED", nil)
+
+	// The demoted connection only watches the game now
+	for _, tc := range []struct {
+		event   string
+		payload any
+	}{
+		{"MAKE_MOVE", messages.MakeMovePayload{GameID: gameID, Move: "e2e4"}},
+		{"PREMOVE", messages.PremovePayload{GameID: gameID, Move: "e2e4"}},
+		{"TAKEBACK_REQUEST", messages.TakebackRequestPayload{GameID: gameID}},
+		{"REQUEST_HINT", messages.RequestHintPayload{GameID: gameID}},
+		{"RESIGN", messages.ResignPayload{GameID: gameID}},
+	} {
+		old.send(tc.event, tc.payload)
+		assert.Equal(t, messages.ErrorForbidden, old.awaitError(), tc.event)
+	}
+
+	claimer.send("MAKE_MOVE", messages.MakeMovePayload{GameID: gameID, Move: "e2e4"})
+	claimer.await("MOVE_PROCESSED", nil)
+}
+
+func TestClaimNeedsKeyOfPlayer(t *testing.T) {
+	th := newTestHub(t)
+
+	owner := th.dial(t, "k1", "alice")
+	gameID := owner.createGame()
+
+	other := th.dial(t, "k2", "bob")
+	other.send("CLAIM_GAME", messages.ClaimGamePayload{GameID: gameID})
+	assert.Equal(t, messages.ErrorForbidden, other.awaitError())
+
+	other.send("MAKE_MOVE", messages.MakeMovePayload{GameID: gameID, Move: "e2e4"})
+	assert.Equal(t, messages.ErrorForbidden, other.awaitError())
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"compress/flate"
+	"encoding/json"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/internal/msgpack"
+)
+
+// Subprotocols a client may request to pick the wire encoding of messages.
+// Connections that request none of them use JSON. The eng.v2 subprotocols
+// wrap outbound messages in messages.Envelope
+const (
+	SubprotocolJSON      = "eng.v1.json"
+	SubprotocolJSONBatch = "eng.v1.json.batch" // JSON, a backlog of messages sent in one frame, one per line
+	SubprotocolMsgPack   = "eng.v1.msgpack"
+
+	SubprotocolJSONV2      = "eng.v2.json"
+	SubprotocolJSONBatchV2 = "eng.v2.json.batch"
+	SubprotocolMsgPackV2   = "eng.v2.msgpack"
+)
+
+// Subprotocols lists the supported subprotocols, most preferred first
+var Subprotocols = []string{
+	SubprotocolMsgPackV2, SubprotocolJSONBatchV2, SubprotocolJSONV2,
+	SubprotocolMsgPack, SubprotocolJSONBatch, SubprotocolJSON,
+}
+
+// Compression configures permessage-deflate for the connections that negotiate it
+type Compression struct {
+	Enabled   bool
+	Threshold int // Messages smaller than this many bytes are sent uncompressed
+	Level     int // Deflate level, from flate.BestSpeed to flate.BestCompression
+}
+
+// DefaultCompression compresses messages of 512 bytes or more, where analysis
+// lines and PGNs start to gain, at the cheapest level
+var DefaultCompression = Compression{
+	Threshold: 512,
+	Level:     flate.BestSpeed,
+}
+
+// codec encodes the messages of a connection in its negotiated wire format
+type codec interface {
+	// encode returns the frame payload of an outbound message
+	encode(v interface{}) ([]byte, error)
+	// decode reads an inbound message from a frame payload
+	decode(data []byte, msg *messages.InboundMessage) error
+	// frameType is the websocket frame type messages are sent in
+	frameType() int
+}
+
+// codecFor returns the codec of a negotiated subprotocol
+func codecFor(subprotocol string) codec {
+	if subprotocol == SubprotocolMsgPack || subprotocol == SubprotocolMsgPackV2 {
+		return msgpackCodec{}
+	}
+	return jsonCodec{}
+}
+
+// envelopeFor returns the version of the envelope of a negotiated subprotocol
+func envelopeFor(subprotocol string) int {
+	switch subprotocol {
+	case SubprotocolJSONV2, SubprotocolJSONBatchV2, SubprotocolMsgPackV2:
+		return messages.EnvelopeVersion
+	default:
+		return 1
+	}
+}
+
+// batched reports whether a negotiated subprotocol joins backlogged messages
+func batched(subprotocol string) bool {
+	return subprotocol == SubprotocolJSONBatch || subprotocol == SubprotocolJSONBatchV2
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) encode(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) decode(data []byte, msg *messages.InboundMessage) error {
+	return json.Unmarshal(data, msg)
+}
+
+func (jsonCodec) frameType() int {
+	return websocket.TextMessage
+}
+
+// msgpackCodec sends MessagePack maps shaped like the JSON messages
+type msgpackCodec struct{}
+
+func (msgpackCodec) encode(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+// decode converts the message to JSON so the payload reaches the handlers
+// as the raw JSON they parse. Inbound messages are rare enough for the detour
+func (msgpackCodec) decode(data []byte, msg *messages.InboundMessage) error {
+	generic, err := msgpack.Unmarshal(data)
+	if err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(generic)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, msg)
+}
+
+func (msgpackCodec) frameType() int {
+	return websocket.BinaryMessage
+}
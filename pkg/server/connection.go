@@ -1,13 +1,15 @@
 package server
 
 import (
-	"encoding/json"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 
+	"github.com/tecu23/eng-server/internal/auth"
 	"github.com/tecu23/eng-server/internal/messages"
 	"github.com/tecu23/eng-server/pkg/events"
 )
@@ -18,6 +20,30 @@ type Connection struct {
 	hub     *Hub
 	send    chan []byte // Buffered channel of outbound messages.
 	writeMu sync.Mutex  // Mutex to protect concurrent writes to ws.
+	outbox  *outbox     // Coalesced high frequency updates waiting for the rate limit
+	codec   codec       // Wire encoding negotiated through the websocket subprotocol
+	version int         // Envelope version negotiated through the websocket subprotocol
+	batch   *batcher    // Joins backlogged messages into one frame, nil unless the client negotiated it
+
+	compression Compression
+	role        auth.Role // Role of the key the client authenticated with
+	key         string    // API key the client authenticated with, the same player on every device
+	user        string    // User the key authenticates, who plays and is rated in the games of the connection
+
+	session *messages.SessionToken // Token for the REST routes sent with CONNECTED, nil when none was issued
+
+	chat  chatLimiter                 // Rate limit of the chat messages sent by the client
+	prefs atomic.Pointer[preferences] // Updates the client chose to receive, nil for all
+
+	done      chan struct{} // Closed once the connection is shutting down
+	closeOnce sync.Once
+
+	overflowMu    sync.Mutex
+	overflowSince time.Time    // When the send buffer was first found full, zero while it has room
+	dropped       atomic.Int64 // Messages dropped because the client could not keep up
+
+	lagMu sync.Mutex
+	lag   time.Duration // Smoothed one way network lag measured with pings
 
 	publisher *events.Publisher
 	logger    *zap.Logger
@@ -26,17 +52,43 @@ type Connection struct {
 func NewConnection(
 	ws *websocket.Conn,
 	hub *Hub,
+	compression Compression,
+	role auth.Role,
+	key string,
+	user string,
+	session *messages.SessionToken,
 	publisher *events.Publisher,
 	logger *zap.Logger,
 ) *Connection {
-	return &Connection{
-		ID:        uuid.New(),
-		ws:        ws,
-		hub:       hub,
-		send:      make(chan []byte, 256), // buffered for outgoing messages
-		publisher: publisher,
-		logger:    logger,
+	if compression.Enabled {
+		if err := ws.SetCompressionLevel(compression.Level); err != nil {
+			logger.Warn("Invalid compression level, using the default", zap.Error(err))
+		}
+	}
+
+	c := &Connection{
+		ID:          uuid.New(),
+		ws:          ws,
+		hub:         hub,
+		send:        make(chan []byte, 256), // buffered for outgoing messages
+		outbox:      newOutbox(),
+		codec:       codecFor(ws.Subprotocol()),
+		version:     envelopeFor(ws.Subprotocol()),
+		compression: compression,
+		role:        role,
+		key:         key,
+		user:        user,
+		session:     session,
+		done:        make(chan struct{}),
+		publisher:   publisher,
+		logger:      logger,
 	}
+	if batched(ws.Subprotocol()) {
+		c.batch = &batcher{}
+	}
+	ws.SetPongHandler(c.handlePong)
+
+	return c
 }
 
 // ReadPump handles inbound messages from the client
@@ -46,14 +98,6 @@ func (c *Connection) ReadPump() {
 		c.ws.Close()
 	}()
 
-	// Publish connection closed event
-	c.publisher.Publish(events.Event{
-		Type: events.EventConnectionClosed,
-		Payload: map[string]string{
-			"connection_id": c.ID.String(),
-		},
-	})
-
 	for {
 		msgType, msg, err := c.ws.ReadMessage()
 		if err != nil {
@@ -61,16 +105,21 @@ func (c *Connection) ReadPump() {
 			break
 		}
 
-		// We only handle text
-		if msgType == websocket.TextMessage {
+		// Text frames always carry JSON, binary frames the negotiated encoding
+		var decoder codec = jsonCodec{}
+		if msgType == websocket.BinaryMessage {
+			decoder = c.codec
+		}
+
+		if msgType == decoder.frameType() {
 			var inbound messages.InboundMessage
-			if err := json.Unmarshal(msg, &inbound); err == nil {
-				c.hub.inbound <- InboundHubMessage{
+			if err := decoder.decode(msg, &inbound); err == nil {
+				c.hub.enqueue(InboundHubMessage{
 					Conn:    c,
 					Message: inbound,
-				}
+				})
 			} else {
-				c.logger.Error("Failed to parse inbound JSON", zap.Error(err))
+				c.logger.Error("Failed to parse inbound message", zap.Error(err))
 			}
 		}
 	}
@@ -78,37 +127,173 @@ func (c *Connection) ReadPump() {
 
 // WritePump handles outbound messages to the client
 func (c *Connection) WritePump() {
+	ticker := time.NewTicker(flushInterval)
+	pinger := time.NewTicker(pingInterval)
 	defer func() {
+		ticker.Stop()
+		pinger.Stop()
 		c.ws.Close()
 	}()
 
 	for {
-		message, ok := <-c.send
-		if !ok {
-			// Channel closed
+		select {
+		case <-c.done:
 			c.logger.Info(
-				"Send channel closed for connection",
+				"Connection closed, stopping writes",
 				zap.String("connection_id", c.ID.String()),
 			)
 			return
-		}
-		c.writeMu.Lock()
-		err := c.ws.WriteMessage(websocket.TextMessage, message)
-		c.writeMu.Unlock()
-		if err != nil {
-			c.logger.Error("write error", zap.Error(err))
-			return
+
+		case message := <-c.send:
+			c.outbox.spend()
+			if c.batch != nil {
+				message = c.drainBacklog(message)
+			}
+			if err := c.write(message); err != nil {
+				c.logger.Error("write error", zap.Error(err))
+				return
+			}
+
+		case <-pinger.C:
+			if err := c.ping(); err != nil {
+				c.logger.Error("ping error", zap.Error(err))
+				return
+			}
+
+		case <-ticker.C:
+			ready := c.outbox.take()
+			if c.batch != nil {
+				ready = c.batch.join(ready)
+			}
+			for _, message := range ready {
+				if err := c.write(message); err != nil {
+					c.logger.Error("write error", zap.Error(err))
+					return
+				}
+			}
 		}
 	}
 }
 
-// SendJSON is a helper for sending JSON to this connection
+// write sends a single frame to the client
+func (c *Connection) write(message []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	// Small messages like clock ticks cost more to deflate than they save
+	c.ws.EnableWriteCompression(c.compression.Enabled && len(message) >= c.compression.Threshold)
+
+	return c.ws.WriteMessage(c.codec.frameType(), message)
+}
+
+// SendJSON is a helper for sending a message to this connection, encoded as
+// JSON or in the encoding negotiated by the client
 func (c *Connection) SendJSON(v interface{}) {
-	data, err := json.Marshal(v)
+	data, err := c.encode(v)
+	if err != nil {
+		c.logger.Error("Error encoding message", zap.Error(err))
+		return
+	}
+
+	c.sendEncoded(data)
+}
+
+// encode encodes a value in the wire format of the connection, outbound
+// messages in the envelope it negotiated
+func (c *Connection) encode(v interface{}) ([]byte, error) {
+	if msg, ok := v.(messages.OutboundMessage); ok {
+		return encodeMessage(c.codec, c.version, msg, time.Now())
+	}
+	return c.codec.encode(v)
+}
+
+// sendEncoded queues a message already in the wire format of the connection
+func (c *Connection) sendEncoded(data []byte) {
+	// Checked first so a closing connection never takes new messages, even
+	// when the buffer still has room
+	select {
+	case <-c.done:
+		return
+	default:
+	}
+
+	select {
+	case c.send <- data:
+		c.overflowMu.Lock()
+		c.overflowSince = time.Time{}
+		c.overflowMu.Unlock()
+	case <-c.done:
+	default:
+		c.overflow()
+	}
+}
+
+// SendLatest queues a high frequency update that a newer update under the
+// same key replaces until the rate limit lets it through
+func (c *Connection) SendLatest(key string, v interface{}) {
+	data, err := c.encode(v)
 	if err != nil {
-		c.logger.Error("Error marshaling JSON", zap.Error(err))
+		c.logger.Error("Error encoding message", zap.Error(err))
+		return
+	}
+
+	c.sendLatestEncoded(key, data)
+}
+
+// sendLatestEncoded coalesces a message already in the wire format of the
+// connection
+func (c *Connection) sendLatestEncoded(key string, data []byte) {
+	if c.outbox.put(key, data) {
+		c.drop()
+	}
+}
+
+// overflow drops a message that did not fit in the send buffer and closes the
+// connection once the client has not kept up for longer than maxOverflow
+func (c *Connection) overflow() {
+	c.drop()
+
+	c.overflowMu.Lock()
+	if c.overflowSince.IsZero() {
+		c.overflowSince = time.Now()
+	}
+	stalled := time.Since(c.overflowSince)
+	c.overflowMu.Unlock()
+
+	if stalled < maxOverflow {
 		return
 	}
 
-	c.send <- data
+	c.logger.Warn("Closing connection that stopped reading",
+		zap.String("connection_id", c.ID.String()),
+		zap.Duration("stalled", stalled),
+		zap.Int64("dropped", c.Dropped()),
+	)
+	c.hub.overflowDisconnects.Add(1)
+	c.Close()
+}
+
+// drop counts a message the client will never receive
+func (c *Connection) drop() {
+	c.dropped.Add(1)
+	c.hub.droppedMessages.Add(1)
+}
+
+// Dropped returns the number of messages dropped for this connection
+func (c *Connection) Dropped() int64 {
+	return c.dropped.Load()
+}
+
+// Done is closed once the connection is shutting down
+func (c *Connection) Done() <-chan struct{} {
+	return c.done
+}
+
+// Close stops all writes to the connection and closes the socket, which ends
+// the read loop and unregisters the connection. Safe to call more than once
+func (c *Connection) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.ws.Close()
+	})
 }
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/tecu23/eng-server/internal/color"
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/engine"
+)
+
+// dashboardInterval is how often the admin dashboard is pushed to the admins
+const dashboardInterval = 2 * time.Second
+
+// recentErrorsSize is how many internal errors the dashboard keeps
+const recentErrorsSize = 20
+
+// DashboardPayload is the server-wide state pushed to the admin topic
+type DashboardPayload struct {
+	Time         time.Time        `json:"time"`
+	Connections  ConnectionCounts `json:"connections"`
+	Games        []DashboardGame  `json:"games"` // Games in progress
+	EnginePool   engine.PoolStats `json:"engine_pool"`
+	Delivery     DeliveryStats    `json:"delivery"`
+	Load         LoadStats        `json:"load"`
+	RecentErrors []RecentError    `json:"recent_errors"` // Latest internal errors, oldest first
+}
+
+// ConnectionCounts counts the clients of the hub by role
+type ConnectionCounts struct {
+	Total      int `json:"total"`      // WebSocket connections
+	Players    int `json:"players"`    // Connections owning at least one game
+	Spectators int `json:"spectators"` // Connections watching at least one game
+	Admins     int `json:"admins"`     // Connections subscribed to the admin topic
+	Streams    int `json:"streams"`    // Server-Sent Events streams
+}
+
+// DashboardGame is a game in progress as shown on the admin dashboard
+type DashboardGame struct {
+	GameID      string      `json:"game_id"`
+	Mode        string      `json:"mode"`
+	Moves       int         `json:"moves"` // Half-moves played
+	WhiteTime   int64       `json:"white_time"`
+	BlackTime   int64       `json:"black_time"`
+	CurrentTurn color.Color `json:"current_turn"`
+	Spectators  int         `json:"spectators"`
+}
+
+// RecentError is an internal error with the time it was reported
+type RecentError struct {
+	Time time.Time `json:"time"`
+	messages.InternalErrorPayload
+}
+
+// recentErrors is a bounded log of the latest internal errors
+type recentErrors struct {
+	mu     sync.Mutex
+	errors []RecentError
+}
+
+// add records an error, forgetting the oldest one when full
+func (r *recentErrors) add(payload messages.InternalErrorPayload) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.errors) == recentErrorsSize {
+		r.errors = append(r.errors[:0], r.errors[1:]...)
+	}
+	r.errors = append(r.errors, RecentError{Time: time.Now(), InternalErrorPayload: payload})
+}
+
+func (r *recentErrors) list() []RecentError {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]RecentError{}, r.errors...)
+}
+
+// runDashboard pushes the dashboard to the admins until the hub shuts down
+func (h *Hub) runDashboard() {
+	ticker := time.NewTicker(dashboardInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if h.adminCount() > 0 {
+				h.sendToAdmins(h.dashboardMessage())
+			}
+		case <-h.done:
+			return
+		}
+	}
+}
+
+// sendDashboard sends the dashboard to a new admin right away, without
+// waiting for the next tick
+func (h *Hub) sendDashboard(conn *Connection) {
+	defer func() {
+		if r := recover(); r != nil {
+			h.publisher.ReportPanic("admin dashboard", "", r)
+		}
+	}()
+
+	h.sendMessage(conn, h.dashboardMessage())
+}
+
+func (h *Hub) adminCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return len(h.admins)
+}
+
+// dashboardMessage builds the current dashboard
+func (h *Hub) dashboardMessage() messages.OutboundMessage {
+	payload := DashboardPayload{
+		Time:         time.Now(),
+		Connections:  h.connectionCounts(),
+		Games:        []DashboardGame{},
+		EnginePool:   h.gameManager.EnginePoolStats(),
+		Delivery:     h.DeliveryStats(),
+		Load:         h.LoadStats(),
+		RecentErrors: h.errors.list(),
+	}
+
+	for _, session := range h.gameManager.ActiveSessions() {
+		// Sessions answer from their own loop, the game may end meanwhile
+		state, err := session.State()
+		if err != nil {
+			continue
+		}
+
+		gameID := session.ID.String()
+		payload.Games = append(payload.Games, DashboardGame{
+			GameID:      gameID,
+			Mode:        string(session.Mode),
+			Moves:       len(state.Moves),
+			WhiteTime:   state.WhiteTime,
+			BlackTime:   state.BlackTime,
+			CurrentTurn: state.CurrentTurn,
+			Spectators:  h.spectatorCount(gameID),
+		})
+	}
+
+	return messages.OutboundMessage{
+		Event:   "ADMIN_DASHBOARD",
+		Payload: payload,
+	}
+}
+
+func (h *Hub) connectionCounts() ConnectionCounts {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	counts := ConnectionCounts{
+		Total:   len(h.connections),
+		Players: len(h.connGames),
+		Admins:  len(h.admins),
+	}
+
+	watching := make(map[*Connection]bool)
+	for _, s := range h.shards {
+		s.mu.RLock()
+		for _, conns := range s.spectators {
+			for conn := range conns {
+				watching[conn] = true
+			}
+		}
+		for _, streams := range s.streams {
+			counts.Streams += len(streams)
+		}
+		s.mu.RUnlock()
+	}
+	counts.Spectators = len(watching)
+
+	return counts
+}
+
+func (h *Hub) spectatorCount(gameID string) int {
+	s := h.shard(gameID)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.spectators[gameID])
+}
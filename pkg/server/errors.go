@@ -0,0 +1,43 @@
+package server
+
+import (
+	"errors"
+
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/engine"
+	"github.com/tecu23/eng-server/pkg/game"
+	"github.com/tecu23/eng-server/pkg/manager"
+	"github.com/tecu23/eng-server/pkg/repository"
+	"github.com/tecu23/eng-server/pkg/tournament"
+)
+
+// errorCode classifies an error returned by a game or the manager
+func errorCode(err error) messages.ErrorCode {
+	switch {
+	case errors.Is(err, game.ErrIllegalMove):
+		return messages.ErrorIllegalMove
+	case errors.Is(err, game.ErrNotYourTurn):
+		return messages.ErrorNotYourTurn
+	case errors.Is(err, game.ErrGameOver), errors.Is(err, game.ErrGameTerminated):
+		return messages.ErrorGameOver
+	case errors.Is(err, engine.ErrNoEngineAvailable):
+		return messages.ErrorEngineUnavailable
+	case errors.Is(err, engine.ErrProfileNotFound):
+		return messages.ErrorInvalidPayload
+	case errors.Is(err, repository.ErrGameNotFound):
+		return messages.ErrorGameNotFound
+	case errors.Is(err, tournament.ErrTournamentNotFound):
+		return messages.ErrorTournamentNotFound
+	case errors.Is(err, manager.ErrSeekNotFound):
+		return messages.ErrorSeekNotFound
+	case errors.Is(err, manager.ErrSeekRating):
+		return messages.ErrorForbidden
+	default:
+		return messages.ErrorInvalidRequest
+	}
+}
+
+// replyErr sends an error returned while handling an inbound message
+func (h *Hub) replyErr(msg InboundHubMessage, err error) {
+	h.replyError(msg, errorCode(err), err.Error())
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tecu23/eng-server/internal/messages"
+)
+
+// frame is an outbound message encoded at most once per wire format, so a
+// message fanned out to many connections is marshalled once for all of them.
+// A frame is used by the goroutine that built it, the encoded bytes are
+// shared by the connections and never modified
+type frame struct {
+	msg      messages.OutboundMessage
+	key      string // Coalescing key of high frequency updates
+	coalesce bool
+	sent     time.Time // Timestamp of the eng.v2 envelope
+
+	encoded map[wireFormat][]byte
+}
+
+// wireFormat is the encoding and envelope version negotiated by a connection
+type wireFormat struct {
+	codec   codec
+	version int
+}
+
+func newFrame(msg messages.OutboundMessage) *frame {
+	f := &frame{msg: msg, sent: time.Now()}
+	f.key, f.coalesce = coalesceKey(msg)
+	return f
+}
+
+// encode returns the message in the wire format of a codec and envelope version
+func (f *frame) encode(c codec, version int) ([]byte, error) {
+	format := wireFormat{codec: c, version: version}
+	if data, ok := f.encoded[format]; ok {
+		return data, nil
+	}
+
+	data, err := encodeMessage(c, version, f.msg, f.sent)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.encoded == nil {
+		f.encoded = make(map[wireFormat][]byte, 2)
+	}
+	f.encoded[format] = data
+	return data, nil
+}
+
+// encodeMessage encodes an outbound message in an envelope version, clock
+// ticks of the first version on the JSON path skip reflection
+func encodeMessage(c codec, version int, msg messages.OutboundMessage, sent time.Time) ([]byte, error) {
+	if version == messages.EnvelopeVersion {
+		return c.encode(msg.Envelope(sent))
+	}
+
+	if _, ok := c.(jsonCodec); ok {
+		if clock, ok := msg.Payload.(messages.ClockUpdatePayload); ok {
+			return encodeClockUpdate(msg, clock), nil
+		}
+	}
+	return c.encode(msg)
+}
+
+// scratchPool holds the buffers clock ticks are encoded in before being
+// copied out at their exact size
+var scratchPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, 256)
+		return &b
+	},
+}
+
+// encodeClockUpdate writes a clock tick as encoding/json would, field by field
+func encodeClockUpdate(msg messages.OutboundMessage, p messages.ClockUpdatePayload) []byte {
+	bp := scratchPool.Get().(*[]byte)
+	b := (*bp)[:0]
+
+	b = append(b, `{"event":`...)
+	b = appendJSONString(b, msg.Event)
+	b = append(b, `,"payload":{"gameId":`...)
+	b = appendJSONString(b, p.GameID)
+	b = append(b, `,"whiteTimeMs":`...)
+	b = strconv.AppendInt(b, p.WhiteTime, 10)
+	b = append(b, `,"blackTimeMs":`...)
+	b = strconv.AppendInt(b, p.BlackTime, 10)
+	b = append(b, `,"activeColor":`...)
+	b = appendJSONString(b, p.ActiveColor)
+	b = append(b, `,"running":`...)
+	b = strconv.AppendBool(b, p.Running)
+	b = append(b, `,"serverTimeMs":`...)
+	b = strconv.AppendInt(b, p.ServerTime, 10)
+	if p.LagCompensation != 0 {
+		b = append(b, `,"lagCompensationMs":`...)
+		b = strconv.AppendInt(b, p.LagCompensation, 10)
+	}
+	b = append(b, '}')
+	if msg.RequestID != "" {
+		b = append(b, `,"request_id":`...)
+		b = appendJSONString(b, msg.RequestID)
+	}
+	if msg.Seq != 0 {
+		b = append(b, `,"seq":`...)
+		b = strconv.AppendInt(b, msg.Seq, 10)
+	}
+	b = append(b, '}')
+
+	data := append([]byte(nil), b...)
+	*bp = b
+	scratchPool.Put(bp)
+	return data
+}
+
+// appendJSONString appends a quoted string. IDs and colors need no escaping,
+// anything else goes through encoding/json to escape it the same way
+func appendJSONString(b []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 0x20 || c >= 0x7f || c == '"' || c == '\\' || c == '<' || c == '>' || c == '&' {
+			quoted, _ := json.Marshal(s)
+			return append(b, quoted...)
+		}
+	}
+
+	b = append(b, '"')
+	b = append(b, s...)
+	return append(b, '"')
+}
@@ -1,19 +1,32 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"math/rand"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 
+	"github.com/tecu23/eng-server/internal/auth"
 	"github.com/tecu23/eng-server/internal/color"
 	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/book"
 	"github.com/tecu23/eng-server/pkg/events"
+	"github.com/tecu23/eng-server/pkg/game"
+	"github.com/tecu23/eng-server/pkg/journal"
 	"github.com/tecu23/eng-server/pkg/manager"
+	"github.com/tecu23/eng-server/pkg/match"
+	"github.com/tecu23/eng-server/pkg/tournament"
 )
 
+// hubQueueSize is how many events wait for the hub before stale updates are dropped
+const hubQueueSize = 1024
+
 // InboundHubMessage are the messages that the hub receives
 type InboundHubMessage struct {
 	Conn    *Connection             // who sent it
@@ -25,35 +38,86 @@ type InboundHubMessage struct {
 type Hub struct {
 	mu sync.RWMutex // Mutex to protect direct access to the connections map.
 
-	connections     map[*Connection]bool     // Registered connections
-	gameConnections map[string]*Connection   // Maps game IDs to connections
-	connGames       map[*Connection][]string // Maps connections to their game IDs
+	connections  map[*Connection]bool     // Registered connections
+	connGames    map[*Connection][]string // Maps connections to their game IDs
+	admins       map[*Connection]bool     // Connections subscribed to the admin topic
+	lobbyMembers map[*Connection]bool     // Connections following the tournaments of the lobby
+
+	shards []*gameShard // Routing and messages of the games, hashed by game ID. Locked after mu when both are
 
 	register   chan *Connection       // Incoming registration
 	unregister chan *Connection       // Incoming unregistration
-	inbound    chan InboundHubMessage // Channel or inbound messages that the hub might route or broadcast
+	inbound    chan InboundHubMessage // Messages about no game in particular, handled by the control loop
 
 	broadcast chan []byte // Channel to broadcast to everyone
 
+	errors recentErrors // Latest internal errors shown on the admin dashboard
+
+	droppedMessages     atomic.Int64 // Messages dropped across all connections
+	overflowDisconnects atomic.Int64 // Connections closed for not keeping up with their messages
+
+	shedding     LoadShedding
+	inboundQueue atomic.Int64 // Messages of the clients waiting for the hub
+	inboundPeak  atomic.Int64 // Deepest inbound queue since startup
+	shedRequests atomic.Int64 // New games refused while saturated
+
 	gameManager *manager.Manager
+	matchRunner *match.Runner
+	lobby       *tournament.Lobby
 	publisher   *events.Publisher
+	build       messages.BuildInfo // Sent to clients when they connect
+	chatFilter  ChatFilter         // nil delivers chat messages unchanged
+	journal     *journal.Journal   // Records the events sent to each game, nil disables it
+
+	running  atomic.Bool   // Set once Run routes messages
+	done     chan struct{} // Closed on shutdown
+	doneOnce sync.Once
+
+	ctx    context.Context    // Parent of the games created through the hub, cancelled on shutdown
+	cancel context.CancelFunc // Cancels ctx
 
 	logger *zap.Logger
 }
 
 // NewHub creates a new hub
-func NewHub(gm *manager.Manager, publisher *events.Publisher, logger *zap.Logger) *Hub {
+func NewHub(
+	gm *manager.Manager,
+	runner *match.Runner,
+	lobby *tournament.Lobby,
+	publisher *events.Publisher,
+	build messages.BuildInfo,
+	chatFilter ChatFilter,
+	journal *journal.Journal,
+	shedding LoadShedding,
+	shards int,
+	logger *zap.Logger,
+) *Hub {
 	hub := &Hub{
-		connections:     make(map[*Connection]bool),
-		gameConnections: make(map[string]*Connection),
-		connGames:       make(map[*Connection][]string),
-		register:        make(chan *Connection),
-		unregister:      make(chan *Connection),
-		inbound:         make(chan InboundHubMessage),
-		broadcast:       make(chan []byte),
-		gameManager:     gm,
-		publisher:       publisher,
-		logger:          logger,
+		connections:  make(map[*Connection]bool),
+		connGames:    make(map[*Connection][]string),
+		admins:       make(map[*Connection]bool),
+		lobbyMembers: make(map[*Connection]bool),
+		shards:       make([]*gameShard, max(shards, 1)),
+		register:     make(chan *Connection),
+		unregister:   make(chan *Connection),
+		inbound:      make(chan InboundHubMessage),
+		broadcast:    make(chan []byte),
+		gameManager:  gm,
+		matchRunner:  runner,
+		lobby:        lobby,
+		publisher:    publisher,
+		build:        build,
+		chatFilter:   chatFilter,
+		journal:      journal,
+		shedding:     shedding,
+		done:         make(chan struct{}),
+		logger:       logger,
+	}
+
+	hub.ctx, hub.cancel = context.WithCancel(context.Background())
+
+	for i := range hub.shards {
+		hub.shards[i] = newGameShard()
 	}
 
 	// Subscribe to events
@@ -64,127 +128,462 @@ func NewHub(gm *manager.Manager, publisher *events.Publisher, logger *zap.Logger
 
 // setupEventHandlers sets up the hub's event handlers
 func (h *Hub) setupEventHandlers() {
+	// One queue keeps the events of a game in order, e.g. a clock tick never
+	// overtakes the move that started the clock
+	sub := h.publisher.NewSubscriber("hub", events.SubscriberOptions{
+		QueueSize: hubQueueSize,
+		Overflow:  events.OverflowDropStale,
+	})
+
 	// Handle game created events
-	h.publisher.Subscribe(events.EventGameCreated, func(event events.Event) {
+	sub.Subscribe(events.EventGameCreated, func(event events.Event) {
 		payload, ok := event.Payload.(messages.GameCreatedPayload)
 		if !ok {
 			h.logger.Error("Invalid game created payload type")
 			return
 		}
-		// Find the connection associated with this game
-		// This mapping would need to be maintained separately
-		conn := h.findConnectionForGame(event.GameID)
-		if conn == nil {
-			h.logger.Error(
-				"Could not find connection for game",
-				zap.String("game_id", event.GameID),
-			)
+
+		h.openJournal(event.GameID)
+
+		resp := messages.OutboundMessage{
+			Event:   "GAME_CREATED",
+			Payload: payload,
+		}
+
+		h.sendToGame(event.GameID, resp)
+	})
+
+	// Handle move processed events
+	sub.Subscribe(events.EventMoveProcessed, func(event events.Event) {
+		payload, ok := event.Payload.(messages.GameStatePayload)
+		if !ok {
+			h.logger.Error("Invalid move processed payload type")
 			return
 		}
 
 		resp := messages.OutboundMessage{
-			Event:   "GAME_CREATED",
+			Event:   "MOVE_PROCESSED",
 			Payload: payload,
 		}
 
-		h.sendMessage(conn, resp)
+		h.sendToGame(event.GameID, resp)
 	})
 
 	// Handle engine move events
-	h.publisher.Subscribe(events.EventEngineMoved, func(event events.Event) {
+	sub.Subscribe(events.EventEngineMoved, func(event events.Event) {
 		payload, ok := event.Payload.(messages.EngineMovePayload)
 		if !ok {
 			h.logger.Error("Invalid engine move payload type")
 			return
 		}
 
-		conn := h.findConnectionForGame(event.GameID)
-		if conn == nil {
-			h.logger.Error(
-				"Could not find connection for game",
-				zap.String("game_id", event.GameID),
-			)
-			return
-		}
-
 		resp := messages.OutboundMessage{
 			Event:   "ENGINE_MOVE",
 			Payload: payload,
 		}
 
-		h.sendMessage(conn, resp)
+		h.sendToGame(event.GameID, resp)
 	})
 
 	// Handle clock update events
-	h.publisher.Subscribe(events.EventClockUpdated, func(event events.Event) {
+	sub.Subscribe(events.EventClockUpdated, func(event events.Event) {
 		payload, ok := event.Payload.(messages.ClockUpdatePayload)
 		if !ok {
 			h.logger.Error("Invalid clock update payload type")
 			return
 		}
 
-		conn := h.findConnectionForGame(event.GameID)
-		if conn == nil {
-			h.logger.Error(
-				"Could not find connection for game",
-				zap.String("game_id", event.GameID),
-			)
+		resp := messages.OutboundMessage{
+			Event:   "CLOCK_UPDATE",
+			Payload: payload,
+		}
+
+		h.sendToGame(event.GameID, resp)
+	})
+
+	// Handle takeback applied events
+	sub.Subscribe(events.EventTakebackApplied, func(event events.Event) {
+		payload, ok := event.Payload.(messages.TakebackAppliedPayload)
+		if !ok {
+			h.logger.Error("Invalid takeback applied payload type")
+			return
+		}
+
+		resp := messages.OutboundMessage{
+			Event:   "TAKEBACK_APPLIED",
+			Payload: payload,
+		}
+
+		h.sendToGame(event.GameID, resp)
+	})
+
+	// Handle board annotation events
+	sub.Subscribe(events.EventBoardAnnotation, func(event events.Event) {
+		payload, ok := event.Payload.(messages.BoardAnnotationPayload)
+		if !ok {
+			h.logger.Error("Invalid board annotation payload type")
 			return
 		}
 
 		resp := messages.OutboundMessage{
-			Event:   "CLOCK_UPDATE",
+			Event:   "BOARD_ANNOTATION",
+			Payload: payload,
+		}
+
+		h.sendToGame(event.GameID, resp)
+	})
+
+	// Handle engine crashed events
+	sub.Subscribe(events.EventEngineCrashed, func(event events.Event) {
+		payload, ok := event.Payload.(messages.EngineCrashedPayload)
+		if !ok {
+			h.logger.Error("Invalid engine crashed payload type")
+			return
+		}
+
+		resp := messages.OutboundMessage{
+			Event:   "ENGINE_CRASHED",
+			Payload: payload,
+		}
+
+		h.sendToGame(event.GameID, resp)
+	})
+
+	// Handle premove discarded events
+	sub.Subscribe(events.EventPremoveDiscarded, func(event events.Event) {
+		payload, ok := event.Payload.(messages.PremoveDiscardedPayload)
+		if !ok {
+			h.logger.Error("Invalid premove discarded payload type")
+			return
+		}
+
+		resp := messages.OutboundMessage{
+			Event:   "PREMOVE_DISCARDED",
+			Payload: payload,
+		}
+
+		h.sendToGame(event.GameID, resp)
+	})
+
+	// Handle hint ready events, hints only go to the player
+	sub.Subscribe(events.EventHintReady, func(event events.Event) {
+		payload, ok := event.Payload.(messages.HintPayload)
+		if !ok {
+			h.logger.Error("Invalid hint ready payload type")
+			return
+		}
+
+		owner := h.findConnectionForGame(event.GameID)
+		if owner == nil {
+			return
+		}
+
+		resp := messages.OutboundMessage{
+			Event:   "HINT",
+			Payload: payload,
+		}
+
+		h.record(event.GameID, resp)
+		h.sendMessage(owner, resp)
+	})
+
+	// Handle game over events
+	sub.Subscribe(events.EventGameOver, func(event events.Event) {
+		payload, ok := event.Payload.(messages.GameOverPayload)
+		if !ok {
+			h.logger.Error("Invalid game over payload type")
+			return
+		}
+
+		resp := messages.OutboundMessage{
+			Event:   "GAME_OVER",
+			Payload: payload,
+		}
+
+		h.sendToGame(event.GameID, resp)
+	})
+
+	// Handle game restored events, for admins and anyone following the game
+	sub.Subscribe(events.EventGameRestored, func(event events.Event) {
+		payload, ok := event.Payload.(messages.GameRestoredPayload)
+		if !ok {
+			h.logger.Error("Invalid game restored payload type")
+			return
+		}
+
+		h.openJournal(event.GameID)
+
+		resp := messages.OutboundMessage{
+			Event:   "GAME_RESTORED",
+			Payload: payload,
+		}
+
+		h.sendToAdmins(resp)
+		h.sendToGame(event.GameID, resp)
+	})
+
+	// Handle game abandoned events
+	sub.Subscribe(events.EventGameAbandoned, func(event events.Event) {
+		payload, ok := event.Payload.(messages.GameAbandonedPayload)
+		if !ok {
+			h.logger.Error("Invalid game abandoned payload type")
+			return
+		}
+
+		resp := messages.OutboundMessage{
+			Event:   "GAME_ABANDONED",
+			Payload: payload,
+		}
+
+		h.sendToGame(event.GameID, resp)
+	})
+
+	// Handle analysis ready events
+	sub.Subscribe(events.EventAnalysisReady, func(event events.Event) {
+		payload, ok := event.Payload.(messages.AnalysisReportPayload)
+		if !ok {
+			h.logger.Error("Invalid analysis ready payload type")
+			return
+		}
+
+		resp := messages.OutboundMessage{
+			Event:   "ANALYSIS_REPORT",
+			Payload: payload,
+		}
+
+		h.sendToGame(event.GameID, resp)
+	})
+
+	// Handle live analysis updates
+	sub.Subscribe(events.EventAnalysisUpdated, func(event events.Event) {
+		payload, ok := event.Payload.(messages.AnalysisUpdatePayload)
+		if !ok {
+			h.logger.Error("Invalid analysis updated payload type")
+			return
+		}
+
+		resp := messages.OutboundMessage{
+			Event:   "ANALYSIS_UPDATE",
+			Payload: payload,
+		}
+
+		h.sendToGame(event.GameID, resp)
+	})
+
+	// Handle eval updated events
+	sub.Subscribe(events.EventEvalUpdated, func(event events.Event) {
+		payload, ok := event.Payload.(messages.EvalPayload)
+		if !ok {
+			h.logger.Error("Invalid eval updated payload type")
+			return
+		}
+
+		resp := messages.OutboundMessage{
+			Event:   "EVAL_UPDATE",
+			Payload: payload,
+		}
+
+		h.sendToGame(event.GameID, resp)
+	})
+
+	// Handle match progress events
+	sub.Subscribe(events.EventMatchProgress, func(event events.Event) {
+		payload, ok := event.Payload.(messages.MatchProgressPayload)
+		if !ok {
+			h.logger.Error("Invalid match progress payload type")
+			return
+		}
+
+		resp := messages.OutboundMessage{
+			Event:   "MATCH_PROGRESS",
+			Payload: payload,
+		}
+
+		h.sendToAdmins(resp)
+	})
+
+	// Handle tournament updates, for everyone following the lobby
+	sub.Subscribe(events.EventTournamentUpdate, func(event events.Event) {
+		payload, ok := event.Payload.(messages.TournamentPayload)
+		if !ok {
+			h.logger.Error("Invalid tournament update payload type")
+			return
+		}
+
+		h.sendToLobby(messages.OutboundMessage{
+			Event:   "TOURNAMENT_UPDATE",
+			Payload: payload,
+		})
+	})
+
+	// Handle internal error events
+	sub.Subscribe(events.EventInternalError, func(event events.Event) {
+		payload, ok := event.Payload.(messages.InternalErrorPayload)
+		if !ok {
+			h.logger.Error("Invalid internal error payload type")
+			return
+		}
+
+		h.errors.add(payload)
+
+		resp := messages.OutboundMessage{
+			Event:   "INTERNAL_ERROR",
 			Payload: payload,
 		}
 
-		h.sendMessage(conn, resp)
+		h.sendToAdmins(resp)
 	})
 
 	// Handle time up events
-	h.publisher.Subscribe(events.EventTimeUp, func(event events.Event) {
+	sub.Subscribe(events.EventTimeUp, func(event events.Event) {
 		payload, ok := event.Payload.(messages.TimeupPayload)
 		if !ok {
 			h.logger.Error("Invalid time up payload type")
 			return
 		}
 
-		conn := h.findConnectionForGame(event.GameID)
-		if conn == nil {
-			h.logger.Error(
-				"Could not find connection for game",
-				zap.String("game_id", event.GameID),
-			)
+		resp := messages.OutboundMessage{
+			Event:   "TIME_UP",
+			Payload: payload,
+		}
+
+		h.sendToGame(event.GameID, resp)
+	})
+
+	// Handle presence events, games nobody follows are skipped quietly
+	sub.Subscribe(events.EventPresenceUpdated, func(event events.Event) {
+		payload, ok := event.Payload.(messages.PresencePayload)
+		if !ok {
+			h.logger.Error("Invalid presence payload type")
+			return
+		}
+
+		resp := messages.OutboundMessage{
+			Event:   "PRESENCE_UPDATE",
+			Payload: payload,
+		}
+
+		h.sendToStreams(event.GameID, resp)
+		f := newFrame(resp)
+		for _, conn := range append(h.spectatorsForGame(event.GameID), h.playersOfGame(event.GameID)...) {
+			h.sendFrame(conn, f)
+		}
+	})
+
+	// Handle game terminated events
+	sub.Subscribe(events.EventGameTerminated, func(event events.Event) {
+		h.forgetGame(event.GameID)
+
+		if h.journal != nil {
+			h.journal.Close(event.GameID)
+		}
+	})
+
+	// Handle chat message events, each channel only reaches its readers
+	sub.Subscribe(events.EventChatMessage, func(event events.Event) {
+		payload, ok := event.Payload.(messages.ChatMessagePayload)
+		if !ok {
+			h.logger.Error("Invalid chat message payload type")
 			return
 		}
 
 		resp := messages.OutboundMessage{
-			Event:   "TIME_UP",
+			Event:   "CHAT_MESSAGE",
 			Payload: payload,
 		}
 
-		h.sendMessage(conn, resp)
+		f := newFrame(resp)
+		for _, conn := range h.chatRecipients(event.GameID, payload.Channel) {
+			h.sendFrame(conn, f)
+		}
 	})
 }
 
 // findConnectionForGame finds the connection associated with a game
 func (h *Hub) findConnectionForGame(gameID string) *Connection {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
+	s := h.shard(gameID)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	conn, exists := h.gameConnections[gameID]
-	if !exists {
-		return nil
+	return s.owners[gameID]
+}
+
+// playersOfGame returns the connections playing a game, its owner and the
+// opponent of a game between players
+func (h *Hub) playersOfGame(gameID string) []*Connection {
+	s := h.shard(gameID)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var conns []*Connection
+	for _, conn := range []*Connection{s.owners[gameID], s.opponents[gameID]} {
+		if conn != nil {
+			conns = append(conns, conn)
+		}
+	}
+	return conns
+}
+
+// playing reports whether a connection plays a game
+func (h *Hub) playing(conn *Connection, gameID string) bool {
+	s := h.shard(gameID)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.owners[gameID] == conn || s.opponents[gameID] == conn
+}
+
+// spectatorsForGame returns the connections watching a game
+func (h *Hub) spectatorsForGame(gameID string) []*Connection {
+	s := h.shard(gameID)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	conns := make([]*Connection, 0, len(s.spectators[gameID]))
+	for conn := range s.spectators[gameID] {
+		conns = append(conns, conn)
+	}
+	return conns
+}
+
+// addSpectator registers a connection as a spectator of a game
+func (h *Hub) addSpectator(conn *Connection, gameID string) {
+	s := h.shard(gameID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.spectators[gameID] == nil {
+		s.spectators[gameID] = make(map[*Connection]bool)
 	}
-	return conn
+	s.spectators[gameID][conn] = true
+
+	h.logger.Info("Connection is spectating game",
+		zap.String("connection_id", conn.ID.String()),
+		zap.String("game_id", gameID))
+}
+
+// addAdmin subscribes a connection to the admin topic
+func (h *Hub) addAdmin(conn *Connection) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.admins[conn] = true
+
+	h.logger.Info("Connection subscribed to admin topic",
+		zap.String("connection_id", conn.ID.String()))
 }
 
 // associateConnectionWithGame registers a connection as the owner of a game
 func (h *Hub) associateConnectionWithGame(conn *Connection, gameID string) {
+	s := h.shard(gameID)
 	h.mu.Lock()
 	defer h.mu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
 	// Add to game->connection mapping
-	h.gameConnections[gameID] = conn
+	s.owners[gameID] = conn
+	s.ownerKeys[gameID] = conn.key
 
 	// Add to connection->games mapping
 	h.connGames[conn] = append(h.connGames[conn], gameID)
@@ -194,20 +593,67 @@ func (h *Hub) associateConnectionWithGame(conn *Connection, gameID string) {
 		zap.String("game_id", gameID))
 }
 
-// removeGameAssociations removes all game associations for a connection
-func (h *Hub) removeGameAssociations(conn *Connection) {
+// associateOpponentWithGame registers a connection as the player of the
+// other color of a game between players
+func (h *Hub) associateOpponentWithGame(conn *Connection, gameID string) {
+	s := h.shard(gameID)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.opponents[gameID] = conn
+	s.opponentKeys[gameID] = conn.key
+
+	h.connGames[conn] = append(h.connGames[conn], gameID)
+
+	h.logger.Info("Associated opponent with game",
+		zap.String("connection_id", conn.ID.String()),
+		zap.String("game_id", gameID))
+}
+
+// removeGameAssociations removes all game associations for a connection and
+// returns the games it played or watched
+func (h *Hub) removeGameAssociations(conn *Connection) []string {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
+	delete(h.admins, conn)
+	delete(h.lobbyMembers, conn)
+
+	// Stop spectating any game
+	var watched []string
+	for _, s := range h.shards {
+		s.mu.Lock()
+		for gameID, conns := range s.spectators {
+			if !conns[conn] {
+				continue
+			}
+			watched = append(watched, gameID)
+			delete(conns, conn)
+			if len(conns) == 0 {
+				delete(s.spectators, gameID)
+			}
+		}
+		s.mu.Unlock()
+	}
+
 	// Get all games for this connection
 	games, exists := h.connGames[conn]
 	if !exists {
-		return
+		return watched
 	}
 
 	// Remove each game->connection mapping
 	for _, gameID := range games {
-		delete(h.gameConnections, gameID)
+		s := h.shard(gameID)
+		s.mu.Lock()
+		if s.opponents[gameID] == conn {
+			delete(s.opponents, gameID)
+		} else {
+			delete(s.owners, gameID)
+		}
+		s.mu.Unlock()
 		h.logger.Info("Removed game association",
 			zap.String("game_id", gameID),
 			zap.String("connection_id", conn.ID.String()))
@@ -215,10 +661,18 @@ func (h *Hub) removeGameAssociations(conn *Connection) {
 
 	// Remove the connection->games mapping
 	delete(h.connGames, conn)
+
+	return append(watched, games...)
 }
 
 // Run is the main execution of the hub
 func (h *Hub) Run() {
+	go h.runDashboard()
+	for _, s := range h.shards {
+		go h.runShard(s)
+	}
+	h.running.Store(true)
+
 	for {
 		select {
 		case conn := <-h.register:
@@ -228,6 +682,7 @@ func (h *Hub) Run() {
 			h.unregisterConnection(conn)
 
 		case msg := <-h.inbound:
+			h.inboundQueue.Add(-1)
 			h.handleInbound(msg)
 		}
 	}
@@ -244,8 +699,18 @@ func (h *Hub) registerConnection(conn *Connection) {
 	h.connections[conn] = true
 	h.logger.Info("New connection registered", zap.Int("total_connections", len(h.connections)))
 
+	h.publisher.Publish(events.Event{
+		Type: events.EventConnectionOpened,
+		Payload: map[string]string{
+			"connection_id": conn.ID.String(),
+			"remote_addr":   conn.ws.RemoteAddr().String(),
+		},
+	})
+
 	var payload messages.ConnectedPayload
 	payload.ConnectionId = conn.ID.String()
+	payload.Server = h.build
+	payload.Session = conn.session
 
 	msg := messages.OutboundMessage{
 		Event:   "CONNECTED",
@@ -262,14 +727,18 @@ func (h *Hub) Unregister(conn *Connection) {
 
 func (h *Hub) unregisterConnection(conn *Connection) {
 	// First, remove any game associations
-	h.removeGameAssociations(conn)
+	affected := h.removeGameAssociations(conn)
+	h.publishPresence(affected...)
 
 	h.mu.Lock()
 	defer h.mu.Unlock()
 	if _, ok := h.connections[conn]; ok {
 		delete(h.connections, conn)
-		close(conn.send)
-		h.logger.Info("Connection unregistered", zap.Int("total_connections", len(h.connections)))
+		conn.Close()
+		h.logger.Info("Connection unregistered",
+			zap.Int("total_connections", len(h.connections)),
+			zap.Int64("dropped_messages", conn.Dropped()),
+		)
 
 		// Publish connection closed event
 		h.publisher.Publish(events.Event{
@@ -284,99 +753,1286 @@ func (h *Hub) unregisterConnection(conn *Connection) {
 
 // handleInbound is where the message from a client is decoded and handled
 func (h *Hub) handleInbound(msg InboundHubMessage) {
+	// Every message gets a correlation ID, echoed in the replies and carried
+	// by the logs down to the engine
+	if msg.Message.RequestID == "" {
+		msg.Message.RequestID = uuid.NewString()
+	}
+	logger := h.requestLogger(msg)
+	logger.Debug("Handling inbound message")
+
+	// A failing handler must not stop the hub loop serving every connection
+	defer func() {
+		if r := recover(); r != nil {
+			h.publisher.ReportPanic("hub "+msg.Message.Event, "", r)
+			h.replyError(msg, messages.ErrorInternal, "Internal server error")
+		}
+	}()
+
+	if !h.permitted(msg) {
+		return
+	}
+
 	switch msg.Message.Event {
 	case "CREATE_SESSION":
 		var payload messages.CreateSession
 		if err := json.Unmarshal(msg.Message.Payload, &payload); err != nil {
-			h.logger.Error("Invalid CREATE_SESSION payload", zap.Error(err))
-			h.sendError(msg.Conn, "Invalid START_NEW_GAME payload")
+			logger.Error("Invalid CREATE_SESSION payload", zap.Error(err))
+			h.replyError(msg, messages.ErrorInvalidPayload, "Invalid START_NEW_GAME payload")
 			return
 		}
 
-		var clr color.Color
+		if h.shed(msg) {
+			return
+		}
 
-		if payload.Color == "w" {
+		// The player takes White unless they ask for Black
+		var clr color.Color
+		switch payload.Color {
+		case "", color.White:
 			clr = color.White
-		} else {
+		case color.Black:
 			clr = color.Black
+		default:
+			h.replyError(msg, messages.ErrorInvalidPayload, "color must be w or b")
+			return
 		}
 
-		gameSession, err := h.gameManager.CreateSession(
-			payload.TimeControl.WhiteTime,
+		variant, fen, err := StartPosition(payload)
+		if err != nil {
+			h.replyErr(msg, err)
+			return
+		}
+
+		userID, ok := h.authenticatedUser(msg, payload.UserID)
+		if !ok {
+			return
+		}
+
+		gameSession, err := h.gameManager.CreateSession(
+			h.ctx,
+			payload.TimeControl.WhiteTime,
 			payload.TimeControl.BlackTime,
 			payload.TimeControl.WhiteIncrement,
 			payload.TimeControl.BlackIncrement,
 			clr,
-			payload.InitialFen,
+			fen,
+			variant,
+			bookOptions(payload.OpeningBook),
+			timeManagement(payload.TimeManagement),
+			game.Difficulty(payload.Difficulty),
+			pacing(payload.Pacing),
+			payload.Profile,
+			userID,
+			payload.Rated,
+			payload.Analysis,
+			payload.Seed,
+			payload.Deterministic,
 			msg.Conn.ID,
 			h.publisher,
 		)
 		if err != nil {
-			h.logger.Error("Error creating game session", zap.Error(err))
-			h.sendError(msg.Conn, err.Error())
+			logger.Error("Error creating game session", zap.Error(err))
+			h.replyErr(msg, err)
+			return
+		}
+
+		// Associate the connection with the game ID
+		h.associateConnectionWithGame(msg.Conn, gameSession.ID.String())
+
+		logger.Info("Game session created", zap.String("game_id", gameSession.ID.String()))
+
+		// The engine opens once the player is listening to the game
+		if gameSession.EngineOpens() {
+			if err := gameSession.RequestEngineMove(msg.Message.RequestID); err != nil {
+				logger.Error("Could not request engine move", zap.Error(err))
+				h.replyErr(msg, err)
+			}
+		}
+
+	case "CREATE_EXHIBITION":
+		var payload messages.CreateExhibitionPayload
+		if err := json.Unmarshal(msg.Message.Payload, &payload); err != nil {
+			logger.Error("Invalid CREATE_EXHIBITION payload", zap.Error(err))
+			h.replyError(msg, messages.ErrorInvalidPayload, "Invalid CREATE_EXHIBITION payload")
+			return
+		}
+
+		if h.shed(msg) {
+			return
+		}
+
+		gameSession, err := h.gameManager.CreateExhibition(
+			h.ctx,
+			payload.TimeControl.WhiteTime,
+			payload.TimeControl.BlackTime,
+			payload.TimeControl.WhiteIncrement,
+			payload.TimeControl.BlackIncrement,
+			payload.InitialFen,
+			bookOptions(payload.OpeningBook),
+			payload.Seed,
+			payload.Deterministic,
+			msg.Conn.ID,
+		)
+		if err != nil {
+			logger.Error("Error creating exhibition game", zap.Error(err))
+			h.replyErr(msg, err)
+			return
+		}
+
+		h.associateConnectionWithGame(msg.Conn, gameSession.ID.String())
+
+		logger.Info("Exhibition game created", zap.String("game_id", gameSession.ID.String()))
+
+	case "CREATE_SIMUL":
+		var payload messages.CreateSimulPayload
+		if err := json.Unmarshal(msg.Message.Payload, &payload); err != nil {
+			logger.Error("Invalid CREATE_SIMUL payload", zap.Error(err))
+			h.replyError(msg, messages.ErrorInvalidPayload, "Invalid CREATE_SIMUL payload")
+			return
+		}
+
+		if h.shed(msg) {
+			return
+		}
+
+		// The engine gives the simul with White unless the player asks for it
+		var clr color.Color
+		switch payload.Color {
+		case "", color.Black:
+			clr = color.Black
+		case color.White:
+			clr = color.White
+		default:
+			h.replyError(msg, messages.ErrorInvalidPayload, "color must be w or b")
+			return
+		}
+
+		userID, ok := h.authenticatedUser(msg, payload.UserID)
+		if !ok {
+			return
+		}
+
+		simul, sessions, err := h.gameManager.CreateSimul(
+			h.ctx,
+			payload.Boards,
+			payload.TimeControl.WhiteTime,
+			payload.TimeControl.BlackTime,
+			payload.TimeControl.WhiteIncrement,
+			payload.TimeControl.BlackIncrement,
+			clr,
+			game.Difficulty(payload.Difficulty),
+			userID,
+			msg.Conn.ID,
+		)
+		if err != nil {
+			logger.Error("Error creating simul", zap.Error(err))
+			h.replyErr(msg, err)
+			return
+		}
+
+		gameIDs := make([]string, 0, len(sessions))
+		for _, session := range sessions {
+			h.associateConnectionWithGame(msg.Conn, session.ID.String())
+			gameIDs = append(gameIDs, session.ID.String())
+		}
+
+		h.reply(msg, messages.OutboundMessage{
+			Event: "SIMUL_CREATED",
+			Payload: messages.SimulCreatedPayload{
+				SimulID: simul.ID.String(),
+				GameIDs: gameIDs,
+				Color:   clr,
+			},
+		})
+
+		logger.Info("Simul created", zap.String("simul_id", simul.ID.String()), zap.Int("boards", len(sessions)))
+
+		// The engine queues its opening moves, one board after the other
+		for _, session := range sessions {
+			if !session.EngineOpens() {
+				continue
+			}
+			if err := session.RequestEngineMove(msg.Message.RequestID); err != nil {
+				logger.Error("Could not request engine move", zap.String("game_id", session.ID.String()), zap.Error(err))
+			}
+		}
+
+	case "SPECTATE":
+		var payload messages.SpectatePayload
+		if err := json.Unmarshal(msg.Message.Payload, &payload); err != nil {
+			logger.Error("Invalid SPECTATE payload", zap.Error(err))
+			h.replyError(msg, messages.ErrorInvalidPayload, "Invalid SPECTATE payload")
+			return
+		}
+
+		session, ok := h.lookupSession(msg, payload.GameID)
+		if !ok {
+			return
+		}
+
+		h.addSpectator(msg.Conn, payload.GameID)
+		h.publishPresence(payload.GameID)
+
+		// Send the current state so the spectator can render the board right away
+		state, err := session.State()
+		if err != nil {
+			logger.Error("Could not get game state", zap.Error(err))
+			h.replyErr(msg, err)
+			return
+		}
+
+		h.reply(msg, messages.OutboundMessage{
+			Event:   "GAME_STATE",
+			Payload: state,
+		})
+
+	case "ADMIN_SUBSCRIBE":
+		h.addAdmin(msg.Conn)
+		h.publishAdminAction(msg.Conn, "admin_subscribe", nil)
+
+		// Building the dashboard waits on every session, so it stays off the hub loop
+		go h.sendDashboard(msg.Conn)
+
+	case "START_MATCH":
+		var payload messages.StartMatchPayload
+		if err := json.Unmarshal(msg.Message.Payload, &payload); err != nil {
+			logger.Error("Invalid START_MATCH payload", zap.Error(err))
+			h.replyError(msg, messages.ErrorInvalidPayload, "Invalid START_MATCH payload")
+			return
+		}
+
+		m, err := h.matchRunner.Start(match.Config{
+			EngineA: payload.EngineA,
+			EngineB: payload.EngineB,
+			Games:   payload.Games,
+			TimeControl: game.TimeControl{
+				WhiteTime:       payload.TimeControl.WhiteTime,
+				BlackTime:       payload.TimeControl.BlackTime,
+				WhiteIncrement:  payload.TimeControl.WhiteIncrement,
+				BlackIncrement:  payload.TimeControl.BlackIncrement,
+				MovesPerControl: 40,
+				TimingMethod:    game.IncrementTiming,
+			},
+			Openings:     payload.Openings,
+			Adjudication: adjudicationRules(payload.Adjudication),
+		})
+		if err != nil {
+			logger.Error("Error starting match", zap.Error(err))
+			h.replyErr(msg, err)
+			return
+		}
+
+		// The requester follows the match progress
+		h.addAdmin(msg.Conn)
+		h.publishAdminAction(msg.Conn, "start_match", map[string]string{
+			"match_id": m.ID.String(),
+			"engine_a": payload.EngineA,
+			"engine_b": payload.EngineB,
+		})
+
+		logger.Info("Match started", zap.String("match_id", m.ID.String()))
+
+	case "SET_PREFERENCES":
+		var payload messages.SetPreferencesPayload
+		if err := json.Unmarshal(msg.Message.Payload, &payload); err != nil {
+			logger.Error("Invalid SET_PREFERENCES payload", zap.Error(err))
+			h.replyError(msg, messages.ErrorInvalidPayload, "Invalid SET_PREFERENCES payload")
+			return
+		}
+
+		prefs, err := msg.Conn.SetPreferences(payload)
+		if err != nil {
+			h.replyError(msg, messages.ErrorInvalidPayload, err.Error())
+			return
+		}
+
+		h.reply(msg, messages.OutboundMessage{
+			Event:   "PREFERENCES",
+			Payload: prefs,
+		})
+
+	case "RESYNC":
+		var payload messages.ResyncPayload
+		if err := json.Unmarshal(msg.Message.Payload, &payload); err != nil {
+			logger.Error("Invalid RESYNC payload", zap.Error(err))
+			h.replyError(msg, messages.ErrorInvalidPayload, "Invalid RESYNC payload")
+			return
+		}
+
+		h.resync(msg, payload)
+
+	case "LOBBY_SUBSCRIBE":
+		h.addLobbyMember(msg.Conn)
+
+		h.reply(msg, messages.OutboundMessage{
+			Event:   "LOBBY_STATE",
+			Payload: h.lobbyState(),
+		})
+
+	case "CREATE_TOURNAMENT":
+		var payload messages.CreateTournamentPayload
+		if err := json.Unmarshal(msg.Message.Payload, &payload); err != nil {
+			logger.Error("Invalid CREATE_TOURNAMENT payload", zap.Error(err))
+			h.replyError(msg, messages.ErrorInvalidPayload, "Invalid CREATE_TOURNAMENT payload")
+			return
+		}
+
+		// The creator follows the lobby to see the players joining
+		h.addLobbyMember(msg.Conn)
+
+		t, err := h.lobby.Create(tournament.Config{
+			Name:        payload.Name,
+			Format:      tournament.Format(payload.Format),
+			Opponents:   tournament.Opponents(payload.Opponents),
+			TimeControl: payload.TimeControl,
+			Difficulty:  game.Difficulty(payload.Difficulty),
+			Rounds:      payload.Rounds,
+			Duration:    time.Duration(payload.DurationMs) * time.Millisecond,
+		}, msg.Conn.ID)
+		if err != nil {
+			h.replyError(msg, messages.ErrorInvalidPayload, err.Error())
+			return
+		}
+
+		logger.Info("Tournament created", zap.String("tournament_id", t.ID.String()))
+
+	case "JOIN_TOURNAMENT":
+		var payload messages.JoinTournamentPayload
+		if err := json.Unmarshal(msg.Message.Payload, &payload); err != nil {
+			logger.Error("Invalid JOIN_TOURNAMENT payload", zap.Error(err))
+			h.replyError(msg, messages.ErrorInvalidPayload, "Invalid JOIN_TOURNAMENT payload")
+			return
+		}
+
+		id, ok := h.parseTournamentID(msg, payload.TournamentID)
+		if !ok {
+			return
+		}
+
+		// Players are bound to the user of their key, nobody plays as someone else
+		user, ok := h.authenticatedUser(msg, payload.UserID)
+		if !ok {
+			return
+		}
+
+		h.addLobbyMember(msg.Conn)
+
+		if _, err := h.lobby.Join(id, user, msg.Conn.ID); err != nil {
+			h.replyErr(msg, err)
+			return
+		}
+
+	case "LEAVE_TOURNAMENT":
+		var payload messages.TournamentRequestPayload
+		if err := json.Unmarshal(msg.Message.Payload, &payload); err != nil {
+			logger.Error("Invalid LEAVE_TOURNAMENT payload", zap.Error(err))
+			h.replyError(msg, messages.ErrorInvalidPayload, "Invalid LEAVE_TOURNAMENT payload")
+			return
+		}
+
+		id, ok := h.parseTournamentID(msg, payload.TournamentID)
+		if !ok {
+			return
+		}
+
+		user, ok := h.authenticatedUser(msg, payload.UserID)
+		if !ok {
+			return
+		}
+
+		if err := h.lobby.Leave(id, user, msg.Conn.ID); err != nil {
+			h.replyErr(msg, err)
+			return
+		}
+
+	case "START_TOURNAMENT":
+		var payload messages.TournamentRequestPayload
+		if err := json.Unmarshal(msg.Message.Payload, &payload); err != nil {
+			logger.Error("Invalid START_TOURNAMENT payload", zap.Error(err))
+			h.replyError(msg, messages.ErrorInvalidPayload, "Invalid START_TOURNAMENT payload")
+			return
+		}
+
+		id, ok := h.parseTournamentID(msg, payload.TournamentID)
+		if !ok {
+			return
+		}
+
+		t, err := h.lobby.Get(id)
+		if err != nil {
+			h.replyErr(msg, err)
+			return
+		}
+
+		if t.Creator != msg.Conn.ID && !msg.Conn.can(auth.PermAdmin) {
+			h.replyError(msg, messages.ErrorForbidden, "Only the creator can start the tournament")
+			return
+		}
+
+		if err := h.lobby.Start(id); err != nil {
+			h.replyErr(msg, err)
+			return
+		}
+
+	case "CREATE_SEEK":
+		var payload messages.CreateSeekPayload
+		if err := json.Unmarshal(msg.Message.Payload, &payload); err != nil {
+			logger.Error("Invalid CREATE_SEEK payload", zap.Error(err))
+			h.replyError(msg, messages.ErrorInvalidPayload, "Invalid CREATE_SEEK payload")
+			return
+		}
+
+		// The player gets a random color unless they ask for one
+		var clr color.Color
+		switch payload.Color {
+		case "":
+		case color.White, color.Black:
+			clr = color.Color(payload.Color)
+		default:
+			h.replyError(msg, messages.ErrorInvalidPayload, "color must be w or b")
+			return
+		}
+
+		variant, fen, err := StartPosition(messages.CreateSession{Variant: payload.Variant})
+		if err != nil {
+			h.replyErr(msg, err)
+			return
+		}
+
+		seek, err := h.gameManager.CreateSeek(manager.Seek{
+			ConnectionID: msg.Conn.ID,
+			UserID:       msg.Conn.user,
+			TimeControl:  payload.TimeControl,
+			Variant:      variant,
+			FEN:          fen,
+			Color:        clr,
+			MinRating:    payload.MinRating,
+			MaxRating:    payload.MaxRating,
+			Rated:        payload.Rated,
+		})
+		if err != nil {
+			h.replyErr(msg, err)
+			return
+		}
+
+		h.reply(msg, messages.OutboundMessage{
+			Event:   "SEEK_CREATED",
+			Payload: seek.Payload(),
+		})
+
+	case "ACCEPT_SEEK":
+		var payload messages.SeekRequestPayload
+		if err := json.Unmarshal(msg.Message.Payload, &payload); err != nil {
+			logger.Error("Invalid ACCEPT_SEEK payload", zap.Error(err))
+			h.replyError(msg, messages.ErrorInvalidPayload, "Invalid ACCEPT_SEEK payload")
+			return
+		}
+
+		id, ok := h.parseSeekID(msg, payload.SeekID)
+		if !ok {
+			return
+		}
+
+		if h.shed(msg) {
+			return
+		}
+
+		gameSession, seek, err := h.gameManager.AcceptSeek(h.ctx, id, manager.Player{ConnectionID: msg.Conn.ID, UserID: msg.Conn.user})
+		if err != nil {
+			logger.Error("Could not accept seek", zap.Error(err))
+			h.replyErr(msg, err)
+			return
+		}
+
+		// The seeker may have disconnected while the game was created
+		seeker := h.connectionByID(seek.ConnectionID.String())
+		if seeker == nil {
+			h.gameManager.RemoveSession(gameSession.ID)
+			h.replyError(msg, messages.ErrorSeekNotFound, "The player of the seek left")
+			return
+		}
+
+		gameID := gameSession.ID.String()
+		h.associateConnectionWithGame(seeker, gameID)
+		h.associateOpponentWithGame(msg.Conn, gameID)
+		h.sendSeekMatched(seeker, msg.Conn, seek, gameSession)
+
+		logger.Info("Seek accepted", zap.String("seek_id", payload.SeekID), zap.String("game_id", gameID))
+
+	case "CANCEL_SEEK":
+		var payload messages.SeekRequestPayload
+		if err := json.Unmarshal(msg.Message.Payload, &payload); err != nil {
+			logger.Error("Invalid CANCEL_SEEK payload", zap.Error(err))
+			h.replyError(msg, messages.ErrorInvalidPayload, "Invalid CANCEL_SEEK payload")
+			return
+		}
+
+		id, ok := h.parseSeekID(msg, payload.SeekID)
+		if !ok {
+			return
+		}
+
+		if err := h.gameManager.CancelSeek(id, msg.Conn.ID); err != nil {
+			h.replyErr(msg, err)
+			return
+		}
+
+		h.reply(msg, messages.OutboundMessage{
+			Event:   "SEEKS_LIST",
+			Payload: h.seeksList(),
+		})
+
+	case "LIST_SEEKS":
+		h.reply(msg, messages.OutboundMessage{
+			Event:   "SEEKS_LIST",
+			Payload: h.seeksList(),
+		})
+
+	case "MAKE_MOVE":
+		var payload messages.MakeMovePayload
+		if err := json.Unmarshal(msg.Message.Payload, &payload); err != nil {
+			logger.Error("Invalid MAKE_MOVE payload", zap.Error(err))
+			h.replyError(msg, messages.ErrorInvalidPayload, "Invalid MAKE_MOVE payload")
+			return
+		}
+
+		session, clr, ok := h.lookupPlayedSession(msg, payload.GameID, "move")
+		if !ok {
+			return
+		}
+
+		outcome, err := session.ProcessMove(clr, payload.Move, payload.MoveID, msg.Conn.Lag(), msg.Message.RequestID)
+		if err != nil {
+			logger.Error("Could not process move", zap.Error(err))
+			h.replyErr(msg, err)
+			return
+		}
+
+		// A retried move was played and answered by the engine the first time
+		if outcome.Replayed {
+			h.reply(msg, messages.OutboundMessage{
+				Event:   "MOVE_PROCESSED",
+				Payload: outcome.State,
+			})
+			return
+		}
+
+		// The opponent of a game between players answers themselves
+		if session.Mode == game.ModeHumanVsHuman {
+			return
+		}
+
+		// Queue the engine reply so the hub keeps serving other connections
+		if err := session.RequestEngineMove(msg.Message.RequestID); err != nil {
+			logger.Error("Could not request engine move", zap.Error(err))
+			h.replyErr(msg, err)
+			return
+		}
+
+	case "RESIGN":
+		var payload messages.ResignPayload
+		if err := json.Unmarshal(msg.Message.Payload, &payload); err != nil {
+			logger.Error("Invalid RESIGN payload", zap.Error(err))
+			h.replyError(msg, messages.ErrorInvalidPayload, "Invalid RESIGN payload")
+			return
+		}
+
+		session, clr, ok := h.lookupPlayedSession(msg, payload.GameID, "resign")
+		if !ok {
+			return
+		}
+
+		if err := session.Resign(clr, msg.Message.RequestID); err != nil {
+			logger.Error("Could not resign game", zap.Error(err))
+			h.replyErr(msg, err)
+			return
+		}
+
+	case "CLAIM_VICTORY":
+		var payload messages.ClaimVictoryPayload
+		if err := json.Unmarshal(msg.Message.Payload, &payload); err != nil {
+			logger.Error("Invalid CLAIM_VICTORY payload", zap.Error(err))
+			h.replyError(msg, messages.ErrorInvalidPayload, "Invalid CLAIM_VICTORY payload")
+			return
+		}
+
+		session, clr, ok := h.lookupPlayedSession(msg, payload.GameID, "claim victory")
+		if !ok {
+			return
+		}
+
+		if err := h.gameManager.ClaimVictory(session.ID, clr, msg.Message.RequestID); err != nil {
+			logger.Info("Could not claim victory", zap.Error(err))
+			h.replyErr(msg, err)
+			return
+		}
+
+	case "PREMOVE":
+		var payload messages.PremovePayload
+		if err := json.Unmarshal(msg.Message.Payload, &payload); err != nil {
+			logger.Error("Invalid PREMOVE payload", zap.Error(err))
+			h.replyError(msg, messages.ErrorInvalidPayload, "Invalid PREMOVE payload")
+			return
+		}
+
+		session, clr, ok := h.lookupPlayedSession(msg, payload.GameID, "premove")
+		if !ok {
+			return
+		}
+
+		if err := session.Premove(clr, payload.Move, msg.Message.RequestID); err != nil {
+			logger.Error("Could not queue premove", zap.Error(err))
+			h.replyErr(msg, err)
+			return
+		}
+
+	case "TAKEBACK_REQUEST":
+		var payload messages.TakebackRequestPayload
+		if err := json.Unmarshal(msg.Message.Payload, &payload); err != nil {
+			logger.Error("Invalid TAKEBACK_REQUEST payload", zap.Error(err))
+			h.replyError(msg, messages.ErrorInvalidPayload, "Invalid TAKEBACK_REQUEST payload")
+			return
+		}
+
+		session, _, ok := h.lookupPlayedSession(msg, payload.GameID, "request a takeback")
+		if !ok {
+			return
+		}
+
+		if err := session.Takeback(msg.Message.RequestID); err != nil {
+			logger.Error("Could not take back move", zap.Error(err))
+			h.replyErr(msg, err)
+			return
+		}
+
+	case "RESUME_GAME":
+		var payload messages.ResumeGamePayload
+		if err := json.Unmarshal(msg.Message.Payload, &payload); err != nil {
+			logger.Error("Invalid RESUME_GAME payload", zap.Error(err))
+			h.replyError(msg, messages.ErrorInvalidPayload, "Invalid RESUME_GAME payload")
+			return
+		}
+
+		id, err := uuid.Parse(payload.GameID)
+		if err != nil {
+			h.replyError(msg, messages.ErrorInvalidPayload, "Invalid game id")
 			return
 		}
 
-		// Associate the connection with the game ID
-		h.associateConnectionWithGame(msg.Conn, gameSession.ID.String())
+		// Only the player of a restored game takes it over, exhibitions have none
+		if restored, ok := h.gameManager.GetSession(id); ok &&
+			restored.Mode != game.ModeExhibition && restored.UserID() != msg.Conn.user {
+			logger.Warn("Resume of the game of another user", zap.String("game_id", payload.GameID))
+			h.replyError(msg, messages.ErrorForbidden, "Only the player can resume the game")
+			return
+		}
+
+		session, err := h.gameManager.ResumeSession(id, msg.Conn.ID)
+		if err != nil {
+			logger.Error("Could not resume game", zap.Error(err))
+			h.replyErr(msg, err)
+			return
+		}
 
-		h.logger.Info("Game session created", zap.String("game_id", gameSession.ID.String()))
+		h.associateConnectionWithGame(msg.Conn, payload.GameID)
+		h.publishPresence(payload.GameID)
 
-	case "MAKE_MOVE":
-		var payload messages.MakeMovePayload
+		state, err := session.State()
+		if err != nil {
+			h.replyErr(msg, err)
+			return
+		}
+
+		h.reply(msg, messages.OutboundMessage{
+			Event:   "GAME_STATE",
+			Payload: state,
+		})
+
+	case "CLAIM_GAME":
+		var payload messages.ClaimGamePayload
 		if err := json.Unmarshal(msg.Message.Payload, &payload); err != nil {
-			h.logger.Error("Invalid MAKE_MOVE payload", zap.Error(err))
-			h.sendError(msg.Conn, "Invalid MAKE_MOVE payload")
+			logger.Error("Invalid CLAIM_GAME payload", zap.Error(err))
+			h.replyError(msg, messages.ErrorInvalidPayload, "Invalid CLAIM_GAME payload")
 			return
 		}
 
-		id, err := uuid.Parse(payload.GameID)
+		previous, err := validateClaim(payload.Previous)
+		if err != nil {
+			h.replyError(msg, messages.ErrorInvalidPayload, err.Error())
+			return
+		}
+
+		claimed, ok := h.lookupSession(msg, payload.GameID)
+		if !ok {
+			return
+		}
+
+		// Only a player may claim the game, from a connection authenticated with the same key
+		opponent, ok := h.mayClaim(msg.Conn, payload.GameID)
+		if !ok {
+			h.replyError(msg, messages.ErrorForbidden, "Only the player can claim the game")
+			return
+		}
+
+		if h.playing(msg.Conn, payload.GameID) {
+			h.replyError(msg, messages.ErrorInvalidRequest, "The game is already played over this connection")
+			return
+		}
+
+		clr := claimed.PlayerColor
+		if opponent {
+			clr = clr.Opp()
+		}
+
+		session, err := h.gameManager.TransferSession(claimed.ID, clr, msg.Conn.ID)
+		if err != nil {
+			logger.Error("Could not claim game", zap.Error(err))
+			h.replyErr(msg, err)
+			return
+		}
+
+		if old := h.transferGame(msg.Conn, payload.GameID, opponent); old != nil {
+			if previous == ClaimClose {
+				closeClaimedConnection(old, payload.GameID)
+			} else {
+				h.addSpectator(old, payload.GameID)
+				h.sendMessage(old, messages.OutboundMessage{
+					Event: "GAME_CLAIMED",
+					Payload: messages.GameClaimedPayload{
+						GameID:       payload.GameID,
+						ConnectionID: msg.Conn.ID.String(),
+					},
+				})
+			}
+		}
+		h.publishPresence(payload.GameID)
+
+		state, err := session.State()
+		if err != nil {
+			h.replyErr(msg, err)
+			return
+		}
+
+		h.reply(msg, messages.OutboundMessage{
+			Event:   "GAME_STATE",
+			Payload: state,
+		})
+
+	case "REQUEST_HINT":
+		var payload messages.RequestHintPayload
+		if err := json.Unmarshal(msg.Message.Payload, &payload); err != nil {
+			logger.Error("Invalid REQUEST_HINT payload", zap.Error(err))
+			h.replyError(msg, messages.ErrorInvalidPayload, "Invalid REQUEST_HINT payload")
+			return
+		}
+
+		session, _, ok := h.lookupPlayedSession(msg, payload.GameID, "request hints")
+		if !ok {
+			return
+		}
+
+		if err := session.RequestHint(payload.Depth, msg.Message.RequestID); err != nil {
+			logger.Error("Could not request hint", zap.Error(err))
+			h.replyErr(msg, err)
+			return
+		}
+
+	case "ANNOTATE":
+		var payload messages.AnnotatePayload
+		if err := json.Unmarshal(msg.Message.Payload, &payload); err != nil {
+			logger.Error("Invalid ANNOTATE payload", zap.Error(err))
+			h.replyError(msg, messages.ErrorInvalidPayload, "Invalid ANNOTATE payload")
+			return
+		}
+
+		if err := validateAnnotation(&payload); err != nil {
+			h.replyError(msg, messages.ErrorInvalidPayload, err.Error())
+			return
+		}
+
+		session, ok := h.lookupSession(msg, payload.GameID)
+		if !ok {
+			return
+		}
+
+		// Annotations refer to the position on the board when they were made
+		state, err := session.State()
 		if err != nil {
-			h.logger.Error("Could not parse game session id", zap.Error(err))
-			h.sendError(msg.Conn, err.Error())
+			h.replyErr(msg, err)
+			return
+		}
+
+		annotation := messages.BoardAnnotationPayload{
+			GameID:       payload.GameID,
+			ConnectionID: msg.Conn.ID.String(),
+			Ply:          len(state.Moves),
+			Arrows:       payload.Arrows,
+			Highlights:   payload.Highlights,
+			Comment:      payload.Comment,
+			Clear:        payload.Clear,
+		}
+		if annotation.Arrows == nil {
+			annotation.Arrows = []messages.Arrow{}
+		}
+		if annotation.Highlights == nil {
+			annotation.Highlights = []messages.Highlight{}
+		}
+
+		h.publisher.Publish(events.Event{
+			Type:    events.EventBoardAnnotation,
+			GameID:  payload.GameID,
+			Payload: annotation,
+		})
+
+	case "CHAT_MESSAGE":
+		var payload messages.ChatPayload
+		if err := json.Unmarshal(msg.Message.Payload, &payload); err != nil {
+			logger.Error("Invalid CHAT_MESSAGE payload", zap.Error(err))
+			h.replyError(msg, messages.ErrorInvalidPayload, "Invalid CHAT_MESSAGE payload")
+			return
+		}
+
+		if err := validateChat(&payload); err != nil {
+			h.replyError(msg, messages.ErrorInvalidPayload, err.Error())
+			return
+		}
+
+		if _, ok := h.lookupSession(msg, payload.GameID); !ok {
+			return
+		}
+
+		if !h.chatMember(msg.Conn, payload.GameID, payload.Channel) {
+			h.replyError(msg, messages.ErrorForbidden, "Not a member of the "+payload.Channel+" chat")
+			return
+		}
+
+		if !msg.Conn.chat.allow(time.Now()) {
+			h.replyError(msg, messages.ErrorRateLimited, "Chat messages are sent too fast")
+			return
+		}
+
+		text := payload.Text
+		if h.chatFilter != nil {
+			var err error
+			if text, err = h.chatFilter(payload.GameID, text); err != nil {
+				h.replyError(msg, messages.ErrorInvalidRequest, err.Error())
+				return
+			}
+		}
+
+		h.publisher.Publish(events.Event{
+			Type:   events.EventChatMessage,
+			GameID: payload.GameID,
+			Payload: messages.ChatMessagePayload{
+				GameID:       payload.GameID,
+				Channel:      payload.Channel,
+				ConnectionID: msg.Conn.ID.String(),
+				Text:         text,
+				SentAt:       time.Now().UTC(),
+			},
+		})
+
+	case "GET_GAME_STATE":
+		var payload messages.GetGameStatePayload
+		if err := json.Unmarshal(msg.Message.Payload, &payload); err != nil {
+			logger.Error("Invalid GET_GAME_STATE payload", zap.Error(err))
+			h.replyError(msg, messages.ErrorInvalidPayload, "Invalid GET_GAME_STATE payload")
 			return
 		}
 
-		session, ok := h.gameManager.GetSession(id)
+		session, ok := h.lookupSession(msg, payload.GameID)
 		if !ok {
-			h.logger.Error("Could not find session", zap.Error(err))
-			h.sendError(
-				msg.Conn,
-				fmt.Sprintf("Could not find session with session id %s", payload.GameID),
-			)
 			return
 		}
 
-		err = session.ProcessMove(payload.Move)
+		state, err := session.State()
+		if err != nil {
+			logger.Error("Could not get game state", zap.Error(err))
+			h.replyErr(msg, err)
+			return
+		}
+
+		h.reply(msg, messages.OutboundMessage{
+			Event:   "GAME_STATE",
+			Payload: state,
+		})
+
+	case "START_ANALYSIS":
+		var payload messages.StartAnalysisPayload
+		if err := json.Unmarshal(msg.Message.Payload, &payload); err != nil {
+			logger.Error("Invalid START_ANALYSIS payload", zap.Error(err))
+			h.replyError(msg, messages.ErrorInvalidPayload, "Invalid START_ANALYSIS payload")
+			return
+		}
+
+		live, err := h.gameManager.StartAnalysis(payload.FEN, payload.MultiPV, payload.Depth, payload.Profile, msg.Conn.ID)
+		if err != nil {
+			logger.Error("Could not start analysis", zap.Error(err))
+			h.replyErr(msg, err)
+			return
+		}
+
+		// Analysis updates are routed like game events
+		h.associateConnectionWithGame(msg.Conn, live.ID.String())
+
+		h.reply(msg, messages.OutboundMessage{
+			Event: "ANALYSIS_STARTED",
+			Payload: messages.AnalysisStartedPayload{
+				AnalysisID: live.ID.String(),
+				FEN:        live.FEN,
+				MultiPV:    live.MultiPV,
+				Shared:     live.Shared,
+			},
+		})
+
+	case "STOP_ANALYSIS":
+		var payload messages.StopAnalysisPayload
+		if err := json.Unmarshal(msg.Message.Payload, &payload); err != nil {
+			logger.Error("Invalid STOP_ANALYSIS payload", zap.Error(err))
+			h.replyError(msg, messages.ErrorInvalidPayload, "Invalid STOP_ANALYSIS payload")
+			return
+		}
+
+		id, err := uuid.Parse(payload.AnalysisID)
+		if err != nil {
+			h.replyError(msg, messages.ErrorInvalidPayload, "Invalid analysis id")
+			return
+		}
+
+		if err := h.gameManager.StopAnalysis(id, msg.Conn.ID); err != nil {
+			h.replyErr(msg, err)
+			return
+		}
+
+	case "LIST_GAMES":
+		var payload messages.ListGamesPayload
+		if err := json.Unmarshal(msg.Message.Payload, &payload); err != nil {
+			logger.Error("Invalid LIST_GAMES payload", zap.Error(err))
+			h.replyError(msg, messages.ErrorInvalidPayload, "Invalid LIST_GAMES payload")
+			return
+		}
+
+		// Without a user the history of every user is listed
+		if payload.UserID == "" && !msg.Conn.can(auth.PermListGames) {
+			h.replyError(msg, messages.ErrorForbidden, "user_id is required without the list_games permission")
+			return
+		}
+
+		games, err := h.gameManager.ListGames(payload)
 		if err != nil {
-			h.logger.Error("Could not process move", zap.Error(err))
-			h.sendError(msg.Conn, err.Error())
+			h.replyErr(msg, err)
 			return
 		}
 
-		// Call engine to make an engine move as well
-		session.ProcessEngineMove()
+		h.reply(msg, messages.OutboundMessage{
+			Event:   "GAMES_LIST",
+			Payload: games,
+		})
+
+	default:
+		logger.Warn("Unknown message type", zap.String("event", msg.Message.Event))
+		h.replyError(msg, messages.ErrorUnknownEvent, "Unknown message type")
+	}
+}
+
+// StartPosition resolves the variant and starting FEN requested by a client,
+// picking a random Chess960 position when none was given, drawn from the
+// seed when the game has one
+func StartPosition(payload messages.CreateSession) (game.Variant, string, error) {
+	switch game.Variant(payload.Variant) {
+	case "", game.VariantStandard:
+		return game.VariantStandard, payload.InitialFen, nil
+
+	case game.VariantChess960:
+		if payload.InitialFen != "" {
+			return game.VariantChess960, payload.InitialFen, nil
+		}
+
+		n := rand.Intn(960)
+		if payload.Seed != 0 {
+			n = rand.New(rand.NewSource(payload.Seed)).Intn(960)
+		}
+		if payload.Chess960Position != nil {
+			n = *payload.Chess960Position
+		}
+
+		fen, err := game.Chess960StartPosition(n)
+		return game.VariantChess960, fen, err
+
+	case game.VariantCrazyhouse, game.VariantKingOfTheHill, game.VariantThreeCheck:
+		return game.Variant(payload.Variant), payload.InitialFen, nil
+
+	default:
+		return "", "", fmt.Errorf("unsupported variant %q", payload.Variant)
+	}
+}
+
+// bookOptions converts the opening book settings requested by a client
+func bookOptions(opts *messages.OpeningBookOptions) *book.Options {
+	if opts == nil {
+		return nil
+	}
 
+	// An empty selection is left for the difficulty of the game to pick
+	selection := book.Selection(opts.Selection)
+	switch selection {
+	case "", book.SelectBest, book.SelectWeighted, book.SelectUniform:
 	default:
-		h.logger.Warn("Unknown message type", zap.String("event", msg.Message.Event))
-		h.sendError(msg.Conn, "Unknown message type")
+		selection = book.SelectWeighted
+	}
+
+	return &book.Options{
+		MaxPly:    opts.MaxPly,
+		Selection: selection,
+	}
+}
+
+// timeManagement converts the engine search limits requested by a client
+func timeManagement(opts *messages.TimeManagementOptions) game.TimeManagement {
+	if opts == nil {
+		return game.TimeManagement{}
+	}
+
+	return game.TimeManagement{
+		Policy:   game.TimePolicy(opts.Policy),
+		MoveTime: time.Duration(opts.MoveTimeMs) * time.Millisecond,
+		Depth:    opts.Depth,
+		Nodes:    opts.Nodes,
+	}
+}
+
+// pacing converts the reply delays requested by a client
+func pacing(opts *messages.PacingOptions) game.Pacing {
+	if opts == nil {
+		return game.Pacing{}
+	}
+
+	return game.Pacing{
+		MinDelay: time.Duration(opts.MinDelayMs) * time.Millisecond,
+		MaxDelay: time.Duration(opts.MaxDelayMs) * time.Millisecond,
+	}
+}
+
+// adjudicationRules converts the adjudication settings requested by a client
+func adjudicationRules(opts *messages.AdjudicationOptions) *game.AdjudicationRules {
+	if opts == nil {
+		return nil
+	}
+
+	return &game.AdjudicationRules{
+		DrawMoveNumber:  opts.DrawMoveNumber,
+		DrawMoveCount:   opts.DrawMoveCount,
+		DrawScore:       opts.DrawScore,
+		ResignMoveCount: opts.ResignMoveCount,
+		ResignScore:     opts.ResignScore,
+	}
+}
+
+// publishAdminAction records an administrative request of a connection
+func (h *Hub) publishAdminAction(conn *Connection, action string, details map[string]string) {
+	payload := map[string]string{
+		"connection_id": conn.ID.String(),
+		"action":        action,
+	}
+	for k, v := range details {
+		payload[k] = v
+	}
+
+	h.publisher.Publish(events.Event{
+		Type:    events.EventAdminAction,
+		Payload: payload,
+	})
+}
+
+// lookupSession resolves a game ID sent by a client, reporting failures back to it
+func (h *Hub) lookupSession(msg InboundHubMessage, gameID string) (*game.Game, bool) {
+	id, err := uuid.Parse(gameID)
+	if err != nil {
+		h.requestLogger(msg).Error("Could not parse game session id", zap.Error(err))
+		h.replyError(msg, messages.ErrorInvalidPayload, "Invalid game id")
+		return nil, false
+	}
+
+	session, ok := h.gameManager.GetSession(id)
+	if !ok {
+		h.requestLogger(msg).Error("Could not find session", zap.String("game_id", gameID))
+		h.replyError(msg, messages.ErrorGameNotFound, fmt.Sprintf("Could not find session with session id %s", gameID))
+		return nil, false
+	}
+
+	return session, true
+}
+
+// lookupPlayedSession resolves the game of a command only its players may
+// send and the color played by the connection, replying FORBIDDEN to
+// spectators and any other connection
+func (h *Hub) lookupPlayedSession(msg InboundHubMessage, gameID, action string) (*game.Game, color.Color, bool) {
+	session, ok := h.lookupSession(msg, gameID)
+	if !ok {
+		return nil, "", false
+	}
+
+	clr, ok := session.ColorOf(msg.Conn.ID)
+	if !ok {
+		h.requestLogger(msg).Warn("Command from a connection not playing the game", zap.String("game_id", gameID))
+		h.replyError(msg, messages.ErrorForbidden, "Only the player can "+action)
+		return nil, "", false
+	}
+
+	return session, clr, true
+}
+
+// authenticatedUser returns the user the key of a connection authenticates.
+// Clients may name it, another user is refused so nobody plays or is rated
+// as someone else
+func (h *Hub) authenticatedUser(msg InboundHubMessage, claimed string) (string, bool) {
+	if claimed != "" && claimed != msg.Conn.user {
+		h.requestLogger(msg).Warn("Message claims another user", zap.String("user_id", claimed))
+		h.replyError(msg, messages.ErrorForbidden, "user_id is not the user of the API key")
+		return "", false
+	}
+	return msg.Conn.user, true
+}
+
+// parseTournamentID parses the tournament ID of a message, replying with an
+// error when it is invalid
+func (h *Hub) parseTournamentID(msg InboundHubMessage, tournamentID string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(tournamentID)
+	if err != nil {
+		h.replyError(msg, messages.ErrorInvalidPayload, "Invalid tournament id")
+		return uuid.Nil, false
 	}
+	return id, true
+}
+
+// requestLogger returns the hub logger tagged with an inbound message
+func (h *Hub) requestLogger(msg InboundHubMessage) *zap.Logger {
+	return h.logger.With(
+		zap.String("request_id", msg.Message.RequestID),
+		zap.String("connection_id", msg.Conn.ID.String()),
+		zap.String("event", msg.Message.Event),
+	)
+}
+
+// reply sends the response to an inbound message, tagged with its request ID
+func (h *Hub) reply(msg InboundHubMessage, resp messages.OutboundMessage) {
+	resp.RequestID = msg.Message.RequestID
+	h.sendMessage(msg.Conn, resp)
 }
 
-func (h *Hub) sendError(conn *Connection, msg string) {
-	resp := messages.OutboundMessage{
+// replyError sends an error in response to an inbound message
+func (h *Hub) replyError(msg InboundHubMessage, code messages.ErrorCode, text string) {
+	h.reply(msg, messages.OutboundMessage{
 		Event: "ERROR",
 		Payload: messages.ErrorPayload{
-			Message: msg,
+			Code:      code,
+			Message:   text,
+			Event:     msg.Message.Event,
+			RequestID: msg.Message.RequestID,
 		},
+	})
+}
+
+// sendToGame sends a message to the players, all spectators and the event
+// streams of a game, numbered after the events sent to it before
+func (h *Hub) sendToGame(gameID string, msg messages.OutboundMessage) {
+	msg.GameID = gameID
+	msg.Seq = h.sequence(gameID, msg)
+	h.record(gameID, msg)
+
+	conns := append(h.spectatorsForGame(gameID), h.playersOfGame(gameID)...)
+
+	streamed := h.sendToStreams(gameID, msg)
+
+	if len(conns) == 0 && !streamed {
+		h.logger.Error(
+			"Could not find connection for game",
+			zap.String("game_id", gameID),
+		)
+		return
+	}
+
+	f := newFrame(msg)
+	for _, conn := range conns {
+		h.sendFrame(conn, f)
+	}
+}
+
+// record adds an event of a game to its journal
+func (h *Hub) record(gameID string, msg messages.OutboundMessage) {
+	if h.journal != nil {
+		h.journal.Append(gameID, msg.Seq, msg.Event, msg.Payload)
+	}
+}
+
+// sendToAdmins sends a message to every connection subscribed to the admin topic
+func (h *Hub) sendToAdmins(msg messages.OutboundMessage) {
+	h.mu.RLock()
+	conns := make([]*Connection, 0, len(h.admins))
+	for conn := range h.admins {
+		conns = append(conns, conn)
+	}
+	h.mu.RUnlock()
+
+	f := newFrame(msg)
+	for _, conn := range conns {
+		h.sendFrame(conn, f)
 	}
-	h.sendMessage(conn, resp)
 }
 
 func (h *Hub) sendMessage(conn *Connection, msg messages.OutboundMessage) {
-	conn.SendJSON(msg)
+	h.sendFrame(conn, newFrame(msg))
+}
+
+// sendFrame sends a message encoded once for all the connections sharing
+// its wire format
+func (h *Hub) sendFrame(conn *Connection, f *frame) {
+	if !conn.wants(f.msg) {
+		return
+	}
+
+	data, err := f.encode(conn.codec, conn.version)
+	if err != nil {
+		conn.logger.Error("Error encoding message", zap.Error(err))
+		return
+	}
+
+	if f.coalesce {
+		conn.sendLatestEncoded(f.key, data)
+		return
+	}
+	conn.sendEncoded(data)
+}
+
+// DeliveryStats counts the messages the hub could not deliver
+type DeliveryStats struct {
+	DroppedMessages     int64 `json:"dropped_messages"`
+	OverflowDisconnects int64 `json:"overflow_disconnects"`
+}
+
+// DeliveryStats returns the delivery counters since the hub started
+func (h *Hub) DeliveryStats() DeliveryStats {
+	return DeliveryStats{
+		DroppedMessages:     h.droppedMessages.Load(),
+		OverflowDisconnects: h.overflowDisconnects.Load(),
+	}
+}
+
+// Running reports whether the hub routes messages and has not been shut down
+func (h *Hub) Running() bool {
+	select {
+	case <-h.done:
+		return false
+	default:
+		return h.running.Load()
+	}
 }
 
 func (h *Hub) Shutdown() error {
+	h.doneOnce.Do(func() {
+		close(h.done)
+		h.cancel()
+	})
 	return nil
 }
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/auth"
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/engine"
+	"github.com/tecu23/eng-server/pkg/events"
+	"github.com/tecu23/eng-server/pkg/game"
+	"github.com/tecu23/eng-server/pkg/manager"
+	"github.com/tecu23/eng-server/pkg/match"
+	"github.com/tecu23/eng-server/pkg/rating"
+	"github.com/tecu23/eng-server/pkg/repository"
+	"github.com/tecu23/eng-server/pkg/tournament"
+)
+
+// testHub is a hub whose games are played by mock engines, reached over
+// websockets like in the server
+type testHub struct {
+	hub *Hub
+	url string
+}
+
+func newTestHub(t *testing.T) *testHub {
+	t.Helper()
+
+	logger := zap.NewNop()
+	publisher := events.NewPublisher(logger)
+	repo := repository.NewInMemoryRepository(logger)
+
+	pool := engine.NewEnginePool(engine.MockPrefix+"latency=1ms", engine.Scaling{MaxEngines: 8}, engine.Limits{}, nil, logger)
+	require.NoError(t, pool.Initialize())
+	t.Cleanup(pool.Shutdown)
+
+	gm := manager.NewManager(repo, pool, "", nil, nil, game.AdjudicationRules{}, rating.Rating{},
+		game.HintSettings{}, 0, 0, nil, nil, engine.NewProfiles(), nil, logger, publisher)
+	runner := match.NewRunner(nil, engine.Limits{}, nil, nil, game.AdjudicationRules{}, repo, publisher, logger)
+	lobby := tournament.NewLobby(publisher, logger)
+
+	hub := NewHub(gm, runner, lobby, publisher, messages.BuildInfo{}, nil, nil, LoadShedding{}, 1, logger)
+	lobby.SetHost(hub)
+	go hub.Run()
+	t.Cleanup(func() { _ = hub.Shutdown() })
+
+	th := &testHub{hub: hub}
+
+	var upgrader websocket.Upgrader
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+
+		key := r.URL.Query().Get("key")
+		conn := NewConnection(ws, hub, Compression{}, auth.RolePlayer, key, r.URL.Query().Get("user"), nil, publisher, logger)
+		hub.Register(conn)
+
+		go conn.WritePump()
+		go conn.ReadPump()
+	}))
+	t.Cleanup(srv.Close)
+	th.url = "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	return th
+}
+
+// testClient is a websocket client of a test hub
+type testClient struct {
+	t  *testing.T
+	ws *websocket.Conn
+}
+
+// dial connects a client authenticated with a key of a user
+func (th *testHub) dial(t *testing.T, key, user string) *testClient {
+	t.Helper()
+
+	ws, _, err := websocket.DefaultDialer.Dial(th.url+"?key="+key+"&user="+user, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ws.Close() })
+
+	return &testClient{t: t, ws: ws}
+}
+
+// send sends an inbound message
+func (c *testClient) send(event string, payload any) {
+	c.t.Helper()
+
+	raw, err := json.Marshal(payload)
+	require.NoError(c.t, err)
+	require.NoError(c.t, c.ws.WriteJSON(messages.InboundMessage{Event: event, Payload: raw}))
+}
+
+// await reads messages until one of the event arrives, decoding its payload
+func (c *testClient) await(event string, payload any) {
+	c.t.Helper()
+
+	for {
+		require.NoError(c.t, c.ws.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+		var msg struct {
+			Event   string          `json:"event"`
+			Payload json.RawMessage `json:"payload"`
+		}
+		require.NoError(c.t, c.ws.ReadJSON(&msg), "waiting for %s", event)
+
+		if msg.Event == event {
+			if payload != nil {
+				require.NoError(c.t, json.Unmarshal(msg.Payload, payload))
+			}
+			return
+		}
+	}
+}
+
+// awaitError reads messages until an error arrives, returning its code
+func (c *testClient) awaitError() messages.ErrorCode {
+	c.t.Helper()
+
+	var payload messages.ErrorPayload
+	c.await("ERROR", &payload)
+	return payload.Code
+}
+
+// createGame creates a game against the engine, returning its ID
+func (c *testClient) createGame() string {
+	c.t.Helper()
+
+	c.send("CREATE_SESSION", messages.CreateSession{
+		TimeControl: messages.TimeControl{WhiteTime: 60000, BlackTime: 60000},
+	})
+
+	var created messages.GameCreatedPayload
+	c.await("GAME_CREATED", &created)
+	return created.GameID
+}
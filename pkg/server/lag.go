@@ -0,0 +1,56 @@
+package server
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// pingInterval is how often the round trip to the client is measured
+const pingInterval = 5 * time.Second
+
+// lagSmoothing is the weight of a new measurement in the lag estimate
+const lagSmoothing = 0.25
+
+// ping sends a ping frame carrying the time it was sent
+func (c *Connection) ping() error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	sent := strconv.FormatInt(time.Now().UnixNano(), 10)
+	return c.ws.WriteControl(websocket.PingMessage, []byte(sent), time.Now().Add(time.Second))
+}
+
+// handlePong updates the lag estimate from the round trip of a ping
+func (c *Connection) handlePong(data string) error {
+	sent, err := strconv.ParseInt(data, 10, 64)
+	if err != nil {
+		// Unsolicited pongs carry no timestamp
+		return nil
+	}
+
+	// The lag of a move is the one way trip from the client
+	lag := time.Since(time.Unix(0, sent)) / 2
+	if lag < 0 {
+		return nil
+	}
+
+	c.lagMu.Lock()
+	defer c.lagMu.Unlock()
+
+	if c.lag == 0 {
+		c.lag = lag
+	} else {
+		c.lag += time.Duration(lagSmoothing * float64(lag-c.lag))
+	}
+	return nil
+}
+
+// Lag returns the estimated one way network lag of the connection, 0 until measured
+func (c *Connection) Lag() time.Duration {
+	c.lagMu.Lock()
+	defer c.lagMu.Unlock()
+
+	return c.lag
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/tecu23/eng-server/internal/messages"
+)
+
+// Outbound rate limits of a connection
+const (
+	maxMessagesPerSecond = 20                    // Sustained rate of messages written to a connection
+	messageBurst         = 40                    // Messages that may be written at once after a quiet period
+	flushInterval        = 50 * time.Millisecond // How often coalesced updates are released
+	maxOverflow          = 5 * time.Second       // How long the send buffer may stay full before the connection is dropped
+)
+
+// coalesceKey returns the key under which high frequency updates replace each
+// other while waiting to be written, false for messages that must all be delivered
+func coalesceKey(msg messages.OutboundMessage) (string, bool) {
+	switch p := msg.Payload.(type) {
+	case messages.ClockUpdatePayload:
+		return msg.Event + "/" + p.GameID, true
+	case messages.AnalysisUpdatePayload:
+		return msg.Event + "/" + p.AnalysisID, true
+	case messages.EvalPayload:
+		return msg.Event + "/" + p.GameID + "/" + p.Source + "/" + string(p.Color), true
+	case DashboardPayload:
+		return msg.Event, true
+	default:
+		return "", false
+	}
+}
+
+// outbox holds the latest coalesced update of each key until the rate limit
+// lets it through. Newer updates replace stale ones instead of queueing up
+type outbox struct {
+	mu      sync.Mutex
+	pending map[string][]byte
+	order   []string // Keys in the order their first pending update arrived
+
+	tokens   float64 // Messages that may be written right now
+	lastFill time.Time
+}
+
+func newOutbox() *outbox {
+	return &outbox{
+		pending:  make(map[string][]byte),
+		tokens:   messageBurst,
+		lastFill: time.Now(),
+	}
+}
+
+// put stores an update, replacing the pending one with the same key, and
+// reports whether a stale update was dropped
+func (o *outbox) put(key string, data []byte) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	_, replaced := o.pending[key]
+	if !replaced {
+		o.order = append(o.order, key)
+	}
+	o.pending[key] = data
+	return replaced
+}
+
+// take returns the pending updates the rate limit allows to write now
+func (o *outbox) take() [][]byte {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.refill()
+
+	var ready [][]byte
+	for len(o.order) > 0 && o.tokens >= 1 {
+		key := o.order[0]
+		o.order = o.order[1:]
+
+		ready = append(ready, o.pending[key])
+		delete(o.pending, key)
+		o.tokens--
+	}
+	return ready
+}
+
+// spend accounts for a message written outside the outbox, which is never held back
+func (o *outbox) spend() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.refill()
+	o.tokens = max(o.tokens-1, -messageBurst)
+}
+
+// refill adds the tokens earned since the last refill
+func (o *outbox) refill() {
+	now := time.Now()
+	o.tokens = min(messageBurst, o.tokens+now.Sub(o.lastFill).Seconds()*maxMessagesPerSecond)
+	o.lastFill = now
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/tecu23/eng-server/internal/messages"
+)
+
+// Event classes a connection may mute, the events that decide a game are
+// always sent
+var mutableEvents = map[string]string{
+	"clock":       "CLOCK_UPDATE",
+	"eval":        "EVAL_UPDATE",
+	"presence":    "PRESENCE_UPDATE",
+	"chat":        "CHAT_MESSAGE",
+	"annotations": "BOARD_ANNOTATION",
+}
+
+// Evaluations sent to a connection that does not mute them
+const (
+	EvalAll   = "all"   // Every search update of the engines and the eval bar
+	EvalFinal = "final" // The evaluation each engine move was played on and the eval bar
+)
+
+// preferences are the updates a client chose to receive, so minimal bots do
+// not pay for ticks and search updates they ignore. They are replaced as a
+// whole and never modified once set on a connection
+type preferences struct {
+	muted     map[string]bool // Events not sent
+	finalEval bool
+	request   messages.PreferencesPayload
+}
+
+// newPreferences validates the preferences requested by a client
+func newPreferences(payload messages.SetPreferencesPayload) (*preferences, error) {
+	p := &preferences{
+		muted: make(map[string]bool, len(payload.Mute)),
+		request: messages.PreferencesPayload{
+			Mute: []string{},
+			Eval: EvalAll,
+		},
+	}
+
+	for _, class := range payload.Mute {
+		event, ok := mutableEvents[class]
+		if !ok {
+			return nil, fmt.Errorf("unknown event class %q", class)
+		}
+		if !p.muted[event] {
+			p.muted[event] = true
+			p.request.Mute = append(p.request.Mute, class)
+		}
+	}
+	slices.Sort(p.request.Mute)
+
+	switch payload.Eval {
+	case "", EvalAll:
+	case EvalFinal:
+		p.finalEval = true
+		p.request.Eval = EvalFinal
+	default:
+		return nil, fmt.Errorf("eval must be %s or %s", EvalAll, EvalFinal)
+	}
+
+	return p, nil
+}
+
+// wants reports whether a message is sent to the connection
+func (p *preferences) wants(msg messages.OutboundMessage) bool {
+	if p.muted[msg.Event] {
+		return false
+	}
+	if eval, ok := msg.Payload.(messages.EvalPayload); ok && p.finalEval {
+		return eval.Final
+	}
+	return true
+}
+
+// SetPreferences replaces the updates the connection receives
+func (c *Connection) SetPreferences(payload messages.SetPreferencesPayload) (messages.PreferencesPayload, error) {
+	p, err := newPreferences(payload)
+	if err != nil {
+		return messages.PreferencesPayload{}, err
+	}
+
+	c.prefs.Store(p)
+	return p.request, nil
+}
+
+// wants reports whether the client chose to receive a message, every message
+// until it sets preferences
+func (c *Connection) wants(msg messages.OutboundMessage) bool {
+	p := c.prefs.Load()
+	return p == nil || p.wants(msg)
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/events"
+)
+
+// presence returns whether the players of a game are connected and who watches it
+func (h *Hub) presence(gameID string) messages.PresencePayload {
+	s := h.shard(gameID)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	presence := messages.PresencePayload{
+		GameID:          gameID,
+		PlayerConnected: s.owners[gameID] != nil,
+		Spectators:      len(s.spectators[gameID]),
+		Streams:         len(s.streams[gameID]),
+	}
+	if _, ok := s.opponentKeys[gameID]; ok {
+		connected := s.opponents[gameID] != nil
+		presence.OpponentConnected = &connected
+	}
+	return presence
+}
+
+// publishPresence publishes the presence of games whose player or watchers changed
+func (h *Hub) publishPresence(gameIDs ...string) {
+	for _, gameID := range gameIDs {
+		h.publisher.Publish(events.Event{
+			Type:    events.EventPresenceUpdated,
+			GameID:  gameID,
+			Payload: h.presence(gameID),
+		})
+	}
+}
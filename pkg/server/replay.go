@@ -0,0 +1,101 @@
+package server
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/journal"
+)
+
+// replayWriteWait bounds the write of a replayed event to a client
+const replayWriteWait = 10 * time.Second
+
+// Replay sends the journal of a game over a websocket, in the wire encoding
+// the client negotiated, keeping the original gaps between the events divided
+// by speed. A speed of 0 sends the events without waiting. The replay ends
+// when every event was sent or the client closed the connection
+func Replay(ws *websocket.Conn, gameID string, entries []journal.Entry, speed float64, logger *zap.Logger) {
+	defer ws.Close()
+
+	codec := codecFor(ws.Subprotocol())
+	version := envelopeFor(ws.Subprotocol())
+	logger = logger.With(zap.String("game_id", gameID))
+
+	// Reading is how a closed connection is noticed, the client sends nothing
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := ws.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(msg messages.OutboundMessage, sent time.Time) bool {
+		msg.GameID = gameID
+		data, err := encodeMessage(codec, version, msg, sent)
+		if err != nil {
+			logger.Error("could not encode replayed event", zap.String("event", msg.Event), zap.Error(err))
+			return true
+		}
+
+		_ = ws.SetWriteDeadline(time.Now().Add(replayWriteWait))
+		if err := ws.WriteMessage(codec.frameType(), data); err != nil {
+			logger.Info("replay client went away", zap.Error(err))
+			return false
+		}
+		return true
+	}
+
+	started := messages.ReplayStartedPayload{GameID: gameID, Events: len(entries), Speed: speed}
+	if len(entries) > 0 {
+		started.DurationMs = entries[len(entries)-1].Time.Sub(entries[0].Time).Milliseconds()
+	}
+	if !send(messages.OutboundMessage{Event: "REPLAY_STARTED", Payload: started}, time.Now()) {
+		return
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for i, entry := range entries {
+		if i > 0 && speed > 0 {
+			gap := time.Duration(float64(entry.Time.Sub(entries[i-1].Time)) / speed)
+			timer.Reset(max(gap, 0))
+
+			select {
+			case <-timer.C:
+			case <-closed:
+				return
+			}
+		}
+
+		// Decoded so MessagePack clients get maps rather than JSON text
+		var payload any
+		if err := json.Unmarshal(entry.Payload, &payload); err != nil {
+			logger.Error("could not decode replayed event", zap.String("event", entry.Event), zap.Error(err))
+			continue
+		}
+
+		// Replayed envelopes keep the time the event was first sent
+		if !send(messages.OutboundMessage{Event: entry.Event, Payload: payload, Seq: entry.Seq}, entry.Time) {
+			return
+		}
+	}
+
+	if !send(messages.OutboundMessage{
+		Event:   "REPLAY_FINISHED",
+		Payload: messages.ReplayFinishedPayload{GameID: gameID, Events: len(entries)},
+	}, time.Now()) {
+		return
+	}
+
+	_ = ws.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, "replay finished"),
+		time.Now().Add(replayWriteWait))
+}
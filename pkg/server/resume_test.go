@@ -0,0 +1,38 @@
+package server
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/repository"
+)
+
+func TestResumeNeedsUserOfRestoredGame(t *testing.T) {
+	store := repository.NewFileSnapshotStore(filepath.Join(t.TempDir(), "snapshots.json"))
+
+	// The game is snapshotted by one server and restored by the next
+	before := newTestHub(t)
+	gameID := before.dial(t, "k1", "alice").createGame()
+	require.NoError(t, before.hub.gameManager.SnapshotSessions(store))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	after := newTestHub(t)
+	require.NoError(t, after.hub.gameManager.RestoreSessions(ctx, store))
+
+	other := after.dial(t, "k2", "bob")
+	other.send("RESUME_GAME", messages.ResumeGamePayload{GameID: gameID})
+	assert.Equal(t, messages.ErrorForbidden, other.awaitError())
+
+	player := after.dial(t, "k1", "alice")
+	player.send("RESUME_GAME", messages.ResumeGamePayload{GameID: gameID})
+	var state messages.GameStatePayload
+	player.await("GAME_STATE", &state)
+	assert.Equal(t, gameID, state.GameID)
+}
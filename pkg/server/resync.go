@@ -0,0 +1,124 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/journal"
+)
+
+// maxResyncEvents is the most events replayed by RESYNC, well within the send
+// buffer of a connection. A client that missed more reloads GET_GAME_STATE
+const maxResyncEvents = 128
+
+// sequence numbers an event of a game. Events are numbered by the single hub
+// subscriber, so the numbers of a game follow the order of its events. Updates
+// that may be coalesced away repeat the number of the last event instead, so
+// a gap in the numbers always means missed events
+func (h *Hub) sequence(gameID string, msg messages.OutboundMessage) int64 {
+	s := h.shard(gameID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, coalesce := coalesceKey(msg); !coalesce {
+		s.seqs[gameID]++
+	}
+	return s.seqs[gameID]
+}
+
+// openJournal starts recording the events of a game. A restored game goes on
+// numbering its events after the ones already in its journal
+func (h *Hub) openJournal(gameID string) {
+	if h.journal == nil {
+		return
+	}
+
+	h.journal.Open(gameID)
+
+	last, err := h.journal.LastSeq(gameID)
+	if err != nil {
+		h.logger.Warn("could not read the last event of the journal", zap.String("game_id", gameID), zap.Error(err))
+		return
+	}
+
+	s := h.shard(gameID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seqs[gameID] = max(s.seqs[gameID], last)
+}
+
+// resync replays from the journal the events of a game a client missed after
+// a transient disconnect, then RESYNCED. Live events may be sent while the
+// missed ones are replayed, clients drop the sequence numbers they already have
+func (h *Hub) resync(msg InboundHubMessage, payload messages.ResyncPayload) {
+	if h.journal == nil {
+		h.replyError(msg, messages.ErrorInvalidRequest, "Events are not journaled on this server")
+		return
+	}
+
+	// The session is gone once the game is over, its journal is still there
+	if !h.playing(msg.Conn, payload.GameID) && !h.spectating(msg.Conn, payload.GameID) {
+		h.replyError(msg, messages.ErrorForbidden, "Not following game "+payload.GameID)
+		return
+	}
+
+	entries, err := h.journal.Read(payload.GameID)
+	if errors.Is(err, journal.ErrNotFound) {
+		h.replyError(msg, messages.ErrorGameNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		h.requestLogger(msg).Error("could not read the event journal", zap.Error(err))
+		h.replyError(msg, messages.ErrorInternal, "Could not read the events of the game")
+		return
+	}
+
+	missed := entries[:0]
+	for _, entry := range entries {
+		if entry.Seq > payload.LastSeq {
+			missed = append(missed, entry)
+		}
+	}
+	if len(missed) > maxResyncEvents {
+		h.replyError(msg, messages.ErrorInvalidRequest, "Too many missed events, reload the game with GET_GAME_STATE")
+		return
+	}
+
+	for _, entry := range missed {
+		// Decoded so MessagePack clients get maps rather than JSON text
+		var p any
+		if err := json.Unmarshal(entry.Payload, &p); err != nil {
+			h.requestLogger(msg).Error("could not decode journaled event", zap.String("event", entry.Event), zap.Error(err))
+			continue
+		}
+
+		h.sendMessage(msg.Conn, messages.OutboundMessage{Event: entry.Event, Payload: p, Seq: entry.Seq, GameID: payload.GameID})
+	}
+
+	last := payload.LastSeq
+	if len(entries) > 0 {
+		last = max(last, entries[len(entries)-1].Seq)
+	}
+
+	h.reply(msg, messages.OutboundMessage{
+		Event: "RESYNCED",
+		Payload: messages.ResyncedPayload{
+			GameID:  payload.GameID,
+			Events:  len(missed),
+			LastSeq: last,
+		},
+	})
+}
+
+// spectating reports whether a connection watches a game
+func (h *Hub) spectating(conn *Connection, gameID string) bool {
+	s := h.shard(gameID)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.spectators[gameID][conn]
+}
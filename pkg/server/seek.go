@@ -0,0 +1,50 @@
+package server
+
+import (
+	"github.com/google/uuid"
+
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/game"
+	"github.com/tecu23/eng-server/pkg/manager"
+)
+
+// parseSeekID parses the seek ID of a message, replying with an error when
+// it is invalid
+func (h *Hub) parseSeekID(msg InboundHubMessage, seekID string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(seekID)
+	if err != nil {
+		h.replyError(msg, messages.ErrorInvalidPayload, "Invalid seek id")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// seeksList returns the open seeks of the seek pool
+func (h *Hub) seeksList() messages.SeeksListPayload {
+	seeks := h.gameManager.Seeks()
+
+	payload := messages.SeeksListPayload{Seeks: make([]messages.SeekPayload, 0, len(seeks))}
+	for _, seek := range seeks {
+		payload.Seeks = append(payload.Seeks, seek.Payload())
+	}
+	return payload
+}
+
+// sendSeekMatched tells both players of an accepted seek the color they play
+// in its game, whose events follow like those of any other game
+func (h *Hub) sendSeekMatched(seeker, accepter *Connection, seek manager.Seek, session *game.Game) {
+	for _, conn := range []*Connection{seeker, accepter} {
+		clr, _ := session.ColorOf(conn.ID)
+		h.sendMessage(conn, messages.OutboundMessage{
+			Event:  "SEEK_MATCHED",
+			GameID: session.ID.String(),
+			Payload: messages.SeekMatchedPayload{
+				SeekID:   seek.ID.String(),
+				GameID:   session.ID.String(),
+				Color:    clr,
+				Opponent: session.UserOf(clr.Opp()),
+				Rated:    seek.Rated,
+			},
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tecu23/eng-server/internal/color"
+	"github.com/tecu23/eng-server/internal/messages"
+)
+
+// createSeek opens a seek where the client plays White, returning its ID
+func (c *testClient) createSeek() string {
+	c.t.Helper()
+
+	c.send("CREATE_SEEK", messages.CreateSeekPayload{
+		TimeControl: messages.TimeControl{WhiteTime: 60000, BlackTime: 60000},
+		Color:       color.White,
+	})
+
+	var seek messages.SeekPayload
+	c.await("SEEK_CREATED", &seek)
+	return seek.SeekID
+}
+
+// matchPlayers pairs two clients through a seek, returning the game ID. The
+// seeker plays White
+func matchPlayers(t *testing.T, white, black *testClient) string {
+	t.Helper()
+
+	seekID := white.createSeek()
+	black.send("ACCEPT_SEEK", messages.SeekRequestPayload{SeekID: seekID})
+
+	var matched messages.SeekMatchedPayload
+	white.await("SEEK_MATCHED", &matched)
+	assert.Equal(t, color.Color(color.White), matched.Color)
+	assert.Equal(t, "bob", matched.Opponent)
+
+	black.await("SEEK_MATCHED", &matched)
+	assert.Equal(t, color.Color(color.Black), matched.Color)
+	assert.Equal(t, "alice", matched.Opponent)
+
+	return matched.GameID
+}
+
+func TestSeekMatchesPlayersWhoAlternateMoves(t *testing.T) {
+	th := newTestHub(t)
+
+	white := th.dial(t, "k1", "alice")
+	black := th.dial(t, "k2", "bob")
+	gameID := matchPlayers(t, white, black)
+
+	// Black cannot move for White, nor White twice in a row
+	black.send("MAKE_MOVE", messages.MakeMovePayload{GameID: gameID, Move: "e7e5"})
+	assert.Equal(t, messages.ErrorNotYourTurn, black.awaitError())
+
+	white.send("MAKE_MOVE", messages.MakeMovePayload{GameID: gameID, Move: "e2e4"})
+	white.await("MOVE_PROCESSED", nil)
+	black.await("MOVE_PROCESSED", nil)
+
+	white.send("MAKE_MOVE", messages.MakeMovePayload{GameID: gameID, Move: "d2d4"})
+	assert.Equal(t, messages.ErrorNotYourTurn, white.awaitError())
+
+	black.send("MAKE_MOVE", messages.MakeMovePayload{GameID: gameID, Move: "e7e5"})
+	var state messages.GameStatePayload
+	white.await("MOVE_PROCESSED", &state)
+	assert.Equal(t, []string{"e4", "e5"}, state.Moves)
+
+	// Assistance from an engine is not available against another player
+	white.send("REQUEST_HINT", messages.RequestHintPayload{GameID: gameID})
+	assert.NotEmpty(t, white.awaitError())
+
+	black.send("RESIGN", messages.ResignPayload{GameID: gameID})
+	var over messages.GameOverPayload
+	white.await("GAME_OVER", &over)
+	assert.Equal(t, "1-0", over.Result)
+}
+
+func TestSeekGameRefusesOtherConnections(t *testing.T) {
+	th := newTestHub(t)
+
+	white := th.dial(t, "k1", "alice")
+	black := th.dial(t, "k2", "bob")
+	gameID := matchPlayers(t, white, black)
+
+	other := th.dial(t, "k3", "carol")
+	other.send("MAKE_MOVE", messages.MakeMovePayload{GameID: gameID, Move: "e2e4"})
+	assert.Equal(t, messages.ErrorForbidden, other.awaitError())
+
+	other.send("RESIGN", messages.ResignPayload{GameID: gameID})
+	assert.Equal(t, messages.ErrorForbidden, other.awaitError())
+}
+
+func TestSeekCannotBeAcceptedTwiceOrByItsPlayer(t *testing.T) {
+	th := newTestHub(t)
+
+	seeker := th.dial(t, "k1", "alice")
+	seekID := seeker.createSeek()
+
+	seeker.send("ACCEPT_SEEK", messages.SeekRequestPayload{SeekID: seekID})
+	assert.NotEmpty(t, seeker.awaitError())
+
+	var seeks messages.SeeksListPayload
+	seeker.send("LIST_SEEKS", nil)
+	seeker.await("SEEKS_LIST", &seeks)
+	require.Len(t, seeks.Seeks, 1)
+	assert.Equal(t, seekID, seeks.Seeks[0].SeekID)
+
+	first := th.dial(t, "k2", "bob")
+	first.send("ACCEPT_SEEK", messages.SeekRequestPayload{SeekID: seekID})
+	first.await("SEEK_MATCHED", nil)
+
+	second := th.dial(t, "k3", "carol")
+	second.send("ACCEPT_SEEK", messages.SeekRequestPayload{SeekID: seekID})
+	assert.Equal(t, messages.ErrorSeekNotFound, second.awaitError())
+}
+
+func TestCancelledSeekLeavesThePool(t *testing.T) {
+	th := newTestHub(t)
+
+	seeker := th.dial(t, "k1", "alice")
+	seekID := seeker.createSeek()
+
+	var seeks messages.SeeksListPayload
+	seeker.send("CANCEL_SEEK", messages.SeekRequestPayload{SeekID: seekID})
+	seeker.await("SEEKS_LIST", &seeks)
+	assert.Empty(t, seeks.Seeks)
+
+	other := th.dial(t, "k2", "bob")
+	other.send("ACCEPT_SEEK", messages.SeekRequestPayload{SeekID: seekID})
+	assert.Equal(t, messages.ErrorSeekNotFound, other.awaitError())
+}
+
+func TestClaimVictoryOverAbsentOpponent(t *testing.T) {
+	th := newTestHub(t)
+
+	white := th.dial(t, "k1", "alice")
+	black := th.dial(t, "k2", "bob")
+	gameID := matchPlayers(t, white, black)
+
+	white.send("CLAIM_VICTORY", messages.ClaimVictoryPayload{GameID: gameID})
+	assert.Equal(t, messages.ErrorInvalidRequest, white.awaitError())
+
+	require.NoError(t, black.ws.Close())
+	for {
+		var presence messages.PresencePayload
+		white.await("PRESENCE_UPDATE", &presence)
+		require.NotNil(t, presence.OpponentConnected)
+		if !*presence.OpponentConnected {
+			break
+		}
+	}
+
+	white.send("CLAIM_VICTORY", messages.ClaimVictoryPayload{GameID: gameID})
+	var over messages.GameOverPayload
+	white.await("GAME_OVER", &over)
+	assert.Equal(t, "abandonment", over.Reason)
+	assert.Equal(t, "1-0", over.Result)
+}
+
+func TestOpponentReclaimsSeat(t *testing.T) {
+	th := newTestHub(t)
+
+	white := th.dial(t, "k1", "alice")
+	black := th.dial(t, "k2", "bob")
+	gameID := matchPlayers(t, white, black)
+
+	white.send("MAKE_MOVE", messages.MakeMovePayload{GameID: gameID, Move: "e2e4"})
+	white.await("MOVE_PROCESSED", nil)
+
+	// Another key cannot take the seat of Black
+	other := th.dial(t, "k3", "carol")
+	other.send("CLAIM_GAME", messages.ClaimGamePayload{GameID: gameID})
+	assert.Equal(t, messages.ErrorForbidden, other.awaitError())
+
+	back := th.dial(t, "k2", "bob")
+	back.send("CLAIM_GAME", messages.ClaimGamePayload{GameID: gameID, Previous: ClaimClose})
+	back.await("GAME_STATE", nil)
+
+	back.send("MAKE_MOVE", messages.MakeMovePayload{GameID: gameID, Move: "e7e5"})
+	back.await("MOVE_PROCESSED", nil)
+
+	white.send("CLAIM_VICTORY", messages.ClaimVictoryPayload{GameID: gameID})
+	assert.Equal(t, messages.ErrorInvalidRequest, white.awaitError())
+}
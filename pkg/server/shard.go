@@ -0,0 +1,89 @@
+package server
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"sync"
+)
+
+// shardQueueSize is how many messages a shard holds before the connections
+// sending to it block
+const shardQueueSize = 64
+
+// gameShard handles the messages and holds the routing of the games hashed
+// to it. Messages of a game are handled in order by the goroutine of its
+// shard, messages of games of different shards in parallel
+type gameShard struct {
+	inbound chan InboundHubMessage
+
+	mu           sync.RWMutex
+	owners       map[string]*Connection          // Maps game IDs to the connections playing them
+	ownerKeys    map[string]string               // Maps game IDs to the API key of their player, kept while the player is away
+	opponents    map[string]*Connection          // Maps games between players to the connections playing the other color
+	opponentKeys map[string]string               // Maps games between players to the API key of the other player, kept while they are away
+	spectators   map[string]map[*Connection]bool // Maps game IDs to the connections watching them
+	streams      map[string]map[*Stream]bool     // Maps game IDs to the event streams following them
+	seqs         map[string]int64                // Maps game IDs to the sequence number of their last event
+}
+
+func newGameShard() *gameShard {
+	return &gameShard{
+		inbound:      make(chan InboundHubMessage, shardQueueSize),
+		owners:       make(map[string]*Connection),
+		ownerKeys:    make(map[string]string),
+		opponents:    make(map[string]*Connection),
+		opponentKeys: make(map[string]string),
+		spectators:   make(map[string]map[*Connection]bool),
+		streams:      make(map[string]map[*Stream]bool),
+		seqs:         make(map[string]int64),
+	}
+}
+
+// shard returns the shard of a game
+func (h *Hub) shard(gameID string) *gameShard {
+	f := fnv.New32a()
+	_, _ = f.Write([]byte(gameID))
+	return h.shards[f.Sum32()%uint32(len(h.shards))]
+}
+
+// enqueue hands a message of a client to the shard of its game, or to the
+// control loop when it is about no game, counting the messages waiting
+func (h *Hub) enqueue(msg InboundHubMessage) {
+	depth := h.inboundQueue.Add(1)
+	for {
+		peak := h.inboundPeak.Load()
+		if depth <= peak || h.inboundPeak.CompareAndSwap(peak, depth) {
+			break
+		}
+	}
+
+	if gameID := routedGameID(msg); gameID != "" {
+		h.shard(gameID).inbound <- msg
+		return
+	}
+	h.inbound <- msg
+}
+
+// runShard handles the messages of the games of a shard
+func (h *Hub) runShard(s *gameShard) {
+	for msg := range s.inbound {
+		h.inboundQueue.Add(-1)
+		h.handleInbound(msg)
+	}
+}
+
+// routedGameID returns the game an inbound message is about, empty for
+// messages handled by the control loop, e.g. the ones creating games
+func routedGameID(msg InboundHubMessage) string {
+	var ids struct {
+		GameID string `json:"game_id"`
+		Legacy string `json:"gameId"` // Spelling of the RESIGN payload
+	}
+	if err := json.Unmarshal(msg.Message.Payload, &ids); err != nil {
+		return ""
+	}
+	if ids.GameID != "" {
+		return ids.GameID
+	}
+	return ids.Legacy
+}
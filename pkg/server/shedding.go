@@ -0,0 +1,72 @@
+package server
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/messages"
+)
+
+// LoadShedding refuses new games while the server is saturated, so clients
+// back off and retry instead of waiting for an engine that does not come
+type LoadShedding struct {
+	MaxInboundQueue int           // Messages waiting for the hub above which new games are refused, 0 disables the check
+	RetryAfter      time.Duration // Hint sent with the refusals
+}
+
+// LoadStats reports how saturated the server is
+type LoadStats struct {
+	Saturated        bool  `json:"saturated"`          // New games are refused
+	PoolExhausted    bool  `json:"pool_exhausted"`     // Every engine is busy and the pool cannot grow
+	InboundQueue     int64 `json:"inbound_queue"`      // Messages of the clients waiting for the hub
+	InboundQueuePeak int64 `json:"inbound_queue_peak"` // Deepest inbound queue since startup
+	MaxInboundQueue  int   `json:"max_inbound_queue"`  // Threshold of the inbound queue, 0 when not checked
+	ShedRequests     int64 `json:"shed_requests"`      // New games refused since startup
+}
+
+// LoadStats returns the current saturation of the server
+func (h *Hub) LoadStats() LoadStats {
+	pool := h.gameManager.EnginePoolStats()
+
+	stats := LoadStats{
+		PoolExhausted:    pool.Available == 0 && pool.Size >= pool.Max,
+		InboundQueue:     h.inboundQueue.Load(),
+		InboundQueuePeak: h.inboundPeak.Load(),
+		MaxInboundQueue:  h.shedding.MaxInboundQueue,
+		ShedRequests:     h.shedRequests.Load(),
+	}
+	stats.Saturated = stats.PoolExhausted ||
+		(stats.MaxInboundQueue > 0 && stats.InboundQueue > int64(stats.MaxInboundQueue))
+
+	return stats
+}
+
+// shed refuses a request creating games with SERVER_BUSY while the server is
+// saturated, and reports whether it did
+func (h *Hub) shed(msg InboundHubMessage) bool {
+	stats := h.LoadStats()
+	if !stats.Saturated {
+		return false
+	}
+
+	h.shedRequests.Add(1)
+
+	reason := "every engine is busy"
+	if !stats.PoolExhausted {
+		reason = "too many requests are waiting"
+	}
+	h.requestLogger(msg).Warn("Refused new game, server is saturated", zap.String("reason", reason))
+
+	h.reply(msg, messages.OutboundMessage{
+		Event: "ERROR",
+		Payload: messages.ErrorPayload{
+			Code:         messages.ErrorServerBusy,
+			Message:      "Server is busy, " + reason,
+			Event:        msg.Message.Event,
+			RequestID:    msg.Message.RequestID,
+			RetryAfterMs: h.shedding.RetryAfter.Milliseconds(),
+		},
+	})
+	return true
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"encoding/json"
+
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/messages"
+)
+
+// streamBuffer is how many events a stream holds before it starts dropping them
+const streamBuffer = 64
+
+// Stream receives the outbound events of a game for clients that cannot use
+// WebSockets, e.g. over Server-Sent Events
+type Stream struct {
+	GameID string
+	events chan messages.OutboundMessage
+}
+
+// Events returns the channel the events of the game are delivered on
+func (s *Stream) Events() <-chan messages.OutboundMessage {
+	return s.events
+}
+
+// Subscribe opens a stream of the events of a game, which must be closed with Unsubscribe
+func (h *Hub) Subscribe(gameID string) *Stream {
+	stream := &Stream{
+		GameID: gameID,
+		events: make(chan messages.OutboundMessage, streamBuffer),
+	}
+
+	s := h.shard(gameID)
+	s.mu.Lock()
+	if s.streams[gameID] == nil {
+		s.streams[gameID] = make(map[*Stream]bool)
+	}
+	s.streams[gameID][stream] = true
+	s.mu.Unlock()
+
+	h.logger.Info("Stream subscribed to game", zap.String("game_id", gameID))
+	h.publishPresence(gameID)
+	return stream
+}
+
+// Unsubscribe stops delivering events to a stream
+func (h *Hub) Unsubscribe(stream *Stream) {
+	s := h.shard(stream.GameID)
+	s.mu.Lock()
+	delete(s.streams[stream.GameID], stream)
+	if len(s.streams[stream.GameID]) == 0 {
+		delete(s.streams, stream.GameID)
+	}
+	s.mu.Unlock()
+
+	h.publishPresence(stream.GameID)
+}
+
+// sendToStreams delivers a message to the streams of a game and reports
+// whether there were any. Events a slow stream has no room for are dropped
+func (h *Hub) sendToStreams(gameID string, msg messages.OutboundMessage) bool {
+	s := h.shard(gameID)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for stream := range s.streams[gameID] {
+		select {
+		case stream.events <- msg:
+		default:
+			h.droppedMessages.Add(1)
+		}
+	}
+
+	return len(s.streams[gameID]) > 0
+}
+
+// EncodeEvent returns a message in the Server-Sent Events format, named
+// after the event and carrying its payload as JSON
+func EncodeEvent(msg messages.OutboundMessage) ([]byte, error) {
+	data, err := json.Marshal(msg.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(msg.Event)+len(data)+16)
+	out = append(out, "event: "...)
+	out = append(out, msg.Event...)
+	out = append(out, "\ndata: "...)
+	out = append(out, data...)
+	out = append(out, "\n\n"...)
+	return out, nil
+}
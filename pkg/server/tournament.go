@@ -0,0 +1,169 @@
+package server
+
+import (
+	"errors"
+
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/game"
+	"github.com/tecu23/eng-server/pkg/manager"
+	"github.com/tecu23/eng-server/pkg/tournament"
+)
+
+// errPlayerGone is returned for a pairing whose player is no longer connected
+var errPlayerGone = errors.New("the player is not connected")
+
+// StartTournamentGame creates a tournament game on the connection of its
+// player, who is told about it with TOURNAMENT_PAIRING
+func (h *Hub) StartTournamentGame(pairing tournament.Pairing) (*game.Game, error) {
+	conn := h.connectionByID(pairing.ConnectionID.String())
+	if conn == nil {
+		return nil, errPlayerGone
+	}
+
+	if pairing.Opponent != "" {
+		return h.startTournamentPlayerGame(pairing, conn)
+	}
+
+	tc := pairing.TimeControl
+	session, err := h.gameManager.CreateSession(
+		h.ctx,
+		tc.WhiteTime,
+		tc.BlackTime,
+		tc.WhiteIncrement,
+		tc.BlackIncrement,
+		pairing.Color,
+		"",
+		game.VariantStandard,
+		nil,
+		game.TimeManagement{},
+		pairing.Difficulty,
+		game.Pacing{},
+		"",
+		pairing.UserID,
+		false,
+		false,
+		0,
+		false,
+		conn.ID,
+		h.publisher,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	gameID := session.ID.String()
+	h.associateConnectionWithGame(conn, gameID)
+
+	h.sendMessage(conn, messages.OutboundMessage{
+		Event: "TOURNAMENT_PAIRING",
+		Payload: messages.TournamentPairingPayload{
+			TournamentID: pairing.TournamentID.String(),
+			Round:        pairing.Round,
+			GameID:       gameID,
+			Color:        pairing.Color,
+			Difficulty:   string(pairing.Difficulty),
+		},
+	})
+
+	if session.EngineOpens() {
+		if err := session.RequestEngineMove(""); err != nil {
+			h.logger.Error("Could not request engine move", zap.String("game_id", gameID), zap.Error(err))
+		}
+	}
+
+	return session, nil
+}
+
+// startTournamentPlayerGame creates a tournament game between two players,
+// both are told about it with TOURNAMENT_PAIRING
+func (h *Hub) startTournamentPlayerGame(pairing tournament.Pairing, conn *Connection) (*game.Game, error) {
+	opponent := h.connectionByID(pairing.OpponentConnectionID.String())
+	if opponent == nil {
+		return nil, errPlayerGone
+	}
+
+	session, err := h.gameManager.CreatePlayerGame(
+		h.ctx,
+		pairing.TimeControl,
+		"",
+		game.VariantStandard,
+		manager.Player{ConnectionID: conn.ID, UserID: pairing.UserID},
+		pairing.Color,
+		manager.Player{ConnectionID: opponent.ID, UserID: pairing.Opponent},
+		false,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	gameID := session.ID.String()
+	h.associateConnectionWithGame(conn, gameID)
+	h.associateOpponentWithGame(opponent, gameID)
+
+	for _, player := range []*Connection{conn, opponent} {
+		clr, _ := session.ColorOf(player.ID)
+		h.sendMessage(player, messages.OutboundMessage{
+			Event: "TOURNAMENT_PAIRING",
+			Payload: messages.TournamentPairingPayload{
+				TournamentID: pairing.TournamentID.String(),
+				Round:        pairing.Round,
+				GameID:       gameID,
+				Color:        clr,
+				Opponent:     session.UserOf(clr.Opp()),
+			},
+		})
+	}
+
+	return session, nil
+}
+
+// connectionByID finds a registered connection
+func (h *Hub) connectionByID(id string) *Connection {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for conn := range h.connections {
+		if conn.ID.String() == id {
+			return conn
+		}
+	}
+	return nil
+}
+
+// addLobbyMember subscribes a connection to the tournament updates of the lobby
+func (h *Hub) addLobbyMember(conn *Connection) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lobbyMembers[conn] = true
+}
+
+// sendToLobby sends a message to every connection following the lobby
+func (h *Hub) sendToLobby(msg messages.OutboundMessage) {
+	h.mu.RLock()
+	conns := make([]*Connection, 0, len(h.lobbyMembers))
+	for conn := range h.lobbyMembers {
+		conns = append(conns, conn)
+	}
+	h.mu.RUnlock()
+
+	f := newFrame(msg)
+	for _, conn := range conns {
+		h.sendFrame(conn, f)
+	}
+}
+
+// lobbyState lists the tournaments of the lobby
+func (h *Hub) lobbyState() messages.LobbyStatePayload {
+	list := h.lobby.List()
+
+	payload := messages.LobbyStatePayload{
+		Tournaments: make([]messages.TournamentPayload, 0, len(list)),
+	}
+	for _, t := range list {
+		payload.Tournaments = append(payload.Tournaments, t.Summary())
+	}
+	return payload
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tecu23/eng-server/internal/messages"
+)
+
+// createTournament opens a tournament and returns its ID once the lobby announces it
+func (c *testClient) createTournament(payload messages.CreateTournamentPayload) string {
+	c.t.Helper()
+
+	c.send("CREATE_TOURNAMENT", payload)
+
+	var update messages.TournamentPayload
+	c.await("TOURNAMENT_UPDATE", &update)
+	return update.TournamentID
+}
+
+func TestJoinTournamentAsAnotherUserIsForbidden(t *testing.T) {
+	th := newTestHub(t)
+
+	alice := th.dial(t, "k1", "alice")
+	id := alice.createTournament(messages.CreateTournamentPayload{
+		Name:        "Arena",
+		Format:      "arena",
+		TimeControl: messages.TimeControl{WhiteTime: 60000, BlackTime: 60000},
+		DurationMs:  60000,
+	})
+
+	alice.send("JOIN_TOURNAMENT", messages.JoinTournamentPayload{TournamentID: id, UserID: "bob"})
+	assert.Equal(t, messages.ErrorForbidden, alice.awaitError())
+
+	alice.send("LEAVE_TOURNAMENT", messages.TournamentRequestPayload{TournamentID: id, UserID: "bob"})
+	assert.Equal(t, messages.ErrorForbidden, alice.awaitError())
+}
+
+func TestSwissBetweenPlayers(t *testing.T) {
+	th := newTestHub(t)
+
+	alice := th.dial(t, "k1", "alice")
+	bob := th.dial(t, "k2", "bob")
+
+	id := alice.createTournament(messages.CreateTournamentPayload{
+		Name:        "Swiss",
+		Format:      "swiss",
+		Opponents:   "players",
+		TimeControl: messages.TimeControl{WhiteTime: 60000, BlackTime: 60000},
+		Rounds:      1,
+	})
+
+	alice.send("JOIN_TOURNAMENT", messages.JoinTournamentPayload{TournamentID: id})
+	bob.send("JOIN_TOURNAMENT", messages.JoinTournamentPayload{TournamentID: id})
+	for {
+		var update messages.TournamentPayload
+		alice.await("TOURNAMENT_UPDATE", &update)
+		if len(update.Standings) == 2 {
+			break
+		}
+	}
+	alice.send("START_TOURNAMENT", messages.TournamentRequestPayload{TournamentID: id})
+
+	var first, second messages.TournamentPairingPayload
+	alice.await("TOURNAMENT_PAIRING", &first)
+	bob.await("TOURNAMENT_PAIRING", &second)
+
+	require.Equal(t, first.GameID, second.GameID)
+	assert.Equal(t, first.Color.Opp(), second.Color)
+	assert.Equal(t, "bob", first.Opponent)
+	assert.Equal(t, "alice", second.Opponent)
+	assert.Empty(t, first.Difficulty)
+
+	// White moves, then Black resigns
+	white, black := alice, bob
+	if first.Color != "w" {
+		white, black = bob, alice
+	}
+	white.send("MAKE_MOVE", messages.MakeMovePayload{GameID: first.GameID, Move: "e2e4"})
+	black.await("MOVE_PROCESSED", nil)
+	black.send("RESIGN", messages.ResignPayload{GameID: first.GameID})
+
+	for {
+		var update messages.TournamentPayload
+		alice.await("TOURNAMENT_UPDATE", &update)
+		if update.Status != "finished" {
+			continue
+		}
+
+		require.Len(t, update.Standings, 2)
+		assert.Equal(t, "players", update.Opponents)
+		assert.Equal(t, 1.0, update.Standings[0].Points)
+		assert.Equal(t, 1, update.Standings[1].Losses)
+		assert.Empty(t, update.Standings[0].Difficulty)
+		return
+	}
+}
@@ -0,0 +1,323 @@
+// Package simulate drives concurrent games against a running server over its
+// WebSocket API, the player moves chosen from a seed, so the whole path from
+// the hub through the game manager to the engines can be load tested. Paired
+// with the mock engine the runs need no engine binary and are repeatable
+package simulate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"github.com/gorilla/websocket"
+
+	"github.com/tecu23/eng-server/internal/color"
+	"github.com/tecu23/eng-server/internal/messages"
+)
+
+// writeWait bounds the write of a message to the server
+const writeWait = 10 * time.Second
+
+// Config describes a simulation run
+type Config struct {
+	URL         string               // WebSocket endpoint of the server, e.g. ws://localhost:8080/ws
+	APIKey      string               // Sent as X-Api-Key
+	Games       int                  // Games played at the same time
+	Moves       int                  // Moves of the player before resigning, 0 plays every game to its end
+	Think       time.Duration        // Time the player takes before each move
+	TimeControl messages.TimeControl // Clocks of every game
+	Seed        int64                // Picks the moves of the players, game i uses Seed+i
+	Timeout     time.Duration        // Bounds every game, 0 means no bound
+}
+
+// GameResult is the outcome of a single simulated game
+type GameResult struct {
+	GameID     string  `json:"game_id,omitempty"`
+	Result     string  `json:"result,omitempty"` // 1-0, 0-1 or 1/2-1/2
+	Reason     string  `json:"reason,omitempty"`
+	Moves      int     `json:"moves"` // Moves of the player
+	Plies      int     `json:"plies"` // Moves of both sides
+	DurationMs int64   `json:"duration_ms"`
+	Replies    []int64 `json:"-"` // Milliseconds from each player move to the engine answer
+	Error      string  `json:"error,omitempty"`
+}
+
+// Latency summarizes the time the engine took to answer the player moves, as
+// seen by the clients
+type Latency struct {
+	Replies int   `json:"replies"`
+	P50Ms   int64 `json:"p50_ms"`
+	P95Ms   int64 `json:"p95_ms"`
+	P99Ms   int64 `json:"p99_ms"`
+	MaxMs   int64 `json:"max_ms"`
+}
+
+// Report is a complete simulation run
+type Report struct {
+	StartedAt  time.Time    `json:"started_at"`
+	DurationMs int64        `json:"duration_ms"`
+	Games      int          `json:"games"`
+	Finished   int          `json:"finished"` // Games that reached GAME_OVER
+	Failed     int          `json:"failed"`
+	Moves      int          `json:"moves"`            // Moves of the players and of the engines
+	MovesPerS  float64      `json:"moves_per_second"` // Over the whole run
+	Latency    Latency      `json:"latency"`
+	Results    []GameResult `json:"results"`
+}
+
+// Run plays the games of the config at the same time and waits for all of them
+func Run(ctx context.Context, cfg Config) (Report, error) {
+	if cfg.Games < 1 {
+		return Report{}, errors.New("at least one game is required")
+	}
+	if cfg.URL == "" {
+		return Report{}, errors.New("no server URL")
+	}
+
+	report := Report{StartedAt: time.Now(), Games: cfg.Games, Results: make([]GameResult, cfg.Games)}
+
+	var wg sync.WaitGroup
+	for i := range cfg.Games {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			gameCtx := ctx
+			if cfg.Timeout > 0 {
+				var cancel context.CancelFunc
+				gameCtx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+				defer cancel()
+			}
+			report.Results[i] = play(gameCtx, cfg, rand.New(rand.NewSource(cfg.Seed+int64(i))))
+		}()
+	}
+	wg.Wait()
+
+	elapsed := time.Since(report.StartedAt)
+	report.DurationMs = elapsed.Milliseconds()
+
+	var replies []int64
+	for _, r := range report.Results {
+		switch {
+		case r.Error != "":
+			report.Failed++
+		case r.Result != "":
+			report.Finished++
+		}
+		report.Moves += r.Plies
+		replies = append(replies, r.Replies...)
+	}
+	if elapsed > 0 {
+		report.MovesPerS = float64(report.Moves) / elapsed.Seconds()
+	}
+	report.Latency = summarize(replies)
+
+	return report, nil
+}
+
+// summarize computes the percentiles of the reply times
+func summarize(replies []int64) Latency {
+	if len(replies) == 0 {
+		return Latency{}
+	}
+
+	slices.Sort(replies)
+	at := func(p int) int64 {
+		return replies[min(len(replies)*p/100, len(replies)-1)]
+	}
+
+	return Latency{
+		Replies: len(replies),
+		P50Ms:   at(50),
+		P95Ms:   at(95),
+		P99Ms:   at(99),
+		MaxMs:   replies[len(replies)-1],
+	}
+}
+
+// inbound is a message of the server, its payload decoded once the event is known
+type inbound struct {
+	Event   string          `json:"event"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// player is the client side of a simulated game, it plays White
+type player struct {
+	cfg Config
+	ws  *websocket.Conn
+	rng *rand.Rand
+
+	gameID string
+	sent   time.Time // When the last move of the player was sent, zero while the engine is not thinking
+
+	result GameResult
+}
+
+// play creates a game and plays it until it is over, the player resigned or
+// the context is done
+func play(ctx context.Context, cfg Config, rng *rand.Rand) GameResult {
+	started := time.Now()
+
+	header := http.Header{}
+	if cfg.APIKey != "" {
+		header.Set("X-Api-Key", cfg.APIKey)
+	}
+
+	ws, resp, err := websocket.DefaultDialer.DialContext(ctx, cfg.URL, header)
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+	if err != nil {
+		if resp != nil {
+			// Rejected handshakes are often the rate limiter of the server
+			return GameResult{Error: fmt.Sprintf("could not connect: %v (%s)", err, resp.Status)}
+		}
+		return GameResult{Error: fmt.Sprintf("could not connect: %v", err)}
+	}
+	defer ws.Close()
+
+	// Closing the connection unblocks the reads once the context is done
+	stop := context.AfterFunc(ctx, func() { ws.Close() })
+	defer stop()
+
+	p := &player{cfg: cfg, ws: ws, rng: rng}
+	err = p.run(ctx)
+
+	p.result.DurationMs = time.Since(started).Milliseconds()
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		p.result.Error = err.Error()
+	}
+	return p.result
+}
+
+func (p *player) run(ctx context.Context) error {
+	if err := p.send("CREATE_SESSION", messages.CreateSession{TimeControl: p.cfg.TimeControl, Color: color.White}); err != nil {
+		return err
+	}
+
+	for {
+		var msg inbound
+		if err := p.ws.ReadJSON(&msg); err != nil {
+			return fmt.Errorf("connection lost: %w", err)
+		}
+
+		switch msg.Event {
+		case "GAME_CREATED":
+			var created messages.GameCreatedPayload
+			if err := json.Unmarshal(msg.Payload, &created); err != nil {
+				return fmt.Errorf("invalid GAME_CREATED: %w", err)
+			}
+			p.gameID = created.GameID
+			p.result.GameID = created.GameID
+
+			if created.CurrentTurn == color.White {
+				if err := p.move(ctx, created.InitialFEN); err != nil {
+					return err
+				}
+			}
+
+		case "MOVE_PROCESSED":
+			var state messages.GameStatePayload
+			if err := json.Unmarshal(msg.Payload, &state); err != nil {
+				return fmt.Errorf("invalid MOVE_PROCESSED: %w", err)
+			}
+			p.result.Plies = len(state.Moves)
+			if state.CurrentTurn != color.White || state.Status != "active" {
+				continue
+			}
+
+			if !p.sent.IsZero() {
+				p.result.Replies = append(p.result.Replies, time.Since(p.sent).Milliseconds())
+				p.sent = time.Time{}
+			}
+			if err := p.move(ctx, state.BoardFEN); err != nil {
+				return err
+			}
+
+		case "GAME_OVER":
+			var over messages.GameOverPayload
+			if err := json.Unmarshal(msg.Payload, &over); err != nil {
+				return fmt.Errorf("invalid GAME_OVER: %w", err)
+			}
+			p.result.Result = over.Result
+			p.result.Reason = over.Reason
+			return nil
+
+		case "ERROR":
+			var e messages.ErrorPayload
+			_ = json.Unmarshal(msg.Payload, &e)
+			if e.Code == messages.ErrorGameOver {
+				// The move of the player ended the game, GAME_OVER follows
+				continue
+			}
+			return fmt.Errorf("server error %s: %s", e.Code, e.Message)
+		}
+	}
+}
+
+// move plays a random legal move in the position after thinking, or resigns
+// once the player made the moves of the config
+func (p *player) move(ctx context.Context, fen string) error {
+	if p.cfg.Moves > 0 && p.result.Moves >= p.cfg.Moves {
+		return p.send("RESIGN", messages.ResignPayload{GameID: p.gameID})
+	}
+
+	pos := chess.StartingPosition()
+	if fen != "" {
+		opt, err := chess.FEN(fen)
+		if err != nil {
+			return fmt.Errorf("invalid position %q: %w", fen, err)
+		}
+		pos = chess.NewGame(opt).Position()
+	}
+
+	valid := pos.ValidMoves()
+	if len(valid) == 0 {
+		// The server ends the game, GAME_OVER follows
+		return nil
+	}
+	move := valid[p.rng.Intn(len(valid))]
+
+	if p.cfg.Think > 0 {
+		select {
+		case <-time.After(p.cfg.Think):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if err := p.send("MAKE_MOVE", messages.MakeMovePayload{
+		GameID: p.gameID,
+		Move:   (chess.UCINotation{}).Encode(pos, &move),
+	}); err != nil {
+		return err
+	}
+	p.result.Moves++
+	p.sent = time.Now()
+
+	return nil
+}
+
+// send writes an event to the server
+func (p *player) send(event string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_ = p.ws.SetWriteDeadline(time.Now().Add(writeWait))
+	if err := p.ws.WriteJSON(messages.InboundMessage{Event: event, Payload: data}); err != nil {
+		return fmt.Errorf("could not send %s: %w", event, err)
+	}
+	return nil
+}
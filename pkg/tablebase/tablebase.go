@@ -0,0 +1,131 @@
+// Package tablebase probes Syzygy endgame tablebases
+package tablebase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// MaxPieces is the largest number of men covered by the Syzygy tablebases
+const MaxPieces = 7
+
+// probeTimeout bounds a single probe of the tablebase files
+const probeTimeout = 5 * time.Second
+
+// WDL is the win/draw/loss value of a position for the side to move
+type WDL string
+
+// All the possible WDL values, cursed wins and blessed losses are
+// drawn under the 50-move rule
+const (
+	WDLLoss        WDL = "loss"
+	WDLBlessedLoss WDL = "blessed_loss"
+	WDLDraw        WDL = "draw"
+	WDLCursedWin   WDL = "cursed_win"
+	WDLWin         WDL = "win"
+)
+
+// ErrTooManyPieces is returned when a position has more men than the tablebases cover
+var ErrTooManyPieces = fmt.Errorf("tablebases cover at most %d men", MaxPieces)
+
+// Result is the outcome of a tablebase probe
+type Result struct {
+	WDL WDL // Value of the position for the side to move
+	DTZ int // Distance to zeroing the 50-move counter in plies
+}
+
+// Decisive reports whether the position is won or lost even with the 50-move rule
+func (r Result) Decisive() bool {
+	return r.WDL == WDLWin || r.WDL == WDLLoss
+}
+
+// Tablebase probes the Syzygy files found under a directory through a
+// Fathom compatible probe command
+type Tablebase struct {
+	Path     string // Directory holding the .rtbw and .rtbz files
+	probeCmd string // Probe executable invoked as <cmd> --path=<path> <fen>
+}
+
+// Open checks the tablebase directory and returns a Tablebase
+func Open(path, probeCmd string) (*Tablebase, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening tablebase: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("tablebase path %s is not a directory", path)
+	}
+
+	if probeCmd == "" {
+		return nil, errors.New("no tablebase probe command configured")
+	}
+
+	return &Tablebase{Path: path, probeCmd: probeCmd}, nil
+}
+
+var (
+	wdlTag = regexp.MustCompile(`\[WDL "([A-Za-z]+)"\]`)
+	dtzTag = regexp.MustCompile(`\[DTZ "(-?\d+)"\]`)
+)
+
+// wdlNames maps the WDL names printed by the probe command
+var wdlNames = map[string]WDL{
+	"Loss":        WDLLoss,
+	"BlessedLoss": WDLBlessedLoss,
+	"Draw":        WDLDraw,
+	"CursedWin":   WDLCursedWin,
+	"Win":         WDLWin,
+}
+
+// Probe looks up the WDL and DTZ values of a position
+func (t *Tablebase) Probe(ctx context.Context, fen string) (Result, error) {
+	if CountPieces(fen) > MaxPieces {
+		return Result{}, ErrTooManyPieces
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, t.probeCmd, "--path="+t.Path, fen).Output()
+	if err != nil {
+		return Result{}, fmt.Errorf("error probing tablebase: %w", err)
+	}
+
+	wdl := wdlTag.FindSubmatch(out)
+	if wdl == nil {
+		return Result{}, errors.New("position not found in tablebase")
+	}
+
+	value, ok := wdlNames[string(wdl[1])]
+	if !ok {
+		return Result{}, fmt.Errorf("unknown WDL value %q", wdl[1])
+	}
+
+	result := Result{WDL: value}
+	if dtz := dtzTag.FindSubmatch(out); dtz != nil {
+		result.DTZ, _ = strconv.Atoi(string(dtz[1]))
+	}
+
+	return result, nil
+}
+
+// CountPieces returns the number of men on the board of a FEN
+func CountPieces(fen string) int {
+	placement, _, _ := strings.Cut(strings.TrimSpace(fen), " ")
+
+	count := 0
+	for _, r := range placement {
+		if unicode.IsLetter(r) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,378 @@
+package tournament
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/pkg/events"
+	"github.com/tecu23/eng-server/pkg/game"
+)
+
+// Errors returned by the lobby
+var (
+	ErrTournamentNotFound = errors.New("tournament not found")
+	ErrFinished           = errors.New("tournament is already finished")
+	ErrStarted            = errors.New("tournament has already started")
+)
+
+const (
+	// gameGracePeriod is added to the total clock time before a game is considered stuck
+	gameGracePeriod = time.Minute
+	// finishedRetention is how long finished tournaments stay in the lobby
+	finishedRetention = time.Hour
+	// pairingRetry is how long an arena player waits after their game could
+	// not be created, e.g. because every engine was busy
+	pairingRetry = 5 * time.Second
+	// pairingInterval is how often the waiting players of an arena between
+	// players are paired
+	pairingInterval = time.Second
+)
+
+// GameHost creates the games of a tournament on the connection of the player,
+// and of the opponent between players, so they are played like any other game
+type GameHost interface {
+	StartTournamentGame(pairing Pairing) (*game.Game, error)
+}
+
+// Lobby holds the tournaments players can join and runs them
+type Lobby struct {
+	mu          sync.RWMutex
+	tournaments map[uuid.UUID]*Tournament
+	host        GameHost
+
+	publisher *events.Publisher
+	logger    *zap.Logger
+}
+
+// NewLobby creates an empty lobby, players of a closed connection are withdrawn
+func NewLobby(publisher *events.Publisher, logger *zap.Logger) *Lobby {
+	l := &Lobby{
+		tournaments: make(map[uuid.UUID]*Tournament),
+		publisher:   publisher,
+		logger:      logger,
+	}
+
+	sub := publisher.NewSubscriber("tournament", events.SubscriberOptions{})
+	sub.Subscribe(events.EventConnectionClosed, func(event events.Event) {
+		payload, ok := event.Payload.(map[string]string)
+		if !ok {
+			return
+		}
+		if id, err := uuid.Parse(payload["connection_id"]); err == nil {
+			l.withdrawConnection(id)
+		}
+	})
+
+	return l
+}
+
+// SetHost sets who creates the games, it must be set before a tournament starts
+func (l *Lobby) SetHost(host GameHost) {
+	l.host = host
+}
+
+// Create opens a new tournament for players to join
+func (l *Lobby) Create(cfg Config, creator uuid.UUID) (*Tournament, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	t := newTournament(cfg, creator)
+
+	l.mu.Lock()
+	l.tournaments[t.ID] = t
+	l.mu.Unlock()
+
+	l.logger.Info("tournament created",
+		zap.String("tournament_id", t.ID.String()),
+		zap.String("name", cfg.Name),
+		zap.String("format", string(cfg.Format)))
+
+	l.publish(t)
+	return t, nil
+}
+
+// Get returns a tournament of the lobby
+func (l *Lobby) Get(id uuid.UUID) (*Tournament, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	t, ok := l.tournaments[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTournamentNotFound, id)
+	}
+	return t, nil
+}
+
+// List returns every tournament of the lobby, the newest first
+func (l *Lobby) List() []*Tournament {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	list := make([]*Tournament, 0, len(l.tournaments))
+	for _, t := range l.tournaments {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].createdAt.After(list[j].createdAt) })
+	return list
+}
+
+// Join adds a player to a tournament, a player joining a running arena is
+// paired right away and one joining a Swiss tournament from the next round
+func (l *Lobby) Join(id uuid.UUID, userID string, connectionID uuid.UUID) (*Tournament, error) {
+	if userID == "" {
+		return nil, errors.New("joining a tournament needs a user")
+	}
+
+	t, err := l.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.join(userID, connectionID); err != nil {
+		return nil, err
+	}
+
+	l.logger.Info("player joined tournament",
+		zap.String("tournament_id", id.String()),
+		zap.String("user_id", userID))
+
+	l.publish(t)
+
+	// Between players the arena pairs the newcomer with the other waiting players
+	if t.Status() == StatusRunning && t.Config.Format == FormatArena && t.Config.Opponents == OpponentsEngine {
+		go l.playArena(t, userID)
+	}
+	return t, nil
+}
+
+// Leave withdraws a player of the connection from a tournament
+func (l *Lobby) Leave(id uuid.UUID, userID string, connectionID uuid.UUID) error {
+	t, err := l.Get(id)
+	if err != nil {
+		return err
+	}
+
+	if err := t.withdraw(userID, connectionID); err != nil {
+		return err
+	}
+
+	l.publish(t)
+	return nil
+}
+
+// Start starts the games of an open tournament
+func (l *Lobby) Start(id uuid.UUID) error {
+	t, err := l.Get(id)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	if t.status != StatusOpen {
+		t.mu.Unlock()
+		return ErrStarted
+	}
+	if len(t.participants) == 0 {
+		t.mu.Unlock()
+		return errors.New("a tournament needs at least one player")
+	}
+	if t.Config.Opponents == OpponentsPlayers && len(t.participants) < 2 {
+		t.mu.Unlock()
+		return errors.New("a tournament between players needs at least two players")
+	}
+	t.status = StatusRunning
+	t.startedAt = time.Now()
+	if t.Config.Format == FormatArena {
+		t.endsAt = t.startedAt.Add(t.Config.Duration)
+	}
+	players := append([]string{}, t.joined...)
+	t.mu.Unlock()
+
+	l.logger.Info("tournament started",
+		zap.String("tournament_id", id.String()),
+		zap.Int("players", len(players)))
+
+	l.publish(t)
+
+	switch {
+	case t.Config.Format == FormatSwiss:
+		go l.runSwiss(t)
+	case t.Config.Opponents == OpponentsPlayers:
+		go l.runPlayerArena(t)
+	default:
+		go l.runArena(t, players)
+	}
+	return nil
+}
+
+// runSwiss plays the rounds of a Swiss tournament, a round starts once every
+// game of the previous one is over
+func (l *Lobby) runSwiss(t *Tournament) {
+	for round := 1; round <= t.Config.Rounds; round++ {
+		t.mu.Lock()
+		t.round = round
+		players := append([]string{}, t.joined...)
+		t.mu.Unlock()
+
+		l.publish(t)
+
+		var pairings []Pairing
+		if t.Config.Opponents == OpponentsPlayers {
+			pairings = t.pairRound()
+		} else {
+			for _, userID := range players {
+				if pairing, ok := t.pair(userID); ok {
+					pairings = append(pairings, pairing)
+				}
+			}
+		}
+
+		var wg sync.WaitGroup
+		for _, pairing := range pairings {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				l.play(t, pairing)
+			}()
+		}
+		wg.Wait()
+	}
+
+	l.finish(t)
+}
+
+// runArena pairs every player until the arena time is up, games started
+// before the end are still scored
+func (l *Lobby) runArena(t *Tournament, players []string) {
+	var wg sync.WaitGroup
+	for _, userID := range players {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.playArena(t, userID)
+		}()
+	}
+	wg.Wait()
+
+	// Players who joined late may still be playing
+	time.Sleep(time.Until(t.endsAt))
+	for t.playing() {
+		time.Sleep(time.Second)
+	}
+
+	l.finish(t)
+}
+
+// runPlayerArena pairs the waiting players of an arena between players with
+// each other until the time is up, games started before the end are still scored
+func (l *Lobby) runPlayerArena(t *Tournament) {
+	for time.Now().Before(t.endsAt) {
+		for _, pairing := range t.pairWaiting() {
+			go l.play(t, pairing)
+		}
+		time.Sleep(pairingInterval)
+	}
+
+	for t.playing() {
+		time.Sleep(time.Second)
+	}
+
+	l.finish(t)
+}
+
+// playArena plays the games of a player of an arena one after the other,
+// until the time is up or the player withdraws
+func (l *Lobby) playArena(t *Tournament, userID string) {
+	for time.Now().Before(t.endsAt) {
+		pairing, ok := t.pair(userID)
+		if !ok {
+			return
+		}
+
+		if !l.play(t, pairing) {
+			time.Sleep(pairingRetry)
+		}
+	}
+}
+
+// play plays a game of a tournament and reports whether it could be created
+func (l *Lobby) play(t *Tournament, pairing Pairing) bool {
+	logger := l.logger.With(
+		zap.String("tournament_id", t.ID.String()),
+		zap.String("user_id", pairing.UserID))
+
+	session, err := l.host.StartTournamentGame(pairing)
+	if err != nil {
+		logger.Warn("could not start tournament game", zap.Error(err))
+		t.unpair(pairing)
+		l.publish(t)
+		return false
+	}
+
+	l.publish(t)
+
+	tc := pairing.TimeControl
+	timeout := time.Duration(tc.WhiteTime+tc.BlackTime)*time.Millisecond + gameGracePeriod
+
+	// A game terminated before it was over on the board, e.g. because the
+	// player left, is lost
+	result := ""
+	select {
+	case <-session.Finished():
+		if state, err := session.State(); err == nil {
+			result = state.Result
+		}
+	case <-session.Terminated():
+	case <-time.After(timeout):
+		logger.Warn("tournament game did not finish in time", zap.String("game_id", session.ID.String()))
+		session.Close()
+	}
+
+	t.record(pairing.UserID, pairing.Color, result)
+	if pairing.Opponent != "" {
+		t.record(pairing.Opponent, pairing.Color.Opp(), result)
+	}
+	l.publish(t)
+	return true
+}
+
+// finish ends a tournament and drops it from the lobby after a while
+func (l *Lobby) finish(t *Tournament) {
+	t.mu.Lock()
+	t.status = StatusFinished
+	t.finishedAt = time.Now()
+	t.mu.Unlock()
+
+	l.logger.Info("tournament finished", zap.String("tournament_id", t.ID.String()))
+	l.publish(t)
+
+	time.AfterFunc(finishedRetention, func() {
+		l.mu.Lock()
+		delete(l.tournaments, t.ID)
+		l.mu.Unlock()
+	})
+}
+
+// withdrawConnection withdraws the players of a closed connection from every tournament
+func (l *Lobby) withdrawConnection(connectionID uuid.UUID) {
+	for _, t := range l.List() {
+		if t.withdrawConnection(connectionID) {
+			l.publish(t)
+		}
+	}
+}
+
+// publish sends the standings of a tournament to the lobby
+func (l *Lobby) publish(t *Tournament) {
+	l.publisher.Publish(events.Event{
+		Type:    events.EventTournamentUpdate,
+		Payload: t.Summary(),
+	})
+}
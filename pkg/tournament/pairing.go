@@ -0,0 +1,255 @@
+package tournament
+
+import (
+	"sort"
+
+	"github.com/google/uuid"
+
+	"github.com/tecu23/eng-server/internal/color"
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/game"
+)
+
+// ladder orders the difficulties players climb. A win pairs the player one
+// level up for their next game and a loss one level down, so everyone ends up
+// meeting an engine of their own strength
+var ladder = []game.Difficulty{
+	game.DifficultyBeginner,
+	game.DifficultyCasual,
+	game.DifficultyIntermediate,
+	game.DifficultyAdvanced,
+	game.DifficultyExpert,
+	game.DifficultyMaster,
+}
+
+// ladderIndex returns the position of a difficulty in the ladder, -1 when unknown
+func ladderIndex(d game.Difficulty) int {
+	for i, level := range ladder {
+		if level == d {
+			return i
+		}
+	}
+	return -1
+}
+
+// Pairing is a game a player of a tournament plays against the engine, or
+// against another player in a tournament between players
+type Pairing struct {
+	TournamentID uuid.UUID
+	Round        int // Round of a Swiss tournament, the game number of the player in an arena
+	UserID       string
+	ConnectionID uuid.UUID
+	Color        color.Color     // Color the player plays
+	Difficulty   game.Difficulty // Level of the engine, empty between players
+	TimeControl  messages.TimeControl
+
+	Opponent             string    // User playing the other color between players, empty against the engine
+	OpponentConnectionID uuid.UUID // Connection of the opponent
+}
+
+// pair sets up the next game of a player, false when they withdrew or are
+// already playing
+func (t *Tournament) pair(userID string) (Pairing, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.participants[userID]
+	if !ok || p.withdrawn || p.playing {
+		return Pairing{}, false
+	}
+
+	// Colors alternate, every other player in the joining order starts with Black
+	clr := color.Color(color.White)
+	switch p.lastColor {
+	case color.White:
+		clr = color.Black
+	case "":
+		if p.seed%2 == 1 {
+			clr = color.Black
+		}
+	}
+	p.lastColor = clr
+	p.playing = true
+
+	round := t.round
+	if t.Config.Format == FormatArena {
+		round = p.games() + 1
+	}
+
+	return Pairing{
+		TournamentID: t.ID,
+		Round:        round,
+		UserID:       userID,
+		ConnectionID: p.connectionID,
+		Color:        clr,
+		Difficulty:   ladder[p.level],
+		TimeControl:  t.Config.TimeControl,
+	}, true
+}
+
+// pairRound pairs the players of a Swiss round between players. Players are
+// ranked by points and each one meets the best ranked player left they have
+// not met yet, or the best ranked one when they met everyone. With an odd
+// number of players the lowest ranked player who had the fewest byes sits
+// the round out and scores it as a win
+func (t *Tournament) pairRound() []Pairing {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	players := t.waitingLocked()
+	if len(players)%2 == 1 {
+		bye := len(players) - 1
+		for i := len(players) - 1; i >= 0; i-- {
+			if players[i].byes < players[bye].byes {
+				bye = i
+			}
+		}
+		players[bye].byes++
+		players[bye].points++
+		players = append(players[:bye], players[bye+1:]...)
+	}
+
+	return t.pairLocked(players, func(p, q *participant) bool { return p.met[q.userID] == 0 })
+}
+
+// pairWaiting pairs the arena players between players who are not in a game,
+// avoiding an immediate rematch when another opponent is waiting. A player
+// left over waits for the next game to end
+func (t *Tournament) pairWaiting() []Pairing {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	players := t.waitingLocked()
+	if len(players)%2 == 1 {
+		players = players[:len(players)-1]
+	}
+
+	return t.pairLocked(players, func(p, q *participant) bool { return p.lastOpponent != q.userID })
+}
+
+// waitingLocked returns the players who may be paired, the best ranked first
+func (t *Tournament) waitingLocked() []*participant {
+	players := make([]*participant, 0, len(t.joined))
+	for _, userID := range t.joined {
+		if p := t.participants[userID]; !p.withdrawn && !p.playing {
+			players = append(players, p)
+		}
+	}
+
+	sort.SliceStable(players, func(i, j int) bool { return players[i].points > players[j].points })
+	return players
+}
+
+// pairLocked pairs an even number of ranked players, each with the best ranked
+// player left it prefers to meet, or the best ranked one left when it prefers none
+func (t *Tournament) pairLocked(players []*participant, prefers func(p, q *participant) bool) []Pairing {
+	var pairings []Pairing
+	for len(players) > 1 {
+		p := players[0]
+
+		opponent := 1
+		for i := 1; i < len(players); i++ {
+			if prefers(p, players[i]) {
+				opponent = i
+				break
+			}
+		}
+		q := players[opponent]
+		players = append(players[1:opponent], players[opponent+1:]...)
+
+		pairings = append(pairings, t.seatLocked(p, q))
+	}
+	return pairings
+}
+
+// seatLocked sets up a game between two players. The player who had White
+// less often gets it, otherwise colors alternate
+func (t *Tournament) seatLocked(p, q *participant) Pairing {
+	white, black := p, q
+	switch {
+	case p.whites > q.whites:
+		white, black = q, p
+	case p.whites == q.whites && p.lastColor == color.White:
+		white, black = q, p
+	}
+
+	white.whites++
+	white.lastColor, black.lastColor = color.White, color.Black
+	for _, pair := range [][2]*participant{{white, black}, {black, white}} {
+		pair[0].met[pair[1].userID]++
+		pair[0].lastOpponent = pair[1].userID
+		pair[0].playing = true
+	}
+
+	round := t.round
+	if t.Config.Format == FormatArena {
+		round = white.games() + 1
+	}
+
+	return Pairing{
+		TournamentID:         t.ID,
+		Round:                round,
+		UserID:               white.userID,
+		ConnectionID:         white.connectionID,
+		Color:                color.White,
+		TimeControl:          t.Config.TimeControl,
+		Opponent:             black.userID,
+		OpponentConnectionID: black.connectionID,
+	}
+}
+
+// unpair releases the players of a game that could not be created
+func (t *Tournament) unpair(pairing Pairing) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, userID := range []string{pairing.UserID, pairing.Opponent} {
+		if p, ok := t.participants[userID]; ok {
+			p.playing = false
+		}
+	}
+}
+
+// record scores the result of a game for the player of the given color. A game
+// that was not finished on the board counts as a loss. Swiss games score 1 for
+// a win and ½ for a draw, arena games 2 and 1, doubled on a winning streak
+func (t *Tournament) record(userID string, clr color.Color, result string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.participants[userID]
+	if !ok {
+		return
+	}
+	p.playing = false
+
+	win, draw := 1.0, 0.5
+	if t.Config.Format == FormatArena {
+		win, draw = 2, 1
+		if p.streak >= 2 {
+			win, draw = 4, 2
+		}
+	}
+
+	switch {
+	case result == "1/2-1/2":
+		p.draws++
+		p.points += draw
+		p.streak = 0
+
+	case result == "1-0" && clr == color.White, result == "0-1" && clr == color.Black:
+		p.wins++
+		p.points += win
+		p.streak++
+		if t.Config.Opponents == OpponentsEngine {
+			p.level = min(p.level+1, len(ladder)-1)
+		}
+
+	default:
+		p.losses++
+		p.streak = 0
+		if t.Config.Opponents == OpponentsEngine {
+			p.level = max(p.level-1, 0)
+		}
+	}
+}
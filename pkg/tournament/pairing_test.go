@@ -0,0 +1,106 @@
+package tournament
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tecu23/eng-server/internal/color"
+	"github.com/tecu23/eng-server/internal/messages"
+)
+
+// newPlayerTournament creates a Swiss tournament between the given players
+func newPlayerTournament(t *testing.T, users ...string) *Tournament {
+	t.Helper()
+
+	cfg := Config{
+		Name:        "Swiss",
+		Format:      FormatSwiss,
+		Opponents:   OpponentsPlayers,
+		TimeControl: messages.TimeControl{WhiteTime: 60000, BlackTime: 60000},
+		Rounds:      3,
+	}
+	require.NoError(t, cfg.validate())
+
+	tour := newTournament(cfg, uuid.New())
+	for _, user := range users {
+		require.NoError(t, tour.join(user, uuid.New()))
+	}
+	return tour
+}
+
+// opponents returns who every player meets in a set of pairings
+func opponents(pairings []Pairing) map[string]string {
+	met := make(map[string]string)
+	for _, p := range pairings {
+		met[p.UserID] = p.Opponent
+		met[p.Opponent] = p.UserID
+	}
+	return met
+}
+
+func TestSwissRoundsAvoidRematches(t *testing.T) {
+	tour := newPlayerTournament(t, "a", "b", "c", "d")
+
+	first := tour.pairRound()
+	require.Len(t, first, 2)
+	for _, p := range first {
+		tour.record(p.UserID, p.Color, "1-0")
+		tour.record(p.Opponent, p.Color.Opp(), "1-0")
+	}
+
+	second := tour.pairRound()
+	require.Len(t, second, 2)
+
+	before, after := opponents(first), opponents(second)
+	for user, opponent := range after {
+		assert.NotEqual(t, before[user], opponent, user)
+	}
+
+	// Winners of the first round meet each other
+	winners := map[string]bool{first[0].UserID: true, first[1].UserID: true}
+	for _, p := range second {
+		assert.Equal(t, winners[p.UserID], winners[p.Opponent])
+	}
+}
+
+func TestSwissOddPlayerGetsBye(t *testing.T) {
+	tour := newPlayerTournament(t, "a", "b", "c")
+
+	byes := make(map[string]int)
+	for range 3 {
+		pairings := tour.pairRound()
+		require.Len(t, pairings, 1)
+
+		for _, p := range pairings {
+			assert.Equal(t, color.Color(color.White), p.Color)
+			tour.record(p.UserID, p.Color, "1/2-1/2")
+			tour.record(p.Opponent, p.Color.Opp(), "1/2-1/2")
+		}
+		for user, p := range tour.participants {
+			byes[user] = p.byes
+		}
+	}
+
+	// Every player sat out one round
+	assert.Equal(t, map[string]int{"a": 1, "b": 1, "c": 1}, byes)
+	for _, p := range tour.participants {
+		assert.Equal(t, 2.0, p.points)
+	}
+}
+
+func TestPlayerColorsAreBalanced(t *testing.T) {
+	tour := newPlayerTournament(t, "a", "b")
+
+	for range 4 {
+		pairings := tour.pairRound()
+		require.Len(t, pairings, 1)
+		tour.record(pairings[0].UserID, color.White, "1/2-1/2")
+		tour.record(pairings[0].Opponent, color.Black, "1/2-1/2")
+	}
+
+	assert.Equal(t, 2, tour.participants["a"].whites)
+	assert.Equal(t, 2, tour.participants["b"].whites)
+}
@@ -0,0 +1,314 @@
+// Package tournament runs arena and Swiss tournaments in which players meet
+// the engine, paired by the server against levels matching their results, or
+// each other, paired by the server against players with a similar score
+package tournament
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/tecu23/eng-server/internal/color"
+	"github.com/tecu23/eng-server/internal/messages"
+	"github.com/tecu23/eng-server/pkg/game"
+)
+
+// Format is how the games of a tournament are scheduled
+type Format string
+
+// All the tournament formats
+const (
+	FormatArena Format = "arena" // Players start a new game as soon as theirs ends, until the time is up
+	FormatSwiss Format = "swiss" // Every player plays one game per round, rounds start together
+)
+
+// Opponents is who the players of a tournament play against
+type Opponents string
+
+// All the opponents of tournament players
+const (
+	OpponentsEngine  Opponents = "engine"  // Every game is against the engine, at the level of the player
+	OpponentsPlayers Opponents = "players" // Players are paired with each other
+)
+
+// Status represents the lifecycle of a tournament
+type Status string
+
+// All the possible tournament statuses
+const (
+	StatusOpen     Status = "open"     // Players may join
+	StatusRunning  Status = "running"  // Games are being played, players may still join
+	StatusFinished Status = "finished" // Every game is over
+)
+
+// Tournament limits
+const (
+	maxParticipants = 100
+	maxRounds       = 15
+	maxDuration     = 3 * time.Hour
+	maxNameLength   = 60
+)
+
+// Config describes a tournament
+type Config struct {
+	Name        string
+	Format      Format
+	Opponents   Opponents
+	TimeControl messages.TimeControl // Clock of every game
+	Difficulty  game.Difficulty      // Level every player starts at against the engine
+	Rounds      int                  // Rounds of a Swiss tournament
+	Duration    time.Duration        // Length of an arena tournament
+}
+
+// validate checks a configuration and fills in its defaults
+func (c *Config) validate() error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" || len(c.Name) > maxNameLength {
+		return fmt.Errorf("a tournament needs a name of at most %d characters", maxNameLength)
+	}
+
+	if c.TimeControl.WhiteTime <= 0 || c.TimeControl.BlackTime <= 0 {
+		return errors.New("a tournament needs a positive time control")
+	}
+
+	switch c.Opponents {
+	case "", OpponentsEngine:
+		c.Opponents = OpponentsEngine
+		if c.Difficulty == "" {
+			c.Difficulty = game.DifficultyIntermediate
+		}
+		if ladderIndex(c.Difficulty) < 0 {
+			return fmt.Errorf("unknown difficulty %q", c.Difficulty)
+		}
+	case OpponentsPlayers:
+		c.Difficulty = ""
+	default:
+		return fmt.Errorf("opponents must be %s or %s", OpponentsEngine, OpponentsPlayers)
+	}
+
+	switch c.Format {
+	case FormatSwiss:
+		if c.Rounds < 1 || c.Rounds > maxRounds {
+			return fmt.Errorf("a Swiss tournament has between 1 and %d rounds", maxRounds)
+		}
+		c.Duration = 0
+	case FormatArena:
+		if c.Duration <= 0 || c.Duration > maxDuration {
+			return fmt.Errorf("an arena lasts between 1 second and %s", maxDuration)
+		}
+		c.Rounds = 0
+	default:
+		return fmt.Errorf("format must be %s or %s", FormatArena, FormatSwiss)
+	}
+	return nil
+}
+
+// participant is a player of a tournament and their score
+type participant struct {
+	userID       string
+	connectionID uuid.UUID
+	seed         int // Position in the joining order
+	level        int // Index of the difficulty of their next game in the ladder
+	lastColor    color.Color
+	whites       int            // Games played with White, colors are balanced between players
+	met          map[string]int // Games against each other player
+	lastOpponent string
+
+	points  float64
+	wins    int
+	draws   int
+	losses  int
+	streak  int // Consecutive wins, arena wins count double from the third one
+	byes    int // Swiss rounds without an opponent, scored as a win
+	playing bool
+
+	withdrawn bool
+}
+
+// games returns the games a participant finished
+func (p *participant) games() int {
+	return p.wins + p.draws + p.losses
+}
+
+// Tournament is an open, running or finished tournament
+type Tournament struct {
+	ID      uuid.UUID
+	Config  Config
+	Creator uuid.UUID // Connection that created the tournament, which may start it
+
+	mu           sync.RWMutex
+	status       Status
+	round        int
+	participants map[string]*participant // Keyed by user ID
+	joined       []string                // User IDs in the order they joined
+	createdAt    time.Time
+	startedAt    time.Time
+	endsAt       time.Time
+	finishedAt   time.Time
+}
+
+// newTournament creates an open tournament
+func newTournament(cfg Config, creator uuid.UUID) *Tournament {
+	return &Tournament{
+		ID:           uuid.New(),
+		Config:       cfg,
+		Creator:      creator,
+		status:       StatusOpen,
+		participants: make(map[string]*participant),
+		createdAt:    time.Now(),
+	}
+}
+
+// Status returns the current status of the tournament
+func (t *Tournament) Status() Status {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.status
+}
+
+// playing reports whether a player of the tournament is in a game
+func (t *Tournament) playing() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for _, p := range t.participants {
+		if p.playing {
+			return true
+		}
+	}
+	return false
+}
+
+// join adds a player, or brings back a player who withdrew, possibly on a
+// new connection
+func (t *Tournament) join(userID string, connectionID uuid.UUID) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.status == StatusFinished {
+		return ErrFinished
+	}
+
+	if p, ok := t.participants[userID]; ok {
+		if !p.withdrawn && p.connectionID != connectionID {
+			return fmt.Errorf("%s already plays in the tournament from another connection", userID)
+		}
+		p.connectionID = connectionID
+		p.withdrawn = false
+		return nil
+	}
+
+	if len(t.participants) >= maxParticipants {
+		return fmt.Errorf("a tournament has at most %d players", maxParticipants)
+	}
+
+	t.participants[userID] = &participant{
+		userID:       userID,
+		connectionID: connectionID,
+		seed:         len(t.joined),
+		level:        max(ladderIndex(t.Config.Difficulty), 0),
+		met:          make(map[string]int),
+	}
+	t.joined = append(t.joined, userID)
+	return nil
+}
+
+// withdraw stops pairing a player of the connection, their game in progress
+// is still scored
+func (t *Tournament) withdraw(userID string, connectionID uuid.UUID) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.participants[userID]
+	if !ok || p.connectionID != connectionID {
+		return fmt.Errorf("%s does not play in the tournament from this connection", userID)
+	}
+	p.withdrawn = true
+	return nil
+}
+
+// withdrawConnection withdraws the players of a closed connection and reports
+// whether there were any
+func (t *Tournament) withdrawConnection(connectionID uuid.UUID) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.status == StatusFinished {
+		return false
+	}
+
+	found := false
+	for _, p := range t.participants {
+		if p.connectionID == connectionID && !p.withdrawn {
+			p.withdrawn = true
+			found = true
+		}
+	}
+	return found
+}
+
+// Summary returns the tournament and its standings
+func (t *Tournament) Summary() messages.TournamentPayload {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	players := make([]*participant, 0, len(t.participants))
+	for _, userID := range t.joined {
+		players = append(players, t.participants[userID])
+	}
+
+	// Players with the same points rank by wins, then by the strength they reached
+	sort.SliceStable(players, func(i, j int) bool {
+		a, b := players[i], players[j]
+		if a.points != b.points {
+			return a.points > b.points
+		}
+		if a.wins != b.wins {
+			return a.wins > b.wins
+		}
+		return a.level > b.level
+	})
+
+	standings := make([]messages.TournamentStanding, 0, len(players))
+	for i, p := range players {
+		standing := messages.TournamentStanding{
+			Rank:      i + 1,
+			UserID:    p.userID,
+			Points:    p.points,
+			Games:     p.games(),
+			Wins:      p.wins,
+			Draws:     p.draws,
+			Losses:    p.losses,
+			Byes:      p.byes,
+			Playing:   p.playing,
+			Withdrawn: p.withdrawn,
+		}
+		if t.Config.Opponents == OpponentsEngine {
+			standing.Difficulty = string(ladder[p.level])
+		}
+		standings = append(standings, standing)
+	}
+
+	return messages.TournamentPayload{
+		TournamentID: t.ID.String(),
+		Name:         t.Config.Name,
+		Format:       string(t.Config.Format),
+		Opponents:    string(t.Config.Opponents),
+		Status:       string(t.status),
+		TimeControl:  t.Config.TimeControl,
+		Difficulty:   string(t.Config.Difficulty),
+		Round:        t.round,
+		Rounds:       t.Config.Rounds,
+		DurationMs:   t.Config.Duration.Milliseconds(),
+		StartedAt:    t.startedAt,
+		EndsAt:       t.endsAt,
+		FinishedAt:   t.finishedAt,
+		Standings:    standings,
+	}
+}
@@ -0,0 +1,300 @@
+// Package uciproxy lets a client speak raw UCI over a websocket to an engine
+// leased from the pool, so desktop GUIs can use the server as a remote engine
+package uciproxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/pkg/engine"
+)
+
+const (
+	maxCommandSize = 4096            // Largest frame a client may send
+	outputBuffer   = 1024            // Engine lines waiting for the client before the session is closed
+	writeTimeout   = 5 * time.Second // Longest write of a frame to the client
+	leaseTimeout   = 5 * time.Second // Longest wait for an engine of the pool
+)
+
+// ErrOverloaded is returned when every proxy session is in use
+var ErrOverloaded = errors.New("every UCI proxy session is in use")
+
+// allowedOptions are the options clients may set. Others, string options
+// like file paths above all, would let a client reach into the server
+var allowedOptions = []string{"Hash", "Threads", "MultiPV", "UCI_Elo", "UCI_LimitStrength"}
+
+// Limits bounds the resources a proxy session may use
+type Limits struct {
+	MaxSessions int              // Sessions open at the same time, each holding an engine of the pool
+	IdleTimeout time.Duration    // Sessions without a command for this long are closed
+	MaxDuration time.Duration    // Sessions are closed after this long, 0 keeps them open
+	MaxOptions  map[string]int64 // Largest value of spin options, e.g. Hash and Threads
+}
+
+// Proxy hands engines of the pool to websocket clients speaking UCI
+type Proxy struct {
+	pool   *engine.Pool
+	limits Limits
+	slots  chan struct{} // Holds a token per open session
+
+	logger *zap.Logger
+}
+
+// New creates a proxy, a MaxSessions of 0 leaves it disabled
+func New(pool *engine.Pool, limits Limits, logger *zap.Logger) *Proxy {
+	return &Proxy{
+		pool:   pool,
+		limits: limits,
+		slots:  make(chan struct{}, max(limits.MaxSessions, 0)),
+		logger: logger,
+	}
+}
+
+// Enabled reports whether the proxy accepts sessions
+func (p *Proxy) Enabled() bool {
+	return p.limits.MaxSessions > 0
+}
+
+// Serve runs a session until the client quits, goes idle or exceeds the
+// session duration, then returns the engine to the pool. Clients beyond the
+// session limit or finding no engine are closed with a try again later code
+func (p *Proxy) Serve(ws *websocket.Conn) {
+	defer ws.Close()
+
+	select {
+	case p.slots <- struct{}{}:
+		defer func() { <-p.slots }()
+	default:
+		p.logger.Warn("UCI proxy session refused", zap.Error(ErrOverloaded))
+		tryAgainLater(ws, "every session is in use")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), leaseTimeout)
+	eng, err := p.pool.GetEngine(ctx)
+	cancel()
+	if err != nil {
+		p.logger.Warn("No engine for UCI proxy session", zap.Error(err))
+		tryAgainLater(ws, "no engine available")
+		return
+	}
+
+	s := &session{
+		proxy:  p,
+		ws:     ws,
+		engine: eng,
+		output: make(chan string, outputBuffer),
+		done:   make(chan struct{}),
+		logger: p.logger.With(zap.String("engine_id", eng.ID.String())),
+	}
+	s.run()
+}
+
+// tryAgainLater closes a websocket the proxy can not serve now
+func tryAgainLater(ws *websocket.Conn, reason string) {
+	_ = ws.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseTryAgainLater, reason),
+		time.Now().Add(writeTimeout))
+}
+
+// session is a client connected to a leased engine
+type session struct {
+	proxy  *Proxy
+	ws     *websocket.Conn
+	engine *engine.UCIEngine
+	output chan string // Lines for the client, written by a single goroutine
+
+	done      chan struct{}
+	closeOnce sync.Once
+
+	logger *zap.Logger
+}
+
+// run forwards the engine output and the client commands until the session ends
+func (s *session) run() {
+	started := time.Now()
+	s.logger.Info("UCI proxy session started", zap.String("remote_addr", s.ws.RemoteAddr().String()))
+
+	s.engine.Tap(s.forward)
+	defer func() {
+		s.engine.Tap(nil)
+		s.proxy.pool.ReturnEngine(s.engine.ID.String())
+		s.logger.Info("UCI proxy session ended", zap.Duration("duration", time.Since(started)))
+	}()
+
+	if d := s.proxy.limits.MaxDuration; d > 0 {
+		timer := time.AfterFunc(d, func() {
+			s.logger.Info("UCI proxy session reached its maximum duration")
+			s.close()
+		})
+		defer timer.Stop()
+	}
+
+	go s.writeLoop()
+	defer s.close()
+
+	s.ws.SetReadLimit(maxCommandSize)
+	for {
+		if s.proxy.limits.IdleTimeout > 0 {
+			_ = s.ws.SetReadDeadline(time.Now().Add(s.proxy.limits.IdleTimeout))
+		}
+
+		msgType, data, err := s.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		if msgType != websocket.TextMessage {
+			continue
+		}
+
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line == "" {
+				continue
+			}
+			if quit := s.handle(line); quit {
+				return
+			}
+		}
+	}
+}
+
+// close ends the session, the read loop stops once the websocket is closed
+func (s *session) close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		_ = s.ws.Close()
+	})
+}
+
+// forward queues a line of the engine output, a client too slow to read
+// the output of its own engine loses its session
+func (s *session) forward(line string) {
+	select {
+	case s.output <- line:
+	case <-s.done:
+	default:
+		s.logger.Warn("UCI proxy client does not keep up with the engine output")
+		s.close()
+	}
+}
+
+// writeLoop writes every queued line to the client as a text frame
+func (s *session) writeLoop() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case line := <-s.output:
+			_ = s.ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := s.ws.WriteMessage(websocket.TextMessage, []byte(line)); err != nil {
+				s.close()
+				return
+			}
+		}
+	}
+}
+
+// handle passes a client command to the engine and reports whether the client quit.
+// The handshake is answered from what the engine reported when it started, and
+// options go through the engine so they are checked and restored for its next user
+func (s *session) handle(line string) bool {
+	fields := strings.Fields(line)
+
+	var err error
+	switch fields[0] {
+	case "quit":
+		return true
+
+	case "uci":
+		s.handshake()
+
+	case "setoption":
+		err = s.setOption(line)
+
+	case "isready", "ucinewgame", "position", "go", "stop", "ponderhit", "debug":
+		err = s.engine.SendCommand(line)
+
+	default:
+		err = fmt.Errorf("unknown command %s", fields[0])
+	}
+
+	if err != nil {
+		s.forward("info string " + err.Error())
+	}
+	return false
+}
+
+// handshake answers the uci command
+func (s *session) handshake() {
+	meta := s.engine.Metadata()
+
+	s.forward("id name " + meta.Name)
+	if meta.Author != "" {
+		s.forward("id author " + meta.Author)
+	}
+	for _, opt := range meta.Options {
+		if !allowedOption(opt.Name) {
+			continue
+		}
+		if limit, ok := s.proxy.optionLimit(opt.Name); ok && opt.Max != nil && *opt.Max > limit {
+			opt.Max = &limit
+		}
+		s.forward(opt.String())
+	}
+	s.forward("uciok")
+}
+
+// setOption parses a setoption command, e.g. setoption name Hash value 64,
+// and sets the option within the limits of the proxy. Only the allowed
+// options may be set
+func (s *session) setOption(line string) error {
+	rest, ok := strings.CutPrefix(line, "setoption name ")
+	if !ok {
+		return errors.New("invalid setoption command")
+	}
+	name, value, _ := strings.Cut(rest, " value ")
+	name = strings.TrimSpace(name)
+
+	if !allowedOption(name) {
+		s.proxy.logger.Warn("UCI proxy option refused", zap.String("option", name))
+		return fmt.Errorf("option %s cannot be set through the proxy", name)
+	}
+
+	if limit, ok := s.proxy.optionLimit(name); ok {
+		if n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64); err == nil && n > limit {
+			return fmt.Errorf("option %s is limited to %d", name, limit)
+		}
+	}
+
+	return s.engine.SetOption(name, strings.TrimSpace(value))
+}
+
+// allowedOption reports whether clients may set an option, UCI option names
+// are case insensitive
+func allowedOption(name string) bool {
+	for _, option := range allowedOptions {
+		if strings.EqualFold(option, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// optionLimit returns the largest value the proxy allows for an option, UCI
+// option names are case insensitive
+func (p *Proxy) optionLimit(name string) (int64, bool) {
+	for option, limit := range p.limits.MaxOptions {
+		if strings.EqualFold(option, name) {
+			return limit, true
+		}
+	}
+	return 0, false
+}
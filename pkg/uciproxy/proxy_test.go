@@ -0,0 +1,97 @@
+package uciproxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/tecu23/eng-server/pkg/engine"
+)
+
+// dialProxy starts a proxy on a mock engine and connects a client to it
+func dialProxy(t *testing.T) *websocket.Conn {
+	t.Helper()
+
+	logger := zap.NewNop()
+	pool := engine.NewEnginePool(engine.MockPrefix, engine.Scaling{}, engine.Limits{}, nil, logger)
+	require.NoError(t, pool.Initialize())
+	t.Cleanup(pool.Shutdown)
+
+	proxy := New(pool, Limits{MaxSessions: 1, MaxOptions: map[string]int64{"Hash": 64}}, logger)
+
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		proxy.Serve(ws)
+	}))
+	t.Cleanup(srv.Close)
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ws.Close() })
+	return ws
+}
+
+// exchange sends a command and returns the lines received until one starts with until
+func exchange(t *testing.T, ws *websocket.Conn, command, until string) []string {
+	t.Helper()
+
+	require.NoError(t, ws.WriteMessage(websocket.TextMessage, []byte(command)))
+
+	var lines []string
+	for {
+		require.NoError(t, ws.SetReadDeadline(time.Now().Add(5*time.Second)))
+		_, data, err := ws.ReadMessage()
+		require.NoError(t, err)
+
+		lines = append(lines, string(data))
+		if strings.HasPrefix(string(data), until) {
+			return lines
+		}
+	}
+}
+
+func TestProxyHandshakeListsAllowedOptions(t *testing.T) {
+	ws := dialProxy(t)
+
+	lines := exchange(t, ws, "uci", "uciok")
+
+	var options []string
+	for _, line := range lines {
+		if name, ok := strings.CutPrefix(line, "option name "); ok {
+			options = append(options, strings.Fields(name)[0])
+		}
+	}
+	assert.ElementsMatch(t, []string{"Hash", "Threads", "MultiPV", "UCI_LimitStrength", "UCI_Elo"}, options)
+	assert.Contains(t, lines, "option name Hash type spin default 16 min 1 max 64")
+}
+
+func TestProxyRefusesOptionsOutsideAllowlist(t *testing.T) {
+	ws := dialProxy(t)
+
+	for _, command := range []string{
+		"setoption name SyzygyPath value /etc",
+		"setoption name Ponder value true",
+		"setoption name Skill Level value 3",
+	} {
+		lines := exchange(t, ws, command, "info string")
+		assert.Contains(t, lines[len(lines)-1], "cannot be set through the proxy", command)
+	}
+
+	lines := exchange(t, ws, "setoption name Hash value 128", "info string")
+	assert.Equal(t, "info string option Hash is limited to 64", lines[len(lines)-1])
+
+	// Allowed options reach the engine, the option names are case insensitive
+	require.NoError(t, ws.WriteMessage(websocket.TextMessage, []byte("setoption name multipv value 3")))
+	assert.Equal(t, []string{"readyok"}, exchange(t, ws, "isready", "readyok"))
+}
@@ -0,0 +1,5 @@
+module github.com/corentings/chess/v2
+
+go 1.22.0
+
+require golang.org/x/exp v0.0.0-20250128182459-e0ece0dbea4c